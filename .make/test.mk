@@ -298,6 +298,7 @@ define cleanup-coverage-file
 @sed -i '/.*\/bindata_assetfs\.go.*/d' $(1)
 @sed -i '/.*\/sqlbindata\.go.*/d' $(1)
 @sed -i '/.*\/confbindata\.go.*/d' $(1)
+@sed -i '/.*\/catalogbindata\.go.*/d' $(1)
 endef
 
 .PHONY: coverage-unit
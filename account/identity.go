@@ -11,6 +11,7 @@ import (
 	"github.com/fabric8-services/fabric8-auth/errors"
 	"github.com/fabric8-services/fabric8-auth/gormsupport"
 	"github.com/fabric8-services/fabric8-auth/log"
+	"github.com/fabric8-services/fabric8-auth/tracing"
 
 	"github.com/goadesign/goa"
 	"github.com/jinzhu/gorm"
@@ -130,7 +131,7 @@ func (m *GormIdentityRepository) TableName() string {
 // Load returns a single Identity as a Database Model
 // This is more for use internally, and probably not what you want in  your controllers
 func (m *GormIdentityRepository) Load(ctx context.Context, id uuid.UUID) (*Identity, error) {
-	defer goa.MeasureSince([]string{"goa", "db", "identity", "load"}, time.Now())
+	defer tracing.MeasureSince(ctx, []string{"goa", "db", "identity", "load"}, time.Now())
 
 	var native Identity
 	err := m.db.Table(m.TableName()).Where("id = ?", id).Find(&native).Error
@@ -143,13 +144,13 @@ func (m *GormIdentityRepository) Load(ctx context.Context, id uuid.UUID) (*Ident
 
 // CheckExists returns nil if the given ID exists otherwise returns an error
 func (m *GormIdentityRepository) CheckExists(ctx context.Context, id string) error {
-	defer goa.MeasureSince([]string{"goa", "db", "identity", "exists"}, time.Now())
+	defer tracing.MeasureSince(ctx, []string{"goa", "db", "identity", "exists"}, time.Now())
 	return repository.CheckExists(ctx, m.db, m.TableName(), id)
 }
 
 // Create creates a new record.
 func (m *GormIdentityRepository) Create(ctx context.Context, model *Identity) error {
-	defer goa.MeasureSince([]string{"goa", "db", "identity", "create"}, time.Now())
+	defer tracing.MeasureSince(ctx, []string{"goa", "db", "identity", "create"}, time.Now())
 	if model.ID == uuid.Nil {
 		model.ID = uuid.NewV4()
 	}
@@ -200,7 +201,7 @@ func (m *GormIdentityRepository) Lookup(ctx context.Context, username, profileUR
 
 // Save modifies a single record.
 func (m *GormIdentityRepository) Save(ctx context.Context, model *Identity) error {
-	defer goa.MeasureSince([]string{"goa", "db", "identity", "save"}, time.Now())
+	defer tracing.MeasureSince(ctx, []string{"goa", "db", "identity", "save"}, time.Now())
 
 	err := m.db.Save(model).Error
 
@@ -213,7 +214,7 @@ func (m *GormIdentityRepository) Save(ctx context.Context, model *Identity) erro
 
 // Delete removes a single record.
 func (m *GormIdentityRepository) Delete(ctx context.Context, id uuid.UUID) error {
-	defer goa.MeasureSince([]string{"goa", "db", "identity", "delete"}, time.Now())
+	defer tracing.MeasureSince(ctx, []string{"goa", "db", "identity", "delete"}, time.Now())
 
 	obj := Identity{ID: id}
 	db := m.db.Delete(obj)
@@ -324,7 +325,7 @@ func IdentityFilterByRegistrationCompleted(registrationCompleted bool) func(db *
 
 // List return all user identities
 func (m *GormIdentityRepository) List(ctx context.Context) ([]Identity, error) {
-	defer goa.MeasureSince([]string{"goa", "db", "identity", "list"}, time.Now())
+	defer tracing.MeasureSince(ctx, []string{"goa", "db", "identity", "list"}, time.Now())
 	var rows []Identity
 
 	err := m.db.Model(&Identity{}).Order("username").Find(&rows).Error
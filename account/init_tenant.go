@@ -4,49 +4,63 @@ import (
 	"context"
 	"net/http"
 	"net/url"
+	"path"
 
-	"github.com/fabric8-services/fabric8-auth/account/tenant"
-	"github.com/fabric8-services/fabric8-auth/goasupport"
 	"github.com/fabric8-services/fabric8-auth/rest"
-	"github.com/goadesign/goa/client"
+	"github.com/goadesign/goa/middleware"
+	"github.com/pkg/errors"
 )
 
 type tenantConfig interface {
 	GetTenantServiceURL() string
 }
 
-// NewInitTenant creates a new tenant service in oso
-func NewInitTenant(config tenantConfig) func(context.Context) error {
-	return func(ctx context.Context) error {
-		return InitTenant(ctx, config)
+// UpdateTenantCluster notifies the tenant service that the user of the given identity was reassigned to
+// another cluster, so the tenant of the user is moved to the cluster the user is now assigned to.
+// The request is authenticated with the given service account token.
+func UpdateTenantCluster(ctx context.Context, config tenantConfig, serviceAccountToken string, identityID string) error {
+	response, err := callTenantService(ctx, config, http.MethodPatch, serviceAccountToken, identityID)
+	if err != nil {
+		return err
+	}
+	defer rest.CloseResponse(response)
+	if response.StatusCode >= 400 {
+		return errors.Errorf("unable to update the cluster of the tenant. Response status: %s", response.Status)
 	}
+	return nil
 }
 
-// InitTenant creates a new tenant service in oso
-func InitTenant(ctx context.Context, config tenantConfig) error {
-	c, err := createClient(ctx, config)
+// SetupTenantOnBehalf sets up the tenant of the user of the given identity on behalf of the auth service,
+// e.g. when the provisioning is triggered by the outbox dispatcher and no token of the user is available.
+// The request is authenticated with the given service account token.
+// Returns an error if the tenant service rejected the request, unless the tenant already exists.
+func SetupTenantOnBehalf(ctx context.Context, config tenantConfig, serviceAccountToken string, identityID string) error {
+	response, err := callTenantService(ctx, config, http.MethodPost, serviceAccountToken, identityID)
 	if err != nil {
 		return err
 	}
-
-	// Ignore response for now
-	response, err := c.SetupTenant(goasupport.ForwardContextRequestID(ctx), tenant.SetupTenantPath())
-	if err == nil {
-		defer rest.CloseResponse(response)
+	defer rest.CloseResponse(response)
+	if response.StatusCode >= 400 && response.StatusCode != http.StatusConflict {
+		return errors.Errorf("unable to set up the tenant. Response status: %s", response.Status)
 	}
-
-	return err
+	return nil
 }
 
-func createClient(ctx context.Context, config tenantConfig) (*tenant.Client, error) {
+// callTenantService sends a request with the given method to the tenant of the given identity, on the endpoint
+// of the tenant service which only accepts the tokens of the service accounts
+func callTenantService(ctx context.Context, config tenantConfig, method string, serviceAccountToken string, identityID string) (*http.Response, error) {
 	u, err := url.Parse(config.GetTenantServiceURL())
 	if err != nil {
 		return nil, err
 	}
-
-	c := tenant.New(client.HTTPClientDoer(http.DefaultClient))
-	c.Host = u.Host
-	c.Scheme = u.Scheme
-	c.SetJWTSigner(goasupport.NewForwardSigner(ctx))
-	return c, nil
+	u.Path = path.Join(u.Path, "api", "tenants", identityID)
+	req, err := http.NewRequest(method, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+serviceAccountToken)
+	if reqID := middleware.ContextRequestID(ctx); reqID != "" {
+		req.Header.Set("X-Request-Id", reqID)
+	}
+	return http.DefaultClient.Do(req.WithContext(ctx))
 }
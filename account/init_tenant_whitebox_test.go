@@ -2,14 +2,12 @@ package account
 
 import (
 	"context"
-	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"testing"
 
-	"github.com/fabric8-services/fabric8-auth/configuration"
 	"github.com/fabric8-services/fabric8-auth/resource"
 
-	"github.com/dgrijalva/jwt-go"
-	goajwt "github.com/goadesign/goa/middleware/security/jwt"
 	"github.com/stretchr/testify/require"
 	"github.com/stretchr/testify/suite"
 )
@@ -21,37 +19,56 @@ func TestInitTenant(t *testing.T) {
 
 type TestInitTenantSuite struct {
 	suite.Suite
-	config *configuration.ConfigurationData
 }
 
-func (s *TestInitTenantSuite) SetupSuite() {
-	var err error
-	s.config, err = configuration.GetConfigurationData()
-	if err != nil {
-		panic(fmt.Errorf("failed to setup the configuration: %s", err.Error()))
-	}
-}
+func (s *TestInitTenantSuite) TestUpdateTenantCluster() {
+	var method, path, authorization string
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		method, path, authorization = req.Method, req.URL.Path, req.Header.Get("Authorization")
+		rw.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	err := UpdateTenantCluster(context.Background(), &urlConfig{url: server.URL}, "sa-token", "00000000-0000-0000-0000-000000000001")
 
-func (s *TestInitTenantSuite) TearDownSuite() {
+	require.Nil(s.T(), err)
+	require.Equal(s.T(), http.MethodPatch, method)
+	require.Equal(s.T(), "/api/tenants/00000000-0000-0000-0000-000000000001", path)
+	require.Equal(s.T(), "Bearer sa-token", authorization)
 }
 
-func (s *TestInitTenantSuite) TestNewInitTenantOK() {
-	require.NotNil(s.T(), NewInitTenant(s.config))
+func (s *TestInitTenantSuite) TestUpdateTenantClusterRejected() {
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.WriteHeader(http.StatusForbidden)
+	}))
+	defer server.Close()
+
+	err := UpdateTenantCluster(context.Background(), &urlConfig{url: server.URL}, "sa-token", "00000000-0000-0000-0000-000000000001")
+
+	require.NotNil(s.T(), err)
 }
 
-func (s *TestInitTenantSuite) TestCreateClientOK() {
-	claims := jwt.MapClaims{}
-	token := jwt.NewWithClaims(jwt.SigningMethodRS512, claims)
-	ctx := goajwt.WithJWT(context.Background(), token)
+func (s *TestInitTenantSuite) TestSetupTenantOnBehalf() {
+	var method, path, authorization string
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		method, path, authorization = req.Method, req.URL.Path, req.Header.Get("Authorization")
+		rw.WriteHeader(http.StatusConflict)
+	}))
+	defer server.Close()
+
+	// the tenant which already exists is set up
+	err := SetupTenantOnBehalf(context.Background(), &urlConfig{url: server.URL}, "sa-token", "00000000-0000-0000-0000-000000000001")
 
-	c, err := createClient(ctx, &dummyConfig{})
 	require.Nil(s.T(), err)
-	require.NotNil(s.T(), c)
+	require.Equal(s.T(), http.MethodPost, method)
+	require.Equal(s.T(), "/api/tenants/00000000-0000-0000-0000-000000000001", path)
+	require.Equal(s.T(), "Bearer sa-token", authorization)
 }
 
-type dummyConfig struct {
+type urlConfig struct {
+	url string
 }
 
-func (c *dummyConfig) GetTenantServiceURL() string {
-	return "https://tenant.local"
+func (c *urlConfig) GetTenantServiceURL() string {
+	return c.url
 }
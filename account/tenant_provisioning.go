@@ -0,0 +1,124 @@
+package account
+
+import (
+	"context"
+	"time"
+
+	"github.com/fabric8-services/fabric8-auth/errors"
+	"github.com/fabric8-services/fabric8-auth/gormsupport"
+	"github.com/fabric8-services/fabric8-auth/log"
+	"github.com/fabric8-services/fabric8-auth/tracing"
+
+	"github.com/jinzhu/gorm"
+	errs "github.com/pkg/errors"
+	uuid "github.com/satori/go.uuid"
+)
+
+const (
+	// TenantProvisioningStatePending is the state of the tenants which are requested but not set up yet
+	TenantProvisioningStatePending = "pending"
+	// TenantProvisioningStateInProgress is the state of the tenants the tenant service failed to set up so far,
+	// and whose setup is retried
+	TenantProvisioningStateInProgress = "in_progress"
+	// TenantProvisioningStateReady is the state of the tenants which are set up
+	TenantProvisioningStateReady = "ready"
+	// TenantProvisioningStateFailed is the state of the tenants which could not be set up after the max number of attempts
+	TenantProvisioningStateFailed = "failed"
+)
+
+// TenantProvisioning describes the state of the provisioning of the tenant of the user of an identity
+type TenantProvisioning struct {
+	gormsupport.LifecycleHardDelete
+	IdentityID uuid.UUID `sql:"type:uuid" gorm:"primary_key"` // This is the PK field
+	State      string
+	// Attempts is the number of failed attempts to set up the tenant since it was last requested
+	Attempts int
+	// Error is the error returned by the last failed attempt, if any
+	Error string
+}
+
+// TableName overrides the table name settings in Gorm to force a specific table name
+// in the database.
+func (m TenantProvisioning) TableName() string {
+	return "tenant_provisionings"
+}
+
+// GormTenantProvisioningRepository is the implementation of the storage interface for TenantProvisioning.
+type GormTenantProvisioningRepository struct {
+	db *gorm.DB
+}
+
+// NewTenantProvisioningRepository creates a new storage type.
+func NewTenantProvisioningRepository(db *gorm.DB) *GormTenantProvisioningRepository {
+	return &GormTenantProvisioningRepository{db: db}
+}
+
+// TenantProvisioningRepository represents the storage interface.
+type TenantProvisioningRepository interface {
+	Load(ctx context.Context, identityID uuid.UUID) (*TenantProvisioning, error)
+	Request(ctx context.Context, identityID uuid.UUID) error
+	RecordAttempt(ctx context.Context, identityID uuid.UUID, state string, attemptErr error) error
+}
+
+// Load returns the tenant provisioning of the given identity
+// returns NotFoundError or InternalError
+func (m *GormTenantProvisioningRepository) Load(ctx context.Context, identityID uuid.UUID) (*TenantProvisioning, error) {
+	defer tracing.MeasureSince(ctx, []string{"goa", "db", "tenant_provisioning", "load"}, time.Now())
+	provisioning := TenantProvisioning{}
+	tx := m.db.Where("identity_id = ?", identityID).First(&provisioning)
+	if tx.RecordNotFound() {
+		return nil, errors.NewNotFoundError("tenant_provisioning", identityID.String())
+	}
+	if tx.Error != nil {
+		return nil, errors.NewInternalError(ctx, tx.Error)
+	}
+	return &provisioning, nil
+}
+
+// Request marks the tenant of the given identity as pending, so the attempts and the error of a previous
+// provisioning are reset
+func (m *GormTenantProvisioningRepository) Request(ctx context.Context, identityID uuid.UUID) error {
+	defer tracing.MeasureSince(ctx, []string{"goa", "db", "tenant_provisioning", "request"}, time.Now())
+	err := m.db.Exec(`INSERT INTO tenant_provisionings (identity_id, created_at, updated_at, state, attempts, error)
+		VALUES (?, now(), now(), ?, 0, '')
+		ON CONFLICT (identity_id) DO UPDATE SET updated_at = now(), state = excluded.state, attempts = 0, error = ''`,
+		identityID, TenantProvisioningStatePending).Error
+	if err != nil {
+		log.Error(ctx, map[string]interface{}{
+			"identity_id": identityID,
+			"err":         err,
+		}, "unable to request the tenant provisioning")
+		return errs.WithStack(err)
+	}
+	return nil
+}
+
+// RecordAttempt records the outcome of an attempt to set up the tenant of the given identity.
+// Only the failed attempts are counted. The error is cleared if the attempt succeeded.
+func (m *GormTenantProvisioningRepository) RecordAttempt(ctx context.Context, identityID uuid.UUID, state string, attemptErr error) error {
+	defer tracing.MeasureSince(ctx, []string{"goa", "db", "tenant_provisioning", "recordAttempt"}, time.Now())
+	errMsg := ""
+	failedAttempts := 0
+	if attemptErr != nil {
+		errMsg = attemptErr.Error()
+		failedAttempts = 1
+	}
+	err := m.db.Exec(`INSERT INTO tenant_provisionings (identity_id, created_at, updated_at, state, attempts, error)
+		VALUES (?, now(), now(), ?, ?, ?)
+		ON CONFLICT (identity_id) DO UPDATE SET updated_at = now(), state = excluded.state,
+		attempts = tenant_provisionings.attempts + excluded.attempts, error = excluded.error`,
+		identityID, state, failedAttempts, errMsg).Error
+	if err != nil {
+		log.Error(ctx, map[string]interface{}{
+			"identity_id": identityID,
+			"state":       state,
+			"err":         err,
+		}, "unable to record the tenant provisioning attempt")
+		return errs.WithStack(err)
+	}
+	log.Debug(ctx, map[string]interface{}{
+		"identity_id": identityID,
+		"state":       state,
+	}, "tenant provisioning attempt recorded")
+	return nil
+}
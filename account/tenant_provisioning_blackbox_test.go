@@ -0,0 +1,87 @@
+package account_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/fabric8-services/fabric8-auth/account"
+	autherrors "github.com/fabric8-services/fabric8-auth/errors"
+	"github.com/fabric8-services/fabric8-auth/gormtestsupport"
+	"github.com/fabric8-services/fabric8-auth/resource"
+	testsupport "github.com/fabric8-services/fabric8-auth/test"
+
+	errs "github.com/pkg/errors"
+	"github.com/satori/go.uuid"
+	"github.com/stretchr/testify/suite"
+)
+
+type tenantProvisioningBlackBoxTest struct {
+	gormtestsupport.DBTestSuite
+	repo account.TenantProvisioningRepository
+}
+
+func TestRunTenantProvisioningBlackBoxTest(t *testing.T) {
+	resource.Require(t, resource.Database)
+	suite.Run(t, &tenantProvisioningBlackBoxTest{DBTestSuite: gormtestsupport.NewDBTestSuite()})
+}
+
+func (s *tenantProvisioningBlackBoxTest) SetupTest() {
+	s.DBTestSuite.SetupTest()
+	s.repo = account.NewTenantProvisioningRepository(s.DB)
+}
+
+func (s *tenantProvisioningBlackBoxTest) TestRequestAndRecordAttempts() {
+	// given
+	identity, err := testsupport.CreateTestIdentity(s.DB, "tenant-"+uuid.NewV4().String(), "kc")
+	s.Require().NoError(err)
+
+	// when
+	err = s.repo.Request(s.Ctx, identity.ID)
+
+	// then
+	s.Require().NoError(err)
+	provisioning, err := s.repo.Load(s.Ctx, identity.ID)
+	s.Require().NoError(err)
+	s.Equal(account.TenantProvisioningStatePending, provisioning.State)
+	s.Equal(0, provisioning.Attempts)
+
+	// when an attempt fails
+	err = s.repo.RecordAttempt(s.Ctx, identity.ID, account.TenantProvisioningStateInProgress, errors.New("tenant service unavailable"))
+
+	// then
+	s.Require().NoError(err)
+	provisioning, err = s.repo.Load(s.Ctx, identity.ID)
+	s.Require().NoError(err)
+	s.Equal(account.TenantProvisioningStateInProgress, provisioning.State)
+	s.Equal(1, provisioning.Attempts)
+	s.Equal("tenant service unavailable", provisioning.Error)
+
+	// when the next attempt succeeds
+	err = s.repo.RecordAttempt(s.Ctx, identity.ID, account.TenantProvisioningStateReady, nil)
+
+	// then the error is cleared and the successful attempt is not counted
+	s.Require().NoError(err)
+	provisioning, err = s.repo.Load(s.Ctx, identity.ID)
+	s.Require().NoError(err)
+	s.Equal(account.TenantProvisioningStateReady, provisioning.State)
+	s.Equal(1, provisioning.Attempts)
+	s.Empty(provisioning.Error)
+
+	// when requested again
+	err = s.repo.Request(s.Ctx, identity.ID)
+
+	// then the attempts are reset
+	s.Require().NoError(err)
+	provisioning, err = s.repo.Load(s.Ctx, identity.ID)
+	s.Require().NoError(err)
+	s.Equal(account.TenantProvisioningStatePending, provisioning.State)
+	s.Equal(0, provisioning.Attempts)
+}
+
+func (s *tenantProvisioningBlackBoxTest) TestLoadUnknownNotFound() {
+	// when
+	_, err := s.repo.Load(s.Ctx, uuid.NewV4())
+
+	// then
+	s.IsType(autherrors.NotFoundError{}, errs.Cause(err))
+}
@@ -9,6 +9,7 @@ import (
 	"github.com/fabric8-services/fabric8-auth/errors"
 	"github.com/fabric8-services/fabric8-auth/gormsupport"
 	"github.com/fabric8-services/fabric8-auth/log"
+	"github.com/fabric8-services/fabric8-auth/tracing"
 
 	"github.com/goadesign/goa"
 	"github.com/jinzhu/gorm"
@@ -23,16 +24,18 @@ import (
 // User describes a User account. A few identities can be assosiated with one user account
 type User struct {
 	gormsupport.Lifecycle
-	ID                 uuid.UUID          `sql:"type:uuid default uuid_generate_v4()" gorm:"primary_key"` // This is the ID PK field
-	Email              string             `sql:"unique_index"`                                            // This is the unique email field
-	FullName           string             // The fullname of the User
-	ImageURL           string             // The image URL for the User
-	Bio                string             // The bio of the User
-	URL                string             // The URL of the User
-	Company            string             // The (optional) Company of the User
-	Cluster            string             // The OpenShift cluster allocted to the user.
-	Identities         []Identity         // has many Identities from different IDPs
-	ContextInformation ContextInformation `sql:"type:jsonb"` // context information of the user activity
+	ID                  uuid.UUID          `sql:"type:uuid default uuid_generate_v4()" gorm:"primary_key"` // This is the ID PK field
+	Email               string             `sql:"unique_index"`                                            // This is the unique email field
+	EmailVerified       bool               // Whether the email was verified in Keycloak
+	FullName            string             // The fullname of the User
+	ImageURL            string             // The image URL for the User
+	Bio                 string             // The bio of the User
+	URL                 string             // The URL of the User
+	Company             string             // The (optional) Company of the User
+	Cluster             string             // The OpenShift cluster allocted to the user.
+	ClusterLinkRequired bool               // Whether the OpenShift account must be linked again, after the user was reassigned to another cluster
+	Identities          []Identity         // has many Identities from different IDPs
+	ContextInformation  ContextInformation `sql:"type:jsonb"` // context information of the user activity
 }
 
 // TableName overrides the table name settings in Gorm to force a specific table name
@@ -41,10 +44,19 @@ func (m User) TableName() string {
 	return "users"
 }
 
+// VerifiedEmail returns the email of the User if it was verified in Keycloak, an empty string otherwise
+func (m User) VerifiedEmail() string {
+	if !m.EmailVerified {
+		return ""
+	}
+	return m.Email
+}
+
 // GetETagData returns the field values to use to generate the ETag
 func (m User) GetETagData() []interface{} {
-	// using the 'ID' and 'UpdatedAt' (converted to number of seconds since epoch) fields
-	return []interface{}{m.ID, strconv.FormatInt(m.UpdatedAt.Unix(), 10)}
+	// using the 'ID' and 'UpdatedAt' (converted to number of microseconds since epoch, the precision of the database) fields,
+	// so the ETag changes on every update, even within the same second
+	return []interface{}{m.ID, strconv.FormatInt(m.UpdatedAt.UnixNano()/int64(time.Microsecond), 10)}
 }
 
 // GetLastModified returns the last modification time
@@ -66,11 +78,13 @@ func NewUserRepository(db *gorm.DB) UserRepository {
 type UserRepository interface {
 	repository.Exister
 	Load(ctx context.Context, ID uuid.UUID) (*User, error)
+	Lock(ctx context.Context, ID uuid.UUID) (*User, error)
 	Create(ctx context.Context, u *User) error
 	Save(ctx context.Context, u *User) error
 	List(ctx context.Context) ([]User, error)
 	Delete(ctx context.Context, ID uuid.UUID) error
 	Query(funcs ...func(*gorm.DB) *gorm.DB) ([]User, error)
+	CountByCluster(ctx context.Context) (map[string]int, error)
 }
 
 // TableName overrides the table name settings in Gorm to force a specific table name
@@ -84,7 +98,7 @@ func (m *GormUserRepository) TableName() string {
 // Load returns a single User as a Database Model
 // This is more for use internally, and probably not what you want in  your controllers
 func (m *GormUserRepository) Load(ctx context.Context, id uuid.UUID) (*User, error) {
-	defer goa.MeasureSince([]string{"goa", "db", "user", "load"}, time.Now())
+	defer tracing.MeasureSince(ctx, []string{"goa", "db", "user", "load"}, time.Now())
 	var native User
 	err := m.db.Table(m.TableName()).Where("id = ?", id).Find(&native).Error
 	if err == gorm.ErrRecordNotFound {
@@ -93,15 +107,27 @@ func (m *GormUserRepository) Load(ctx context.Context, id uuid.UUID) (*User, err
 	return &native, errs.WithStack(err)
 }
 
+// Lock loads the User with the given ID and locks it until the end of the transaction,
+// so it can't be updated concurrently, e.g. between a conditional write check and the update
+func (m *GormUserRepository) Lock(ctx context.Context, id uuid.UUID) (*User, error) {
+	defer tracing.MeasureSince(ctx, []string{"goa", "db", "user", "lock"}, time.Now())
+	var native User
+	err := m.db.Table(m.TableName()).Set("gorm:query_option", "FOR UPDATE").Where("id = ?", id).Find(&native).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, errors.NewNotFoundError("user", id.String())
+	}
+	return &native, errs.WithStack(err)
+}
+
 // CheckExists returns nil if the given ID exists otherwise returns an error
 func (m *GormUserRepository) CheckExists(ctx context.Context, id string) error {
-	defer goa.MeasureSince([]string{"goa", "db", "user", "exists"}, time.Now())
+	defer tracing.MeasureSince(ctx, []string{"goa", "db", "user", "exists"}, time.Now())
 	return repository.CheckExists(ctx, m.db, m.TableName(), id)
 }
 
 // Create creates a new record.
 func (m *GormUserRepository) Create(ctx context.Context, u *User) error {
-	defer goa.MeasureSince([]string{"goa", "db", "user", "create"}, time.Now())
+	defer tracing.MeasureSince(ctx, []string{"goa", "db", "user", "create"}, time.Now())
 	if u.ID == uuid.Nil {
 		u.ID = uuid.NewV4()
 	}
@@ -122,7 +148,7 @@ func (m *GormUserRepository) Create(ctx context.Context, u *User) error {
 
 // Save modifies a single record
 func (m *GormUserRepository) Save(ctx context.Context, model *User) error {
-	defer goa.MeasureSince([]string{"goa", "db", "user", "save"}, time.Now())
+	defer tracing.MeasureSince(ctx, []string{"goa", "db", "user", "save"}, time.Now())
 
 	err := m.db.Save(model).Error
 	if err != nil {
@@ -137,7 +163,7 @@ func (m *GormUserRepository) Save(ctx context.Context, model *User) error {
 
 // Delete removes a single record.
 func (m *GormUserRepository) Delete(ctx context.Context, id uuid.UUID) error {
-	defer goa.MeasureSince([]string{"goa", "db", "user", "delete"}, time.Now())
+	defer tracing.MeasureSince(ctx, []string{"goa", "db", "user", "delete"}, time.Now())
 
 	obj := User{ID: id}
 
@@ -160,7 +186,7 @@ func (m *GormUserRepository) Delete(ctx context.Context, id uuid.UUID) error {
 
 // List return all users
 func (m *GormUserRepository) List(ctx context.Context) ([]User, error) {
-	defer goa.MeasureSince([]string{"goa", "db", "user", "list"}, time.Now())
+	defer tracing.MeasureSince(ctx, []string{"goa", "db", "user", "list"}, time.Now())
 	var rows []User
 
 	err := m.db.Model(&User{}).Order("email").Find(&rows).Error
@@ -187,6 +213,33 @@ func (m *GormUserRepository) Query(funcs ...func(*gorm.DB) *gorm.DB) ([]User, er
 	return objs, nil
 }
 
+// CountByCluster returns the number of users by cluster API URL
+func (m *GormUserRepository) CountByCluster(ctx context.Context) (map[string]int, error) {
+	defer tracing.MeasureSince(ctx, []string{"goa", "db", "user", "countByCluster"}, time.Now())
+	rows, err := m.db.Table(m.TableName()).Select("cluster, count(*)").Where("deleted_at IS NULL").Group("cluster").Rows()
+	if err != nil {
+		return nil, errs.WithStack(err)
+	}
+	defer rows.Close()
+	counts := map[string]int{}
+	for rows.Next() {
+		var cluster string
+		var count int
+		if err := rows.Scan(&cluster, &count); err != nil {
+			return nil, errs.WithStack(err)
+		}
+		counts[cluster] = count
+	}
+	return counts, errs.WithStack(rows.Err())
+}
+
+// UserWithLatestFirst is a gorm scope which orders the users from the most recently created one
+func UserWithLatestFirst() func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Order("created_at DESC")
+	}
+}
+
 // UserFilterByID is a gorm filter for User ID.
 func UserFilterByID(userID uuid.UUID) func(db *gorm.DB) *gorm.DB {
 	return func(db *gorm.DB) *gorm.DB {
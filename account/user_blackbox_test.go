@@ -59,6 +59,28 @@ func (s *userBlackBoxTest) TestOKToLoad() {
 	createAndLoadUser(s) // this function does the needful already
 }
 
+func (s *userBlackBoxTest) TestOKToLock() {
+	t := s.T()
+	resource.Require(t, resource.Database)
+
+	t.Run("user exists", func(t *testing.T) {
+		user := createAndLoadUser(s)
+		// when
+		locked, err := s.repo.Lock(s.Ctx, user.ID)
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, user.ID, locked.ID)
+		assert.Equal(t, user.GetETagData(), locked.GetETagData())
+	})
+
+	t.Run("user doesn't exist", func(t *testing.T) {
+		// when
+		_, err := s.repo.Lock(s.Ctx, uuid.NewV4())
+		// then
+		require.IsType(t, errors.NotFoundError{}, err)
+	})
+}
+
 func (s *userBlackBoxTest) TestExistsUser() {
 	t := s.T()
 	resource.Require(t, resource.Database)
@@ -171,6 +193,28 @@ func (s *userBlackBoxTest) TestUpdateToEmptyString() {
 	require.Empty(t, u.Bio)
 }
 
+func (s *userBlackBoxTest) TestCountByCluster() {
+	t := s.T()
+	resource.Require(t, resource.Database)
+
+	// given
+	user := createAndLoadUser(s)
+	other := createAndLoadUser(s)
+	other.Cluster = user.Cluster
+	require.Nil(t, s.repo.Save(s.Ctx, other))
+	deleted := createAndLoadUser(s)
+	deleted.Cluster = user.Cluster
+	require.Nil(t, s.repo.Save(s.Ctx, deleted))
+	require.Nil(t, s.repo.Delete(s.Ctx, deleted.ID))
+
+	// when
+	counts, err := s.repo.CountByCluster(s.Ctx)
+
+	// then the deleted users are not counted
+	require.Nil(t, err)
+	assert.Equal(t, 2, counts[user.Cluster])
+}
+
 func createAndLoadUser(s *userBlackBoxTest) *account.User {
 	user := &account.User{
 		ID:       uuid.NewV4(),
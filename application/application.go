@@ -2,21 +2,40 @@ package application
 
 import (
 	"github.com/fabric8-services/fabric8-auth/account"
+	"github.com/fabric8-services/fabric8-auth/audit"
 	"github.com/fabric8-services/fabric8-auth/auth"
 	"github.com/fabric8-services/fabric8-auth/authorization/resource"
+	"github.com/fabric8-services/fabric8-auth/authorization/role"
+	"github.com/fabric8-services/fabric8-auth/cluster/registry"
+	"github.com/fabric8-services/fabric8-auth/invitation"
+	"github.com/fabric8-services/fabric8-auth/outbox"
 	"github.com/fabric8-services/fabric8-auth/space"
 	"github.com/fabric8-services/fabric8-auth/token/provider"
+	"github.com/fabric8-services/fabric8-auth/webhook"
 )
 
 //An Application stands for a particular implementation of the business logic of our application
 type Application interface {
 	Identities() account.IdentityRepository
 	SpaceResources() space.ResourceRepository
+	SpaceTeams() space.TeamRepository
+	SpaceAccessRequests() space.AccessRequestRepository
+	SpaceCollaboratorExpiries() space.CollaboratorExpiryRepository
 	Users() account.UserRepository
+	TenantProvisionings() account.TenantProvisioningRepository
 	OauthStates() auth.OauthStateReferenceRepository
 	ExternalTokens() provider.ExternalTokenRepository
 	ResourceRepository() resource.ResourceRepository
 	ResourceTypeRepository() resource.ResourceTypeRepository
+	ResourceTypeScopeRepository() resource.ResourceTypeScopeRepository
+	RoleRepository() role.RoleRepository
+	IdentityRoleRepository() role.IdentityRoleRepository
+	OutboxEvents() outbox.EventRepository
+	Invitations() invitation.InvitationRepository
+	AuditEvents() audit.EventRepository
+	Clusters() registry.ClusterRepository
+	WebhookSubscriptions() webhook.SubscriptionRepository
+	WebhookDeliveries() webhook.DeliveryRepository
 }
 
 // A Transaction abstracts a database transaction. The repositories created for the transaction object make changes inside the the transaction
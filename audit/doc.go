@@ -0,0 +1,4 @@
+// Package audit stores the audit trail of the changes of the access rights: the role assignments
+// and the space collaborators added, removed, extended or expired, along with the user who made
+// the change and the request it was made in.
+package audit
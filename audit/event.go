@@ -0,0 +1,168 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/fabric8-services/fabric8-auth/errors"
+	"github.com/fabric8-services/fabric8-auth/log"
+	"github.com/fabric8-services/fabric8-auth/tracing"
+
+	"github.com/goadesign/goa"
+	"github.com/goadesign/goa/middleware"
+	"github.com/jinzhu/gorm"
+	errs "github.com/pkg/errors"
+	uuid "github.com/satori/go.uuid"
+)
+
+const (
+	// EventTypeCollaboratorAdded is the type of the events recorded when a user is added to the collaborators of a space
+	EventTypeCollaboratorAdded = "collaborator.added"
+	// EventTypeCollaboratorRemoved is the type of the events recorded when a user is removed from the collaborators of a space
+	EventTypeCollaboratorRemoved = "collaborator.removed"
+	// EventTypeRoleExtended is the type of the events recorded when the expiry of a role assignment is extended
+	EventTypeRoleExtended = "role.extended"
+	// EventTypeRoleExpired is the type of the events recorded when an expired role assignment is removed
+	EventTypeRoleExpired = "role.expired"
+	// EventTypeCollaboratorExtended is the type of the events recorded when the expiry of a space collaborator is extended
+	EventTypeCollaboratorExtended = "collaborator.extended"
+	// EventTypeCollaboratorExpired is the type of the events recorded when an expired space collaborator is removed
+	EventTypeCollaboratorExpired = "collaborator.expired"
+
+	// ResourceTypeSpace is the type of the resource of the events about the space collaborators
+	ResourceTypeSpace = "space"
+)
+
+// Event describes a change of the access rights of a user on a resource. The actor is nil
+// when the change was not made by a user, e.g. when an expired role assignment is removed.
+type Event struct {
+	ID           uuid.UUID `sql:"type:uuid default uuid_generate_v4()" gorm:"primary_key"` // This is the ID PK field
+	CreatedAt    time.Time
+	EventType    string
+	ActorID      *uuid.UUID `sql:"type:uuid"`
+	TargetID     *uuid.UUID `sql:"type:uuid"`
+	ResourceType string
+	ResourceID   string
+	RequestID    string
+	Details      *string `sql:"type:jsonb"`
+}
+
+// TableName overrides the table name settings in Gorm to force a specific table name
+// in the database.
+func (m Event) TableName() string {
+	return "audit_events"
+}
+
+// NewEvent returns a new event about the given target on the given resource, with the given details encoded as JSON.
+// The request ID is read from the given context, if any.
+func NewEvent(ctx context.Context, eventType string, actorID *uuid.UUID, targetID *uuid.UUID, resourceType string, resourceID string, details map[string]interface{}) (*Event, error) {
+	event := &Event{
+		EventType:    eventType,
+		ActorID:      actorID,
+		TargetID:     targetID,
+		ResourceType: resourceType,
+		ResourceID:   resourceID,
+		RequestID:    middleware.ContextRequestID(ctx),
+	}
+	if details != nil {
+		js, err := json.Marshal(details)
+		if err != nil {
+			return nil, errs.Wrapf(err, "unable to encode the details of the %s audit event", eventType)
+		}
+		d := string(js)
+		event.Details = &d
+	}
+	return event, nil
+}
+
+// GormEventRepository is the implementation of the storage interface for Event.
+type GormEventRepository struct {
+	db *gorm.DB
+}
+
+// NewEventRepository creates a new storage type.
+func NewEventRepository(db *gorm.DB) *GormEventRepository {
+	return &GormEventRepository{db: db}
+}
+
+// EventRepository represents the storage interface.
+type EventRepository interface {
+	Create(ctx context.Context, event *Event) error
+	List(ctx context.Context, resourceType string, resourceID string, offset int, limit int) ([]Event, int, error)
+	Query(funcs ...func(*gorm.DB) *gorm.DB) ([]Event, error)
+}
+
+// TableName overrides the table name settings in Gorm to force a specific table name
+// in the database.
+func (m *GormEventRepository) TableName() string {
+	return "audit_events"
+}
+
+// Create creates a new record.
+func (m *GormEventRepository) Create(ctx context.Context, model *Event) error {
+	defer tracing.MeasureSince(ctx, []string{"goa", "db", "audit_event", "create"}, time.Now())
+	if model.ID == uuid.Nil {
+		model.ID = uuid.NewV4()
+	}
+	err := m.db.Create(model).Error
+	if err != nil {
+		log.Error(ctx, map[string]interface{}{
+			"event_type":    model.EventType,
+			"resource_type": model.ResourceType,
+			"resource_id":   model.ResourceID,
+			"err":           err,
+		}, "unable to create the audit event")
+		return errors.NewInternalError(ctx, err)
+	}
+	log.Info(ctx, map[string]interface{}{
+		"audit_event_id": model.ID,
+		"event_type":     model.EventType,
+		"actor_id":       model.ActorID,
+		"target_id":      model.TargetID,
+		"resource_type":  model.ResourceType,
+		"resource_id":    model.ResourceID,
+	}, "audit event recorded")
+	return nil
+}
+
+// List returns the given page of events about the given resource, the most recent first, along with the total number of events
+func (m *GormEventRepository) List(ctx context.Context, resourceType string, resourceID string, offset int, limit int) ([]Event, int, error) {
+	defer tracing.MeasureSince(ctx, []string{"goa", "db", "audit_event", "list"}, time.Now())
+	db := m.db.Model(&Event{}).Scopes(EventFilterByResource(resourceType, resourceID))
+	var count int
+	if err := db.Count(&count).Error; err != nil {
+		return nil, 0, errors.NewInternalError(ctx, err)
+	}
+	var events []Event
+	err := db.Order("created_at DESC").Offset(offset).Limit(limit).Find(&events).Error
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return nil, 0, errors.NewInternalError(ctx, err)
+	}
+	return events, count, nil
+}
+
+// Query expose an open ended Query model
+func (m *GormEventRepository) Query(funcs ...func(*gorm.DB) *gorm.DB) ([]Event, error) {
+	defer goa.MeasureSince([]string{"goa", "db", "audit_event", "query"}, time.Now())
+	var events []Event
+	err := m.db.Scopes(funcs...).Table(m.TableName()).Order("created_at").Find(&events).Error
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return nil, errs.WithStack(err)
+	}
+	return events, nil
+}
+
+// EventFilterByResource is a gorm filter for the resource the events are about
+func EventFilterByResource(resourceType string, resourceID string) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where("resource_type = ? AND resource_id = ?", resourceType, resourceID)
+	}
+}
+
+// EventFilterByTargetID is a gorm filter for the identity the events are about
+func EventFilterByTargetID(targetID uuid.UUID) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where("target_id = ?", targetID)
+	}
+}
@@ -0,0 +1,102 @@
+package audit_test
+
+import (
+	"testing"
+
+	"github.com/fabric8-services/fabric8-auth/audit"
+	"github.com/fabric8-services/fabric8-auth/gormtestsupport"
+	"github.com/fabric8-services/fabric8-auth/resource"
+
+	"github.com/satori/go.uuid"
+	"github.com/stretchr/testify/suite"
+)
+
+type eventBlackBoxTest struct {
+	gormtestsupport.DBTestSuite
+	repo audit.EventRepository
+}
+
+func TestRunEventBlackBoxTest(t *testing.T) {
+	resource.Require(t, resource.Database)
+	suite.Run(t, &eventBlackBoxTest{DBTestSuite: gormtestsupport.NewDBTestSuite()})
+}
+
+func (s *eventBlackBoxTest) SetupTest() {
+	s.DBTestSuite.SetupTest()
+	s.repo = audit.NewEventRepository(s.DB)
+}
+
+func (s *eventBlackBoxTest) TestCreateAndQuery() {
+	// given
+	spaceID := uuid.NewV4().String()
+	actorID := uuid.NewV4()
+	targetID := uuid.NewV4()
+	event, err := audit.NewEvent(s.Ctx, audit.EventTypeCollaboratorExtended, &actorID, &targetID, audit.ResourceTypeSpace, spaceID, map[string]interface{}{
+		"expires_at": "2030-01-01T00:00:00Z",
+	})
+	s.Require().NoError(err)
+
+	// when
+	err = s.repo.Create(s.Ctx, event)
+
+	// then
+	s.Require().NoError(err)
+	s.NotEqual(uuid.Nil, event.ID)
+	events, err := s.repo.Query(audit.EventFilterByResource(audit.ResourceTypeSpace, spaceID))
+	s.Require().NoError(err)
+	s.Require().Len(events, 1)
+	s.Equal(audit.EventTypeCollaboratorExtended, events[0].EventType)
+	s.Equal(actorID, *events[0].ActorID)
+	s.Equal(targetID, *events[0].TargetID)
+	s.Require().NotNil(events[0].Details)
+	s.Contains(*events[0].Details, "2030-01-01T00:00:00Z")
+	events, err = s.repo.Query(audit.EventFilterByTargetID(targetID))
+	s.Require().NoError(err)
+	s.Len(events, 1)
+}
+
+func (s *eventBlackBoxTest) TestCreateWithoutActor() {
+	// given
+	event, err := audit.NewEvent(s.Ctx, audit.EventTypeRoleExpired, nil, nil, "organization", uuid.NewV4().String(), nil)
+	s.Require().NoError(err)
+
+	// when
+	err = s.repo.Create(s.Ctx, event)
+
+	// then
+	s.Require().NoError(err)
+	events, err := s.repo.Query(audit.EventFilterByResource("organization", event.ResourceID))
+	s.Require().NoError(err)
+	s.Require().Len(events, 1)
+	s.Nil(events[0].ActorID)
+	s.Nil(events[0].Details)
+}
+
+func (s *eventBlackBoxTest) TestList() {
+	// given
+	spaceID := uuid.NewV4().String()
+	for _, eventType := range []string{audit.EventTypeCollaboratorAdded, audit.EventTypeCollaboratorExtended, audit.EventTypeCollaboratorRemoved} {
+		event, err := audit.NewEvent(s.Ctx, eventType, nil, nil, audit.ResourceTypeSpace, spaceID, nil)
+		s.Require().NoError(err)
+		s.Require().NoError(s.repo.Create(s.Ctx, event))
+	}
+
+	// when
+	events, count, err := s.repo.List(s.Ctx, audit.ResourceTypeSpace, spaceID, 0, 2)
+
+	// then the most recent events come first
+	s.Require().NoError(err)
+	s.Equal(3, count)
+	s.Require().Len(events, 2)
+	s.Equal(audit.EventTypeCollaboratorRemoved, events[0].EventType)
+	s.Equal(audit.EventTypeCollaboratorExtended, events[1].EventType)
+
+	// when
+	events, count, err = s.repo.List(s.Ctx, audit.ResourceTypeSpace, spaceID, 2, 2)
+
+	// then
+	s.Require().NoError(err)
+	s.Equal(3, count)
+	s.Require().Len(events, 1)
+	s.Equal(audit.EventTypeCollaboratorAdded, events[0].EventType)
+}
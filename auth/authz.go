@@ -189,7 +189,7 @@ func CreateResource(ctx context.Context, resource KeycloakResource, authzEndpoin
 	}
 	req.Header.Add("Content-Type", "application/json")
 	req.Header.Add("Authorization", "Bearer "+protectionAPIToken)
-	res, err := http.DefaultClient.Do(req)
+	res, err := http.DefaultClient.Do(req.WithContext(ctx))
 	if err != nil {
 		log.Error(ctx, map[string]interface{}{
 			"auth_endpoint": authzEndpoint,
@@ -239,7 +239,7 @@ func GetClientID(ctx context.Context, clientsEndpoint string, publicClientID str
 		return "", errors.NewInternalError(ctx, errs.Wrap(err, "unable to create http request"))
 	}
 	req.Header.Add("Authorization", "Bearer "+protectionAPIToken)
-	res, err := http.DefaultClient.Do(req)
+	res, err := http.DefaultClient.Do(req.WithContext(ctx))
 	if err != nil {
 		log.Error(ctx, map[string]interface{}{
 			"public_client_id": publicClientID,
@@ -299,7 +299,7 @@ func CreatePolicy(ctx context.Context, clientsEndpoint string, clientID string,
 	}
 	req.Header.Add("Content-Type", "application/json")
 	req.Header.Add("Authorization", "Bearer "+protectionAPIToken)
-	res, err := http.DefaultClient.Do(req)
+	res, err := http.DefaultClient.Do(req.WithContext(ctx))
 	if err != nil {
 		log.Error(ctx, map[string]interface{}{
 			"client_id": clientID,
@@ -354,7 +354,7 @@ func CreatePermission(ctx context.Context, clientsEndpoint string, clientID stri
 	}
 	req.Header.Add("Content-Type", "application/json")
 	req.Header.Add("Authorization", "Bearer "+protectionAPIToken)
-	res, err := http.DefaultClient.Do(req)
+	res, err := http.DefaultClient.Do(req.WithContext(ctx))
 	if err != nil {
 		log.Error(ctx, map[string]interface{}{
 			"client_id":  clientID,
@@ -407,7 +407,7 @@ func DeleteResource(ctx context.Context, kcResourceID string, authzEndpoint stri
 		return errors.NewInternalError(ctx, errs.Wrap(err, "unable to create http request"))
 	}
 	req.Header.Add("Authorization", "Bearer "+protectionAPIToken)
-	res, err := http.DefaultClient.Do(req)
+	res, err := http.DefaultClient.Do(req.WithContext(ctx))
 	if err != nil {
 		log.Error(ctx, map[string]interface{}{
 			"kc_resource_id": kcResourceID,
@@ -447,7 +447,7 @@ func DeletePolicy(ctx context.Context, clientsEndpoint string, clientID string,
 		return errors.NewInternalError(ctx, errs.Wrap(err, "unable to create http request"))
 	}
 	req.Header.Add("Authorization", "Bearer "+protectionAPIToken)
-	res, err := http.DefaultClient.Do(req)
+	res, err := http.DefaultClient.Do(req.WithContext(ctx))
 	if err != nil {
 		log.Error(ctx, map[string]interface{}{
 			"policy_id": policyID,
@@ -487,7 +487,7 @@ func DeletePermission(ctx context.Context, clientsEndpoint string, clientID stri
 		return errors.NewInternalError(ctx, errs.Wrap(err, "unable to create http request"))
 	}
 	req.Header.Add("Authorization", "Bearer "+protectionAPIToken)
-	res, err := http.DefaultClient.Do(req)
+	res, err := http.DefaultClient.Do(req.WithContext(ctx))
 	if err != nil {
 		log.Error(ctx, map[string]interface{}{
 			"permission_id": permissionID,
@@ -527,7 +527,7 @@ func GetPolicy(ctx context.Context, clientsEndpoint string, clientID string, pol
 		return nil, errors.NewInternalError(ctx, errs.Wrap(err, "unable to create http request"))
 	}
 	req.Header.Add("Authorization", "Bearer "+protectionAPIToken)
-	res, err := http.DefaultClient.Do(req)
+	res, err := http.DefaultClient.Do(req.WithContext(ctx))
 	if err != nil {
 		log.Error(ctx, map[string]interface{}{
 			"client_id": clientID,
@@ -595,7 +595,7 @@ func UpdatePolicy(ctx context.Context, clientsEndpoint string, clientID string,
 	}
 	req.Header.Add("Content-Type", "application/json")
 	req.Header.Add("Authorization", "Bearer "+protectionAPIToken)
-	res, err := http.DefaultClient.Do(req)
+	res, err := http.DefaultClient.Do(req.WithContext(ctx))
 	if err != nil {
 		log.Error(ctx, map[string]interface{}{
 			"client_id": clientID,
@@ -648,7 +648,7 @@ func GetEntitlement(ctx context.Context, entitlementEndpoint string, entitlement
 	}
 
 	req.Header.Add("Authorization", "Bearer "+userAccesToken)
-	res, err := http.DefaultClient.Do(req)
+	res, err := http.DefaultClient.Do(req.WithContext(ctx))
 	if err != nil {
 		log.Error(ctx, map[string]interface{}{
 			"entitlement_resource": entitlementResource,
@@ -695,7 +695,7 @@ func GetUserInfo(ctx context.Context, userInfoEndpoint string, userAccessToken s
 		return nil, errors.NewInternalError(ctx, errs.Wrap(err, "unable to create http request"))
 	}
 	req.Header.Add("Authorization", "Bearer "+userAccessToken)
-	res, err := http.DefaultClient.Do(req)
+	res, err := http.DefaultClient.Do(req.WithContext(ctx))
 	if err != nil {
 		log.Error(ctx, map[string]interface{}{
 			"err": err.Error(),
@@ -731,7 +731,7 @@ func ValidateKeycloakUser(ctx context.Context, adminEndpoint string, userID, pro
 		return false, errors.NewInternalError(ctx, errs.Wrap(err, "unable to create http request"))
 	}
 	req.Header.Add("Authorization", "Bearer "+protectionAPIToken)
-	res, err := http.DefaultClient.Do(req)
+	res, err := http.DefaultClient.Do(req.WithContext(ctx))
 	if err != nil {
 		log.Error(ctx, map[string]interface{}{
 			"user_id": userID,
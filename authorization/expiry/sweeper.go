@@ -0,0 +1,204 @@
+// Package expiry removes the expired role assignments and space collaborators. The removals are
+// recorded as audit events. The expired space collaborators are removed from the Keycloak policies
+// of the spaces by the outbox dispatcher, like the members of the teams collaborating on the spaces whose
+// roles expired.
+package expiry
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/fabric8-services/fabric8-auth/application"
+	"github.com/fabric8-services/fabric8-auth/audit"
+	"github.com/fabric8-services/fabric8-auth/authorization/organization"
+	"github.com/fabric8-services/fabric8-auth/authorization/role"
+	"github.com/fabric8-services/fabric8-auth/errors"
+	"github.com/fabric8-services/fabric8-auth/log"
+	"github.com/fabric8-services/fabric8-auth/outbox"
+	"github.com/fabric8-services/fabric8-auth/webhook"
+
+	"github.com/jinzhu/gorm"
+	uuid "github.com/satori/go.uuid"
+)
+
+// Configuration represents the expiry sweeper configuration
+type Configuration interface {
+	GetRoleExpirySweepInterval() time.Duration
+	GetRoleExpirySweepBatchSize() int
+}
+
+// Sweeper removes the expired role assignments and space collaborators
+type Sweeper struct {
+	db     application.DB
+	config Configuration
+}
+
+// New creates a sweeper which removes the expired role assignments and space collaborators stored in the given DB
+func New(db application.DB, config Configuration) *Sweeper {
+	return &Sweeper{db: db, config: config}
+}
+
+// Start sweeps the expired role assignments and space collaborators in the background at the configured
+// interval until the returned function is called.
+func (s *Sweeper) Start(ctx context.Context) func() {
+	ticker := time.NewTicker(s.config.GetRoleExpirySweepInterval())
+	done := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case <-ticker.C:
+				if _, err := s.Sweep(ctx); err != nil {
+					log.Error(ctx, map[string]interface{}{
+						"err": err,
+					}, "failed to sweep the expired role assignments")
+				}
+			case <-done:
+				ticker.Stop()
+				return
+			}
+		}
+	}()
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			close(done)
+		})
+	}
+}
+
+// Sweep removes a batch of expired role assignments and a batch of expired space collaborators and
+// returns the number of removals. The expired entries are locked until they are removed, so the replicas
+// of the service never remove the same entry concurrently. The expired role of the last admin of a resource is
+// kept without time limit instead.
+func (s *Sweeper) Sweep(ctx context.Context) (int, error) {
+	removed := 0
+	err := application.Transactional(s.db, func(appl application.Application) error {
+		roles, err := s.sweepRoles(ctx, appl)
+		removed += roles
+		if err != nil {
+			return err
+		}
+		collaborators, err := s.sweepCollaborators(ctx, appl)
+		removed += collaborators
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+	return removed, nil
+}
+
+// sweepRoles removes the expired role assignments
+func (s *Sweeper) sweepRoles(ctx context.Context, appl application.Application) (int, error) {
+	identityRoles, err := appl.IdentityRoleRepository().Query(role.IdentityRoleFilterExpired(), s.lockBatch)
+	if err != nil {
+		return 0, errors.NewInternalError(ctx, err)
+	}
+	removed := 0
+	for _, identityRole := range identityRoles {
+		r, err := appl.RoleRepository().Load(ctx, identityRole.RoleID)
+		if err != nil {
+			return 0, errors.NewInternalError(ctx, err)
+		}
+		resourceType, err := appl.ResourceTypeRepository().Load(ctx, r.ResourceTypeID)
+		if err != nil {
+			return 0, errors.NewInternalError(ctx, err)
+		}
+		if r.Name == organization.RoleAdmin {
+			kept, err := keepLastAdmin(ctx, appl, identityRole)
+			if err != nil {
+				return 0, err
+			}
+			if kept {
+				continue
+			}
+		}
+		if err := appl.IdentityRoleRepository().Delete(ctx, identityRole.IdentityRoleID); err != nil {
+			return 0, errors.NewInternalError(ctx, err)
+		}
+		identityID := identityRole.IdentityID
+		if resourceType.Name == organization.ResourceTypeTeam {
+			// the member is removed from the space policies unless it has another role in the team
+			if err := organization.EnqueueTeamMembers(ctx, appl, identityRole.ResourceID, []uuid.UUID{identityID}, false); err != nil {
+				return 0, err
+			}
+		}
+		event, err := audit.NewEvent(ctx, audit.EventTypeRoleExpired, nil, &identityID, resourceType.Name, identityRole.ResourceID, map[string]interface{}{
+			"role":       r.Name,
+			"expired_at": identityRole.ExpiresAt,
+		})
+		if err != nil {
+			return 0, errors.NewInternalError(ctx, err)
+		}
+		if err := appl.AuditEvents().Create(ctx, event); err != nil {
+			return 0, err
+		}
+		removed++
+	}
+	return removed, nil
+}
+
+// keepLastAdmin removes the time limit of the given expired admin role instead of removing it if no other admin
+// of the resource is left, so a resource never loses all its admins. Returns true if the role is kept.
+func keepLastAdmin(ctx context.Context, appl application.Application, identityRole role.IdentityRole) (bool, error) {
+	admins, err := appl.IdentityRoleRepository().Query(
+		role.IdentityRoleFilterByResourceID(identityRole.ResourceID),
+		role.IdentityRoleFilterByRoleID(identityRole.RoleID),
+		role.IdentityRoleFilterNotExpired())
+	if err != nil {
+		return false, errors.NewInternalError(ctx, err)
+	}
+	if len(admins) > 0 {
+		return false, nil
+	}
+	if err := appl.IdentityRoleRepository().SetExpiry(ctx, identityRole.IdentityRoleID, nil); err != nil {
+		return false, errors.NewInternalError(ctx, err)
+	}
+	log.Warn(ctx, map[string]interface{}{
+		"identity_id": identityRole.IdentityID,
+		"resource_id": identityRole.ResourceID,
+	}, "the role of the last admin expired and is kept without time limit")
+	return true, nil
+}
+
+// sweepCollaborators schedules the removal of the expired space collaborators from the space policies
+func (s *Sweeper) sweepCollaborators(ctx context.Context, appl application.Application) (int, error) {
+	expiries, err := appl.SpaceCollaboratorExpiries().LockExpired(ctx, s.config.GetRoleExpirySweepBatchSize())
+	if err != nil {
+		return 0, err
+	}
+	for _, expiry := range expiries {
+		removal, err := outbox.NewCollaboratorRemovedEvent(expiry.IdentityID, expiry.SpaceID, expiry.AuthURL)
+		if err != nil {
+			return 0, errors.NewInternalError(ctx, err)
+		}
+		if err := appl.OutboxEvents().Create(ctx, removal); err != nil {
+			return 0, err
+		}
+		if err := appl.SpaceCollaboratorExpiries().Delete(ctx, expiry.SpaceID, expiry.IdentityID); err != nil {
+			return 0, err
+		}
+		identityID := expiry.IdentityID
+		event, err := audit.NewEvent(ctx, audit.EventTypeCollaboratorExpired, nil, &identityID, audit.ResourceTypeSpace, expiry.SpaceID.String(), map[string]interface{}{
+			"expired_at": expiry.ExpiresAt,
+		})
+		if err != nil {
+			return 0, errors.NewInternalError(ctx, err)
+		}
+		if err := appl.AuditEvents().Create(ctx, event); err != nil {
+			return 0, err
+		}
+		spaceID := expiry.SpaceID
+		if err := webhook.Emit(ctx, appl, webhook.EventTypeCollaboratorRemoved, webhook.Data{IdentityID: identityID, SpaceID: &spaceID}); err != nil {
+			return 0, err
+		}
+	}
+	return len(expiries), nil
+}
+
+// lockBatch limits the query to a batch of rows which are locked until the end of the transaction.
+// The rows locked by a concurrent transaction are skipped.
+func (s *Sweeper) lockBatch(db *gorm.DB) *gorm.DB {
+	return db.Set("gorm:query_option", "FOR UPDATE SKIP LOCKED").Limit(s.config.GetRoleExpirySweepBatchSize())
+}
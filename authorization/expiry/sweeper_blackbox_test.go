@@ -0,0 +1,186 @@
+package expiry_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/fabric8-services/fabric8-auth/account"
+	"github.com/fabric8-services/fabric8-auth/audit"
+	"github.com/fabric8-services/fabric8-auth/authorization/expiry"
+	"github.com/fabric8-services/fabric8-auth/authorization/organization"
+	"github.com/fabric8-services/fabric8-auth/authorization/role"
+	"github.com/fabric8-services/fabric8-auth/gormtestsupport"
+	"github.com/fabric8-services/fabric8-auth/outbox"
+	"github.com/fabric8-services/fabric8-auth/resource"
+	"github.com/fabric8-services/fabric8-auth/space"
+	"github.com/fabric8-services/fabric8-auth/test"
+
+	"github.com/satori/go.uuid"
+	"github.com/stretchr/testify/suite"
+)
+
+type sweeperBlackBoxTest struct {
+	gormtestsupport.DBTestSuite
+	sweeper *expiry.Sweeper
+	admin   account.Identity
+	member  account.Identity
+}
+
+func TestRunSweeperBlackBoxTest(t *testing.T) {
+	resource.Require(t, resource.Database)
+	suite.Run(t, &sweeperBlackBoxTest{DBTestSuite: gormtestsupport.NewDBTestSuite()})
+}
+
+func (s *sweeperBlackBoxTest) SetupTest() {
+	s.DBTestSuite.SetupTest()
+	s.sweeper = expiry.New(s.Application, sweeperConfig{})
+	var err error
+	s.admin, err = test.CreateTestIdentity(s.DB, "sweeper-admin-"+uuid.NewV4().String(), account.KeycloakIDP)
+	s.Require().NoError(err)
+	s.member, err = test.CreateTestIdentity(s.DB, "sweeper-member-"+uuid.NewV4().String(), account.KeycloakIDP)
+	s.Require().NoError(err)
+}
+
+func (s *sweeperBlackBoxTest) TestSweepExpiredRoles() {
+	// given an organization with a member whose role expired
+	service := organization.NewService(s.Application)
+	org, err := service.Create(s.Ctx, organization.ResourceTypeOrganization, s.admin.ID, "Acme "+uuid.NewV4().String(), "", nil)
+	s.Require().NoError(err)
+	expiresAt := time.Now().Add(time.Hour)
+	_, err = service.AddMember(s.Ctx, organization.ResourceTypeOrganization, s.admin.ID, org.ResourceID, s.member.ID, organization.RoleMember, &expiresAt)
+	s.Require().NoError(err)
+	identityRoles, err := s.Application.IdentityRoleRepository().Query(role.IdentityRoleFilterByResourceID(org.ResourceID), role.IdentityRoleFilterByIdentityID(s.member.ID))
+	s.Require().NoError(err)
+	s.Require().Len(identityRoles, 1)
+	expiredAt := time.Now().Add(-time.Minute)
+	s.Require().NoError(s.Application.IdentityRoleRepository().SetExpiry(s.Ctx, identityRoles[0].IdentityRoleID, &expiredAt))
+
+	// when
+	removed, err := s.sweeper.Sweep(s.Ctx)
+
+	// then
+	s.Require().NoError(err)
+	s.True(removed > 0)
+	identityRoles, err = s.Application.IdentityRoleRepository().Query(role.IdentityRoleFilterByResourceID(org.ResourceID))
+	s.Require().NoError(err)
+	s.Require().Len(identityRoles, 2)
+	for _, identityRole := range identityRoles {
+		s.Equal(s.admin.ID, identityRole.IdentityID)
+	}
+	events, err := s.Application.AuditEvents().Query(audit.EventFilterByResource(organization.ResourceTypeOrganization, org.ResourceID))
+	s.Require().NoError(err)
+	s.Require().Len(events, 1)
+	s.Equal(audit.EventTypeRoleExpired, events[0].EventType)
+	s.Nil(events[0].ActorID)
+	s.Equal(s.member.ID, *events[0].TargetID)
+}
+
+func (s *sweeperBlackBoxTest) TestSweepKeepsLastAdmin() {
+	// given an organization with two admins, one of them time-bound
+	service := organization.NewService(s.Application)
+	org, err := service.Create(s.Ctx, organization.ResourceTypeOrganization, s.admin.ID, "Acme "+uuid.NewV4().String(), "", nil)
+	s.Require().NoError(err)
+	_, err = service.AddMember(s.Ctx, organization.ResourceTypeOrganization, s.admin.ID, org.ResourceID, s.member.ID, organization.RoleAdmin, nil)
+	s.Require().NoError(err)
+	expiresAt := time.Now().Add(time.Hour)
+	_, err = service.AddMember(s.Ctx, organization.ResourceTypeOrganization, s.admin.ID, org.ResourceID, s.admin.ID, organization.RoleAdmin, &expiresAt)
+	s.Require().NoError(err)
+	// and the admin without time limit left
+	_, err = service.RemoveMember(s.Ctx, organization.ResourceTypeOrganization, s.member.ID, org.ResourceID, s.member.ID)
+	s.Require().NoError(err)
+	identityRoles, err := s.Application.IdentityRoleRepository().Query(role.IdentityRoleFilterByResourceID(org.ResourceID), role.IdentityRoleFilterByIdentityID(s.admin.ID))
+	s.Require().NoError(err)
+	expiredAt := time.Now().Add(-time.Minute)
+	for _, identityRole := range identityRoles {
+		s.Require().NoError(s.Application.IdentityRoleRepository().SetExpiry(s.Ctx, identityRole.IdentityRoleID, &expiredAt))
+	}
+
+	// when the roles of the last admin expire
+	_, err = s.sweeper.Sweep(s.Ctx)
+
+	// then the admin role is kept without time limit
+	s.Require().NoError(err)
+	loaded, err := service.Load(s.Ctx, organization.ResourceTypeOrganization, org.ResourceID)
+	s.Require().NoError(err)
+	s.Equal([]uuid.UUID{s.admin.ID}, loaded.Admins)
+	identityRoles, err = s.Application.IdentityRoleRepository().Query(role.IdentityRoleFilterByResourceID(org.ResourceID), role.IdentityRoleFilterByIdentityID(s.admin.ID))
+	s.Require().NoError(err)
+	s.Require().Len(identityRoles, 1)
+	s.Nil(identityRoles[0].ExpiresAt)
+}
+
+func (s *sweeperBlackBoxTest) TestSweepExpiredCollaborators() {
+	// given
+	expiredSpaceID := uuid.NewV4()
+	activeSpaceID := uuid.NewV4()
+	s.Require().NoError(s.Application.SpaceCollaboratorExpiries().Set(s.Ctx, &space.CollaboratorExpiry{SpaceID: expiredSpaceID, IdentityID: s.member.ID, ExpiresAt: time.Now().Add(-time.Minute), AuthURL: "https://auth.openshift.io"}))
+	s.Require().NoError(s.Application.SpaceCollaboratorExpiries().Set(s.Ctx, &space.CollaboratorExpiry{SpaceID: activeSpaceID, IdentityID: s.member.ID, ExpiresAt: time.Now().Add(time.Hour), AuthURL: "https://auth.openshift.io"}))
+
+	// when
+	_, err := s.sweeper.Sweep(s.Ctx)
+
+	// then the expired collaborator is removed from the space policy by the outbox dispatcher
+	s.Require().NoError(err)
+	removals, err := s.Application.OutboxEvents().Query(s.Ctx, outbox.EventFilterByIdentityID(s.member.ID), outbox.EventFilterByTarget(outbox.TargetKeycloak))
+	s.Require().NoError(err)
+	s.Require().Len(removals, 1)
+	s.Equal(outbox.EventTypeCollaboratorRemoved, removals[0].EventType)
+	var payload outbox.CollaboratorPayload
+	s.Require().NoError(removals[0].DecodePayload(&payload))
+	s.Equal(expiredSpaceID, payload.SpaceID)
+	s.Equal("https://auth.openshift.io", payload.AuthURL)
+	// and the expiry is removed
+	_, err = s.Application.SpaceCollaboratorExpiries().Load(s.Ctx, expiredSpaceID, s.member.ID)
+	s.Error(err)
+	_, err = s.Application.SpaceCollaboratorExpiries().Load(s.Ctx, activeSpaceID, s.member.ID)
+	s.NoError(err)
+	events, err := s.Application.AuditEvents().Query(audit.EventFilterByResource(audit.ResourceTypeSpace, expiredSpaceID.String()))
+	s.Require().NoError(err)
+	s.Require().Len(events, 1)
+	s.Equal(audit.EventTypeCollaboratorExpired, events[0].EventType)
+	s.Equal(s.member.ID, *events[0].TargetID)
+}
+
+func (s *sweeperBlackBoxTest) TestSweepExpiredTeamMembers() {
+	// given a team collaborating on a space with a member whose role expired
+	service := organization.NewService(s.Application)
+	org, err := service.Create(s.Ctx, organization.ResourceTypeOrganization, s.admin.ID, "Acme "+uuid.NewV4().String(), "", nil)
+	s.Require().NoError(err)
+	team, err := service.Create(s.Ctx, organization.ResourceTypeTeam, s.admin.ID, "Developers", "", &org.ResourceID)
+	s.Require().NoError(err)
+	spaceID := uuid.NewV4()
+	s.Require().NoError(s.Application.SpaceTeams().Add(s.Ctx, spaceID, team.ResourceID, "https://auth.openshift.io"))
+	expiresAt := time.Now().Add(time.Hour)
+	_, err = service.AddMember(s.Ctx, organization.ResourceTypeTeam, s.admin.ID, team.ResourceID, s.member.ID, organization.RoleMember, &expiresAt)
+	s.Require().NoError(err)
+	identityRoles, err := s.Application.IdentityRoleRepository().Query(role.IdentityRoleFilterByResourceID(team.ResourceID), role.IdentityRoleFilterByIdentityID(s.member.ID))
+	s.Require().NoError(err)
+	s.Require().Len(identityRoles, 1)
+	expiredAt := time.Now().Add(-time.Minute)
+	s.Require().NoError(s.Application.IdentityRoleRepository().SetExpiry(s.Ctx, identityRoles[0].IdentityRoleID, &expiredAt))
+
+	// when
+	_, err = s.sweeper.Sweep(s.Ctx)
+
+	// then the member is removed from the space policy by the outbox dispatcher
+	s.Require().NoError(err)
+	events, err := s.Application.OutboxEvents().Query(s.Ctx, outbox.EventFilterByIdentityID(s.member.ID), outbox.EventFilterByTarget(outbox.TargetKeycloak))
+	s.Require().NoError(err)
+	s.Require().Len(events, 2)
+	s.Equal(outbox.EventTypeTeamMemberAdded, events[0].EventType)
+	s.Equal(outbox.EventTypeTeamMemberRemoved, events[1].EventType)
+	var payload outbox.CollaboratorPayload
+	s.Require().NoError(events[1].DecodePayload(&payload))
+	s.Equal(spaceID, payload.SpaceID)
+	s.Equal("https://auth.openshift.io", payload.AuthURL)
+}
+
+type sweeperConfig struct{}
+
+func (c sweeperConfig) GetRoleExpirySweepInterval() time.Duration {
+	return time.Second
+}
+
+func (c sweeperConfig) GetRoleExpirySweepBatchSize() int {
+	return 100
+}
@@ -0,0 +1,6 @@
+// Package organization manages the organizations and their teams. They are stored as resources
+// of the well-known "organization" and "team" resource types, a team having its organization as
+// parent resource. The admins and members are stored as identity roles. A team can be added to
+// the collaborators of a space, in which case its members are resolved when the access to the
+// space is authorized.
+package organization
@@ -0,0 +1,573 @@
+package organization
+
+import (
+	"context"
+	"time"
+
+	"github.com/fabric8-services/fabric8-auth/application"
+	"github.com/fabric8-services/fabric8-auth/audit"
+	"github.com/fabric8-services/fabric8-auth/authorization/resource"
+	"github.com/fabric8-services/fabric8-auth/authorization/role"
+	"github.com/fabric8-services/fabric8-auth/errors"
+	"github.com/fabric8-services/fabric8-auth/log"
+	"github.com/fabric8-services/fabric8-auth/outbox"
+	"github.com/fabric8-services/fabric8-auth/space"
+
+	"github.com/jinzhu/gorm"
+	uuid "github.com/satori/go.uuid"
+)
+
+const (
+	// ResourceTypeOrganization is the name of the well-known resource type of the organizations
+	ResourceTypeOrganization = "organization"
+	// ResourceTypeTeam is the name of the well-known resource type of the teams
+	ResourceTypeTeam = "team"
+
+	// RoleAdmin is the role of the identities which manage an organization or a team
+	RoleAdmin = "admin"
+	// RoleMember is the role of the identities which belong to an organization or a team
+	RoleMember = "member"
+)
+
+// Group is an organization or a team, with the identities of its admins and members.
+// The admins are members too.
+type Group struct {
+	resource.Resource
+	Admins  []uuid.UUID
+	Members []uuid.UUID
+}
+
+// Service manages the organizations and their teams
+type Service struct {
+	db application.DB
+}
+
+// NewService creates a service which manages the organizations and teams stored in the given DB
+func NewService(db application.DB) *Service {
+	return &Service{db: db}
+}
+
+// Create creates an organization or a team of the given type, with the creator as admin.
+// A team belongs to the given organization, which the creator must be an admin of.
+func (s *Service) Create(ctx context.Context, resourceType string, creatorID uuid.UUID, name string, description string, organizationID *string) (*Group, error) {
+	if name == "" {
+		return nil, errors.NewBadParameterError("name", name).Expected("not empty")
+	}
+	var group *Group
+	err := application.Transactional(s.db, func(appl application.Application) error {
+		var parentID *string
+		if resourceType == ResourceTypeTeam {
+			if organizationID == nil {
+				return errors.NewBadParameterError("organizationID", nil).Expected("the ID of the organization of the team")
+			}
+			org, err := loadGroup(ctx, appl, ResourceTypeOrganization, *organizationID)
+			if err != nil {
+				return err
+			}
+			if err := checkAdmin(ctx, appl, ResourceTypeOrganization, org, creatorID); err != nil {
+				return err
+			}
+			parentID = &org.ResourceID
+		}
+		resType, err := appl.ResourceTypeRepository().LookupOrCreate(ctx, resourceType)
+		if err != nil {
+			return errors.NewInternalError(ctx, err)
+		}
+		res := &resource.Resource{
+			ResourceID:       uuid.NewV4().String(),
+			ParentResourceID: parentID,
+			OwnerID:          creatorID,
+			ResourceTypeID:   resType.ResourceTypeID,
+			Name:             name,
+			Description:      description,
+		}
+		if err := appl.ResourceRepository().Create(ctx, res); err != nil {
+			return errors.NewInternalError(ctx, err)
+		}
+		if err := addRoles(ctx, appl, resourceType, res.ResourceID, creatorID, RoleAdmin, nil); err != nil {
+			return err
+		}
+		group, err = loadGroup(ctx, appl, resourceType, res.ResourceID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	log.Info(ctx, map[string]interface{}{
+		"resource_id":   group.ResourceID,
+		"resource_type": resourceType,
+		"identity_id":   creatorID,
+	}, "%s created", resourceType)
+	return group, nil
+}
+
+// Load returns the organization or the team with the given ID
+func (s *Service) Load(ctx context.Context, resourceType string, id string) (*Group, error) {
+	var group *Group
+	err := application.Transactional(s.db, func(appl application.Application) error {
+		var err error
+		group, err = loadGroup(ctx, appl, resourceType, id)
+		return err
+	})
+	return group, err
+}
+
+// List returns the organizations the given identity is a member of, ordered by name
+func (s *Service) List(ctx context.Context, identityID uuid.UUID) ([]Group, error) {
+	var groups []Group
+	err := application.Transactional(s.db, func(appl application.Application) error {
+		identityRoles, err := appl.IdentityRoleRepository().Query(role.IdentityRoleFilterByIdentityID(identityID), role.IdentityRoleFilterNotExpired())
+		if err != nil {
+			return errors.NewInternalError(ctx, err)
+		}
+		resourceIDs := make([]string, len(identityRoles))
+		for i, identityRole := range identityRoles {
+			resourceIDs[i] = identityRole.ResourceID
+		}
+		if len(resourceIDs) == 0 {
+			groups = []Group{}
+			return nil
+		}
+		groups, err = queryGroups(ctx, appl, ResourceTypeOrganization, resource.ResourceFilterByIDs(resourceIDs))
+		return err
+	})
+	return groups, err
+}
+
+// ListTeams returns the teams of the given organization, ordered by name
+func (s *Service) ListTeams(ctx context.Context, organizationID string) ([]Group, error) {
+	var groups []Group
+	err := application.Transactional(s.db, func(appl application.Application) error {
+		if _, err := loadGroup(ctx, appl, ResourceTypeOrganization, organizationID); err != nil {
+			return err
+		}
+		var err error
+		groups, err = queryGroups(ctx, appl, ResourceTypeTeam, resource.ResourceFilterByParentID(organizationID))
+		return err
+	})
+	return groups, err
+}
+
+// Update updates the name and/or the description of the given organization or team.
+// Only the admins can update it.
+func (s *Service) Update(ctx context.Context, resourceType string, actorID uuid.UUID, id string, name *string, description *string) (*Group, error) {
+	if name != nil && *name == "" {
+		return nil, errors.NewBadParameterError("name", *name).Expected("not empty")
+	}
+	var group *Group
+	err := application.Transactional(s.db, func(appl application.Application) error {
+		var err error
+		group, err = loadGroup(ctx, appl, resourceType, id)
+		if err != nil {
+			return err
+		}
+		if err := checkAdmin(ctx, appl, resourceType, group, actorID); err != nil {
+			return err
+		}
+		if name != nil {
+			group.Name = *name
+		}
+		if description != nil {
+			group.Description = *description
+		}
+		if err := appl.ResourceRepository().Save(ctx, &group.Resource); err != nil {
+			return errors.NewInternalError(ctx, err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return group, nil
+}
+
+// Delete deletes the given organization or team, along with the roles of its members.
+// The teams of a deleted organization are deleted too, and their members are removed from the Keycloak
+// policies of the spaces they collaborate on. Only the admins can delete it.
+func (s *Service) Delete(ctx context.Context, resourceType string, actorID uuid.UUID, id string) error {
+	err := application.Transactional(s.db, func(appl application.Application) error {
+		group, err := loadGroup(ctx, appl, resourceType, id)
+		if err != nil {
+			return err
+		}
+		if err := checkAdmin(ctx, appl, resourceType, group, actorID); err != nil {
+			return err
+		}
+		if resourceType == ResourceTypeOrganization {
+			teams, err := appl.ResourceRepository().Query(resource.ResourceFilterByParentID(id), resource.ResourceFilterByTypeName(ResourceTypeTeam))
+			if err != nil {
+				return errors.NewInternalError(ctx, err)
+			}
+			for _, team := range teams {
+				if err := deleteGroup(ctx, appl, ResourceTypeTeam, team.ResourceID); err != nil {
+					return err
+				}
+			}
+		}
+		return deleteGroup(ctx, appl, resourceType, id)
+	})
+	if err != nil {
+		return err
+	}
+	log.Info(ctx, map[string]interface{}{
+		"resource_id":   id,
+		"resource_type": resourceType,
+		"identity_id":   actorID,
+	}, "%s deleted", resourceType)
+	return nil
+}
+
+// AddMember gives the given role to the given identity in the given organization or team, until the given
+// date if not nil. The expiry of the roles the identity already has is replaced. Admins are members too,
+// and an admin given the member role is not an admin anymore, unless it's the last admin. The roles of the
+// last admin can't be given an expiry. The members of a team are added to the Keycloak policies of the spaces
+// the team collaborates on through the outbox. Only the admins can add members.
+func (s *Service) AddMember(ctx context.Context, resourceType string, actorID uuid.UUID, id string, identityID uuid.UUID, roleName string, expiresAt *time.Time) (*Group, error) {
+	if roleName != RoleAdmin && roleName != RoleMember {
+		return nil, errors.NewBadParameterError("role", roleName).Expected(RoleAdmin + " or " + RoleMember)
+	}
+	if expiresAt != nil && !expiresAt.After(time.Now()) {
+		return nil, errors.NewBadParameterError("expiresAt", *expiresAt).Expected("a date in the future")
+	}
+	var group *Group
+	err := application.Transactional(s.db, func(appl application.Application) error {
+		current, err := loadGroup(ctx, appl, resourceType, id)
+		if err != nil {
+			return err
+		}
+		if err := checkAdmin(ctx, appl, resourceType, current, actorID); err != nil {
+			return err
+		}
+		if _, err := appl.Identities().Load(ctx, identityID); err != nil {
+			return err
+		}
+		if roleName != RoleAdmin && current.isLastAdmin(identityID) {
+			return errors.NewBadParameterError("identityID", identityID.String()).Expected("not the last admin")
+		}
+		// the roles of the last admin never expire, so the group always keeps an admin
+		if expiresAt != nil && current.isLastAdmin(identityID) {
+			return errors.NewBadParameterError("expiresAt", *expiresAt).Expected("no expiry for the last admin")
+		}
+		if err := addRoles(ctx, appl, resourceType, id, identityID, roleName, expiresAt); err != nil {
+			return err
+		}
+		if resourceType == ResourceTypeTeam {
+			if err := EnqueueTeamMembers(ctx, appl, id, []uuid.UUID{identityID}, true); err != nil {
+				return err
+			}
+		}
+		group, err = loadGroup(ctx, appl, resourceType, id)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return group, nil
+}
+
+// ExtendMember sets the expiry of the time-bound roles of the given identity in the given organization or team
+// to the given date. The roles without time limit are left unchanged. The roles of the last admin can't be
+// time-bound, so they can't be extended either. Only the admins can extend the roles.
+func (s *Service) ExtendMember(ctx context.Context, resourceType string, actorID uuid.UUID, id string, identityID uuid.UUID, expiresAt time.Time) (*Group, error) {
+	if !expiresAt.After(time.Now()) {
+		return nil, errors.NewBadParameterError("expiresAt", expiresAt).Expected("a date in the future")
+	}
+	var group *Group
+	err := application.Transactional(s.db, func(appl application.Application) error {
+		current, err := loadGroup(ctx, appl, resourceType, id)
+		if err != nil {
+			return err
+		}
+		if err := checkAdmin(ctx, appl, resourceType, current, actorID); err != nil {
+			return err
+		}
+		if current.isLastAdmin(identityID) {
+			return errors.NewBadParameterError("identityID", identityID.String()).Expected("not the last admin")
+		}
+		identityRoles, err := appl.IdentityRoleRepository().Query(
+			role.IdentityRoleFilterByResourceID(id),
+			role.IdentityRoleFilterByIdentityID(identityID),
+			role.IdentityRoleFilterNotExpired())
+		if err != nil {
+			return errors.NewInternalError(ctx, err)
+		}
+		extended := 0
+		for _, identityRole := range identityRoles {
+			if identityRole.ExpiresAt == nil {
+				continue
+			}
+			if err := appl.IdentityRoleRepository().SetExpiry(ctx, identityRole.IdentityRoleID, &expiresAt); err != nil {
+				return errors.NewInternalError(ctx, err)
+			}
+			extended++
+		}
+		if extended == 0 {
+			return errors.NewNotFoundError("time-bound member", identityID.String())
+		}
+		event, err := audit.NewEvent(ctx, audit.EventTypeRoleExtended, &actorID, &identityID, resourceType, id, map[string]interface{}{
+			"expires_at": expiresAt,
+		})
+		if err != nil {
+			return errors.NewInternalError(ctx, err)
+		}
+		if err := appl.AuditEvents().Create(ctx, event); err != nil {
+			return err
+		}
+		group, err = loadGroup(ctx, appl, resourceType, id)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return group, nil
+}
+
+// RemoveMember removes all the roles of the given identity in the given organization or team.
+// Only the admins can remove the other members. The last admin can't be removed. The members of a team
+// are removed from the Keycloak policies of the spaces the team collaborates on through the outbox.
+func (s *Service) RemoveMember(ctx context.Context, resourceType string, actorID uuid.UUID, id string, identityID uuid.UUID) (*Group, error) {
+	var group *Group
+	err := application.Transactional(s.db, func(appl application.Application) error {
+		current, err := loadGroup(ctx, appl, resourceType, id)
+		if err != nil {
+			return err
+		}
+		if !uuid.Equal(actorID, identityID) {
+			if err := checkAdmin(ctx, appl, resourceType, current, actorID); err != nil {
+				return err
+			}
+		}
+		if !contains(current.Members, identityID) && !contains(current.Admins, identityID) {
+			return errors.NewNotFoundError("member", identityID.String())
+		}
+		if current.isLastAdmin(identityID) {
+			return errors.NewBadParameterError("identityID", identityID.String()).Expected("not the last admin")
+		}
+		identityRoles, err := appl.IdentityRoleRepository().Query(role.IdentityRoleFilterByResourceID(id), role.IdentityRoleFilterByIdentityID(identityID))
+		if err != nil {
+			return errors.NewInternalError(ctx, err)
+		}
+		for _, identityRole := range identityRoles {
+			if err := appl.IdentityRoleRepository().Delete(ctx, identityRole.IdentityRoleID); err != nil {
+				return errors.NewInternalError(ctx, err)
+			}
+		}
+		if resourceType == ResourceTypeTeam {
+			if err := EnqueueTeamMembers(ctx, appl, id, []uuid.UUID{identityID}, false); err != nil {
+				return err
+			}
+		}
+		group, err = loadGroup(ctx, appl, resourceType, id)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return group, nil
+}
+
+// IsAdmin returns true if the given identity is an admin of the given group.
+// The admins of an organization are admins of all its teams.
+func IsAdmin(ctx context.Context, appl application.Application, resourceType string, group *Group, identityID uuid.UUID) (bool, error) {
+	if contains(group.Admins, identityID) {
+		return true, nil
+	}
+	if resourceType != ResourceTypeTeam || group.ParentResourceID == nil {
+		return false, nil
+	}
+	org, err := loadGroup(ctx, appl, ResourceTypeOrganization, *group.ParentResourceID)
+	if err != nil {
+		return false, err
+	}
+	return contains(org.Admins, identityID), nil
+}
+
+func checkAdmin(ctx context.Context, appl application.Application, resourceType string, group *Group, identityID uuid.UUID) error {
+	admin, err := IsAdmin(ctx, appl, resourceType, group, identityID)
+	if err != nil {
+		return err
+	}
+	if !admin {
+		log.Warn(ctx, map[string]interface{}{
+			"resource_id":   group.ResourceID,
+			"resource_type": resourceType,
+			"identity_id":   identityID,
+		}, "identity is not an admin")
+		return errors.NewForbiddenError("user is not an admin of the " + resourceType)
+	}
+	return nil
+}
+
+// loadGroup loads the resource of the given type and the identities of its admins and members
+func loadGroup(ctx context.Context, appl application.Application, resourceType string, id string) (*Group, error) {
+	if _, err := uuid.FromString(id); err != nil {
+		return nil, errors.NewNotFoundError(resourceType, id)
+	}
+	groups, err := queryGroups(ctx, appl, resourceType, resource.ResourceFilterByIDs([]string{id}))
+	if err != nil {
+		return nil, err
+	}
+	if len(groups) == 0 {
+		return nil, errors.NewNotFoundError(resourceType, id)
+	}
+	return &groups[0], nil
+}
+
+func queryGroups(ctx context.Context, appl application.Application, resourceType string, filter func(*gorm.DB) *gorm.DB) ([]Group, error) {
+	resources, err := appl.ResourceRepository().Query(filter, resource.ResourceFilterByTypeName(resourceType), orderByName)
+	if err != nil {
+		return nil, errors.NewInternalError(ctx, err)
+	}
+	admin, err := appl.RoleRepository().Lookup(ctx, RoleAdmin, resourceType)
+	if err != nil {
+		return nil, errors.NewInternalError(ctx, err)
+	}
+	member, err := appl.RoleRepository().Lookup(ctx, RoleMember, resourceType)
+	if err != nil {
+		return nil, errors.NewInternalError(ctx, err)
+	}
+	groups := make([]Group, len(resources))
+	if len(resources) == 0 {
+		return groups, nil
+	}
+	resourceIDs := make([]string, len(resources))
+	byResourceID := make(map[string]*Group, len(resources))
+	for i, res := range resources {
+		groups[i] = Group{Resource: res, Admins: []uuid.UUID{}, Members: []uuid.UUID{}}
+		resourceIDs[i] = res.ResourceID
+		byResourceID[res.ResourceID] = &groups[i]
+	}
+	// the roles of all the groups are loaded at once
+	identityRoles, err := appl.IdentityRoleRepository().Query(role.IdentityRoleFilterByResourceIDs(resourceIDs), role.IdentityRoleFilterNotExpired())
+	if err != nil {
+		return nil, errors.NewInternalError(ctx, err)
+	}
+	for _, identityRole := range identityRoles {
+		group := byResourceID[identityRole.ResourceID]
+		switch identityRole.RoleID {
+		case admin.RoleID:
+			group.Admins = append(group.Admins, identityRole.IdentityID)
+		case member.RoleID:
+			group.Members = append(group.Members, identityRole.IdentityID)
+		}
+	}
+	return groups, nil
+}
+
+// addRoles gives the given role to the identity until the given date if not nil, along with the member role for an admin.
+// The admin role is removed from an admin given the member role.
+func addRoles(ctx context.Context, appl application.Application, resourceType string, resourceID string, identityID uuid.UUID, roleName string, expiresAt *time.Time) error {
+	for _, name := range []string{RoleMember, RoleAdmin} {
+		r, err := appl.RoleRepository().Lookup(ctx, name, resourceType)
+		if err != nil {
+			return errors.NewInternalError(ctx, err)
+		}
+		existing, err := appl.IdentityRoleRepository().Query(
+			role.IdentityRoleFilterByResourceID(resourceID),
+			role.IdentityRoleFilterByIdentityID(identityID),
+			role.IdentityRoleFilterByRoleID(r.RoleID))
+		if err != nil {
+			return errors.NewInternalError(ctx, err)
+		}
+		if name == RoleAdmin && roleName != RoleAdmin {
+			for _, identityRole := range existing {
+				if err := appl.IdentityRoleRepository().Delete(ctx, identityRole.IdentityRoleID); err != nil {
+					return errors.NewInternalError(ctx, err)
+				}
+			}
+			continue
+		}
+		if len(existing) > 0 {
+			if err := appl.IdentityRoleRepository().SetExpiry(ctx, existing[0].IdentityRoleID, expiresAt); err != nil {
+				return errors.NewInternalError(ctx, err)
+			}
+			continue
+		}
+		err = appl.IdentityRoleRepository().Create(ctx, &role.IdentityRole{
+			IdentityID: identityID,
+			ResourceID: resourceID,
+			RoleID:     r.RoleID,
+			ExpiresAt:  expiresAt,
+		})
+		if err != nil {
+			return errors.NewInternalError(ctx, err)
+		}
+	}
+	return nil
+}
+
+func deleteGroup(ctx context.Context, appl application.Application, resourceType string, id string) error {
+	identityRoles, err := appl.IdentityRoleRepository().Query(role.IdentityRoleFilterByResourceID(id))
+	if err != nil {
+		return errors.NewInternalError(ctx, err)
+	}
+	var identityIDs []uuid.UUID
+	for _, identityRole := range identityRoles {
+		if err := appl.IdentityRoleRepository().Delete(ctx, identityRole.IdentityRoleID); err != nil {
+			return errors.NewInternalError(ctx, err)
+		}
+		if !contains(identityIDs, identityRole.IdentityID) {
+			identityIDs = append(identityIDs, identityRole.IdentityID)
+		}
+	}
+	if resourceType == ResourceTypeTeam {
+		// the members are removed from the space policies before the team stops collaborating on the spaces
+		if err := EnqueueTeamMembers(ctx, appl, id, identityIDs, false); err != nil {
+			return err
+		}
+		if err := appl.SpaceTeams().RemoveTeam(ctx, id); err != nil {
+			return err
+		}
+	}
+	return appl.ResourceRepository().Delete(ctx, id)
+}
+
+// EnqueueTeamMembers enqueues the outbox events which add the given members of the given team to or remove them
+// from the Keycloak policies of the spaces the team collaborates on. Their membership is checked when the events
+// are delivered, so the events must be enqueued along with the change of the members, in the same transaction.
+func EnqueueTeamMembers(ctx context.Context, appl application.Application, teamID string, identityIDs []uuid.UUID, added bool) error {
+	spaceTeams, err := appl.SpaceTeams().ListSpaces(ctx, teamID)
+	if err != nil {
+		return err
+	}
+	return EnqueueSpaceTeamMembers(ctx, appl, spaceTeams, identityIDs, added)
+}
+
+// EnqueueSpaceTeamMembers enqueues the outbox events which add the given members of the teams to or remove them
+// from the Keycloak policies of the given spaces, with the auth URL recorded when the teams were added to the spaces
+func EnqueueSpaceTeamMembers(ctx context.Context, appl application.Application, spaceTeams []space.Team, identityIDs []uuid.UUID, added bool) error {
+	newEvent := outbox.NewTeamMemberRemovedEvent
+	if added {
+		newEvent = outbox.NewTeamMemberAddedEvent
+	}
+	for _, spaceTeam := range spaceTeams {
+		for _, identityID := range identityIDs {
+			event, err := newEvent(identityID, spaceTeam.SpaceID, spaceTeam.AuthURL)
+			if err != nil {
+				return errors.NewInternalError(ctx, err)
+			}
+			if err := appl.OutboxEvents().Create(ctx, event); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func orderByName(db *gorm.DB) *gorm.DB {
+	return db.Order("name")
+}
+
+// isLastAdmin returns true if the given identity is the only admin of the group
+func (g *Group) isLastAdmin(identityID uuid.UUID) bool {
+	return len(g.Admins) == 1 && uuid.Equal(g.Admins[0], identityID)
+}
+
+func contains(ids []uuid.UUID, id uuid.UUID) bool {
+	for _, i := range ids {
+		if uuid.Equal(i, id) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,364 @@
+package organization_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/fabric8-services/fabric8-auth/account"
+	"github.com/fabric8-services/fabric8-auth/audit"
+	"github.com/fabric8-services/fabric8-auth/authorization/organization"
+	"github.com/fabric8-services/fabric8-auth/authorization/role"
+	"github.com/fabric8-services/fabric8-auth/errors"
+	"github.com/fabric8-services/fabric8-auth/gormtestsupport"
+	"github.com/fabric8-services/fabric8-auth/outbox"
+	"github.com/fabric8-services/fabric8-auth/resource"
+	"github.com/fabric8-services/fabric8-auth/test"
+
+	errs "github.com/pkg/errors"
+	"github.com/satori/go.uuid"
+	"github.com/stretchr/testify/suite"
+)
+
+type organizationBlackBoxTest struct {
+	gormtestsupport.DBTestSuite
+	service *organization.Service
+	admin   account.Identity
+	member  account.Identity
+}
+
+func TestRunOrganizationBlackBoxTest(t *testing.T) {
+	resource.Require(t, resource.Database)
+	suite.Run(t, &organizationBlackBoxTest{DBTestSuite: gormtestsupport.NewDBTestSuite()})
+}
+
+func (s *organizationBlackBoxTest) SetupTest() {
+	s.DBTestSuite.SetupTest()
+	s.service = organization.NewService(s.Application)
+	var err error
+	s.admin, err = test.CreateTestIdentity(s.DB, "organization-admin-"+uuid.NewV4().String(), account.KeycloakIDP)
+	s.Require().NoError(err)
+	s.member, err = test.CreateTestIdentity(s.DB, "organization-member-"+uuid.NewV4().String(), account.KeycloakIDP)
+	s.Require().NoError(err)
+}
+
+func (s *organizationBlackBoxTest) createOrganization() *organization.Group {
+	org, err := s.service.Create(s.Ctx, organization.ResourceTypeOrganization, s.admin.ID, "Acme "+uuid.NewV4().String(), "Acme Corp.", nil)
+	s.Require().NoError(err)
+	return org
+}
+
+func (s *organizationBlackBoxTest) TestCreateOrganization() {
+	// when
+	org := s.createOrganization()
+
+	// then
+	s.Equal([]uuid.UUID{s.admin.ID}, org.Admins)
+	s.Equal([]uuid.UUID{s.admin.ID}, org.Members)
+	s.Equal("Acme Corp.", org.Description)
+	s.Nil(org.ParentResourceID)
+	loaded, err := s.service.Load(s.Ctx, organization.ResourceTypeOrganization, org.ResourceID)
+	s.Require().NoError(err)
+	s.Equal(org.Name, loaded.Name)
+}
+
+func (s *organizationBlackBoxTest) TestCreateOrganizationWithoutName() {
+	_, err := s.service.Create(s.Ctx, organization.ResourceTypeOrganization, s.admin.ID, "", "", nil)
+	s.IsType(errors.BadParameterError{}, errs.Cause(err))
+}
+
+func (s *organizationBlackBoxTest) TestLoadWrongType() {
+	org := s.createOrganization()
+
+	_, err := s.service.Load(s.Ctx, organization.ResourceTypeTeam, org.ResourceID)
+	s.IsType(errors.NotFoundError{}, errs.Cause(err))
+	_, err = s.service.Load(s.Ctx, organization.ResourceTypeOrganization, "not-a-uuid")
+	s.IsType(errors.NotFoundError{}, errs.Cause(err))
+}
+
+func (s *organizationBlackBoxTest) TestCreateTeam() {
+	// given
+	org := s.createOrganization()
+
+	// when
+	team, err := s.service.Create(s.Ctx, organization.ResourceTypeTeam, s.admin.ID, "Developers", "", &org.ResourceID)
+
+	// then
+	s.Require().NoError(err)
+	s.Require().NotNil(team.ParentResourceID)
+	s.Equal(org.ResourceID, *team.ParentResourceID)
+	teams, err := s.service.ListTeams(s.Ctx, org.ResourceID)
+	s.Require().NoError(err)
+	s.Require().Len(teams, 1)
+	s.Equal(team.ResourceID, teams[0].ResourceID)
+}
+
+func (s *organizationBlackBoxTest) TestCreateTeamForbiddenForNonAdmin() {
+	org := s.createOrganization()
+
+	_, err := s.service.Create(s.Ctx, organization.ResourceTypeTeam, s.member.ID, "Developers", "", &org.ResourceID)
+	s.IsType(errors.ForbiddenError{}, errs.Cause(err))
+}
+
+func (s *organizationBlackBoxTest) TestAddAndRemoveMember() {
+	// given
+	org := s.createOrganization()
+
+	// when
+	updated, err := s.service.AddMember(s.Ctx, organization.ResourceTypeOrganization, s.admin.ID, org.ResourceID, s.member.ID, organization.RoleMember, nil)
+
+	// then
+	s.Require().NoError(err)
+	s.Equal([]uuid.UUID{s.admin.ID}, updated.Admins)
+	s.Len(updated.Members, 2)
+	orgs, err := s.service.List(s.Ctx, s.member.ID)
+	s.Require().NoError(err)
+	s.Require().Len(orgs, 1)
+	s.Equal(org.ResourceID, orgs[0].ResourceID)
+
+	// and adding the member again is a no-op
+	updated, err = s.service.AddMember(s.Ctx, organization.ResourceTypeOrganization, s.admin.ID, org.ResourceID, s.member.ID, organization.RoleMember, nil)
+	s.Require().NoError(err)
+	s.Len(updated.Members, 2)
+
+	// when the member is removed
+	updated, err = s.service.RemoveMember(s.Ctx, organization.ResourceTypeOrganization, s.admin.ID, org.ResourceID, s.member.ID)
+
+	// then
+	s.Require().NoError(err)
+	s.Equal([]uuid.UUID{s.admin.ID}, updated.Members)
+	orgs, err = s.service.List(s.Ctx, s.member.ID)
+	s.Require().NoError(err)
+	s.Empty(orgs)
+}
+
+func (s *organizationBlackBoxTest) TestTimeBoundMember() {
+	// given
+	org := s.createOrganization()
+	expiresAt := time.Now().Add(time.Hour)
+
+	// when
+	updated, err := s.service.AddMember(s.Ctx, organization.ResourceTypeOrganization, s.admin.ID, org.ResourceID, s.member.ID, organization.RoleMember, &expiresAt)
+
+	// then
+	s.Require().NoError(err)
+	s.Len(updated.Members, 2)
+
+	// when the role is extended
+	extendedAt := time.Now().Add(24 * time.Hour)
+	_, err = s.service.ExtendMember(s.Ctx, organization.ResourceTypeOrganization, s.admin.ID, org.ResourceID, s.member.ID, extendedAt)
+
+	// then
+	s.Require().NoError(err)
+	identityRoles, err := s.Application.IdentityRoleRepository().Query(role.IdentityRoleFilterByResourceID(org.ResourceID), role.IdentityRoleFilterByIdentityID(s.member.ID))
+	s.Require().NoError(err)
+	s.Require().Len(identityRoles, 1)
+	s.Require().NotNil(identityRoles[0].ExpiresAt)
+	s.WithinDuration(extendedAt, *identityRoles[0].ExpiresAt, time.Second)
+	events, err := s.Application.AuditEvents().Query(audit.EventFilterByResource(organization.ResourceTypeOrganization, org.ResourceID))
+	s.Require().NoError(err)
+	s.Require().Len(events, 1)
+	s.Equal(audit.EventTypeRoleExtended, events[0].EventType)
+	s.Equal(s.admin.ID, *events[0].ActorID)
+	s.Equal(s.member.ID, *events[0].TargetID)
+
+	// when the role expires
+	expiredAt := time.Now().Add(-time.Minute)
+	err = s.Application.IdentityRoleRepository().SetExpiry(s.Ctx, identityRoles[0].IdentityRoleID, &expiredAt)
+	s.Require().NoError(err)
+
+	// then the expired member is ignored
+	loaded, err := s.service.Load(s.Ctx, organization.ResourceTypeOrganization, org.ResourceID)
+	s.Require().NoError(err)
+	s.Equal([]uuid.UUID{s.admin.ID}, loaded.Members)
+	orgs, err := s.service.List(s.Ctx, s.member.ID)
+	s.Require().NoError(err)
+	s.Empty(orgs)
+	_, err = s.service.ExtendMember(s.Ctx, organization.ResourceTypeOrganization, s.admin.ID, org.ResourceID, s.member.ID, extendedAt)
+	s.IsType(errors.NotFoundError{}, errs.Cause(err))
+}
+
+func (s *organizationBlackBoxTest) TestTimeBoundMemberBadRequest() {
+	org := s.createOrganization()
+
+	// a role can't be given until a date in the past
+	expiresAt := time.Now().Add(-time.Hour)
+	_, err := s.service.AddMember(s.Ctx, organization.ResourceTypeOrganization, s.admin.ID, org.ResourceID, s.member.ID, organization.RoleMember, &expiresAt)
+	s.IsType(errors.BadParameterError{}, errs.Cause(err))
+	// the roles without time limit can't be extended
+	_, err = s.service.AddMember(s.Ctx, organization.ResourceTypeOrganization, s.admin.ID, org.ResourceID, s.member.ID, organization.RoleMember, nil)
+	s.Require().NoError(err)
+	_, err = s.service.ExtendMember(s.Ctx, organization.ResourceTypeOrganization, s.admin.ID, org.ResourceID, s.member.ID, time.Now().Add(time.Hour))
+	s.IsType(errors.NotFoundError{}, errs.Cause(err))
+}
+
+func (s *organizationBlackBoxTest) TestLastAdminNotTimeBound() {
+	org := s.createOrganization()
+	expiresAt := time.Now().Add(time.Hour)
+
+	// the roles of the last admin can't expire
+	_, err := s.service.AddMember(s.Ctx, organization.ResourceTypeOrganization, s.admin.ID, org.ResourceID, s.admin.ID, organization.RoleAdmin, &expiresAt)
+	s.IsType(errors.BadParameterError{}, errs.Cause(err))
+	_, err = s.service.ExtendMember(s.Ctx, organization.ResourceTypeOrganization, s.admin.ID, org.ResourceID, s.admin.ID, expiresAt)
+	s.IsType(errors.BadParameterError{}, errs.Cause(err))
+
+	// but an admin can be time-bound when there is another admin
+	_, err = s.service.AddMember(s.Ctx, organization.ResourceTypeOrganization, s.admin.ID, org.ResourceID, s.member.ID, organization.RoleAdmin, nil)
+	s.Require().NoError(err)
+	_, err = s.service.AddMember(s.Ctx, organization.ResourceTypeOrganization, s.admin.ID, org.ResourceID, s.admin.ID, organization.RoleAdmin, &expiresAt)
+	s.Require().NoError(err)
+}
+
+func (s *organizationBlackBoxTest) TestAddMemberForbiddenForNonAdmin() {
+	org := s.createOrganization()
+
+	_, err := s.service.AddMember(s.Ctx, organization.ResourceTypeOrganization, s.member.ID, org.ResourceID, s.member.ID, organization.RoleAdmin, nil)
+	s.IsType(errors.ForbiddenError{}, errs.Cause(err))
+}
+
+func (s *organizationBlackBoxTest) TestRemoveLastAdmin() {
+	org := s.createOrganization()
+
+	_, err := s.service.RemoveMember(s.Ctx, organization.ResourceTypeOrganization, s.admin.ID, org.ResourceID, s.admin.ID)
+	s.IsType(errors.BadParameterError{}, errs.Cause(err))
+}
+
+func (s *organizationBlackBoxTest) TestDemoteAdmin() {
+	// given an organization with two admins
+	org := s.createOrganization()
+	_, err := s.service.AddMember(s.Ctx, organization.ResourceTypeOrganization, s.admin.ID, org.ResourceID, s.member.ID, organization.RoleAdmin, nil)
+	s.Require().NoError(err)
+
+	// when one of them is given the member role
+	updated, err := s.service.AddMember(s.Ctx, organization.ResourceTypeOrganization, s.admin.ID, org.ResourceID, s.member.ID, organization.RoleMember, nil)
+
+	// then it's not an admin anymore
+	s.Require().NoError(err)
+	s.Equal([]uuid.UUID{s.admin.ID}, updated.Admins)
+	s.Len(updated.Members, 2)
+	_, err = s.service.Update(s.Ctx, organization.ResourceTypeOrganization, s.member.ID, org.ResourceID, nil, &org.Description)
+	s.IsType(errors.ForbiddenError{}, errs.Cause(err))
+
+	// and the last admin can't be demoted
+	_, err = s.service.AddMember(s.Ctx, organization.ResourceTypeOrganization, s.admin.ID, org.ResourceID, s.admin.ID, organization.RoleMember, nil)
+	s.IsType(errors.BadParameterError{}, errs.Cause(err))
+}
+
+func (s *organizationBlackBoxTest) TestListTeamsWithMembers() {
+	// given two teams with different members
+	org := s.createOrganization()
+	developers, err := s.service.Create(s.Ctx, organization.ResourceTypeTeam, s.admin.ID, "Developers", "", &org.ResourceID)
+	s.Require().NoError(err)
+	_, err = s.service.Create(s.Ctx, organization.ResourceTypeTeam, s.admin.ID, "Testers", "", &org.ResourceID)
+	s.Require().NoError(err)
+	_, err = s.service.AddMember(s.Ctx, organization.ResourceTypeTeam, s.admin.ID, developers.ResourceID, s.member.ID, organization.RoleMember, nil)
+	s.Require().NoError(err)
+
+	// when
+	teams, err := s.service.ListTeams(s.Ctx, org.ResourceID)
+
+	// then the roles are matched with their teams
+	s.Require().NoError(err)
+	s.Require().Len(teams, 2)
+	s.Equal("Developers", teams[0].Name)
+	s.Len(teams[0].Members, 2)
+	s.Equal([]uuid.UUID{s.admin.ID}, teams[0].Admins)
+	s.Equal("Testers", teams[1].Name)
+	s.Equal([]uuid.UUID{s.admin.ID}, teams[1].Members)
+	s.Equal([]uuid.UUID{s.admin.ID}, teams[1].Admins)
+}
+
+func (s *organizationBlackBoxTest) TestOrganizationAdminManagesTeams() {
+	// given a team created by a team admin who is not an admin of the organization
+	org := s.createOrganization()
+	_, err := s.service.AddMember(s.Ctx, organization.ResourceTypeOrganization, s.admin.ID, org.ResourceID, s.member.ID, organization.RoleAdmin, nil)
+	s.Require().NoError(err)
+	team, err := s.service.Create(s.Ctx, organization.ResourceTypeTeam, s.member.ID, "Developers", "", &org.ResourceID)
+	s.Require().NoError(err)
+	s.Equal([]uuid.UUID{s.member.ID}, team.Admins)
+
+	// when
+	updated, err := s.service.Update(s.Ctx, organization.ResourceTypeTeam, s.admin.ID, team.ResourceID, nil, &org.Description)
+
+	// then
+	s.Require().NoError(err)
+	s.Equal(org.Description, updated.Description)
+	s.Equal("Developers", updated.Name)
+}
+
+func (s *organizationBlackBoxTest) TestDeleteOrganization() {
+	// given
+	org := s.createOrganization()
+	team, err := s.service.Create(s.Ctx, organization.ResourceTypeTeam, s.admin.ID, "Developers", "", &org.ResourceID)
+	s.Require().NoError(err)
+	spaceID := uuid.NewV4()
+	s.Require().NoError(s.Application.SpaceTeams().Add(s.Ctx, spaceID, team.ResourceID, "https://auth.openshift.io"))
+
+	// when
+	err = s.service.Delete(s.Ctx, organization.ResourceTypeOrganization, s.admin.ID, org.ResourceID)
+
+	// then
+	s.Require().NoError(err)
+	s.checkTeamMemberEvents(s.admin.ID, spaceID, outbox.EventTypeTeamMemberRemoved)
+	_, err = s.service.Load(s.Ctx, organization.ResourceTypeOrganization, org.ResourceID)
+	s.IsType(errors.NotFoundError{}, errs.Cause(err))
+	_, err = s.service.Load(s.Ctx, organization.ResourceTypeTeam, team.ResourceID)
+	s.IsType(errors.NotFoundError{}, errs.Cause(err))
+	teams, err := s.Application.SpaceTeams().List(s.Ctx, spaceID)
+	s.Require().NoError(err)
+	s.Empty(teams)
+	orgs, err := s.service.List(s.Ctx, s.admin.ID)
+	s.Require().NoError(err)
+	s.Empty(orgs)
+}
+
+func (s *organizationBlackBoxTest) TestTeamMembersPropagatedToSpaces() {
+	// given a team collaborating on a space
+	org := s.createOrganization()
+	team, err := s.service.Create(s.Ctx, organization.ResourceTypeTeam, s.admin.ID, "Developers", "", &org.ResourceID)
+	s.Require().NoError(err)
+	spaceID := uuid.NewV4()
+	s.Require().NoError(s.Application.SpaceTeams().Add(s.Ctx, spaceID, team.ResourceID, "https://auth.openshift.io"))
+
+	// when
+	_, err = s.service.AddMember(s.Ctx, organization.ResourceTypeTeam, s.admin.ID, team.ResourceID, s.member.ID, organization.RoleMember, nil)
+
+	// then
+	s.Require().NoError(err)
+	s.checkTeamMemberEvents(s.member.ID, spaceID, outbox.EventTypeTeamMemberAdded)
+
+	// when
+	_, err = s.service.RemoveMember(s.Ctx, organization.ResourceTypeTeam, s.admin.ID, team.ResourceID, s.member.ID)
+
+	// then
+	s.Require().NoError(err)
+	s.checkTeamMemberEvents(s.member.ID, spaceID, outbox.EventTypeTeamMemberAdded, outbox.EventTypeTeamMemberRemoved)
+}
+
+func (s *organizationBlackBoxTest) TestOrganizationMembersNotPropagated() {
+	// given
+	org := s.createOrganization()
+
+	// when
+	_, err := s.service.AddMember(s.Ctx, organization.ResourceTypeOrganization, s.admin.ID, org.ResourceID, s.member.ID, organization.RoleMember, nil)
+
+	// then
+	s.Require().NoError(err)
+	events, err := s.Application.OutboxEvents().Query(s.Ctx, outbox.EventFilterByIdentityID(s.member.ID))
+	s.Require().NoError(err)
+	s.Empty(events)
+}
+
+// checkTeamMemberEvents checks that the outbox events of the given identity have the given types, in order,
+// and update the Keycloak policy of the given space
+func (s *organizationBlackBoxTest) checkTeamMemberEvents(identityID uuid.UUID, spaceID uuid.UUID, eventTypes ...string) {
+	events, err := s.Application.OutboxEvents().Query(s.Ctx, outbox.EventFilterByIdentityID(identityID), outbox.EventFilterByTarget(outbox.TargetKeycloak))
+	s.Require().NoError(err)
+	s.Require().Len(events, len(eventTypes))
+	for i, event := range events {
+		s.Equal(eventTypes[i], event.EventType)
+		var payload outbox.CollaboratorPayload
+		s.Require().NoError(event.DecodePayload(&payload))
+		s.Equal(spaceID, payload.SpaceID)
+		s.Equal("https://auth.openshift.io", payload.AuthURL)
+	}
+}
@@ -2,17 +2,19 @@ package resource
 
 import (
 	"context"
+	"strconv"
 	"time"
 
 	"github.com/fabric8-services/fabric8-auth/account"
 	"github.com/fabric8-services/fabric8-auth/errors"
 	"github.com/fabric8-services/fabric8-auth/gormsupport"
 	"github.com/fabric8-services/fabric8-auth/log"
+	"github.com/fabric8-services/fabric8-auth/tracing"
+	"github.com/goadesign/goa"
 	"github.com/jinzhu/gorm"
 
 	"fmt"
 	"github.com/fabric8-services/fabric8-auth/application/repository"
-	"github.com/goadesign/goa"
 	errs "github.com/pkg/errors"
 	"github.com/satori/go.uuid"
 )
@@ -23,7 +25,9 @@ type Resource struct {
 	// This is the primary key value
 	ResourceID string `sql:"type:string" gorm:"primary_key" gorm:"column:resource_id"`
 	// The parent resource
-	ParentResource *Resource
+	ParentResource *Resource `gorm:"ForeignKey:ParentResourceID;AssociationForeignKey:ResourceID"`
+	// The identifier of the parent resource
+	ParentResourceID *string
 	// The owning identity
 	Owner account.Identity
 	// The identifier for the owning identity
@@ -32,6 +36,8 @@ type Resource struct {
 	ResourceType ResourceType
 	// The identifier for the resource type
 	ResourceTypeID uuid.UUID
+	// Resource name
+	Name string
 	// Resource description
 	Description string
 }
@@ -42,6 +48,13 @@ func (m Resource) TableName() string {
 	return "resource"
 }
 
+// GetETagData returns the field values to use to generate the ETag
+func (m Resource) GetETagData() []interface{} {
+	// using the 'ResourceID' and 'UpdatedAt' (converted to number of microseconds since epoch, the precision of the database) fields,
+	// so the ETag changes on every update, even within the same second
+	return []interface{}{m.ResourceID, strconv.FormatInt(m.UpdatedAt.UnixNano()/int64(time.Microsecond), 10)}
+}
+
 // GetLastModified returns the last modification time
 func (m Resource) GetLastModified() time.Time {
 	return m.UpdatedAt
@@ -62,9 +75,11 @@ func NewResourceRepository(db *gorm.DB) ResourceRepository {
 type ResourceRepository interface {
 	repository.Exister
 	Load(ctx context.Context, id string) (*Resource, error)
+	Lock(ctx context.Context, id string) (*Resource, error)
 	Create(ctx context.Context, resource *Resource) error
 	Save(ctx context.Context, resource *Resource) error
 	Delete(ctx context.Context, id string) error
+	Query(funcs ...func(*gorm.DB) *gorm.DB) ([]Resource, error)
 }
 
 // TableName overrides the table name settings in Gorm to force a specific table name
@@ -77,7 +92,7 @@ func (m *GormResourceRepository) TableName() string {
 
 // Load returns a single Resource as a Database Model
 func (m *GormResourceRepository) Load(ctx context.Context, id string) (*Resource, error) {
-	defer goa.MeasureSince([]string{"goa", "db", "resource", "load"}, time.Now())
+	defer tracing.MeasureSince(ctx, []string{"goa", "db", "resource", "load"}, time.Now())
 
 	var native Resource
 	err := m.db.Table(m.TableName()).Where("resource_id = ?", id).Find(&native).Error
@@ -88,9 +103,23 @@ func (m *GormResourceRepository) Load(ctx context.Context, id string) (*Resource
 	return &native, errs.WithStack(err)
 }
 
+// Lock loads the resource with the given ID and locks it until the end of the transaction,
+// so it can't be updated concurrently, e.g. between a conditional write check and the update
+func (m *GormResourceRepository) Lock(ctx context.Context, id string) (*Resource, error) {
+	defer tracing.MeasureSince(ctx, []string{"goa", "db", "resource", "lock"}, time.Now())
+
+	var native Resource
+	err := m.db.Table(m.TableName()).Set("gorm:query_option", "FOR UPDATE").Where("resource_id = ?", id).Find(&native).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, errs.WithStack(errors.NewNotFoundError("resource", id))
+	}
+
+	return &native, errs.WithStack(err)
+}
+
 // CheckExists returns nil if the given ID exists otherwise returns an error
 func (m *GormResourceRepository) CheckExists(ctx context.Context, id string) error {
-	defer goa.MeasureSince([]string{"goa", "db", "resource", "exists"}, time.Now())
+	defer tracing.MeasureSince(ctx, []string{"goa", "db", "resource", "exists"}, time.Now())
 
 	var exists bool
 	query := fmt.Sprintf(`
@@ -113,14 +142,14 @@ func (m *GormResourceRepository) CheckExists(ctx context.Context, id string) err
 
 // Create creates a new record.
 func (m *GormResourceRepository) Create(ctx context.Context, resource *Resource) error {
-	defer goa.MeasureSince([]string{"goa", "db", "resource", "create"}, time.Now())
+	defer tracing.MeasureSince(ctx, []string{"goa", "db", "resource", "create"}, time.Now())
 
 	// If no identifier has been specified for the new resource, then generate one
 	if resource.ResourceID == "" {
 		resource.ResourceID = uuid.NewV4().String()
 	}
 
-	if resource.ResourceTypeID.String() == "" {
+	if resource.ResourceTypeID == uuid.Nil {
 		resourceType, err := m.resourceTypeRepo.LookupOrCreate(ctx, resource.ResourceType.Name)
 		if err != nil {
 			log.Error(ctx, map[string]interface{}{
@@ -148,7 +177,7 @@ func (m *GormResourceRepository) Create(ctx context.Context, resource *Resource)
 
 // Save modifies a single record.
 func (m *GormResourceRepository) Save(ctx context.Context, resource *Resource) error {
-	defer goa.MeasureSince([]string{"goa", "db", "resource", "save"}, time.Now())
+	defer tracing.MeasureSince(ctx, []string{"goa", "db", "resource", "save"}, time.Now())
 
 	obj, err := m.Load(ctx, resource.ResourceID)
 	if err != nil {
@@ -169,7 +198,7 @@ func (m *GormResourceRepository) Save(ctx context.Context, resource *Resource) e
 
 // Delete removes a single record.
 func (m *GormResourceRepository) Delete(ctx context.Context, id string) error {
-	defer goa.MeasureSince([]string{"goa", "db", "resource", "delete"}, time.Now())
+	defer tracing.MeasureSince(ctx, []string{"goa", "db", "resource", "delete"}, time.Now())
 
 	obj := Resource{ResourceID: id}
 	db := m.db.Delete(obj)
@@ -191,3 +220,35 @@ func (m *GormResourceRepository) Delete(ctx context.Context, id string) error {
 
 	return nil
 }
+
+// Query expose an open ended Query model
+func (m *GormResourceRepository) Query(funcs ...func(*gorm.DB) *gorm.DB) ([]Resource, error) {
+	defer goa.MeasureSince([]string{"goa", "db", "resource", "query"}, time.Now())
+	var objs []Resource
+	err := m.db.Scopes(funcs...).Table(m.TableName()).Find(&objs).Error
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return nil, errs.WithStack(err)
+	}
+	return objs, nil
+}
+
+// ResourceFilterByTypeName is a gorm filter for the name of the resource type
+func ResourceFilterByTypeName(name string) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where("resource_type_id IN (SELECT resource_type_id FROM resource_type WHERE name = ? AND deleted_at IS NULL)", name)
+	}
+}
+
+// ResourceFilterByParentID is a gorm filter for the parent resource ID
+func ResourceFilterByParentID(parentResourceID string) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where("parent_resource_id = ?", parentResourceID)
+	}
+}
+
+// ResourceFilterByIDs is a gorm filter for a set of resource IDs
+func ResourceFilterByIDs(resourceIDs []string) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where("resource_id IN (?)", resourceIDs)
+	}
+}
@@ -7,8 +7,8 @@ import (
 	"github.com/fabric8-services/fabric8-auth/errors"
 	"github.com/fabric8-services/fabric8-auth/gormsupport"
 	"github.com/fabric8-services/fabric8-auth/log"
+	"github.com/fabric8-services/fabric8-auth/tracing"
 
-	"github.com/goadesign/goa"
 	"github.com/jinzhu/gorm"
 	"github.com/satori/go.uuid"
 
@@ -69,7 +69,7 @@ func (m *GormResourceTypeRepository) TableName() string {
 // Load returns a single ResourceType as a Database Model
 // This is more for use internally, and probably not what you want in  your controllers
 func (m *GormResourceTypeRepository) Load(ctx context.Context, id uuid.UUID) (*ResourceType, error) {
-	defer goa.MeasureSince([]string{"goa", "db", "resource_type", "load"}, time.Now())
+	defer tracing.MeasureSince(ctx, []string{"goa", "db", "resource_type", "load"}, time.Now())
 	var native ResourceType
 	err := m.db.Table(m.TableName()).Where("resource_type_id = ?", id).Find(&native).Error
 	if err == gorm.ErrRecordNotFound {
@@ -81,7 +81,7 @@ func (m *GormResourceTypeRepository) Load(ctx context.Context, id uuid.UUID) (*R
 // LookupOrCreate looks up the ResourceType record with the specified name.  If there is no such record, then
 // a new ResourceType will be created with the specified name and returned.
 func (m *GormResourceTypeRepository) LookupOrCreate(ctx context.Context, name string) (*ResourceType, error) {
-	defer goa.MeasureSince([]string{"goa", "db", "resource_type", "lookupOrCreate"}, time.Now())
+	defer tracing.MeasureSince(ctx, []string{"goa", "db", "resource_type", "lookupOrCreate"}, time.Now())
 
 	var native ResourceType
 	err := m.db.Table(m.TableName()).Where("name = ?", name).First(&native).Error
@@ -113,7 +113,7 @@ func (m *GormResourceTypeRepository) LookupOrCreate(ctx context.Context, name st
 
 // CheckExists returns nil if the given ID exists otherwise returns an error
 func (m *GormResourceTypeRepository) CheckExists(ctx context.Context, id string) (bool, error) {
-	defer goa.MeasureSince([]string{"goa", "db", "resource_type", "exists"}, time.Now())
+	defer tracing.MeasureSince(ctx, []string{"goa", "db", "resource_type", "exists"}, time.Now())
 
 	//return repository.CheckExists(ctx, m.db, m.TableName(), id)
 
@@ -138,7 +138,7 @@ func (m *GormResourceTypeRepository) CheckExists(ctx context.Context, id string)
 
 // Create creates a new record.
 func (m *GormResourceTypeRepository) Create(ctx context.Context, u *ResourceType) error {
-	defer goa.MeasureSince([]string{"goa", "db", "resource_type", "create"}, time.Now())
+	defer tracing.MeasureSince(ctx, []string{"goa", "db", "resource_type", "create"}, time.Now())
 	if u.ResourceTypeID == uuid.Nil {
 		u.ResourceTypeID = uuid.NewV4()
 	}
@@ -158,7 +158,7 @@ func (m *GormResourceTypeRepository) Create(ctx context.Context, u *ResourceType
 
 // Save modifies a single record
 func (m *GormResourceTypeRepository) Save(ctx context.Context, model *ResourceType) error {
-	defer goa.MeasureSince([]string{"goa", "db", "resource_type", "save"}, time.Now())
+	defer tracing.MeasureSince(ctx, []string{"goa", "db", "resource_type", "save"}, time.Now())
 
 	obj, err := m.Load(ctx, model.ResourceTypeID)
 	if err != nil {
@@ -181,7 +181,7 @@ func (m *GormResourceTypeRepository) Save(ctx context.Context, model *ResourceTy
 
 // Delete removes a single record.
 func (m *GormResourceTypeRepository) Delete(ctx context.Context, id uuid.UUID) error {
-	defer goa.MeasureSince([]string{"goa", "db", "resource_type", "delete"}, time.Now())
+	defer tracing.MeasureSince(ctx, []string{"goa", "db", "resource_type", "delete"}, time.Now())
 
 	obj := ResourceType{ResourceTypeID: id}
 
@@ -204,7 +204,7 @@ func (m *GormResourceTypeRepository) Delete(ctx context.Context, id uuid.UUID) e
 
 // List return all resource types
 func (m *GormResourceTypeRepository) List(ctx context.Context) ([]ResourceType, error) {
-	defer goa.MeasureSince([]string{"goa", "db", "resource_type", "list"}, time.Now())
+	defer tracing.MeasureSince(ctx, []string{"goa", "db", "resource_type", "list"}, time.Now())
 	var rows []ResourceType
 
 	err := m.db.Model(&ResourceType{}).Order("name").Find(&rows).Error
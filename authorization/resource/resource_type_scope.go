@@ -7,8 +7,8 @@ import (
 	"github.com/fabric8-services/fabric8-auth/errors"
 	"github.com/fabric8-services/fabric8-auth/gormsupport"
 	"github.com/fabric8-services/fabric8-auth/log"
+	"github.com/fabric8-services/fabric8-auth/tracing"
 
-	"github.com/goadesign/goa"
 	"github.com/jinzhu/gorm"
 	"github.com/satori/go.uuid"
 
@@ -70,7 +70,7 @@ func (m *GormResourceTypeScopeRepository) TableName() string {
 
 // CheckExists returns nil if the given ID exists otherwise returns an error
 func (m *GormResourceTypeScopeRepository) CheckExists(ctx context.Context, id string) (bool, error) {
-	defer goa.MeasureSince([]string{"goa", "db", "resource_type_scope", "exists"}, time.Now())
+	defer tracing.MeasureSince(ctx, []string{"goa", "db", "resource_type_scope", "exists"}, time.Now())
 
 	var exists bool
 	query := fmt.Sprintf(`
@@ -96,7 +96,7 @@ func (m *GormResourceTypeScopeRepository) CheckExists(ctx context.Context, id st
 // Load returns a single ResourceTypeScope as a Database Model
 // This is more for use internally, and probably not what you want in  your controllers
 func (m *GormResourceTypeScopeRepository) Load(ctx context.Context, id uuid.UUID) (*ResourceTypeScope, error) {
-	defer goa.MeasureSince([]string{"goa", "db", "resource_type_scope", "load"}, time.Now())
+	defer tracing.MeasureSince(ctx, []string{"goa", "db", "resource_type_scope", "load"}, time.Now())
 	var native ResourceTypeScope
 	//err := m.db.Preload("ResourceType").Table(m.TableName()).Where("resource_type_scope_id = ?", id).Find(&native).Error
 	err := m.db.Table(m.TableName()).Preload("ResourceType").Where("resource_type_scope_id = ?", id).Find(&native).Error
@@ -108,7 +108,7 @@ func (m *GormResourceTypeScopeRepository) Load(ctx context.Context, id uuid.UUID
 
 // Create creates a new record.
 func (m *GormResourceTypeScopeRepository) Create(ctx context.Context, u *ResourceTypeScope) error {
-	defer goa.MeasureSince([]string{"goa", "db", "resource_type_scope", "create"}, time.Now())
+	defer tracing.MeasureSince(ctx, []string{"goa", "db", "resource_type_scope", "create"}, time.Now())
 	if u.ResourceTypeScopeID == uuid.Nil {
 		u.ResourceTypeScopeID = uuid.NewV4()
 	}
@@ -128,7 +128,7 @@ func (m *GormResourceTypeScopeRepository) Create(ctx context.Context, u *Resourc
 
 // Save modifies a single record
 func (m *GormResourceTypeScopeRepository) Save(ctx context.Context, model *ResourceTypeScope) error {
-	defer goa.MeasureSince([]string{"goa", "db", "resource_type_scope", "save"}, time.Now())
+	defer tracing.MeasureSince(ctx, []string{"goa", "db", "resource_type_scope", "save"}, time.Now())
 
 	obj, err := m.Load(ctx, model.ResourceTypeScopeID)
 	if err != nil {
@@ -151,7 +151,7 @@ func (m *GormResourceTypeScopeRepository) Save(ctx context.Context, model *Resou
 
 // Delete removes a single record.
 func (m *GormResourceTypeScopeRepository) Delete(ctx context.Context, id uuid.UUID) error {
-	defer goa.MeasureSince([]string{"goa", "db", "resource_type_scope", "delete"}, time.Now())
+	defer tracing.MeasureSince(ctx, []string{"goa", "db", "resource_type_scope", "delete"}, time.Now())
 
 	obj := ResourceTypeScope{ResourceTypeScopeID: id}
 
@@ -174,7 +174,7 @@ func (m *GormResourceTypeScopeRepository) Delete(ctx context.Context, id uuid.UU
 
 // List return all resource type scopes
 func (m *GormResourceTypeScopeRepository) List(ctx context.Context, resourceType *ResourceType) ([]ResourceTypeScope, error) {
-	defer goa.MeasureSince([]string{"goa", "db", "resource_type_scope", "list"}, time.Now())
+	defer tracing.MeasureSince(ctx, []string{"goa", "db", "resource_type_scope", "list"}, time.Now())
 	var rows []ResourceTypeScope
 
 	var err error
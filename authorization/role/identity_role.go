@@ -10,6 +10,7 @@ import (
 	"github.com/fabric8-services/fabric8-auth/errors"
 	"github.com/fabric8-services/fabric8-auth/gormsupport"
 	"github.com/fabric8-services/fabric8-auth/log"
+	"github.com/fabric8-services/fabric8-auth/tracing"
 
 	"github.com/goadesign/goa"
 	"github.com/jinzhu/gorm"
@@ -24,11 +25,19 @@ type IdentityRole struct {
 	// This is the primary key value
 	IdentityRoleID uuid.UUID `sql:"type:uuid default uuid_generate_v4()" gorm:"primary_key" gorm:"column:identity_role_id"`
 	// The identity to which the role is assigned
-	Identity account.Identity
+	Identity account.Identity `gorm:"ForeignKey:IdentityID;AssociationForeignKey:ID"`
+	// The identifier of the identity to which the role is assigned
+	IdentityID uuid.UUID
 	// The resource to which the role is applied
-	Resource resource.Resource
+	Resource resource.Resource `gorm:"ForeignKey:ResourceID;AssociationForeignKey:ResourceID"`
+	// The identifier of the resource to which the role is applied
+	ResourceID string
 	// The role that is assigned
-	Role Role
+	Role Role `gorm:"ForeignKey:RoleID;AssociationForeignKey:RoleID"`
+	// The identifier of the role that is assigned
+	RoleID uuid.UUID
+	// The date the role assignment expires at, or nil if the role is assigned without time limit
+	ExpiresAt *time.Time
 }
 
 // TableName overrides the table name settings in Gorm to force a specific table name
@@ -60,6 +69,8 @@ type IdentityRoleRepository interface {
 	Save(ctx context.Context, u *IdentityRole) error
 	List(ctx context.Context) ([]IdentityRole, error)
 	Delete(ctx context.Context, ID uuid.UUID) error
+	SetExpiry(ctx context.Context, ID uuid.UUID, expiresAt *time.Time) error
+	Query(funcs ...func(*gorm.DB) *gorm.DB) ([]IdentityRole, error)
 }
 
 // TableName overrides the table name settings in Gorm to force a specific table name
@@ -73,7 +84,7 @@ func (m *GormIdentityRoleRepository) TableName() string {
 // Load returns a single IdentityRole as a Database Model
 // This is more for use internally, and probably not what you want in  your controllers
 func (m *GormIdentityRoleRepository) Load(ctx context.Context, id uuid.UUID) (*IdentityRole, error) {
-	defer goa.MeasureSince([]string{"goa", "db", "identity_role", "load"}, time.Now())
+	defer tracing.MeasureSince(ctx, []string{"goa", "db", "identity_role", "load"}, time.Now())
 	var native IdentityRole
 	err := m.db.Table(m.TableName()).Where("identity_role_id = ?", id).Find(&native).Error
 	if err == gorm.ErrRecordNotFound {
@@ -84,13 +95,13 @@ func (m *GormIdentityRoleRepository) Load(ctx context.Context, id uuid.UUID) (*I
 
 // CheckExists returns nil if the given ID exists otherwise returns an error
 func (m *GormIdentityRoleRepository) CheckExists(ctx context.Context, id string) error {
-	defer goa.MeasureSince([]string{"goa", "db", "identity_role", "exists"}, time.Now())
+	defer tracing.MeasureSince(ctx, []string{"goa", "db", "identity_role", "exists"}, time.Now())
 	return repository.CheckExists(ctx, m.db, m.TableName(), id)
 }
 
 // Create creates a new record.
 func (m *GormIdentityRoleRepository) Create(ctx context.Context, u *IdentityRole) error {
-	defer goa.MeasureSince([]string{"goa", "db", "identity_role", "create"}, time.Now())
+	defer tracing.MeasureSince(ctx, []string{"goa", "db", "identity_role", "create"}, time.Now())
 	if u.IdentityRoleID == uuid.Nil {
 		u.IdentityRoleID = uuid.NewV4()
 	}
@@ -110,7 +121,7 @@ func (m *GormIdentityRoleRepository) Create(ctx context.Context, u *IdentityRole
 
 // Save modifies a single record
 func (m *GormIdentityRoleRepository) Save(ctx context.Context, model *IdentityRole) error {
-	defer goa.MeasureSince([]string{"goa", "db", "identity_role", "save"}, time.Now())
+	defer tracing.MeasureSince(ctx, []string{"goa", "db", "identity_role", "save"}, time.Now())
 
 	obj, err := m.Load(ctx, model.IdentityRoleID)
 	if err != nil {
@@ -133,7 +144,7 @@ func (m *GormIdentityRoleRepository) Save(ctx context.Context, model *IdentityRo
 
 // Delete removes a single record.
 func (m *GormIdentityRoleRepository) Delete(ctx context.Context, id uuid.UUID) error {
-	defer goa.MeasureSince([]string{"goa", "db", "identity_role", "delete"}, time.Now())
+	defer tracing.MeasureSince(ctx, []string{"goa", "db", "identity_role", "delete"}, time.Now())
 
 	obj := IdentityRole{IdentityRoleID: id}
 
@@ -154,9 +165,27 @@ func (m *GormIdentityRoleRepository) Delete(ctx context.Context, id uuid.UUID) e
 	return nil
 }
 
+// SetExpiry sets the date the given identity role expires at, or removes its time limit if the date is nil
+func (m *GormIdentityRoleRepository) SetExpiry(ctx context.Context, id uuid.UUID, expiresAt *time.Time) error {
+	defer tracing.MeasureSince(ctx, []string{"goa", "db", "identity_role", "setExpiry"}, time.Now())
+	err := m.db.Model(&IdentityRole{IdentityRoleID: id}).UpdateColumn("expires_at", expiresAt).Error
+	if err != nil {
+		log.Error(ctx, map[string]interface{}{
+			"identity_role_id": id,
+			"err":              err,
+		}, "unable to set the expiry of the identity role")
+		return errs.WithStack(err)
+	}
+	log.Debug(ctx, map[string]interface{}{
+		"identity_role_id": id,
+		"expires_at":       expiresAt,
+	}, "Identity role expiry set!")
+	return nil
+}
+
 // List returns all identity roles
 func (m *GormIdentityRoleRepository) List(ctx context.Context) ([]IdentityRole, error) {
-	defer goa.MeasureSince([]string{"goa", "db", "identity_role", "list"}, time.Now())
+	defer tracing.MeasureSince(ctx, []string{"goa", "db", "identity_role", "list"}, time.Now())
 	var rows []IdentityRole
 
 	err := m.db.Model(&resource.ResourceType{}).Find(&rows).Error
@@ -166,9 +195,62 @@ func (m *GormIdentityRoleRepository) List(ctx context.Context) ([]IdentityRole,
 	return rows, nil
 }
 
+// Query expose an open ended Query model
+func (m *GormIdentityRoleRepository) Query(funcs ...func(*gorm.DB) *gorm.DB) ([]IdentityRole, error) {
+	defer goa.MeasureSince([]string{"goa", "db", "identity_role", "query"}, time.Now())
+	var rows []IdentityRole
+	err := m.db.Scopes(funcs...).Table(m.TableName()).Find(&rows).Error
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return nil, errs.WithStack(err)
+	}
+	return rows, nil
+}
+
 // IdentityRoleFilterByID is a gorm filter for Identity Role ID.
 func IdentityRoleFilterByID(identityRoleID uuid.UUID) func(db *gorm.DB) *gorm.DB {
 	return func(db *gorm.DB) *gorm.DB {
 		return db.Where("identity_role_id = ?", identityRoleID)
 	}
 }
+
+// IdentityRoleFilterByIdentityID is a gorm filter for the identity ID
+func IdentityRoleFilterByIdentityID(identityID uuid.UUID) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where("identity_id = ?", identityID)
+	}
+}
+
+// IdentityRoleFilterByResourceID is a gorm filter for the resource ID
+func IdentityRoleFilterByResourceID(resourceID string) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where("resource_id = ?", resourceID)
+	}
+}
+
+// IdentityRoleFilterByResourceIDs is a gorm filter for a set of resource IDs
+func IdentityRoleFilterByResourceIDs(resourceIDs []string) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where("resource_id IN (?)", resourceIDs)
+	}
+}
+
+// IdentityRoleFilterByRoleID is a gorm filter for the role ID
+func IdentityRoleFilterByRoleID(roleID uuid.UUID) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where("role_id = ?", roleID)
+	}
+}
+
+// IdentityRoleFilterNotExpired is a gorm filter for the role assignments which are not expired
+func IdentityRoleFilterNotExpired() func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where("expires_at IS NULL OR expires_at > ?", gorm.NowFunc())
+	}
+}
+
+// IdentityRoleFilterExpired is a gorm filter for the expired role assignments
+func IdentityRoleFilterExpired() func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where("expires_at <= ?", gorm.NowFunc())
+	}
+}
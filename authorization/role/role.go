@@ -8,7 +8,7 @@ import (
 	"github.com/fabric8-services/fabric8-auth/errors"
 	"github.com/fabric8-services/fabric8-auth/gormsupport"
 	"github.com/fabric8-services/fabric8-auth/log"
-	"github.com/goadesign/goa"
+	"github.com/fabric8-services/fabric8-auth/tracing"
 	"github.com/jinzhu/gorm"
 
 	"fmt"
@@ -75,6 +75,7 @@ func NewRoleRepository(db *gorm.DB) RoleRepository {
 type RoleRepository interface {
 	CheckExists(ctx context.Context, id string) (bool, error)
 	Load(ctx context.Context, ID uuid.UUID) (*Role, error)
+	Lookup(ctx context.Context, name string, resourceTypeName string) (*Role, error)
 	Create(ctx context.Context, u *Role) error
 	Save(ctx context.Context, u *Role) error
 	List(ctx context.Context) ([]Role, error)
@@ -92,7 +93,7 @@ func (m *GormRoleRepository) TableName() string {
 
 // CheckExists returns nil if the given ID exists otherwise returns an error
 func (m *GormRoleRepository) CheckExists(ctx context.Context, id string) (bool, error) {
-	defer goa.MeasureSince([]string{"goa", "db", "role", "exists"}, time.Now())
+	defer tracing.MeasureSince(ctx, []string{"goa", "db", "role", "exists"}, time.Now())
 
 	var exists bool
 	query := fmt.Sprintf(`
@@ -118,7 +119,7 @@ func (m *GormRoleRepository) CheckExists(ctx context.Context, id string) (bool,
 // Load returns a single Role as a Database Model
 // This is more for use internally, and probably not what you want in  your controllers
 func (m *GormRoleRepository) Load(ctx context.Context, id uuid.UUID) (*Role, error) {
-	defer goa.MeasureSince([]string{"goa", "db", "role", "load"}, time.Now())
+	defer tracing.MeasureSince(ctx, []string{"goa", "db", "role", "load"}, time.Now())
 	var native Role
 	err := m.db.Table(m.TableName()).Preload("ResourceType"). /*.Preload("Scopes")*/ Where("role_id = ?", id).Find(&native).Error
 	if err == gorm.ErrRecordNotFound {
@@ -127,9 +128,23 @@ func (m *GormRoleRepository) Load(ctx context.Context, id uuid.UUID) (*Role, err
 	return &native, errs.WithStack(err)
 }
 
+// Lookup returns the role with the given name for the resource type with the given name
+func (m *GormRoleRepository) Lookup(ctx context.Context, name string, resourceTypeName string) (*Role, error) {
+	defer tracing.MeasureSince(ctx, []string{"goa", "db", "role", "lookup"}, time.Now())
+	var native Role
+	err := m.db.Table(m.TableName()).Preload("ResourceType").
+		Joins("JOIN resource_type ON resource_type.resource_type_id = role.resource_type_id AND resource_type.deleted_at IS NULL").
+		Where("role.name = ? AND resource_type.name = ?", name, resourceTypeName).
+		First(&native).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, errors.NewNotFoundError("role", name)
+	}
+	return &native, errs.WithStack(err)
+}
+
 // Create creates a new record.
 func (m *GormRoleRepository) Create(ctx context.Context, u *Role) error {
-	defer goa.MeasureSince([]string{"goa", "db", "role", "create"}, time.Now())
+	defer tracing.MeasureSince(ctx, []string{"goa", "db", "role", "create"}, time.Now())
 	if u.RoleID == uuid.Nil {
 		u.RoleID = uuid.NewV4()
 	}
@@ -149,7 +164,7 @@ func (m *GormRoleRepository) Create(ctx context.Context, u *Role) error {
 
 // Save modifies a single record
 func (m *GormRoleRepository) Save(ctx context.Context, model *Role) error {
-	defer goa.MeasureSince([]string{"goa", "db", "role", "save"}, time.Now())
+	defer tracing.MeasureSince(ctx, []string{"goa", "db", "role", "save"}, time.Now())
 
 	obj, err := m.Load(ctx, model.RoleID)
 	if err != nil {
@@ -172,7 +187,7 @@ func (m *GormRoleRepository) Save(ctx context.Context, model *Role) error {
 
 // Delete removes a single record.
 func (m *GormRoleRepository) Delete(ctx context.Context, id uuid.UUID) error {
-	defer goa.MeasureSince([]string{"goa", "db", "role", "delete"}, time.Now())
+	defer tracing.MeasureSince(ctx, []string{"goa", "db", "role", "delete"}, time.Now())
 
 	obj := Role{RoleID: id}
 
@@ -195,7 +210,7 @@ func (m *GormRoleRepository) Delete(ctx context.Context, id uuid.UUID) error {
 
 // List returns all roles
 func (m *GormRoleRepository) List(ctx context.Context) ([]Role, error) {
-	defer goa.MeasureSince([]string{"goa", "db", "role", "list"}, time.Now())
+	defer tracing.MeasureSince(ctx, []string{"goa", "db", "role", "list"}, time.Now())
 	var rows []Role
 
 	err := m.db.Model(&Role{}).Find(&rows).Error
@@ -206,7 +221,7 @@ func (m *GormRoleRepository) List(ctx context.Context) ([]Role, error) {
 }
 
 func (m *GormRoleRepository) ListScopes(ctx context.Context, u *Role) ([]resource.ResourceTypeScope, error) {
-	defer goa.MeasureSince([]string{"goa", "db", "role", "listscopes"}, time.Now())
+	defer tracing.MeasureSince(ctx, []string{"goa", "db", "role", "listscopes"}, time.Now())
 
 	var scopes []RoleScope
 
@@ -224,7 +239,7 @@ func (m *GormRoleRepository) ListScopes(ctx context.Context, u *Role) ([]resourc
 }
 
 func (m *GormRoleRepository) AddScope(ctx context.Context, u *Role, s *resource.ResourceTypeScope) error {
-	defer goa.MeasureSince([]string{"goa", "db", "role", "addscope"}, time.Now())
+	defer tracing.MeasureSince(ctx, []string{"goa", "db", "role", "addscope"}, time.Now())
 
 	roleScope := &RoleScope{
 		RoleID:  u.RoleID,
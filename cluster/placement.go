@@ -0,0 +1,93 @@
+// Package cluster chooses the OpenShift cluster the new users are provisioned to, among the clusters
+// of the registry, with the placement strategy of the configuration.
+package cluster
+
+import (
+	"context"
+	"sort"
+
+	"github.com/fabric8-services/fabric8-auth/account"
+	"github.com/fabric8-services/fabric8-auth/application"
+	"github.com/fabric8-services/fabric8-auth/errors"
+	"github.com/fabric8-services/fabric8-auth/log"
+
+	"github.com/jinzhu/gorm"
+)
+
+// Configuration represents the configuration of the cluster placement
+type Configuration interface {
+	GetClusterPlacementStrategy() string
+	GetClusterPlacementRegion() string
+}
+
+// Placement chooses the cluster of the new users
+type Placement struct {
+	config Configuration
+}
+
+// NewPlacement creates a placement which uses the strategy of the given configuration
+func NewPlacement(config Configuration) *Placement {
+	return &Placement{config: config}
+}
+
+// Place returns the API URL of the cluster a new user is provisioned to, in the given region if any.
+// The clusters are loaded by the users stored in the given application, so the users created in the
+// same transaction are taken into account.
+func (p *Placement) Place(ctx context.Context, appl application.Application, region string) (string, error) {
+	strategy, err := NewStrategy(p.config.GetClusterPlacementStrategy(), p.config.GetClusterPlacementRegion())
+	if err != nil {
+		return "", errors.NewInternalError(ctx, err)
+	}
+	candidates, err := p.Candidates(ctx, appl)
+	if err != nil {
+		return "", err
+	}
+	if len(candidates) == 0 {
+		return "", errors.NewInternalErrorFromString(ctx, "no cluster registered")
+	}
+	request := Request{Region: region}
+	latest, err := appl.Users().Query(account.UserWithLatestFirst(), func(db *gorm.DB) *gorm.DB {
+		return db.Limit(1)
+	})
+	if err != nil {
+		return "", errors.NewInternalError(ctx, err)
+	}
+	if len(latest) > 0 {
+		request.LatestCluster = latest[0].Cluster
+	}
+	chosen, err := strategy.Choose(ctx, candidates, request)
+	if err != nil {
+		return "", err
+	}
+	log.Info(ctx, map[string]interface{}{
+		"cluster":  chosen.URL,
+		"strategy": p.config.GetClusterPlacementStrategy(),
+		"region":   region,
+	}, "cluster chosen for the new user")
+	return chosen.URL, nil
+}
+
+// Candidates returns the registered clusters with their number of users, sorted by API URL
+func (p *Placement) Candidates(ctx context.Context, appl application.Application) ([]Candidate, error) {
+	counts, err := appl.Users().CountByCluster(ctx)
+	if err != nil {
+		return nil, errors.NewInternalError(ctx, err)
+	}
+	clusters, err := appl.Clusters().List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var candidates []Candidate
+	for _, cluster := range clusters {
+		candidates = append(candidates, Candidate{OSOCluster: cluster.OSOCluster(), Users: counts[cluster.URL]})
+	}
+	sort.Sort(byURL(candidates))
+	return candidates, nil
+}
+
+// byURL sorts the candidate clusters by API URL
+type byURL []Candidate
+
+func (c byURL) Len() int           { return len(c) }
+func (c byURL) Swap(i, j int)      { c[i], c[j] = c[j], c[i] }
+func (c byURL) Less(i, j int) bool { return c[i].URL < c[j].URL }
@@ -0,0 +1,118 @@
+package cluster_test
+
+import (
+	"testing"
+
+	"github.com/fabric8-services/fabric8-auth/account"
+	"github.com/fabric8-services/fabric8-auth/cluster"
+	"github.com/fabric8-services/fabric8-auth/cluster/registry"
+	"github.com/fabric8-services/fabric8-auth/gormtestsupport"
+	"github.com/fabric8-services/fabric8-auth/resource"
+
+	uuid "github.com/satori/go.uuid"
+	"github.com/stretchr/testify/suite"
+)
+
+type placementBlackBoxTest struct {
+	gormtestsupport.DBTestSuite
+	config *placementConfig
+	// clusters holds the API URL of the registered clusters by region
+	clusters map[string]string
+}
+
+func TestRunPlacementBlackBoxTest(t *testing.T) {
+	resource.Require(t, resource.Database)
+	suite.Run(t, &placementBlackBoxTest{DBTestSuite: gormtestsupport.NewDBTestSuite()})
+}
+
+func (s *placementBlackBoxTest) SetupTest() {
+	s.DBTestSuite.SetupTest()
+	// the clusters are unique to each test, so the users of the other tests are not counted
+	s.config = &placementConfig{strategy: cluster.StrategyLeastLoaded}
+	s.clusters = map[string]string{}
+	for _, region := range []string{"us-east", "us-west"} {
+		c := registry.Cluster{
+			Name:            region,
+			URL:             "https://api." + region + "." + uuid.NewV4().String(),
+			TokenProviderID: uuid.NewV4(),
+			Region:          region,
+		}
+		s.Require().NoError(s.Application.Clusters().Create(s.Ctx, &c))
+		s.clusters[region] = c.URL
+	}
+}
+
+func (s *placementBlackBoxTest) clusterOf(region string) string {
+	return s.clusters[region]
+}
+
+func (s *placementBlackBoxTest) createUser(clusterURL string) {
+	err := s.Application.Users().Create(s.Ctx, &account.User{
+		ID:      uuid.NewV4(),
+		Email:   "placement-" + uuid.NewV4().String(),
+		Cluster: clusterURL,
+	})
+	s.Require().NoError(err)
+}
+
+func (s *placementBlackBoxTest) TestPlaceLeastLoaded() {
+	// given
+	s.createUser(s.clusterOf("us-east"))
+
+	// when
+	chosen, err := cluster.NewPlacement(s.config).Place(s.Ctx, s.Application, "")
+
+	// then
+	s.Require().NoError(err)
+	s.Equal(s.clusterOf("us-west"), chosen)
+}
+
+func (s *placementBlackBoxTest) TestPlaceRoundRobin() {
+	// given
+	s.config.strategy = cluster.StrategyRoundRobin
+	s.createUser(s.clusterOf("us-west"))
+
+	// when
+	chosen, err := cluster.NewPlacement(s.config).Place(s.Ctx, s.Application, "")
+
+	// then the cluster following the one of the latest user is chosen
+	s.Require().NoError(err)
+	s.Equal(s.clusterOf("us-east"), chosen)
+}
+
+func (s *placementBlackBoxTest) TestPlacePerRegion() {
+	// given
+	s.config.strategy = cluster.StrategyPerRegion
+	s.createUser(s.clusterOf("us-east"))
+
+	// when
+	chosen, err := cluster.NewPlacement(s.config).Place(s.Ctx, s.Application, "us-east")
+
+	// then
+	s.Require().NoError(err)
+	s.Equal(s.clusterOf("us-east"), chosen)
+}
+
+func (s *placementBlackBoxTest) TestPlaceWithUnknownStrategyFails() {
+	// given
+	s.config.strategy = "random"
+
+	// when
+	_, err := cluster.NewPlacement(s.config).Place(s.Ctx, s.Application, "")
+
+	// then
+	s.Error(err)
+}
+
+type placementConfig struct {
+	strategy string
+	region   string
+}
+
+func (c *placementConfig) GetClusterPlacementStrategy() string {
+	return c.strategy
+}
+
+func (c *placementConfig) GetClusterPlacementRegion() string {
+	return c.region
+}
@@ -0,0 +1,251 @@
+package registry
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/fabric8-services/fabric8-auth/configuration"
+	"github.com/fabric8-services/fabric8-auth/errors"
+	"github.com/fabric8-services/fabric8-auth/gormsupport"
+	"github.com/fabric8-services/fabric8-auth/log"
+	"github.com/fabric8-services/fabric8-auth/tracing"
+
+	"github.com/jinzhu/gorm"
+	uuid "github.com/satori/go.uuid"
+)
+
+// Cluster describes an OpenShift cluster the users are provisioned to, along with the OAuth client
+// used to link the accounts of the users on the cluster.
+// The service account token and the auth client secret are references to the secrets, like "vault://path#key",
+// which are resolved when the cluster is used (see configuration.ResolveSecret).
+type Cluster struct {
+	gormsupport.Lifecycle
+	ID                     uuid.UUID `sql:"type:uuid default uuid_generate_v4()" gorm:"primary_key"` // This is the ID PK field
+	Name                   string
+	URL                    string
+	ConsoleURL             string
+	ServiceAccountToken    string
+	TokenProviderID        uuid.UUID `sql:"type:uuid"`
+	AuthClientID           string
+	AuthClientSecret       string
+	AuthClientDefaultScope string
+	// Capacity is the max number of users provisioned to the cluster. Unlimited if zero.
+	Capacity int
+	Region   string
+}
+
+// TableName overrides the table name settings in Gorm to force a specific table name
+// in the database.
+func (m Cluster) TableName() string {
+	return "clusters"
+}
+
+// OSOCluster returns the cluster as a cluster of the configuration.
+// The references to the secrets of the cluster are not resolved.
+func (m Cluster) OSOCluster() configuration.OSOCluster {
+	return configuration.OSOCluster{
+		Name:                   m.Name,
+		URL:                    m.URL,
+		ConsoleURL:             m.ConsoleURL,
+		ServiceAccountToken:    m.ServiceAccountToken,
+		TokenProviderID:        m.TokenProviderID.String(),
+		AuthClientID:           m.AuthClientID,
+		AuthClientSecret:       m.AuthClientSecret,
+		AuthClientDefaultScope: m.AuthClientDefaultScope,
+		Capacity:               m.Capacity,
+		Region:                 m.Region,
+	}
+}
+
+// Serves returns true if the given resource URL is an URL of the cluster
+func (m Cluster) Serves(resourceURL string) bool {
+	return strings.HasPrefix(resourceURL, m.URL)
+}
+
+// NewCluster returns the cluster of the registry for the given cluster of the configuration
+func NewCluster(cluster configuration.OSOCluster) (*Cluster, error) {
+	providerID, err := uuid.FromString(cluster.TokenProviderID)
+	if err != nil {
+		return nil, errors.NewBadParameterError("token-provider-id", cluster.TokenProviderID).Expected("UUID")
+	}
+	return &Cluster{
+		Name:                   cluster.Name,
+		URL:                    cluster.URL,
+		ConsoleURL:             cluster.ConsoleURL,
+		ServiceAccountToken:    cluster.ServiceAccountToken,
+		TokenProviderID:        providerID,
+		AuthClientID:           cluster.AuthClientID,
+		AuthClientSecret:       cluster.AuthClientSecret,
+		AuthClientDefaultScope: cluster.AuthClientDefaultScope,
+		Capacity:               cluster.Capacity,
+		Region:                 cluster.Region,
+	}, nil
+}
+
+// ClusterRepository represents the storage interface.
+type ClusterRepository interface {
+	Load(ctx context.Context, id uuid.UUID) (*Cluster, error)
+	LoadByURL(ctx context.Context, url string) (*Cluster, error)
+	Create(ctx context.Context, cluster *Cluster) error
+	Save(ctx context.Context, cluster *Cluster) error
+	Delete(ctx context.Context, id uuid.UUID) error
+	List(ctx context.Context) ([]Cluster, error)
+}
+
+// NewClusterRepository creates a new storage type.
+func NewClusterRepository(db *gorm.DB) *GormClusterRepository {
+	return &GormClusterRepository{db: db}
+}
+
+// GormClusterRepository is the implementation of the storage interface for Cluster.
+type GormClusterRepository struct {
+	db *gorm.DB
+}
+
+// Load returns the cluster for the given id
+// returns NotFoundError or InternalError
+func (m *GormClusterRepository) Load(ctx context.Context, id uuid.UUID) (*Cluster, error) {
+	defer tracing.MeasureSince(ctx, []string{"goa", "db", "cluster", "load"}, time.Now())
+	cluster := Cluster{}
+	tx := m.db.Where("id = ?", id).First(&cluster)
+	if tx.RecordNotFound() {
+		return nil, errors.NewNotFoundError("cluster", id.String())
+	}
+	if tx.Error != nil {
+		return nil, errors.NewInternalError(ctx, tx.Error)
+	}
+	return &cluster, nil
+}
+
+// LoadByURL returns the cluster for the given API URL
+// returns NotFoundError or InternalError
+func (m *GormClusterRepository) LoadByURL(ctx context.Context, url string) (*Cluster, error) {
+	defer tracing.MeasureSince(ctx, []string{"goa", "db", "cluster", "loadByURL"}, time.Now())
+	cluster := Cluster{}
+	tx := m.db.Where("url = ?", url).First(&cluster)
+	if tx.RecordNotFound() {
+		return nil, errors.NewNotFoundError("cluster", url)
+	}
+	if tx.Error != nil {
+		return nil, errors.NewInternalError(ctx, tx.Error)
+	}
+	return &cluster, nil
+}
+
+// Create creates a new cluster
+// returns BadParameterError if a cluster with the same API URL is already registered, or InternalError
+func (m *GormClusterRepository) Create(ctx context.Context, cluster *Cluster) error {
+	defer tracing.MeasureSince(ctx, []string{"goa", "db", "cluster", "create"}, time.Now())
+	if cluster.ID == uuid.Nil {
+		cluster.ID = uuid.NewV4()
+	}
+	err := m.db.Create(cluster).Error
+	if gormsupport.IsUniqueViolation(err, "idx_clusters_url") {
+		return errors.NewBadParameterError("url", cluster.URL).Expected("the API URL of a cluster which is not registered yet")
+	}
+	if err != nil {
+		log.Error(ctx, map[string]interface{}{
+			"cluster_url": cluster.URL,
+			"err":         err,
+		}, "unable to create the cluster")
+		return errors.NewInternalError(ctx, err)
+	}
+	log.Info(ctx, map[string]interface{}{
+		"cluster_id":  cluster.ID,
+		"cluster_url": cluster.URL,
+	}, "Cluster registered")
+	return nil
+}
+
+// Save updates the given cluster
+// returns BadParameterError if another cluster with the same API URL is registered, or InternalError
+func (m *GormClusterRepository) Save(ctx context.Context, cluster *Cluster) error {
+	defer tracing.MeasureSince(ctx, []string{"goa", "db", "cluster", "save"}, time.Now())
+	err := m.db.Save(cluster).Error
+	if gormsupport.IsUniqueViolation(err, "idx_clusters_url") {
+		return errors.NewBadParameterError("url", cluster.URL).Expected("the API URL of a cluster which is not registered yet")
+	}
+	if err != nil {
+		log.Error(ctx, map[string]interface{}{
+			"cluster_id": cluster.ID,
+			"err":        err,
+		}, "unable to update the cluster")
+		return errors.NewInternalError(ctx, err)
+	}
+	return nil
+}
+
+// Delete removes the cluster with the given id from the registry
+// returns NotFoundError or InternalError
+func (m *GormClusterRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	defer tracing.MeasureSince(ctx, []string{"goa", "db", "cluster", "delete"}, time.Now())
+	tx := m.db.Delete(&Cluster{ID: id})
+	if tx.Error != nil {
+		log.Error(ctx, map[string]interface{}{
+			"cluster_id": id,
+			"err":        tx.Error,
+		}, "unable to delete the cluster")
+		return errors.NewInternalError(ctx, tx.Error)
+	}
+	if tx.RowsAffected == 0 {
+		return errors.NewNotFoundError("cluster", id.String())
+	}
+	log.Info(ctx, map[string]interface{}{
+		"cluster_id": id,
+	}, "Cluster removed from the registry")
+	return nil
+}
+
+// List returns all the registered clusters, sorted by API URL
+// returns InternalError
+func (m *GormClusterRepository) List(ctx context.Context) ([]Cluster, error) {
+	defer tracing.MeasureSince(ctx, []string{"goa", "db", "cluster", "list"}, time.Now())
+	var clusters []Cluster
+	err := m.db.Order("url").Find(&clusters).Error
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return nil, errors.NewInternalError(ctx, err)
+	}
+	return clusters, nil
+}
+
+// Seed registers the given clusters of the configuration, unless a cluster with the same API URL
+// is already registered. The registered clusters are never overwritten, so the changes made through
+// the API are kept when the service restarts, nor when another instance of the service seeds the
+// registry at the same time.
+// The secrets of the given clusters are stored as is, so the clusters should be the seeds of the configuration
+// (see configuration.GetOSOClusterSeeds) which keep the references to the secrets.
+func Seed(ctx context.Context, clusters ClusterRepository, seed map[string]configuration.OSOCluster) error {
+	for apiURL, c := range seed {
+		_, err := clusters.LoadByURL(ctx, apiURL)
+		if err == nil {
+			continue
+		}
+		if ok, _ := errors.IsNotFoundError(err); !ok {
+			return err
+		}
+		cluster, err := NewCluster(c)
+		if err != nil {
+			return err
+		}
+		err = clusters.Create(ctx, cluster)
+		if ok, _ := errors.IsBadParameterError(err); ok {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Resolve returns the cluster among the given ones which serves the given resource URL
+// returns NotFoundError if none of the clusters serves the resource
+func Resolve(clusters []Cluster, resourceURL string) (*Cluster, error) {
+	for _, cluster := range clusters {
+		if cluster.Serves(resourceURL) {
+			return &cluster, nil
+		}
+	}
+	return nil, errors.NewNotFoundError("cluster", resourceURL)
+}
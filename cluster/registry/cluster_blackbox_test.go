@@ -0,0 +1,154 @@
+package registry_test
+
+import (
+	"testing"
+
+	"github.com/fabric8-services/fabric8-auth/cluster/registry"
+	"github.com/fabric8-services/fabric8-auth/configuration"
+	"github.com/fabric8-services/fabric8-auth/errors"
+	"github.com/fabric8-services/fabric8-auth/gormtestsupport"
+	"github.com/fabric8-services/fabric8-auth/resource"
+
+	errs "github.com/pkg/errors"
+	"github.com/satori/go.uuid"
+	"github.com/stretchr/testify/suite"
+)
+
+type clusterBlackBoxTest struct {
+	gormtestsupport.DBTestSuite
+	repo registry.ClusterRepository
+}
+
+func TestRunClusterBlackBoxTest(t *testing.T) {
+	resource.Require(t, resource.Database)
+	suite.Run(t, &clusterBlackBoxTest{DBTestSuite: gormtestsupport.NewDBTestSuite()})
+}
+
+func (s *clusterBlackBoxTest) SetupTest() {
+	s.DBTestSuite.SetupTest()
+	s.repo = registry.NewClusterRepository(s.DB)
+}
+
+func (s *clusterBlackBoxTest) newCluster() *registry.Cluster {
+	return &registry.Cluster{
+		Name:                   "cluster-" + uuid.NewV4().String(),
+		URL:                    "https://api." + uuid.NewV4().String() + ".openshift.com/",
+		ConsoleURL:             "https://console." + uuid.NewV4().String() + ".openshift.com/",
+		TokenProviderID:        uuid.NewV4(),
+		AuthClientID:           "client",
+		AuthClientSecret:       "secret",
+		AuthClientDefaultScope: "user:full",
+		Capacity:               100,
+		Region:                 "us-east",
+	}
+}
+
+func (s *clusterBlackBoxTest) TestCreateAndLoad() {
+	// given
+	cluster := s.newCluster()
+
+	// when
+	err := s.repo.Create(s.Ctx, cluster)
+
+	// then
+	s.Require().NoError(err)
+	loaded, err := s.repo.Load(s.Ctx, cluster.ID)
+	s.Require().NoError(err)
+	s.Equal(cluster.URL, loaded.URL)
+	s.Equal(cluster.ConsoleURL, loaded.ConsoleURL)
+	s.Equal(cluster.TokenProviderID, loaded.TokenProviderID)
+	s.Equal(100, loaded.Capacity)
+	loaded, err = s.repo.LoadByURL(s.Ctx, cluster.URL)
+	s.Require().NoError(err)
+	s.Equal(cluster.ID, loaded.ID)
+}
+
+func (s *clusterBlackBoxTest) TestCreateWithRegisteredURLFails() {
+	// given
+	cluster := s.newCluster()
+	s.Require().NoError(s.repo.Create(s.Ctx, cluster))
+	other := s.newCluster()
+	other.URL = cluster.URL
+
+	// when
+	err := s.repo.Create(s.Ctx, other)
+
+	// then
+	s.Require().Error(err)
+	s.IsType(errors.BadParameterError{}, errs.Cause(err))
+}
+
+func (s *clusterBlackBoxTest) TestSaveAndDelete() {
+	// given
+	cluster := s.newCluster()
+	s.Require().NoError(s.repo.Create(s.Ctx, cluster))
+
+	// when
+	cluster.Capacity = 200
+	err := s.repo.Save(s.Ctx, cluster)
+
+	// then
+	s.Require().NoError(err)
+	loaded, err := s.repo.Load(s.Ctx, cluster.ID)
+	s.Require().NoError(err)
+	s.Equal(200, loaded.Capacity)
+
+	// when
+	err = s.repo.Delete(s.Ctx, cluster.ID)
+
+	// then the cluster can be registered again
+	s.Require().NoError(err)
+	_, err = s.repo.Load(s.Ctx, cluster.ID)
+	s.IsType(errors.NotFoundError{}, errs.Cause(err))
+	err = s.repo.Delete(s.Ctx, cluster.ID)
+	s.IsType(errors.NotFoundError{}, errs.Cause(err))
+	s.Require().NoError(s.repo.Create(s.Ctx, &registry.Cluster{URL: cluster.URL, Name: cluster.Name, TokenProviderID: uuid.NewV4()}))
+}
+
+func (s *clusterBlackBoxTest) TestSeed() {
+	// given a registered cluster which was changed through the API
+	registered := s.newCluster()
+	s.Require().NoError(s.repo.Create(s.Ctx, registered))
+	seeded := configuration.OSOCluster{
+		Name:            "seeded",
+		URL:             "https://api." + uuid.NewV4().String() + ".openshift.com/",
+		TokenProviderID: uuid.NewV4().String(),
+	}
+	seed := map[string]configuration.OSOCluster{
+		registered.URL: {Name: "from-file", URL: registered.URL, TokenProviderID: uuid.NewV4().String()},
+		seeded.URL:     seeded,
+	}
+
+	// when
+	err := registry.Seed(s.Ctx, s.repo, seed)
+
+	// then the missing cluster is registered and the registered one is kept
+	s.Require().NoError(err)
+	loaded, err := s.repo.LoadByURL(s.Ctx, seeded.URL)
+	s.Require().NoError(err)
+	s.Equal(seeded, loaded.OSOCluster())
+	loaded, err = s.repo.LoadByURL(s.Ctx, registered.URL)
+	s.Require().NoError(err)
+	s.Equal(registered.Name, loaded.Name)
+
+	// when seeded again
+	err = registry.Seed(s.Ctx, s.repo, seed)
+
+	// then
+	s.Require().NoError(err)
+}
+
+func (s *clusterBlackBoxTest) TestResolve() {
+	// given
+	cluster := s.newCluster()
+	clusters := []registry.Cluster{*s.newCluster(), *cluster}
+
+	// when
+	resolved, err := registry.Resolve(clusters, cluster.URL+"api/v1/namespaces")
+
+	// then
+	s.Require().NoError(err)
+	s.Equal(cluster.URL, resolved.URL)
+	_, err = registry.Resolve(clusters, "https://github.com/fabric8-services")
+	s.IsType(errors.NotFoundError{}, errs.Cause(err))
+}
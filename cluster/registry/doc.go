@@ -0,0 +1,4 @@
+// Package registry stores the OpenShift clusters the users are provisioned to. The clusters of the
+// OSO cluster configuration file are a seed: they are added to the registry at startup unless a
+// cluster with the same API URL is already registered, and are managed through the API afterwards.
+package registry
@@ -0,0 +1,134 @@
+package cluster
+
+import (
+	"context"
+
+	"github.com/fabric8-services/fabric8-auth/configuration"
+	"github.com/fabric8-services/fabric8-auth/errors"
+)
+
+const (
+	// StrategyLeastLoaded places the new users on the cluster with the fewest users relative to its capacity
+	StrategyLeastLoaded = "least-loaded"
+	// StrategyRoundRobin places the new users on the clusters in turn
+	StrategyRoundRobin = "round-robin"
+	// StrategyPerRegion places the new users on the least loaded cluster of their region
+	StrategyPerRegion = "per-region"
+)
+
+// Candidate is a cluster a new user may be provisioned to
+type Candidate struct {
+	configuration.OSOCluster
+	// Users is the number of users provisioned to the cluster
+	Users int
+}
+
+// Full returns true if the cluster reached its capacity. The clusters without capacity are never full.
+func (c Candidate) Full() bool {
+	return c.Capacity > 0 && c.Users >= c.Capacity
+}
+
+// load returns the part of the capacity of the cluster which is used, or the number of users of the
+// cluster if it has no capacity
+func (c Candidate) load() float64 {
+	if c.Capacity > 0 {
+		return float64(c.Users) / float64(c.Capacity)
+	}
+	return float64(c.Users)
+}
+
+// Request holds what is known when choosing the cluster of a new user
+type Request struct {
+	// Region is the region requested for the user, if any
+	Region string
+	// LatestCluster is the API URL of the cluster the most recently created user was provisioned to
+	LatestCluster string
+}
+
+// Strategy chooses the cluster a new user is provisioned to among the candidates, which are sorted by API URL
+type Strategy interface {
+	Choose(ctx context.Context, candidates []Candidate, request Request) (*Candidate, error)
+}
+
+// NewStrategy returns the strategy with the given name. The "per-region" strategy uses the given default region
+// for the users without requested region.
+func NewStrategy(name string, defaultRegion string) (Strategy, error) {
+	switch name {
+	case StrategyLeastLoaded:
+		return LeastLoaded{}, nil
+	case StrategyRoundRobin:
+		return RoundRobin{}, nil
+	case StrategyPerRegion:
+		return PerRegion{DefaultRegion: defaultRegion}, nil
+	}
+	return nil, errors.NewBadParameterError("strategy", name).Expected(StrategyLeastLoaded + ", " + StrategyRoundRobin + " or " + StrategyPerRegion)
+}
+
+// LeastLoaded chooses the cluster with the fewest users relative to its capacity
+type LeastLoaded struct{}
+
+// Choose returns the least loaded cluster which is not full
+func (s LeastLoaded) Choose(ctx context.Context, candidates []Candidate, request Request) (*Candidate, error) {
+	var chosen *Candidate
+	for i := range candidates {
+		if candidates[i].Full() {
+			continue
+		}
+		if chosen == nil || candidates[i].load() < chosen.load() {
+			chosen = &candidates[i]
+		}
+	}
+	if chosen == nil {
+		return nil, errors.NewInternalErrorFromString(ctx, "all the clusters are full")
+	}
+	return chosen, nil
+}
+
+// RoundRobin chooses the clusters in turn. The turn is derived from the cluster of the most recently created user,
+// so all the instances of the service share it.
+type RoundRobin struct{}
+
+// Choose returns the first cluster which is not full after the cluster of the most recently created user
+func (s RoundRobin) Choose(ctx context.Context, candidates []Candidate, request Request) (*Candidate, error) {
+	start := 0
+	for i := range candidates {
+		if candidates[i].URL == request.LatestCluster {
+			start = i + 1
+			break
+		}
+	}
+	for i := range candidates {
+		candidate := &candidates[(start+i)%len(candidates)]
+		if !candidate.Full() {
+			return candidate, nil
+		}
+	}
+	return nil, errors.NewInternalErrorFromString(ctx, "all the clusters are full")
+}
+
+// PerRegion chooses the least loaded cluster of the requested region, or of the default region if no region
+// is requested. Any cluster may be chosen if there is no default region either.
+type PerRegion struct {
+	DefaultRegion string
+}
+
+// Choose returns the least loaded cluster of the region of the user
+func (s PerRegion) Choose(ctx context.Context, candidates []Candidate, request Request) (*Candidate, error) {
+	region := request.Region
+	if region == "" {
+		region = s.DefaultRegion
+	}
+	if region == "" {
+		return LeastLoaded{}.Choose(ctx, candidates, request)
+	}
+	var inRegion []Candidate
+	for _, candidate := range candidates {
+		if candidate.Region == region {
+			inRegion = append(inRegion, candidate)
+		}
+	}
+	if len(inRegion) == 0 {
+		return nil, errors.NewBadParameterError("region", region).Expected("the region of a cluster")
+	}
+	return LeastLoaded{}.Choose(ctx, inRegion, request)
+}
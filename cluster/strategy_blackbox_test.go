@@ -0,0 +1,108 @@
+package cluster_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/fabric8-services/fabric8-auth/cluster"
+	"github.com/fabric8-services/fabric8-auth/configuration"
+	"github.com/fabric8-services/fabric8-auth/errors"
+	"github.com/fabric8-services/fabric8-auth/resource"
+
+	errs "github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func candidate(url string, region string, capacity int, users int) cluster.Candidate {
+	return cluster.Candidate{
+		OSOCluster: configuration.OSOCluster{URL: url, Region: region, Capacity: capacity},
+		Users:      users,
+	}
+}
+
+func TestLeastLoaded(t *testing.T) {
+	resource.Require(t, resource.UnitTest)
+	ctx := context.Background()
+
+	t.Run("relative to capacity", func(t *testing.T) {
+		candidates := []cluster.Candidate{
+			candidate("https://api.a", "", 100, 50),
+			candidate("https://api.b", "", 1000, 100),
+		}
+		chosen, err := cluster.LeastLoaded{}.Choose(ctx, candidates, cluster.Request{})
+		require.NoError(t, err)
+		assert.Equal(t, "https://api.b", chosen.URL)
+	})
+
+	t.Run("skips full clusters", func(t *testing.T) {
+		candidates := []cluster.Candidate{
+			candidate("https://api.a", "", 10, 10),
+			candidate("https://api.b", "", 0, 500),
+		}
+		chosen, err := cluster.LeastLoaded{}.Choose(ctx, candidates, cluster.Request{})
+		require.NoError(t, err)
+		assert.Equal(t, "https://api.b", chosen.URL)
+	})
+
+	t.Run("all full", func(t *testing.T) {
+		candidates := []cluster.Candidate{candidate("https://api.a", "", 10, 10)}
+		_, err := cluster.LeastLoaded{}.Choose(ctx, candidates, cluster.Request{})
+		assert.IsType(t, errors.InternalError{}, errs.Cause(err))
+	})
+}
+
+func TestRoundRobin(t *testing.T) {
+	resource.Require(t, resource.UnitTest)
+	ctx := context.Background()
+	candidates := []cluster.Candidate{
+		candidate("https://api.a", "", 0, 0),
+		candidate("https://api.b", "", 10, 10),
+		candidate("https://api.c", "", 0, 0),
+	}
+
+	chosen, err := cluster.RoundRobin{}.Choose(ctx, candidates, cluster.Request{})
+	require.NoError(t, err)
+	assert.Equal(t, "https://api.a", chosen.URL)
+	// the full cluster is skipped
+	chosen, err = cluster.RoundRobin{}.Choose(ctx, candidates, cluster.Request{LatestCluster: "https://api.a"})
+	require.NoError(t, err)
+	assert.Equal(t, "https://api.c", chosen.URL)
+	chosen, err = cluster.RoundRobin{}.Choose(ctx, candidates, cluster.Request{LatestCluster: "https://api.c"})
+	require.NoError(t, err)
+	assert.Equal(t, "https://api.a", chosen.URL)
+}
+
+func TestPerRegion(t *testing.T) {
+	resource.Require(t, resource.UnitTest)
+	ctx := context.Background()
+	candidates := []cluster.Candidate{
+		candidate("https://api.east-1", "us-east", 0, 10),
+		candidate("https://api.east-2", "us-east", 0, 5),
+		candidate("https://api.west-1", "us-west", 0, 1),
+	}
+	strategy := cluster.PerRegion{DefaultRegion: "us-east"}
+
+	chosen, err := strategy.Choose(ctx, candidates, cluster.Request{})
+	require.NoError(t, err)
+	assert.Equal(t, "https://api.east-2", chosen.URL)
+	chosen, err = strategy.Choose(ctx, candidates, cluster.Request{Region: "us-west"})
+	require.NoError(t, err)
+	assert.Equal(t, "https://api.west-1", chosen.URL)
+	_, err = strategy.Choose(ctx, candidates, cluster.Request{Region: "eu-west"})
+	assert.IsType(t, errors.BadParameterError{}, errs.Cause(err))
+	// without default region
+	chosen, err = cluster.PerRegion{}.Choose(ctx, candidates, cluster.Request{})
+	require.NoError(t, err)
+	assert.Equal(t, "https://api.west-1", chosen.URL)
+}
+
+func TestNewStrategy(t *testing.T) {
+	resource.Require(t, resource.UnitTest)
+
+	strategy, err := cluster.NewStrategy(cluster.StrategyRoundRobin, "")
+	require.NoError(t, err)
+	assert.IsType(t, cluster.RoundRobin{}, strategy)
+	_, err = cluster.NewStrategy("random", "")
+	assert.IsType(t, errors.BadParameterError{}, errs.Cause(err))
+}
@@ -4,7 +4,9 @@ import (
 	"bytes"
 	"fmt"
 	"os"
+	"regexp"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/fabric8-services/fabric8-auth/rest"
@@ -44,6 +46,7 @@ const (
 	varPostgresConnectionRetrySleep         = "postgres.connection.retrysleep"
 	varPostgresConnectionMaxIdle            = "postgres.connection.maxidle"
 	varPostgresConnectionMaxOpen            = "postgres.connection.maxopen"
+	varPostgresMigrationLockTimeout         = "postgres.migration.locktimeout"
 	varHTTPAddress                          = "http.address"
 	varMetricsHTTPAddress                   = "metrics.http.address"
 	varDeveloperModeEnabled                 = "developer.mode.enabled"
@@ -85,11 +88,45 @@ const (
 	varValidRedirectURLs                    = "redirect.valid"
 	varLogLevel                             = "log.level"
 	varLogJSON                              = "log.json"
+	varLogRedactFields                      = "log.redact.fields"
+	varLogRedactHeaders                     = "log.redact.headers"
 	varWITDomainPrefix                      = "wit.domain.prefix"
 	varWITURL                               = "wit.url"
 
 	varTenantServiceURL = "tenant.serviceurl"
 
+	varHealthCheckTimeout  = "health.check.timeout"
+	varHealthCheckCacheTTL = "health.check.cachettl"
+
+	varRateLimitEnabled        = "ratelimit.enabled"
+	varRateLimitStore          = "ratelimit.store"
+	varRateLimitActions        = "ratelimit.actions"
+	varRateLimitTrustedProxies = "ratelimit.trustedproxies"
+
+	varOutboxDispatchInterval  = "outbox.dispatch.interval"
+	varOutboxDispatchBatchSize = "outbox.dispatch.batchsize"
+	varOutboxMaxAttempts       = "outbox.maxattempts"
+	varOutboxBackoffMin        = "outbox.backoff.min"
+	varOutboxBackoffMax        = "outbox.backoff.max"
+
+	varWebhookDeliveryTimeout     = "webhook.delivery.timeout"
+	varWebhookAllowPrivateTargets = "webhook.allow.private.targets"
+
+	varInvitationExpiry = "invitation.expiry"
+
+	varRoleExpirySweepInterval  = "roleexpiry.sweep.interval"
+	varRoleExpirySweepBatchSize = "roleexpiry.sweep.batchsize"
+
+	varClusterPlacementStrategy = "cluster.placement.strategy"
+	varClusterPlacementRegion   = "cluster.placement.region"
+	varClusterAutoLinkClients   = "cluster.autolink.clients"
+
+	varTracingFile        = "tracing.file"
+	varTracingSampleRatio = "tracing.sampleratio"
+
+	varVaultAddress = "vault.address"
+	varVaultToken   = "vault.token"
+
 	varKeycloakTestsDisabled = "keycloak.tests.disabled"
 )
 
@@ -112,11 +149,16 @@ type ServiceAccount struct {
 type OSOCluster struct {
 	Name                   string `mapstructure:"name"`
 	URL                    string `mapstructure:"url"`
+	ConsoleURL             string `mapstructure:"console-url"`
 	ServiceAccountToken    string `mapstructure:"service-account-token"`
 	TokenProviderID        string `mapstructure:"token-provider-id"`
 	AuthClientID           string `mapstructure:"auth-client-id"`
 	AuthClientSecret       string `mapstructure:"auth-client-secret"`
 	AuthClientDefaultScope string `mapstructure:"auth-client-default-scope"`
+	// Capacity is the max number of users provisioned to the cluster. Unlimited if not set.
+	Capacity int `mapstructure:"capacity"`
+	// Region is the region the cluster is located in, used to place the new users
+	Region string `mapstructure:"region"`
 }
 
 // ConfigurationData encapsulates the Viper configuration object which stores the configuration data in-memory.
@@ -124,19 +166,46 @@ type ConfigurationData struct {
 	// Main Configuration
 	v *viper.Viper
 
+	// Paths of the configuration files. Used to reload the configuration.
+	mainConfigFile           string
+	serviceAccountConfigFile string
+	osoClusterConfigFile     string
+
+	// The part of the configuration which can be reloaded without restarting the service.
+	// Guarded by mux.
+	reloadable *reloadableConfig
+	mux        sync.RWMutex
+
+	defaultConfigurationError error
+}
+
+// reloadableConfig holds the settings which are swapped when the configuration is reloaded
+type reloadableConfig struct {
 	// Service Account Configuration is a map of service accounts where the key == the service account ID
 	sa map[string]ServiceAccount
 
 	// OSO Cluster Configuration is a map of clusters where the key == the OSO cluster API URL
 	clusters map[string]OSOCluster
+	// clusterSeeds are the same clusters with the references to their secrets left unresolved
+	clusterSeeds map[string]OSOCluster
 
-	defaultConfigurationError error
+	logLevel                  string
+	cacheControlUsers         string
+	cacheControlCollaborators string
+	cacheControlUser          string
+	validRedirectURLs         string
+	// Rate limits by lower case action name, e.g. "token.exchange"
+	rateLimits map[string]ActionRateLimits
 }
 
-// NewConfigurationData creates a configuration reader object using configurable configuration file paths
+// NewConfigurationData creates a configuration reader object using configurable configuration file paths.
+// An error is returned if one of the configuration files can't be read or if the reloadable settings are invalid.
 func NewConfigurationData(mainConfigFile string, serviceAccountConfigFile string, osoClusterConfigFile string) (*ConfigurationData, error) {
 	c := ConfigurationData{
-		v: viper.New(),
+		v:                        viper.New(),
+		mainConfigFile:           mainConfigFile,
+		serviceAccountConfigFile: serviceAccountConfigFile,
+		osoClusterConfigFile:     osoClusterConfigFile,
 	}
 
 	// Set up the main configuration
@@ -155,8 +224,21 @@ func NewConfigurationData(mainConfigFile string, serviceAccountConfigFile string
 		}
 	}
 
+	// Secrets can be references to files or to a secret store
+	if err := c.resolveSecrets(); err != nil {
+		return nil, err
+	}
+
+	rateLimits, err := c.readRateLimits()
+	if err != nil {
+		return nil, err
+	}
+
 	// Set up the service account configuration (stored in a separate config file)
 	saViper, defaultConfigErrorMsg, err := readFromJSONFile(serviceAccountConfigFile, defaultServiceAccountConfigPath, serviceAccountConfigFileName)
+	if err != nil {
+		return nil, err
+	}
 	c.appendDefaultConfigErrorMessage(defaultConfigErrorMsg)
 
 	var saConf serviceAccountConfig
@@ -164,13 +246,16 @@ func NewConfigurationData(mainConfigFile string, serviceAccountConfigFile string
 	if err != nil {
 		return nil, err
 	}
-	c.sa = map[string]ServiceAccount{}
+	sa := map[string]ServiceAccount{}
 	for _, account := range saConf.Accounts {
-		c.sa[account.ID] = account
+		sa[account.ID] = account
 	}
 
 	// Set up the OSO cluster configuration (stored in a separate config file)
 	clusterViper, defaultConfigErrorMsg, err := readFromJSONFile(osoClusterConfigFile, defaultOsoClusterConfigPath, osoClusterConfigFileName)
+	if err != nil {
+		return nil, err
+	}
 	c.appendDefaultConfigErrorMessage(defaultConfigErrorMsg)
 
 	var clusterConf osoClusterConfig
@@ -178,9 +263,34 @@ func NewConfigurationData(mainConfigFile string, serviceAccountConfigFile string
 	if err != nil {
 		return nil, err
 	}
-	c.clusters = map[string]OSOCluster{}
+	clusters := map[string]OSOCluster{}
+	clusterSeeds := map[string]OSOCluster{}
 	for _, cluster := range clusterConf.Clusters {
-		c.clusters[cluster.URL] = cluster
+		clusterSeeds[cluster.URL] = cluster
+		if !IsSecretReference(cluster.ServiceAccountToken) || !IsSecretReference(cluster.AuthClientSecret) {
+			msg := fmt.Sprintf("the secrets of OSO cluster %s are not secret references and are stored in the cluster registry as is", cluster.Name)
+			c.appendDefaultConfigErrorMessage(&msg)
+		}
+		if err := c.resolveClusterSecrets(&cluster); err != nil {
+			return nil, err
+		}
+		clusters[cluster.URL] = cluster
+	}
+
+	c.reloadable = &reloadableConfig{
+		sa:                        sa,
+		clusters:                  clusters,
+		clusterSeeds:              clusterSeeds,
+		logLevel:                  c.v.GetString(varLogLevel),
+		cacheControlUsers:         c.v.GetString(varCacheControlUsers),
+		cacheControlCollaborators: c.v.GetString(varCacheControlCollaborators),
+		cacheControlUser:          c.v.GetString(varCacheControlUser),
+		validRedirectURLs:         c.validRedirectURLs(),
+		rateLimits:                rateLimits,
+	}
+	// the same checks as on reload, so a configuration which would be rejected on reload can't be used at startup either
+	if err := c.reloadable.validate(); err != nil {
+		return nil, errors.Wrap(err, "invalid configuration")
 	}
 
 	// Check sensitive default configuration
@@ -213,6 +323,10 @@ func NewConfigurationData(mainConfigFile string, serviceAccountConfigFile string
 		msg := "TLS verification disabled"
 		c.appendDefaultConfigErrorMessage(&msg)
 	}
+	if c.IsWebhookPrivateTargetAllowed() {
+		msg := "webhooks may target private addresses"
+		c.appendDefaultConfigErrorMessage(&msg)
+	}
 	if c.GetValidRedirectURLs() == ".*" {
 		msg := "no restrictions for valid redirect URLs"
 		c.appendDefaultConfigErrorMessage(&msg)
@@ -317,14 +431,56 @@ func (c *ConfigurationData) DefaultConfigurationError() error {
 	return c.defaultConfigurationError
 }
 
+// current returns the reloadable part of the configuration which is currently in use
+func (c *ConfigurationData) current() *reloadableConfig {
+	c.mux.RLock()
+	defer c.mux.RUnlock()
+	return c.reloadable
+}
+
+// validate checks the reloadable configuration, so an invalid configuration file
+// never replaces the configuration in use
+func (r *reloadableConfig) validate() error {
+	if _, err := log.ParseLevel(r.logLevel); err != nil {
+		return errors.Wrapf(err, "invalid log level")
+	}
+	if _, err := regexp.Compile(r.validRedirectURLs); err != nil {
+		return errors.Wrapf(err, "invalid regex of valid redirect URLs")
+	}
+	for id, account := range r.sa {
+		if id == "" || account.Name == "" {
+			return errors.Errorf("service account ID and name must be set (ID: '%s', name: '%s')", id, account.Name)
+		}
+		if len(account.Secrets) == 0 {
+			return errors.Errorf("no secrets set for service account %s", account.Name)
+		}
+	}
+	for url, cluster := range r.clusters {
+		if url == "" || cluster.Name == "" {
+			return errors.Errorf("OSO cluster URL and name must be set (URL: '%s', name: '%s')", url, cluster.Name)
+		}
+		if cluster.TokenProviderID == "" || cluster.AuthClientID == "" {
+			return errors.Errorf("token provider ID and auth client ID must be set for OSO cluster %s", cluster.Name)
+		}
+	}
+	return nil
+}
+
 // GetServiceAccounts returns a map of service account configurations by service account ID
 func (c *ConfigurationData) GetServiceAccounts() map[string]ServiceAccount {
-	return c.sa
+	return c.current().sa
 }
 
 // GetOSOClusters returns a map of OSO cluster configurations by cluster API URL
 func (c *ConfigurationData) GetOSOClusters() map[string]OSOCluster {
-	return c.clusters
+	return c.current().clusters
+}
+
+// GetOSOClusterSeeds returns the map of OSO cluster configurations by cluster API URL used to seed the cluster registry.
+// Unlike GetOSOClusters, the references to the secrets of the clusters are not resolved, so the registry
+// never stores the secrets themselves when they are references.
+func (c *ConfigurationData) GetOSOClusterSeeds() map[string]OSOCluster {
+	return c.current().clusterSeeds
 }
 
 // GetDefaultConfigurationFile returns the default configuration file.
@@ -362,6 +518,9 @@ func (c *ConfigurationData) setConfigDefaults() {
 	// Timeout of a transaction in minutes
 	c.v.SetDefault(varPostgresTransactionTimeout, time.Duration(5*time.Minute))
 
+	// Max duration to wait for the database migration lock held by another instance
+	c.v.SetDefault(varPostgresMigrationLockTimeout, time.Duration(5*time.Minute))
+
 	//-----
 	// HTTP
 	//-----
@@ -369,6 +528,59 @@ func (c *ConfigurationData) setConfigDefaults() {
 	c.v.SetDefault(varMetricsHTTPAddress, "0.0.0.0:8089")
 	c.v.SetDefault(varHeaderMaxLength, defaultHeaderMaxLength)
 
+	//--------------
+	// Health checks
+	//--------------
+	c.v.SetDefault(varHealthCheckTimeout, time.Duration(5*time.Second))
+	c.v.SetDefault(varHealthCheckCacheTTL, time.Duration(10*time.Second))
+
+	//--------------
+	// Rate limiting
+	//--------------
+	c.v.SetDefault(varRateLimitEnabled, true)
+	c.v.SetDefault(varRateLimitStore, "memory")
+	c.v.SetDefault(varRateLimitTrustedProxies, 0)
+
+	//-------
+	// Outbox
+	//-------
+	c.v.SetDefault(varOutboxDispatchInterval, time.Duration(5*time.Second))
+	c.v.SetDefault(varOutboxDispatchBatchSize, 20)
+	c.v.SetDefault(varOutboxMaxAttempts, 12)
+	c.v.SetDefault(varOutboxBackoffMin, time.Duration(10*time.Second))
+	c.v.SetDefault(varOutboxBackoffMax, time.Duration(time.Hour))
+
+	//---------
+	// Webhooks
+	//---------
+	c.v.SetDefault(varWebhookDeliveryTimeout, time.Duration(10*time.Second))
+	c.v.SetDefault(varWebhookAllowPrivateTargets, false)
+
+	//------------
+	// Invitations
+	//------------
+	c.v.SetDefault(varInvitationExpiry, time.Duration(7*24*time.Hour))
+
+	//------------
+	// Role expiry
+	//------------
+	c.v.SetDefault(varRoleExpirySweepInterval, time.Duration(time.Minute))
+	c.v.SetDefault(varRoleExpirySweepBatchSize, 100)
+
+	//------------------
+	// Cluster placement
+	//------------------
+	c.v.SetDefault(varClusterPlacementStrategy, "least-loaded")
+	// The OpenShift account is linked at login for the browser clients only
+	c.v.SetDefault(varClusterAutoLinkClients, []string{"browser"})
+
+	//--------
+	// Tracing
+	//--------
+	// No tracing file by default: traces are not recorded
+	c.v.SetDefault(varTracingFile, "")
+	c.v.SetDefault(varTracingSampleRatio, 1.0)
+
 	//-----
 	// Misc
 	//-----
@@ -459,6 +671,12 @@ func (c *ConfigurationData) GetPostgresTransactionTimeout() time.Duration {
 	return c.v.GetDuration(varPostgresTransactionTimeout)
 }
 
+// GetPostgresMigrationLockTimeout returns the max duration to wait for the cluster-wide
+// migration lock which may be held by another auth instance migrating the same database
+func (c *ConfigurationData) GetPostgresMigrationLockTimeout() time.Duration {
+	return c.v.GetDuration(varPostgresMigrationLockTimeout)
+}
+
 // GetPostgresConnectionMaxIdle returns the number of connections that should be keept alive in the database connection pool at
 // any given time. -1 represents no restrictions/default behavior
 func (c *ConfigurationData) GetPostgresConnectionMaxIdle() int {
@@ -496,6 +714,130 @@ func (c *ConfigurationData) GetMetricsHTTPAddress() string {
 	return c.v.GetString(varMetricsHTTPAddress)
 }
 
+// GetHealthCheckTimeout returns the max duration of a single dependency check
+func (c *ConfigurationData) GetHealthCheckTimeout() time.Duration {
+	return c.v.GetDuration(varHealthCheckTimeout)
+}
+
+// GetHealthCheckCacheTTL returns the duration the results of the dependency checks are cached for
+func (c *ConfigurationData) GetHealthCheckCacheTTL() time.Duration {
+	return c.v.GetDuration(varHealthCheckCacheTTL)
+}
+
+// IsRateLimitEnabled returns true if the requests to the rate limited actions are throttled
+func (c *ConfigurationData) IsRateLimitEnabled() bool {
+	return c.v.GetBool(varRateLimitEnabled)
+}
+
+// GetRateLimitStore returns where the rate limit buckets are stored: "memory" for limits per replica
+// or "postgres" for limits shared across all the replicas of the service
+func (c *ConfigurationData) GetRateLimitStore() string {
+	return c.v.GetString(varRateLimitStore)
+}
+
+// GetRateLimitTrustedProxies returns the number of reverse proxies in front of the service which append
+// the address of their client to the X-Forwarded-For header. The header is ignored if it's zero.
+func (c *ConfigurationData) GetRateLimitTrustedProxies() int {
+	return c.v.GetInt(varRateLimitTrustedProxies)
+}
+
+// GetRateLimits returns the rate limits of the given action, e.g. "token.exchange".
+// The action name is case insensitive. Returns false if the action is not rate limited.
+func (c *ConfigurationData) GetRateLimits(action string) (ActionRateLimits, bool) {
+	limits, found := c.current().rateLimits[strings.ToLower(action)]
+	return limits, found
+}
+
+// GetOutboxDispatchInterval returns the delay between two runs of the outbox dispatcher
+func (c *ConfigurationData) GetOutboxDispatchInterval() time.Duration {
+	return c.v.GetDuration(varOutboxDispatchInterval)
+}
+
+// GetOutboxDispatchBatchSize returns the max number of outbox events delivered in a single run of the dispatcher
+func (c *ConfigurationData) GetOutboxDispatchBatchSize() int {
+	return c.v.GetInt(varOutboxDispatchBatchSize)
+}
+
+// GetOutboxMaxAttempts returns the number of failed deliveries after which an outbox event is dead-lettered
+func (c *ConfigurationData) GetOutboxMaxAttempts() int {
+	return c.v.GetInt(varOutboxMaxAttempts)
+}
+
+// GetOutboxBackoffMin returns the delay before the first retry of a failed outbox event delivery.
+// The delay doubles after each failed attempt.
+func (c *ConfigurationData) GetOutboxBackoffMin() time.Duration {
+	return c.v.GetDuration(varOutboxBackoffMin)
+}
+
+// GetOutboxBackoffMax returns the max delay between two attempts to deliver an outbox event
+func (c *ConfigurationData) GetOutboxBackoffMax() time.Duration {
+	return c.v.GetDuration(varOutboxBackoffMax)
+}
+
+// GetWebhookDeliveryTimeout returns the max time to get the response of the URL of a webhook subscription
+func (c *ConfigurationData) GetWebhookDeliveryTimeout() time.Duration {
+	return c.v.GetDuration(varWebhookDeliveryTimeout)
+}
+
+// IsWebhookPrivateTargetAllowed returns true if the URLs of the webhook subscriptions may target loopback, private
+// or link-local addresses, e.g. to test the webhooks locally. Never enable it in production, since the service accounts
+// could reach the internal services (like the cloud metadata endpoints) through the webhooks otherwise.
+func (c *ConfigurationData) IsWebhookPrivateTargetAllowed() bool {
+	return c.v.GetBool(varWebhookAllowPrivateTargets)
+}
+
+// GetInvitationExpiry returns the duration after which the invitations to collaborate on a space expire
+func (c *ConfigurationData) GetInvitationExpiry() time.Duration {
+	return c.v.GetDuration(varInvitationExpiry)
+}
+
+// GetRoleExpirySweepInterval returns the delay between two runs of the sweeper which removes
+// the expired role assignments and space collaborators
+func (c *ConfigurationData) GetRoleExpirySweepInterval() time.Duration {
+	return c.v.GetDuration(varRoleExpirySweepInterval)
+}
+
+// GetRoleExpirySweepBatchSize returns the max number of expired role assignments and space collaborators
+// removed in a single run of the sweeper
+func (c *ConfigurationData) GetRoleExpirySweepBatchSize() int {
+	return c.v.GetInt(varRoleExpirySweepBatchSize)
+}
+
+// GetClusterPlacementStrategy returns the name of the strategy which chooses the cluster the new users
+// are provisioned to: "least-loaded", "round-robin" or "per-region"
+func (c *ConfigurationData) GetClusterPlacementStrategy() string {
+	return c.v.GetString(varClusterPlacementStrategy)
+}
+
+// GetClusterPlacementRegion returns the region the new users are provisioned to by the "per-region"
+// placement strategy if no region is requested for them
+func (c *ConfigurationData) GetClusterPlacementRegion() string {
+	return c.v.GetString(varClusterPlacementRegion)
+}
+
+// GetClusterAutoLinkClients returns the clients for which the OpenShift account of the cluster the user is
+// provisioned to is linked at login if it isn't linked yet. A client is identified by the api_client
+// param of the login request, or "browser" if no api_client is given. "*" matches all the clients.
+func (c *ConfigurationData) GetClusterAutoLinkClients() []string {
+	return c.v.GetStringSlice(varClusterAutoLinkClients)
+}
+
+// IsTracingEnabled returns true if the traces are recorded, i.e. if a tracing file is set
+func (c *ConfigurationData) IsTracingEnabled() bool {
+	return c.GetTracingFile() != ""
+}
+
+// GetTracingFile returns the path of the file the traces are written to as OTLP/JSON
+func (c *ConfigurationData) GetTracingFile() string {
+	return c.v.GetString(varTracingFile)
+}
+
+// GetTracingSampleRatio returns the ratio of the traces started by the service which are recorded.
+// Traces started by a caller are recorded if the caller sampled them.
+func (c *ConfigurationData) GetTracingSampleRatio() float64 {
+	return c.v.GetFloat64(varTracingSampleRatio)
+}
+
 // GetHeaderMaxLength returns the max length of HTTP headers allowed in the system
 // For example it can be used to limit the size of bearer tokens returned by the api service
 func (c *ConfigurationData) GetHeaderMaxLength() int64 {
@@ -516,19 +858,19 @@ func (c *ConfigurationData) GetMaxUsersListLimit() int {
 // GetCacheControlUsers returns the value to set in the "Cache-Control" HTTP response header
 // when returning users.
 func (c *ConfigurationData) GetCacheControlUsers() string {
-	return c.v.GetString(varCacheControlUsers)
+	return c.current().cacheControlUsers
 }
 
 // GetCacheControlCollaborators returns the value to set in the "Cache-Control" HTTP response header
 // when returning collaborators.
 func (c *ConfigurationData) GetCacheControlCollaborators() string {
-	return c.v.GetString(varCacheControlCollaborators)
+	return c.current().cacheControlCollaborators
 }
 
 // GetCacheControlUser returns the value to set in the "Cache-Control" HTTP response header
 // when data for the current user.
 func (c *ConfigurationData) GetCacheControlUser() string {
-	return c.v.GetString(varCacheControlUser)
+	return c.current().cacheControlUser
 }
 
 // GetDeprecatedServiceAccountPrivateKey returns the deprecated service account private key (if any) and its ID
@@ -549,6 +891,7 @@ func (c *ConfigurationData) GetGitHubClientID() string {
 }
 
 // GetGitHubClientSecret return GitHub client secret used to link GitHub accounts
+// The secret may be set as a reference to a file ("file:///path") or to a Vault secret ("vault://path#key").
 func (c *ConfigurationData) GetGitHubClientSecret() string {
 	return c.v.GetString(varGitHubClientSecret)
 }
@@ -577,6 +920,7 @@ func (c *ConfigurationData) GetNotApprovedRedirect() string {
 
 // GetKeycloakSecret returns the keycloak client secret (as set via config file or environment variable)
 // that is used to make authorized Keycloak API Calls.
+// The secret may be set as a reference to a file ("file:///path") or to a Vault secret ("vault://path#key").
 func (c *ConfigurationData) GetKeycloakSecret() string {
 	return c.v.GetString(varKeycloakSecret)
 }
@@ -830,6 +1174,9 @@ func (c *ConfigurationData) getKeycloakURL(req *goa.RequestData, path string) (s
 }
 
 func (c *ConfigurationData) calculateWITURL(req *goa.RequestData) (string, error) {
+	if req == nil {
+		return "", errors.New("unable to calculate the WIT URL without a request")
+	}
 	scheme := "http"
 	if req.URL != nil && req.URL.Scheme == "https" { // isHTTPS
 		scheme = "https"
@@ -850,7 +1197,7 @@ func (c *ConfigurationData) calculateWITURL(req *goa.RequestData) (string, error
 
 // GetLogLevel returns the logging level (as set via config file or environment variable)
 func (c *ConfigurationData) GetLogLevel() string {
-	return c.v.GetString(varLogLevel)
+	return c.current().logLevel
 }
 
 // IsLogJSON returns if we should log json format (as set via config file or environment variable)
@@ -864,10 +1211,26 @@ func (c *ConfigurationData) IsLogJSON() bool {
 	return true
 }
 
+// GetLogRedactFields returns the names of the log fields, request params and payload attributes
+// which are redacted in addition to the default ones (passwords, secrets and tokens)
+func (c *ConfigurationData) GetLogRedactFields() []string {
+	return c.v.GetStringSlice(varLogRedactFields)
+}
+
+// GetLogRedactHeaders returns the names of the request headers which are redacted in the logs
+// in addition to the default ones (Authorization, Cookie, ...)
+func (c *ConfigurationData) GetLogRedactHeaders() []string {
+	return c.v.GetStringSlice(varLogRedactHeaders)
+}
+
 // GetValidRedirectURLs returns the RegEx of valid redirect URLs for auth requests
 // If AUTH_REDIRECT_VALID is not set then in Dev Mode all redirects allowed - *
 // Otherwise only *.openshift.io URLs are considered valid
 func (c *ConfigurationData) GetValidRedirectURLs() string {
+	return c.current().validRedirectURLs
+}
+
+func (c *ConfigurationData) validRedirectURLs() string {
 	if c.v.IsSet(varValidRedirectURLs) {
 		return c.v.GetString(varValidRedirectURLs)
 	}
@@ -0,0 +1,71 @@
+package configuration
+
+import (
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// RateLimit is a token bucket: up to Burst requests are accepted at once
+// and the bucket is refilled at PerMinute requests per minute
+type RateLimit struct {
+	PerMinute float64 `mapstructure:"per_minute"`
+	Burst     int     `mapstructure:"burst"`
+}
+
+// ActionRateLimits represents the rate limits of a goa action, e.g. "token.exchange".
+// The requests are limited per client IP, per service account ID (the client_id param of the request)
+// and per identity (the subject of the token of the request). Nil limits are not enforced.
+type ActionRateLimits struct {
+	Action         string     `mapstructure:"action"`
+	IP             *RateLimit `mapstructure:"ip"`
+	ServiceAccount *RateLimit `mapstructure:"service_account"`
+	Identity       *RateLimit `mapstructure:"identity"`
+}
+
+// defaultRateLimits protect the service account secrets and the user logins against brute force attacks.
+// Used if ratelimit.actions is not set.
+var defaultRateLimits = []ActionRateLimits{
+	{
+		Action:         "token.exchange",
+		IP:             &RateLimit{PerMinute: 60, Burst: 20},
+		ServiceAccount: &RateLimit{PerMinute: 30, Burst: 10},
+	},
+	{
+		Action: "token.refresh",
+		IP:     &RateLimit{PerMinute: 120, Burst: 30},
+	},
+	{
+		Action: "login.login",
+		IP:     &RateLimit{PerMinute: 60, Burst: 20},
+	},
+	{
+		Action:   "searchcontroller.users",
+		IP:       &RateLimit{PerMinute: 300, Burst: 60},
+		Identity: &RateLimit{PerMinute: 120, Burst: 30},
+	},
+}
+
+// readRateLimits reads the rate limits of the ratelimit.actions list
+func (c *ConfigurationData) readRateLimits() (map[string]ActionRateLimits, error) {
+	limits := defaultRateLimits
+	if c.v.IsSet(varRateLimitActions) {
+		limits = nil
+		if err := c.v.UnmarshalKey(varRateLimitActions, &limits); err != nil {
+			return nil, errors.Wrapf(err, "failed to read %s", varRateLimitActions)
+		}
+	}
+	result := make(map[string]ActionRateLimits, len(limits))
+	for _, l := range limits {
+		if l.Action == "" {
+			return nil, errors.Errorf("invalid %s: action name is missing", varRateLimitActions)
+		}
+		for _, limit := range []*RateLimit{l.IP, l.ServiceAccount, l.Identity} {
+			if limit != nil && (limit.PerMinute <= 0 || limit.Burst < 1) {
+				return nil, errors.Errorf("invalid %s: the rate limits of %s must be positive", varRateLimitActions, l.Action)
+			}
+		}
+		result[strings.ToLower(l.Action)] = l
+	}
+	return result, nil
+}
@@ -0,0 +1,159 @@
+package configuration
+
+import (
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// reloadDelay is the time to wait after a configuration file changed before reloading the configuration.
+// Editors and Kubernetes config map updates usually trigger several file events in a row.
+const reloadDelay = time.Second
+
+// Reload re-reads the main, the service account and the OSO cluster configuration files
+// and swaps the reloadable settings: service accounts, OSO clusters, log level,
+// cache control values, the valid redirect URLs and the rate limits of the actions.
+// The new configuration is validated first, with the same checks as at startup. If it's invalid then
+// the configuration in use is kept and the validation error is returned.
+// All the other settings require a restart of the service.
+// The state the service derives from the reloadable settings, like the cluster registry and the health checks
+// of the clusters, is not refreshed by Reload: it's up to the listeners given to Watch.
+func (c *ConfigurationData) Reload() error {
+	newConfig, err := NewConfigurationData(c.mainConfigFile, c.serviceAccountConfigFile, c.osoClusterConfigFile)
+	if err != nil {
+		return errors.Wrap(err, "failed to reload the configuration")
+	}
+
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	c.reloadable = newConfig.reloadable
+	return nil
+}
+
+// Watch reloads the configuration whenever one of the configuration files changes
+// or the process receives SIGHUP. The given listeners are called after every successful reload,
+// e.g. to apply the new log level.
+// Call the returned function to stop watching.
+func (c *ConfigurationData) Watch(listeners ...func()) (func(), error) {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create the configuration file watcher")
+	}
+	files := c.configFiles()
+	// Watch the directories rather than the files themselves because files replaced
+	// by a rename (like mounted Kubernetes config maps and secrets) are not tracked anymore otherwise
+	dirs := map[string]bool{}
+	for _, file := range files {
+		dirs[filepath.Dir(file)] = true
+	}
+	for dir := range dirs {
+		if err := watcher.Add(dir); err != nil {
+			watcher.Close()
+			return nil, errors.Wrapf(err, "failed to watch the configuration directory %s", dir)
+		}
+	}
+
+	sighup := make(chan os.Signal, 1)
+	signal.Notify(sighup, syscall.SIGHUP)
+	done := make(chan struct{})
+
+	go func() {
+		var reload <-chan time.Time
+		for {
+			select {
+			case <-done:
+				return
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				if isConfigFileEvent(event, files) {
+					reload = time.After(reloadDelay)
+				}
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				log.WithFields(map[string]interface{}{
+					"err": err,
+				}).Errorln("Error while watching the configuration files")
+			case <-sighup:
+				log.Infoln("SIGHUP received. Reloading the configuration")
+				c.reloadAndNotify(listeners)
+			case <-reload:
+				reload = nil
+				log.WithFields(map[string]interface{}{
+					"files": files,
+				}).Infoln("Configuration files changed. Reloading the configuration")
+				c.reloadAndNotify(listeners)
+			}
+		}
+	}()
+
+	return func() {
+		signal.Stop(sighup)
+		close(done)
+		watcher.Close()
+	}, nil
+}
+
+func (c *ConfigurationData) reloadAndNotify(listeners []func()) {
+	if err := c.Reload(); err != nil {
+		log.WithFields(map[string]interface{}{
+			"err": err,
+		}).Errorln("Failed to reload the configuration. Keeping the current configuration")
+		return
+	}
+	log.Infoln("Configuration reloaded")
+	for _, listener := range listeners {
+		listener()
+	}
+}
+
+// configFiles returns the paths of the configuration files in use
+func (c *ConfigurationData) configFiles() []string {
+	var files []string
+	if c.mainConfigFile != "" {
+		files = append(files, c.mainConfigFile)
+	}
+	for _, jsonFile := range []struct {
+		path        string
+		defaultPath string
+	}{
+		{c.serviceAccountConfigFile, defaultServiceAccountConfigPath},
+		{c.osoClusterConfigFile, defaultOsoClusterConfigPath},
+	} {
+		file := jsonFile.path
+		if file == "" {
+			// the default file is used if it exists
+			file, _ = pathExists(jsonFile.defaultPath)
+		}
+		if file != "" {
+			files = append(files, file)
+		}
+	}
+	return files
+}
+
+// isConfigFileEvent returns true if the event is about one of the given files.
+// Kubernetes updates mounted config maps and secrets by swapping the "..data" symlink
+// so events about such hidden entries are considered too.
+func isConfigFileEvent(event fsnotify.Event, files []string) bool {
+	name := filepath.Base(event.Name)
+	if strings.HasPrefix(name, "..") {
+		return true
+	}
+	for _, file := range files {
+		if filepath.Base(file) == name {
+			return true
+		}
+	}
+	return false
+}
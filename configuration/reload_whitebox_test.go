@@ -0,0 +1,123 @@
+package configuration
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/fabric8-services/fabric8-auth/resource"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	reloadTestServiceAccounts = `{
+    "accounts": [
+        {
+            "name":"fabric8-wit",
+            "id":"5dec5fdb-09e3-4453-b73f-5c828832b28e",
+            "secrets":["witsecret"]
+        }
+    ]
+}`
+	reloadTestServiceAccountsUpdated = `{
+    "accounts": [
+        {
+            "name":"fabric8-wit",
+            "id":"5dec5fdb-09e3-4453-b73f-5c828832b28e",
+            "secrets":["witsecret", "witsecret2"]
+        },
+        {
+            "name":"fabric8-tenant",
+            "id":"c211f1bd-17a7-4f8c-9f80-0917d167889d",
+            "secrets":["tenantsecret"]
+        }
+    ]
+}`
+	reloadTestServiceAccountsInvalid = `{
+    "accounts": [
+        {
+            "name":"fabric8-wit",
+            "id":"5dec5fdb-09e3-4453-b73f-5c828832b28e",
+            "secrets":[]
+        }
+    ]
+}`
+)
+
+func TestReloadConfiguration(t *testing.T) {
+	resource.Require(t, resource.UnitTest)
+
+	dir, err := ioutil.TempDir("", "auth-config")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+	mainConfigFile := filepath.Join(dir, "config.yaml")
+	saConfigFile := filepath.Join(dir, "service-account-secrets.conf")
+	require.Nil(t, ioutil.WriteFile(mainConfigFile, []byte("cachecontrol.users: max-age=2\n"), 0644))
+	require.Nil(t, ioutil.WriteFile(saConfigFile, []byte(reloadTestServiceAccounts), 0644))
+
+	c, err := NewConfigurationData(mainConfigFile, saConfigFile, "")
+	require.Nil(t, err)
+	require.Len(t, c.GetServiceAccounts(), 1)
+	require.Equal(t, "max-age=2", c.GetCacheControlUsers())
+
+	t.Run("valid configuration is swapped", func(t *testing.T) {
+		require.Nil(t, ioutil.WriteFile(mainConfigFile, []byte("cachecontrol.users: max-age=5\n"), 0644))
+		require.Nil(t, ioutil.WriteFile(saConfigFile, []byte(reloadTestServiceAccountsUpdated), 0644))
+
+		require.Nil(t, c.Reload())
+		assert.Len(t, c.GetServiceAccounts(), 2)
+		assert.Len(t, c.GetServiceAccounts()["5dec5fdb-09e3-4453-b73f-5c828832b28e"].Secrets, 2)
+		assert.Equal(t, "max-age=5", c.GetCacheControlUsers())
+	})
+
+	t.Run("rate limits are swapped", func(t *testing.T) {
+		require.Nil(t, ioutil.WriteFile(mainConfigFile, []byte("cachecontrol.users: max-age=5\nratelimit.actions:\n- action: token.exchange\n  ip: {per_minute: 10, burst: 5}\n"), 0644))
+
+		require.Nil(t, c.Reload())
+		limits, found := c.GetRateLimits("token.exchange")
+		require.True(t, found)
+		assert.Equal(t, 10.0, limits.IP.PerMinute)
+		_, found = c.GetRateLimits("login.login")
+		assert.False(t, found)
+	})
+
+	t.Run("invalid configuration is not swapped", func(t *testing.T) {
+		require.Nil(t, ioutil.WriteFile(mainConfigFile, []byte("cachecontrol.users: max-age=10\n"), 0644))
+		require.Nil(t, ioutil.WriteFile(saConfigFile, []byte(reloadTestServiceAccountsInvalid), 0644))
+
+		assert.NotNil(t, c.Reload())
+		assert.Len(t, c.GetServiceAccounts(), 2)
+		assert.Equal(t, "max-age=5", c.GetCacheControlUsers())
+	})
+
+	t.Run("invalid log level is not swapped", func(t *testing.T) {
+		require.Nil(t, ioutil.WriteFile(mainConfigFile, []byte("log.level: verbose\n"), 0644))
+		require.Nil(t, ioutil.WriteFile(saConfigFile, []byte(reloadTestServiceAccounts), 0644))
+
+		assert.NotNil(t, c.Reload())
+		assert.Len(t, c.GetServiceAccounts(), 2)
+	})
+}
+
+func TestNewConfigurationDataValidates(t *testing.T) {
+	resource.Require(t, resource.UnitTest)
+
+	dir, err := ioutil.TempDir("", "auth-config")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+	saConfigFile := filepath.Join(dir, "service-account-secrets.conf")
+
+	t.Run("invalid configuration is rejected at startup too", func(t *testing.T) {
+		require.Nil(t, ioutil.WriteFile(saConfigFile, []byte(reloadTestServiceAccountsInvalid), 0644))
+		_, err := NewConfigurationData("", saConfigFile, "")
+		assert.NotNil(t, err)
+	})
+
+	t.Run("missing configuration file is an error", func(t *testing.T) {
+		_, err := NewConfigurationData("", filepath.Join(dir, "missing.conf"), "")
+		assert.NotNil(t, err)
+	})
+}
@@ -0,0 +1,231 @@
+package configuration
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// SecretResolver resolves references to secrets which are stored outside of the configuration.
+// A reference is a URL, the scheme of the URL tells which resolver is used.
+// For example "file:///etc/fabric8/keycloak-secret" or "vault://secret/auth#keycloak".
+type SecretResolver interface {
+	Resolve(ref *url.URL) (string, error)
+}
+
+var (
+	secretResolvers = map[string]SecretResolver{
+		"file": FileSecretResolver{},
+	}
+	secretResolversLock = &sync.RWMutex{}
+)
+
+// RegisterSecretResolver registers the resolver used for the secret references with the given URL scheme.
+// A resolver registered for the "vault" scheme takes precedence over the Vault configured with vault.address.
+func RegisterSecretResolver(scheme string, resolver SecretResolver) {
+	secretResolversLock.Lock()
+	defer secretResolversLock.Unlock()
+	secretResolvers[scheme] = resolver
+}
+
+// UnregisterSecretResolver removes the resolver registered for the given URL scheme
+func UnregisterSecretResolver(scheme string) {
+	secretResolversLock.Lock()
+	defer secretResolversLock.Unlock()
+	delete(secretResolvers, scheme)
+}
+
+// FileSecretResolver resolves "file:///path/to/secret" references by reading the file,
+// e.g. a Kubernetes secret mounted into the pod. Trailing new lines are ignored.
+type FileSecretResolver struct{}
+
+// Resolve returns the content of the referenced file
+func (r FileSecretResolver) Resolve(ref *url.URL) (string, error) {
+	data, err := ioutil.ReadFile(ref.Path)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to read secret file %s", ref.Path)
+	}
+	return strings.TrimRight(string(data), "\r\n"), nil
+}
+
+// VaultSecretResolver resolves "vault://path#key" references using the HTTP API of a Vault server.
+// Both the KV version 1 and version 2 secret engines are supported.
+type VaultSecretResolver struct {
+	address string
+	token   string
+	client  *http.Client
+}
+
+// NewVaultSecretResolver creates a resolver for the Vault server running at the given address
+func NewVaultSecretResolver(address string, token string) *VaultSecretResolver {
+	return &VaultSecretResolver{
+		address: strings.TrimSuffix(address, "/"),
+		token:   token,
+		client:  &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// Resolve returns the value of the key (the URL fragment) of the secret stored at the given path
+func (r *VaultSecretResolver) Resolve(ref *url.URL) (string, error) {
+	path := vaultSecretPath(ref)
+	req, err := http.NewRequest("GET", fmt.Sprintf("%s/v1/%s", r.address, path), nil)
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+	req.Header.Set("X-Vault-Token", r.token)
+	res, err := r.client.Do(req)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to read the secret %s from Vault", path)
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return "", errors.Errorf("failed to read the secret %s from Vault. Response status: %s", path, res.Status)
+	}
+	var secret struct {
+		Data map[string]interface{} `json:"data"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&secret); err != nil {
+		return "", errors.Wrapf(err, "failed to decode the secret %s read from Vault", path)
+	}
+	data := secret.Data
+	// KV version 2 wraps the secret in another data object next to its metadata
+	if nested, ok := data["data"].(map[string]interface{}); ok {
+		if _, ok := data["metadata"]; ok {
+			data = nested
+		}
+	}
+	return secretValue(data, path, ref.Fragment)
+}
+
+// LocalVaultSecretResolver is a stand-in for Vault which resolves "vault://path#key" references
+// from JSON files stored in a local directory: the reference "vault://secret/auth#keycloak" is resolved
+// to the "keycloak" value of the <dir>/secret/auth.json file.
+// It's meant to be used for tests and local development.
+type LocalVaultSecretResolver struct {
+	dir string
+}
+
+// NewLocalVaultSecretResolver creates a resolver for secrets stored in the given directory
+func NewLocalVaultSecretResolver(dir string) *LocalVaultSecretResolver {
+	return &LocalVaultSecretResolver{dir: dir}
+}
+
+// Resolve returns the value of the key (the URL fragment) of the secret stored at the given path
+func (r *LocalVaultSecretResolver) Resolve(ref *url.URL) (string, error) {
+	path := vaultSecretPath(ref)
+	file := filepath.Join(r.dir, filepath.FromSlash(path)+".json")
+	content, err := ioutil.ReadFile(file)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to read the secret %s", path)
+	}
+	var data map[string]interface{}
+	if err := json.Unmarshal(content, &data); err != nil {
+		return "", errors.Wrapf(err, "failed to decode the secret %s", path)
+	}
+	return secretValue(data, path, ref.Fragment)
+}
+
+func vaultSecretPath(ref *url.URL) string {
+	return strings.Trim(ref.Host+ref.Path, "/")
+}
+
+func secretValue(data map[string]interface{}, path string, key string) (string, error) {
+	if key == "" {
+		return "", errors.Errorf("no key set in the reference to the secret %s", path)
+	}
+	value, ok := data[key].(string)
+	if !ok {
+		return "", errors.Errorf("key %s not found in the secret %s", key, path)
+	}
+	return value, nil
+}
+
+// ResolveSecret returns the secret referenced by the given value if the value is a reference
+// like "file:///path" or "vault://path#key". Any other value is returned as is.
+// A "vault://" reference is an error if no Vault is configured, so the service never uses the reference itself as the secret.
+func (c *ConfigurationData) ResolveSecret(value string) (string, error) {
+	i := strings.Index(value, "://")
+	if i <= 0 {
+		return value, nil
+	}
+	scheme := value[:i]
+	resolver := c.secretResolver(scheme)
+	if resolver == nil {
+		if scheme == "vault" {
+			return "", errors.Errorf("unable to resolve the vault secret reference: %s is not set", varVaultAddress)
+		}
+		return value, nil
+	}
+	ref, err := url.Parse(value)
+	if err != nil {
+		return "", errors.Wrapf(err, "invalid %s secret reference", scheme)
+	}
+	secret, err := resolver.Resolve(ref)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to resolve %s secret reference", scheme)
+	}
+	return secret, nil
+}
+
+// IsSecretReference returns true if the given value is a "file://" or "vault://" reference to a secret
+// rather than the secret itself
+func IsSecretReference(value string) bool {
+	return strings.HasPrefix(value, "file://") || strings.HasPrefix(value, "vault://")
+}
+
+func (c *ConfigurationData) secretResolver(scheme string) SecretResolver {
+	secretResolversLock.RLock()
+	resolver, found := secretResolvers[scheme]
+	secretResolversLock.RUnlock()
+	if found {
+		return resolver
+	}
+	if scheme == "vault" && c.v.GetString(varVaultAddress) != "" {
+		return NewVaultSecretResolver(c.v.GetString(varVaultAddress), c.v.GetString(varVaultToken))
+	}
+	return nil
+}
+
+// resolveSecrets replaces the references to secrets in the main configuration with the referenced secrets
+func (c *ConfigurationData) resolveSecrets() error {
+	// The Vault token itself may be stored in a file
+	token, err := c.ResolveSecret(c.v.GetString(varVaultToken))
+	if err != nil {
+		return errors.Wrapf(err, "failed to resolve %s", varVaultToken)
+	}
+	c.v.Set(varVaultToken, token)
+
+	for _, varName := range []string{varKeycloakSecret, varGitHubClientSecret, varPostgresPassword} {
+		value := c.v.GetString(varName)
+		secret, err := c.ResolveSecret(value)
+		if err != nil {
+			return errors.Wrapf(err, "failed to resolve %s", varName)
+		}
+		if secret != value {
+			c.v.Set(varName, secret)
+		}
+	}
+	return nil
+}
+
+// resolveClusterSecrets replaces the references to secrets in the OSO cluster configuration with the referenced secrets
+func (c *ConfigurationData) resolveClusterSecrets(cluster *OSOCluster) error {
+	var err error
+	cluster.ServiceAccountToken, err = c.ResolveSecret(cluster.ServiceAccountToken)
+	if err != nil {
+		return errors.Wrapf(err, "failed to resolve the service account token of OSO cluster %s", cluster.Name)
+	}
+	cluster.AuthClientSecret, err = c.ResolveSecret(cluster.AuthClientSecret)
+	if err != nil {
+		return errors.Wrapf(err, "failed to resolve the auth client secret of OSO cluster %s", cluster.Name)
+	}
+	return nil
+}
@@ -0,0 +1,140 @@
+package configuration_test
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/fabric8-services/fabric8-auth/configuration"
+	"github.com/fabric8-services/fabric8-auth/resource"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSecretReferences(t *testing.T) {
+	resource.Require(t, resource.UnitTest)
+
+	dir, err := ioutil.TempDir("", "auth-secrets")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	// Keycloak secret mounted as a file
+	keycloakSecretFile := filepath.Join(dir, "keycloak-secret")
+	require.Nil(t, ioutil.WriteFile(keycloakSecretFile, []byte("mounted-keycloak-secret\n"), 0600))
+
+	// GitHub and OSO cluster secrets stored in the local Vault stand-in
+	vaultDir := filepath.Join(dir, "vault")
+	require.Nil(t, os.MkdirAll(filepath.Join(vaultDir, "secret"), 0700))
+	require.Nil(t, ioutil.WriteFile(filepath.Join(vaultDir, "secret", "auth.json"), []byte(`{"github":"vault-github-secret","cluster-token":"vault-cluster-token","cluster-secret":"vault-cluster-secret"}`), 0600))
+	configuration.RegisterSecretResolver("vault", configuration.NewLocalVaultSecretResolver(vaultDir))
+	defer configuration.UnregisterSecretResolver("vault")
+
+	clusterConfigFile := filepath.Join(dir, "oso-clusters.conf")
+	require.Nil(t, ioutil.WriteFile(clusterConfigFile, []byte(`{
+    "clusters": [
+        {
+            "name":"us-east-2",
+            "url":"https://api.starter-us-east-2.openshift.com",
+            "service-account-token":"vault://secret/auth#cluster-token",
+            "token-provider-id":"f867ac10-5e05-4359-a0c6-b855ece59090",
+            "auth-client-id":"autheast2",
+            "auth-client-secret":"vault://secret/auth#cluster-secret",
+            "auth-client-default-scope":"user:full"
+        }
+    ]
+}`), 0600))
+
+	env := map[string]string{
+		"AUTH_KEYCLOAK_SECRET":      "file://" + keycloakSecretFile,
+		"AUTH_GITHUB_CLIENT_SECRET": "vault://secret/auth#github",
+	}
+	for name, value := range env {
+		old, set := os.LookupEnv(name)
+		os.Setenv(name, value)
+		defer func(name, old string, set bool) {
+			if set {
+				os.Setenv(name, old)
+			} else {
+				os.Unsetenv(name)
+			}
+		}(name, old, set)
+	}
+
+	c, err := configuration.NewConfigurationData("", "", clusterConfigFile)
+	require.Nil(t, err)
+	assert.Equal(t, "mounted-keycloak-secret", c.GetKeycloakSecret())
+	assert.Equal(t, "vault-github-secret", c.GetGitHubClientSecret())
+	cluster := c.GetOSOClusters()["https://api.starter-us-east-2.openshift.com"]
+	assert.Equal(t, "vault-cluster-token", cluster.ServiceAccountToken)
+	assert.Equal(t, "vault-cluster-secret", cluster.AuthClientSecret)
+	// the cluster registry is seeded with the references, not with the secrets
+	seed := c.GetOSOClusterSeeds()["https://api.starter-us-east-2.openshift.com"]
+	assert.Equal(t, "vault://secret/auth#cluster-token", seed.ServiceAccountToken)
+	assert.Equal(t, "vault://secret/auth#cluster-secret", seed.AuthClientSecret)
+
+	t.Run("unknown key fails", func(t *testing.T) {
+		os.Setenv("AUTH_GITHUB_CLIENT_SECRET", "vault://secret/auth#unknown")
+		_, err := configuration.NewConfigurationData("", "", clusterConfigFile)
+		assert.NotNil(t, err)
+	})
+
+	t.Run("unresolvable vault reference fails", func(t *testing.T) {
+		configuration.UnregisterSecretResolver("vault")
+		defer configuration.RegisterSecretResolver("vault", configuration.NewLocalVaultSecretResolver(vaultDir))
+		os.Setenv("AUTH_GITHUB_CLIENT_SECRET", "vault://secret/auth#github")
+		_, err := configuration.NewConfigurationData("", "", clusterConfigFile)
+		assert.NotNil(t, err)
+	})
+
+	t.Run("missing file fails", func(t *testing.T) {
+		os.Setenv("AUTH_GITHUB_CLIENT_SECRET", "file://"+filepath.Join(dir, "unknown"))
+		_, err := configuration.NewConfigurationData("", "", clusterConfigFile)
+		assert.NotNil(t, err)
+	})
+}
+
+func TestVaultSecretResolver(t *testing.T) {
+	resource.Require(t, resource.UnitTest)
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Vault-Token") != "vault-token" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		switch r.URL.Path {
+		case "/v1/secret/auth":
+			w.Write([]byte(`{"data":{"keycloak":"kv1-secret"}}`))
+		case "/v1/secret/data/auth":
+			w.Write([]byte(`{"data":{"data":{"keycloak":"kv2-secret"},"metadata":{"version":1}}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+
+	resolver := configuration.NewVaultSecretResolver(ts.URL, "vault-token")
+	resolve := func(ref string) (string, error) {
+		u, err := url.Parse(ref)
+		require.Nil(t, err)
+		return resolver.Resolve(u)
+	}
+
+	secret, err := resolve("vault://secret/auth#keycloak")
+	require.Nil(t, err)
+	assert.Equal(t, "kv1-secret", secret)
+
+	secret, err = resolve("vault://secret/data/auth#keycloak")
+	require.Nil(t, err)
+	assert.Equal(t, "kv2-secret", secret)
+
+	_, err = resolve("vault://secret/unknown#keycloak")
+	assert.NotNil(t, err)
+
+	_, err = configuration.NewVaultSecretResolver(ts.URL, "wrong-token").Resolve(&url.URL{Host: "secret", Path: "/auth", Fragment: "keycloak"})
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,225 @@
+package controller
+
+import (
+	"context"
+	"time"
+
+	"github.com/fabric8-services/fabric8-auth/app"
+	"github.com/fabric8-services/fabric8-auth/application"
+	"github.com/fabric8-services/fabric8-auth/audit"
+	"github.com/fabric8-services/fabric8-auth/errors"
+	"github.com/fabric8-services/fabric8-auth/jsonapi"
+	"github.com/fabric8-services/fabric8-auth/log"
+	"github.com/fabric8-services/fabric8-auth/login"
+	"github.com/fabric8-services/fabric8-auth/space"
+	"github.com/fabric8-services/fabric8-auth/space/authz"
+
+	"github.com/goadesign/goa"
+	uuid "github.com/satori/go.uuid"
+)
+
+// AccessRequestsController implements the access_requests resource.
+type AccessRequestsController struct {
+	*goa.Controller
+	db            application.DB
+	collaborators *CollaboratorsController
+}
+
+// NewAccessRequestsController creates an access_requests controller. The approved requesters are
+// added to the space collaborators with the given collaborators controller.
+func NewAccessRequestsController(service *goa.Service, db application.DB, collaborators *CollaboratorsController) *AccessRequestsController {
+	return &AccessRequestsController{Controller: service.NewController("AccessRequestsController"), db: db, collaborators: collaborators}
+}
+
+// Create asks to collaborate on the space on behalf of the current user.
+func (c *AccessRequestsController) Create(ctx *app.CreateAccessRequestsContext) error {
+	currentUser, err := login.ContextIdentity(ctx)
+	if err != nil {
+		return jsonapi.JSONErrorResponse(ctx, goa.ErrUnauthorized(err.Error()))
+	}
+	collaborator, err := authz.Authorize(ctx, ctx.SpaceID.String())
+	if err != nil {
+		return jsonapi.JSONErrorResponse(ctx, goa.ErrUnauthorized(err.Error()))
+	}
+	if collaborator {
+		return jsonapi.JSONErrorResponse(ctx, errors.NewBadParameterError("space", ctx.SpaceID.String()).Expected("a space the user doesn't collaborate on"))
+	}
+	request := space.AccessRequest{
+		SpaceID:     ctx.SpaceID,
+		RequesterID: *currentUser,
+		Message:     stringValue(ctx.Payload.Data.Attributes.Message),
+	}
+	err = application.Transactional(c.db, func(appl application.Application) error {
+		if _, err := appl.SpaceResources().LoadBySpace(ctx, &ctx.SpaceID); err != nil {
+			return err
+		}
+		return appl.SpaceAccessRequests().Create(ctx, &request)
+	})
+	if err != nil {
+		return jsonapi.JSONErrorResponse(ctx, err)
+	}
+	return ctx.Created(&app.AccessRequestSingle{Data: ConvertToAppAccessRequest(request)})
+}
+
+// List lists the requests to access the space in the given state. Only the space collaborators can list the requests.
+func (c *AccessRequestsController) List(ctx *app.ListAccessRequestsContext) error {
+	if err := authorizeCollaborator(ctx, ctx.SpaceID); err != nil {
+		return jsonapi.JSONErrorResponse(ctx, err)
+	}
+	var requests []space.AccessRequest
+	err := application.Transactional(c.db, func(appl application.Application) error {
+		if _, err := appl.SpaceResources().LoadBySpace(ctx, &ctx.SpaceID); err != nil {
+			return err
+		}
+		var err error
+		requests, err = appl.SpaceAccessRequests().List(ctx, ctx.SpaceID, ctx.State)
+		return err
+	})
+	if err != nil {
+		return jsonapi.JSONErrorResponse(ctx, err)
+	}
+	data := make([]*app.AccessRequest, len(requests))
+	for i := range requests {
+		data[i] = ConvertToAppAccessRequest(requests[i])
+	}
+	return ctx.OK(&app.AccessRequestList{Data: data})
+}
+
+// Show returns the request to access the space, so the requester can follow its state.
+// Only the requester and the space collaborators can get the request.
+func (c *AccessRequestsController) Show(ctx *app.ShowAccessRequestsContext) error {
+	currentUser, err := login.ContextIdentity(ctx)
+	if err != nil {
+		return jsonapi.JSONErrorResponse(ctx, goa.ErrUnauthorized(err.Error()))
+	}
+	request, err := c.loadRequest(ctx, ctx.SpaceID, ctx.RequestID)
+	if err != nil {
+		return jsonapi.JSONErrorResponse(ctx, err)
+	}
+	if request.RequesterID != *currentUser {
+		if err := authorizeCollaborator(ctx, ctx.SpaceID); err != nil {
+			return jsonapi.JSONErrorResponse(ctx, err)
+		}
+	}
+	return ctx.OK(&app.AccessRequestSingle{Data: ConvertToAppAccessRequest(*request)})
+}
+
+// Approve approves a pending request to access the space. The requester is added to the space
+// collaborators the same way as by the collaborators resource, so only the space collaborators
+// can approve the requests. The decision is recorded in the same transaction as the update of the
+// collaborators, so the request is approved only if the requester is added.
+func (c *AccessRequestsController) Approve(ctx *app.ApproveAccessRequestsContext) error {
+	currentUser, err := login.ContextIdentity(ctx)
+	if err != nil {
+		return jsonapi.JSONErrorResponse(ctx, goa.ErrUnauthorized(err.Error()))
+	}
+	if err := authorizeCollaborator(ctx, ctx.SpaceID); err != nil {
+		return jsonapi.JSONErrorResponse(ctx, err)
+	}
+	var request *space.AccessRequest
+	err = application.Transactional(c.db, func(appl application.Application) error {
+		var err error
+		request, err = c.decide(ctx, appl, ctx.SpaceID, ctx.RequestID, *currentUser, space.AccessRequestStateApproved)
+		if err != nil {
+			return err
+		}
+		identityIDs := []*app.UpdateUserID{{ID: request.RequesterID.String()}}
+		_, err = c.collaborators.updatePolicyInTransaction(ctx, appl, ctx.RequestData, ctx.SpaceID, identityIDs, c.collaborators.policyManager.AddUserToPolicy, audit.EventTypeCollaboratorAdded, nil)
+		return err
+	})
+	if err != nil {
+		return jsonapi.JSONErrorResponse(ctx, err)
+	}
+	logDecision(ctx, request)
+	return ctx.OK(&app.AccessRequestSingle{Data: ConvertToAppAccessRequest(*request)})
+}
+
+// Deny denies a pending request to access the space. Only the space collaborators can deny the requests.
+func (c *AccessRequestsController) Deny(ctx *app.DenyAccessRequestsContext) error {
+	currentUser, err := login.ContextIdentity(ctx)
+	if err != nil {
+		return jsonapi.JSONErrorResponse(ctx, goa.ErrUnauthorized(err.Error()))
+	}
+	if err := authorizeCollaborator(ctx, ctx.SpaceID); err != nil {
+		return jsonapi.JSONErrorResponse(ctx, err)
+	}
+	var request *space.AccessRequest
+	err = application.Transactional(c.db, func(appl application.Application) error {
+		var err error
+		request, err = c.decide(ctx, appl, ctx.SpaceID, ctx.RequestID, *currentUser, space.AccessRequestStateDenied)
+		return err
+	})
+	if err != nil {
+		return jsonapi.JSONErrorResponse(ctx, err)
+	}
+	logDecision(ctx, request)
+	return ctx.OK(&app.AccessRequestSingle{Data: ConvertToAppAccessRequest(*request)})
+}
+
+// loadRequest loads the request to access the given space
+func (c *AccessRequestsController) loadRequest(ctx context.Context, spaceID uuid.UUID, requestID uuid.UUID) (*space.AccessRequest, error) {
+	var request *space.AccessRequest
+	err := application.Transactional(c.db, func(appl application.Application) error {
+		var err error
+		request, err = appl.SpaceAccessRequests().Load(ctx, requestID)
+		if err != nil {
+			return err
+		}
+		if request.SpaceID != spaceID {
+			return errors.NewNotFoundError("space access request", requestID.String())
+		}
+		return nil
+	})
+	return request, err
+}
+
+// decide locks the pending request to access the given space until the end of the given transaction and records
+// the decision of the given space collaborator, so the concurrent decisions on the same request are rejected
+func (c *AccessRequestsController) decide(ctx context.Context, appl application.Application, spaceID uuid.UUID, requestID uuid.UUID, deciderID uuid.UUID, state string) (*space.AccessRequest, error) {
+	request, err := appl.SpaceAccessRequests().Lock(ctx, requestID)
+	if err != nil {
+		return nil, err
+	}
+	if request.SpaceID != spaceID {
+		return nil, errors.NewNotFoundError("space access request", requestID.String())
+	}
+	if request.State != space.AccessRequestStatePending {
+		return nil, errors.NewBadParameterError("state", request.State).Expected(space.AccessRequestStatePending)
+	}
+	now := time.Now()
+	request.State = state
+	request.DecidedBy = &deciderID
+	request.DecidedAt = &now
+	if err := appl.SpaceAccessRequests().Save(ctx, request); err != nil {
+		return nil, err
+	}
+	return request, nil
+}
+
+// logDecision logs the decision recorded on the given request
+func logDecision(ctx context.Context, request *space.AccessRequest) {
+	log.Info(ctx, map[string]interface{}{
+		"access_request_id": request.ID,
+		"space_id":          request.SpaceID,
+		"requester_id":      request.RequesterID,
+		"decided_by":        *request.DecidedBy,
+		"state":             request.State,
+	}, "space access request decided")
+}
+
+// ConvertToAppAccessRequest converts a space access request to its REST representation
+func ConvertToAppAccessRequest(request space.AccessRequest) *app.AccessRequest {
+	return &app.AccessRequest{
+		Type: "accessrequests",
+		ID:   request.ID.String(),
+		Attributes: &app.AccessRequestAttributes{
+			SpaceID:     &request.SpaceID,
+			RequesterID: &request.RequesterID,
+			Message:     &request.Message,
+			State:       &request.State,
+			DecidedBy:   request.DecidedBy,
+			DecidedAt:   request.DecidedAt,
+			CreatedAt:   &request.CreatedAt,
+		},
+	}
+}
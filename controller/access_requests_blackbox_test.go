@@ -0,0 +1,136 @@
+package controller_test
+
+import (
+	"github.com/fabric8-services/fabric8-auth/account"
+	"github.com/fabric8-services/fabric8-auth/app"
+	"github.com/fabric8-services/fabric8-auth/app/test"
+	. "github.com/fabric8-services/fabric8-auth/controller"
+	"github.com/fabric8-services/fabric8-auth/space"
+	testsupport "github.com/fabric8-services/fabric8-auth/test"
+
+	"github.com/goadesign/goa"
+	"github.com/satori/go.uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func (rest *TestCollaboratorsREST) accessRequestsController(identity account.Identity) (*goa.Service, *AccessRequestsController) {
+	svc := testsupport.ServiceAsSpaceUser("AccessRequests-Service", identity, &DummySpaceAuthzService{rest})
+	collaboratorsCtrl := NewCollaboratorsController(svc, rest.Application, rest.Configuration, &DummyPolicyManager{rest: rest})
+	return svc, NewAccessRequestsController(svc, rest.Application, collaboratorsCtrl)
+}
+
+func (rest *TestCollaboratorsREST) createAccessRequest(requester account.Identity, message string) *app.AccessRequest {
+	svc, ctrl := rest.accessRequestsController(requester)
+	payload := &app.CreateAccessRequestsPayload{
+		Data: &app.CreateAccessRequestData{
+			Type:       "accessrequests",
+			Attributes: &app.CreateAccessRequestAttributes{Message: &message},
+		},
+	}
+	_, created := test.CreateAccessRequestsCreated(rest.T(), svc.Context, svc, ctrl, rest.spaceID, payload)
+	require.NotNil(rest.T(), created.Data)
+	return created.Data
+}
+
+func (rest *TestCollaboratorsREST) TestApproveAccessRequestOK() {
+	// given the space owner as the only collaborator and a request of another user
+	rest.policy.AddUserToPolicy(rest.testIdentity1.ID.String())
+	request := rest.createAccessRequest(rest.testIdentity2, "please let me in")
+	assert.Equal(rest.T(), space.AccessRequestStatePending, *request.Attributes.State)
+	requestID, err := uuid.FromString(request.ID)
+	require.Nil(rest.T(), err)
+
+	// the pending request is listed for the collaborators only
+	svc, ctrl := rest.accessRequestsController(rest.testIdentity1)
+	_, requests := test.ListAccessRequestsOK(rest.T(), svc.Context, svc, ctrl, rest.spaceID, nil)
+	require.Len(rest.T(), requests.Data, 1)
+	assert.Equal(rest.T(), "please let me in", *requests.Data[0].Attributes.Message)
+	requesterSvc, requesterCtrl := rest.accessRequestsController(rest.testIdentity2)
+	test.ListAccessRequestsUnauthorized(rest.T(), requesterSvc.Context, requesterSvc, requesterCtrl, rest.spaceID, nil)
+
+	// when
+	_, approved := test.ApproveAccessRequestsOK(rest.T(), svc.Context, svc, ctrl, rest.spaceID, requestID)
+
+	// then the requester is a collaborator and can see the state of the request
+	assert.Equal(rest.T(), space.AccessRequestStateApproved, *approved.Data.Attributes.State)
+	assert.Equal(rest.T(), rest.testIdentity1.ID, *approved.Data.Attributes.DecidedBy)
+	assert.Contains(rest.T(), rest.policy.Config.UserIDs, rest.testIdentity2.ID.String())
+	_, shown := test.ShowAccessRequestsOK(rest.T(), requesterSvc.Context, requesterSvc, requesterCtrl, rest.spaceID, requestID)
+	assert.Equal(rest.T(), space.AccessRequestStateApproved, *shown.Data.Attributes.State)
+	// and the request can't be approved twice
+	test.ApproveAccessRequestsBadRequest(rest.T(), svc.Context, svc, ctrl, rest.spaceID, requestID)
+}
+
+func (rest *TestCollaboratorsREST) TestDenyAccessRequestOK() {
+	// given
+	rest.policy.AddUserToPolicy(rest.testIdentity1.ID.String())
+	request := rest.createAccessRequest(rest.testIdentity2, "")
+	requestID, err := uuid.FromString(request.ID)
+	require.Nil(rest.T(), err)
+	svc, ctrl := rest.accessRequestsController(rest.testIdentity1)
+
+	// when
+	_, denied := test.DenyAccessRequestsOK(rest.T(), svc.Context, svc, ctrl, rest.spaceID, requestID)
+
+	// then
+	assert.Equal(rest.T(), space.AccessRequestStateDenied, *denied.Data.Attributes.State)
+	assert.NotContains(rest.T(), rest.policy.Config.UserIDs, rest.testIdentity2.ID.String())
+	state := space.AccessRequestStateDenied
+	_, requests := test.ListAccessRequestsOK(rest.T(), svc.Context, svc, ctrl, rest.spaceID, &state)
+	require.Len(rest.T(), requests.Data, 1)
+}
+
+func (rest *TestCollaboratorsREST) TestApproveDeniedAccessRequestBadRequest() {
+	// given
+	rest.policy.AddUserToPolicy(rest.testIdentity1.ID.String())
+	request := rest.createAccessRequest(rest.testIdentity2, "")
+	requestID, err := uuid.FromString(request.ID)
+	require.Nil(rest.T(), err)
+	svc, ctrl := rest.accessRequestsController(rest.testIdentity1)
+	test.DenyAccessRequestsOK(rest.T(), svc.Context, svc, ctrl, rest.spaceID, requestID)
+
+	// when
+	test.ApproveAccessRequestsBadRequest(rest.T(), svc.Context, svc, ctrl, rest.spaceID, requestID)
+
+	// then the requester is not added to the collaborators and the request stays denied
+	assert.NotContains(rest.T(), rest.policy.Config.UserIDs, rest.testIdentity2.ID.String())
+	_, shown := test.ShowAccessRequestsOK(rest.T(), svc.Context, svc, ctrl, rest.spaceID, requestID)
+	assert.Equal(rest.T(), space.AccessRequestStateDenied, *shown.Data.Attributes.State)
+	assert.Equal(rest.T(), rest.testIdentity1.ID, *shown.Data.Attributes.DecidedBy)
+}
+
+func (rest *TestCollaboratorsREST) TestDecideAccessRequestUnauthorizedIfCurrentUserIsNotCollaborator() {
+	// given
+	rest.policy.AddUserToPolicy(rest.testIdentity1.ID.String())
+	request := rest.createAccessRequest(rest.testIdentity2, "")
+	requestID, err := uuid.FromString(request.ID)
+	require.Nil(rest.T(), err)
+	svc, ctrl := rest.accessRequestsController(rest.testIdentity3)
+
+	// when/then
+	test.ApproveAccessRequestsUnauthorized(rest.T(), svc.Context, svc, ctrl, rest.spaceID, requestID)
+	test.DenyAccessRequestsUnauthorized(rest.T(), svc.Context, svc, ctrl, rest.spaceID, requestID)
+	test.ShowAccessRequestsUnauthorized(rest.T(), svc.Context, svc, ctrl, rest.spaceID, requestID)
+}
+
+func (rest *TestCollaboratorsREST) TestCreateAccessRequestBadRequest() {
+	// given
+	rest.policy.AddUserToPolicy(rest.testIdentity1.ID.String())
+	message := "let me in"
+	payload := &app.CreateAccessRequestsPayload{
+		Data: &app.CreateAccessRequestData{
+			Type:       "accessrequests",
+			Attributes: &app.CreateAccessRequestAttributes{Message: &message},
+		},
+	}
+
+	// when the user is already a collaborator
+	svc, ctrl := rest.accessRequestsController(rest.testIdentity1)
+	test.CreateAccessRequestsBadRequest(rest.T(), svc.Context, svc, ctrl, rest.spaceID, payload)
+
+	// when the user already has a pending request
+	rest.createAccessRequest(rest.testIdentity2, message)
+	svc, ctrl = rest.accessRequestsController(rest.testIdentity2)
+	test.CreateAccessRequestsBadRequest(rest.T(), svc.Context, svc, ctrl, rest.spaceID, payload)
+}
@@ -0,0 +1,226 @@
+package controller
+
+import (
+	"context"
+
+	"github.com/fabric8-services/fabric8-auth/app"
+	"github.com/fabric8-services/fabric8-auth/application"
+	"github.com/fabric8-services/fabric8-auth/cluster/registry"
+	"github.com/fabric8-services/fabric8-auth/configuration"
+	"github.com/fabric8-services/fabric8-auth/errors"
+	"github.com/fabric8-services/fabric8-auth/jsonapi"
+	"github.com/fabric8-services/fabric8-auth/log"
+	"github.com/fabric8-services/fabric8-auth/token"
+
+	"github.com/goadesign/goa"
+)
+
+// ClustersController implements the clusters resource.
+type ClustersController struct {
+	*goa.Controller
+	db application.DB
+}
+
+// NewClustersController creates a clusters controller.
+func NewClustersController(service *goa.Service, db application.DB) *ClustersController {
+	return &ClustersController{Controller: service.NewController("ClustersController"), db: db}
+}
+
+// List lists the registered clusters. The OAuth clients of the clusters are not returned.
+func (c *ClustersController) List(ctx *app.ListClustersContext) error {
+	clusters, err := c.db.Clusters().List(ctx)
+	if err != nil {
+		return jsonapi.JSONErrorResponse(ctx, err)
+	}
+	res := &app.ClusterList{Data: []*app.Cluster{}}
+	for _, cluster := range clusters {
+		res.Data = append(res.Data, ConvertToAppCluster(cluster, false))
+	}
+	return ctx.OK(res)
+}
+
+// Show shows the registered cluster, including its OAuth client. Only the administrators can see the OAuth client of a cluster.
+func (c *ClustersController) Show(ctx *app.ShowClustersContext) error {
+	if err := authorizeClusterAdmin(ctx); err != nil {
+		return jsonapi.JSONErrorResponse(ctx, err)
+	}
+	cluster, err := c.db.Clusters().Load(ctx, ctx.ClusterID)
+	if err != nil {
+		return jsonapi.JSONErrorResponse(ctx, err)
+	}
+	return ctx.OK(&app.ClusterSingle{Data: ConvertToAppCluster(*cluster, true)})
+}
+
+// Create registers a cluster. Only the administrators can register a cluster.
+func (c *ClustersController) Create(ctx *app.CreateClustersContext) error {
+	if err := authorizeClusterAdmin(ctx); err != nil {
+		return jsonapi.JSONErrorResponse(ctx, err)
+	}
+	attributes := ctx.Payload.Data.Attributes
+	cluster := registry.Cluster{
+		Name:                   attributes.Name,
+		URL:                    attributes.APIURL,
+		ConsoleURL:             stringValue(attributes.ConsoleURL),
+		ServiceAccountToken:    stringValue(attributes.ServiceAccountToken),
+		TokenProviderID:        attributes.TokenProviderID,
+		AuthClientID:           attributes.AuthClientID,
+		AuthClientSecret:       attributes.AuthClientSecret,
+		AuthClientDefaultScope: attributes.AuthClientDefaultScope,
+		Region:                 stringValue(attributes.Region),
+	}
+	if attributes.Capacity != nil {
+		cluster.Capacity = *attributes.Capacity
+	}
+	if err := checkClusterSecretReferences(attributes.ServiceAccountToken, &attributes.AuthClientSecret); err != nil {
+		return jsonapi.JSONErrorResponse(ctx, err)
+	}
+	err := application.Transactional(c.db, func(appl application.Application) error {
+		return appl.Clusters().Create(ctx, &cluster)
+	})
+	if err != nil {
+		return jsonapi.JSONErrorResponse(ctx, err)
+	}
+	return ctx.Created(&app.ClusterSingle{Data: ConvertToAppCluster(cluster, true)})
+}
+
+// Update updates the given attributes of the registered cluster. Only the administrators can update a cluster.
+// The API URL can't be changed while users are provisioned to the cluster, since the users refer to their cluster by API URL.
+func (c *ClustersController) Update(ctx *app.UpdateClustersContext) error {
+	if err := authorizeClusterAdmin(ctx); err != nil {
+		return jsonapi.JSONErrorResponse(ctx, err)
+	}
+	attributes := ctx.Payload.Data.Attributes
+	if err := checkClusterSecretReferences(attributes.ServiceAccountToken, attributes.AuthClientSecret); err != nil {
+		return jsonapi.JSONErrorResponse(ctx, err)
+	}
+	var cluster *registry.Cluster
+	err := application.Transactional(c.db, func(appl application.Application) error {
+		var err error
+		cluster, err = appl.Clusters().Load(ctx, ctx.ClusterID)
+		if err != nil {
+			return err
+		}
+		if attributes.APIURL != nil && *attributes.APIURL != cluster.URL {
+			if err := checkNoUserOnCluster(ctx, appl, cluster.URL, "data.attributes.api-url"); err != nil {
+				return err
+			}
+			cluster.URL = *attributes.APIURL
+		}
+		if attributes.Name != nil {
+			cluster.Name = *attributes.Name
+		}
+		if attributes.ConsoleURL != nil {
+			cluster.ConsoleURL = *attributes.ConsoleURL
+		}
+		if attributes.Region != nil {
+			cluster.Region = *attributes.Region
+		}
+		if attributes.Capacity != nil {
+			cluster.Capacity = *attributes.Capacity
+		}
+		if attributes.ServiceAccountToken != nil {
+			cluster.ServiceAccountToken = *attributes.ServiceAccountToken
+		}
+		if attributes.TokenProviderID != nil {
+			cluster.TokenProviderID = *attributes.TokenProviderID
+		}
+		if attributes.AuthClientID != nil {
+			cluster.AuthClientID = *attributes.AuthClientID
+		}
+		if attributes.AuthClientSecret != nil {
+			cluster.AuthClientSecret = *attributes.AuthClientSecret
+		}
+		if attributes.AuthClientDefaultScope != nil {
+			cluster.AuthClientDefaultScope = *attributes.AuthClientDefaultScope
+		}
+		return appl.Clusters().Save(ctx, cluster)
+	})
+	if err != nil {
+		return jsonapi.JSONErrorResponse(ctx, err)
+	}
+	log.Info(ctx, map[string]interface{}{
+		"cluster_id":  cluster.ID,
+		"cluster_url": cluster.URL,
+	}, "cluster updated")
+	return ctx.OK(&app.ClusterSingle{Data: ConvertToAppCluster(*cluster, true)})
+}
+
+// Delete removes the cluster from the registry. Only the administrators can remove a cluster.
+// The cluster can't be removed while users are provisioned to it.
+func (c *ClustersController) Delete(ctx *app.DeleteClustersContext) error {
+	if err := authorizeClusterAdmin(ctx); err != nil {
+		return jsonapi.JSONErrorResponse(ctx, err)
+	}
+	err := application.Transactional(c.db, func(appl application.Application) error {
+		cluster, err := appl.Clusters().Load(ctx, ctx.ClusterID)
+		if err != nil {
+			return err
+		}
+		if err := checkNoUserOnCluster(ctx, appl, cluster.URL, "clusterID"); err != nil {
+			return err
+		}
+		return appl.Clusters().Delete(ctx, cluster.ID)
+	})
+	if err != nil {
+		return jsonapi.JSONErrorResponse(ctx, err)
+	}
+	return ctx.OK([]byte{})
+}
+
+// authorizeClusterAdmin returns an UnauthorizedError unless the current account is the service account allowed to manage the clusters
+func authorizeClusterAdmin(ctx context.Context) error {
+	if !token.IsSpecificServiceAccount(ctx, []string{"online-registration"}) {
+		log.Error(ctx, nil, "The account is not an authorized service account allowed to manage the clusters")
+		return errors.NewUnauthorizedError("account not authorized to manage the clusters.")
+	}
+	return nil
+}
+
+// checkNoUserOnCluster returns a BadParameterError for the given parameter if users are provisioned to the cluster with the given API URL
+func checkNoUserOnCluster(ctx context.Context, appl application.Application, clusterURL string, param string) error {
+	counts, err := appl.Users().CountByCluster(ctx)
+	if err != nil {
+		return errors.NewInternalError(ctx, err)
+	}
+	if counts[clusterURL] > 0 {
+		return errors.NewBadParameterError(param, clusterURL).Expected("a cluster without users")
+	}
+	return nil
+}
+
+// checkClusterSecretReferences returns a BadParameterError unless the given secrets of a cluster are references
+// to the secrets, like "vault://path#key", so the secrets themselves are never stored in the cluster registry.
+// The secrets which are not set are ignored.
+func checkClusterSecretReferences(serviceAccountToken *string, authClientSecret *string) error {
+	if serviceAccountToken != nil && *serviceAccountToken != "" && !configuration.IsSecretReference(*serviceAccountToken) {
+		return errors.NewBadParameterError("data.attributes.service-account-token", "***").Expected("a vault:// or file:// secret reference")
+	}
+	if authClientSecret != nil && !configuration.IsSecretReference(*authClientSecret) {
+		return errors.NewBadParameterError("data.attributes.auth-client-secret", "***").Expected("a vault:// or file:// secret reference")
+	}
+	return nil
+}
+
+// ConvertToAppCluster converts the cluster of the registry to the app representation.
+// The OAuth client of the cluster is included if withClient is true. The secrets are never included.
+func ConvertToAppCluster(cluster registry.Cluster, withClient bool) *app.Cluster {
+	res := &app.Cluster{
+		Type: "clusters",
+		ID:   cluster.ID.String(),
+		Attributes: &app.ClusterAttributes{
+			Name:       &cluster.Name,
+			APIURL:     &cluster.URL,
+			ConsoleURL: &cluster.ConsoleURL,
+			Region:     &cluster.Region,
+			Capacity:   &cluster.Capacity,
+			CreatedAt:  &cluster.CreatedAt,
+			UpdatedAt:  &cluster.UpdatedAt,
+		},
+	}
+	if withClient {
+		res.Attributes.TokenProviderID = &cluster.TokenProviderID
+		res.Attributes.AuthClientID = &cluster.AuthClientID
+		res.Attributes.AuthClientDefaultScope = &cluster.AuthClientDefaultScope
+	}
+	return res
+}
@@ -0,0 +1,229 @@
+package controller_test
+
+import (
+	"testing"
+
+	"github.com/fabric8-services/fabric8-auth/account"
+	"github.com/fabric8-services/fabric8-auth/app"
+	"github.com/fabric8-services/fabric8-auth/app/test"
+	"github.com/fabric8-services/fabric8-auth/cluster/registry"
+	. "github.com/fabric8-services/fabric8-auth/controller"
+	"github.com/fabric8-services/fabric8-auth/gormtestsupport"
+	"github.com/fabric8-services/fabric8-auth/resource"
+	testsupport "github.com/fabric8-services/fabric8-auth/test"
+
+	"github.com/goadesign/goa"
+	"github.com/satori/go.uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+)
+
+type TestClustersREST struct {
+	gormtestsupport.DBTestSuite
+}
+
+func TestRunClustersREST(t *testing.T) {
+	resource.Require(t, resource.Database)
+	suite.Run(t, &TestClustersREST{DBTestSuite: gormtestsupport.NewDBTestSuite()})
+}
+
+func (rest *TestClustersREST) AdminController() (*goa.Service, *ClustersController) {
+	svc := testsupport.ServiceAsServiceAccountUser("Clusters-ServiceAccount-Service", testsupport.TestOnlineRegistrationAppIdentity)
+	return svc, NewClustersController(svc, rest.Application)
+}
+
+func (rest *TestClustersREST) UserController() (*goa.Service, *ClustersController) {
+	svc := testsupport.ServiceAsUser("Clusters-Service", testsupport.TestIdentity)
+	return svc, NewClustersController(svc, rest.Application)
+}
+
+func (rest *TestClustersREST) createPayload() *app.CreateClustersPayload {
+	capacity := 1000
+	region := "us-east"
+	return &app.CreateClustersPayload{
+		Data: &app.CreateClusterData{
+			Type: "clusters",
+			Attributes: &app.CreateClusterAttributes{
+				Name:                   "cluster-" + uuid.NewV4().String(),
+				APIURL:                 "https://api." + uuid.NewV4().String() + ".openshift.com/",
+				TokenProviderID:        uuid.NewV4(),
+				AuthClientID:           "client",
+				AuthClientSecret:       "vault://secret/clusters#secret",
+				AuthClientDefaultScope: "user:full",
+				Capacity:               &capacity,
+				Region:                 &region,
+			},
+		},
+	}
+}
+
+func (rest *TestClustersREST) TestCreateShowAndListOK() {
+	// given
+	svc, ctrl := rest.AdminController()
+	payload := rest.createPayload()
+
+	// when
+	_, created := test.CreateClustersCreated(rest.T(), svc.Context, svc, ctrl, payload)
+
+	// then
+	require.NotNil(rest.T(), created.Data)
+	assert.Equal(rest.T(), payload.Data.Attributes.APIURL, *created.Data.Attributes.APIURL)
+	assert.Equal(rest.T(), payload.Data.Attributes.TokenProviderID, *created.Data.Attributes.TokenProviderID)
+	clusterID, err := uuid.FromString(created.Data.ID)
+	require.Nil(rest.T(), err)
+	stored, err := rest.Application.Clusters().Load(svc.Context, clusterID)
+	require.Nil(rest.T(), err)
+	assert.Equal(rest.T(), "vault://secret/clusters#secret", stored.AuthClientSecret)
+	assert.Equal(rest.T(), 1000, stored.Capacity)
+
+	// when
+	_, shown := test.ShowClustersOK(rest.T(), svc.Context, svc, ctrl, clusterID)
+
+	// then
+	assert.Equal(rest.T(), created.Data.ID, shown.Data.ID)
+	assert.Equal(rest.T(), "client", *shown.Data.Attributes.AuthClientID)
+
+	// when the clusters are listed by a user
+	userSvc, userCtrl := rest.UserController()
+	_, list := test.ListClustersOK(rest.T(), userSvc.Context, userSvc, userCtrl)
+
+	// then the OAuth clients of the clusters are not returned
+	var listed *app.Cluster
+	for _, cluster := range list.Data {
+		if cluster.ID == created.Data.ID {
+			listed = cluster
+		}
+	}
+	require.NotNil(rest.T(), listed)
+	assert.Equal(rest.T(), payload.Data.Attributes.APIURL, *listed.Attributes.APIURL)
+	assert.Nil(rest.T(), listed.Attributes.AuthClientID)
+	assert.Nil(rest.T(), listed.Attributes.TokenProviderID)
+}
+
+func (rest *TestClustersREST) TestCreateWithRegisteredURLBadRequest() {
+	// given
+	svc, ctrl := rest.AdminController()
+	payload := rest.createPayload()
+	test.CreateClustersCreated(rest.T(), svc.Context, svc, ctrl, payload)
+
+	// when/then
+	test.CreateClustersBadRequest(rest.T(), svc.Context, svc, ctrl, payload)
+}
+
+func (rest *TestClustersREST) TestUpdateOK() {
+	// given
+	svc, ctrl := rest.AdminController()
+	_, created := test.CreateClustersCreated(rest.T(), svc.Context, svc, ctrl, rest.createPayload())
+	clusterID, err := uuid.FromString(created.Data.ID)
+	require.Nil(rest.T(), err)
+	capacity := 2000
+	secret := "vault://secret/clusters#new-secret"
+	payload := &app.UpdateClustersPayload{
+		Data: &app.UpdateClusterData{
+			Type: "clusters",
+			Attributes: &app.UpdateClusterAttributes{
+				Capacity:         &capacity,
+				AuthClientSecret: &secret,
+			},
+		},
+	}
+
+	// when
+	_, updated := test.UpdateClustersOK(rest.T(), svc.Context, svc, ctrl, clusterID, payload)
+
+	// then only the given attributes are updated
+	assert.Equal(rest.T(), 2000, *updated.Data.Attributes.Capacity)
+	assert.Equal(rest.T(), *created.Data.Attributes.APIURL, *updated.Data.Attributes.APIURL)
+	stored, err := rest.Application.Clusters().Load(svc.Context, clusterID)
+	require.Nil(rest.T(), err)
+	assert.Equal(rest.T(), "vault://secret/clusters#new-secret", stored.AuthClientSecret)
+	assert.Equal(rest.T(), "client", stored.AuthClientID)
+}
+
+func (rest *TestClustersREST) TestCreateAndUpdateWithPlainSecretBadRequest() {
+	// given
+	svc, ctrl := rest.AdminController()
+	payload := rest.createPayload()
+	payload.Data.Attributes.AuthClientSecret = "secret"
+
+	// when/then
+	test.CreateClustersBadRequest(rest.T(), svc.Context, svc, ctrl, payload)
+
+	// given
+	_, created := test.CreateClustersCreated(rest.T(), svc.Context, svc, ctrl, rest.createPayload())
+	clusterID, err := uuid.FromString(created.Data.ID)
+	require.Nil(rest.T(), err)
+	token := "sa-token"
+	update := &app.UpdateClustersPayload{
+		Data: &app.UpdateClusterData{
+			Type:       "clusters",
+			Attributes: &app.UpdateClusterAttributes{ServiceAccountToken: &token},
+		},
+	}
+
+	// when/then
+	test.UpdateClustersBadRequest(rest.T(), svc.Context, svc, ctrl, clusterID, update)
+	stored, err := rest.Application.Clusters().Load(svc.Context, clusterID)
+	require.Nil(rest.T(), err)
+	assert.Equal(rest.T(), "", stored.ServiceAccountToken)
+}
+
+func (rest *TestClustersREST) TestUpdateAndDeleteClusterWithUsersBadRequest() {
+	// given
+	svc, ctrl := rest.AdminController()
+	_, created := test.CreateClustersCreated(rest.T(), svc.Context, svc, ctrl, rest.createPayload())
+	clusterID, err := uuid.FromString(created.Data.ID)
+	require.Nil(rest.T(), err)
+	err = rest.Application.Users().Create(svc.Context, &account.User{
+		ID:      uuid.NewV4(),
+		Email:   "cluster-user-" + uuid.NewV4().String(),
+		Cluster: *created.Data.Attributes.APIURL,
+	})
+	require.Nil(rest.T(), err)
+	apiURL := "https://api." + uuid.NewV4().String() + ".openshift.com/"
+	payload := &app.UpdateClustersPayload{
+		Data: &app.UpdateClusterData{
+			Type:       "clusters",
+			Attributes: &app.UpdateClusterAttributes{APIURL: &apiURL},
+		},
+	}
+
+	// when/then
+	test.UpdateClustersBadRequest(rest.T(), svc.Context, svc, ctrl, clusterID, payload)
+	test.DeleteClustersBadRequest(rest.T(), svc.Context, svc, ctrl, clusterID)
+}
+
+func (rest *TestClustersREST) TestDeleteOK() {
+	// given
+	svc, ctrl := rest.AdminController()
+	_, created := test.CreateClustersCreated(rest.T(), svc.Context, svc, ctrl, rest.createPayload())
+	clusterID, err := uuid.FromString(created.Data.ID)
+	require.Nil(rest.T(), err)
+
+	// when
+	test.DeleteClustersOK(rest.T(), svc.Context, svc, ctrl, clusterID)
+
+	// then
+	test.ShowClustersNotFound(rest.T(), svc.Context, svc, ctrl, clusterID)
+	test.DeleteClustersNotFound(rest.T(), svc.Context, svc, ctrl, clusterID)
+}
+
+func (rest *TestClustersREST) TestManageClustersUnauthorized() {
+	// given
+	cluster := registry.Cluster{
+		Name:            "cluster-" + uuid.NewV4().String(),
+		URL:             "https://api." + uuid.NewV4().String() + ".openshift.com/",
+		TokenProviderID: uuid.NewV4(),
+	}
+	require.Nil(rest.T(), rest.Application.Clusters().Create(rest.Ctx, &cluster))
+	svc, ctrl := rest.UserController()
+
+	// when/then
+	test.ShowClustersUnauthorized(rest.T(), svc.Context, svc, ctrl, cluster.ID)
+	test.CreateClustersUnauthorized(rest.T(), svc.Context, svc, ctrl, rest.createPayload())
+	test.UpdateClustersUnauthorized(rest.T(), svc.Context, svc, ctrl, cluster.ID, &app.UpdateClustersPayload{
+		Data: &app.UpdateClusterData{Type: "clusters", Attributes: &app.UpdateClusterAttributes{}},
+	})
+	test.DeleteClustersUnauthorized(rest.T(), svc.Context, svc, ctrl, cluster.ID)
+}
@@ -4,15 +4,23 @@ import (
 	"context"
 	"errors"
 	"strings"
+	"time"
 
 	"github.com/fabric8-services/fabric8-auth/account"
 	"github.com/fabric8-services/fabric8-auth/app"
 	"github.com/fabric8-services/fabric8-auth/application"
+	"github.com/fabric8-services/fabric8-auth/audit"
 	"github.com/fabric8-services/fabric8-auth/auth"
+	"github.com/fabric8-services/fabric8-auth/authorization/organization"
 	autherrors "github.com/fabric8-services/fabric8-auth/errors"
 	"github.com/fabric8-services/fabric8-auth/jsonapi"
 	"github.com/fabric8-services/fabric8-auth/log"
+	"github.com/fabric8-services/fabric8-auth/login"
+	"github.com/fabric8-services/fabric8-auth/outbox"
+	"github.com/fabric8-services/fabric8-auth/rest"
+	"github.com/fabric8-services/fabric8-auth/space"
 	"github.com/fabric8-services/fabric8-auth/space/authz"
+	"github.com/fabric8-services/fabric8-auth/webhook"
 
 	"github.com/goadesign/goa"
 	uuid "github.com/satori/go.uuid"
@@ -24,6 +32,7 @@ type CollaboratorsController struct {
 	db            application.DB
 	config        collaboratorsConfiguration
 	policyManager auth.AuthzPolicyManager
+	organizations *organization.Service
 }
 
 type collaboratorsConfiguration interface {
@@ -38,7 +47,7 @@ type collaboratorContext interface {
 
 // NewCollaboratorsController creates a collaborators controller.
 func NewCollaboratorsController(service *goa.Service, db application.DB, config collaboratorsConfiguration, policyManager auth.AuthzPolicyManager) *CollaboratorsController {
-	return &CollaboratorsController{Controller: service.NewController("CollaboratorsController"), db: db, config: config, policyManager: policyManager}
+	return &CollaboratorsController{Controller: service.NewController("CollaboratorsController"), db: db, config: config, policyManager: policyManager, organizations: organization.NewService(db)}
 }
 
 // List collaborators for the given space ID.
@@ -115,31 +124,40 @@ func (c *CollaboratorsController) List(ctx *app.ListCollaboratorsContext) error
 	})
 }
 
-// Add user's identity to the list of space collaborators.
+// Add user's identity to the list of space collaborators. The user is a time-bound collaborator if an expiry date is given.
 func (c *CollaboratorsController) Add(ctx *app.AddCollaboratorsContext) error {
+	if err := validateCollaboratorExpiry(ctx.ExpiresAt); err != nil {
+		return jsonapi.JSONErrorResponse(ctx, err)
+	}
 	identityIDs := []*app.UpdateUserID{{ID: ctx.IdentityID}}
-	err := c.updatePolicy(ctx, ctx.RequestData, ctx.SpaceID, identityIDs, c.policyManager.AddUserToPolicy)
+	_, err := c.updatePolicy(ctx, ctx.RequestData, ctx.SpaceID, identityIDs, c.policyManager.AddUserToPolicy, audit.EventTypeCollaboratorAdded, ctx.ExpiresAt)
 	if err != nil {
 		return jsonapi.JSONErrorResponse(ctx, err)
 	}
 	return ctx.OK([]byte{})
 }
 
-// AddMany adds user's identities to the list of space collaborators.
+// AddMany adds user's identities to the list of space collaborators. The users are time-bound collaborators if an expiry date is given.
+// Either all the users are added or none of them. The response lists the users who were already among the space collaborators.
 func (c *CollaboratorsController) AddMany(ctx *app.AddManyCollaboratorsContext) error {
-	if ctx.Payload != nil && ctx.Payload.Data != nil {
-		err := c.updatePolicy(ctx, ctx.RequestData, ctx.SpaceID, ctx.Payload.Data, c.policyManager.AddUserToPolicy)
-		if err != nil {
-			return jsonapi.JSONErrorResponse(ctx, err)
-		}
+	if err := validateCollaboratorExpiry(ctx.ExpiresAt); err != nil {
+		return jsonapi.JSONErrorResponse(ctx, err)
 	}
-	return ctx.OK([]byte{})
+	var identityIDs []*app.UpdateUserID
+	if ctx.Payload != nil {
+		identityIDs = ctx.Payload.Data
+	}
+	result, err := c.updatePolicy(ctx, ctx.RequestData, ctx.SpaceID, identityIDs, c.policyManager.AddUserToPolicy, audit.EventTypeCollaboratorAdded, ctx.ExpiresAt)
+	if err != nil {
+		return jsonapi.JSONErrorResponse(ctx, err)
+	}
+	return ctx.OK(result)
 }
 
 // Remove user from the list of space collaborators.
 func (c *CollaboratorsController) Remove(ctx *app.RemoveCollaboratorsContext) error {
 	identityIDs := []*app.UpdateUserID{{ID: ctx.IdentityID}}
-	err := c.updatePolicy(ctx, ctx.RequestData, ctx.SpaceID, identityIDs, c.policyManager.RemoveUserFromPolicy)
+	_, err := c.updatePolicy(ctx, ctx.RequestData, ctx.SpaceID, identityIDs, c.policyManager.RemoveUserFromPolicy, audit.EventTypeCollaboratorRemoved, nil)
 	if err != nil {
 		return jsonapi.JSONErrorResponse(ctx, err)
 	}
@@ -147,19 +165,166 @@ func (c *CollaboratorsController) Remove(ctx *app.RemoveCollaboratorsContext) er
 }
 
 // RemoveMany removes users from the list of space collaborators.
+// Either all the users are removed or none of them. The response lists the users who were not among the space collaborators.
 func (c *CollaboratorsController) RemoveMany(ctx *app.RemoveManyCollaboratorsContext) error {
-	if ctx.Payload != nil && ctx.Payload.Data != nil {
-		err := c.updatePolicy(ctx, ctx.RequestData, ctx.SpaceID, ctx.Payload.Data, c.policyManager.RemoveUserFromPolicy)
+	var identityIDs []*app.UpdateUserID
+	if ctx.Payload != nil {
+		identityIDs = ctx.Payload.Data
+	}
+	result, err := c.updatePolicy(ctx, ctx.RequestData, ctx.SpaceID, identityIDs, c.policyManager.RemoveUserFromPolicy, audit.EventTypeCollaboratorRemoved, nil)
+	if err != nil {
+		return jsonapi.JSONErrorResponse(ctx, err)
+	}
+	return ctx.OK(result)
+}
+
+// Extend extends the access of a time-bound space collaborator.
+func (c *CollaboratorsController) Extend(ctx *app.ExtendCollaboratorsContext) error {
+	currentUser, err := login.ContextIdentity(ctx)
+	if err != nil {
+		return jsonapi.JSONErrorResponse(ctx, goa.ErrUnauthorized(err.Error()))
+	}
+	if err := authorizeCollaborator(ctx, ctx.SpaceID); err != nil {
+		return jsonapi.JSONErrorResponse(ctx, err)
+	}
+	if err := validateCollaboratorExpiry(&ctx.ExpiresAt); err != nil {
+		return jsonapi.JSONErrorResponse(ctx, err)
+	}
+	err = application.Transactional(c.db, func(appl application.Application) error {
+		expiry, err := appl.SpaceCollaboratorExpiries().Load(ctx, ctx.SpaceID, ctx.IdentityID)
+		if err != nil {
+			return err
+		}
+		if expiry.Expired() {
+			return autherrors.NewNotFoundError("space collaborator expiry", ctx.IdentityID.String())
+		}
+		expiry.ExpiresAt = ctx.ExpiresAt
+		if err := appl.SpaceCollaboratorExpiries().Set(ctx, expiry); err != nil {
+			return err
+		}
+		event, err := audit.NewEvent(ctx, audit.EventTypeCollaboratorExtended, currentUser, &ctx.IdentityID, audit.ResourceTypeSpace, ctx.SpaceID.String(), map[string]interface{}{
+			"expires_at": ctx.ExpiresAt,
+		})
+		if err != nil {
+			return autherrors.NewInternalError(ctx, err)
+		}
+		return appl.AuditEvents().Create(ctx, event)
+	})
+	if err != nil {
+		return jsonapi.JSONErrorResponse(ctx, err)
+	}
+	return ctx.OK([]byte{})
+}
+
+// History lists the changes of the collaborators of the given space ID, the most recent first.
+func (c *CollaboratorsController) History(ctx *app.HistoryCollaboratorsContext) error {
+	if err := authorizeCollaborator(ctx, ctx.SpaceID); err != nil {
+		return jsonapi.JSONErrorResponse(ctx, err)
+	}
+	offset, limit := computePagingLimits(ctx.PageOffset, ctx.PageLimit)
+	var events []audit.Event
+	var count int
+	err := application.Transactional(c.db, func(appl application.Application) error {
+		if _, err := appl.SpaceResources().LoadBySpace(ctx, &ctx.SpaceID); err != nil {
+			return err
+		}
+		var err error
+		events, count, err = appl.AuditEvents().List(ctx, audit.ResourceTypeSpace, ctx.SpaceID.String(), offset, limit)
+		return err
+	})
+	if err != nil {
+		return jsonapi.JSONErrorResponse(ctx, err)
+	}
+	data := make([]*app.CollaboratorEvent, len(events))
+	for i := range events {
+		data[i] = ConvertToAppCollaboratorEvent(events[i])
+	}
+	response := app.CollaboratorEventList{
+		Links: &app.PagingLinks{},
+		Meta:  &app.CollaboratorEventListMeta{TotalCount: count},
+		Data:  data,
+	}
+	setPagingLinks(response.Links, buildAbsoluteURL(ctx.RequestData), len(events), offset, limit, count)
+	return ctx.OK(&response)
+}
+
+// ListTeams lists the teams collaborating on the given space ID.
+func (c *CollaboratorsController) ListTeams(ctx *app.ListTeamsCollaboratorsContext) error {
+	var spaceTeams []space.Team
+	err := application.Transactional(c.db, func(appl application.Application) error {
+		if _, err := appl.SpaceResources().LoadBySpace(ctx, &ctx.SpaceID); err != nil {
+			return err
+		}
+		var err error
+		spaceTeams, err = appl.SpaceTeams().List(ctx, ctx.SpaceID)
+		return err
+	})
+	if err != nil {
+		return jsonapi.JSONErrorResponse(ctx, err)
+	}
+	teams := make([]organization.Group, len(spaceTeams))
+	for i, spaceTeam := range spaceTeams {
+		team, err := c.organizations.Load(ctx, organization.ResourceTypeTeam, spaceTeam.TeamID)
 		if err != nil {
 			return jsonapi.JSONErrorResponse(ctx, err)
 		}
+		teams[i] = *team
 	}
+	return ctx.OK(&app.TeamList{Data: convertToAppTeams(teams)})
+}
 
+// AddTeam adds a team to the space collaborators. The members of the team are given access to the space,
+// and are added to the Keycloak policy of the space by the outbox dispatcher.
+func (c *CollaboratorsController) AddTeam(ctx *app.AddTeamCollaboratorsContext) error {
+	if err := authorizeCollaborator(ctx, ctx.SpaceID); err != nil {
+		return jsonapi.JSONErrorResponse(ctx, err)
+	}
+	team, err := c.organizations.Load(ctx, organization.ResourceTypeTeam, ctx.TeamID)
+	if err != nil {
+		return jsonapi.JSONErrorResponse(ctx, err)
+	}
+	spaceTeam := space.Team{SpaceID: ctx.SpaceID, TeamID: team.ResourceID, AuthURL: rest.AbsoluteURL(ctx.RequestData, "")}
+	err = application.Transactional(c.db, func(appl application.Application) error {
+		if _, err := appl.SpaceResources().LoadBySpace(ctx, &ctx.SpaceID); err != nil {
+			return err
+		}
+		if err := appl.SpaceTeams().Add(ctx, spaceTeam.SpaceID, spaceTeam.TeamID, spaceTeam.AuthURL); err != nil {
+			return err
+		}
+		return organization.EnqueueSpaceTeamMembers(ctx, appl, []space.Team{spaceTeam}, team.Members, true)
+	})
+	if err != nil {
+		return jsonapi.JSONErrorResponse(ctx, err)
+	}
 	return ctx.OK([]byte{})
 }
 
-func (c *CollaboratorsController) updatePolicy(ctx collaboratorContext, req *goa.RequestData, spaceID uuid.UUID, identityIDs []*app.UpdateUserID, update func(policy *auth.KeycloakPolicy, identityID string) bool) error {
-	// Authorize current user
+// RemoveTeam removes a team from the space collaborators. The members of the team who are not direct
+// collaborators nor members of the other teams of the space are removed from the Keycloak policy of the space
+// by the outbox dispatcher.
+func (c *CollaboratorsController) RemoveTeam(ctx *app.RemoveTeamCollaboratorsContext) error {
+	if err := authorizeCollaborator(ctx, ctx.SpaceID); err != nil {
+		return jsonapi.JSONErrorResponse(ctx, err)
+	}
+	team, err := c.organizations.Load(ctx, organization.ResourceTypeTeam, ctx.TeamID)
+	if err != nil {
+		return jsonapi.JSONErrorResponse(ctx, err)
+	}
+	spaceTeam := space.Team{SpaceID: ctx.SpaceID, TeamID: team.ResourceID, AuthURL: rest.AbsoluteURL(ctx.RequestData, "")}
+	err = application.Transactional(c.db, func(appl application.Application) error {
+		if err := appl.SpaceTeams().Remove(ctx, spaceTeam.SpaceID, spaceTeam.TeamID); err != nil {
+			return err
+		}
+		return organization.EnqueueSpaceTeamMembers(ctx, appl, []space.Team{spaceTeam}, team.Members, false)
+	})
+	if err != nil {
+		return jsonapi.JSONErrorResponse(ctx, err)
+	}
+	return ctx.OK([]byte{})
+}
+
+// authorizeCollaborator checks that the current user is among the space collaborators
+func authorizeCollaborator(ctx context.Context, spaceID uuid.UUID) error {
 	authorized, err := authz.Authorize(ctx, spaceID.String())
 	if err != nil {
 		return goa.ErrUnauthorized(err.Error())
@@ -167,89 +332,193 @@ func (c *CollaboratorsController) updatePolicy(ctx collaboratorContext, req *goa
 	if !authorized {
 		return goa.ErrUnauthorized("User not among space collaborators")
 	}
+	return nil
+}
 
-	// Update policy
-	policy, pat, err := c.getPolicy(ctx, req, spaceID)
-	if err != nil {
+// updatePolicy applies the given update to the policy of the space for each given identity and records the date
+// their access expires at, if any. The identities whose access actually changed are recorded in the audit trail
+// of the space with the given event type, on behalf of the current user.
+// The space resource is locked until the policy is updated, so the concurrent updates of the collaborators of
+// the same space are applied one after the other and none of them is lost. The policy is updated last, so
+// nothing is recorded if one of the identities can't be updated or if Keycloak rejects the new policy.
+func (c *CollaboratorsController) updatePolicy(ctx collaboratorContext, req *goa.RequestData, spaceID uuid.UUID, identityIDs []*app.UpdateUserID, update func(policy *auth.KeycloakPolicy, identityID string) bool, eventType string, expiresAt *time.Time) (*app.CollaboratorsUpdate, error) {
+	// Authorize current user
+	if err := authorizeCollaborator(ctx, spaceID); err != nil {
+		return nil, err
+	}
+	var result *app.CollaboratorsUpdate
+	err := application.Transactional(c.db, func(appl application.Application) error {
+		var err error
+		result, err = c.updatePolicyInTransaction(ctx, appl, req, spaceID, identityIDs, update, eventType, expiresAt)
 		return err
+	})
+	if err != nil {
+		return nil, err
 	}
-	updated := false
+	return result, nil
+}
+
+// updatePolicyInTransaction is updatePolicy within the given transaction, for the callers which record other changes
+// along with the update of the collaborators. The current user must have been authorized by the caller.
+func (c *CollaboratorsController) updatePolicyInTransaction(ctx context.Context, appl application.Application, req *goa.RequestData, spaceID uuid.UUID, identityIDs []*app.UpdateUserID, update func(policy *auth.KeycloakPolicy, identityID string) bool, eventType string, expiresAt *time.Time) (*app.CollaboratorsUpdate, error) {
+	currentUser, err := login.ContextIdentity(ctx)
+	if err != nil {
+		return nil, goa.ErrUnauthorized(err.Error())
+	}
+
+	result := app.CollaboratorsUpdate{
+		Updated:   []*app.UpdateUserID{},
+		Unchanged: []*app.UpdateUserID{},
+	}
+	resource, err := appl.SpaceResources().LockBySpace(ctx, spaceID)
+	if err != nil {
+		return nil, err
+	}
+	policy, pat, err := c.policyManager.GetPolicy(ctx, req, resource.PolicyID)
+	if err != nil {
+		return nil, goa.ErrInternal(err.Error())
+	}
+	var changed []uuid.UUID
 	for _, identityIDData := range identityIDs {
-		if identityIDData != nil {
-			identityID := identityIDData.ID
-			identityUUID, err := uuid.FromString(identityID)
-			if err != nil {
-				log.Error(ctx, map[string]interface{}{
-					"identity_id": identityID,
-				}, "unable to convert the identity ID to uuid v4")
-				return goa.ErrBadRequest(err.Error())
-			}
-			var identity *account.Identity
-			var ownerID uuid.UUID
-			err = application.Transactional(c.db, func(appl application.Application) error {
-				identities, err := appl.Identities().Query(account.IdentityFilterByID(identityUUID), account.IdentityWithUser())
-				if err != nil {
-					log.Error(ctx, map[string]interface{}{
-						"identity_id": identityID,
-						"err":         err,
-					}, "unable to query for the identity")
-					return err
-				}
-				if len(identities) == 0 {
-					log.Error(ctx, map[string]interface{}{
-						"identity_id": identityID,
-					}, "unable to find the identity")
-					return autherrors.NewNotFoundError("identity", identityID)
-				}
-				identity = &identities[0]
-				resource, err := appl.SpaceResources().LoadBySpace(ctx, &spaceID)
-				if err != nil {
-					return err
-				}
-				ownerID = resource.OwnerID
-				return nil
-			})
-			if err != nil {
-				return err
-			}
-			updated = update(policy, identityID) || updated
-			if !strings.Contains(policy.Config.UserIDs, ownerID.String()) {
-				// Updated policy has no User IDs
-				return autherrors.NewBadParameterError("identity", identityID).Expected("not the space owner")
+		if identityIDData == nil {
+			continue
+		}
+		identityID := identityIDData.ID
+		identityUUID, err := uuid.FromString(identityID)
+		if err != nil {
+			log.Error(ctx, map[string]interface{}{
+				"identity_id": identityID,
+			}, "unable to convert the identity ID to uuid v4")
+			return nil, goa.ErrBadRequest(err.Error())
+		}
+		identities, err := appl.Identities().Query(account.IdentityFilterByID(identityUUID), account.IdentityWithUser())
+		if err != nil {
+			log.Error(ctx, map[string]interface{}{
+				"identity_id": identityID,
+				"err":         err,
+			}, "unable to query for the identity")
+			return nil, err
+		}
+		if len(identities) == 0 {
+			log.Error(ctx, map[string]interface{}{
+				"identity_id": identityID,
+			}, "unable to find the identity")
+			return nil, autherrors.NewNotFoundError("identity", identityID)
+		}
+		identityIDResult := &app.UpdateUserID{ID: identityID, Type: "identities"}
+		if update(policy, identityID) {
+			changed = append(changed, identityUUID)
+			result.Updated = append(result.Updated, identityIDResult)
+		} else {
+			result.Unchanged = append(result.Unchanged, identityIDResult)
+		}
+		if !strings.Contains(policy.Config.UserIDs, resource.OwnerID.String()) {
+			// Updated policy has no User IDs
+			return nil, autherrors.NewBadParameterError("identity", identityID).Expected("not the space owner")
+		}
+		if err := c.setCollaboratorExpiry(ctx, appl, req, resource, identityUUID, expiresAt); err != nil {
+			return nil, err
+		}
+		if eventType == audit.EventTypeCollaboratorAdded {
+			// a member of the teams of the space added as direct collaborator stays in the policy when it leaves the teams
+			if _, err := appl.SpaceTeams().RemoveGrant(ctx, spaceID, identityUUID); err != nil {
+				return nil, err
 			}
 		}
 	}
-	if !updated {
+	if len(changed) == 0 {
 		// Nothing changed. No need to update
-		return nil
+		return &result, nil
 	}
 
-	err = c.policyManager.UpdatePolicy(ctx, req, *policy, *pat)
+	for i := range changed {
+		event, err := audit.NewEvent(ctx, eventType, currentUser, &changed[i], audit.ResourceTypeSpace, spaceID.String(), nil)
+		if err != nil {
+			return nil, err
+		}
+		if err := appl.AuditEvents().Create(ctx, event); err != nil {
+			return nil, err
+		}
+		// the collaborators events have the same types in the audit trail and in the webhooks
+		if err := webhook.Emit(ctx, appl, eventType, webhook.Data{IdentityID: changed[i], SpaceID: &spaceID}); err != nil {
+			return nil, err
+		}
+		if eventType == audit.EventTypeCollaboratorRemoved {
+			if err := keepTeamMember(ctx, appl, req, spaceID, changed[i]); err != nil {
+				return nil, err
+			}
+		}
+	}
+	// We need to update the resource to triger RPT token refreshing when users try to access this space
+	_, err = appl.SpaceResources().Save(ctx, resource)
 	if err != nil {
-		return goa.ErrInternal(err.Error())
+		log.Error(ctx, map[string]interface{}{
+			"resource":   resource,
+			"space_uuid": spaceID.String(),
+			"err":        err,
+		}, "unable to update the space resource")
+		return nil, err
+	}
+	if err := c.policyManager.UpdatePolicy(ctx, req, *policy, *pat); err != nil {
+		return nil, goa.ErrInternal(err.Error())
 	}
+	return &result, nil
+}
 
-	// We need to update the resource to triger RPT token refreshing when users try to access this space
-	err = application.Transactional(c.db, func(appl application.Application) error {
-		resource, err := appl.SpaceResources().LoadBySpace(ctx, &spaceID)
-		_, err = appl.SpaceResources().Save(ctx, resource)
-		if err != nil {
-			log.Error(ctx, map[string]interface{}{
-				"resource":   resource,
-				"space_uuid": spaceID.String(),
-				"err":        err,
-			}, "unable to update the space resource")
-			return err
-		}
-		return nil
-	})
+// keepTeamMember enqueues the addition of the given collaborator removed from the policy of the space back to the
+// policy if it's a member of the teams collaborating on the space, since the teams still grant it the space access
+func keepTeamMember(ctx context.Context, appl application.Application, req *goa.RequestData, spaceID uuid.UUID, identityID uuid.UUID) error {
+	member, err := appl.SpaceTeams().HasMember(ctx, spaceID, identityID)
+	if err != nil || !member {
+		return err
+	}
+	event, err := outbox.NewTeamMemberAddedEvent(identityID, spaceID, rest.AbsoluteURL(req, ""))
 	if err != nil {
-		return goa.ErrInternal(err.Error())
+		return autherrors.NewInternalError(ctx, err)
 	}
+	return appl.OutboxEvents().Create(ctx, event)
+}
 
+// validateCollaboratorExpiry checks that the given expiry date of the collaborators, if any, is in the future
+func validateCollaboratorExpiry(expiresAt *time.Time) error {
+	if expiresAt != nil && !expiresAt.After(time.Now()) {
+		return autherrors.NewBadParameterError("expires_at", *expiresAt).Expected("a date in the future")
+	}
 	return nil
 }
 
+// setCollaboratorExpiry records the date the access of the given collaborator of the space expires at,
+// or removes their time limit if the date is nil. The space owner can't be a time-bound collaborator.
+func (c *CollaboratorsController) setCollaboratorExpiry(ctx context.Context, appl application.Application, req *goa.RequestData, resource *space.Resource, identityID uuid.UUID, expiresAt *time.Time) error {
+	if expiresAt == nil {
+		return appl.SpaceCollaboratorExpiries().Delete(ctx, resource.SpaceID, identityID)
+	}
+	if uuid.Equal(identityID, resource.OwnerID) {
+		return autherrors.NewBadParameterError("identity", identityID.String()).Expected("not the space owner")
+	}
+	return appl.SpaceCollaboratorExpiries().Set(ctx, &space.CollaboratorExpiry{
+		SpaceID:    resource.SpaceID,
+		IdentityID: identityID,
+		ExpiresAt:  *expiresAt,
+		AuthURL:    rest.AbsoluteURL(req, ""),
+	})
+}
+
+// ConvertToAppCollaboratorEvent converts an audit event about a space collaborator to its REST representation
+func ConvertToAppCollaboratorEvent(event audit.Event) *app.CollaboratorEvent {
+	return &app.CollaboratorEvent{
+		Type: "collaboratorevents",
+		ID:   event.ID.String(),
+		Attributes: &app.CollaboratorEventAttributes{
+			EventType: &event.EventType,
+			ActorID:   event.ActorID,
+			TargetID:  event.TargetID,
+			RequestID: &event.RequestID,
+			CreatedAt: &event.CreatedAt,
+		},
+	}
+}
+
 func (c *CollaboratorsController) getPolicy(ctx collaboratorContext, req *goa.RequestData, spaceID uuid.UUID) (*auth.KeycloakPolicy, *string, error) {
 	var policyID string
 	err := application.Transactional(c.db, func(appl application.Application) error {
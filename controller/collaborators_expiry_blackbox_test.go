@@ -0,0 +1,89 @@
+package controller_test
+
+import (
+	"time"
+
+	"github.com/fabric8-services/fabric8-auth/app"
+	"github.com/fabric8-services/fabric8-auth/app/test"
+	"github.com/fabric8-services/fabric8-auth/audit"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func (rest *TestCollaboratorsREST) TestAddTimeBoundCollaboratorAndExtendOK() {
+	// given
+	rest.policy.AddUserToPolicy(rest.testIdentity1.ID.String())
+	svc, ctrl := rest.SecuredController()
+	expiresAt := time.Now().Add(time.Hour)
+
+	// when
+	test.AddCollaboratorsOK(rest.T(), svc.Context, svc, ctrl, rest.spaceID, rest.testIdentity2.ID.String(), &expiresAt)
+
+	// then
+	assert.Contains(rest.T(), rest.policy.Config.UserIDs, rest.testIdentity2.ID.String())
+	expiry, err := rest.Application.SpaceCollaboratorExpiries().Load(svc.Context, rest.spaceID, rest.testIdentity2.ID)
+	require.Nil(rest.T(), err)
+	assert.WithinDuration(rest.T(), expiresAt, expiry.ExpiresAt, time.Second)
+
+	// when the access of the collaborator is extended
+	extendedAt := time.Now().Add(24 * time.Hour)
+	test.ExtendCollaboratorsOK(rest.T(), svc.Context, svc, ctrl, rest.spaceID, rest.testIdentity2.ID, extendedAt)
+
+	// then
+	expiry, err = rest.Application.SpaceCollaboratorExpiries().Load(svc.Context, rest.spaceID, rest.testIdentity2.ID)
+	require.Nil(rest.T(), err)
+	assert.WithinDuration(rest.T(), extendedAt, expiry.ExpiresAt, time.Second)
+	events, err := rest.Application.AuditEvents().Query(audit.EventFilterByResource(audit.ResourceTypeSpace, rest.spaceID.String()), audit.EventFilterByTargetID(rest.testIdentity2.ID))
+	require.Nil(rest.T(), err)
+	require.Len(rest.T(), events, 1)
+	assert.Equal(rest.T(), audit.EventTypeCollaboratorExtended, events[0].EventType)
+	assert.Equal(rest.T(), rest.testIdentity1.ID, *events[0].ActorID)
+
+	// when the collaborator is added again without time limit
+	payload := &app.AddManyCollaboratorsPayload{Data: []*app.UpdateUserID{{ID: rest.testIdentity2.ID.String(), Type: idnType}}}
+	test.AddManyCollaboratorsOK(rest.T(), svc.Context, svc, ctrl, rest.spaceID, nil, payload)
+
+	// then the access doesn't expire anymore
+	_, err = rest.Application.SpaceCollaboratorExpiries().Load(svc.Context, rest.spaceID, rest.testIdentity2.ID)
+	require.NotNil(rest.T(), err)
+	test.ExtendCollaboratorsNotFound(rest.T(), svc.Context, svc, ctrl, rest.spaceID, rest.testIdentity2.ID, extendedAt)
+}
+
+func (rest *TestCollaboratorsREST) TestRemoveTimeBoundCollaboratorOK() {
+	// given
+	rest.policy.AddUserToPolicy(rest.testIdentity1.ID.String())
+	svc, ctrl := rest.SecuredController()
+	expiresAt := time.Now().Add(time.Hour)
+	test.AddCollaboratorsOK(rest.T(), svc.Context, svc, ctrl, rest.spaceID, rest.testIdentity2.ID.String(), &expiresAt)
+
+	// when
+	test.RemoveCollaboratorsOK(rest.T(), svc.Context, svc, ctrl, rest.spaceID, rest.testIdentity2.ID.String())
+
+	// then
+	assert.NotContains(rest.T(), rest.policy.Config.UserIDs, rest.testIdentity2.ID.String())
+	_, err := rest.Application.SpaceCollaboratorExpiries().Load(svc.Context, rest.spaceID, rest.testIdentity2.ID)
+	require.NotNil(rest.T(), err)
+}
+
+func (rest *TestCollaboratorsREST) TestAddTimeBoundCollaboratorBadRequest() {
+	// given
+	rest.policy.AddUserToPolicy(rest.testIdentity1.ID.String())
+	svc, ctrl := rest.SecuredController()
+
+	// the access can't expire in the past
+	expiredAt := time.Now().Add(-time.Hour)
+	test.AddCollaboratorsBadRequest(rest.T(), svc.Context, svc, ctrl, rest.spaceID, rest.testIdentity2.ID.String(), &expiredAt)
+	test.ExtendCollaboratorsBadRequest(rest.T(), svc.Context, svc, ctrl, rest.spaceID, rest.testIdentity2.ID, expiredAt)
+	// the space owner can't be a time-bound collaborator
+	expiresAt := time.Now().Add(time.Hour)
+	test.AddCollaboratorsBadRequest(rest.T(), svc.Context, svc, ctrl, rest.spaceID, rest.testIdentity1.ID.String(), &expiresAt)
+}
+
+func (rest *TestCollaboratorsREST) TestExtendCollaboratorUnauthorizedIfCurrentUserIsNotCollaborator() {
+	// given
+	svc, ctrl := rest.SecuredController()
+
+	// when/then
+	test.ExtendCollaboratorsUnauthorized(rest.T(), svc.Context, svc, ctrl, rest.spaceID, rest.testIdentity2.ID, time.Now().Add(time.Hour))
+}
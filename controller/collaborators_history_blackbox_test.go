@@ -0,0 +1,51 @@
+package controller_test
+
+import (
+	"github.com/fabric8-services/fabric8-auth/app/test"
+	"github.com/fabric8-services/fabric8-auth/audit"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func (rest *TestCollaboratorsREST) TestHistoryCollaboratorsOK() {
+	// given
+	rest.policy.AddUserToPolicy(rest.testIdentity1.ID.String())
+	svc, ctrl := rest.SecuredController()
+	test.AddCollaboratorsOK(rest.T(), svc.Context, svc, ctrl, rest.spaceID, rest.testIdentity2.ID.String(), nil)
+	// adding the same collaborator again doesn't change anything
+	test.AddCollaboratorsOK(rest.T(), svc.Context, svc, ctrl, rest.spaceID, rest.testIdentity2.ID.String(), nil)
+	test.RemoveCollaboratorsOK(rest.T(), svc.Context, svc, ctrl, rest.spaceID, rest.testIdentity2.ID.String())
+
+	// when
+	_, history := test.HistoryCollaboratorsOK(rest.T(), svc.Context, svc, ctrl, rest.spaceID, nil, nil)
+
+	// then the most recent change comes first
+	require.Len(rest.T(), history.Data, 2)
+	assert.Equal(rest.T(), 2, history.Meta.TotalCount)
+	assert.Equal(rest.T(), audit.EventTypeCollaboratorRemoved, *history.Data[0].Attributes.EventType)
+	assert.Equal(rest.T(), audit.EventTypeCollaboratorAdded, *history.Data[1].Attributes.EventType)
+	for _, event := range history.Data {
+		assert.Equal(rest.T(), "collaboratorevents", event.Type)
+		assert.Equal(rest.T(), rest.testIdentity1.ID, *event.Attributes.ActorID)
+		assert.Equal(rest.T(), rest.testIdentity2.ID, *event.Attributes.TargetID)
+	}
+
+	// when the history is paged
+	offset := "1"
+	limit := 1
+	_, history = test.HistoryCollaboratorsOK(rest.T(), svc.Context, svc, ctrl, rest.spaceID, &offset, &limit)
+
+	// then
+	require.Len(rest.T(), history.Data, 1)
+	assert.Equal(rest.T(), 2, history.Meta.TotalCount)
+	assert.Equal(rest.T(), audit.EventTypeCollaboratorAdded, *history.Data[0].Attributes.EventType)
+}
+
+func (rest *TestCollaboratorsREST) TestHistoryCollaboratorsUnauthorizedIfCurrentUserIsNotCollaborator() {
+	// given
+	svc, ctrl := rest.SecuredController()
+
+	// when/then
+	test.HistoryCollaboratorsUnauthorized(rest.T(), svc.Context, svc, ctrl, rest.spaceID, nil, nil)
+}
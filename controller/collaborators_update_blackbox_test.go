@@ -0,0 +1,64 @@
+package controller_test
+
+import (
+	"time"
+
+	"github.com/fabric8-services/fabric8-auth/app"
+	"github.com/fabric8-services/fabric8-auth/app/test"
+	"github.com/fabric8-services/fabric8-auth/audit"
+
+	uuid "github.com/satori/go.uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func (rest *TestCollaboratorsREST) TestAddManyCollaboratorsListsUnchangedCollaborators() {
+	// given
+	rest.policy.AddUserToPolicy(rest.testIdentity1.ID.String())
+	svc, ctrl := rest.SecuredController()
+	payload := &app.AddManyCollaboratorsPayload{Data: []*app.UpdateUserID{{ID: rest.testIdentity1.ID.String(), Type: idnType}, {ID: rest.testIdentity2.ID.String(), Type: idnType}}}
+
+	// when
+	_, result := test.AddManyCollaboratorsOK(rest.T(), svc.Context, svc, ctrl, rest.spaceID, nil, payload)
+
+	// then
+	require.Len(rest.T(), result.Updated, 1)
+	assert.Equal(rest.T(), rest.testIdentity2.ID.String(), result.Updated[0].ID)
+	require.Len(rest.T(), result.Unchanged, 1)
+	assert.Equal(rest.T(), rest.testIdentity1.ID.String(), result.Unchanged[0].ID)
+}
+
+func (rest *TestCollaboratorsREST) TestRemoveManyCollaboratorsListsMissingCollaborators() {
+	// given
+	rest.policy.AddUserToPolicy(rest.testIdentity1.ID.String())
+	rest.policy.AddUserToPolicy(rest.testIdentity2.ID.String())
+	svc, ctrl := rest.SecuredController()
+	payload := &app.RemoveManyCollaboratorsPayload{Data: []*app.UpdateUserID{{ID: rest.testIdentity2.ID.String(), Type: idnType}, {ID: rest.testIdentity3.ID.String(), Type: idnType}}}
+
+	// when
+	_, result := test.RemoveManyCollaboratorsOK(rest.T(), svc.Context, svc, ctrl, rest.spaceID, payload)
+
+	// then
+	require.Len(rest.T(), result.Updated, 1)
+	assert.Equal(rest.T(), rest.testIdentity2.ID.String(), result.Updated[0].ID)
+	require.Len(rest.T(), result.Unchanged, 1)
+	assert.Equal(rest.T(), rest.testIdentity3.ID.String(), result.Unchanged[0].ID)
+}
+
+func (rest *TestCollaboratorsREST) TestAddManyCollaboratorsNothingRecordedIfOneIdentityIsUnknown() {
+	// given
+	rest.policy.AddUserToPolicy(rest.testIdentity1.ID.String())
+	svc, ctrl := rest.SecuredController()
+	expiresAt := time.Now().Add(time.Hour)
+	payload := &app.AddManyCollaboratorsPayload{Data: []*app.UpdateUserID{{ID: rest.testIdentity2.ID.String(), Type: idnType}, {ID: uuid.NewV4().String(), Type: idnType}}}
+
+	// when
+	test.AddManyCollaboratorsNotFound(rest.T(), svc.Context, svc, ctrl, rest.spaceID, &expiresAt, payload)
+
+	// then neither the expiry nor the change of the known identity is recorded
+	_, err := rest.Application.SpaceCollaboratorExpiries().Load(svc.Context, rest.spaceID, rest.testIdentity2.ID)
+	require.NotNil(rest.T(), err)
+	events, err := rest.Application.AuditEvents().Query(audit.EventFilterByResource(audit.ResourceTypeSpace, rest.spaceID.String()))
+	require.Nil(rest.T(), err)
+	assert.Empty(rest.T(), events)
+}
@@ -0,0 +1,171 @@
+package controller
+
+import (
+	"time"
+
+	"github.com/fabric8-services/fabric8-auth/account"
+	"github.com/fabric8-services/fabric8-auth/app"
+	"github.com/fabric8-services/fabric8-auth/application"
+	"github.com/fabric8-services/fabric8-auth/errors"
+	"github.com/fabric8-services/fabric8-auth/invitation"
+	"github.com/fabric8-services/fabric8-auth/jsonapi"
+	"github.com/fabric8-services/fabric8-auth/login"
+	"github.com/fabric8-services/fabric8-auth/rest"
+	"github.com/fabric8-services/fabric8-auth/webhook"
+
+	"github.com/goadesign/goa"
+)
+
+type invitationsConfiguration interface {
+	GetInvitationExpiry() time.Duration
+}
+
+// InvitationsController implements the invitations resource.
+type InvitationsController struct {
+	*goa.Controller
+	db     application.DB
+	config invitationsConfiguration
+}
+
+// NewInvitationsController creates an invitations controller.
+func NewInvitationsController(service *goa.Service, db application.DB, config invitationsConfiguration) *InvitationsController {
+	return &InvitationsController{Controller: service.NewController("InvitationsController"), db: db, config: config}
+}
+
+// Create invites a user to collaborate on the space, by email or username. Only the space collaborators can invite other users.
+func (c *InvitationsController) Create(ctx *app.CreateInvitationsContext) error {
+	currentUser, err := login.ContextIdentity(ctx)
+	if err != nil {
+		return jsonapi.JSONErrorResponse(ctx, goa.ErrUnauthorized(err.Error()))
+	}
+	if err := authorizeCollaborator(ctx, ctx.SpaceID); err != nil {
+		return jsonapi.JSONErrorResponse(ctx, err)
+	}
+	attributes := ctx.Payload.Data.Attributes
+	if stringValue(attributes.Email) == "" && stringValue(attributes.Username) == "" {
+		return jsonapi.JSONErrorResponse(ctx, errors.NewBadParameterError("data.attributes", nil).Expected("an email or a username"))
+	}
+	inv := invitation.Invitation{
+		ResourceType: invitation.ResourceTypeSpace,
+		ResourceID:   ctx.SpaceID,
+		Role:         invitation.RoleCollaborator,
+		InviterID:    *currentUser,
+		Email:        attributes.Email,
+		Username:     attributes.Username,
+		ExpiresAt:    time.Now().Add(c.config.GetInvitationExpiry()),
+		State:        invitation.StatePending,
+	}
+	err = application.Transactional(c.db, func(appl application.Application) error {
+		if _, err := appl.SpaceResources().LoadBySpace(ctx, &ctx.SpaceID); err != nil {
+			return err
+		}
+		return appl.Invitations().Create(ctx, &inv)
+	})
+	if err != nil {
+		return jsonapi.JSONErrorResponse(ctx, err)
+	}
+	return ctx.Created(&app.InvitationSingle{Data: ConvertToAppInvitation(inv)})
+}
+
+// List lists the pending invitations to collaborate on the space.
+func (c *InvitationsController) List(ctx *app.ListInvitationsContext) error {
+	if err := authorizeCollaborator(ctx, ctx.SpaceID); err != nil {
+		return jsonapi.JSONErrorResponse(ctx, err)
+	}
+	var invitations []invitation.Invitation
+	err := application.Transactional(c.db, func(appl application.Application) error {
+		if _, err := appl.SpaceResources().LoadBySpace(ctx, &ctx.SpaceID); err != nil {
+			return err
+		}
+		var err error
+		invitations, err = appl.Invitations().Query(ctx, invitation.InvitationFilterByResource(invitation.ResourceTypeSpace, ctx.SpaceID), invitation.InvitationFilterPending())
+		return err
+	})
+	if err != nil {
+		return jsonapi.JSONErrorResponse(ctx, err)
+	}
+	data := make([]*app.Invitation, len(invitations))
+	for i := range invitations {
+		data[i] = ConvertToAppInvitation(invitations[i])
+	}
+	return ctx.OK(&app.InvitationList{Data: data})
+}
+
+// Cancel cancels a pending invitation to collaborate on the space.
+func (c *InvitationsController) Cancel(ctx *app.CancelInvitationsContext) error {
+	if err := authorizeCollaborator(ctx, ctx.SpaceID); err != nil {
+		return jsonapi.JSONErrorResponse(ctx, err)
+	}
+	err := application.Transactional(c.db, func(appl application.Application) error {
+		inv, err := appl.Invitations().Lock(ctx, ctx.InvitationID)
+		if err != nil {
+			return err
+		}
+		if inv.ResourceType != invitation.ResourceTypeSpace || inv.ResourceID != ctx.SpaceID {
+			return errors.NewNotFoundError("invitation", ctx.InvitationID.String())
+		}
+		if inv.State != invitation.StatePending {
+			return errors.NewBadParameterError("invitation", ctx.InvitationID.String()).Expected("a pending invitation")
+		}
+		inv.State = invitation.StateCancelled
+		return appl.Invitations().Save(ctx, inv)
+	})
+	if err != nil {
+		return jsonapi.JSONErrorResponse(ctx, err)
+	}
+	return ctx.OK([]byte{})
+}
+
+// Accept accepts an invitation sent to the verified email or username of the current user, who is added to the space collaborators.
+func (c *InvitationsController) Accept(ctx *app.AcceptInvitationsContext) error {
+	currentUser, err := login.ContextIdentity(ctx)
+	if err != nil {
+		return jsonapi.JSONErrorResponse(ctx, goa.ErrUnauthorized(err.Error()))
+	}
+	var inv *invitation.Invitation
+	err = application.Transactional(c.db, func(appl application.Application) error {
+		identities, err := appl.Identities().Query(account.IdentityFilterByID(*currentUser), account.IdentityWithUser())
+		if err != nil {
+			return err
+		}
+		if len(identities) == 0 {
+			return errors.NewUnauthorizedError("unknown identity")
+		}
+		inv, err = appl.Invitations().Lock(ctx, ctx.InvitationID)
+		if err != nil {
+			return err
+		}
+		// the invitations sent to other users, or to an email which is not verified yet, are not disclosed
+		if !inv.SentTo(identities[0].User.VerifiedEmail(), identities[0].Username) {
+			return errors.NewNotFoundError("invitation", ctx.InvitationID.String())
+		}
+		err = invitation.Accept(ctx, appl.Invitations(), appl.OutboxEvents(), appl.AuditEvents(), inv, *currentUser, rest.AbsoluteURL(ctx.RequestData, ""))
+		if err != nil {
+			return err
+		}
+		return webhook.Emit(ctx, appl, webhook.EventTypeCollaboratorAdded, webhook.Data{IdentityID: *currentUser, Username: identities[0].Username, SpaceID: &inv.ResourceID})
+	})
+	if err != nil {
+		return jsonapi.JSONErrorResponse(ctx, err)
+	}
+	return ctx.OK(&app.InvitationSingle{Data: ConvertToAppInvitation(*inv)})
+}
+
+// ConvertToAppInvitation converts an invitation to its REST representation
+func ConvertToAppInvitation(inv invitation.Invitation) *app.Invitation {
+	return &app.Invitation{
+		Type: "invitations",
+		ID:   inv.ID.String(),
+		Attributes: &app.InvitationAttributes{
+			Email:      inv.Email,
+			Username:   inv.Username,
+			SpaceID:    &inv.ResourceID,
+			Role:       &inv.Role,
+			State:      &inv.State,
+			InviterID:  &inv.InviterID,
+			AcceptedBy: inv.AcceptedBy,
+			ExpiresAt:  &inv.ExpiresAt,
+			CreatedAt:  &inv.CreatedAt,
+		},
+	}
+}
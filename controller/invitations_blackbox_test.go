@@ -0,0 +1,112 @@
+package controller_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/fabric8-services/fabric8-auth/account"
+	"github.com/fabric8-services/fabric8-auth/app/test"
+	. "github.com/fabric8-services/fabric8-auth/controller"
+	"github.com/fabric8-services/fabric8-auth/gormtestsupport"
+	"github.com/fabric8-services/fabric8-auth/invitation"
+	"github.com/fabric8-services/fabric8-auth/resource"
+	testsupport "github.com/fabric8-services/fabric8-auth/test"
+
+	"github.com/goadesign/goa"
+	"github.com/satori/go.uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+)
+
+type TestInvitationsREST struct {
+	gormtestsupport.DBTestSuite
+	inviter account.Identity
+	invitee account.Identity
+	email   string
+}
+
+func TestRunInvitationsREST(t *testing.T) {
+	resource.Require(t, resource.Database)
+	suite.Run(t, &TestInvitationsREST{DBTestSuite: gormtestsupport.NewDBTestSuite()})
+}
+
+func (rest *TestInvitationsREST) SetupTest() {
+	rest.DBTestSuite.SetupTest()
+	var err error
+	rest.inviter, err = testsupport.CreateTestIdentity(rest.DB, "invitations-inviter-"+uuid.NewV4().String(), account.KeycloakIDP)
+	require.Nil(rest.T(), err)
+	rest.email = "invitations-invitee-" + uuid.NewV4().String() + "@email.com"
+	user := account.User{ID: uuid.NewV4(), Email: rest.email}
+	require.Nil(rest.T(), rest.Application.Users().Create(rest.Ctx, &user))
+	rest.invitee = account.Identity{
+		Username:     "invitations-invitee-" + uuid.NewV4().String(),
+		ProviderType: account.KeycloakIDP,
+		UserID:       account.NullUUID{UUID: user.ID, Valid: true},
+	}
+	require.Nil(rest.T(), testsupport.CreateTestIdentityForAccountIdentity(rest.DB, &rest.invitee))
+}
+
+func (rest *TestInvitationsREST) SecuredController(identity account.Identity) (*goa.Service, *InvitationsController) {
+	svc := testsupport.ServiceAsUser("Invitations-Service", identity)
+	return svc, NewInvitationsController(svc, rest.Application, rest.Configuration)
+}
+
+func (rest *TestInvitationsREST) createInvitation() *invitation.Invitation {
+	inv := &invitation.Invitation{
+		ResourceType: invitation.ResourceTypeSpace,
+		ResourceID:   uuid.NewV4(),
+		Role:         invitation.RoleCollaborator,
+		InviterID:    rest.inviter.ID,
+		Email:        &rest.email,
+		ExpiresAt:    time.Now().Add(time.Hour),
+	}
+	require.Nil(rest.T(), rest.Application.Invitations().Create(rest.Ctx, inv))
+	return inv
+}
+
+func (rest *TestInvitationsREST) verifyEmail() {
+	user, err := rest.Application.Users().Load(rest.Ctx, rest.invitee.UserID.UUID)
+	require.Nil(rest.T(), err)
+	user.EmailVerified = true
+	require.Nil(rest.T(), rest.Application.Users().Save(rest.Ctx, user))
+}
+
+func (rest *TestInvitationsREST) TestAcceptByVerifiedEmailOK() {
+	// given
+	rest.verifyEmail()
+	inv := rest.createInvitation()
+	svc, ctrl := rest.SecuredController(rest.invitee)
+
+	// when
+	_, accepted := test.AcceptInvitationsOK(rest.T(), svc.Context, svc, ctrl, inv.ID)
+
+	// then
+	require.NotNil(rest.T(), accepted.Data)
+	assert.Equal(rest.T(), invitation.StateAccepted, *accepted.Data.Attributes.State)
+	assert.Equal(rest.T(), rest.invitee.ID, *accepted.Data.Attributes.AcceptedBy)
+}
+
+func (rest *TestInvitationsREST) TestAcceptByUnverifiedEmailNotFound() {
+	// given an invitee whose email was not verified in Keycloak
+	inv := rest.createInvitation()
+	svc, ctrl := rest.SecuredController(rest.invitee)
+
+	// when
+	test.AcceptInvitationsNotFound(rest.T(), svc.Context, svc, ctrl, inv.ID)
+
+	// then the invitation is still pending
+	loaded, err := rest.Application.Invitations().Load(rest.Ctx, inv.ID)
+	require.Nil(rest.T(), err)
+	assert.Equal(rest.T(), invitation.StatePending, loaded.State)
+	assert.Nil(rest.T(), loaded.AcceptedBy)
+}
+
+func (rest *TestInvitationsREST) TestAcceptSentToOtherUserNotFound() {
+	// given
+	inv := rest.createInvitation()
+	svc, ctrl := rest.SecuredController(rest.inviter)
+
+	// when/then
+	test.AcceptInvitationsNotFound(rest.T(), svc.Context, svc, ctrl, inv.ID)
+}
@@ -38,6 +38,9 @@ type LoginConfiguration interface {
 	GetKeycloakURL() string
 	GetKeycloakRealm() string
 	GetServiceAccounts() map[string]configuration.ServiceAccount
+	GetClusterPlacementStrategy() string
+	GetClusterPlacementRegion() string
+	GetClusterAutoLinkClients() []string
 }
 
 // LoginController implements the login resource.
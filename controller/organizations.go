@@ -0,0 +1,194 @@
+package controller
+
+import (
+	"github.com/fabric8-services/fabric8-auth/app"
+	"github.com/fabric8-services/fabric8-auth/application"
+	"github.com/fabric8-services/fabric8-auth/authorization/organization"
+	"github.com/fabric8-services/fabric8-auth/jsonapi"
+	"github.com/fabric8-services/fabric8-auth/login"
+
+	"github.com/goadesign/goa"
+	uuid "github.com/satori/go.uuid"
+)
+
+// OrganizationsController implements the organizations resource.
+type OrganizationsController struct {
+	*goa.Controller
+	organizations *organization.Service
+}
+
+// NewOrganizationsController creates an organizations controller.
+func NewOrganizationsController(service *goa.Service, db application.DB) *OrganizationsController {
+	return &OrganizationsController{Controller: service.NewController("OrganizationsController"), organizations: organization.NewService(db)}
+}
+
+// Create creates an organization with the current user as admin.
+func (c *OrganizationsController) Create(ctx *app.CreateOrganizationsContext) error {
+	currentUser, err := login.ContextIdentity(ctx)
+	if err != nil {
+		return jsonapi.JSONErrorResponse(ctx, goa.ErrUnauthorized(err.Error()))
+	}
+	attributes := ctx.Payload.Data.Attributes
+	org, err := c.organizations.Create(ctx, organization.ResourceTypeOrganization, *currentUser, stringValue(attributes.Name), stringValue(attributes.Description), nil)
+	if err != nil {
+		return jsonapi.JSONErrorResponse(ctx, err)
+	}
+	return ctx.Created(&app.OrganizationSingle{Data: ConvertToAppOrganization(*org)})
+}
+
+// List lists the organizations the current user is a member of.
+func (c *OrganizationsController) List(ctx *app.ListOrganizationsContext) error {
+	currentUser, err := login.ContextIdentity(ctx)
+	if err != nil {
+		return jsonapi.JSONErrorResponse(ctx, goa.ErrUnauthorized(err.Error()))
+	}
+	orgs, err := c.organizations.List(ctx, *currentUser)
+	if err != nil {
+		return jsonapi.JSONErrorResponse(ctx, err)
+	}
+	data := make([]*app.Organization, len(orgs))
+	for i := range orgs {
+		data[i] = ConvertToAppOrganization(orgs[i])
+	}
+	return ctx.OK(&app.OrganizationList{Data: data})
+}
+
+// Show returns the organization with the given ID.
+func (c *OrganizationsController) Show(ctx *app.ShowOrganizationsContext) error {
+	org, err := c.organizations.Load(ctx, organization.ResourceTypeOrganization, ctx.OrganizationID)
+	if err != nil {
+		return jsonapi.JSONErrorResponse(ctx, err)
+	}
+	return ctx.OK(&app.OrganizationSingle{Data: ConvertToAppOrganization(*org)})
+}
+
+// Update updates the name and description of the organization with the given ID.
+func (c *OrganizationsController) Update(ctx *app.UpdateOrganizationsContext) error {
+	currentUser, err := login.ContextIdentity(ctx)
+	if err != nil {
+		return jsonapi.JSONErrorResponse(ctx, goa.ErrUnauthorized(err.Error()))
+	}
+	attributes := ctx.Payload.Data.Attributes
+	org, err := c.organizations.Update(ctx, organization.ResourceTypeOrganization, *currentUser, ctx.OrganizationID, attributes.Name, attributes.Description)
+	if err != nil {
+		return jsonapi.JSONErrorResponse(ctx, err)
+	}
+	return ctx.OK(&app.OrganizationSingle{Data: ConvertToAppOrganization(*org)})
+}
+
+// Delete deletes the organization with the given ID and all its teams.
+func (c *OrganizationsController) Delete(ctx *app.DeleteOrganizationsContext) error {
+	currentUser, err := login.ContextIdentity(ctx)
+	if err != nil {
+		return jsonapi.JSONErrorResponse(ctx, goa.ErrUnauthorized(err.Error()))
+	}
+	err = c.organizations.Delete(ctx, organization.ResourceTypeOrganization, *currentUser, ctx.OrganizationID)
+	if err != nil {
+		return jsonapi.JSONErrorResponse(ctx, err)
+	}
+	return ctx.OK([]byte{})
+}
+
+// AddMember adds a user to the members or admins of the organization.
+func (c *OrganizationsController) AddMember(ctx *app.AddMemberOrganizationsContext) error {
+	currentUser, err := login.ContextIdentity(ctx)
+	if err != nil {
+		return jsonapi.JSONErrorResponse(ctx, goa.ErrUnauthorized(err.Error()))
+	}
+	org, err := c.organizations.AddMember(ctx, organization.ResourceTypeOrganization, *currentUser, ctx.OrganizationID, ctx.IdentityID, ctx.Role, ctx.ExpiresAt)
+	if err != nil {
+		return jsonapi.JSONErrorResponse(ctx, err)
+	}
+	return ctx.OK(&app.OrganizationSingle{Data: ConvertToAppOrganization(*org)})
+}
+
+// RemoveMember removes a user from the organization.
+func (c *OrganizationsController) RemoveMember(ctx *app.RemoveMemberOrganizationsContext) error {
+	currentUser, err := login.ContextIdentity(ctx)
+	if err != nil {
+		return jsonapi.JSONErrorResponse(ctx, goa.ErrUnauthorized(err.Error()))
+	}
+	org, err := c.organizations.RemoveMember(ctx, organization.ResourceTypeOrganization, *currentUser, ctx.OrganizationID, ctx.IdentityID)
+	if err != nil {
+		return jsonapi.JSONErrorResponse(ctx, err)
+	}
+	return ctx.OK(&app.OrganizationSingle{Data: ConvertToAppOrganization(*org)})
+}
+
+// ExtendMember extends the time-bound roles of a user in the organization.
+func (c *OrganizationsController) ExtendMember(ctx *app.ExtendMemberOrganizationsContext) error {
+	currentUser, err := login.ContextIdentity(ctx)
+	if err != nil {
+		return jsonapi.JSONErrorResponse(ctx, goa.ErrUnauthorized(err.Error()))
+	}
+	org, err := c.organizations.ExtendMember(ctx, organization.ResourceTypeOrganization, *currentUser, ctx.OrganizationID, ctx.IdentityID, ctx.ExpiresAt)
+	if err != nil {
+		return jsonapi.JSONErrorResponse(ctx, err)
+	}
+	return ctx.OK(&app.OrganizationSingle{Data: ConvertToAppOrganization(*org)})
+}
+
+// ListTeams lists the teams of the organization.
+func (c *OrganizationsController) ListTeams(ctx *app.ListTeamsOrganizationsContext) error {
+	teams, err := c.organizations.ListTeams(ctx, ctx.OrganizationID)
+	if err != nil {
+		return jsonapi.JSONErrorResponse(ctx, err)
+	}
+	return ctx.OK(&app.TeamList{Data: convertToAppTeams(teams)})
+}
+
+// ConvertToAppOrganization converts an organization to its REST representation
+func ConvertToAppOrganization(org organization.Group) *app.Organization {
+	return &app.Organization{
+		Type: "organizations",
+		ID:   org.ResourceID,
+		Attributes: &app.OrganizationAttributes{
+			Name:        &org.Name,
+			Description: &org.Description,
+			Admins:      identityIDs(org.Admins),
+			Members:     identityIDs(org.Members),
+			CreatedAt:   &org.CreatedAt,
+			UpdatedAt:   &org.UpdatedAt,
+		},
+	}
+}
+
+// ConvertToAppTeam converts a team to its REST representation
+func ConvertToAppTeam(team organization.Group) *app.Team {
+	return &app.Team{
+		Type: "teams",
+		ID:   team.ResourceID,
+		Attributes: &app.TeamAttributes{
+			Name:           &team.Name,
+			Description:    &team.Description,
+			OrganizationID: team.ParentResourceID,
+			Admins:         identityIDs(team.Admins),
+			Members:        identityIDs(team.Members),
+			CreatedAt:      &team.CreatedAt,
+			UpdatedAt:      &team.UpdatedAt,
+		},
+	}
+}
+
+func convertToAppTeams(teams []organization.Group) []*app.Team {
+	data := make([]*app.Team, len(teams))
+	for i := range teams {
+		data[i] = ConvertToAppTeam(teams[i])
+	}
+	return data
+}
+
+func identityIDs(ids []uuid.UUID) []string {
+	result := make([]string, len(ids))
+	for i, id := range ids {
+		result[i] = id.String()
+	}
+	return result
+}
+
+func stringValue(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
@@ -0,0 +1,193 @@
+package controller_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/fabric8-services/fabric8-auth/account"
+	"github.com/fabric8-services/fabric8-auth/app"
+	"github.com/fabric8-services/fabric8-auth/app/test"
+	. "github.com/fabric8-services/fabric8-auth/controller"
+	"github.com/fabric8-services/fabric8-auth/gormtestsupport"
+	"github.com/fabric8-services/fabric8-auth/resource"
+	testsupport "github.com/fabric8-services/fabric8-auth/test"
+
+	"github.com/goadesign/goa"
+	"github.com/satori/go.uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+)
+
+type TestOrganizationsREST struct {
+	gormtestsupport.DBTestSuite
+	admin  account.Identity
+	member account.Identity
+}
+
+func TestRunOrganizationsREST(t *testing.T) {
+	resource.Require(t, resource.Database)
+	suite.Run(t, &TestOrganizationsREST{DBTestSuite: gormtestsupport.NewDBTestSuite()})
+}
+
+func (rest *TestOrganizationsREST) SetupTest() {
+	rest.DBTestSuite.SetupTest()
+	var err error
+	rest.admin, err = testsupport.CreateTestIdentity(rest.DB, "organizations-admin-"+uuid.NewV4().String(), account.KeycloakIDP)
+	require.Nil(rest.T(), err)
+	rest.member, err = testsupport.CreateTestIdentity(rest.DB, "organizations-member-"+uuid.NewV4().String(), account.KeycloakIDP)
+	require.Nil(rest.T(), err)
+}
+
+func (rest *TestOrganizationsREST) SecuredController(identity account.Identity) (*goa.Service, *OrganizationsController) {
+	svc := testsupport.ServiceAsUser("Organizations-Service", identity)
+	return svc, NewOrganizationsController(svc, rest.Application)
+}
+
+func (rest *TestOrganizationsREST) UnsecuredController() (*goa.Service, *OrganizationsController) {
+	svc := goa.New("Organizations-Service")
+	return svc, NewOrganizationsController(svc, rest.Application)
+}
+
+func organizationPayload(name string, description string) *app.CreateOrganizationsPayload {
+	return &app.CreateOrganizationsPayload{
+		Data: &app.OrganizationPayloadData{
+			Type: "organizations",
+			Attributes: &app.OrganizationPayloadAttributes{
+				Name:        &name,
+				Description: &description,
+			},
+		},
+	}
+}
+
+func (rest *TestOrganizationsREST) createOrganization() *app.Organization {
+	svc, ctrl := rest.SecuredController(rest.admin)
+	_, created := test.CreateOrganizationsCreated(rest.T(), svc.Context, svc, ctrl, organizationPayload("Acme "+uuid.NewV4().String(), "Acme Corp."))
+	require.NotNil(rest.T(), created.Data)
+	return created.Data
+}
+
+func (rest *TestOrganizationsREST) TestCreateAndShowOrganizationOK() {
+	// when
+	org := rest.createOrganization()
+
+	// then the creator is the admin
+	assert.Equal(rest.T(), []string{rest.admin.ID.String()}, org.Attributes.Admins)
+	assert.Equal(rest.T(), []string{rest.admin.ID.String()}, org.Attributes.Members)
+	svc, ctrl := rest.SecuredController(rest.member)
+	_, shown := test.ShowOrganizationsOK(rest.T(), svc.Context, svc, ctrl, org.ID)
+	assert.Equal(rest.T(), org.Attributes.Name, shown.Data.Attributes.Name)
+	assert.Equal(rest.T(), "Acme Corp.", *shown.Data.Attributes.Description)
+}
+
+func (rest *TestOrganizationsREST) TestCreateOrganizationBadRequest() {
+	svc, ctrl := rest.SecuredController(rest.admin)
+	test.CreateOrganizationsBadRequest(rest.T(), svc.Context, svc, ctrl, organizationPayload("", ""))
+}
+
+func (rest *TestOrganizationsREST) TestCreateOrganizationUnauthorized() {
+	svc, ctrl := rest.UnsecuredController()
+	test.CreateOrganizationsUnauthorized(rest.T(), svc.Context, svc, ctrl, organizationPayload("Acme", ""))
+}
+
+func (rest *TestOrganizationsREST) TestShowOrganizationNotFound() {
+	svc, ctrl := rest.SecuredController(rest.admin)
+	test.ShowOrganizationsNotFound(rest.T(), svc.Context, svc, ctrl, uuid.NewV4().String())
+	test.ShowOrganizationsNotFound(rest.T(), svc.Context, svc, ctrl, "not-a-uuid")
+}
+
+func (rest *TestOrganizationsREST) TestUpdateOrganizationOK() {
+	// given
+	org := rest.createOrganization()
+	svc, ctrl := rest.SecuredController(rest.admin)
+	description := "Acme Corporation"
+	payload := &app.UpdateOrganizationsPayload{
+		Data: &app.OrganizationPayloadData{
+			Type:       "organizations",
+			Attributes: &app.OrganizationPayloadAttributes{Description: &description},
+		},
+	}
+
+	// when
+	_, updated := test.UpdateOrganizationsOK(rest.T(), svc.Context, svc, ctrl, org.ID, payload)
+
+	// then
+	assert.Equal(rest.T(), description, *updated.Data.Attributes.Description)
+	assert.Equal(rest.T(), *org.Attributes.Name, *updated.Data.Attributes.Name)
+	// and the members can't update it
+	memberSvc, memberCtrl := rest.SecuredController(rest.member)
+	test.UpdateOrganizationsForbidden(rest.T(), memberSvc.Context, memberSvc, memberCtrl, org.ID, payload)
+}
+
+func (rest *TestOrganizationsREST) TestAddAndRemoveMemberOK() {
+	// given
+	org := rest.createOrganization()
+	svc, ctrl := rest.SecuredController(rest.admin)
+
+	// when
+	_, updated := test.AddMemberOrganizationsOK(rest.T(), svc.Context, svc, ctrl, org.ID, rest.member.ID, nil, "member")
+
+	// then the organization is listed for the new member
+	assert.Len(rest.T(), updated.Data.Attributes.Members, 2)
+	assert.Equal(rest.T(), []string{rest.admin.ID.String()}, updated.Data.Attributes.Admins)
+	memberSvc, memberCtrl := rest.SecuredController(rest.member)
+	_, orgs := test.ListOrganizationsOK(rest.T(), memberSvc.Context, memberSvc, memberCtrl)
+	require.Len(rest.T(), orgs.Data, 1)
+	assert.Equal(rest.T(), org.ID, orgs.Data[0].ID)
+	// and the member can't add members
+	test.AddMemberOrganizationsForbidden(rest.T(), memberSvc.Context, memberSvc, memberCtrl, org.ID, rest.admin.ID, nil, "admin")
+
+	// when the member leaves
+	_, updated = test.RemoveMemberOrganizationsOK(rest.T(), memberSvc.Context, memberSvc, memberCtrl, org.ID, rest.member.ID)
+
+	// then
+	assert.Equal(rest.T(), []string{rest.admin.ID.String()}, updated.Data.Attributes.Members)
+	_, orgs = test.ListOrganizationsOK(rest.T(), memberSvc.Context, memberSvc, memberCtrl)
+	assert.Empty(rest.T(), orgs.Data)
+}
+
+func (rest *TestOrganizationsREST) TestDemoteAdminOK() {
+	// given two admins
+	org := rest.createOrganization()
+	svc, ctrl := rest.SecuredController(rest.admin)
+	test.AddMemberOrganizationsOK(rest.T(), svc.Context, svc, ctrl, org.ID, rest.member.ID, nil, "admin")
+
+	// when
+	_, updated := test.AddMemberOrganizationsOK(rest.T(), svc.Context, svc, ctrl, org.ID, rest.member.ID, nil, "member")
+
+	// then
+	assert.Equal(rest.T(), []string{rest.admin.ID.String()}, updated.Data.Attributes.Admins)
+	assert.Len(rest.T(), updated.Data.Attributes.Members, 2)
+	// and the last admin can't be demoted nor removed
+	test.AddMemberOrganizationsBadRequest(rest.T(), svc.Context, svc, ctrl, org.ID, rest.admin.ID, nil, "member")
+	test.RemoveMemberOrganizationsBadRequest(rest.T(), svc.Context, svc, ctrl, org.ID, rest.admin.ID)
+}
+
+func (rest *TestOrganizationsREST) TestExtendMemberOK() {
+	// given a time-bound member
+	org := rest.createOrganization()
+	svc, ctrl := rest.SecuredController(rest.admin)
+	expiresAt := time.Now().Add(time.Hour)
+	test.AddMemberOrganizationsOK(rest.T(), svc.Context, svc, ctrl, org.ID, rest.member.ID, &expiresAt, "member")
+
+	// when
+	test.ExtendMemberOrganizationsOK(rest.T(), svc.Context, svc, ctrl, org.ID, rest.member.ID, expiresAt.Add(24*time.Hour))
+
+	// then the members without time limit can't be extended
+	test.ExtendMemberOrganizationsNotFound(rest.T(), svc.Context, svc, ctrl, org.ID, rest.admin.ID, expiresAt.Add(24*time.Hour))
+}
+
+func (rest *TestOrganizationsREST) TestDeleteOrganizationOK() {
+	// given
+	org := rest.createOrganization()
+	memberSvc, memberCtrl := rest.SecuredController(rest.member)
+	test.DeleteOrganizationsForbidden(rest.T(), memberSvc.Context, memberSvc, memberCtrl, org.ID)
+	svc, ctrl := rest.SecuredController(rest.admin)
+
+	// when
+	test.DeleteOrganizationsOK(rest.T(), svc.Context, svc, ctrl, org.ID)
+
+	// then
+	test.ShowOrganizationsNotFound(rest.T(), svc.Context, svc, ctrl, org.ID)
+}
@@ -133,7 +133,74 @@ func (c *ResourceController) Register(ctx *app.RegisterResourceContext) error {
 	return ctx.Created(&app.RegisterResource{ID: &res.ResourceID})
 }
 
-// Update runs the update action.
+// Update updates the name and description of a resource, unless it was modified since the client's last call.
 func (c *ResourceController) Update(ctx *app.UpdateResourceContext) error {
-	return ctx.MethodNotAllowed()
+
+	if !token.IsServiceAccount(ctx) {
+		log.Error(ctx, map[string]interface{}{}, "Unable to update resource. Not a service account")
+		return jsonapi.JSONErrorResponse(ctx, errors.NewUnauthorizedError("not a service account"))
+	}
+
+	var res *resource.Resource
+	var resourceType *resource.ResourceType
+	var scopes []resource.ResourceTypeScope
+
+	err := application.Transactional(c.db, func(appl application.Application) error {
+		var err error
+		// the resource is locked so it can't be modified between the check of the preconditions and the update
+		res, err = appl.ResourceRepository().Lock(ctx, ctx.ResourceID)
+		if err != nil {
+			return err
+		}
+		// reject the update if the resource was modified since the client's last call
+		if err := ctx.ConditionalWrite(*res); err != nil {
+			return err
+		}
+		if ctx.Payload.Name != nil {
+			res.Name = *ctx.Payload.Name
+		}
+		if ctx.Payload.Description != nil {
+			res.Description = *ctx.Payload.Description
+		}
+		if err := appl.ResourceRepository().Save(ctx, res); err != nil {
+			return err
+		}
+		// reload the resource to get the modification date as stored in the database, which the ETag is generated from
+		res, err = appl.ResourceRepository().Load(ctx, res.ResourceID)
+		if err != nil {
+			return err
+		}
+		resourceType, err = appl.ResourceTypeRepository().Load(ctx, res.ResourceTypeID)
+		if err != nil {
+			return err
+		}
+		scopes, err = appl.ResourceTypeScopeRepository().List(ctx, resourceType)
+		return err
+	})
+
+	if err != nil {
+		return jsonapi.JSONErrorResponse(ctx, err)
+	}
+
+	log.Debug(ctx, map[string]interface{}{
+		"resource_id": res.ResourceID,
+		"name":        res.Name,
+		"description": res.Description,
+	}, "resource updated")
+
+	resourceScopes := make([]string, len(scopes))
+	for i, scope := range scopes {
+		resourceScopes[i] = scope.Name
+	}
+	// return the tags of the updated resource for the next conditional updates
+	ctx.ResponseData.Header().Set(app.ETag, app.GenerateEntityTag(*res))
+	ctx.ResponseData.Header().Set(app.LastModified, app.ToHTTPTime(res.GetLastModified()))
+	return ctx.OK(&app.Resource{
+		ResourceID:       &res.ResourceID,
+		ParentResourceID: res.ParentResourceID,
+		Name:             res.Name,
+		Description:      &res.Description,
+		Type:             resourceType.Name,
+		ResourceScopes:   resourceScopes,
+	})
 }
@@ -2,6 +2,7 @@ package controller_test
 
 import (
 	"testing"
+	"time"
 
 	"github.com/fabric8-services/fabric8-auth/account"
 	"github.com/fabric8-services/fabric8-auth/app"
@@ -14,6 +15,7 @@ import (
 	"github.com/goadesign/goa"
 
 	"github.com/satori/go.uuid"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"github.com/stretchr/testify/suite"
 )
@@ -207,3 +209,83 @@ func (rest *TestResourceREST) TestFailRegisterResourceUnknownOwner() {
 
 	test.RegisterResourceNotFound(rest.T(), rest.service.Context, rest.service, rest.securedController, payload)
 }
+
+func (rest *TestResourceREST) registerResource() string {
+	resourceDescription := "Resource description"
+	resourceID := uuid.NewV4().String()
+	payload := &app.RegisterResourcePayload{
+		Description:     &resourceDescription,
+		Name:            "My new resource",
+		ResourceScopes:  []string{},
+		ResourceID:      &resourceID,
+		ResourceOwnerID: rest.testIdentity.ID.String(),
+		Type:            "Area",
+	}
+	_, created := test.RegisterResourceCreated(rest.T(), rest.service.Context, rest.service, rest.securedController, payload)
+	require.NotNil(rest.T(), created.ID)
+	return *created.ID
+}
+
+func (rest *TestResourceREST) TestUpdateResourceOK() {
+	resourceID := rest.registerResource()
+	name := "My updated resource"
+	description := "Updated description"
+
+	res, updated := test.UpdateResourceOK(rest.T(), rest.service.Context, rest.service, rest.securedController, resourceID, nil, nil, &app.UpdateResourcePayload{Name: &name, Description: &description})
+
+	require.NotNil(rest.T(), updated)
+	assert.Equal(rest.T(), resourceID, *updated.ResourceID)
+	assert.Equal(rest.T(), name, updated.Name)
+	assert.Equal(rest.T(), description, *updated.Description)
+	assert.Equal(rest.T(), "Area", updated.Type)
+	assert.NotEmpty(rest.T(), res.Header().Get(app.ETag))
+	assert.NotEmpty(rest.T(), res.Header().Get(app.LastModified))
+}
+
+func (rest *TestResourceREST) TestUpdateResourceWithIfMatch() {
+	resourceID := rest.registerResource()
+	r, err := rest.Application.ResourceRepository().Load(rest.Ctx, resourceID)
+	require.Nil(rest.T(), err)
+	etag := app.GenerateEntityTag(*r)
+	description := "Updated description"
+	payload := &app.UpdateResourcePayload{Description: &description}
+
+	// a stale ETag fails the update
+	staleETag := "stale"
+	test.UpdateResourcePreconditionFailed(rest.T(), rest.service.Context, rest.service, rest.securedController, resourceID, &staleETag, nil, payload)
+	r, err = rest.Application.ResourceRepository().Load(rest.Ctx, resourceID)
+	require.Nil(rest.T(), err)
+	assert.Equal(rest.T(), "Resource description", r.Description)
+
+	// the current ETag allows the update
+	_, updated := test.UpdateResourceOK(rest.T(), rest.service.Context, rest.service, rest.securedController, resourceID, &etag, nil, payload)
+	assert.Equal(rest.T(), description, *updated.Description)
+}
+
+func (rest *TestResourceREST) TestUpdateResourceWithIfUnmodifiedSince() {
+	resourceID := rest.registerResource()
+	r, err := rest.Application.ResourceRepository().Load(rest.Ctx, resourceID)
+	require.Nil(rest.T(), err)
+	description := "Updated description"
+	payload := &app.UpdateResourcePayload{Description: &description}
+
+	// a date before the last modification fails the update
+	ifUnmodifiedSince := app.ToHTTPTime(r.UpdatedAt.Add(-1 * time.Hour))
+	test.UpdateResourcePreconditionFailed(rest.T(), rest.service.Context, rest.service, rest.securedController, resourceID, nil, &ifUnmodifiedSince, payload)
+
+	// the date of the last modification allows the update
+	ifUnmodifiedSince = app.ToHTTPTime(r.UpdatedAt)
+	test.UpdateResourceOK(rest.T(), rest.service.Context, rest.service, rest.securedController, resourceID, nil, &ifUnmodifiedSince, payload)
+}
+
+func (rest *TestResourceREST) TestUpdateResourceNotFound() {
+	description := "Updated description"
+	test.UpdateResourceNotFound(rest.T(), rest.service.Context, rest.service, rest.securedController, uuid.NewV4().String(), nil, nil, &app.UpdateResourcePayload{Description: &description})
+}
+
+func (rest *TestResourceREST) TestFailUpdateResourceNonServiceAccount() {
+	resourceID := rest.registerResource()
+	service, controller := rest.SecuredController(account.Identity{Username: "unknown-account"})
+	description := "Updated description"
+	test.UpdateResourceUnauthorized(rest.T(), service.Context, service, controller, resourceID, nil, nil, &app.UpdateResourcePayload{Description: &description})
+}
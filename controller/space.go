@@ -9,6 +9,7 @@ import (
 	"github.com/fabric8-services/fabric8-auth/log"
 	"github.com/fabric8-services/fabric8-auth/login"
 	"github.com/fabric8-services/fabric8-auth/space"
+	"github.com/fabric8-services/fabric8-auth/webhook"
 	"github.com/goadesign/goa"
 	uuid "github.com/satori/go.uuid"
 )
@@ -112,7 +113,10 @@ func (c *SpaceController) Delete(ctx *app.DeleteSpaceContext) error {
 		permissionID = resource.PermissionID
 		policyID = resource.PolicyID
 
-		return appl.SpaceResources().Delete(ctx, resource.ID)
+		if err := appl.SpaceResources().Delete(ctx, resource.ID); err != nil {
+			return err
+		}
+		return webhook.Emit(ctx, appl, webhook.EventTypeSpaceDeleted, webhook.Data{IdentityID: *currentUser, SpaceID: &ctx.SpaceID})
 	})
 
 	if err != nil {
@@ -1,9 +1,11 @@
 package controller
 
 import (
+	"context"
 	"time"
 
 	"github.com/fabric8-services/fabric8-auth/app"
+	"github.com/fabric8-services/fabric8-auth/health"
 
 	"fmt"
 	"github.com/goadesign/goa"
@@ -32,21 +34,54 @@ type DBChecker interface {
 // StatusController implements the status resource.
 type StatusController struct {
 	*goa.Controller
-	dbChecker DBChecker
-	config    statusConfiguration
+	dbChecker    DBChecker
+	dependencies *health.Registry
+	config       statusConfiguration
 }
 
 // NewStatusController creates a status controller.
-func NewStatusController(service *goa.Service, dbChecker DBChecker, config statusConfiguration) *StatusController {
+func NewStatusController(service *goa.Service, dbChecker DBChecker, dependencies *health.Registry, config statusConfiguration) *StatusController {
 	return &StatusController{
-		Controller: service.NewController("StatusController"),
-		dbChecker:  dbChecker,
-		config:     config,
+		Controller:   service.NewController("StatusController"),
+		dbChecker:    dbChecker,
+		dependencies: dependencies,
+		config:       config,
 	}
 }
 
 // Show runs the show action.
+// Responds with 503 if the database is not available or if the default configuration is used in production mode.
+// The dependencies are only reported, so the status doesn't depend on the availability of the other services.
 func (c *StatusController) Show(ctx *app.ShowStatusContext) error {
+	res, ok, _ := c.status(ctx)
+	if !ok {
+		return ctx.ServiceUnavailable(res)
+	}
+	return ctx.OK(res)
+}
+
+// Liveness runs the liveness action.
+func (c *StatusController) Liveness(ctx *app.LivenessStatusContext) error {
+	return ctx.OK(&app.Liveness{
+		Commit:    Commit,
+		BuildTime: BuildTime,
+		StartTime: StartTime,
+	})
+}
+
+// Readiness runs the readiness action.
+// Responds with 503 like the show action, and also if any of the critical dependencies is not available.
+func (c *StatusController) Readiness(ctx *app.ReadinessStatusContext) error {
+	res, ok, ready := c.status(ctx)
+	if !ok || !ready {
+		return ctx.ServiceUnavailable(res)
+	}
+	return ctx.OK(res)
+}
+
+// status returns the status of the database, the configuration and the dependencies, if both the database
+// and the configuration are fine and if all the critical dependencies are available
+func (c *StatusController) status(ctx context.Context) (*app.Status, bool, bool) {
 	res := &app.Status{
 		Commit:    Commit,
 		BuildTime: BuildTime,
@@ -72,10 +107,33 @@ func (c *StatusController) Show(ctx *app.ShowStatusContext) error {
 		res.ConfigurationStatus = "OK"
 	}
 
-	if dbErr != nil || (configErr != nil && !devMode) {
-		return ctx.ServiceUnavailable(res)
+	ready, dependencies := c.dependencies.IsReady(ctx)
+	res.Dependencies = convertDependencyStatuses(dependencies)
+
+	return res, dbErr == nil && (configErr == nil || devMode), ready
+}
+
+func convertDependencyStatuses(statuses []health.DependencyStatus) []*app.DependencyStatus {
+	result := make([]*app.DependencyStatus, len(statuses))
+	for i, status := range statuses {
+		converted := &app.DependencyStatus{
+			Name:          status.Name,
+			Status:        "OK",
+			Critical:      status.Critical,
+			LatencyMillis: int(status.Latency / time.Millisecond),
+			CheckedAt:     status.CheckedAt,
+		}
+		if status.Err != nil {
+			converted.Status = fmt.Sprintf("Error: %s", status.Err.Error())
+		}
+		if status.LastError != nil {
+			lastError := status.LastError.Error()
+			converted.LastError = &lastError
+			converted.LastErrorAt = status.LastErrorAt
+		}
+		result[i] = converted
 	}
-	return ctx.OK(res)
+	return result
 }
 
 // GormDBChecker implements DB checker
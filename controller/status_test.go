@@ -1,6 +1,7 @@
 package controller_test
 
 import (
+	"context"
 	"os"
 	"testing"
 	"time"
@@ -9,6 +10,7 @@ import (
 	"github.com/fabric8-services/fabric8-auth/configuration"
 	. "github.com/fabric8-services/fabric8-auth/controller"
 	"github.com/fabric8-services/fabric8-auth/gormtestsupport"
+	"github.com/fabric8-services/fabric8-auth/health"
 	"github.com/fabric8-services/fabric8-auth/resource"
 
 	"github.com/goadesign/goa"
@@ -34,12 +36,22 @@ func TestRunStatusREST(t *testing.T) {
 
 func (rest *TestStatusREST) UnSecuredController() (*goa.Service, *StatusController) {
 	svc := goa.New("Status-Service")
-	return svc, NewStatusController(svc, NewGormDBChecker(rest.DB), rest.Configuration)
+	return svc, NewStatusController(svc, NewGormDBChecker(rest.DB), health.NewRegistry(time.Second, 0), rest.Configuration)
+}
+
+func (rest *TestStatusREST) UnSecuredControllerWithDependencies(dependencies ...health.DependencyChecker) (*goa.Service, *StatusController) {
+	svc := goa.New("Status-Service")
+	registry := health.NewRegistry(time.Second, 0)
+	for i, dependency := range dependencies {
+		// only the first dependency is critical
+		registry.Register(dependency, i == 0)
+	}
+	return svc, NewStatusController(svc, NewGormDBChecker(rest.DB), registry, rest.Configuration)
 }
 
 func (rest *TestStatusREST) UnSecuredControllerWithUnreachableDB() (*goa.Service, *StatusController) {
 	svc := goa.New("Status-Service")
-	return svc, NewStatusController(svc, &dummyDBChecker{}, rest.Configuration)
+	return svc, NewStatusController(svc, &dummyDBChecker{}, health.NewRegistry(time.Second, 0), rest.Configuration)
 }
 
 func (rest *TestStatusREST) TestShowStatusInDevModeOK() {
@@ -88,6 +100,60 @@ func (rest *TestStatusREST) TestShowStatusWithDefaultConfigInProdModeFails() {
 	assert.Equal(rest.T(), "Error: DB is unreachable", res.DatabaseStatus)
 }
 
+func (rest *TestStatusREST) TestShowStatusWithDependencies() {
+	t := rest.T()
+	available := health.NewFuncChecker("keycloak", func(ctx context.Context) error {
+		return nil
+	})
+	unavailable := health.NewFuncChecker("wit", func(ctx context.Context) error {
+		return errors.New("WIT is unreachable")
+	})
+
+	t.Run("all available", func(t *testing.T) {
+		svc, ctrl := rest.UnSecuredControllerWithDependencies(available)
+		_, res := test.ShowStatusOK(t, svc.Context, svc, ctrl)
+		require.Len(t, res.Dependencies, 1)
+		assert.Equal(t, "keycloak", res.Dependencies[0].Name)
+		assert.Equal(t, "OK", res.Dependencies[0].Status)
+		assert.True(t, res.Dependencies[0].Critical)
+		assert.Nil(t, res.Dependencies[0].LastError)
+	})
+
+	t.Run("non critical dependency unavailable", func(t *testing.T) {
+		svc, ctrl := rest.UnSecuredControllerWithDependencies(available, unavailable)
+		// the unavailable dependency is only reported
+		_, res := test.ShowStatusOK(t, svc.Context, svc, ctrl)
+		require.Len(t, res.Dependencies, 2)
+		assert.Equal(t, "Error: WIT is unreachable", res.Dependencies[1].Status)
+		require.NotNil(t, res.Dependencies[1].LastError)
+		assert.Equal(t, "WIT is unreachable", *res.Dependencies[1].LastError)
+		// the instance is still ready
+		test.ReadinessStatusOK(t, svc.Context, svc, ctrl)
+	})
+
+	t.Run("critical dependency unavailable", func(t *testing.T) {
+		svc, ctrl := rest.UnSecuredControllerWithDependencies(unavailable, available)
+		// the status only reports the dependencies
+		_, res := test.ShowStatusOK(t, svc.Context, svc, ctrl)
+		require.Len(t, res.Dependencies, 2)
+		assert.Equal(t, "Error: WIT is unreachable", res.Dependencies[0].Status)
+		// but the instance is not ready
+		_, res = test.ReadinessStatusServiceUnavailable(t, svc.Context, svc, ctrl)
+		require.Len(t, res.Dependencies, 2)
+		// but the instance is alive
+		test.LivenessStatusOK(t, svc.Context, svc, ctrl)
+	})
+}
+
+func (rest *TestStatusREST) TestReadinessWithoutDBFails() {
+	svc, ctrl := rest.UnSecuredControllerWithUnreachableDB()
+	_, res := test.ReadinessStatusServiceUnavailable(rest.T(), svc.Context, svc, ctrl)
+	assert.Equal(rest.T(), "Error: DB is unreachable", res.DatabaseStatus)
+
+	_, liveness := test.LivenessStatusOK(rest.T(), svc.Context, svc, ctrl)
+	assert.Equal(rest.T(), StartTime, liveness.StartTime)
+}
+
 func (rest *TestStatusREST) resetConfiguration() {
 	config, err := configuration.GetConfigurationData()
 	require.Nil(rest.T(), err)
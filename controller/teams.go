@@ -0,0 +1,111 @@
+package controller
+
+import (
+	"github.com/fabric8-services/fabric8-auth/app"
+	"github.com/fabric8-services/fabric8-auth/application"
+	"github.com/fabric8-services/fabric8-auth/authorization/organization"
+	"github.com/fabric8-services/fabric8-auth/jsonapi"
+	"github.com/fabric8-services/fabric8-auth/login"
+
+	"github.com/goadesign/goa"
+)
+
+// TeamsController implements the teams resource.
+type TeamsController struct {
+	*goa.Controller
+	organizations *organization.Service
+}
+
+// NewTeamsController creates a teams controller.
+func NewTeamsController(service *goa.Service, db application.DB) *TeamsController {
+	return &TeamsController{Controller: service.NewController("TeamsController"), organizations: organization.NewService(db)}
+}
+
+// Create creates a team in an organization the current user is an admin of.
+func (c *TeamsController) Create(ctx *app.CreateTeamsContext) error {
+	currentUser, err := login.ContextIdentity(ctx)
+	if err != nil {
+		return jsonapi.JSONErrorResponse(ctx, goa.ErrUnauthorized(err.Error()))
+	}
+	attributes := ctx.Payload.Data.Attributes
+	team, err := c.organizations.Create(ctx, organization.ResourceTypeTeam, *currentUser, stringValue(attributes.Name), stringValue(attributes.Description), attributes.OrganizationID)
+	if err != nil {
+		return jsonapi.JSONErrorResponse(ctx, err)
+	}
+	return ctx.Created(&app.TeamSingle{Data: ConvertToAppTeam(*team)})
+}
+
+// Show returns the team with the given ID.
+func (c *TeamsController) Show(ctx *app.ShowTeamsContext) error {
+	team, err := c.organizations.Load(ctx, organization.ResourceTypeTeam, ctx.TeamID)
+	if err != nil {
+		return jsonapi.JSONErrorResponse(ctx, err)
+	}
+	return ctx.OK(&app.TeamSingle{Data: ConvertToAppTeam(*team)})
+}
+
+// Update updates the name and description of the team with the given ID.
+func (c *TeamsController) Update(ctx *app.UpdateTeamsContext) error {
+	currentUser, err := login.ContextIdentity(ctx)
+	if err != nil {
+		return jsonapi.JSONErrorResponse(ctx, goa.ErrUnauthorized(err.Error()))
+	}
+	attributes := ctx.Payload.Data.Attributes
+	team, err := c.organizations.Update(ctx, organization.ResourceTypeTeam, *currentUser, ctx.TeamID, attributes.Name, attributes.Description)
+	if err != nil {
+		return jsonapi.JSONErrorResponse(ctx, err)
+	}
+	return ctx.OK(&app.TeamSingle{Data: ConvertToAppTeam(*team)})
+}
+
+// Delete deletes the team with the given ID.
+func (c *TeamsController) Delete(ctx *app.DeleteTeamsContext) error {
+	currentUser, err := login.ContextIdentity(ctx)
+	if err != nil {
+		return jsonapi.JSONErrorResponse(ctx, goa.ErrUnauthorized(err.Error()))
+	}
+	err = c.organizations.Delete(ctx, organization.ResourceTypeTeam, *currentUser, ctx.TeamID)
+	if err != nil {
+		return jsonapi.JSONErrorResponse(ctx, err)
+	}
+	return ctx.OK([]byte{})
+}
+
+// AddMember adds a user to the members or admins of the team.
+func (c *TeamsController) AddMember(ctx *app.AddMemberTeamsContext) error {
+	currentUser, err := login.ContextIdentity(ctx)
+	if err != nil {
+		return jsonapi.JSONErrorResponse(ctx, goa.ErrUnauthorized(err.Error()))
+	}
+	team, err := c.organizations.AddMember(ctx, organization.ResourceTypeTeam, *currentUser, ctx.TeamID, ctx.IdentityID, ctx.Role, ctx.ExpiresAt)
+	if err != nil {
+		return jsonapi.JSONErrorResponse(ctx, err)
+	}
+	return ctx.OK(&app.TeamSingle{Data: ConvertToAppTeam(*team)})
+}
+
+// RemoveMember removes a user from the team.
+func (c *TeamsController) RemoveMember(ctx *app.RemoveMemberTeamsContext) error {
+	currentUser, err := login.ContextIdentity(ctx)
+	if err != nil {
+		return jsonapi.JSONErrorResponse(ctx, goa.ErrUnauthorized(err.Error()))
+	}
+	team, err := c.organizations.RemoveMember(ctx, organization.ResourceTypeTeam, *currentUser, ctx.TeamID, ctx.IdentityID)
+	if err != nil {
+		return jsonapi.JSONErrorResponse(ctx, err)
+	}
+	return ctx.OK(&app.TeamSingle{Data: ConvertToAppTeam(*team)})
+}
+
+// ExtendMember extends the time-bound roles of a user in the team.
+func (c *TeamsController) ExtendMember(ctx *app.ExtendMemberTeamsContext) error {
+	currentUser, err := login.ContextIdentity(ctx)
+	if err != nil {
+		return jsonapi.JSONErrorResponse(ctx, goa.ErrUnauthorized(err.Error()))
+	}
+	team, err := c.organizations.ExtendMember(ctx, organization.ResourceTypeTeam, *currentUser, ctx.TeamID, ctx.IdentityID, ctx.ExpiresAt)
+	if err != nil {
+		return jsonapi.JSONErrorResponse(ctx, err)
+	}
+	return ctx.OK(&app.TeamSingle{Data: ConvertToAppTeam(*team)})
+}
@@ -0,0 +1,132 @@
+package controller_test
+
+import (
+	"testing"
+
+	"github.com/fabric8-services/fabric8-auth/account"
+	"github.com/fabric8-services/fabric8-auth/app"
+	"github.com/fabric8-services/fabric8-auth/app/test"
+	. "github.com/fabric8-services/fabric8-auth/controller"
+	"github.com/fabric8-services/fabric8-auth/gormtestsupport"
+	"github.com/fabric8-services/fabric8-auth/resource"
+	testsupport "github.com/fabric8-services/fabric8-auth/test"
+
+	"github.com/goadesign/goa"
+	"github.com/satori/go.uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+)
+
+type TestTeamsREST struct {
+	gormtestsupport.DBTestSuite
+	admin          account.Identity
+	member         account.Identity
+	organizationID string
+}
+
+func TestRunTeamsREST(t *testing.T) {
+	resource.Require(t, resource.Database)
+	suite.Run(t, &TestTeamsREST{DBTestSuite: gormtestsupport.NewDBTestSuite()})
+}
+
+func (rest *TestTeamsREST) SetupTest() {
+	rest.DBTestSuite.SetupTest()
+	var err error
+	rest.admin, err = testsupport.CreateTestIdentity(rest.DB, "teams-admin-"+uuid.NewV4().String(), account.KeycloakIDP)
+	require.Nil(rest.T(), err)
+	rest.member, err = testsupport.CreateTestIdentity(rest.DB, "teams-member-"+uuid.NewV4().String(), account.KeycloakIDP)
+	require.Nil(rest.T(), err)
+	svc := testsupport.ServiceAsUser("Organizations-Service", rest.admin)
+	_, org := test.CreateOrganizationsCreated(rest.T(), svc.Context, svc, NewOrganizationsController(svc, rest.Application), organizationPayload("Acme "+uuid.NewV4().String(), ""))
+	rest.organizationID = org.Data.ID
+}
+
+func (rest *TestTeamsREST) SecuredController(identity account.Identity) (*goa.Service, *TeamsController) {
+	svc := testsupport.ServiceAsUser("Teams-Service", identity)
+	return svc, NewTeamsController(svc, rest.Application)
+}
+
+func (rest *TestTeamsREST) teamPayload(name string) *app.CreateTeamsPayload {
+	return &app.CreateTeamsPayload{
+		Data: &app.OrganizationPayloadData{
+			Type: "teams",
+			Attributes: &app.OrganizationPayloadAttributes{
+				Name:           &name,
+				OrganizationID: &rest.organizationID,
+			},
+		},
+	}
+}
+
+func (rest *TestTeamsREST) TestCreateTeamOK() {
+	// given
+	svc, ctrl := rest.SecuredController(rest.admin)
+
+	// when
+	_, created := test.CreateTeamsCreated(rest.T(), svc.Context, svc, ctrl, rest.teamPayload("Developers"))
+
+	// then the team is listed in its organization
+	require.NotNil(rest.T(), created.Data)
+	assert.Equal(rest.T(), rest.organizationID, *created.Data.Attributes.OrganizationID)
+	assert.Equal(rest.T(), []string{rest.admin.ID.String()}, created.Data.Attributes.Admins)
+	orgSvc := testsupport.ServiceAsUser("Organizations-Service", rest.member)
+	_, teams := test.ListTeamsOrganizationsOK(rest.T(), orgSvc.Context, orgSvc, NewOrganizationsController(orgSvc, rest.Application), rest.organizationID)
+	require.Len(rest.T(), teams.Data, 1)
+	assert.Equal(rest.T(), created.Data.ID, teams.Data[0].ID)
+}
+
+func (rest *TestTeamsREST) TestCreateTeamForbiddenForNonAdmin() {
+	svc, ctrl := rest.SecuredController(rest.member)
+	test.CreateTeamsForbidden(rest.T(), svc.Context, svc, ctrl, rest.teamPayload("Developers"))
+}
+
+func (rest *TestTeamsREST) TestCreateTeamNotFound() {
+	svc, ctrl := rest.SecuredController(rest.admin)
+	payload := rest.teamPayload("Developers")
+	organizationID := uuid.NewV4().String()
+	payload.Data.Attributes.OrganizationID = &organizationID
+	test.CreateTeamsNotFound(rest.T(), svc.Context, svc, ctrl, payload)
+}
+
+func (rest *TestTeamsREST) TestTeamMembersOK() {
+	// given a team created by a team admin who is not an admin of the organization
+	orgSvc := testsupport.ServiceAsUser("Organizations-Service", rest.admin)
+	test.AddMemberOrganizationsOK(rest.T(), orgSvc.Context, orgSvc, NewOrganizationsController(orgSvc, rest.Application), rest.organizationID, rest.member.ID, nil, "admin")
+	memberSvc, memberCtrl := rest.SecuredController(rest.member)
+	_, team := test.CreateTeamsCreated(rest.T(), memberSvc.Context, memberSvc, memberCtrl, rest.teamPayload("Developers"))
+	svc, ctrl := rest.SecuredController(rest.admin)
+
+	// when the organization admin joins the team as a member
+	_, updated := test.AddMemberTeamsOK(rest.T(), svc.Context, svc, ctrl, team.Data.ID, rest.admin.ID, nil, "member")
+
+	// then
+	assert.Equal(rest.T(), []string{rest.member.ID.String()}, updated.Data.Attributes.Admins)
+	assert.Len(rest.T(), updated.Data.Attributes.Members, 2)
+	_, shown := test.ShowTeamsOK(rest.T(), svc.Context, svc, ctrl, team.Data.ID)
+	assert.Len(rest.T(), shown.Data.Attributes.Members, 2)
+
+	// and the last admin of the team can't be removed
+	test.RemoveMemberTeamsBadRequest(rest.T(), svc.Context, svc, ctrl, team.Data.ID, rest.member.ID)
+
+	// when the organization admin becomes a team admin and removes the former one
+	test.AddMemberTeamsOK(rest.T(), svc.Context, svc, ctrl, team.Data.ID, rest.admin.ID, nil, "admin")
+	_, updated = test.RemoveMemberTeamsOK(rest.T(), svc.Context, svc, ctrl, team.Data.ID, rest.member.ID)
+
+	// then
+	assert.Equal(rest.T(), []string{rest.admin.ID.String()}, updated.Data.Attributes.Members)
+}
+
+func (rest *TestTeamsREST) TestDeleteTeamOK() {
+	// given
+	svc, ctrl := rest.SecuredController(rest.admin)
+	_, team := test.CreateTeamsCreated(rest.T(), svc.Context, svc, ctrl, rest.teamPayload("Developers"))
+	memberSvc, memberCtrl := rest.SecuredController(rest.member)
+	test.DeleteTeamsForbidden(rest.T(), memberSvc.Context, memberSvc, memberCtrl, team.Data.ID)
+
+	// when
+	test.DeleteTeamsOK(rest.T(), svc.Context, svc, ctrl, team.Data.ID)
+
+	// then
+	test.ShowTeamsNotFound(rest.T(), svc.Context, svc, ctrl, team.Data.ID)
+}
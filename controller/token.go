@@ -14,16 +14,17 @@ import (
 	"github.com/fabric8-services/fabric8-auth/application"
 	"github.com/fabric8-services/fabric8-auth/client"
 	"github.com/fabric8-services/fabric8-auth/errors"
+	"github.com/fabric8-services/fabric8-auth/i18n"
 	"github.com/fabric8-services/fabric8-auth/jsonapi"
 	"github.com/fabric8-services/fabric8-auth/log"
 	"github.com/fabric8-services/fabric8-auth/login"
+	"github.com/fabric8-services/fabric8-auth/metric"
 	"github.com/fabric8-services/fabric8-auth/rest"
 	"github.com/fabric8-services/fabric8-auth/test"
 	"github.com/fabric8-services/fabric8-auth/token"
 	"github.com/fabric8-services/fabric8-auth/token/keycloak"
 	"github.com/fabric8-services/fabric8-auth/token/link"
 	"github.com/fabric8-services/fabric8-auth/token/provider"
-	"github.com/fabric8-services/fabric8-auth/wit"
 
 	"github.com/goadesign/goa"
 	goajwt "github.com/goadesign/goa/middleware/security/jwt"
@@ -145,7 +146,9 @@ func (c *TokenController) Generate(ctx *app.GenerateTokenContext) error {
 		return jsonapi.JSONErrorResponse(ctx, errors.NewInternalError(ctx, errs.Wrap(err, "unable to generate test token ")))
 	}
 
-	identity, _, err := c.Auth.CreateOrUpdateIdentity(ctx, *testuser.Token.AccessToken, c.Configuration)
+	// Creates the testuser user and identity if they don't yet exist.
+	// The users are created in WIT by the outbox dispatcher.
+	_, _, err = c.Auth.CreateOrUpdateIdentity(ctx, ctx.RequestData, *testuser.Token.AccessToken, c.Configuration)
 	if err != nil {
 		log.Error(ctx, map[string]interface{}{
 			"err": err,
@@ -153,24 +156,6 @@ func (c *TokenController) Generate(ctx *app.GenerateTokenContext) error {
 	}
 	tokens = append(tokens, testuser)
 
-	var remoteWITService wit.RemoteWITServiceCaller
-	witURL, err := c.Configuration.GetWITURL(ctx.RequestData)
-	if err != nil {
-		return jsonapi.JSONErrorResponse(ctx, err)
-	}
-
-	if identity != nil {
-		err = remoteWITService.CreateWITUser(ctx, ctx.RequestData, identity, witURL, identity.ID.String())
-		if err != nil {
-			log.Warn(ctx, map[string]interface{}{
-				"err":         err,
-				"identity_id": identity.ID,
-				"username":    identity.Username,
-				"wit_url":     witURL,
-			}, "unable to create user in WIT ")
-		}
-	}
-
 	testuser, err = GenerateUserToken(ctx, tokenEndpoint, c.Configuration, c.Configuration.GetKeycloakTestUser2Name(), c.Configuration.GetKeycloakTestUser2Secret())
 	if err != nil {
 		log.Error(ctx, map[string]interface{}{
@@ -180,7 +165,7 @@ func (c *TokenController) Generate(ctx *app.GenerateTokenContext) error {
 	}
 
 	// Creates the testuser2 user and identity if they don't yet exist
-	identity, _, err = c.Auth.CreateOrUpdateIdentity(ctx, *testuser.Token.AccessToken, c.Configuration)
+	_, _, err = c.Auth.CreateOrUpdateIdentity(ctx, ctx.RequestData, *testuser.Token.AccessToken, c.Configuration)
 	if err != nil {
 		log.Error(ctx, map[string]interface{}{
 			"err": err,
@@ -188,18 +173,6 @@ func (c *TokenController) Generate(ctx *app.GenerateTokenContext) error {
 	}
 	tokens = append(tokens, testuser)
 
-	if identity != nil {
-		err = remoteWITService.CreateWITUser(ctx, ctx.RequestData, identity, witURL, identity.ID.String())
-		if err != nil {
-			log.Warn(ctx, map[string]interface{}{
-				"err":         err,
-				"identity_id": identity.ID,
-				"username":    identity.Username,
-				"wit_url":     witURL,
-			}, "unable to create user in WIT ")
-		}
-	}
-
 	ctx.ResponseData.Header().Set("Cache-Control", "no-cache")
 	return ctx.OK(tokens)
 }
@@ -247,12 +220,15 @@ func (c *TokenController) Retrieve(ctx *app.RetrieveTokenContext) error {
 		return ctx.OK(&clusterToken)
 	}
 
+	providerName := providerConfig.TypeName()
 	externalToken, err := c.retrieveToken(ctx, providerConfig, *currentIdentity)
 	if err != nil {
+		metric.RecordExternalTokenRetrieval(providerName, false, err)
 		return jsonapi.JSONErrorResponse(ctx, err)
 	}
 	if externalToken != nil {
 		updatedToken, err := c.updateProfileIfEmpty(ctx, providerConfig, externalToken, ctx.ForcePull)
+		metric.RecordExternalTokenRetrieval(providerName, true, err)
 		if err != nil {
 			return jsonapi.JSONErrorResponse(ctx, err)
 		}
@@ -260,7 +236,6 @@ func (c *TokenController) Retrieve(ctx *app.RetrieveTokenContext) error {
 		return ctx.OK(&appResponse)
 	}
 
-	providerName := providerConfig.TypeName()
 	log.Info(ctx, map[string]interface{}{
 		"provider_name": providerName,
 		"identity_id":   currentIdentity,
@@ -272,19 +247,23 @@ func (c *TokenController) Retrieve(ctx *app.RetrieveTokenContext) error {
 			"for":           ctx.For,
 			"provider_name": providerName,
 		}, "Unable to obtain external token from Keycloak. Account linking may be required.")
+		metric.RecordExternalTokenRetrieval(providerName, false, err)
 
 		linkURL := rest.AbsoluteURL(ctx.RequestData, client.LinkTokenPath())
-		errorResponse := fmt.Sprintf("LINK url=%s, description=\"%s token is missing. Link %s account\"", linkURL, providerName, providerName)
+		description := i18n.Message(ctx, "link.token_missing.description", map[string]interface{}{"provider": providerName})
+		errorResponse := fmt.Sprintf("LINK url=%s, description=\"%s\"", linkURL, description)
 		ctx.ResponseData.Header().Set("Access-Control-Expose-Headers", "WWW-Authenticate")
 		ctx.ResponseData.Header().Set("WWW-Authenticate", errorResponse)
-		return jsonapi.JSONErrorResponse(ctx, errors.NewUnauthorizedError("token is missing"))
+		return jsonapi.JSONErrorResponse(ctx, errors.NewUnauthorizedError(i18n.Message(ctx, "link.token_missing", nil)))
 	}
 
 	externalToken, err = c.saveKeycloakToken(ctx, *keycloakTokenResponse, providerConfig, *currentIdentity)
 	if err != nil {
+		metric.RecordExternalTokenRetrieval(providerName, false, err)
 		return jsonapi.JSONErrorResponse(ctx, err)
 	}
 	updatedToken, err := c.updateProfileIfEmpty(ctx, providerConfig, externalToken, ctx.ForcePull)
+	metric.RecordExternalTokenRetrieval(providerName, false, err)
 	if err != nil {
 		return jsonapi.JSONErrorResponse(ctx, err)
 	}
@@ -364,9 +343,9 @@ func (c *TokenController) Exchange(ctx *app.ExchangeTokenContext) error {
 	sa, found := c.Configuration.GetServiceAccounts()[*payload.ClientID]
 	if !found {
 		log.Error(ctx, map[string]interface{}{
-			"client_id":     *payload.ClientID,
-			"client_secret": *payload.ClientSecret,
+			"client_id": *payload.ClientID,
 		}, "Unknown Service Account ID")
+		metric.RecordServiceAccountTokenExchange("unknown", errors.NewUnauthorizedError("unknown Service Account ID"))
 		return jsonapi.JSONErrorResponse(ctx, errors.NewUnauthorizedError("invalid Service Account ID or secret"))
 	}
 	secret := []byte(*payload.ClientSecret)
@@ -374,6 +353,7 @@ func (c *TokenController) Exchange(ctx *app.ExchangeTokenContext) error {
 		if bcrypt.CompareHashAndPassword([]byte(hash), secret) == nil {
 			tokenType := "bearer"
 			accessToken, err := c.TokenManager.GenerateServiceAccountToken(ctx.RequestData, sa.ID, sa.Name)
+			metric.RecordServiceAccountTokenExchange(sa.Name, err)
 			if err != nil {
 				return jsonapi.JSONErrorResponse(ctx, err)
 			}
@@ -385,9 +365,9 @@ func (c *TokenController) Exchange(ctx *app.ExchangeTokenContext) error {
 		}
 	}
 	log.Error(ctx, map[string]interface{}{
-		"client_id":     *payload.ClientID,
-		"client_secret": *payload.ClientSecret,
+		"client_id": *payload.ClientID,
 	}, "Service Account secret doesn't match")
+	metric.RecordServiceAccountTokenExchange(sa.Name, errors.NewUnauthorizedError("Service Account secret doesn't match"))
 	return jsonapi.JSONErrorResponse(ctx, errors.NewUnauthorizedError("invalid Service Account ID or secret"))
 }
 
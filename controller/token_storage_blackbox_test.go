@@ -45,11 +45,12 @@ func TestRunTokenStorageREST(t *testing.T) {
 
 func (rest *TestTokenStorageREST) SetupTest() {
 	rest.DBTestSuite.SetupTest()
+	rest.SeedClusters()
 	rest.mockKeycloakExternalTokenServiceClient = newMockKeycloakExternalTokenServiceClient()
 	rest.identityRepository = account.NewIdentityRepository(rest.DB)
 	rest.externalTokenRepository = provider.NewExternalTokenRepository(rest.DB)
 	rest.userRepository = account.NewUserRepository(rest.DB)
-	rest.providerConfigFactory = link.NewOauthProviderFactory(rest.Configuration)
+	rest.providerConfigFactory = link.NewOauthProviderFactory(rest.Configuration, rest.Application)
 	rest.dummyProviderConfigFactory = &testsupport.DummyProviderFactory{Token: uuid.NewV4().String(), Config: rest.Configuration}
 }
 
@@ -7,12 +7,16 @@ import (
 	"github.com/fabric8-services/fabric8-auth/account"
 	"github.com/fabric8-services/fabric8-auth/app"
 	"github.com/fabric8-services/fabric8-auth/application"
+	autherrors "github.com/fabric8-services/fabric8-auth/errors"
 	"github.com/fabric8-services/fabric8-auth/jsonapi"
 	"github.com/fabric8-services/fabric8-auth/log"
+	"github.com/fabric8-services/fabric8-auth/outbox"
+	"github.com/fabric8-services/fabric8-auth/rest"
 	"github.com/fabric8-services/fabric8-auth/token"
 
 	"github.com/goadesign/goa"
 	"github.com/pkg/errors"
+	uuid "github.com/satori/go.uuid"
 )
 
 // UserController implements the user resource.
@@ -21,12 +25,12 @@ type UserController struct {
 	db           application.DB
 	tokenManager token.Manager
 	config       UserControllerConfiguration
-	InitTenant   func(ctx context.Context) error
 }
 
 // UserControllerConfiguration the Configuration for the UserController
 type UserControllerConfiguration interface {
 	GetCacheControlUser() string
+	GetTenantServiceURL() string
 }
 
 // NewUserController creates a user controller.
@@ -47,7 +51,8 @@ func (c *UserController) Show(ctx *app.ShowUserContext) error {
 		return ctx.BadRequest(jerrors)
 	}
 
-	return application.Transactional(c.db, func(appl application.Application) error {
+	var user *account.User
+	err = application.Transactional(c.db, func(appl application.Application) error {
 		identity, err := appl.Identities().Load(ctx, id)
 		if err != nil || identity == nil {
 			log.Error(ctx, map[string]interface{}{
@@ -56,7 +61,6 @@ func (c *UserController) Show(ctx *app.ShowUserContext) error {
 			jerrors, _ := jsonapi.ErrorToJSONAPIErrors(ctx, goa.ErrUnauthorized(fmt.Sprintf("Auth token contains id %s of unknown Identity\n", id)))
 			return ctx.Unauthorized(jerrors)
 		}
-		var user *account.User
 		userID := identity.UserID
 		if userID.Valid {
 			user, err = appl.Users().Load(ctx.Context, userID.UUID)
@@ -65,12 +69,114 @@ func (c *UserController) Show(ctx *app.ShowUserContext) error {
 			}
 		}
 		return ctx.ConditionalRequest(*user, c.config.GetCacheControlUser, func() error {
-			if c.InitTenant != nil {
-				go func(ctx context.Context) {
-					c.InitTenant(ctx)
-				}(ctx)
-			}
 			return ctx.OK(ConvertToAppUser(ctx.RequestData, user, identity))
 		})
 	})
+	if err == nil && user != nil {
+		c.requestMissingTenant(ctx, id)
+	}
+	return err
+}
+
+// requestMissingTenant requests the provisioning of the tenant of the given identity if it was never requested, e.g. for
+// the users created before the provisioning was tracked. The tenant is set up by the outbox dispatcher, so showing the user
+// never calls the tenant service, and a failure is only logged.
+func (c *UserController) requestMissingTenant(ctx *app.ShowUserContext, identityID uuid.UUID) {
+	if c.config.GetTenantServiceURL() == "" {
+		return
+	}
+	err := application.Transactional(c.db, func(appl application.Application) error {
+		_, err := appl.TenantProvisionings().Load(ctx, identityID)
+		if notFound, _ := autherrors.IsNotFoundError(err); !notFound {
+			return err
+		}
+		return requestTenant(ctx, appl, identityID, rest.AbsoluteURL(ctx.RequestData, ""), false)
+	})
+	if err != nil {
+		log.Error(ctx, map[string]interface{}{
+			"identity_id": identityID,
+			"err":         err,
+		}, "unable to request the provisioning of the tenant")
+	}
+}
+
+// ShowTenant returns the state of the provisioning of the tenant of the authorized user
+func (c *UserController) ShowTenant(ctx *app.ShowTenantUserContext) error {
+	id, err := c.tokenManager.Locate(ctx)
+	if err != nil {
+		jerrors, _ := jsonapi.ErrorToJSONAPIErrors(ctx, goa.ErrBadRequest(err.Error()))
+		return ctx.BadRequest(jerrors)
+	}
+	provisioning, err := c.db.TenantProvisionings().Load(ctx, id)
+	if err != nil {
+		return jsonapi.JSONErrorResponse(ctx, err)
+	}
+	return ctx.OK(&app.TenantProvisioningSingle{Data: ConvertToAppTenantProvisioning(*provisioning)})
+}
+
+// ProvisionTenant sets up the tenant of the authorized user unless it is already set up or being set up,
+// e.g. after the provisioning failed
+func (c *UserController) ProvisionTenant(ctx *app.ProvisionTenantUserContext) error {
+	id, err := c.tokenManager.Locate(ctx)
+	if err != nil {
+		jerrors, _ := jsonapi.ErrorToJSONAPIErrors(ctx, goa.ErrBadRequest(err.Error()))
+		return ctx.BadRequest(jerrors)
+	}
+	if c.config.GetTenantServiceURL() == "" {
+		return jsonapi.JSONErrorResponse(ctx, autherrors.NewInternalErrorFromString(ctx, "the tenant service is not configured"))
+	}
+	var provisioning *account.TenantProvisioning
+	err = application.Transactional(c.db, func(appl application.Application) error {
+		if err := requestTenant(ctx, appl, id, rest.AbsoluteURL(ctx.RequestData, ""), false); err != nil {
+			return err
+		}
+		provisioning, err = appl.TenantProvisionings().Load(ctx, id)
+		return err
+	})
+	if err != nil {
+		return jsonapi.JSONErrorResponse(ctx, err)
+	}
+	return ctx.Accepted(&app.TenantProvisioningSingle{Data: ConvertToAppTenantProvisioning(*provisioning)})
+}
+
+// requestTenant enqueues the event which sets up the tenant of the given identity with the service account token of the auth
+// service with the given URL, unless the tenant is already being set up. A tenant which is set up is only requested again if
+// forced, e.g. by an administrator. The tenant service ignores the tenants which already exist.
+func requestTenant(ctx context.Context, appl application.Application, identityID uuid.UUID, authURL string, force bool) error {
+	provisioning, err := appl.TenantProvisionings().Load(ctx, identityID)
+	if err != nil {
+		if notFound, _ := autherrors.IsNotFoundError(err); !notFound {
+			return err
+		}
+	} else if provisioning.State == account.TenantProvisioningStatePending || provisioning.State == account.TenantProvisioningStateInProgress {
+		return nil
+	} else if provisioning.State == account.TenantProvisioningStateReady && !force {
+		return nil
+	}
+	event, err := outbox.NewTenantRequestedEvent(identityID, authURL)
+	if err != nil {
+		return err
+	}
+	if err := appl.OutboxEvents().Create(ctx, event); err != nil {
+		return err
+	}
+	return appl.TenantProvisionings().Request(ctx, identityID)
+}
+
+// ConvertToAppTenantProvisioning converts the provisioning of the tenant of an identity to the app representation
+func ConvertToAppTenantProvisioning(provisioning account.TenantProvisioning) *app.TenantProvisioning {
+	res := &app.TenantProvisioning{
+		Type: "tenantprovisionings",
+		ID:   provisioning.IdentityID.String(),
+		Attributes: &app.TenantProvisioningAttributes{
+			State:     provisioning.State,
+			Attempts:  &provisioning.Attempts,
+			CreatedAt: &provisioning.CreatedAt,
+			UpdatedAt: &provisioning.UpdatedAt,
+		},
+	}
+	if provisioning.Error != "" {
+		res.Attributes.Error = &provisioning.Error
+	}
+	return res
 }
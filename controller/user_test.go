@@ -11,15 +11,21 @@ import (
 	"github.com/fabric8-services/fabric8-auth/app"
 	"github.com/fabric8-services/fabric8-auth/app/test"
 	"github.com/fabric8-services/fabric8-auth/application"
+	"github.com/fabric8-services/fabric8-auth/audit"
 	"github.com/fabric8-services/fabric8-auth/auth"
 	res "github.com/fabric8-services/fabric8-auth/authorization/resource"
+	"github.com/fabric8-services/fabric8-auth/authorization/role"
+	"github.com/fabric8-services/fabric8-auth/cluster/registry"
 	"github.com/fabric8-services/fabric8-auth/configuration"
 	. "github.com/fabric8-services/fabric8-auth/controller"
 	"github.com/fabric8-services/fabric8-auth/gormsupport"
+	"github.com/fabric8-services/fabric8-auth/invitation"
+	"github.com/fabric8-services/fabric8-auth/outbox"
 	"github.com/fabric8-services/fabric8-auth/resource"
 	"github.com/fabric8-services/fabric8-auth/space"
 	testtoken "github.com/fabric8-services/fabric8-auth/test/token"
 	"github.com/fabric8-services/fabric8-auth/token/provider"
+	"github.com/fabric8-services/fabric8-auth/webhook"
 
 	token "github.com/dgrijalva/jwt-go"
 	"github.com/goadesign/goa"
@@ -249,6 +255,10 @@ func (m TestUserRepository) Load(ctx context.Context, id uuid.UUID) (*account.Us
 	return m.User, nil
 }
 
+func (m TestUserRepository) Lock(ctx context.Context, id uuid.UUID) (*account.User, error) {
+	return m.Load(ctx, id)
+}
+
 func (m TestUserRepository) CheckExists(ctx context.Context, id string) error {
 	if m.User == nil {
 		return errors.New("not found")
@@ -283,6 +293,11 @@ func (m TestUserRepository) Query(funcs ...func(*gorm.DB) *gorm.DB) ([]account.U
 	return []account.User{*m.User}, nil
 }
 
+// CountByCluster returns the number of users by cluster
+func (m TestUserRepository) CountByCluster(ctx context.Context) (map[string]int, error) {
+	return map[string]int{m.User.Cluster: 1}, nil
+}
+
 type GormTestBase struct {
 	IdentityRepository account.IdentityRepository
 	UserRepository     account.UserRepository
@@ -298,6 +313,10 @@ func (g *GormTestBase) Users() account.UserRepository {
 	return g.UserRepository
 }
 
+func (g *GormTestBase) TenantProvisionings() account.TenantProvisioningRepository {
+	return nil
+}
+
 func (g *GormTestBase) OauthStates() auth.OauthStateReferenceRepository {
 	return nil
 }
@@ -306,6 +325,18 @@ func (g *GormTestBase) SpaceResources() space.ResourceRepository {
 	return nil
 }
 
+func (g *GormTestBase) SpaceTeams() space.TeamRepository {
+	return nil
+}
+
+func (g *GormTestBase) SpaceAccessRequests() space.AccessRequestRepository {
+	return nil
+}
+
+func (g *GormTestBase) SpaceCollaboratorExpiries() space.CollaboratorExpiryRepository {
+	return nil
+}
+
 func (g *GormTestBase) ExternalTokens() provider.ExternalTokenRepository {
 	return nil
 }
@@ -318,6 +349,42 @@ func (g *GormTestBase) ResourceTypeRepository() res.ResourceTypeRepository {
 	return nil
 }
 
+func (g *GormTestBase) ResourceTypeScopeRepository() res.ResourceTypeScopeRepository {
+	return nil
+}
+
+func (g *GormTestBase) RoleRepository() role.RoleRepository {
+	return nil
+}
+
+func (g *GormTestBase) IdentityRoleRepository() role.IdentityRoleRepository {
+	return nil
+}
+
+func (g *GormTestBase) OutboxEvents() outbox.EventRepository {
+	return nil
+}
+
+func (g *GormTestBase) Invitations() invitation.InvitationRepository {
+	return nil
+}
+
+func (g *GormTestBase) AuditEvents() audit.EventRepository {
+	return nil
+}
+
+func (g *GormTestBase) Clusters() registry.ClusterRepository {
+	return nil
+}
+
+func (g *GormTestBase) WebhookSubscriptions() webhook.SubscriptionRepository {
+	return nil
+}
+
+func (g *GormTestBase) WebhookDeliveries() webhook.DeliveryRepository {
+	return nil
+}
+
 func (g *GormTestBase) DB() *gorm.DB {
 	return nil
 }
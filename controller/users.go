@@ -10,14 +10,16 @@ import (
 	"github.com/fabric8-services/fabric8-auth/app"
 	"github.com/fabric8-services/fabric8-auth/application"
 	"github.com/fabric8-services/fabric8-auth/auth"
+	"github.com/fabric8-services/fabric8-auth/cluster"
 	"github.com/fabric8-services/fabric8-auth/errors"
 	"github.com/fabric8-services/fabric8-auth/jsonapi"
 	"github.com/fabric8-services/fabric8-auth/log"
 	"github.com/fabric8-services/fabric8-auth/login"
 	linkAPI "github.com/fabric8-services/fabric8-auth/login/link"
+	"github.com/fabric8-services/fabric8-auth/outbox"
 	"github.com/fabric8-services/fabric8-auth/rest"
 	"github.com/fabric8-services/fabric8-auth/token"
-	"github.com/fabric8-services/fabric8-auth/wit"
+	"github.com/fabric8-services/fabric8-auth/webhook"
 
 	"github.com/goadesign/goa"
 	goajwt "github.com/goadesign/goa/middleware/security/jwt"
@@ -32,7 +34,6 @@ type UsersController struct {
 	db                  application.DB
 	config              UsersControllerConfiguration
 	userProfileService  login.UserProfileService
-	RemoteWITService    wit.RemoteWITService
 	keycloakLinkService linkAPI.KeycloakIDPService
 }
 
@@ -47,6 +48,8 @@ type UsersControllerConfiguration interface {
 	GetKeycloakClientID() string
 	GetKeycloakSecret() string
 	GetKeycloakEndpointLinkIDP(req *goa.RequestData, id string, idp string) (string, error)
+	GetTenantServiceURL() string
+	cluster.Configuration
 }
 
 // NewUsersController creates a users controller.
@@ -56,7 +59,6 @@ func NewUsersController(service *goa.Service, db application.DB, config UsersCon
 		db:                  db,
 		config:              config,
 		userProfileService:  userProfileService,
-		RemoteWITService:    &wit.RemoteWITServiceCaller{},
 		keycloakLinkService: linkService,
 	}
 }
@@ -145,26 +147,6 @@ func (c *UsersController) Create(ctx *app.CreateUsersContext) error {
 		return jsonapi.JSONErrorResponse(ctx, errors.NewInternalError(ctx, err))
 	}
 
-	// finally, if all works, we create a user in WIT too.
-	witURL, err := c.config.GetWITURL(ctx.RequestData)
-	if err != nil {
-		log.Error(ctx, map[string]interface{}{
-			"err":              err,
-			"keycloak_user_id": *keycloakUserID,
-		}, "failed to create user in WIT")
-
-		return jsonapi.JSONErrorResponse(ctx, errors.NewInternalError(ctx, err))
-	}
-
-	err = c.RemoteWITService.CreateWITUser(ctx.Context, ctx.RequestData, identity, witURL, identityID.String())
-	if err != nil {
-		log.Error(ctx, map[string]interface{}{
-			"err":              err,
-			"keycloak_user_id": *keycloakUserID,
-		}, "failed to create user in WIT")
-		// Not a blocker. Log the error and proceed.
-	}
-
 	return ctx.OK(ConvertToAppUser(ctx.RequestData, user, identity))
 }
 
@@ -194,7 +176,7 @@ func rhdUserName(userAttributes app.CreateIdentityDataAttributes) string {
 func (c *UsersController) createUserInKeycloak(ctx *app.CreateUsersContext, protectedAccessToken string) (*string, error) {
 
 	// All the below attributes are mandatory: "username", "email"
-	// "cluster" is mandatory too but we do not store it in Keycloak
+	// "cluster" is not stored in Keycloak
 
 	userAttributes := ctx.Payload.Data.Attributes
 
@@ -259,12 +241,11 @@ func (c *UsersController) createUserInDB(ctx *app.CreateUsersContext, identityID
 	var identity *account.Identity
 
 	// Mandatory attributes
-	// "username", "email", "cluster"
+	// "username", "email"
 
 	user = &account.User{
-		ID:      userID,
-		Email:   ctx.Payload.Data.Attributes.Email,
-		Cluster: ctx.Payload.Data.Attributes.Cluster,
+		ID:    userID,
+		Email: ctx.Payload.Data.Attributes.Email,
 	}
 	identity = &account.Identity{
 		ID:           identityID,
@@ -318,6 +299,19 @@ func (c *UsersController) createUserInDB(ctx *app.CreateUsersContext, identityID
 	}
 
 	returnErrorResponse := application.Transactional(c.db, func(appl application.Application) error {
+		// the cluster is chosen by the placement strategy if not set
+		if ctx.Payload.Data.Attributes.Cluster != nil {
+			user.Cluster = *ctx.Payload.Data.Attributes.Cluster
+		} else {
+			region := ""
+			if ctx.Payload.Data.Attributes.Region != nil {
+				region = *ctx.Payload.Data.Attributes.Region
+			}
+			user.Cluster, err = cluster.NewPlacement(c.config).Place(ctx, appl, region)
+			if err != nil {
+				return err
+			}
+		}
 		err = appl.Users().Create(ctx, user)
 		if err != nil {
 			return err
@@ -326,7 +320,8 @@ func (c *UsersController) createUserInDB(ctx *app.CreateUsersContext, identityID
 		if err != nil {
 			return err
 		}
-		return nil
+		// the user is created in WIT by the outbox dispatcher
+		return c.enqueueUserEvents(ctx, ctx.RequestData, appl, outbox.EventTypeUserCreated, identity.ID, identity.Username)
 	})
 
 	if returnErrorResponse != nil {
@@ -442,10 +437,15 @@ func (c *UsersController) Update(ctx *app.UpdateUsersContext) error {
 		}
 
 		if identity.UserID.Valid {
-			user, err = appl.Users().Load(ctx.Context, identity.UserID.UUID)
+			// the user is locked so it can't be modified between the check of the preconditions and the update
+			user, err = appl.Users().Lock(ctx.Context, identity.UserID.UUID)
 			if err != nil {
 				return errs.Wrap(err, fmt.Sprintf("Can't load user with id %s", identity.UserID.UUID))
 			}
+			// reject the update if the user was modified since the client's last call
+			if err := ctx.ConditionalWrite(*user); err != nil {
+				return err
+			}
 		}
 
 		updatedEmail := ctx.Payload.Data.Attributes.Email
@@ -571,13 +571,19 @@ func (c *UsersController) Update(ctx *app.UpdateUsersContext) error {
 		if err != nil {
 			return err
 		}
+		// reload the user to get the modification date as stored in the database, which the ETag is generated from
+		user, err = appl.Users().Load(ctx, user.ID)
+		if err != nil {
+			return err
+		}
 
 		err = appl.Identities().Save(ctx, identity)
 		if err != nil {
 			return err
 		}
 
-		return nil
+		// the user is updated in WIT by the outbox dispatcher
+		return c.enqueueUserEvents(ctx, ctx.RequestData, appl, outbox.EventTypeUserUpdated, identity.ID, identity.Username)
 	})
 
 	if err != nil {
@@ -618,42 +624,180 @@ func (c *UsersController) Update(ctx *app.UpdateUsersContext) error {
 			}
 		}
 	}
-	err = c.updateWITUser(ctx, ctx.RequestData, identity.ID.String())
+	// return the tags of the updated user for the next conditional updates
+	ctx.ResponseData.Header().Set(app.ETag, app.GenerateEntityTag(*user))
+	ctx.ResponseData.Header().Set(app.LastModified, app.ToHTTPTime(user.GetLastModified()))
+	return ctx.OK(ConvertToAppUser(ctx.RequestData, user, identity))
+}
+
+// ReassignCluster moves a user to another cluster when requested using a service account. The OpenShift token
+// of the user for the previous cluster is removed and the user is redirected to the new cluster to link their
+// account at their next login, and the tenant service is notified by the outbox dispatcher to move the tenant of the user.
+func (c *UsersController) ReassignCluster(ctx *app.ReassignClusterUsersContext) error {
+	isSvcAccount := token.IsSpecificServiceAccount(ctx, []string{"online-registration"})
+	if !isSvcAccount {
+		log.Error(ctx, nil, "The account is not an authorized service account allowed to reassign the cluster of a user")
+		return jsonapi.JSONErrorResponse(ctx, errors.NewUnauthorizedError("account not authorized to reassign the cluster of users."))
+	}
+	identityID, err := uuid.FromString(ctx.ID)
 	if err != nil {
-		log.Error(ctx, map[string]interface{}{
-			"user_id":     user.ID,
-			"identity_id": identity.ID,
-			"username":    identity.Username,
-			"err":         err,
-		}, "failed to update WIT user/identity")
-		// Let's not disrupt the response if there was an issue with updating WIT.
+		return jsonapi.JSONErrorResponse(ctx, errors.NewBadParameterError("identity_id", ctx.ID))
+	}
+	var identity account.Identity
+	err = application.Transactional(c.db, func(appl application.Application) error {
+		newCluster, err := appl.Clusters().LoadByURL(ctx, ctx.Cluster)
+		if ok, _ := errors.IsNotFoundError(err); ok {
+			return errors.NewBadParameterError("cluster", ctx.Cluster).Expected("the API URL of a registered cluster")
+		}
+		if err != nil {
+			return err
+		}
+		identities, err := appl.Identities().Query(account.IdentityFilterByID(identityID), account.IdentityWithUser())
+		if err != nil {
+			return err
+		}
+		if len(identities) == 0 || !identities[0].UserID.Valid {
+			return errors.NewNotFoundError("identity", ctx.ID)
+		}
+		identity = identities[0]
+		previousCluster := identity.User.Cluster
+		if previousCluster == newCluster.URL {
+			return nil
+		}
+		identity.User.Cluster = newCluster.URL
+		// the OpenShift account of the new cluster is linked at the next login of the user, whatever the client
+		identity.User.ClusterLinkRequired = true
+		if err := appl.Users().Save(ctx, &identity.User); err != nil {
+			return err
+		}
+		if err := c.unlinkCluster(ctx, appl, identity.ID, previousCluster); err != nil {
+			return err
+		}
+		event, err := outbox.NewTenantClusterChangedEvent(identity.ID, previousCluster, newCluster.URL, rest.AbsoluteURL(ctx.RequestData, ""))
+		if err != nil {
+			return err
+		}
+		if err := appl.OutboxEvents().Create(ctx, event); err != nil {
+			return err
+		}
+		log.Info(ctx, map[string]interface{}{
+			"identity_id":      identity.ID,
+			"previous_cluster": previousCluster,
+			"cluster":          newCluster.URL,
+		}, "user reassigned to another cluster")
+		// the user is updated in WIT by the outbox dispatcher
+		return c.enqueueUserEvents(ctx, ctx.RequestData, appl, outbox.EventTypeUserUpdated, identity.ID, identity.Username)
+	})
+	if err != nil {
+		return jsonapi.JSONErrorResponse(ctx, err)
 	}
+	return ctx.OK(ConvertToAppUser(ctx.RequestData, &identity.User, &identity))
+}
 
-	return ctx.OK(ConvertToAppUser(ctx.RequestData, user, identity))
+// ShowTenant returns the state of the provisioning of the tenant of the user when requested using a service account.
+func (c *UsersController) ShowTenant(ctx *app.ShowTenantUsersContext) error {
+	isSvcAccount := token.IsSpecificServiceAccount(ctx, []string{"online-registration"})
+	if !isSvcAccount {
+		log.Error(ctx, nil, "The account is not an authorized service account allowed to show the tenant of a user")
+		return jsonapi.JSONErrorResponse(ctx, errors.NewUnauthorizedError("account not authorized to show the tenant of users."))
+	}
+	identityID, err := uuid.FromString(ctx.ID)
+	if err != nil {
+		return jsonapi.JSONErrorResponse(ctx, errors.NewBadParameterError("identity_id", ctx.ID))
+	}
+	provisioning, err := c.db.TenantProvisionings().Load(ctx, identityID)
+	if err != nil {
+		return jsonapi.JSONErrorResponse(ctx, err)
+	}
+	return ctx.OK(&app.TenantProvisioningSingle{Data: ConvertToAppTenantProvisioning(*provisioning)})
 }
 
-func (c *UsersController) updateWITUser(ctx *app.UpdateUsersContext, request *goa.RequestData, identityID string) error {
-	updateUserPayload := &app.UpdateUsersPayload{
-		Data: &app.UpdateUserData{
-			Attributes: &app.UpdateIdentityDataAttributes{
-				Bio:                   ctx.Payload.Data.Attributes.Bio,
-				Company:               ctx.Payload.Data.Attributes.Company,
-				ContextInformation:    ctx.Payload.Data.Attributes.ContextInformation,
-				Email:                 ctx.Payload.Data.Attributes.Email,
-				FullName:              ctx.Payload.Data.Attributes.FullName,
-				ImageURL:              ctx.Payload.Data.Attributes.ImageURL,
-				RegistrationCompleted: ctx.Payload.Data.Attributes.RegistrationCompleted,
-				URL:      ctx.Payload.Data.Attributes.URL,
-				Username: ctx.Payload.Data.Attributes.Username,
-			},
-			Type: ctx.Payload.Data.Type,
-		},
+// ProvisionTenant sets up the tenant of the user again when requested using a service account, e.g. after the provisioning
+// failed, unless the tenant is already being set up. The tenant is set up by the outbox dispatcher with the service account token of the auth service.
+func (c *UsersController) ProvisionTenant(ctx *app.ProvisionTenantUsersContext) error {
+	isSvcAccount := token.IsSpecificServiceAccount(ctx, []string{"online-registration"})
+	if !isSvcAccount {
+		log.Error(ctx, nil, "The account is not an authorized service account allowed to provision the tenant of a user")
+		return jsonapi.JSONErrorResponse(ctx, errors.NewUnauthorizedError("account not authorized to provision the tenant of users."))
+	}
+	identityID, err := uuid.FromString(ctx.ID)
+	if err != nil {
+		return jsonapi.JSONErrorResponse(ctx, errors.NewBadParameterError("identity_id", ctx.ID))
+	}
+	if c.config.GetTenantServiceURL() == "" {
+		return jsonapi.JSONErrorResponse(ctx, errors.NewInternalErrorFromString(ctx, "the tenant service is not configured"))
+	}
+	var provisioning *account.TenantProvisioning
+	err = application.Transactional(c.db, func(appl application.Application) error {
+		identities, err := appl.Identities().Query(account.IdentityFilterByID(identityID))
+		if err != nil {
+			return err
+		}
+		if len(identities) == 0 || !identities[0].UserID.Valid {
+			return errors.NewNotFoundError("identity", ctx.ID)
+		}
+		if err := requestTenant(ctx, appl, identityID, rest.AbsoluteURL(ctx.RequestData, ""), true); err != nil {
+			return err
+		}
+		provisioning, err = appl.TenantProvisionings().Load(ctx, identityID)
+		return err
+	})
+	if err != nil {
+		return jsonapi.JSONErrorResponse(ctx, err)
+	}
+	log.Info(ctx, map[string]interface{}{
+		"identity_id": identityID,
+	}, "tenant provisioning requested again")
+	return ctx.Accepted(&app.TenantProvisioningSingle{Data: ConvertToAppTenantProvisioning(*provisioning)})
+}
+
+// unlinkCluster removes the OpenShift tokens of the given identity for the cluster with the given API URL.
+// Nothing is removed if the cluster is not registered anymore.
+func (c *UsersController) unlinkCluster(ctx context.Context, appl application.Application, identityID uuid.UUID, clusterURL string) error {
+	previousCluster, err := appl.Clusters().LoadByURL(ctx, clusterURL)
+	if ok, _ := errors.IsNotFoundError(err); ok {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	tokens, err := appl.ExternalTokens().LoadByProviderIDAndIdentityID(ctx, previousCluster.TokenProviderID, identityID)
+	if err != nil {
+		return err
+	}
+	for _, t := range tokens {
+		if err := appl.ExternalTokens().Delete(ctx, t.ID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// enqueueUserEvents writes the event which creates or updates the user of the given identity in WIT,
+// and notifies the webhook subscriptions of the change
+func (c *UsersController) enqueueUserEvents(ctx context.Context, req *goa.RequestData, appl application.Application, eventType string, identityID uuid.UUID, username string) error {
+	if err := c.enqueueWITEvent(ctx, req, appl, eventType, identityID); err != nil {
+		return err
+	}
+	return webhook.Emit(ctx, appl, eventType, webhook.Data{IdentityID: identityID, Username: username})
+}
+
+// enqueueWITEvent writes the event which creates or updates the user of the given identity in WIT
+func (c *UsersController) enqueueWITEvent(ctx context.Context, req *goa.RequestData, appl application.Application, eventType string, identityID uuid.UUID) error {
+	witURL, err := c.config.GetWITURL(req)
+	if err != nil {
+		return err
+	}
+	var event *outbox.Event
+	if eventType == outbox.EventTypeUserCreated {
+		event, err = outbox.NewWITUserCreatedEvent(identityID, witURL, rest.AbsoluteURL(req, ""))
+	} else {
+		event, err = outbox.NewWITUserUpdatedEvent(identityID, witURL, rest.AbsoluteURL(req, ""))
 	}
-	witURL, err := c.config.GetWITURL(ctx.RequestData)
 	if err != nil {
 		return err
 	}
-	return c.RemoteWITService.UpdateWITUser(ctx, request, updateUserPayload, witURL, identityID)
+	return appl.OutboxEvents().Create(ctx, event)
 }
 
 func isEmailValid(email string) bool {
@@ -16,6 +16,7 @@ import (
 	"github.com/fabric8-services/fabric8-auth/log"
 	"github.com/fabric8-services/fabric8-auth/login"
 	"github.com/fabric8-services/fabric8-auth/login/link"
+	"github.com/fabric8-services/fabric8-auth/outbox"
 	"github.com/fabric8-services/fabric8-auth/resource"
 	testsupport "github.com/fabric8-services/fabric8-auth/test"
 
@@ -52,20 +53,17 @@ func (s *TestUsersSuite) SetupSuite() {
 	s.controller = NewUsersController(s.svc, s.Application, s.Configuration, s.profileService, s.linkAPIService)
 	s.userRepo = s.Application.Users()
 	s.identityRepo = s.Application.Identities()
-	s.controller.RemoteWITService = &dummyRemoteWITService{}
 }
 
 func (s *TestUsersSuite) SecuredController(identity account.Identity) (*goa.Service, *UsersController) {
 	svc := testsupport.ServiceAsUser("Users-Service", identity)
 	controller := NewUsersController(s.svc, s.Application, s.Configuration, s.profileService, s.linkAPIService)
-	controller.RemoteWITService = &dummyRemoteWITService{}
 	return svc, controller
 }
 
 func (s *TestUsersSuite) SecuredServiceAccountController(identity account.Identity) (*goa.Service, *UsersController) {
 	svc := testsupport.ServiceAsServiceAccountUser("Users-ServiceAccount-Service", identity)
 	controller := NewUsersController(s.svc, s.Application, s.Configuration, s.profileService, s.linkAPIService)
-	controller.RemoteWITService = &dummyRemoteWITService{}
 	return svc, controller
 }
 
@@ -98,7 +96,7 @@ func (s *TestUsersSuite) TestUpdateUserOK() {
 	}
 	//secureController, secureService := createSecureController(t, identity)
 	updateUsersPayload := createUpdateUsersPayload(&newEmail, &newFullName, &newBio, &newImageURL, &newProfileURL, &newCompany, nil, nil, contextInformation)
-	_, result = test.UpdateUsersOK(s.T(), secureService.Context, secureService, secureController, updateUsersPayload)
+	_, result = test.UpdateUsersOK(s.T(), secureService.Context, secureService, secureController, nil, nil, updateUsersPayload)
 
 	// then
 	require.NotNil(s.T(), result)
@@ -119,6 +117,13 @@ func (s *TestUsersSuite) TestUpdateUserOK() {
 	assert.True(s.T(), ok)
 	assert.Equal(s.T(), contextInformation["count"], int(countValue))
 	assert.Equal(s.T(), contextInformation["rate"], updatedContextInformation["rate"])
+
+	// the user is updated in WIT by the outbox dispatcher
+	events, err := s.Application.OutboxEvents().Query(s.Ctx, outbox.EventFilterByIdentityID(identity.ID))
+	require.NoError(s.T(), err)
+	require.Len(s.T(), events, 1)
+	assert.Equal(s.T(), outbox.EventTypeUserUpdated, events[0].EventType)
+	assert.Equal(s.T(), outbox.TargetWIT, events[0].Target)
 }
 
 func (s *TestUsersSuite) TestUpdateUserNameMulitpleTimesForbidden() {
@@ -138,16 +143,16 @@ func (s *TestUsersSuite) TestUpdateUserNameMulitpleTimesForbidden() {
 	// you can update username multiple times.
 	// also omit registrationCompleted
 	updateUsersPayload := createUpdateUsersPayload(nil, nil, nil, nil, nil, nil, &newUserName, nil, contextInformation)
-	_, result = test.UpdateUsersOK(s.T(), secureService.Context, secureService, secureController, updateUsersPayload)
+	_, result = test.UpdateUsersOK(s.T(), secureService.Context, secureService, secureController, nil, nil, updateUsersPayload)
 
 	boolTrue := true
 	updateUsersPayload = createUpdateUsersPayload(nil, nil, nil, nil, nil, nil, &newUserName, &boolTrue, contextInformation)
-	_, result = test.UpdateUsersOK(s.T(), secureService.Context, secureService, secureController, updateUsersPayload)
+	_, result = test.UpdateUsersOK(s.T(), secureService.Context, secureService, secureController, nil, nil, updateUsersPayload)
 
 	// next attempt should fail.
 	newUserName = identity.Username + uuid.NewV4().String()
 	updateUsersPayload = createUpdateUsersPayload(nil, nil, nil, nil, nil, nil, &newUserName, nil, contextInformation)
-	test.UpdateUsersForbidden(s.T(), secureService.Context, secureService, secureController, updateUsersPayload)
+	test.UpdateUsersForbidden(s.T(), secureService.Context, secureService, secureController, nil, nil, updateUsersPayload)
 }
 
 func (s *TestUsersSuite) TestUpdateUserNameMulitpleTimesOK() {
@@ -165,11 +170,11 @@ func (s *TestUsersSuite) TestUpdateUserNameMulitpleTimesOK() {
 	}
 
 	updateUsersPayload := createUpdateUsersPayload(nil, nil, nil, nil, nil, nil, &newUserName, nil, contextInformation)
-	_, result = test.UpdateUsersOK(s.T(), secureService.Context, secureService, secureController, updateUsersPayload)
+	_, result = test.UpdateUsersOK(s.T(), secureService.Context, secureService, secureController, nil, nil, updateUsersPayload)
 	require.False(s.T(), *result.Data.Attributes.RegistrationCompleted)
 
 	// next attempt should PASS.
-	_, result = test.UpdateUsersOK(s.T(), secureService.Context, secureService, secureController, updateUsersPayload)
+	_, result = test.UpdateUsersOK(s.T(), secureService.Context, secureService, secureController, nil, nil, updateUsersPayload)
 	require.False(s.T(), *result.Data.Attributes.RegistrationCompleted)
 
 }
@@ -187,13 +192,13 @@ func (s *TestUsersSuite) TestUpdateRegistrationCompletedOK() {
 	}
 
 	updateUsersPayload := createUpdateUsersPayload(nil, nil, nil, nil, nil, nil, nil, nil, contextInformation)
-	_, result = test.UpdateUsersOK(s.T(), secureService.Context, secureService, secureController, updateUsersPayload)
+	_, result = test.UpdateUsersOK(s.T(), secureService.Context, secureService, secureController, nil, nil, updateUsersPayload)
 	require.False(s.T(), *result.Data.Attributes.RegistrationCompleted)
 
 	// next attempt should PASS.
 	boolTrue := true
 	updateUsersPayload = createUpdateUsersPayload(nil, nil, nil, nil, nil, nil, nil, &boolTrue, contextInformation)
-	test.UpdateUsersOK(s.T(), secureService.Context, secureService, secureController, updateUsersPayload)
+	test.UpdateUsersOK(s.T(), secureService.Context, secureService, secureController, nil, nil, updateUsersPayload)
 }
 
 func (s *TestUsersSuite) TestUpdateRegistrationCompletedBadRequest() {
@@ -209,14 +214,18 @@ func (s *TestUsersSuite) TestUpdateRegistrationCompletedBadRequest() {
 	}
 
 	updateUsersPayload := createUpdateUsersPayload(nil, nil, nil, nil, nil, nil, nil, nil, contextInformation)
-	_, result = test.UpdateUsersOK(s.T(), secureService.Context, secureService, secureController, updateUsersPayload)
+	_, result = test.UpdateUsersOK(s.T(), secureService.Context, secureService, secureController, nil, nil, updateUsersPayload)
 	require.False(s.T(), *result.Data.Attributes.RegistrationCompleted)
 
 	// next attempt should fail.
 	boolFalse := false
 	updateUsersPayload = createUpdateUsersPayload(nil, nil, nil, nil, nil, nil, nil, &boolFalse, contextInformation)
-	test.UpdateUsersBadRequest(s.T(), secureService.Context, secureService, secureController, updateUsersPayload)
+	test.UpdateUsersBadRequest(s.T(), secureService.Context, secureService, secureController, nil, nil, updateUsersPayload)
 
+	// only the first update is delivered to WIT
+	events, err := s.Application.OutboxEvents().Query(s.Ctx, outbox.EventFilterByIdentityID(identity.ID))
+	require.NoError(s.T(), err)
+	assert.Len(s.T(), events, 1)
 }
 
 func (s *TestUsersSuite) TestUpdateRegistrationCompletedAndUsernameOK() {
@@ -236,16 +245,69 @@ func (s *TestUsersSuite) TestUpdateRegistrationCompletedAndUsernameOK() {
 	}
 
 	updateUsersPayload := createUpdateUsersPayload(nil, nil, nil, nil, nil, nil, nil, nil, contextInformation)
-	_, result = test.UpdateUsersOK(s.T(), secureService.Context, secureService, secureController, updateUsersPayload)
+	_, result = test.UpdateUsersOK(s.T(), secureService.Context, secureService, secureController, nil, nil, updateUsersPayload)
 	require.False(s.T(), *result.Data.Attributes.RegistrationCompleted)
 
 	boolTrue := true
 	newUserName := identity.Username + uuid.NewV4().String()
 	updateUsersPayload = createUpdateUsersPayload(nil, nil, nil, nil, nil, nil, &newUserName, &boolTrue, contextInformation)
-	test.UpdateUsersOK(s.T(), secureService.Context, secureService, secureController, updateUsersPayload)
+	test.UpdateUsersOK(s.T(), secureService.Context, secureService, secureController, nil, nil, updateUsersPayload)
 
 }
 
+func (s *TestUsersSuite) TestUpdateUserWithIfMatch() {
+	user := s.createRandomUser("TestUpdateUserWithIfMatch")
+	identity := s.createRandomIdentity(user, account.KeycloakIDP)
+	res, _ := test.ShowUsersOK(s.T(), nil, nil, s.controller, identity.ID.String(), nil, nil)
+	etag := res.Header().Get(app.ETag)
+	require.NotEmpty(s.T(), etag)
+	secureService, secureController := s.SecuredController(identity)
+	newBio := "new bio"
+	updateUsersPayload := createUpdateUsersPayload(nil, nil, &newBio, nil, nil, nil, nil, nil, nil)
+
+	s.T().Run("precondition failed", func(t *testing.T) {
+		// when
+		staleETag := "\"stale\""
+		test.UpdateUsersPreconditionFailed(t, secureService.Context, secureService, secureController, &staleETag, nil, updateUsersPayload)
+		// then the user is not updated
+		events, err := s.Application.OutboxEvents().Query(s.Ctx, outbox.EventFilterByIdentityID(identity.ID))
+		require.NoError(t, err)
+		assert.Empty(t, events)
+	})
+
+	s.T().Run("ok", func(t *testing.T) {
+		// when
+		quotedETag := "\"" + etag + "\""
+		res, result := test.UpdateUsersOK(t, secureService.Context, secureService, secureController, &quotedETag, nil, updateUsersPayload)
+		// then
+		assert.Equal(t, newBio, *result.Data.Attributes.Bio)
+		assert.NotEmpty(t, res.Header().Get(app.ETag))
+		assert.NotEmpty(t, res.Header().Get(app.LastModified))
+	})
+}
+
+func (s *TestUsersSuite) TestUpdateUserWithIfUnmodifiedSince() {
+	user := s.createRandomUser("TestUpdateUserWithIfUnmodifiedSince")
+	identity := s.createRandomIdentity(user, account.KeycloakIDP)
+	secureService, secureController := s.SecuredController(identity)
+	newBio := "new bio"
+	updateUsersPayload := createUpdateUsersPayload(nil, nil, &newBio, nil, nil, nil, nil, nil, nil)
+
+	s.T().Run("precondition failed", func(t *testing.T) {
+		// when
+		ifUnmodifiedSince := app.ToHTTPTime(user.UpdatedAt.Add(-1 * time.Hour))
+		test.UpdateUsersPreconditionFailed(t, secureService.Context, secureService, secureController, nil, &ifUnmodifiedSince, updateUsersPayload)
+	})
+
+	s.T().Run("ok", func(t *testing.T) {
+		// when
+		ifUnmodifiedSince := app.ToHTTPTime(user.UpdatedAt)
+		_, result := test.UpdateUsersOK(t, secureService.Context, secureService, secureController, nil, &ifUnmodifiedSince, updateUsersPayload)
+		// then
+		assert.Equal(t, newBio, *result.Data.Attributes.Bio)
+	})
+}
+
 func (s *TestUsersSuite) TestUpdateExistingUsernameForbidden() {
 	// create 2 users.
 	user := s.createRandomUser("OK")
@@ -267,7 +329,7 @@ func (s *TestUsersSuite) TestUpdateExistingUsernameForbidden() {
 
 	newUserName := identity.Username
 	updateUsersPayload := createUpdateUsersPayload(nil, nil, nil, nil, nil, nil, &newUserName, nil, contextInformation)
-	test.UpdateUsersBadRequest(s.T(), secureService.Context, secureService, secureController, updateUsersPayload)
+	test.UpdateUsersBadRequest(s.T(), secureService.Context, secureService, secureController, nil, nil, updateUsersPayload)
 }
 
 func (s *TestUsersSuite) TestUpdateExistingEmailForbidden() {
@@ -291,7 +353,7 @@ func (s *TestUsersSuite) TestUpdateExistingEmailForbidden() {
 
 	newEmail := user.Email
 	updateUsersPayload := createUpdateUsersPayload(&newEmail, nil, nil, nil, nil, nil, nil, nil, contextInformation)
-	test.UpdateUsersBadRequest(s.T(), secureService.Context, secureService, secureController, updateUsersPayload)
+	test.UpdateUsersBadRequest(s.T(), secureService.Context, secureService, secureController, nil, nil, updateUsersPayload)
 }
 
 func (s *TestUsersSuite) TestUpdateUserVariableSpacesInNameOK() {
@@ -326,7 +388,7 @@ func (s *TestUsersSuite) TestUpdateUserVariableSpacesInNameOK() {
 	}
 	//secureController, secureService := createSecureController(t, identity)
 	updateUsersPayload := createUpdateUsersPayload(&newEmail, &newFullName, &newBio, &newImageURL, &newProfileURL, &newCompany, nil, nil, contextInformation)
-	_, result = test.UpdateUsersOK(s.T(), secureService.Context, secureService, secureController, updateUsersPayload)
+	_, result = test.UpdateUsersOK(s.T(), secureService.Context, secureService, secureController, nil, nil, updateUsersPayload)
 	// then
 	require.NotNil(s.T(), result)
 	// let's fetch it and validate
@@ -375,7 +437,7 @@ func (s *TestUsersSuite) TestUpdateUserUnsetVariableInContextInfo() {
 	}
 	//secureController, secureService := createSecureController(t, identity)
 	updateUsersPayload := createUpdateUsersPayload(&newEmail, &newFullName, &newBio, &newImageURL, &newProfileURL, nil, nil, nil, contextInformation)
-	_, result = test.UpdateUsersOK(s.T(), secureService.Context, secureService, secureController, updateUsersPayload)
+	_, result = test.UpdateUsersOK(s.T(), secureService.Context, secureService, secureController, nil, nil, updateUsersPayload)
 	// then
 	require.NotNil(s.T(), result)
 	// let's fetch it and validate the usual stuff.
@@ -398,7 +460,7 @@ func (s *TestUsersSuite) TestUpdateUserUnsetVariableInContextInfo() {
 	}
 
 	updateUsersPayload = createUpdateUsersPayload(&newEmail, &newFullName, &newBio, &newImageURL, &newProfileURL, nil, nil, nil, contextInformation)
-	_, result = test.UpdateUsersOK(s.T(), secureService.Context, secureService, secureController, updateUsersPayload)
+	_, result = test.UpdateUsersOK(s.T(), secureService.Context, secureService, secureController, nil, nil, updateUsersPayload)
 	// then
 	require.NotNil(s.T(), result)
 	// let's fetch it and validate the usual stuff.
@@ -436,7 +498,7 @@ func (s *TestUsersSuite) TestUpdateUserOKWithoutContextInfo() {
 	secureService, secureController := s.SecuredController(identity)
 
 	updateUsersPayload := createUpdateUsersPayloadWithoutContextInformation(&newEmail, &newFullName, &newBio, &newImageURL, &newProfileURL)
-	test.UpdateUsersOK(s.T(), secureService.Context, secureService, secureController, updateUsersPayload)
+	test.UpdateUsersOK(s.T(), secureService.Context, secureService, secureController, nil, nil, updateUsersPayload)
 }
 
 //Pass " " as email in HTTP PATCH  /api/Users
@@ -457,7 +519,7 @@ func (s *TestUsersSuite) TestUpdateUserWithInvalidEmail() {
 
 	//then
 	updateUsersPayload := createUpdateUsersPayloadWithoutContextInformation(&newEmail, &newFullName, &newBio, &newImageURL, &newProfileURL)
-	test.UpdateUsersBadRequest(s.T(), secureService.Context, secureService, secureController, updateUsersPayload)
+	test.UpdateUsersBadRequest(s.T(), secureService.Context, secureService, secureController, nil, nil, updateUsersPayload)
 }
 
 //Pass " " as username in HTTP PATCH  /api/Users
@@ -478,7 +540,7 @@ func (s *TestUsersSuite) TestUpdateUserWithInvalidUsername() {
 	updateUsersPayload := createUpdateUsersPayload(nil, nil, nil, nil, nil, nil, &username, nil, contextInformation)
 
 	//then
-	test.UpdateUsersBadRequest(s.T(), secureService.Context, secureService, secureController, updateUsersPayload)
+	test.UpdateUsersBadRequest(s.T(), secureService.Context, secureService, secureController, nil, nil, updateUsersPayload)
 }
 
 func (s *TestUsersSuite) TestPatchUserContextInformation() {
@@ -497,7 +559,7 @@ func (s *TestUsersSuite) TestPatchUserContextInformation() {
 	}
 	//secureController, secureService := createSecureController(t, identity)
 	updateUsersPayload := createUpdateUsersPayload(nil, nil, nil, nil, nil, nil, nil, nil, contextInformation)
-	_, result = test.UpdateUsersOK(s.T(), secureService.Context, secureService, secureController, updateUsersPayload)
+	_, result = test.UpdateUsersOK(s.T(), secureService.Context, secureService, secureController, nil, nil, updateUsersPayload)
 	// then
 	require.NotNil(s.T(), result)
 
@@ -519,7 +581,7 @@ func (s *TestUsersSuite) TestPatchUserContextInformation() {
 	}
 
 	updateUsersPayload = createUpdateUsersPayload(nil, nil, nil, nil, nil, nil, nil, nil, patchedContextInformation)
-	_, result = test.UpdateUsersOK(s.T(), secureService.Context, secureService, secureController, updateUsersPayload)
+	_, result = test.UpdateUsersOK(s.T(), secureService.Context, secureService, secureController, nil, nil, updateUsersPayload)
 	require.NotNil(s.T(), result)
 
 	// let's fetch it and validate the usual stuff.
@@ -559,7 +621,7 @@ func (s *TestUsersSuite) TestUpdateUserUnauthorized() {
 	//secureController, secureService := createSecureController(t, identity)
 	updateUsersPayload := createUpdateUsersPayload(&newEmail, &newFullName, &newBio, &newImageURL, &newProfileURL, nil, nil, nil, contextInformation)
 	// when/then
-	test.UpdateUsersUnauthorized(s.T(), context.Background(), nil, s.controller, updateUsersPayload)
+	test.UpdateUsersUnauthorized(s.T(), context.Background(), nil, s.controller, nil, nil, updateUsersPayload)
 }
 
 func (s *TestUsersSuite) TestShowUserOK() {
@@ -972,16 +1034,6 @@ func (s *TestUsersSuite) generateUsersTag(allUsers app.UserArray) string {
 	return app.GenerateEntitiesTag(entities)
 }
 
-type dummyRemoteWITService struct{}
-
-func (r *dummyRemoteWITService) UpdateWITUser(ctx context.Context, req *goa.RequestData, updatePayload *app.UpdateUsersPayload, witURL string, identityID string) error {
-	return nil
-}
-
-func (r *dummyRemoteWITService) CreateWITUser(ctx context.Context, req *goa.RequestData, identity *account.Identity, witURL string, identityID string) error {
-	return nil
-}
-
 type dummyKeycloakLinkService struct{}
 
 func (d *dummyKeycloakLinkService) Create(ctx context.Context, keycloakLinkIDPRequest *link.KeycloakLinkIDPRequest, protectedAccessToken string, keycloakIDPLinkURL string) error {
@@ -1090,9 +1142,9 @@ func (s *TestUsersSuite) TestCreateUserAsServiceAccountWithMissingRequiredFields
 	createUserPayload = createCreateUsersAsServiceAccountPayload(nil, nil, nil, nil, nil, nil, &identity.Username, nil, &cluster, nil, nil, nil)
 	require.NotNil(s.T(), createUserPayload.Validate())
 
-	// Missing cluster
+	// Missing cluster is OK, the user is placed on one of the configured clusters
 	createUserPayload = createCreateUsersAsServiceAccountPayload(&user.Email, nil, nil, nil, nil, nil, &identity.Username, nil, nil, nil, nil, nil)
-	require.NotNil(s.T(), createUserPayload.Validate())
+	require.Nil(s.T(), createUserPayload.Validate())
 }
 
 func (s *TestUsersSuite) TestCreateUserAsServiceAccountUnauthorized() {
@@ -1153,7 +1205,7 @@ func createCreateUsersAsServiceAccountPayload(email, fullName, bio, imageURL, pr
 		attributes.Username = *username
 	}
 	if cluster != nil {
-		attributes.Cluster = *cluster
+		attributes.Cluster = cluster
 	}
 
 	return &app.CreateUsersPayload{
@@ -0,0 +1,125 @@
+package controller_test
+
+import (
+	"context"
+
+	"github.com/fabric8-services/fabric8-auth/account"
+	"github.com/fabric8-services/fabric8-auth/app/test"
+	"github.com/fabric8-services/fabric8-auth/configuration"
+	"github.com/fabric8-services/fabric8-auth/outbox"
+	testsupport "github.com/fabric8-services/fabric8-auth/test"
+	"github.com/fabric8-services/fabric8-auth/token/provider"
+
+	"github.com/satori/go.uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func (s *TestUsersSuite) TestCreateUserAsServiceAccountWithoutClusterOK() {
+	// given
+	s.SeedClusters()
+	user := testsupport.TestUser
+	identity := testsupport.TestIdentity
+	secureService, secureController := s.SecuredServiceAccountController(testsupport.TestOnlineRegistrationAppIdentity)
+	createUserPayload := createCreateUsersAsServiceAccountPayload(&user.Email, nil, nil, nil, nil, nil, &identity.Username, nil, nil, nil, nil, nil)
+
+	// when
+	_, appUser := test.CreateUsersOK(s.T(), secureService.Context, secureService, secureController, createUserPayload)
+
+	// then the user is placed on one of the configured clusters
+	require.NotNil(s.T(), appUser.Data.Attributes.Cluster)
+	assert.Contains(s.T(), s.Configuration.GetOSOClusters(), *appUser.Data.Attributes.Cluster)
+}
+
+func (s *TestUsersSuite) TestReassignClusterOK() {
+	// given
+	previous, cluster := s.clusters()
+	user := s.createRandomUser("TestReassignClusterOK")
+	user.Cluster = previous.URL
+	require.Nil(s.T(), s.userRepo.Save(context.Background(), &user))
+	identity := s.createRandomIdentity(user, account.KeycloakIDP)
+	providerID, err := uuid.FromString(previous.TokenProviderID)
+	require.Nil(s.T(), err)
+	externalToken := provider.ExternalToken{
+		ProviderID: providerID,
+		IdentityID: identity.ID,
+		Token:      "1234-from-db",
+		Username:   identity.Username,
+	}
+	require.Nil(s.T(), s.Application.ExternalTokens().Create(context.Background(), &externalToken))
+	secureService, secureController := s.SecuredServiceAccountController(testsupport.TestOnlineRegistrationAppIdentity)
+
+	// when
+	_, appUser := test.ReassignClusterUsersOK(s.T(), secureService.Context, secureService, secureController, identity.ID.String(), cluster.URL)
+
+	// then
+	assert.Equal(s.T(), cluster.URL, *appUser.Data.Attributes.Cluster)
+	updated, err := s.userRepo.Load(context.Background(), user.ID)
+	require.Nil(s.T(), err)
+	assert.Equal(s.T(), cluster.URL, updated.Cluster)
+	// the user must link the new cluster at the next login
+	assert.True(s.T(), updated.ClusterLinkRequired)
+	tokens, err := s.Application.ExternalTokens().LoadByProviderIDAndIdentityID(context.Background(), providerID, identity.ID)
+	require.Nil(s.T(), err)
+	assert.Empty(s.T(), tokens)
+	// the tenant is moved to the new cluster
+	events, err := s.Application.OutboxEvents().Query(s.Ctx, outbox.EventFilterByIdentityID(identity.ID), outbox.EventFilterByTarget(outbox.TargetTenant))
+	require.Nil(s.T(), err)
+	require.Len(s.T(), events, 1)
+	assert.Equal(s.T(), outbox.EventTypeUserClusterChanged, events[0].EventType)
+	var payload outbox.TenantClusterPayload
+	require.Nil(s.T(), events[0].DecodePayload(&payload))
+	assert.Equal(s.T(), previous.URL, payload.PreviousCluster)
+	assert.Equal(s.T(), cluster.URL, payload.Cluster)
+
+	// when the user is reassigned to the same cluster again
+	test.ReassignClusterUsersOK(s.T(), secureService.Context, secureService, secureController, identity.ID.String(), cluster.URL)
+
+	// then nothing changes
+	events, err = s.Application.OutboxEvents().Query(s.Ctx, outbox.EventFilterByIdentityID(identity.ID), outbox.EventFilterByTarget(outbox.TargetTenant))
+	require.Nil(s.T(), err)
+	assert.Len(s.T(), events, 1)
+}
+
+func (s *TestUsersSuite) TestReassignClusterBadRequest() {
+	// given
+	_, cluster := s.clusters()
+	identity := s.createRandomIdentity(s.createRandomUser("TestReassignClusterBadRequest"), account.KeycloakIDP)
+	secureService, secureController := s.SecuredServiceAccountController(testsupport.TestOnlineRegistrationAppIdentity)
+
+	// when/then
+	test.ReassignClusterUsersBadRequest(s.T(), secureService.Context, secureService, secureController, identity.ID.String(), "https://api.unknown.openshift.com")
+	test.ReassignClusterUsersBadRequest(s.T(), secureService.Context, secureService, secureController, "not-a-uuid", cluster.URL)
+}
+
+func (s *TestUsersSuite) TestReassignClusterNotFound() {
+	// given
+	_, cluster := s.clusters()
+	secureService, secureController := s.SecuredServiceAccountController(testsupport.TestOnlineRegistrationAppIdentity)
+
+	// when/then
+	test.ReassignClusterUsersNotFound(s.T(), secureService.Context, secureService, secureController, uuid.NewV4().String(), cluster.URL)
+}
+
+func (s *TestUsersSuite) TestReassignClusterUnauthorized() {
+	// given
+	_, cluster := s.clusters()
+	identity := s.createRandomIdentity(s.createRandomUser("TestReassignClusterUnauthorized"), account.KeycloakIDP)
+
+	// when/then
+	secureService, secureController := s.SecuredServiceAccountController(testsupport.TestIdentity)
+	test.ReassignClusterUsersUnauthorized(s.T(), secureService.Context, secureService, secureController, identity.ID.String(), cluster.URL)
+	secureService, secureController = s.SecuredController(identity)
+	test.ReassignClusterUsersUnauthorized(s.T(), secureService.Context, secureService, secureController, identity.ID.String(), cluster.URL)
+}
+
+// clusters registers the configured clusters and returns two of them
+func (s *TestUsersSuite) clusters() (configuration.OSOCluster, configuration.OSOCluster) {
+	s.SeedClusters()
+	var clusters []configuration.OSOCluster
+	for _, cluster := range s.Configuration.GetOSOClusters() {
+		clusters = append(clusters, cluster)
+	}
+	require.True(s.T(), len(clusters) > 1)
+	return clusters[0], clusters[1]
+}
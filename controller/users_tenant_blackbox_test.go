@@ -0,0 +1,196 @@
+package controller_test
+
+import (
+	"context"
+	"errors"
+
+	"github.com/fabric8-services/fabric8-auth/account"
+	"github.com/fabric8-services/fabric8-auth/app/test"
+	"github.com/fabric8-services/fabric8-auth/configuration"
+	. "github.com/fabric8-services/fabric8-auth/controller"
+	"github.com/fabric8-services/fabric8-auth/outbox"
+	testsupport "github.com/fabric8-services/fabric8-auth/test"
+	testtoken "github.com/fabric8-services/fabric8-auth/test/token"
+
+	"github.com/goadesign/goa"
+	"github.com/satori/go.uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func (s *TestUsersSuite) TestProvisionTenantAccepted() {
+	// given a user whose tenant could not be set up
+	identity := s.createRandomIdentity(s.createRandomUser("TestProvisionTenantAccepted"), account.KeycloakIDP)
+	err := s.Application.TenantProvisionings().RecordAttempt(context.Background(), identity.ID, account.TenantProvisioningStateFailed, nil)
+	require.Nil(s.T(), err)
+	secureService, secureController := s.securedTenantServiceAccountController(testsupport.TestOnlineRegistrationAppIdentity)
+
+	// when
+	_, provisioning := test.ProvisionTenantUsersAccepted(s.T(), secureService.Context, secureService, secureController, identity.ID.String())
+
+	// then the tenant is set up again with the service account token of the auth service
+	assert.Equal(s.T(), identity.ID.String(), provisioning.Data.ID)
+	assert.Equal(s.T(), account.TenantProvisioningStatePending, provisioning.Data.Attributes.State)
+	events, err := s.Application.OutboxEvents().Query(s.Ctx, outbox.EventFilterByIdentityID(identity.ID), outbox.EventFilterByTarget(outbox.TargetTenant))
+	require.Nil(s.T(), err)
+	require.Len(s.T(), events, 1)
+	assert.Equal(s.T(), outbox.EventTypeUserTenantRequested, events[0].EventType)
+
+	// when
+	_, provisioning = test.ShowTenantUsersOK(s.T(), secureService.Context, secureService, secureController, identity.ID.String())
+
+	// then
+	assert.Equal(s.T(), account.TenantProvisioningStatePending, provisioning.Data.Attributes.State)
+	assert.Equal(s.T(), 0, *provisioning.Data.Attributes.Attempts)
+
+	// when requested again while the tenant is being set up
+	test.ProvisionTenantUsersAccepted(s.T(), secureService.Context, secureService, secureController, identity.ID.String())
+
+	// then no other event is enqueued
+	events, err = s.Application.OutboxEvents().Query(s.Ctx, outbox.EventFilterByIdentityID(identity.ID), outbox.EventFilterByTarget(outbox.TargetTenant))
+	require.Nil(s.T(), err)
+	assert.Len(s.T(), events, 1)
+}
+
+func (s *TestUsersSuite) TestProvisionTenantForcedWhenReady() {
+	// given a user whose tenant is set up
+	identity := s.createRandomIdentity(s.createRandomUser("TestProvisionTenantForcedWhenReady"), account.KeycloakIDP)
+	err := s.Application.TenantProvisionings().RecordAttempt(context.Background(), identity.ID, account.TenantProvisioningStateReady, nil)
+	require.Nil(s.T(), err)
+	secureService, secureController := s.securedTenantServiceAccountController(testsupport.TestOnlineRegistrationAppIdentity)
+
+	// when
+	_, provisioning := test.ProvisionTenantUsersAccepted(s.T(), secureService.Context, secureService, secureController, identity.ID.String())
+
+	// then the tenant is set up again
+	assert.Equal(s.T(), account.TenantProvisioningStatePending, provisioning.Data.Attributes.State)
+	events, err := s.Application.OutboxEvents().Query(s.Ctx, outbox.EventFilterByIdentityID(identity.ID), outbox.EventFilterByTarget(outbox.TargetTenant))
+	require.Nil(s.T(), err)
+	assert.Len(s.T(), events, 1)
+}
+
+func (s *TestUsersSuite) TestProvisionTenantNotConfigured() {
+	// given
+	identity := s.createRandomIdentity(s.createRandomUser("TestProvisionTenantNotConfigured"), account.KeycloakIDP)
+	secureService, secureController := s.SecuredServiceAccountController(testsupport.TestOnlineRegistrationAppIdentity)
+
+	// when/then
+	test.ProvisionTenantUsersInternalServerError(s.T(), secureService.Context, secureService, secureController, identity.ID.String())
+}
+
+func (s *TestUsersSuite) TestProvisionTenantNotFound() {
+	// given
+	secureService, secureController := s.securedTenantServiceAccountController(testsupport.TestOnlineRegistrationAppIdentity)
+	identity := s.createRandomIdentity(s.createRandomUser("TestProvisionTenantNotFound"), account.KeycloakIDP)
+
+	// when/then
+	test.ProvisionTenantUsersNotFound(s.T(), secureService.Context, secureService, secureController, uuid.NewV4().String())
+	test.ShowTenantUsersNotFound(s.T(), secureService.Context, secureService, secureController, identity.ID.String())
+}
+
+func (s *TestUsersSuite) TestProvisionTenantUnauthorized() {
+	// given
+	identity := s.createRandomIdentity(s.createRandomUser("TestProvisionTenantUnauthorized"), account.KeycloakIDP)
+	secureService, secureController := s.SecuredController(identity)
+
+	// when/then
+	test.ProvisionTenantUsersUnauthorized(s.T(), secureService.Context, secureService, secureController, identity.ID.String())
+	test.ShowTenantUsersUnauthorized(s.T(), secureService.Context, secureService, secureController, identity.ID.String())
+}
+
+func (s *TestUsersSuite) TestShowTenantOfCurrentUserOK() {
+	// given
+	identity := s.createRandomIdentity(s.createRandomUser("TestShowTenantOfCurrentUserOK"), account.KeycloakIDP)
+	err := s.Application.TenantProvisionings().Request(context.Background(), identity.ID)
+	require.Nil(s.T(), err)
+	err = s.Application.TenantProvisionings().RecordAttempt(context.Background(), identity.ID, account.TenantProvisioningStateReady, nil)
+	require.Nil(s.T(), err)
+	svc := testsupport.ServiceAsUser("User-Service", identity)
+	ctrl := NewUserController(svc, s.Application, testtoken.TokenManager, s.Configuration)
+
+	// when
+	_, provisioning := test.ShowTenantUserOK(s.T(), svc.Context, svc, ctrl)
+
+	// then
+	assert.Equal(s.T(), identity.ID.String(), provisioning.Data.ID)
+	assert.Equal(s.T(), account.TenantProvisioningStateReady, provisioning.Data.Attributes.State)
+	assert.Equal(s.T(), 0, *provisioning.Data.Attributes.Attempts)
+	assert.Nil(s.T(), provisioning.Data.Attributes.Error)
+}
+
+func (s *TestUsersSuite) TestProvisionTenantOfCurrentUserAccepted() {
+	// given a user whose tenant could not be set up
+	identity := s.createRandomIdentity(s.createRandomUser("TestProvisionTenantOfCurrentUserAccepted"), account.KeycloakIDP)
+	err := s.Application.TenantProvisionings().RecordAttempt(context.Background(), identity.ID, account.TenantProvisioningStateFailed, errors.New("tenant service unavailable"))
+	require.Nil(s.T(), err)
+	svc := testsupport.ServiceAsUser("User-Service", identity)
+	ctrl := NewUserController(svc, s.Application, testtoken.TokenManager, tenantUserConfig{s.Configuration})
+
+	// when the user is shown
+	test.ShowUserOK(s.T(), svc.Context, svc, ctrl, nil, nil)
+
+	// then the tenant is not requested again
+	events, err := s.Application.OutboxEvents().Query(s.Ctx, outbox.EventFilterByIdentityID(identity.ID), outbox.EventFilterByTarget(outbox.TargetTenant))
+	require.Nil(s.T(), err)
+	assert.Empty(s.T(), events)
+
+	// when
+	_, provisioning := test.ProvisionTenantUserAccepted(s.T(), svc.Context, svc, ctrl)
+
+	// then
+	assert.Equal(s.T(), account.TenantProvisioningStatePending, provisioning.Data.Attributes.State)
+	events, err = s.Application.OutboxEvents().Query(s.Ctx, outbox.EventFilterByIdentityID(identity.ID), outbox.EventFilterByTarget(outbox.TargetTenant))
+	require.Nil(s.T(), err)
+	require.Len(s.T(), events, 1)
+
+	// when requested again while the tenant is being set up
+	_, provisioning = test.ProvisionTenantUserAccepted(s.T(), svc.Context, svc, ctrl)
+
+	// then no other event is enqueued
+	assert.Equal(s.T(), account.TenantProvisioningStatePending, provisioning.Data.Attributes.State)
+	events, err = s.Application.OutboxEvents().Query(s.Ctx, outbox.EventFilterByIdentityID(identity.ID), outbox.EventFilterByTarget(outbox.TargetTenant))
+	require.Nil(s.T(), err)
+	assert.Len(s.T(), events, 1)
+}
+
+func (s *TestUsersSuite) TestShowUserRequestsMissingTenant() {
+	// given a user created before the provisioning of the tenants was tracked
+	identity := s.createRandomIdentity(s.createRandomUser("TestShowUserRequestsMissingTenant"), account.KeycloakIDP)
+	svc := testsupport.ServiceAsUser("User-Service", identity)
+	ctrl := NewUserController(svc, s.Application, testtoken.TokenManager, tenantUserConfig{s.Configuration})
+
+	// when the user is shown
+	test.ShowUserOK(s.T(), svc.Context, svc, ctrl, nil, nil)
+
+	// then the tenant is requested
+	provisioning, err := s.Application.TenantProvisionings().Load(s.Ctx, identity.ID)
+	require.Nil(s.T(), err)
+	assert.Equal(s.T(), account.TenantProvisioningStatePending, provisioning.State)
+	events, err := s.Application.OutboxEvents().Query(s.Ctx, outbox.EventFilterByIdentityID(identity.ID), outbox.EventFilterByTarget(outbox.TargetTenant))
+	require.Nil(s.T(), err)
+	require.Len(s.T(), events, 1)
+
+	// when the user is shown again
+	test.ShowUserOK(s.T(), svc.Context, svc, ctrl, nil, nil)
+
+	// then the tenant is not requested again
+	events, err = s.Application.OutboxEvents().Query(s.Ctx, outbox.EventFilterByIdentityID(identity.ID), outbox.EventFilterByTarget(outbox.TargetTenant))
+	require.Nil(s.T(), err)
+	assert.Len(s.T(), events, 1)
+}
+
+// securedTenantServiceAccountController returns a users controller configured with a tenant service, secured as the given
+// service account
+func (s *TestUsersSuite) securedTenantServiceAccountController(identity account.Identity) (*goa.Service, *UsersController) {
+	svc := testsupport.ServiceAsServiceAccountUser("Users-ServiceAccount-Service", identity)
+	return svc, NewUsersController(s.svc, s.Application, tenantUserConfig{s.Configuration}, s.profileService, s.linkAPIService)
+}
+
+// tenantUserConfig is the configuration of the tests with a tenant service
+type tenantUserConfig struct {
+	*configuration.ConfigurationData
+}
+
+func (c tenantUserConfig) GetTenantServiceURL() string {
+	return "https://tenant.local"
+}
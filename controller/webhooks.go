@@ -0,0 +1,210 @@
+package controller
+
+import (
+	"context"
+	"net/url"
+
+	"github.com/fabric8-services/fabric8-auth/app"
+	"github.com/fabric8-services/fabric8-auth/application"
+	"github.com/fabric8-services/fabric8-auth/errors"
+	"github.com/fabric8-services/fabric8-auth/jsonapi"
+	"github.com/fabric8-services/fabric8-auth/log"
+	"github.com/fabric8-services/fabric8-auth/token"
+	"github.com/fabric8-services/fabric8-auth/webhook"
+
+	"github.com/goadesign/goa"
+	uuid "github.com/satori/go.uuid"
+)
+
+// WebhooksController implements the webhooks resource.
+type WebhooksController struct {
+	*goa.Controller
+	db     application.DB
+	config WebhooksControllerConfiguration
+}
+
+// WebhooksControllerConfiguration the Configuration for the WebhooksController
+type WebhooksControllerConfiguration interface {
+	IsWebhookPrivateTargetAllowed() bool
+}
+
+// NewWebhooksController creates a webhooks controller.
+func NewWebhooksController(service *goa.Service, db application.DB, config WebhooksControllerConfiguration) *WebhooksController {
+	return &WebhooksController{Controller: service.NewController("WebhooksController"), db: db, config: config}
+}
+
+// List lists the webhook subscriptions of the current service account
+func (c *WebhooksController) List(ctx *app.ListWebhooksContext) error {
+	serviceAccount, err := currentServiceAccount(ctx)
+	if err != nil {
+		return jsonapi.JSONErrorResponse(ctx, err)
+	}
+	subscriptions, err := c.db.WebhookSubscriptions().ListByServiceAccount(ctx, serviceAccount)
+	if err != nil {
+		return jsonapi.JSONErrorResponse(ctx, err)
+	}
+	res := &app.WebhookList{Data: []*app.Webhook{}}
+	for _, subscription := range subscriptions {
+		res.Data = append(res.Data, ConvertToAppWebhook(subscription, false))
+	}
+	return ctx.OK(res)
+}
+
+// Show shows a webhook subscription of the current service account
+func (c *WebhooksController) Show(ctx *app.ShowWebhooksContext) error {
+	subscription, err := loadOwnSubscription(ctx, c.db, ctx.SubscriptionID)
+	if err != nil {
+		return jsonapi.JSONErrorResponse(ctx, err)
+	}
+	return ctx.OK(&app.WebhookSingle{Data: ConvertToAppWebhook(*subscription, false)})
+}
+
+// Create subscribes the current service account to the events of the given types.
+// The URL can't target a loopback, private or link-local address, unless allowed by the configuration.
+// The secret of the signature of the events is only returned in the response.
+func (c *WebhooksController) Create(ctx *app.CreateWebhooksContext) error {
+	serviceAccount, err := currentServiceAccount(ctx)
+	if err != nil {
+		return jsonapi.JSONErrorResponse(ctx, err)
+	}
+	attributes := ctx.Payload.Data.Attributes
+	u, err := url.Parse(attributes.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return jsonapi.JSONErrorResponse(ctx, errors.NewBadParameterError("data.attributes.url", attributes.URL).Expected("an absolute http or https URL"))
+	}
+	if !c.config.IsWebhookPrivateTargetAllowed() {
+		if err := webhook.CheckURL(ctx, attributes.URL); err != nil {
+			log.Error(ctx, map[string]interface{}{
+				"url": attributes.URL,
+				"err": err,
+			}, "the webhook URL targets a non public address")
+			return jsonapi.JSONErrorResponse(ctx, errors.NewBadParameterError("data.attributes.url", attributes.URL).Expected("a URL of a public host"))
+		}
+	}
+	eventTypes := webhook.EventTypes{}
+	for _, eventType := range attributes.EventTypes {
+		if !webhook.IsSupported(eventType) {
+			return jsonapi.JSONErrorResponse(ctx, errors.NewBadParameterError("data.attributes.event-types", eventType).Expected(webhook.SupportedEventTypes))
+		}
+		if !eventTypes.Contains(eventType) {
+			eventTypes = append(eventTypes, eventType)
+		}
+	}
+	secret, err := webhook.NewSecret()
+	if err != nil {
+		return jsonapi.JSONErrorResponse(ctx, errors.NewInternalError(ctx, err))
+	}
+	subscription := webhook.Subscription{
+		ServiceAccount: serviceAccount,
+		URL:            attributes.URL,
+		EventTypes:     eventTypes,
+		Secret:         secret,
+	}
+	err = application.Transactional(c.db, func(appl application.Application) error {
+		return appl.WebhookSubscriptions().Create(ctx, &subscription)
+	})
+	if err != nil {
+		return jsonapi.JSONErrorResponse(ctx, err)
+	}
+	return ctx.Created(&app.WebhookSingle{Data: ConvertToAppWebhook(subscription, true)})
+}
+
+// Delete deletes a webhook subscription of the current service account
+func (c *WebhooksController) Delete(ctx *app.DeleteWebhooksContext) error {
+	err := application.Transactional(c.db, func(appl application.Application) error {
+		subscription, err := loadOwnSubscription(ctx, appl, ctx.SubscriptionID)
+		if err != nil {
+			return err
+		}
+		return appl.WebhookSubscriptions().Delete(ctx, subscription.ID)
+	})
+	if err != nil {
+		return jsonapi.JSONErrorResponse(ctx, err)
+	}
+	return ctx.OK([]byte{})
+}
+
+// Deliveries lists the most recent attempts to deliver the events to a webhook subscription of the current service account
+func (c *WebhooksController) Deliveries(ctx *app.DeliveriesWebhooksContext) error {
+	subscription, err := loadOwnSubscription(ctx, c.db, ctx.SubscriptionID)
+	if err != nil {
+		return jsonapi.JSONErrorResponse(ctx, err)
+	}
+	_, limit := computePagingLimits(nil, ctx.PageLimit)
+	deliveries, err := c.db.WebhookDeliveries().List(ctx, subscription.ID, limit)
+	if err != nil {
+		return jsonapi.JSONErrorResponse(ctx, err)
+	}
+	res := &app.WebhookDeliveryLogList{Data: []*app.WebhookDeliveryLog{}}
+	for _, delivery := range deliveries {
+		res.Data = append(res.Data, ConvertToAppWebhookDeliveryLog(delivery))
+	}
+	return ctx.OK(res)
+}
+
+// currentServiceAccount returns the name of the service account of the request,
+// or an UnauthorizedError if the request is not done by a service account
+func currentServiceAccount(ctx context.Context) (string, error) {
+	name, ok := token.ServiceAccountName(ctx)
+	if !ok || name == "" {
+		log.Error(ctx, nil, "The account is not a service account allowed to subscribe to the webhooks")
+		return "", errors.NewUnauthorizedError("account not authorized to subscribe to the webhooks.")
+	}
+	return name, nil
+}
+
+// loadOwnSubscription loads the given subscription of the current service account.
+// The subscriptions of the other service accounts are not found.
+func loadOwnSubscription(ctx context.Context, appl application.Application, id uuid.UUID) (*webhook.Subscription, error) {
+	serviceAccount, err := currentServiceAccount(ctx)
+	if err != nil {
+		return nil, err
+	}
+	subscription, err := appl.WebhookSubscriptions().Load(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if subscription.ServiceAccount != serviceAccount {
+		return nil, errors.NewNotFoundError("webhook_subscription", id.String())
+	}
+	return subscription, nil
+}
+
+// ConvertToAppWebhook converts the webhook subscription to the app representation.
+// The secret of the subscription is included if withSecret is true.
+func ConvertToAppWebhook(subscription webhook.Subscription, withSecret bool) *app.Webhook {
+	res := &app.Webhook{
+		Type: "webhooks",
+		ID:   subscription.ID.String(),
+		Attributes: &app.WebhookAttributes{
+			URL:        &subscription.URL,
+			EventTypes: subscription.EventTypes,
+			CreatedAt:  &subscription.CreatedAt,
+		},
+	}
+	if withSecret {
+		res.Attributes.Secret = &subscription.Secret
+	}
+	return res
+}
+
+// ConvertToAppWebhookDeliveryLog converts the attempt to deliver an event to a webhook subscription to the app representation
+func ConvertToAppWebhookDeliveryLog(delivery webhook.Delivery) *app.WebhookDeliveryLog {
+	duration := int(delivery.DurationMillis)
+	res := &app.WebhookDeliveryLog{
+		Type: "webhookdeliverylogs",
+		ID:   delivery.ID.String(),
+		Attributes: &app.WebhookDeliveryLogAttributes{
+			EventID:    &delivery.OutboxEventID,
+			EventType:  &delivery.EventType,
+			Attempt:    &delivery.Attempt,
+			StatusCode: &delivery.StatusCode,
+			DurationMs: &duration,
+			CreatedAt:  &delivery.CreatedAt,
+		},
+	}
+	if delivery.Error != "" {
+		res.Attributes.Error = &delivery.Error
+	}
+	return res
+}
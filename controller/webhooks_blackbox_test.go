@@ -0,0 +1,176 @@
+package controller_test
+
+import (
+	"testing"
+
+	"github.com/fabric8-services/fabric8-auth/account"
+	"github.com/fabric8-services/fabric8-auth/app"
+	"github.com/fabric8-services/fabric8-auth/app/test"
+	. "github.com/fabric8-services/fabric8-auth/controller"
+	"github.com/fabric8-services/fabric8-auth/gormtestsupport"
+	"github.com/fabric8-services/fabric8-auth/resource"
+	testsupport "github.com/fabric8-services/fabric8-auth/test"
+	"github.com/fabric8-services/fabric8-auth/webhook"
+
+	"github.com/goadesign/goa"
+	"github.com/satori/go.uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+)
+
+type TestWebhooksREST struct {
+	gormtestsupport.DBTestSuite
+}
+
+func TestRunWebhooksREST(t *testing.T) {
+	resource.Require(t, resource.Database)
+	suite.Run(t, &TestWebhooksREST{DBTestSuite: gormtestsupport.NewDBTestSuite()})
+}
+
+func (rest *TestWebhooksREST) ServiceAccountController(name string) (*goa.Service, *WebhooksController) {
+	svc := testsupport.ServiceAsServiceAccountUser("Webhooks-ServiceAccount-Service", account.Identity{ID: uuid.NewV4(), Username: name})
+	return svc, NewWebhooksController(svc, rest.Application, rest.Configuration)
+}
+
+func (rest *TestWebhooksREST) createPayload(url string, eventTypes ...string) *app.CreateWebhooksPayload {
+	return &app.CreateWebhooksPayload{
+		Data: &app.CreateWebhookData{
+			Type: "webhooks",
+			Attributes: &app.CreateWebhookAttributes{
+				URL:        url,
+				EventTypes: eventTypes,
+			},
+		},
+	}
+}
+
+func (rest *TestWebhooksREST) TestCreateShowAndListOK() {
+	// given
+	svc, ctrl := rest.ServiceAccountController("service-" + uuid.NewV4().String())
+	payload := rest.createPayload("https://example.com/hooks", webhook.EventTypeUserCreated, webhook.EventTypeIdentityLinked, webhook.EventTypeUserCreated)
+
+	// when
+	_, created := test.CreateWebhooksCreated(rest.T(), svc.Context, svc, ctrl, payload)
+
+	// then the secret is only returned at creation
+	require.NotNil(rest.T(), created.Data)
+	assert.Equal(rest.T(), "https://example.com/hooks", *created.Data.Attributes.URL)
+	assert.Equal(rest.T(), []string{webhook.EventTypeUserCreated, webhook.EventTypeIdentityLinked}, created.Data.Attributes.EventTypes)
+	require.NotNil(rest.T(), created.Data.Attributes.Secret)
+	assert.Len(rest.T(), *created.Data.Attributes.Secret, 64)
+	subscriptionID, err := uuid.FromString(created.Data.ID)
+	require.Nil(rest.T(), err)
+
+	// when
+	_, shown := test.ShowWebhooksOK(rest.T(), svc.Context, svc, ctrl, subscriptionID)
+
+	// then
+	assert.Equal(rest.T(), created.Data.ID, shown.Data.ID)
+	assert.Nil(rest.T(), shown.Data.Attributes.Secret)
+
+	// when
+	_, list := test.ListWebhooksOK(rest.T(), svc.Context, svc, ctrl)
+
+	// then
+	require.Len(rest.T(), list.Data, 1)
+	assert.Equal(rest.T(), created.Data.ID, list.Data[0].ID)
+	assert.Nil(rest.T(), list.Data[0].Attributes.Secret)
+}
+
+func (rest *TestWebhooksREST) TestCreateBadRequest() {
+	// given
+	svc, ctrl := rest.ServiceAccountController("service-" + uuid.NewV4().String())
+
+	// when/then
+	test.CreateWebhooksBadRequest(rest.T(), svc.Context, svc, ctrl, rest.createPayload("/hooks", webhook.EventTypeUserCreated))
+	test.CreateWebhooksBadRequest(rest.T(), svc.Context, svc, ctrl, rest.createPayload("ftp://example.com/hooks", webhook.EventTypeUserCreated))
+	test.CreateWebhooksBadRequest(rest.T(), svc.Context, svc, ctrl, rest.createPayload("https://example.com/hooks", "user.deleted"))
+}
+
+func (rest *TestWebhooksREST) TestCreateWithNonPublicURLBadRequest() {
+	// given
+	svc, ctrl := rest.ServiceAccountController("service-" + uuid.NewV4().String())
+
+	// when/then
+	test.CreateWebhooksBadRequest(rest.T(), svc.Context, svc, ctrl, rest.createPayload("http://127.0.0.1:8089/hooks", webhook.EventTypeUserCreated))
+	test.CreateWebhooksBadRequest(rest.T(), svc.Context, svc, ctrl, rest.createPayload("http://localhost/hooks", webhook.EventTypeUserCreated))
+	test.CreateWebhooksBadRequest(rest.T(), svc.Context, svc, ctrl, rest.createPayload("http://10.1.2.3/hooks", webhook.EventTypeUserCreated))
+	test.CreateWebhooksBadRequest(rest.T(), svc.Context, svc, ctrl, rest.createPayload("http://169.254.169.254/latest/meta-data", webhook.EventTypeUserCreated))
+	test.CreateWebhooksBadRequest(rest.T(), svc.Context, svc, ctrl, rest.createPayload("http://[::1]/hooks", webhook.EventTypeUserCreated))
+}
+
+func (rest *TestWebhooksREST) TestSubscriptionsOfOtherServiceAccountsNotFound() {
+	// given
+	svc, ctrl := rest.ServiceAccountController("service-" + uuid.NewV4().String())
+	_, created := test.CreateWebhooksCreated(rest.T(), svc.Context, svc, ctrl, rest.createPayload("https://example.com/hooks", webhook.EventTypeSpaceDeleted))
+	subscriptionID, err := uuid.FromString(created.Data.ID)
+	require.Nil(rest.T(), err)
+	otherSvc, otherCtrl := rest.ServiceAccountController("other-" + uuid.NewV4().String())
+
+	// when/then
+	test.ShowWebhooksNotFound(rest.T(), otherSvc.Context, otherSvc, otherCtrl, subscriptionID)
+	test.DeliveriesWebhooksNotFound(rest.T(), otherSvc.Context, otherSvc, otherCtrl, subscriptionID, nil)
+	test.DeleteWebhooksNotFound(rest.T(), otherSvc.Context, otherSvc, otherCtrl, subscriptionID)
+	_, list := test.ListWebhooksOK(rest.T(), otherSvc.Context, otherSvc, otherCtrl)
+	assert.Empty(rest.T(), list.Data)
+}
+
+func (rest *TestWebhooksREST) TestDeleteOK() {
+	// given
+	svc, ctrl := rest.ServiceAccountController("service-" + uuid.NewV4().String())
+	_, created := test.CreateWebhooksCreated(rest.T(), svc.Context, svc, ctrl, rest.createPayload("https://example.com/hooks", webhook.EventTypeUserUpdated))
+	subscriptionID, err := uuid.FromString(created.Data.ID)
+	require.Nil(rest.T(), err)
+
+	// when
+	test.DeleteWebhooksOK(rest.T(), svc.Context, svc, ctrl, subscriptionID)
+
+	// then
+	test.ShowWebhooksNotFound(rest.T(), svc.Context, svc, ctrl, subscriptionID)
+}
+
+func (rest *TestWebhooksREST) TestDeliveriesOK() {
+	// given
+	svc, ctrl := rest.ServiceAccountController("service-" + uuid.NewV4().String())
+	_, created := test.CreateWebhooksCreated(rest.T(), svc.Context, svc, ctrl, rest.createPayload("https://example.com/hooks", webhook.EventTypeUserCreated))
+	subscriptionID, err := uuid.FromString(created.Data.ID)
+	require.Nil(rest.T(), err)
+	eventID := uuid.NewV4()
+	for attempt := 1; attempt <= 2; attempt++ {
+		delivery := webhook.Delivery{
+			SubscriptionID: subscriptionID,
+			OutboxEventID:  eventID,
+			EventType:      webhook.EventTypeUserCreated,
+			Attempt:        attempt,
+			StatusCode:     204,
+		}
+		if attempt == 1 {
+			delivery.StatusCode = 503
+			delivery.Error = "the webhook URL responded with the status 503"
+		}
+		require.Nil(rest.T(), rest.Application.WebhookDeliveries().Create(rest.Ctx, &delivery))
+	}
+	limit := 1
+
+	// when
+	_, deliveries := test.DeliveriesWebhooksOK(rest.T(), svc.Context, svc, ctrl, subscriptionID, &limit)
+
+	// then only the most recent attempt is returned
+	require.Len(rest.T(), deliveries.Data, 1)
+	assert.Equal(rest.T(), 2, *deliveries.Data[0].Attributes.Attempt)
+	assert.Equal(rest.T(), eventID, *deliveries.Data[0].Attributes.EventID)
+	assert.Equal(rest.T(), 204, *deliveries.Data[0].Attributes.StatusCode)
+	assert.Nil(rest.T(), deliveries.Data[0].Attributes.Error)
+}
+
+func (rest *TestWebhooksREST) TestWebhooksUnauthorizedForUsers() {
+	// given
+	svc := testsupport.ServiceAsUser("Webhooks-Service", testsupport.TestIdentity)
+	ctrl := NewWebhooksController(svc, rest.Application, rest.Configuration)
+
+	// when/then
+	test.ListWebhooksUnauthorized(rest.T(), svc.Context, svc, ctrl)
+	test.CreateWebhooksUnauthorized(rest.T(), svc.Context, svc, ctrl, rest.createPayload("https://example.com/hooks", webhook.EventTypeUserCreated))
+	test.ShowWebhooksUnauthorized(rest.T(), svc.Context, svc, ctrl, uuid.NewV4())
+}
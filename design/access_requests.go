@@ -0,0 +1,143 @@
+package design
+
+import (
+	d "github.com/goadesign/goa/design"
+	a "github.com/goadesign/goa/design/apidsl"
+)
+
+var accessRequestAttributes = a.Type("AccessRequestAttributes", func() {
+	a.Attribute("spaceID", d.UUID, "The ID of the space the user asks to collaborate on")
+	a.Attribute("requesterID", d.UUID, "The identity ID of the user who asks to collaborate on the space")
+	a.Attribute("message", d.String, "The message of the user to the space collaborators")
+	a.Attribute("state", d.String, "The state of the request", func() {
+		a.Enum("pending", "approved", "denied")
+	})
+	a.Attribute("decidedBy", d.UUID, "The identity ID of the space collaborator who approved or denied the request")
+	a.Attribute("decided-at", d.DateTime, "The date of the approval or denial of the request")
+	a.Attribute("created-at", d.DateTime, "The date of creation of the request")
+})
+
+var accessRequestData = JSONResourceObject("AccessRequest", accessRequestAttributes, nil)
+
+var accessRequestSingle = JSONSingle(
+	"AccessRequest", "Holds a single space access request",
+	accessRequestData,
+	nil)
+
+var accessRequestList = JSONList(
+	"AccessRequest", "Holds the list of space access requests",
+	accessRequestData,
+	nil,
+	nil)
+
+// createAccessRequestPayload is the payload to ask to collaborate on a space
+var createAccessRequestPayload = a.Type("CreateAccessRequestPayload", func() {
+	a.Attribute("data", createAccessRequestData)
+	a.Required("data")
+})
+
+var createAccessRequestData = a.Type("CreateAccessRequestData", func() {
+	a.Attribute("type", d.String, func() {
+		a.Enum("accessrequests")
+	})
+	a.Attribute("attributes", createAccessRequestAttributes)
+	a.Required("type", "attributes")
+})
+
+var createAccessRequestAttributes = a.Type("CreateAccessRequestAttributes", func() {
+	a.Attribute("message", d.String, "The message of the user to the space collaborators", func() {
+		a.MaxLength(1000)
+	})
+})
+
+var _ = a.Resource("access_requests", func() {
+	a.BasePath("/spaces")
+
+	a.Action("create", func() {
+		a.Security("jwt")
+		a.Routing(
+			a.POST("/:spaceID/access-requests"),
+		)
+		a.Description("Ask to collaborate on the space on behalf of the current user")
+		a.Params(func() {
+			a.Param("spaceID", d.UUID, "ID of the space")
+		})
+		a.Payload(createAccessRequestPayload)
+		a.Response(d.Created, accessRequestSingle)
+		a.Response(d.NotFound, JSONAPIErrors)
+		a.Response(d.BadRequest, JSONAPIErrors)
+		a.Response(d.InternalServerError, JSONAPIErrors)
+		a.Response(d.Unauthorized, JSONAPIErrors)
+	})
+
+	a.Action("list", func() {
+		a.Security("jwt")
+		a.Routing(
+			a.GET("/:spaceID/access-requests"),
+		)
+		a.Description("List the requests to access the space. Only the space collaborators can list the requests")
+		a.Params(func() {
+			a.Param("spaceID", d.UUID, "ID of the space")
+			a.Param("state", d.String, "The state of the requests to list", func() {
+				a.Enum("pending", "approved", "denied")
+				a.Default("pending")
+			})
+		})
+		a.Response(d.OK, accessRequestList)
+		a.Response(d.NotFound, JSONAPIErrors)
+		a.Response(d.BadRequest, JSONAPIErrors)
+		a.Response(d.InternalServerError, JSONAPIErrors)
+		a.Response(d.Unauthorized, JSONAPIErrors)
+	})
+
+	a.Action("show", func() {
+		a.Security("jwt")
+		a.Routing(
+			a.GET("/:spaceID/access-requests/:requestID"),
+		)
+		a.Description("Get the request to access the space with the given ID. Only the requester and the space collaborators can get the request")
+		a.Params(func() {
+			a.Param("spaceID", d.UUID, "ID of the space")
+			a.Param("requestID", d.UUID, "ID of the access request")
+		})
+		a.Response(d.OK, accessRequestSingle)
+		a.Response(d.NotFound, JSONAPIErrors)
+		a.Response(d.BadRequest, JSONAPIErrors)
+		a.Response(d.InternalServerError, JSONAPIErrors)
+		a.Response(d.Unauthorized, JSONAPIErrors)
+	})
+
+	a.Action("approve", func() {
+		a.Security("jwt")
+		a.Routing(
+			a.POST("/:spaceID/access-requests/:requestID/approve"),
+		)
+		a.Description("Approve a pending request to access the space. The requester is added to the space collaborators")
+		a.Params(func() {
+			a.Param("spaceID", d.UUID, "ID of the space")
+			a.Param("requestID", d.UUID, "ID of the access request")
+		})
+		a.Response(d.OK, accessRequestSingle)
+		a.Response(d.NotFound, JSONAPIErrors)
+		a.Response(d.BadRequest, JSONAPIErrors)
+		a.Response(d.InternalServerError, JSONAPIErrors)
+		a.Response(d.Unauthorized, JSONAPIErrors)
+	})
+
+	a.Action("deny", func() {
+		a.Security("jwt")
+		a.Routing(
+			a.POST("/:spaceID/access-requests/:requestID/deny"),
+		)
+		a.Description("Deny a pending request to access the space")
+		a.Params(func() {
+			a.Param("spaceID", d.UUID, "ID of the space")
+			a.Param("requestID", d.UUID, "ID of the access request")
+		})
+		a.Response(d.OK, accessRequestSingle)
+		a.Response(d.NotFound, JSONAPIErrors)
+		a.Response(d.BadRequest, JSONAPIErrors)
+		a.Response(d.InternalServerError, JSONAPIErrors)
+		a.Response(d.Unauthorized, JSONAPIErrors)
+	})
+})
@@ -101,7 +101,7 @@ var _ = a.Resource("user", func() {
 		a.Routing(
 			a.GET(""),
 		)
-		a.Description("Get the authenticated user")
+		a.Description("Get the authenticated user. The provisioning of the tenant of the user is requested if it was never requested. The ETag is computed from the modification time of the user with a microsecond precision, so the ETags returned before this precision was introduced don't match anymore.")
 		a.UseTrait("conditional")
 		a.Response(d.OK, user)
 		a.Response(d.NotModified)
@@ -109,6 +109,31 @@ var _ = a.Resource("user", func() {
 		a.Response(d.InternalServerError, JSONAPIErrors)
 		a.Response(d.Unauthorized, JSONAPIErrors)
 	})
+
+	a.Action("showTenant", func() {
+		a.Security("jwt")
+		a.Routing(
+			a.GET("/tenant"),
+		)
+		a.Description("Get the state of the provisioning of the tenant of the authenticated user")
+		a.Response(d.OK, tenantProvisioningSingle)
+		a.Response(d.NotFound, JSONAPIErrors)
+		a.Response(d.BadRequest, JSONAPIErrors)
+		a.Response(d.InternalServerError, JSONAPIErrors)
+		a.Response(d.Unauthorized, JSONAPIErrors)
+	})
+
+	a.Action("provisionTenant", func() {
+		a.Security("jwt")
+		a.Routing(
+			a.POST("/tenant"),
+		)
+		a.Description("Set up the tenant of the authenticated user unless it is already set up or being set up, e.g. after the provisioning failed. The tenant is set up in the background.")
+		a.Response(d.Accepted, tenantProvisioningSingle)
+		a.Response(d.BadRequest, JSONAPIErrors)
+		a.Response(d.InternalServerError, JSONAPIErrors)
+		a.Response(d.Unauthorized, JSONAPIErrors)
+	})
 })
 
 var _ = a.Resource("users", func() {
@@ -118,7 +143,7 @@ var _ = a.Resource("users", func() {
 		a.Routing(
 			a.GET("/:id"),
 		)
-		a.Description("Retrieve user for the given ID.")
+		a.Description("Retrieve user for the given ID. The ETag is computed from the modification time of the user with a microsecond precision, so the ETags returned before this precision was introduced don't match anymore.")
 		a.Params(func() {
 			a.Param("id", d.String, "id")
 		})
@@ -151,7 +176,8 @@ var _ = a.Resource("users", func() {
 		a.Routing(
 			a.PATCH(""),
 		)
-		a.Description("update the authenticated user")
+		a.Description("update the authenticated user. The update fails with a '412 Precondition Failed' response if the user was modified since the 'If-Match' ETag or the 'If-Unmodified-Since' date. The ETag is computed from the modification time of the user with a microsecond precision, so the ETags returned before this precision was introduced don't match anymore.")
+		a.UseTrait("conditional-write")
 		a.Payload(updateUser)
 		a.Response(d.OK, func() {
 			a.Media(user)
@@ -162,7 +188,60 @@ var _ = a.Resource("users", func() {
 		a.Response(d.Unauthorized, JSONAPIErrors)
 		a.Response(d.Forbidden, JSONAPIErrors)
 		a.Response(d.Conflict, JSONAPIErrors)
+		a.Response(d.PreconditionFailed, JSONAPIErrors)
+
+	})
+
+	a.Action("reassign-cluster", func() {
+		a.Security("jwt")
+		a.Routing(
+			a.POST("/:id/cluster"),
+		)
+		a.Description("Reassign the user to another cluster using a service account. The OpenShift account of the user on the previous cluster is unlinked and the tenant service is notified.")
+		a.Params(func() {
+			a.Param("id", d.String, "id")
+			a.Param("cluster", d.String, "The API URL of the cluster the user is reassigned to")
+			a.Required("cluster")
+		})
+		a.Response(d.OK, func() {
+			a.Media(user)
+		})
+		a.Response(d.BadRequest, JSONAPIErrors)
+		a.Response(d.InternalServerError, JSONAPIErrors)
+		a.Response(d.NotFound, JSONAPIErrors)
+		a.Response(d.Unauthorized, JSONAPIErrors)
+	})
 
+	a.Action("showTenant", func() {
+		a.Security("jwt")
+		a.Routing(
+			a.GET("/:id/tenant"),
+		)
+		a.Description("Get the state of the provisioning of the tenant of the user using a service account")
+		a.Params(func() {
+			a.Param("id", d.String, "id")
+		})
+		a.Response(d.OK, tenantProvisioningSingle)
+		a.Response(d.BadRequest, JSONAPIErrors)
+		a.Response(d.InternalServerError, JSONAPIErrors)
+		a.Response(d.NotFound, JSONAPIErrors)
+		a.Response(d.Unauthorized, JSONAPIErrors)
+	})
+
+	a.Action("provisionTenant", func() {
+		a.Security("jwt")
+		a.Routing(
+			a.POST("/:id/tenant"),
+		)
+		a.Description("Set up the tenant of the user again using a service account, even if it is already set up, unless it is being set up. The tenant is set up in the background and the provisioning is pending until then.")
+		a.Params(func() {
+			a.Param("id", d.String, "id")
+		})
+		a.Response(d.Accepted, tenantProvisioningSingle)
+		a.Response(d.BadRequest, JSONAPIErrors)
+		a.Response(d.InternalServerError, JSONAPIErrors)
+		a.Response(d.NotFound, JSONAPIErrors)
+		a.Response(d.Unauthorized, JSONAPIErrors)
 	})
 
 	a.Action("list", func() {
@@ -183,6 +262,25 @@ var _ = a.Resource("users", func() {
 	})
 })
 
+// tenantProvisioningAttributes represents the state of the provisioning of the tenant of a user
+var tenantProvisioningAttributes = a.Type("TenantProvisioningAttributes", func() {
+	a.Attribute("state", d.String, "The state of the provisioning", func() {
+		a.Enum("pending", "in_progress", "ready", "failed")
+	})
+	a.Attribute("error", d.String, "The error of the last failed attempt to set up the tenant")
+	a.Attribute("attempts", d.Integer, "The number of failed attempts to set up the tenant since it was last requested")
+	a.Attribute("created-at", d.DateTime, "The date the provisioning was first requested")
+	a.Attribute("updated-at", d.DateTime, "The date of the last change of state")
+	a.Required("state")
+})
+
+var tenantProvisioningData = JSONResourceObject("TenantProvisioning", tenantProvisioningAttributes, nil)
+
+var tenantProvisioningSingle = JSONSingle(
+	"TenantProvisioning", "Holds the state of the provisioning of the tenant of a user",
+	tenantProvisioningData,
+	nil)
+
 // userData represents an identified user object
 var userData = a.Type("UserData", func() {
 	a.Attribute("id", d.String, "unique id for the user")
@@ -258,11 +356,12 @@ var createUserDataAttributes = a.Type("CreateIdentityDataAttributes", func() {
 	a.Attribute("bio", d.String, "The bio")
 	a.Attribute("url", d.String, "The url")
 	a.Attribute("company", d.String, "The company")
-	a.Attribute("cluster", d.String, "The OpenShift API URL of the cluster where the user is provisioned to")
+	a.Attribute("cluster", d.String, "The OpenShift API URL of the cluster where the user is provisioned to. Chosen by the cluster placement strategy if not set")
+	a.Attribute("region", d.String, "The region of the cluster the user is provisioned to if no cluster is set. Used by the 'per-region' cluster placement strategy")
 	a.Attribute("providerType", d.String, "The IDP provided this identity")
 	a.Attribute("contextInformation", a.HashOf(d.String, d.Any), "User context information of any type as a json", func() {
 		a.Example(map[string]interface{}{"last_visited_url": "https://a.openshift.io", "space": "3d6dab8d-f204-42e8-ab29-cdb1c93130ad"})
 	})
 	// Based on the request from online-registration app.
-	a.Required("username", "email", "cluster")
+	a.Required("username", "email")
 })
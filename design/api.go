@@ -27,7 +27,7 @@ var _ = a.API("auth", func() {
 	})
 	a.Origin("/[.*openshift.io|localhost]/", func() {
 		a.Methods("GET", "POST", "PUT", "PATCH", "DELETE")
-		a.Headers("X-Request-Id", "Content-Type", "Authorization", "If-None-Match", "If-Modified-Since")
+		a.Headers("X-Request-Id", "Content-Type", "Authorization", "If-None-Match", "If-Modified-Since", "If-Match", "If-Unmodified-Since")
 		a.MaxAge(600)
 		a.Credentials()
 	})
@@ -49,6 +49,13 @@ var _ = a.API("auth", func() {
 		})
 	})
 
+	a.Trait("conditional-write", func() {
+		a.Headers(func() {
+			a.Header("If-Unmodified-Since", d.String)
+			a.Header("If-Match", d.String)
+		})
+	})
+
 	a.JWTSecurity("jwt", func() {
 		a.Description("JWT Token Auth")
 		a.TokenURL("/api/login")
@@ -0,0 +1,161 @@
+package design
+
+import (
+	d "github.com/goadesign/goa/design"
+	a "github.com/goadesign/goa/design/apidsl"
+)
+
+var clusterAttributes = a.Type("ClusterAttributes", func() {
+	a.Attribute("name", d.String, "The name of the cluster")
+	a.Attribute("api-url", d.String, "The API URL of the cluster")
+	a.Attribute("console-url", d.String, "The URL of the web console of the cluster")
+	a.Attribute("region", d.String, "The region the cluster is located in")
+	a.Attribute("capacity", d.Integer, "The max number of users provisioned to the cluster. Unlimited if zero")
+	a.Attribute("token-provider-id", d.UUID, "The ID of the provider of the tokens of the linked OpenShift accounts. Only returned to the administrators")
+	a.Attribute("auth-client-id", d.String, "The ID of the OAuth client used to link the OpenShift accounts. Only returned to the administrators")
+	a.Attribute("auth-client-default-scope", d.String, "The scope requested when linking the OpenShift accounts. Only returned to the administrators")
+	a.Attribute("created-at", d.DateTime, "The date the cluster was registered")
+	a.Attribute("updated-at", d.DateTime, "The date the cluster was last updated")
+})
+
+var clusterData = JSONResourceObject("Cluster", clusterAttributes, nil)
+
+var clusterSingle = JSONSingle(
+	"Cluster", "Holds a single cluster",
+	clusterData,
+	nil)
+
+var clusterList = JSONList(
+	"Cluster", "Holds the list of the registered clusters",
+	clusterData,
+	nil,
+	nil)
+
+// clusterPayloadAttributes are the attributes of a cluster which can be set by the administrators.
+// The secrets can be set but are never returned. Only references to the secrets are accepted, the secrets themselves are not stored.
+var clusterPayloadAttributes = func() {
+	a.Attribute("name", d.String, "The name of the cluster")
+	a.Attribute("api-url", d.String, "The API URL of the cluster")
+	a.Attribute("console-url", d.String, "The URL of the web console of the cluster")
+	a.Attribute("region", d.String, "The region the cluster is located in")
+	a.Attribute("capacity", d.Integer, "The max number of users provisioned to the cluster. Unlimited if zero", func() {
+		a.Minimum(0)
+	})
+	a.Attribute("service-account-token", d.String, "The reference to the token of the service account of the auth service on the cluster, like vault://path#key or file:///path")
+	a.Attribute("token-provider-id", d.UUID, "The ID of the provider of the tokens of the linked OpenShift accounts")
+	a.Attribute("auth-client-id", d.String, "The ID of the OAuth client used to link the OpenShift accounts")
+	a.Attribute("auth-client-secret", d.String, "The reference to the secret of the OAuth client used to link the OpenShift accounts, like vault://path#key or file:///path")
+	a.Attribute("auth-client-default-scope", d.String, "The scope requested when linking the OpenShift accounts")
+}
+
+// createClusterPayload is the payload to register a cluster
+var createClusterPayload = a.Type("CreateClusterPayload", func() {
+	a.Attribute("data", createClusterData)
+	a.Required("data")
+})
+
+var createClusterData = a.Type("CreateClusterData", func() {
+	a.Attribute("type", d.String, func() {
+		a.Enum("clusters")
+	})
+	a.Attribute("attributes", createClusterAttributes)
+	a.Required("type", "attributes")
+})
+
+var createClusterAttributes = a.Type("CreateClusterAttributes", func() {
+	clusterPayloadAttributes()
+	a.Required("name", "api-url", "token-provider-id", "auth-client-id", "auth-client-secret", "auth-client-default-scope")
+})
+
+// updateClusterPayload is the payload to update a registered cluster. Only the given attributes are updated.
+var updateClusterPayload = a.Type("UpdateClusterPayload", func() {
+	a.Attribute("data", updateClusterData)
+	a.Required("data")
+})
+
+var updateClusterData = a.Type("UpdateClusterData", func() {
+	a.Attribute("type", d.String, func() {
+		a.Enum("clusters")
+	})
+	a.Attribute("attributes", updateClusterAttributes)
+	a.Required("type", "attributes")
+})
+
+var updateClusterAttributes = a.Type("UpdateClusterAttributes", func() {
+	clusterPayloadAttributes()
+})
+
+var _ = a.Resource("clusters", func() {
+	a.BasePath("/clusters")
+
+	a.Action("list", func() {
+		a.Routing(
+			a.GET(""),
+		)
+		a.Description("List the registered clusters")
+		a.Response(d.OK, clusterList)
+		a.Response(d.InternalServerError, JSONAPIErrors)
+	})
+
+	a.Action("show", func() {
+		a.Security("jwt")
+		a.Routing(
+			a.GET("/:clusterID"),
+		)
+		a.Description("Show the registered cluster, including its OAuth client, using a service account")
+		a.Params(func() {
+			a.Param("clusterID", d.UUID, "ID of the cluster")
+		})
+		a.Response(d.OK, clusterSingle)
+		a.Response(d.NotFound, JSONAPIErrors)
+		a.Response(d.BadRequest, JSONAPIErrors)
+		a.Response(d.InternalServerError, JSONAPIErrors)
+		a.Response(d.Unauthorized, JSONAPIErrors)
+	})
+
+	a.Action("create", func() {
+		a.Security("jwt")
+		a.Routing(
+			a.POST(""),
+		)
+		a.Description("Register a cluster using a service account")
+		a.Payload(createClusterPayload)
+		a.Response(d.Created, clusterSingle)
+		a.Response(d.BadRequest, JSONAPIErrors)
+		a.Response(d.InternalServerError, JSONAPIErrors)
+		a.Response(d.Unauthorized, JSONAPIErrors)
+	})
+
+	a.Action("update", func() {
+		a.Security("jwt")
+		a.Routing(
+			a.PATCH("/:clusterID"),
+		)
+		a.Description("Update the registered cluster using a service account. The API URL can't be changed while users are provisioned to the cluster")
+		a.Params(func() {
+			a.Param("clusterID", d.UUID, "ID of the cluster")
+		})
+		a.Payload(updateClusterPayload)
+		a.Response(d.OK, clusterSingle)
+		a.Response(d.NotFound, JSONAPIErrors)
+		a.Response(d.BadRequest, JSONAPIErrors)
+		a.Response(d.InternalServerError, JSONAPIErrors)
+		a.Response(d.Unauthorized, JSONAPIErrors)
+	})
+
+	a.Action("delete", func() {
+		a.Security("jwt")
+		a.Routing(
+			a.DELETE("/:clusterID"),
+		)
+		a.Description("Remove the cluster from the registry using a service account. The cluster can't be removed while users are provisioned to it")
+		a.Params(func() {
+			a.Param("clusterID", d.UUID, "ID of the cluster")
+		})
+		a.Response(d.OK)
+		a.Response(d.NotFound, JSONAPIErrors)
+		a.Response(d.BadRequest, JSONAPIErrors)
+		a.Response(d.InternalServerError, JSONAPIErrors)
+		a.Response(d.Unauthorized, JSONAPIErrors)
+	})
+})
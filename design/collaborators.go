@@ -34,8 +34,9 @@ var _ = a.Resource("collaborators", func() {
 		a.Description("Add users to the list of space collaborators.")
 		a.Params(func() {
 			a.Param("spaceID", d.UUID, "ID of the space")
+			collaboratorExpiryParam()
 		})
-		a.Response(d.OK)
+		a.Response(d.OK, collaboratorsUpdate)
 		a.Payload(updateUserIDList)
 		a.Response(d.NotFound, JSONAPIErrors)
 		a.Response(d.BadRequest, JSONAPIErrors)
@@ -51,6 +52,7 @@ var _ = a.Resource("collaborators", func() {
 		a.Description("Add a user to the list of space collaborators.")
 		a.Params(func() {
 			a.Param("spaceID", d.UUID, "ID of the space")
+			collaboratorExpiryParam()
 		})
 		a.Response(d.OK)
 		a.Response(d.NotFound, JSONAPIErrors)
@@ -68,7 +70,7 @@ var _ = a.Resource("collaborators", func() {
 		a.Params(func() {
 			a.Param("spaceID", d.UUID, "ID of the space")
 		})
-		a.Response(d.OK)
+		a.Response(d.OK, collaboratorsUpdate)
 		a.Payload(updateUserIDList)
 		a.Response(d.NotFound, JSONAPIErrors)
 		a.Response(d.BadRequest, JSONAPIErrors)
@@ -91,8 +93,138 @@ var _ = a.Resource("collaborators", func() {
 		a.Response(d.InternalServerError, JSONAPIErrors)
 		a.Response(d.Unauthorized, JSONAPIErrors)
 	})
+
+	a.Action("extend", func() {
+		a.Security("jwt")
+		a.Routing(
+			a.POST("/:spaceID/collaborators/:identityID/extend"),
+		)
+		a.Description("Extend the access of a time-bound space collaborator.")
+		a.Params(func() {
+			a.Param("spaceID", d.UUID, "ID of the space")
+			a.Param("identityID", d.UUID, "ID of the user identity")
+			a.Param("expires_at", d.DateTime, "The new date the access of the collaborator expires at")
+			a.Required("expires_at")
+		})
+		a.Response(d.OK)
+		a.Response(d.NotFound, JSONAPIErrors)
+		a.Response(d.BadRequest, JSONAPIErrors)
+		a.Response(d.InternalServerError, JSONAPIErrors)
+		a.Response(d.Unauthorized, JSONAPIErrors)
+	})
+
+	a.Action("history", func() {
+		a.Security("jwt")
+		a.Routing(
+			a.GET("/:spaceID/collaborators/history"),
+		)
+		a.Description("List the changes of the collaborators of the given space ID, the most recent first.")
+		a.Params(func() {
+			a.Param("spaceID", d.UUID, "ID of the space")
+			a.Param("page[offset]", d.String, "Paging start position")
+			a.Param("page[limit]", d.Integer, "Paging size")
+		})
+		a.Response(d.OK, collaboratorEventList)
+		a.Response(d.NotFound, JSONAPIErrors)
+		a.Response(d.BadRequest, JSONAPIErrors)
+		a.Response(d.InternalServerError, JSONAPIErrors)
+		a.Response(d.Unauthorized, JSONAPIErrors)
+	})
+
+	a.Action("list-teams", func() {
+		a.Routing(
+			a.GET("/:spaceID/collaborators/teams"),
+		)
+		a.Description("List the teams collaborating on the given space ID.")
+		a.Params(func() {
+			a.Param("spaceID", d.UUID, "ID of the space")
+		})
+		a.Response(d.OK, teamList)
+		a.Response(d.NotFound, JSONAPIErrors)
+		a.Response(d.BadRequest, JSONAPIErrors)
+		a.Response(d.InternalServerError, JSONAPIErrors)
+	})
+
+	a.Action("add-team", func() {
+		a.Security("jwt")
+		a.Routing(
+			a.POST("/:spaceID/collaborators/teams/:teamID"),
+		)
+		a.Description(`Add a team to the space collaborators. The members of the team are given access to the space
+and are added to the Keycloak policy of the space asynchronously, along with the members who join the team later.`)
+		a.Params(func() {
+			a.Param("spaceID", d.UUID, "ID of the space")
+			a.Param("teamID", d.String, "ID of the team")
+		})
+		a.Response(d.OK)
+		a.Response(d.NotFound, JSONAPIErrors)
+		a.Response(d.BadRequest, JSONAPIErrors)
+		a.Response(d.InternalServerError, JSONAPIErrors)
+		a.Response(d.Unauthorized, JSONAPIErrors)
+	})
+
+	a.Action("remove-team", func() {
+		a.Security("jwt")
+		a.Routing(
+			a.DELETE("/:spaceID/collaborators/teams/:teamID"),
+		)
+		a.Description("Remove a team from the space collaborators.")
+		a.Params(func() {
+			a.Param("spaceID", d.UUID, "ID of the space")
+			a.Param("teamID", d.String, "ID of the team")
+		})
+		a.Response(d.OK)
+		a.Response(d.NotFound, JSONAPIErrors)
+		a.Response(d.BadRequest, JSONAPIErrors)
+		a.Response(d.InternalServerError, JSONAPIErrors)
+		a.Response(d.Unauthorized, JSONAPIErrors)
+	})
+})
+
+var collaboratorEventAttributes = a.Type("CollaboratorEventAttributes", func() {
+	a.Attribute("eventType", d.String, "The type of the change", func() {
+		a.Enum("collaborator.added", "collaborator.removed", "collaborator.extended", "collaborator.expired")
+	})
+	a.Attribute("actorID", d.UUID, "The identity ID of the user who made the change. Not set if the change was not made by a user, e.g. when the access of the collaborator expired")
+	a.Attribute("targetID", d.UUID, "The identity ID of the collaborator")
+	a.Attribute("requestID", d.String, "The ID of the request which made the change")
+	a.Attribute("created-at", d.DateTime, "The date of the change")
 })
 
+var collaboratorEventData = JSONResourceObject("CollaboratorEvent", collaboratorEventAttributes, nil)
+
+var collaboratorEventListMeta = a.Type("CollaboratorEventListMeta", func() {
+	a.Attribute("totalCount", d.Integer)
+	a.Required("totalCount")
+})
+
+var collaboratorEventList = JSONList(
+	"CollaboratorEvent", "Holds the paginated list of the changes of the space collaborators",
+	collaboratorEventData,
+	pagingLinks,
+	collaboratorEventListMeta)
+
+// collaboratorsUpdate holds the result of an update of the space collaborators
+var collaboratorsUpdate = a.MediaType("application/vnd.collaboratorsupdate+json", func() {
+	a.UseTrait("jsonapi-media-type")
+	a.TypeName("CollaboratorsUpdate")
+	a.Description("The result of an update of the space collaborators")
+	a.Attributes(func() {
+		a.Attribute("updated", a.ArrayOf(updateUserID), "The user identities whose access to the space changed")
+		a.Attribute("unchanged", a.ArrayOf(updateUserID), "The user identities whose access to the space didn't change because they were already among the collaborators when added, or not among them when removed")
+		a.Required("updated", "unchanged")
+	})
+	a.View("default", func() {
+		a.Attribute("updated")
+		a.Attribute("unchanged")
+		a.Required("updated", "unchanged")
+	})
+})
+
+var collaboratorExpiryParam = func() {
+	a.Param("expires_at", d.DateTime, "The date the access of the collaborators expires at. The access is given without time limit if not set")
+}
+
 var updateUserIDList = JSONList(
 	"UpdateUserID", "Holds the response of user idenitity IDs for updating list of user IDs",
 	updateUserID,
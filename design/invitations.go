@@ -0,0 +1,120 @@
+package design
+
+import (
+	d "github.com/goadesign/goa/design"
+	a "github.com/goadesign/goa/design/apidsl"
+)
+
+var invitationAttributes = a.Type("InvitationAttributes", func() {
+	a.Attribute("email", d.String, "The email the invitation was sent to")
+	a.Attribute("username", d.String, "The username the invitation was sent to")
+	a.Attribute("spaceID", d.UUID, "The ID of the space the user is invited to collaborate on")
+	a.Attribute("role", d.String, "The role given to the user who accepts the invitation")
+	a.Attribute("state", d.String, "The state of the invitation", func() {
+		a.Enum("pending", "accepted", "cancelled")
+	})
+	a.Attribute("inviterID", d.UUID, "The identity ID of the user who sent the invitation")
+	a.Attribute("acceptedBy", d.UUID, "The identity ID of the user who accepted the invitation")
+	a.Attribute("expires-at", d.DateTime, "The date after which the invitation can't be accepted anymore")
+	a.Attribute("created-at", d.DateTime, "The date of creation of the invitation")
+})
+
+var invitationData = JSONResourceObject("Invitation", invitationAttributes, nil)
+
+var invitationSingle = JSONSingle(
+	"Invitation", "Holds a single invitation",
+	invitationData,
+	nil)
+
+var invitationList = JSONList(
+	"Invitation", "Holds the list of invitations",
+	invitationData,
+	nil,
+	nil)
+
+// createInvitationPayload is the payload to invite a user by email or username
+var createInvitationPayload = a.Type("CreateInvitationPayload", func() {
+	a.Attribute("data", createInvitationData)
+	a.Required("data")
+})
+
+var createInvitationData = a.Type("CreateInvitationData", func() {
+	a.Attribute("type", d.String, func() {
+		a.Enum("invitations")
+	})
+	a.Attribute("attributes", createInvitationAttributes)
+	a.Required("type", "attributes")
+})
+
+var createInvitationAttributes = a.Type("CreateInvitationAttributes", func() {
+	a.Attribute("email", d.String, "The email of the invited user. Either the email or the username is required")
+	a.Attribute("username", d.String, "The username of the invited user. Either the email or the username is required")
+})
+
+var _ = a.Resource("invitations", func() {
+
+	a.Action("create", func() {
+		a.Security("jwt")
+		a.Routing(
+			a.POST("/spaces/:spaceID/invitations"),
+		)
+		a.Description("Invite a user to collaborate on the space, by email or username. The user doesn't need to have signed up")
+		a.Params(func() {
+			a.Param("spaceID", d.UUID, "ID of the space")
+		})
+		a.Payload(createInvitationPayload)
+		a.Response(d.Created, invitationSingle)
+		a.Response(d.NotFound, JSONAPIErrors)
+		a.Response(d.BadRequest, JSONAPIErrors)
+		a.Response(d.InternalServerError, JSONAPIErrors)
+		a.Response(d.Unauthorized, JSONAPIErrors)
+	})
+
+	a.Action("list", func() {
+		a.Security("jwt")
+		a.Routing(
+			a.GET("/spaces/:spaceID/invitations"),
+		)
+		a.Description("List the pending invitations to collaborate on the space")
+		a.Params(func() {
+			a.Param("spaceID", d.UUID, "ID of the space")
+		})
+		a.Response(d.OK, invitationList)
+		a.Response(d.NotFound, JSONAPIErrors)
+		a.Response(d.InternalServerError, JSONAPIErrors)
+		a.Response(d.Unauthorized, JSONAPIErrors)
+	})
+
+	a.Action("cancel", func() {
+		a.Security("jwt")
+		a.Routing(
+			a.DELETE("/spaces/:spaceID/invitations/:invitationID"),
+		)
+		a.Description("Cancel a pending invitation to collaborate on the space")
+		a.Params(func() {
+			a.Param("spaceID", d.UUID, "ID of the space")
+			a.Param("invitationID", d.UUID, "ID of the invitation")
+		})
+		a.Response(d.OK)
+		a.Response(d.NotFound, JSONAPIErrors)
+		a.Response(d.BadRequest, JSONAPIErrors)
+		a.Response(d.InternalServerError, JSONAPIErrors)
+		a.Response(d.Unauthorized, JSONAPIErrors)
+	})
+
+	a.Action("accept", func() {
+		a.Security("jwt")
+		a.Routing(
+			a.POST("/invitations/:invitationID/accept"),
+		)
+		a.Description("Accept an invitation on behalf of the current user, who is added to the space collaborators")
+		a.Params(func() {
+			a.Param("invitationID", d.UUID, "ID of the invitation")
+		})
+		a.Response(d.OK, invitationSingle)
+		a.Response(d.NotFound, JSONAPIErrors)
+		a.Response(d.BadRequest, JSONAPIErrors)
+		a.Response(d.InternalServerError, JSONAPIErrors)
+		a.Response(d.Unauthorized, JSONAPIErrors)
+	})
+})
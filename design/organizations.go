@@ -0,0 +1,358 @@
+package design
+
+import (
+	d "github.com/goadesign/goa/design"
+	a "github.com/goadesign/goa/design/apidsl"
+)
+
+var organizationAttributes = a.Type("OrganizationAttributes", func() {
+	a.Attribute("name", d.String, "The name of the organization")
+	a.Attribute("description", d.String, "The description of the organization")
+	a.Attribute("admins", a.ArrayOf(d.String), "The identity IDs of the organization admins")
+	a.Attribute("members", a.ArrayOf(d.String), "The identity IDs of the organization members, admins included")
+	a.Attribute("created-at", d.DateTime, "The date of creation of the organization")
+	a.Attribute("updated-at", d.DateTime, "The date of the last update of the organization")
+})
+
+var teamAttributes = a.Type("TeamAttributes", func() {
+	a.Attribute("name", d.String, "The name of the team")
+	a.Attribute("description", d.String, "The description of the team")
+	a.Attribute("organizationID", d.String, "The ID of the organization of the team")
+	a.Attribute("admins", a.ArrayOf(d.String), "The identity IDs of the team admins")
+	a.Attribute("members", a.ArrayOf(d.String), "The identity IDs of the team members, admins included")
+	a.Attribute("created-at", d.DateTime, "The date of creation of the team")
+	a.Attribute("updated-at", d.DateTime, "The date of the last update of the team")
+})
+
+var organizationData = JSONResourceObject("Organization", organizationAttributes, nil)
+
+var teamData = JSONResourceObject("Team", teamAttributes, nil)
+
+var organizationSingle = JSONSingle(
+	"Organization", "Holds a single organization",
+	organizationData,
+	nil)
+
+var organizationList = JSONList(
+	"Organization", "Holds the list of organizations",
+	organizationData,
+	nil,
+	nil)
+
+var teamSingle = JSONSingle(
+	"Team", "Holds a single team",
+	teamData,
+	nil)
+
+var teamList = JSONList(
+	"Team", "Holds the list of teams",
+	teamData,
+	nil,
+	nil)
+
+// organizationPayload is the payload to create or update an organization or a team
+var organizationPayload = a.Type("OrganizationPayload", func() {
+	a.Attribute("data", organizationPayloadData)
+	a.Required("data")
+})
+
+var organizationPayloadData = a.Type("OrganizationPayloadData", func() {
+	a.Attribute("type", d.String, func() {
+		a.Enum("organizations", "teams")
+	})
+	a.Attribute("attributes", organizationPayloadAttributes)
+	a.Required("type", "attributes")
+})
+
+var organizationPayloadAttributes = a.Type("OrganizationPayloadAttributes", func() {
+	a.Attribute("name", d.String, "The name of the organization or team. Required on creation")
+	a.Attribute("description", d.String, "The description of the organization or team")
+	a.Attribute("organizationID", d.String, "The ID of the organization of the team. Required on team creation")
+})
+
+var memberRoleParam = func() {
+	a.Param("role", d.String, "The role of the member", func() {
+		a.Enum("member", "admin")
+		a.Default("member")
+	})
+}
+
+var memberExpiryParam = func() {
+	a.Param("expires_at", d.DateTime, "The date the role of the member expires at. The role is given without time limit if not set")
+}
+
+var _ = a.Resource("organizations", func() {
+	a.BasePath("/organizations")
+
+	a.Action("create", func() {
+		a.Security("jwt")
+		a.Routing(
+			a.POST(""),
+		)
+		a.Description("Create an organization with the current user as admin")
+		a.Payload(organizationPayload)
+		a.Response(d.Created, organizationSingle)
+		a.Response(d.BadRequest, JSONAPIErrors)
+		a.Response(d.InternalServerError, JSONAPIErrors)
+		a.Response(d.Unauthorized, JSONAPIErrors)
+	})
+
+	a.Action("list", func() {
+		a.Security("jwt")
+		a.Routing(
+			a.GET(""),
+		)
+		a.Description("List the organizations the current user is a member of")
+		a.Response(d.OK, organizationList)
+		a.Response(d.InternalServerError, JSONAPIErrors)
+		a.Response(d.Unauthorized, JSONAPIErrors)
+	})
+
+	a.Action("show", func() {
+		a.Security("jwt")
+		a.Routing(
+			a.GET("/:organizationID"),
+		)
+		a.Description("Get the organization with the given ID")
+		a.Params(func() {
+			a.Param("organizationID", d.String, "ID of the organization")
+		})
+		a.Response(d.OK, organizationSingle)
+		a.Response(d.NotFound, JSONAPIErrors)
+		a.Response(d.InternalServerError, JSONAPIErrors)
+		a.Response(d.Unauthorized, JSONAPIErrors)
+	})
+
+	a.Action("update", func() {
+		a.Security("jwt")
+		a.Routing(
+			a.PATCH("/:organizationID"),
+		)
+		a.Description("Update the name and description of the organization with the given ID")
+		a.Params(func() {
+			a.Param("organizationID", d.String, "ID of the organization")
+		})
+		a.Payload(organizationPayload)
+		a.Response(d.OK, organizationSingle)
+		a.Response(d.BadRequest, JSONAPIErrors)
+		a.Response(d.NotFound, JSONAPIErrors)
+		a.Response(d.InternalServerError, JSONAPIErrors)
+		a.Response(d.Unauthorized, JSONAPIErrors)
+		a.Response(d.Forbidden, JSONAPIErrors)
+	})
+
+	a.Action("delete", func() {
+		a.Security("jwt")
+		a.Routing(
+			a.DELETE("/:organizationID"),
+		)
+		a.Description("Delete the organization with the given ID and all its teams")
+		a.Params(func() {
+			a.Param("organizationID", d.String, "ID of the organization")
+		})
+		a.Response(d.OK)
+		a.Response(d.NotFound, JSONAPIErrors)
+		a.Response(d.InternalServerError, JSONAPIErrors)
+		a.Response(d.Unauthorized, JSONAPIErrors)
+		a.Response(d.Forbidden, JSONAPIErrors)
+	})
+
+	a.Action("add-member", func() {
+		a.Security("jwt")
+		a.Routing(
+			a.PUT("/:organizationID/members/:identityID"),
+		)
+		a.Description("Add a user to the members or admins of the organization")
+		a.Params(func() {
+			a.Param("organizationID", d.String, "ID of the organization")
+			a.Param("identityID", d.UUID, "ID of the user identity")
+			memberRoleParam()
+			memberExpiryParam()
+		})
+		a.Response(d.OK, organizationSingle)
+		a.Response(d.BadRequest, JSONAPIErrors)
+		a.Response(d.NotFound, JSONAPIErrors)
+		a.Response(d.InternalServerError, JSONAPIErrors)
+		a.Response(d.Unauthorized, JSONAPIErrors)
+		a.Response(d.Forbidden, JSONAPIErrors)
+	})
+
+	a.Action("remove-member", func() {
+		a.Security("jwt")
+		a.Routing(
+			a.DELETE("/:organizationID/members/:identityID"),
+		)
+		a.Description("Remove a user from the organization")
+		a.Params(func() {
+			a.Param("organizationID", d.String, "ID of the organization")
+			a.Param("identityID", d.UUID, "ID of the user identity")
+		})
+		a.Response(d.OK, organizationSingle)
+		a.Response(d.BadRequest, JSONAPIErrors)
+		a.Response(d.NotFound, JSONAPIErrors)
+		a.Response(d.InternalServerError, JSONAPIErrors)
+		a.Response(d.Unauthorized, JSONAPIErrors)
+		a.Response(d.Forbidden, JSONAPIErrors)
+	})
+
+	a.Action("extend-member", func() {
+		a.Security("jwt")
+		a.Routing(
+			a.POST("/:organizationID/members/:identityID/extend"),
+		)
+		a.Description("Extend the time-bound roles of a user in the organization")
+		a.Params(func() {
+			a.Param("organizationID", d.String, "ID of the organization")
+			a.Param("identityID", d.UUID, "ID of the user identity")
+			a.Param("expires_at", d.DateTime, "The new date the roles of the member expire at")
+			a.Required("expires_at")
+		})
+		a.Response(d.OK, organizationSingle)
+		a.Response(d.BadRequest, JSONAPIErrors)
+		a.Response(d.NotFound, JSONAPIErrors)
+		a.Response(d.InternalServerError, JSONAPIErrors)
+		a.Response(d.Unauthorized, JSONAPIErrors)
+		a.Response(d.Forbidden, JSONAPIErrors)
+	})
+
+	a.Action("list-teams", func() {
+		a.Security("jwt")
+		a.Routing(
+			a.GET("/:organizationID/teams"),
+		)
+		a.Description("List the teams of the organization")
+		a.Params(func() {
+			a.Param("organizationID", d.String, "ID of the organization")
+		})
+		a.Response(d.OK, teamList)
+		a.Response(d.NotFound, JSONAPIErrors)
+		a.Response(d.InternalServerError, JSONAPIErrors)
+		a.Response(d.Unauthorized, JSONAPIErrors)
+	})
+})
+
+var _ = a.Resource("teams", func() {
+	a.BasePath("/teams")
+
+	a.Action("create", func() {
+		a.Security("jwt")
+		a.Routing(
+			a.POST(""),
+		)
+		a.Description("Create a team in an organization the current user is an admin of")
+		a.Payload(organizationPayload)
+		a.Response(d.Created, teamSingle)
+		a.Response(d.BadRequest, JSONAPIErrors)
+		a.Response(d.NotFound, JSONAPIErrors)
+		a.Response(d.InternalServerError, JSONAPIErrors)
+		a.Response(d.Unauthorized, JSONAPIErrors)
+		a.Response(d.Forbidden, JSONAPIErrors)
+	})
+
+	a.Action("show", func() {
+		a.Security("jwt")
+		a.Routing(
+			a.GET("/:teamID"),
+		)
+		a.Description("Get the team with the given ID")
+		a.Params(func() {
+			a.Param("teamID", d.String, "ID of the team")
+		})
+		a.Response(d.OK, teamSingle)
+		a.Response(d.NotFound, JSONAPIErrors)
+		a.Response(d.InternalServerError, JSONAPIErrors)
+		a.Response(d.Unauthorized, JSONAPIErrors)
+	})
+
+	a.Action("update", func() {
+		a.Security("jwt")
+		a.Routing(
+			a.PATCH("/:teamID"),
+		)
+		a.Description("Update the name and description of the team with the given ID")
+		a.Params(func() {
+			a.Param("teamID", d.String, "ID of the team")
+		})
+		a.Payload(organizationPayload)
+		a.Response(d.OK, teamSingle)
+		a.Response(d.BadRequest, JSONAPIErrors)
+		a.Response(d.NotFound, JSONAPIErrors)
+		a.Response(d.InternalServerError, JSONAPIErrors)
+		a.Response(d.Unauthorized, JSONAPIErrors)
+		a.Response(d.Forbidden, JSONAPIErrors)
+	})
+
+	a.Action("delete", func() {
+		a.Security("jwt")
+		a.Routing(
+			a.DELETE("/:teamID"),
+		)
+		a.Description("Delete the team with the given ID")
+		a.Params(func() {
+			a.Param("teamID", d.String, "ID of the team")
+		})
+		a.Response(d.OK)
+		a.Response(d.NotFound, JSONAPIErrors)
+		a.Response(d.InternalServerError, JSONAPIErrors)
+		a.Response(d.Unauthorized, JSONAPIErrors)
+		a.Response(d.Forbidden, JSONAPIErrors)
+	})
+
+	a.Action("add-member", func() {
+		a.Security("jwt")
+		a.Routing(
+			a.PUT("/:teamID/members/:identityID"),
+		)
+		a.Description("Add a user to the members or admins of the team")
+		a.Params(func() {
+			a.Param("teamID", d.String, "ID of the team")
+			a.Param("identityID", d.UUID, "ID of the user identity")
+			memberRoleParam()
+			memberExpiryParam()
+		})
+		a.Response(d.OK, teamSingle)
+		a.Response(d.BadRequest, JSONAPIErrors)
+		a.Response(d.NotFound, JSONAPIErrors)
+		a.Response(d.InternalServerError, JSONAPIErrors)
+		a.Response(d.Unauthorized, JSONAPIErrors)
+		a.Response(d.Forbidden, JSONAPIErrors)
+	})
+
+	a.Action("remove-member", func() {
+		a.Security("jwt")
+		a.Routing(
+			a.DELETE("/:teamID/members/:identityID"),
+		)
+		a.Description("Remove a user from the team")
+		a.Params(func() {
+			a.Param("teamID", d.String, "ID of the team")
+			a.Param("identityID", d.UUID, "ID of the user identity")
+		})
+		a.Response(d.OK, teamSingle)
+		a.Response(d.BadRequest, JSONAPIErrors)
+		a.Response(d.NotFound, JSONAPIErrors)
+		a.Response(d.InternalServerError, JSONAPIErrors)
+		a.Response(d.Unauthorized, JSONAPIErrors)
+		a.Response(d.Forbidden, JSONAPIErrors)
+	})
+
+	a.Action("extend-member", func() {
+		a.Security("jwt")
+		a.Routing(
+			a.POST("/:teamID/members/:identityID/extend"),
+		)
+		a.Description("Extend the time-bound roles of a user in the team")
+		a.Params(func() {
+			a.Param("teamID", d.String, "ID of the team")
+			a.Param("identityID", d.UUID, "ID of the user identity")
+			a.Param("expires_at", d.DateTime, "The new date the roles of the member expire at")
+			a.Required("expires_at")
+		})
+		a.Response(d.OK, teamSingle)
+		a.Response(d.BadRequest, JSONAPIErrors)
+		a.Response(d.NotFound, JSONAPIErrors)
+		a.Response(d.InternalServerError, JSONAPIErrors)
+		a.Response(d.Unauthorized, JSONAPIErrors)
+		a.Response(d.Forbidden, JSONAPIErrors)
+	})
+})
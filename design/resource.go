@@ -47,14 +47,17 @@ var _ = a.Resource("resource", func() {
 		a.Params(func() {
 			a.Param("resourceId", d.String, "Identifier of the resource to update")
 		})
-		a.Description("Update the details of the specified resource")
+		a.Description("Update the name and description of the specified resource. The update fails with a '412 Precondition Failed' response if the resource was modified since the 'If-Match' ETag or the 'If-Unmodified-Since' date. The ETag is computed from the modification time of the resource with a microsecond precision, so the ETags returned before this precision was introduced don't match anymore.")
+		a.UseTrait("conditional-write")
+		a.Payload(updateResourcePayload)
+		a.Response(d.OK, ResourceMedia)
 		a.Response(d.Unauthorized, JSONAPIErrors)
 		a.Response(d.Forbidden, JSONAPIErrors)
 		a.Response(d.TemporaryRedirect)
 		a.Response(d.InternalServerError, JSONAPIErrors)
 		a.Response(d.BadRequest, JSONAPIErrors)
 		a.Response(d.NotFound, JSONAPIErrors)
-		a.Response(d.MethodNotAllowed)
+		a.Response(d.PreconditionFailed, JSONAPIErrors)
 	})
 
 	a.Action("delete", func() {
@@ -98,6 +101,12 @@ var ResourceMedia = a.MediaType("application/vnd.resource+json", func() {
 	})
 })
 
+// updateResourcePayload represents the details of a protected resource which can be updated
+var updateResourcePayload = a.Type("UpdateResourceDetails", func() {
+	a.Attribute("name", d.String, "The name of the resource")
+	a.Attribute("description", d.String, "Description of the resource")
+})
+
 var RegisterResourceMedia = a.MediaType("application/vnd.register_resource+json", func() {
 	a.Description("Response returned when a resource is registered")
 	a.Attributes(func() {
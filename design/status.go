@@ -15,6 +15,7 @@ var AuthStatus = a.MediaType("application/vnd.status+json", func() {
 		a.Attribute("devMode", d.Boolean, "'True' if the Developer Mode is enabled")
 		a.Attribute("databaseStatus", d.String, "The status of Database connection. 'OK' or an error message is displayed.")
 		a.Attribute("configurationStatus", d.String, "The status of the used configuration. 'OK' or an error message if there is something wrong with the configuration used by service.")
+		a.Attribute("dependencies", a.ArrayOf(dependencyStatus), "The status of the services this instance depends on")
 		a.Required("commit", "buildTime", "startTime", "databaseStatus", "configurationStatus")
 	})
 	a.View("default", func() {
@@ -24,6 +25,35 @@ var AuthStatus = a.MediaType("application/vnd.status+json", func() {
 		a.Attribute("devMode")
 		a.Attribute("databaseStatus")
 		a.Attribute("configurationStatus")
+		a.Attribute("dependencies")
+	})
+})
+
+// dependencyStatus defines the status of a service the current running Auth instance depends on
+var dependencyStatus = a.Type("DependencyStatus", func() {
+	a.Attribute("name", d.String, "The name of the dependency")
+	a.Attribute("status", d.String, "The status of the dependency. 'OK' or an error message is displayed.")
+	a.Attribute("critical", d.Boolean, "'True' if the instance is not ready to serve requests without the dependency")
+	a.Attribute("latencyMillis", d.Integer, "The duration of the last check in milliseconds")
+	a.Attribute("checkedAt", d.DateTime, "The time of the last check")
+	a.Attribute("lastError", d.String, "The last error, even if the dependency has recovered since")
+	a.Attribute("lastErrorAt", d.DateTime, "The time of the last error")
+	a.Required("name", "status", "critical", "latencyMillis", "checkedAt")
+})
+
+// AuthLiveness defines the liveness of the current running Auth instance
+var AuthLiveness = a.MediaType("application/vnd.liveness+json", func() {
+	a.Description("The liveness of the current running instance")
+	a.Attributes(func() {
+		a.Attribute("commit", d.String, "Commit SHA this build is based on")
+		a.Attribute("buildTime", d.String, "The time when built")
+		a.Attribute("startTime", d.String, "The time when started")
+		a.Required("commit", "buildTime", "startTime")
+	})
+	a.View("default", func() {
+		a.Attribute("commit")
+		a.Attribute("buildTime")
+		a.Attribute("startTime")
 	})
 })
 
@@ -36,7 +66,24 @@ var _ = a.Resource("status", func() {
 		a.Routing(
 			a.GET(""),
 		)
-		a.Description("Show the status of the current running instance")
+		a.Description("Show the status of the current running instance including the status of all its dependencies. Responds with 503 only if the database is not available or if the default configuration is used in production mode. The dependencies are only reported.")
+		a.Response(d.OK)
+		a.Response(d.ServiceUnavailable, AuthStatus)
+	})
+
+	a.Action("liveness", func() {
+		a.Routing(
+			a.GET("/liveness"),
+		)
+		a.Description("Show if the current running instance is alive. To be used by liveness probes. No dependency is checked.")
+		a.Response(d.OK, AuthLiveness)
+	})
+
+	a.Action("readiness", func() {
+		a.Routing(
+			a.GET("/readiness"),
+		)
+		a.Description("Show if the current running instance is ready to serve requests. To be used by readiness probes. Only the database and the critical dependencies, like Keycloak, are required to be available.")
 		a.Response(d.OK)
 		a.Response(d.ServiceUnavailable, AuthStatus)
 	})
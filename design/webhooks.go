@@ -0,0 +1,143 @@
+package design
+
+import (
+	d "github.com/goadesign/goa/design"
+	a "github.com/goadesign/goa/design/apidsl"
+)
+
+var webhookAttributes = a.Type("WebhookAttributes", func() {
+	a.Attribute("url", d.String, "The URL the events are posted to")
+	a.Attribute("event-types", a.ArrayOf(d.String), "The types of the events posted to the URL")
+	a.Attribute("secret", d.String, "The key of the HMAC-SHA256 signature of the bodies posted to the URL, sent in the X-Auth-Signature header. Only returned when the subscription is created")
+	a.Attribute("created-at", d.DateTime, "The date the subscription was created")
+})
+
+var webhookData = JSONResourceObject("Webhook", webhookAttributes, nil)
+
+var webhookSingle = JSONSingle(
+	"Webhook", "Holds a single webhook subscription",
+	webhookData,
+	nil)
+
+var webhookList = JSONList(
+	"Webhook", "Holds the list of the webhook subscriptions of a service account",
+	webhookData,
+	nil,
+	nil)
+
+var webhookDeliveryLogAttributes = a.Type("WebhookDeliveryLogAttributes", func() {
+	a.Attribute("event-id", d.UUID, "The ID of the delivered event, which is the same for all the attempts to deliver it")
+	a.Attribute("event-type", d.String, "The type of the delivered event")
+	a.Attribute("attempt", d.Integer, "The number of the attempt to deliver the event, starting at 1")
+	a.Attribute("status-code", d.Integer, "The HTTP status of the response of the URL. Zero if no response was received")
+	a.Attribute("error", d.String, "The reason why the delivery failed, if it did")
+	a.Attribute("duration-ms", d.Integer, "The time to get the response of the URL, in milliseconds")
+	a.Attribute("created-at", d.DateTime, "The date of the attempt")
+})
+
+var webhookDeliveryLogData = JSONResourceObject("WebhookDeliveryLog", webhookDeliveryLogAttributes, nil)
+
+var webhookDeliveryLogList = JSONList(
+	"WebhookDeliveryLog", "Holds the most recent attempts to deliver the events to a webhook subscription",
+	webhookDeliveryLogData,
+	nil,
+	nil)
+
+// createWebhookPayload is the payload to subscribe to events
+var createWebhookPayload = a.Type("CreateWebhookPayload", func() {
+	a.Attribute("data", createWebhookData)
+	a.Required("data")
+})
+
+var createWebhookData = a.Type("CreateWebhookData", func() {
+	a.Attribute("type", d.String, func() {
+		a.Enum("webhooks")
+	})
+	a.Attribute("attributes", createWebhookAttributes)
+	a.Required("type", "attributes")
+})
+
+var createWebhookAttributes = a.Type("CreateWebhookAttributes", func() {
+	a.Attribute("url", d.String, "The absolute http or https URL the events are posted to")
+	a.Attribute("event-types", a.ArrayOf(d.String), "The types of the events posted to the URL, among user.created, user.updated, identity.linked, collaborator.added, collaborator.removed and space.deleted", func() {
+		a.MinLength(1)
+	})
+	a.Required("url", "event-types")
+})
+
+var _ = a.Resource("webhooks", func() {
+	a.BasePath("/webhooks")
+
+	a.Action("list", func() {
+		a.Security("jwt")
+		a.Routing(
+			a.GET(""),
+		)
+		a.Description("List the webhook subscriptions of the current service account")
+		a.Response(d.OK, webhookList)
+		a.Response(d.InternalServerError, JSONAPIErrors)
+		a.Response(d.Unauthorized, JSONAPIErrors)
+	})
+
+	a.Action("show", func() {
+		a.Security("jwt")
+		a.Routing(
+			a.GET("/:subscriptionID"),
+		)
+		a.Description("Show a webhook subscription of the current service account")
+		a.Params(func() {
+			a.Param("subscriptionID", d.UUID, "ID of the subscription")
+		})
+		a.Response(d.OK, webhookSingle)
+		a.Response(d.NotFound, JSONAPIErrors)
+		a.Response(d.BadRequest, JSONAPIErrors)
+		a.Response(d.InternalServerError, JSONAPIErrors)
+		a.Response(d.Unauthorized, JSONAPIErrors)
+	})
+
+	a.Action("create", func() {
+		a.Security("jwt")
+		a.Routing(
+			a.POST(""),
+		)
+		a.Description("Subscribe the current service account to the events of the given types. The secret of the signature of the events is only returned in the response")
+		a.Payload(createWebhookPayload)
+		a.Response(d.Created, webhookSingle)
+		a.Response(d.BadRequest, JSONAPIErrors)
+		a.Response(d.InternalServerError, JSONAPIErrors)
+		a.Response(d.Unauthorized, JSONAPIErrors)
+	})
+
+	a.Action("delete", func() {
+		a.Security("jwt")
+		a.Routing(
+			a.DELETE("/:subscriptionID"),
+		)
+		a.Description("Delete a webhook subscription of the current service account. The events which are not delivered yet are discarded")
+		a.Params(func() {
+			a.Param("subscriptionID", d.UUID, "ID of the subscription")
+		})
+		a.Response(d.OK)
+		a.Response(d.NotFound, JSONAPIErrors)
+		a.Response(d.BadRequest, JSONAPIErrors)
+		a.Response(d.InternalServerError, JSONAPIErrors)
+		a.Response(d.Unauthorized, JSONAPIErrors)
+	})
+
+	a.Action("deliveries", func() {
+		a.Security("jwt")
+		a.Routing(
+			a.GET("/:subscriptionID/deliveries"),
+		)
+		a.Description("List the most recent attempts to deliver the events to a webhook subscription of the current service account, newest first")
+		a.Params(func() {
+			a.Param("subscriptionID", d.UUID, "ID of the subscription")
+			a.Param("page[limit]", d.Integer, "The max number of attempts returned")
+		})
+		a.Response(d.OK, webhookDeliveryLogList)
+		a.Response(d.NotFound, JSONAPIErrors)
+		a.Response(d.BadRequest, JSONAPIErrors)
+		a.Response(d.InternalServerError, JSONAPIErrors)
+		a.Response(d.Unauthorized, JSONAPIErrors)
+	})
+})
@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"time"
 
 	errs "github.com/pkg/errors"
 )
@@ -77,6 +78,22 @@ func IsForbiddenError(err error) (bool, error) {
 	return true, e
 }
 
+// NewTooManyRequestsError returns the custom defined error of type TooManyRequestsError.
+// The client should not retry before retryAfter.
+func NewTooManyRequestsError(msg string, retryAfter time.Duration) TooManyRequestsError {
+	return TooManyRequestsError{simpleError: simpleError{msg}, RetryAfter: retryAfter}
+}
+
+// IsTooManyRequestsError returns true if the cause of the given error can be
+// converted to an TooManyRequestsError, which is returned as the second result.
+func IsTooManyRequestsError(err error) (bool, error) {
+	e, ok := errs.Cause(err).(TooManyRequestsError)
+	if !ok {
+		return false, nil
+	}
+	return true, e
+}
+
 // InternalError means that the operation failed for some internal, unexpected reason
 type InternalError struct {
 	Err error
@@ -96,6 +113,12 @@ type ForbiddenError struct {
 	simpleError
 }
 
+// TooManyRequestsError means that the client exceeded the rate limit of the operation
+type TooManyRequestsError struct {
+	simpleError
+	RetryAfter time.Duration
+}
+
 // VersionConflictError means that the version was not as expected in an update operation
 type VersionConflictError struct {
 	simpleError
@@ -133,6 +156,28 @@ func (err BadParameterError) Error() string {
 
 }
 
+// MessageKey returns the key of the message of the error in the message catalogs
+func (err BadParameterError) MessageKey() string {
+	if err.hasExpectedValue {
+		return "bad_parameter.expected.detail"
+	}
+	return "bad_parameter.detail"
+}
+
+// MessageParams returns the parameters of the message of the error
+func (err BadParameterError) MessageParams() map[string]interface{} {
+	return map[string]interface{}{
+		"parameter": err.parameter,
+		"value":     err.value,
+		"expected":  err.expectedValue,
+	}
+}
+
+// Parameter returns the name of the parameter which has a bad value
+func (err BadParameterError) Parameter() string {
+	return err.parameter
+}
+
 // Expected sets the optional expectedValue parameter on the BadParameterError
 func (err BadParameterError) Expected(expexcted interface{}) BadParameterError {
 	err.expectedValue = expexcted
@@ -185,6 +230,19 @@ func (err NotFoundError) Error() string {
 	return fmt.Sprintf(stNotFoundErrorMsg, err.entity, err.ID)
 }
 
+// MessageKey returns the key of the message of the error in the message catalogs
+func (err NotFoundError) MessageKey() string {
+	return "not_found.detail"
+}
+
+// MessageParams returns the parameters of the message of the error
+func (err NotFoundError) MessageParams() map[string]interface{} {
+	return map[string]interface{}{
+		"entity": err.entity,
+		"id":     err.ID,
+	}
+}
+
 // NewNotFoundError returns the custom defined error of type NewNotFoundError.
 func NewNotFoundError(entity string, id string) NotFoundError {
 	return NotFoundError{entity: entity, ID: id}
@@ -74,14 +74,16 @@ var ignoredStructs []string
 func init() {
 	// aliases for packages (aliases are used to avoid naming conflicts with other structures generated by Goa)
 	packageAliases = map[string]string{
-		"spacedsl":   "github.com/fabric8-services/fabric8-auth/space",
-		"accountdsl": "github.com/fabric8-services/fabric8-auth/account",
+		"spacedsl":    "github.com/fabric8-services/fabric8-auth/space",
+		"accountdsl":  "github.com/fabric8-services/fabric8-auth/account",
+		"resourcedsl": "github.com/fabric8-services/fabric8-auth/authorization/resource",
 	}
 	// model structures and their corresponding package alias
 	structPackages = map[string]string{
 		"Space":    "spacedsl",
 		"User":     "accountdsl",
 		"Identity": "accountdsl",
+		"Resource": "resourcedsl",
 	}
 	// structures to ignore during code generation (mostly because they correspond to model structures which were already taken into account)
 	ignoredStructs = []string{
@@ -99,10 +101,54 @@ func canIgnoreStruct(structName string) bool {
 	return false
 }
 
+// responseEntity returns the entity of the "OK" response of the given action if this response
+// has the conditional response headers, nil otherwise.
+func responseEntity(act *design.ActionDefinition) *Entity {
+	for _, response := range act.Responses {
+		if response.Name != design.OK || response.Type == nil {
+			continue
+		}
+		mt, ok := response.Type.(*design.MediaTypeDefinition)
+		if !ok {
+			continue
+		}
+		// lookup conditional request/response headers
+		for header := range response.Headers.Type.ToObject() {
+			if header == "ETag" || header == "LastModified" {
+				// assume that a "list" entities have their name ending with "List"
+				// and "single" entities have their name ending with "Single"
+				isList := strings.HasSuffix(mt.TypeName, "List")
+				isArray := strings.HasSuffix(mt.TypeName, "Array")
+				var domainTypeName string
+				if isList {
+					domainTypeName = strings.TrimSuffix(mt.TypeName, "List")
+				} else if isArray {
+					domainTypeName = strings.TrimSuffix(mt.TypeName, "Array")
+				} else {
+					domainTypeName = strings.TrimSuffix(mt.TypeName, "Single")
+				}
+				if canIgnoreStruct(domainTypeName) {
+					continue
+				}
+				// prepend the package
+				domainTypeName = structPackages[domainTypeName] + "." + domainTypeName
+				return &Entity{
+					AppTypeName:    mt.TypeName,
+					DomainTypeName: domainTypeName,
+					IsList:         isList || isArray,
+					IsSingle:       !(isList || isArray),
+				}
+			}
+		}
+	}
+	return nil
+}
+
 // WriteNames creates the names.txt file.
 func WriteNames(api *design.APIDefinition, outDir string) ([]string, error) {
 	// Now iterate through the resources to gather their names
 	var contexts []RequestContext
+	var writeContexts []RequestContext
 	var entities []Entity
 
 	api.IterateResources(func(res *design.ResourceDefinition) error {
@@ -111,57 +157,22 @@ func WriteNames(api *design.APIDefinition, outDir string) ([]string, error) {
 			// look-up headers for conditional request support
 			if act.Headers != nil {
 				// look-up headers and entity types in responses
-				if act.Responses != nil {
-					for _, response := range act.Responses {
-						if response.Name == design.OK && response.Type != nil {
-							if mt, ok := response.Type.(*design.MediaTypeDefinition); ok {
-								var entity *Entity
-								// lookup conditional request/response headers
-								for header := range response.Headers.Type.ToObject() {
-									if header == "ETag" || header == "LastModified" {
-										// assume that a "list" entities have their name ending with "List"
-										// and "single" entities have their name ending with "Single"
-										isList := strings.HasSuffix(mt.TypeName, "List")
-										isArray := strings.HasSuffix(mt.TypeName, "Array")
-										var domainTypeName string
-										if isList {
-											domainTypeName = strings.TrimSuffix(mt.TypeName, "List")
-										} else if isArray {
-											domainTypeName = strings.TrimSuffix(mt.TypeName, "Array")
-										} else {
-											domainTypeName = strings.TrimSuffix(mt.TypeName, "Single")
-										}
-										if canIgnoreStruct(domainTypeName) {
-											continue
-										}
-										// prepend the package
-										domainTypeName = structPackages[domainTypeName] + "." + domainTypeName
-										entity = &Entity{
-											AppTypeName:    mt.TypeName,
-											DomainTypeName: domainTypeName,
-											IsList:         isList || isArray,
-											IsSingle:       !(isList || isArray),
-										}
-										break
-									}
-								}
-								// skip if no response header was found
-								if entity != nil {
-									fmt.Printf("Response context: %s -> entity: %v\n", name, entity)
-									// for k, v := range m.ToObject() {
-									// 	fmt.Printf("%s -> %v\n", k, v)
-									// }
-									ctx := RequestContext{Name: name, Entity: *entity}
-									contexts = append(contexts, ctx)
-									if !contains(entities, *entity) {
-										entities = append(entities, *entity)
-									}
-
-								}
-							}
-						}
+				entity := responseEntity(act)
+				if entity == nil {
+					return nil
+				}
+				headers := act.Headers.Type.ToObject()
+				if _, ok := headers["If-None-Match"]; ok {
+					fmt.Printf("Response context: %s -> entity: %v\n", name, entity)
+					contexts = append(contexts, RequestContext{Name: name, Entity: *entity})
+					if !contains(entities, *entity) {
+						entities = append(entities, *entity)
 					}
 				}
+				if _, ok := headers["If-Match"]; ok && entity.IsSingle {
+					fmt.Printf("Write context: %s -> entity: %v\n", name, entity)
+					writeContexts = append(writeContexts, RequestContext{Name: name, Entity: *entity})
+				}
 			}
 			return nil
 		})
@@ -183,7 +194,9 @@ func WriteNames(api *design.APIDefinition, outDir string) ([]string, error) {
 		codegen.SimpleImport("time"),
 		codegen.SimpleImport("fmt"),
 		codegen.SimpleImport("reflect"),
+		codegen.SimpleImport("strings"),
 		codegen.SimpleImport("github.com/fabric8-services/fabric8-auth/configuration"),
+		codegen.SimpleImport("github.com/fabric8-services/fabric8-auth/errors"),
 		codegen.SimpleImport("github.com/fabric8-services/fabric8-auth/log"),
 		codegen.NewImport("uuid", "github.com/satori/go.uuid"),
 	}
@@ -219,6 +232,15 @@ func WriteNames(api *design.APIDefinition, outDir string) ([]string, error) {
 	if err := ctxWr.ExecuteTemplate("toHTTPTime", toHTTPTime, nil, nil); err != nil {
 		return nil, err
 	}
+	if err := ctxWr.ExecuteTemplate("conditionalWriteContext", conditionalWriteContext, nil, nil); err != nil {
+		return nil, err
+	}
+	if err := ctxWr.ExecuteTemplate("doConditionalWrite", doConditionalWrite, nil, nil); err != nil {
+		return nil, err
+	}
+	if err := ctxWr.ExecuteTemplate("matchesIfMatch", matchesIfMatch, nil, nil); err != nil {
+		return nil, err
+	}
 	for _, ctx := range contexts {
 		if err := ctxWr.ExecuteTemplate("conditional", conditional, nil, ctx); err != nil {
 			return nil, err
@@ -239,6 +261,17 @@ func WriteNames(api *design.APIDefinition, outDir string) ([]string, error) {
 			return nil, err
 		}
 	}
+	for _, ctx := range writeContexts {
+		if err := ctxWr.ExecuteTemplate("conditionalWrite", conditionalWrite, nil, ctx); err != nil {
+			return nil, err
+		}
+		if err := ctxWr.ExecuteTemplate("getIfMatch", getIfMatch, nil, ctx); err != nil {
+			return nil, err
+		}
+		if err := ctxWr.ExecuteTemplate("getIfUnmodifiedSince", getIfUnmodifiedSince, nil, ctx); err != nil {
+			return nil, err
+		}
+	}
 	err = ctxWr.FormatCode()
 	if err != nil {
 		return nil, err
@@ -260,6 +293,10 @@ const (
 	// Plus, RFC 2616 specifies that header names are case insensitive:
 	// https://www.w3.org/Protocols/rfc2616/rfc2616-sec4.html#sec4.2
 	ETag = "Etag"
+	// IfMatch the "If-Match" HTTP request header name
+	IfMatch = "If-Match"
+	// IfUnmodifiedSince the "If-Unmodified-Since" HTTP request header name
+	IfUnmodifiedSince = "If-Unmodified-Since"
 	// CacheControl the "Cache-Control" HTTP response header name
 	CacheControl = "Cache-Control"
 	// MaxAge the "max-age" HTTP response header value
@@ -523,4 +560,70 @@ func (ctx *{{$resp.Name}}) setCacheControl(value string) {
 func ToHTTPTime(value time.Time) string {
 	return value.UTC().Format(http.TimeFormat)
 }`
+
+	conditionalWriteContext = `
+// ConditionalWriteContext interface with methods for the contexts of the conditional writes
+type ConditionalWriteContext interface {
+	getIfMatch() *string
+	getIfUnmodifiedSince() *time.Time
+}`
+
+	doConditionalWrite = `
+func doConditionalWrite(ctx ConditionalWriteContext, entity ConditionalRequestEntity) error {
+	// check the 'If-Match' header first.
+	if ifMatch := ctx.getIfMatch(); ifMatch != nil {
+		if !matchesIfMatch(*ifMatch, GenerateEntityTag(entity)) {
+			return errors.NewVersionConflictError("the entity does not match the 'If-Match' header")
+		}
+	} else if ifUnmodifiedSince := ctx.getIfUnmodifiedSince(); ifUnmodifiedSince != nil {
+		// check the 'If-Unmodified-Since' header only if no 'If-Match' header was provided
+		if ifUnmodifiedSince.UTC().Truncate(time.Second).Before(entity.GetLastModified().UTC().Truncate(time.Second)) {
+			return errors.NewVersionConflictError("the entity was modified since the 'If-Unmodified-Since' header")
+		}
+	}
+	return nil
+}`
+
+	conditionalWrite = `
+{{ $resp := . }}
+{{ $entity := $resp.Entity }}
+// ConditionalWrite checks if the entity to update changed since the client's last call and returns a 'VersionConflictError' if so,
+// in which case the update must not be carried on.
+func (ctx *{{$resp.Name}}) ConditionalWrite(entity {{$entity.DomainTypeName}}) error {
+	return doConditionalWrite(ctx, entity)
+}`
+
+	matchesIfMatch = `
+// matchesIfMatch returns 'true' if the given 'If-Match' header value is '*' or contains the given 'etag' argument,
+// quoted or not.
+func matchesIfMatch(ifMatch, etag string) bool {
+	for _, value := range strings.Split(ifMatch, ",") {
+		value = strings.Trim(strings.TrimSpace(value), "\"")
+		if value == "*" || value == etag {
+			return true
+		}
+	}
+	return false
+}`
+
+	getIfMatch = `
+{{ $resp := . }}
+// getIfMatch gets the 'If-Match' header
+func (ctx *{{$resp.Name}}) getIfMatch() *string {
+	return ctx.IfMatch
+}`
+
+	getIfUnmodifiedSince = `
+{{ $resp := . }}
+// getIfUnmodifiedSince gets the 'If-Unmodified-Since' header. An invalid date is ignored.
+func (ctx *{{$resp.Name}}) getIfUnmodifiedSince() *time.Time {
+	if ctx.IfUnmodifiedSince != nil {
+		val, err := http.ParseTime(*ctx.IfUnmodifiedSince)
+		if err != nil {
+			return nil
+		}
+		return &val
+	}
+	return nil
+}`
 )
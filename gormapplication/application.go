@@ -6,10 +6,16 @@ import (
 
 	"github.com/fabric8-services/fabric8-auth/account"
 	"github.com/fabric8-services/fabric8-auth/application"
+	"github.com/fabric8-services/fabric8-auth/audit"
 	"github.com/fabric8-services/fabric8-auth/auth"
 	"github.com/fabric8-services/fabric8-auth/authorization/resource"
+	"github.com/fabric8-services/fabric8-auth/authorization/role"
+	"github.com/fabric8-services/fabric8-auth/cluster/registry"
+	"github.com/fabric8-services/fabric8-auth/invitation"
+	"github.com/fabric8-services/fabric8-auth/outbox"
 	"github.com/fabric8-services/fabric8-auth/space"
 	"github.com/fabric8-services/fabric8-auth/token/provider"
+	"github.com/fabric8-services/fabric8-auth/webhook"
 	"github.com/jinzhu/gorm"
 	"github.com/pkg/errors"
 )
@@ -64,6 +70,21 @@ func (g *GormBase) SpaceResources() space.ResourceRepository {
 	return space.NewResourceRepository(g.db)
 }
 
+// SpaceTeams returns a space team repository
+func (g *GormBase) SpaceTeams() space.TeamRepository {
+	return space.NewTeamRepository(g.db)
+}
+
+// SpaceAccessRequests returns a space access request repository
+func (g *GormBase) SpaceAccessRequests() space.AccessRequestRepository {
+	return space.NewAccessRequestRepository(g.db)
+}
+
+// SpaceCollaboratorExpiries returns a space collaborator expiry repository
+func (g *GormBase) SpaceCollaboratorExpiries() space.CollaboratorExpiryRepository {
+	return space.NewCollaboratorExpiryRepository(g.db)
+}
+
 // Identities creates new Identity repository
 func (g *GormBase) Identities() account.IdentityRepository {
 	return account.NewIdentityRepository(g.db)
@@ -74,6 +95,11 @@ func (g *GormBase) Users() account.UserRepository {
 	return account.NewUserRepository(g.db)
 }
 
+// TenantProvisionings returns a tenant provisioning repository
+func (g *GormBase) TenantProvisionings() account.TenantProvisioningRepository {
+	return account.NewTenantProvisioningRepository(g.db)
+}
+
 // OauthStates returns an oauth state reference repository
 func (g *GormBase) OauthStates() auth.OauthStateReferenceRepository {
 	return auth.NewOauthStateReferenceRepository(g.db)
@@ -92,6 +118,50 @@ func (g *GormBase) ResourceTypeRepository() resource.ResourceTypeRepository {
 	return resource.NewResourceTypeRepository(g.db)
 }
 
+func (g *GormBase) ResourceTypeScopeRepository() resource.ResourceTypeScopeRepository {
+	return resource.NewResourceTypeScopeRepository(g.db)
+}
+
+// RoleRepository returns a role repository
+func (g *GormBase) RoleRepository() role.RoleRepository {
+	return role.NewRoleRepository(g.db)
+}
+
+// IdentityRoleRepository returns an identity role repository
+func (g *GormBase) IdentityRoleRepository() role.IdentityRoleRepository {
+	return role.NewIdentityRoleRepository(g.db)
+}
+
+// OutboxEvents returns an outbox event repository
+func (g *GormBase) OutboxEvents() outbox.EventRepository {
+	return outbox.NewEventRepository(g.db)
+}
+
+// Invitations returns an invitation repository
+func (g *GormBase) Invitations() invitation.InvitationRepository {
+	return invitation.NewInvitationRepository(g.db)
+}
+
+// AuditEvents returns an audit event repository
+func (g *GormBase) AuditEvents() audit.EventRepository {
+	return audit.NewEventRepository(g.db)
+}
+
+// Clusters returns a cluster repository
+func (g *GormBase) Clusters() registry.ClusterRepository {
+	return registry.NewClusterRepository(g.db)
+}
+
+// WebhookSubscriptions returns a webhook subscription repository
+func (g *GormBase) WebhookSubscriptions() webhook.SubscriptionRepository {
+	return webhook.NewSubscriptionRepository(g.db)
+}
+
+// WebhookDeliveries returns a webhook delivery repository
+func (g *GormBase) WebhookDeliveries() webhook.DeliveryRepository {
+	return webhook.NewDeliveryRepository(g.db)
+}
+
 func (g *GormBase) DB() *gorm.DB {
 	return g.db
 }
@@ -11,6 +11,7 @@ import (
 	"context"
 
 	"github.com/fabric8-services/fabric8-auth/application"
+	"github.com/fabric8-services/fabric8-auth/cluster/registry"
 	"github.com/fabric8-services/fabric8-auth/gormapplication"
 	"github.com/fabric8-services/fabric8-auth/gormsupport/cleaner"
 	"github.com/jinzhu/gorm"
@@ -70,6 +71,13 @@ func (s *DBTestSuite) TearDownTest() {
 	s.clean()
 }
 
+// SeedClusters registers the OSO clusters of the configuration in the cluster registry.
+// To be called after SetupTest, so the clusters are removed from the registry at the end of the test.
+func (s *DBTestSuite) SeedClusters() {
+	err := registry.Seed(s.Ctx, s.Application.Clusters(), s.Configuration.GetOSOClusterSeeds())
+	s.Require().NoError(err)
+}
+
 // PopulateDBTestSuite populates the DB with common values
 func (s *DBTestSuite) PopulateDBTestSuite(ctx context.Context) {
 }
@@ -0,0 +1,172 @@
+package health
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fabric8-services/fabric8-auth/log"
+)
+
+// DependencyChecker checks if a dependency of the service (database, Keycloak, WIT, etc) is available
+type DependencyChecker interface {
+	// Name returns the name of the dependency
+	Name() string
+	// Check returns nil if the dependency is available
+	Check(ctx context.Context) error
+}
+
+// DependencyStatus is the result of the last check of a dependency
+type DependencyStatus struct {
+	Name string
+	// Critical dependencies are required for the service to be ready
+	Critical  bool
+	Err       error
+	Latency   time.Duration
+	CheckedAt time.Time
+	// LastError is the last error returned by the check, even if the dependency has recovered since
+	LastError   error
+	LastErrorAt *time.Time
+}
+
+// OK returns true if the dependency was available during the last check
+func (s DependencyStatus) OK() bool {
+	return s.Err == nil
+}
+
+type registeredChecker struct {
+	checker  DependencyChecker
+	critical bool
+}
+
+// Registry holds the dependency checkers. All the dependencies are checked concurrently
+// and the results are cached, so the status endpoints can be polled often without
+// flooding the dependencies with requests.
+type Registry struct {
+	timeout  time.Duration
+	cacheTTL time.Duration
+
+	checkers []registeredChecker
+	mux      sync.Mutex
+	statuses []DependencyStatus
+	// lastErrors holds the last error of each dependency by dependency name
+	lastErrors map[string]DependencyStatus
+	checkedAt  time.Time
+}
+
+// NewRegistry creates a registry which gives up on a check after the given timeout
+// and keeps the results of the checks for cacheTTL
+func NewRegistry(timeout time.Duration, cacheTTL time.Duration) *Registry {
+	return &Registry{
+		timeout:    timeout,
+		cacheTTL:   cacheTTL,
+		lastErrors: map[string]DependencyStatus{},
+	}
+}
+
+// Register adds a dependency checker to the registry. Critical dependencies
+// are required for the service to be ready to serve requests.
+func (r *Registry) Register(checker DependencyChecker, critical bool) {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+	r.checkers = append(r.checkers, registeredChecker{checker: checker, critical: critical})
+	r.checkedAt = time.Time{} // invalidate the cache
+}
+
+// Replace unregisters the dependency checkers whose name starts with the given prefix and registers
+// the given ones instead, e.g. to refresh the checks of the clusters when the cluster registry changes
+func (r *Registry) Replace(prefix string, checkers []DependencyChecker, critical bool) {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+	kept := make([]registeredChecker, 0, len(r.checkers)+len(checkers))
+	for _, c := range r.checkers {
+		if !strings.HasPrefix(c.checker.Name(), prefix) {
+			kept = append(kept, c)
+		}
+	}
+	for _, checker := range checkers {
+		kept = append(kept, registeredChecker{checker: checker, critical: critical})
+	}
+	r.checkers = kept
+	r.checkedAt = time.Time{} // invalidate the cache
+}
+
+// Check returns the status of all the registered dependencies in the registration order.
+// Cached results are returned if they are not older than the cache TTL.
+// The checks don't run with the given context, which is only used for logging: the results are shared by all the callers
+// until they expire, so a caller which gave up (like a probe which timed out) must not cancel them.
+func (r *Registry) Check(ctx context.Context) []DependencyStatus {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+	if !r.checkedAt.IsZero() && time.Since(r.checkedAt) < r.cacheTTL {
+		return r.statuses
+	}
+
+	statuses := make([]DependencyStatus, len(r.checkers))
+	var wg sync.WaitGroup
+	for i, c := range r.checkers {
+		wg.Add(1)
+		go func(i int, c registeredChecker) {
+			defer wg.Done()
+			statuses[i] = r.check(context.Background(), c)
+		}(i, c)
+	}
+	wg.Wait()
+
+	for i, status := range statuses {
+		if status.Err != nil {
+			log.Warn(ctx, map[string]interface{}{
+				"dependency": status.Name,
+				"latency":    status.Latency.String(),
+				"err":        status.Err,
+			}, "dependency check failed")
+			statuses[i].LastError = status.Err
+			checkedAt := status.CheckedAt
+			statuses[i].LastErrorAt = &checkedAt
+			r.lastErrors[status.Name] = statuses[i]
+		} else if last, found := r.lastErrors[status.Name]; found {
+			statuses[i].LastError = last.LastError
+			statuses[i].LastErrorAt = last.LastErrorAt
+		}
+	}
+	r.statuses = statuses
+	r.checkedAt = time.Now()
+	return statuses
+}
+
+// IsReady returns true if all the critical dependencies are available
+func (r *Registry) IsReady(ctx context.Context) (bool, []DependencyStatus) {
+	statuses := r.Check(ctx)
+	for _, status := range statuses {
+		if status.Critical && !status.OK() {
+			return false, statuses
+		}
+	}
+	return true, statuses
+}
+
+// check runs a single check which is given up after the registry timeout
+func (r *Registry) check(ctx context.Context, c registeredChecker) DependencyStatus {
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	start := time.Now()
+	result := make(chan error, 1)
+	go func() {
+		result <- c.checker.Check(ctx)
+	}()
+	var err error
+	select {
+	case err = <-result:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+	return DependencyStatus{
+		Name:      c.checker.Name(),
+		Critical:  c.critical,
+		Err:       err,
+		Latency:   time.Since(start),
+		CheckedAt: start,
+	}
+}
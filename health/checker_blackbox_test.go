@@ -0,0 +1,139 @@
+package health_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/fabric8-services/fabric8-auth/health"
+	"github.com/fabric8-services/fabric8-auth/resource"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegistryCheck(t *testing.T) {
+	resource.Require(t, resource.UnitTest)
+
+	var calls int32
+	// the checks run in other goroutines
+	failing := int32(1)
+	registry := health.NewRegistry(100*time.Millisecond, time.Hour)
+	registry.Register(health.NewFuncChecker("db", func(ctx context.Context) error {
+		atomic.AddInt32(&calls, 1)
+		return nil
+	}), true)
+	registry.Register(health.NewFuncChecker("wit", func(ctx context.Context) error {
+		if atomic.LoadInt32(&failing) == 1 {
+			return errors.New("WIT is down")
+		}
+		return nil
+	}), false)
+	registry.Register(health.NewFuncChecker("tenant", func(ctx context.Context) error {
+		time.Sleep(time.Second)
+		return nil
+	}), false)
+
+	statuses := registry.Check(context.Background())
+	require.Len(t, statuses, 3)
+	assert.Equal(t, "db", statuses[0].Name)
+	assert.True(t, statuses[0].OK())
+	assert.True(t, statuses[0].Critical)
+	assert.Equal(t, "wit", statuses[1].Name)
+	assert.False(t, statuses[1].OK())
+	assert.EqualError(t, statuses[1].LastError, "WIT is down")
+	assert.NotNil(t, statuses[1].LastErrorAt)
+	// the slow dependency timed out
+	assert.False(t, statuses[2].OK())
+	assert.Equal(t, context.DeadlineExceeded, statuses[2].Err)
+	assert.True(t, statuses[2].Latency < time.Second)
+
+	// non critical dependencies don't affect the readiness
+	ready, _ := registry.IsReady(context.Background())
+	assert.True(t, ready)
+
+	// results are cached
+	registry.Check(context.Background())
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+
+	// the last error is kept after recovery
+	registry = health.NewRegistry(100*time.Millisecond, 0)
+	registry.Register(health.NewFuncChecker("wit", func(ctx context.Context) error {
+		if atomic.LoadInt32(&failing) == 1 {
+			return errors.New("WIT is down")
+		}
+		return nil
+	}), true)
+	ready, _ = registry.IsReady(context.Background())
+	assert.False(t, ready)
+	atomic.StoreInt32(&failing, 0)
+	statuses = registry.Check(context.Background())
+	assert.True(t, statuses[0].OK())
+	assert.EqualError(t, statuses[0].LastError, "WIT is down")
+}
+
+func TestRegistryCheckWithCancelledContext(t *testing.T) {
+	resource.Require(t, resource.UnitTest)
+
+	registry := health.NewRegistry(100*time.Millisecond, time.Hour)
+	registry.Register(health.NewFuncChecker("keycloak", func(ctx context.Context) error {
+		return ctx.Err()
+	}), true)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	// the cancellation of the caller is not cached as the status of the dependency
+	ready, statuses := registry.IsReady(ctx)
+	assert.True(t, ready)
+	require.Len(t, statuses, 1)
+	assert.True(t, statuses[0].OK())
+}
+
+func TestRegistryReplace(t *testing.T) {
+	resource.Require(t, resource.UnitTest)
+
+	ok := func(ctx context.Context) error {
+		return nil
+	}
+	registry := health.NewRegistry(100*time.Millisecond, time.Hour)
+	registry.Register(health.NewFuncChecker("keycloak", ok), true)
+	registry.Register(health.NewFuncChecker("oso-cluster-us-east-1", ok), false)
+	require.Len(t, registry.Check(context.Background()), 2)
+
+	registry.Replace("oso-cluster-", []health.DependencyChecker{
+		health.NewFuncChecker("oso-cluster-us-east-2", ok),
+		health.NewFuncChecker("oso-cluster-us-east-3", ok),
+	}, false)
+	// the cached results are invalidated
+	statuses := registry.Check(context.Background())
+	require.Len(t, statuses, 3)
+	assert.Equal(t, "keycloak", statuses[0].Name)
+	assert.Equal(t, "oso-cluster-us-east-2", statuses[1].Name)
+	assert.Equal(t, "oso-cluster-us-east-3", statuses[2].Name)
+}
+
+func TestHTTPChecker(t *testing.T) {
+	resource.Require(t, resource.UnitTest)
+
+	// the status is read by the server goroutine
+	status := int32(http.StatusOK)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(int(atomic.LoadInt32(&status)))
+	}))
+	defer ts.Close()
+
+	checker := health.NewHTTPChecker("wit", ts.URL)
+	assert.Equal(t, "wit", checker.Name())
+	assert.Nil(t, checker.Check(context.Background()))
+
+	atomic.StoreInt32(&status, http.StatusUnauthorized)
+	assert.Nil(t, checker.Check(context.Background()))
+
+	atomic.StoreInt32(&status, http.StatusServiceUnavailable)
+	assert.NotNil(t, checker.Check(context.Background()))
+
+	assert.NotNil(t, health.NewHTTPChecker("unknown", "http://localhost:0").Check(context.Background()))
+}
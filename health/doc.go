@@ -0,0 +1,3 @@
+// Package health provides the checks of the dependencies of the service
+// which are reported by the status endpoints.
+package health
@@ -0,0 +1,73 @@
+package health
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/fabric8-services/fabric8-auth/rest"
+
+	"github.com/pkg/errors"
+)
+
+// HTTPChecker checks a dependency by sending a GET request to one of its endpoints.
+// The dependency is considered available if the response status is lower than 500.
+type HTTPChecker struct {
+	name   string
+	url    string
+	client *http.Client
+}
+
+// NewHTTPChecker creates a checker for the dependency reachable at the given URL
+func NewHTTPChecker(name string, url string) *HTTPChecker {
+	return &HTTPChecker{
+		name:   name,
+		url:    url,
+		client: http.DefaultClient,
+	}
+}
+
+// Name returns the name of the dependency
+func (c *HTTPChecker) Name() string {
+	return c.name
+}
+
+// Check sends a GET request to the dependency
+func (c *HTTPChecker) Check(ctx context.Context) error {
+	req, err := http.NewRequest("GET", c.url, nil)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	res, err := c.client.Do(req.WithContext(ctx))
+	if err != nil {
+		return errors.Wrapf(err, "%s is unreachable", c.url)
+	}
+	defer rest.CloseResponse(res)
+	if res.StatusCode >= http.StatusInternalServerError {
+		return errors.Errorf("%s responded with status %s", c.url, res.Status)
+	}
+	return nil
+}
+
+// FuncChecker turns a function into a dependency checker
+type FuncChecker struct {
+	name  string
+	check func(ctx context.Context) error
+}
+
+// NewFuncChecker creates a checker for the given dependency which calls the given check function
+func NewFuncChecker(name string, check func(ctx context.Context) error) *FuncChecker {
+	return &FuncChecker{
+		name:  name,
+		check: check,
+	}
+}
+
+// Name returns the name of the dependency
+func (c *FuncChecker) Name() string {
+	return c.name
+}
+
+// Check calls the check function
+func (c *FuncChecker) Check(ctx context.Context) error {
+	return c.check(ctx)
+}
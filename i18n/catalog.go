@@ -0,0 +1,165 @@
+package i18n
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/goadesign/goa"
+	errs "github.com/pkg/errors"
+	"gopkg.in/yaml.v2"
+)
+
+// DefaultLanguage is the language of the messages when the language of the user is not supported
+const DefaultLanguage = "en"
+
+// Localizable is an error whose message can be translated with a key of the catalog and the parameters of the message
+type Localizable interface {
+	error
+	MessageKey() string
+	MessageParams() map[string]interface{}
+}
+
+// Catalog holds the messages of each supported language, by key
+type Catalog struct {
+	messages map[string]map[string]string
+}
+
+// NewCatalog creates a catalog with the given messages, by language and by key
+func NewCatalog(messages map[string]map[string]string) *Catalog {
+	return &Catalog{messages: messages}
+}
+
+// LoadCatalog loads the catalog files packed in the binary, named after their language
+func LoadCatalog() (*Catalog, error) {
+	messages := map[string]map[string]string{}
+	for _, name := range AssetNames() {
+		data, err := Asset(name)
+		if err != nil {
+			return nil, errs.Wrapf(err, "unable to load the message catalog '%s'", name)
+		}
+		languageMessages := map[string]string{}
+		err = yaml.Unmarshal(data, &languageMessages)
+		if err != nil {
+			return nil, errs.Wrapf(err, "unable to parse the message catalog '%s'", name)
+		}
+		messages[strings.ToLower(strings.TrimSuffix(name, ".yaml"))] = languageMessages
+	}
+	if _, found := messages[DefaultLanguage]; !found {
+		return nil, errs.Errorf("the message catalog of the default language '%s' is missing", DefaultLanguage)
+	}
+	return NewCatalog(messages), nil
+}
+
+var catalog *Catalog
+
+func init() {
+	var err error
+	catalog, err = LoadCatalog()
+	if err != nil {
+		panic(err)
+	}
+}
+
+// Languages returns the supported languages
+func (c *Catalog) Languages() []string {
+	languages := make([]string, 0, len(c.messages))
+	for language := range c.messages {
+		languages = append(languages, language)
+	}
+	sort.Strings(languages)
+	return languages
+}
+
+var placeholder = regexp.MustCompile(`\{(\w+)\}`)
+
+// Message returns the message of the given key in the given language, with its placeholders replaced by the given parameters.
+// The message of the default language is used if the message is missing in the given language, and the key itself
+// if the message is missing in the default language too.
+func (c *Catalog) Message(language, key string, params map[string]interface{}) string {
+	message, found := c.messages[language][key]
+	if !found {
+		message, found = c.messages[DefaultLanguage][key]
+		if !found {
+			return key
+		}
+	}
+	return placeholder.ReplaceAllStringFunc(message, func(p string) string {
+		if value, found := params[p[1:len(p)-1]]; found {
+			return fmt.Sprint(value)
+		}
+		return p
+	})
+}
+
+// Language returns the supported language which best matches the given value of an 'Accept-Language' header,
+// or the default language if none does.
+func (c *Catalog) Language(acceptLanguage string) string {
+	best := DefaultLanguage
+	var bestQuality float64
+	for _, value := range strings.Split(acceptLanguage, ",") {
+		parts := strings.Split(value, ";")
+		tag := strings.ToLower(strings.TrimSpace(parts[0]))
+		quality := 1.0
+		for _, param := range parts[1:] {
+			param = strings.TrimSpace(param)
+			if strings.HasPrefix(param, "q=") {
+				q, err := strconv.ParseFloat(strings.TrimPrefix(param, "q="), 64)
+				if err != nil {
+					q = 0
+				}
+				quality = q
+			}
+		}
+		if quality <= bestQuality {
+			continue
+		}
+		if language, found := c.lookup(tag); found {
+			best = language
+			bestQuality = quality
+		}
+	}
+	return best
+}
+
+// lookup returns the supported language of the given tag, which may be the primary language of the tag, e.g. 'fr' for 'fr-ca'
+func (c *Catalog) lookup(tag string) (string, bool) {
+	if _, found := c.messages[tag]; found {
+		return tag, true
+	}
+	if i := strings.Index(tag, "-"); i > 0 {
+		if _, found := c.messages[tag[:i]]; found {
+			return tag[:i], true
+		}
+	}
+	return "", false
+}
+
+// Language returns the language of the request of the given context
+func Language(ctx context.Context) string {
+	if ctx == nil {
+		return DefaultLanguage
+	}
+	req := goa.ContextRequest(ctx)
+	if req == nil || req.Request == nil {
+		return DefaultLanguage
+	}
+	return catalog.Language(req.Header.Get("Accept-Language"))
+}
+
+// Message returns the message of the given key in the language of the request of the given context
+func Message(ctx context.Context, key string, params map[string]interface{}) string {
+	return catalog.Message(Language(ctx), key, params)
+}
+
+// ErrorMessage returns the message of the given error in the language of the request of the given context
+// if the error is Localizable, or its message as is otherwise
+func ErrorMessage(ctx context.Context, err error) string {
+	if e, ok := err.(Localizable); ok {
+		return Message(ctx, e.MessageKey(), e.MessageParams())
+	}
+	return err.Error()
+}
@@ -0,0 +1,117 @@
+package i18n_test
+
+import (
+	"context"
+	"net/http/httptest"
+	"sort"
+	"testing"
+
+	"github.com/fabric8-services/fabric8-auth/errors"
+	"github.com/fabric8-services/fabric8-auth/i18n"
+	"github.com/fabric8-services/fabric8-auth/resource"
+
+	"github.com/goadesign/goa"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v2"
+)
+
+func TestLanguage(t *testing.T) {
+	t.Parallel()
+	resource.Require(t, resource.UnitTest)
+	catalog, err := i18n.LoadCatalog()
+	require.NoError(t, err)
+
+	for acceptLanguage, expected := range map[string]string{
+		"":                          "en",
+		"*":                         "en",
+		"de":                        "en",
+		"fr":                        "fr",
+		"FR-ca":                     "fr",
+		"de, fr;q=0.8, en;q=0.5":    "fr",
+		"en;q=0.5, fr;q=0.9":        "fr",
+		"en, fr":                    "en",
+		"fr;q=0":                    "en",
+		"fr;q=invalid, en;q=0.1":    "en",
+		" fr-FR ; q=0.7 , de;q=0.9": "fr",
+	} {
+		assert.Equal(t, expected, catalog.Language(acceptLanguage), "Accept-Language: '%s'", acceptLanguage)
+	}
+}
+
+func TestMessage(t *testing.T) {
+	t.Parallel()
+	resource.Require(t, resource.UnitTest)
+	catalog := i18n.NewCatalog(map[string]map[string]string{
+		"en": {
+			"greeting": "Hello {name}, {unknown}",
+			"farewell": "Goodbye",
+		},
+		"fr": {
+			"greeting": "Bonjour {name}",
+		},
+	})
+
+	t.Run("translated", func(t *testing.T) {
+		assert.Equal(t, "Bonjour John", catalog.Message("fr", "greeting", map[string]interface{}{"name": "John"}))
+	})
+
+	t.Run("missing placeholders kept", func(t *testing.T) {
+		assert.Equal(t, "Hello John, {unknown}", catalog.Message("en", "greeting", map[string]interface{}{"name": "John"}))
+	})
+
+	t.Run("missing message in the default language", func(t *testing.T) {
+		assert.Equal(t, "Goodbye", catalog.Message("fr", "farewell", nil))
+	})
+
+	t.Run("missing message", func(t *testing.T) {
+		assert.Equal(t, "unknown", catalog.Message("fr", "unknown", nil))
+	})
+}
+
+func TestCatalogFilesHaveTheSameMessages(t *testing.T) {
+	t.Parallel()
+	resource.Require(t, resource.UnitTest)
+	catalog, err := i18n.LoadCatalog()
+	require.NoError(t, err)
+	require.Equal(t, []string{"en", "fr"}, catalog.Languages())
+
+	// the messages missing from a catalog file would silently be returned in the default language
+	keys := func(name string) []string {
+		data, err := i18n.Asset(name)
+		require.NoError(t, err)
+		messages := map[string]string{}
+		require.NoError(t, yaml.Unmarshal(data, &messages))
+		result := make([]string, 0, len(messages))
+		for key := range messages {
+			result = append(result, key)
+		}
+		sort.Strings(result)
+		return result
+	}
+	for _, name := range i18n.AssetNames() {
+		assert.Equal(t, keys(i18n.DefaultLanguage+".yaml"), keys(name), "messages of '%s'", name)
+	}
+}
+
+func TestErrorMessage(t *testing.T) {
+	t.Parallel()
+	resource.Require(t, resource.UnitTest)
+	req := httptest.NewRequest("GET", "/api/foo", nil)
+	req.Header.Set("Accept-Language", "fr-FR, en;q=0.8")
+	ctx := goa.NewContext(context.Background(), httptest.NewRecorder(), req, nil)
+
+	t.Run("localizable error", func(t *testing.T) {
+		assert.Equal(t, "identity avec l'identifiant '42' introuvable", i18n.ErrorMessage(ctx, errors.NewNotFoundError("identity", "42")))
+		assert.Equal(t, "Valeur invalide pour le paramètre 'email' : 'foo' (attendu : 'valid email')", i18n.ErrorMessage(ctx, errors.NewBadParameterError("email", "foo").Expected("valid email")))
+	})
+
+	t.Run("other error", func(t *testing.T) {
+		assert.Equal(t, "foo", i18n.ErrorMessage(ctx, errors.NewUnauthorizedError("foo")))
+	})
+
+	t.Run("default language", func(t *testing.T) {
+		assert.Equal(t, "identity with id '42' not found", i18n.ErrorMessage(context.Background(), errors.NewNotFoundError("identity", "42")))
+		assert.Equal(t, "identity with id '42' not found", i18n.ErrorMessage(nil, errors.NewNotFoundError("identity", "42")))
+	})
+}
@@ -0,0 +1,5 @@
+// Package i18n translates the messages returned to the users in their language, taken from the
+// 'Accept-Language' header of their requests. The messages of each language are defined in a
+// catalog file, keyed by error code, and may hold '{name}' placeholders replaced by the parameters
+// of the message.
+package i18n
@@ -0,0 +1,4 @@
+// Package invitation stores the invitations to collaborate on a space, sent by the space
+// collaborators to users identified by email or username. The users don't need to have signed
+// up: the pending invitations are accepted when they first log in.
+package invitation
@@ -0,0 +1,243 @@
+package invitation
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/fabric8-services/fabric8-auth/audit"
+	"github.com/fabric8-services/fabric8-auth/errors"
+	"github.com/fabric8-services/fabric8-auth/gormsupport"
+	"github.com/fabric8-services/fabric8-auth/log"
+	"github.com/fabric8-services/fabric8-auth/outbox"
+	"github.com/fabric8-services/fabric8-auth/tracing"
+
+	"github.com/jinzhu/gorm"
+	errs "github.com/pkg/errors"
+	uuid "github.com/satori/go.uuid"
+)
+
+const (
+	// ResourceTypeSpace is the type of the invitations to collaborate on a space
+	ResourceTypeSpace = "space"
+
+	// RoleCollaborator is the role given to the identity which accepts an invitation to a space
+	RoleCollaborator = "collaborator"
+
+	// StatePending is the state of the invitations which can be accepted, until they expire
+	StatePending = "pending"
+	// StateAccepted is the state of the invitations which have been accepted
+	StateAccepted = "accepted"
+	// StateCancelled is the state of the invitations which have been cancelled before being accepted
+	StateCancelled = "cancelled"
+)
+
+// Invitation describes an invitation to collaborate on a resource, sent to a user by email
+// or username. The user doesn't need to have signed up when the invitation is sent.
+type Invitation struct {
+	gormsupport.Lifecycle
+	ID           uuid.UUID `sql:"type:uuid default uuid_generate_v4()" gorm:"primary_key"` // This is the ID PK field
+	ResourceType string
+	ResourceID   uuid.UUID `sql:"type:uuid"`
+	Role         string
+	InviterID    uuid.UUID `sql:"type:uuid"`
+	Email        *string
+	Username     *string
+	ExpiresAt    time.Time
+	State        string
+	AcceptedBy   *uuid.UUID `sql:"type:uuid"`
+	AcceptedAt   *time.Time
+}
+
+// TableName overrides the table name settings in Gorm to force a specific table name
+// in the database.
+func (m Invitation) TableName() string {
+	return "invitations"
+}
+
+// Expired returns true if the invitation can't be accepted anymore because of its expiry
+func (m Invitation) Expired() bool {
+	return !m.ExpiresAt.After(time.Now())
+}
+
+// SentTo returns true if the invitation was sent to the given email, ignoring the case, or username
+func (m Invitation) SentTo(email string, username string) bool {
+	if m.Email != nil && email != "" && strings.EqualFold(*m.Email, email) {
+		return true
+	}
+	return m.Username != nil && username != "" && *m.Username == username
+}
+
+// GormInvitationRepository is the implementation of the storage interface for Invitation.
+type GormInvitationRepository struct {
+	db *gorm.DB
+}
+
+// NewInvitationRepository creates a new storage type.
+func NewInvitationRepository(db *gorm.DB) *GormInvitationRepository {
+	return &GormInvitationRepository{db: db}
+}
+
+// InvitationRepository represents the storage interface.
+type InvitationRepository interface {
+	Load(ctx context.Context, id uuid.UUID) (*Invitation, error)
+	Lock(ctx context.Context, id uuid.UUID) (*Invitation, error)
+	Create(ctx context.Context, invitation *Invitation) error
+	Save(ctx context.Context, invitation *Invitation) error
+	Query(ctx context.Context, funcs ...func(*gorm.DB) *gorm.DB) ([]Invitation, error)
+}
+
+// TableName overrides the table name settings in Gorm to force a specific table name
+// in the database.
+func (m *GormInvitationRepository) TableName() string {
+	return "invitations"
+}
+
+// CRUD Functions
+
+// Load returns a single Invitation as a Database Model
+func (m *GormInvitationRepository) Load(ctx context.Context, id uuid.UUID) (*Invitation, error) {
+	defer tracing.MeasureSince(ctx, []string{"goa", "db", "invitation", "load"}, time.Now())
+
+	var native Invitation
+	err := m.db.Table(m.TableName()).Where("id = ?", id).Find(&native).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, errors.NewNotFoundError("invitation", id.String())
+	}
+
+	return &native, errs.WithStack(err)
+}
+
+// Lock returns the invitation for the given id and locks it until the end of the current transaction,
+// so an invitation is only accepted or cancelled once.
+func (m *GormInvitationRepository) Lock(ctx context.Context, id uuid.UUID) (*Invitation, error) {
+	defer tracing.MeasureSince(ctx, []string{"goa", "db", "invitation", "lock"}, time.Now())
+
+	var native Invitation
+	err := m.db.Table(m.TableName()).Set("gorm:query_option", "FOR UPDATE").Where("id = ?", id).Find(&native).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, errors.NewNotFoundError("invitation", id.String())
+	}
+
+	return &native, errs.WithStack(err)
+}
+
+// Create creates a new record.
+func (m *GormInvitationRepository) Create(ctx context.Context, model *Invitation) error {
+	defer tracing.MeasureSince(ctx, []string{"goa", "db", "invitation", "create"}, time.Now())
+	if model.ID == uuid.Nil {
+		model.ID = uuid.NewV4()
+	}
+	if model.State == "" {
+		model.State = StatePending
+	}
+	err := m.db.Create(model).Error
+	if err != nil {
+		log.Error(ctx, map[string]interface{}{
+			"invitation_id": model.ID,
+			"err":           err,
+		}, "unable to create the invitation")
+		return errs.WithStack(err)
+	}
+	log.Debug(ctx, map[string]interface{}{
+		"invitation_id": model.ID,
+		"resource_type": model.ResourceType,
+		"resource_id":   model.ResourceID,
+	}, "Invitation created!")
+	return nil
+}
+
+// Save modifies a single record.
+func (m *GormInvitationRepository) Save(ctx context.Context, model *Invitation) error {
+	defer tracing.MeasureSince(ctx, []string{"goa", "db", "invitation", "save"}, time.Now())
+
+	db := m.db.Save(model)
+	if db.Error != nil {
+		log.Error(ctx, map[string]interface{}{
+			"invitation_id": model.ID,
+			"err":           db.Error,
+		}, "unable to update the invitation")
+		return errs.WithStack(db.Error)
+	}
+	log.Debug(ctx, map[string]interface{}{
+		"invitation_id": model.ID,
+		"state":         model.State,
+	}, "Invitation saved!")
+	return nil
+}
+
+// Query expose an open ended Query model
+func (m *GormInvitationRepository) Query(ctx context.Context, funcs ...func(*gorm.DB) *gorm.DB) ([]Invitation, error) {
+	defer tracing.MeasureSince(ctx, []string{"goa", "db", "invitation", "query"}, time.Now())
+	var invitations []Invitation
+	err := m.db.Scopes(funcs...).Table(m.TableName()).Order("created_at").Find(&invitations).Error
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return nil, errs.WithStack(err)
+	}
+	return invitations, nil
+}
+
+// InvitationFilterByResource is a gorm filter for the resource the invitations are about
+func InvitationFilterByResource(resourceType string, resourceID uuid.UUID) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where("resource_type = ? AND resource_id = ?", resourceType, resourceID)
+	}
+}
+
+// InvitationFilterPending is a gorm filter for the pending invitations which are not expired
+func InvitationFilterPending() func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where("state = ? AND expires_at > ?", StatePending, gorm.NowFunc())
+	}
+}
+
+// InvitationFilterByInvitee is a gorm filter for the invitations sent to the given email, ignoring the case, or username.
+// An empty email or username matches no invitation.
+func InvitationFilterByInvitee(email string, username string) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where("(email IS NOT NULL AND ? <> '' AND lower(email) = lower(?)) OR (username IS NOT NULL AND ? <> '' AND username = ?)", email, email, username, username)
+	}
+}
+
+// Accept binds the pending invitation to the given identity and enqueues the addition of the
+// identity to the collaborators of the space in the given outbox. The Keycloak policy of the space
+// is updated by the outbox dispatcher, so the invitation and the policy can't diverge when Keycloak
+// is unavailable. The auth URL is the one the Keycloak endpoints are derived from. The addition is
+// recorded in the given audit trail on behalf of the inviter.
+func Accept(ctx context.Context, invitations InvitationRepository, events outbox.EventRepository, audits audit.EventRepository, invitation *Invitation, identityID uuid.UUID, authURL string) error {
+	if invitation.State != StatePending {
+		return errors.NewBadParameterError("invitation", invitation.ID.String()).Expected("a pending invitation")
+	}
+	if invitation.Expired() {
+		return errors.NewBadParameterError("invitation", invitation.ID.String()).Expected("an invitation which is not expired")
+	}
+	now := time.Now()
+	invitation.State = StateAccepted
+	invitation.AcceptedBy = &identityID
+	invitation.AcceptedAt = &now
+	if err := invitations.Save(ctx, invitation); err != nil {
+		return err
+	}
+	event, err := outbox.NewCollaboratorAddedEvent(identityID, invitation.ResourceID, authURL)
+	if err != nil {
+		return err
+	}
+	if err := events.Create(ctx, event); err != nil {
+		return err
+	}
+	added, err := audit.NewEvent(ctx, audit.EventTypeCollaboratorAdded, &invitation.InviterID, &identityID, audit.ResourceTypeSpace, invitation.ResourceID.String(), map[string]interface{}{
+		"invitation_id": invitation.ID,
+	})
+	if err != nil {
+		return err
+	}
+	if err := audits.Create(ctx, added); err != nil {
+		return err
+	}
+	log.Info(ctx, map[string]interface{}{
+		"invitation_id": invitation.ID,
+		"space_id":      invitation.ResourceID,
+		"identity_id":   identityID,
+	}, "invitation accepted")
+	return nil
+}
@@ -0,0 +1,179 @@
+package invitation_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fabric8-services/fabric8-auth/account"
+	"github.com/fabric8-services/fabric8-auth/audit"
+	"github.com/fabric8-services/fabric8-auth/errors"
+	"github.com/fabric8-services/fabric8-auth/gormtestsupport"
+	"github.com/fabric8-services/fabric8-auth/invitation"
+	"github.com/fabric8-services/fabric8-auth/outbox"
+	"github.com/fabric8-services/fabric8-auth/resource"
+	"github.com/fabric8-services/fabric8-auth/test"
+
+	errs "github.com/pkg/errors"
+	"github.com/satori/go.uuid"
+	"github.com/stretchr/testify/suite"
+)
+
+type invitationBlackBoxTest struct {
+	gormtestsupport.DBTestSuite
+	repo    invitation.InvitationRepository
+	inviter account.Identity
+	invitee account.Identity
+}
+
+func TestRunInvitationBlackBoxTest(t *testing.T) {
+	resource.Require(t, resource.Database)
+	suite.Run(t, &invitationBlackBoxTest{DBTestSuite: gormtestsupport.NewDBTestSuite()})
+}
+
+func (s *invitationBlackBoxTest) SetupTest() {
+	s.DBTestSuite.SetupTest()
+	s.repo = invitation.NewInvitationRepository(s.DB)
+	var err error
+	s.inviter, err = test.CreateTestIdentity(s.DB, "invitation-inviter-"+uuid.NewV4().String(), account.KeycloakIDP)
+	s.Require().NoError(err)
+	s.invitee, err = test.CreateTestIdentity(s.DB, "invitation-invitee-"+uuid.NewV4().String(), account.KeycloakIDP)
+	s.Require().NoError(err)
+}
+
+func (s *invitationBlackBoxTest) createInvitation(spaceID uuid.UUID, email *string, username *string, expiresAt time.Time) *invitation.Invitation {
+	inv := &invitation.Invitation{
+		ResourceType: invitation.ResourceTypeSpace,
+		ResourceID:   spaceID,
+		Role:         invitation.RoleCollaborator,
+		InviterID:    s.inviter.ID,
+		Email:        email,
+		Username:     username,
+		ExpiresAt:    expiresAt,
+	}
+	s.Require().NoError(s.repo.Create(s.Ctx, inv))
+	return inv
+}
+
+func (s *invitationBlackBoxTest) TestCreateAndLoad() {
+	// given
+	email := "invitee-" + uuid.NewV4().String() + "@email.com"
+
+	// when
+	inv := s.createInvitation(uuid.NewV4(), &email, nil, time.Now().Add(time.Hour))
+
+	// then
+	loaded, err := s.repo.Load(s.Ctx, inv.ID)
+	s.Require().NoError(err)
+	s.Equal(invitation.StatePending, loaded.State)
+	s.Require().NotNil(loaded.Email)
+	s.Equal(email, *loaded.Email)
+	s.Nil(loaded.Username)
+	s.Nil(loaded.AcceptedBy)
+	_, err = s.repo.Load(s.Ctx, uuid.NewV4())
+	s.IsType(errors.NotFoundError{}, errs.Cause(err))
+}
+
+func (s *invitationBlackBoxTest) TestLock() {
+	// given
+	inv := s.createInvitation(uuid.NewV4(), nil, &s.invitee.Username, time.Now().Add(time.Hour))
+
+	// when
+	locked, err := s.repo.Lock(s.Ctx, inv.ID)
+
+	// then
+	s.Require().NoError(err)
+	s.Equal(inv.ID, locked.ID)
+	s.Equal(invitation.StatePending, locked.State)
+	_, err = s.repo.Lock(s.Ctx, uuid.NewV4())
+	s.IsType(errors.NotFoundError{}, errs.Cause(err))
+}
+
+func (s *invitationBlackBoxTest) TestQueryPendingByInvitee() {
+	// given
+	spaceID := uuid.NewV4()
+	email := "invitee-" + uuid.NewV4().String() + "@email.com"
+	upperCaseEmail := strings.ToUpper(email)
+	byEmail := s.createInvitation(spaceID, &upperCaseEmail, nil, time.Now().Add(time.Hour))
+	byUsername := s.createInvitation(spaceID, nil, &s.invitee.Username, time.Now().Add(time.Hour))
+	s.createInvitation(spaceID, &email, nil, time.Now().Add(-time.Hour))
+	other := "other-" + uuid.NewV4().String() + "@email.com"
+	s.createInvitation(spaceID, &other, nil, time.Now().Add(time.Hour))
+
+	// when
+	invitations, err := s.repo.Query(s.Ctx, invitation.InvitationFilterPending(), invitation.InvitationFilterByInvitee(email, s.invitee.Username))
+
+	// then the expired invitation and the invitation sent to another user are excluded
+	s.Require().NoError(err)
+	s.Require().Len(invitations, 2)
+	s.Equal(byEmail.ID, invitations[0].ID)
+	s.Equal(byUsername.ID, invitations[1].ID)
+	invitations, err = s.repo.Query(s.Ctx, invitation.InvitationFilterByResource(invitation.ResourceTypeSpace, spaceID), invitation.InvitationFilterPending())
+	s.Require().NoError(err)
+	s.Len(invitations, 3)
+	// an empty email or username matches nothing
+	invitations, err = s.repo.Query(s.Ctx, invitation.InvitationFilterPending(), invitation.InvitationFilterByInvitee("", ""))
+	s.Require().NoError(err)
+	s.Empty(invitations)
+}
+
+func (s *invitationBlackBoxTest) TestAccept() {
+	// given
+	spaceID := uuid.NewV4()
+	inv := s.createInvitation(spaceID, nil, &s.invitee.Username, time.Now().Add(time.Hour))
+
+	// when
+	err := invitation.Accept(s.Ctx, s.repo, s.Application.OutboxEvents(), s.Application.AuditEvents(), inv, s.invitee.ID, "https://auth.openshift.io")
+
+	// then
+	s.Require().NoError(err)
+	loaded, err := s.repo.Load(s.Ctx, inv.ID)
+	s.Require().NoError(err)
+	s.Equal(invitation.StateAccepted, loaded.State)
+	s.Require().NotNil(loaded.AcceptedBy)
+	s.Equal(s.invitee.ID, *loaded.AcceptedBy)
+	s.NotNil(loaded.AcceptedAt)
+	events, err := s.Application.OutboxEvents().Query(s.Ctx, outbox.EventFilterByIdentityID(s.invitee.ID))
+	s.Require().NoError(err)
+	s.Require().Len(events, 1)
+	s.Equal(outbox.EventTypeCollaboratorAdded, events[0].EventType)
+	s.Equal(outbox.TargetKeycloak, events[0].Target)
+	var payload outbox.CollaboratorPayload
+	s.Require().NoError(events[0].DecodePayload(&payload))
+	s.Equal(spaceID, payload.SpaceID)
+	s.Equal("https://auth.openshift.io", payload.AuthURL)
+	// and the inviter is recorded as the one who gave access to the space
+	history, err := s.Application.AuditEvents().Query(audit.EventFilterByResource(audit.ResourceTypeSpace, spaceID.String()))
+	s.Require().NoError(err)
+	s.Require().Len(history, 1)
+	s.Equal(audit.EventTypeCollaboratorAdded, history[0].EventType)
+	s.Equal(s.inviter.ID, *history[0].ActorID)
+	s.Equal(s.invitee.ID, *history[0].TargetID)
+
+	// and the invitation can't be accepted twice
+	err = invitation.Accept(s.Ctx, s.repo, s.Application.OutboxEvents(), s.Application.AuditEvents(), loaded, s.invitee.ID, "https://auth.openshift.io")
+	s.IsType(errors.BadParameterError{}, errs.Cause(err))
+}
+
+func (s *invitationBlackBoxTest) TestAcceptExpired() {
+	// given
+	inv := s.createInvitation(uuid.NewV4(), nil, &s.invitee.Username, time.Now().Add(-time.Minute))
+
+	// when
+	err := invitation.Accept(s.Ctx, s.repo, s.Application.OutboxEvents(), s.Application.AuditEvents(), inv, s.invitee.ID, "https://auth.openshift.io")
+
+	// then
+	s.IsType(errors.BadParameterError{}, errs.Cause(err))
+	events, err := s.Application.OutboxEvents().Query(s.Ctx, outbox.EventFilterByIdentityID(s.invitee.ID))
+	s.Require().NoError(err)
+	s.Empty(events)
+}
+
+func (s *invitationBlackBoxTest) TestSentTo() {
+	email := "Invitee@Email.com"
+	username := "invitee"
+	s.True(invitation.Invitation{Email: &email}.SentTo("invitee@email.com", ""))
+	s.False(invitation.Invitation{Email: &email}.SentTo("", "invitee"))
+	s.True(invitation.Invitation{Username: &username}.SentTo("", "invitee"))
+	s.False(invitation.Invitation{Username: &username}.SentTo("invitee@email.com", "Invitee"))
+}
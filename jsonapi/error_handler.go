@@ -31,6 +31,7 @@ func shortID() string {
 // them, it turns other Go error types into a 500 internal error response.
 // If verbose is false the details of internal errors is not included in HTTP responses.
 // If you use github.com/pkg/errors then wrapping the error will allow a trace to be printed to the logs
+// The error is returned as RFC 7807 problem details instead if the client asked for them in the 'Accept' header.
 func ErrorHandler(service *goa.Service, verbose bool) goa.Middleware {
 	return func(h goa.Handler) goa.Handler {
 		return func(ctx context.Context, rw http.ResponseWriter, req *http.Request) error {
@@ -41,6 +42,8 @@ func ErrorHandler(service *goa.Service, verbose bool) goa.Middleware {
 			cause := errs.Cause(e)
 			status := http.StatusInternalServerError
 			var respBody interface{}
+			// the error returned to the client, which hides the internal errors if verbose is false
+			respErr := e
 			respBody, status = ErrorToJSONAPIErrors(ctx, e)
 			rw.Header().Set("Content-Type", ErrorMediaIdentifier)
 			if err, ok := cause.(goa.ServiceError); ok {
@@ -68,6 +71,7 @@ func ErrorHandler(service *goa.Service, verbose bool) goa.Middleware {
 				if !verbose {
 					rw.Header().Set("Content-Type", goa.ErrorMediaIdentifier)
 					msg := errors.NewInternalError(ctx, errs.Errorf("%s [%s]", http.StatusText(http.StatusInternalServerError), reqID))
+					respErr = msg
 					//respBody = goa.ErrInternal(msg)
 					respBody, status = ErrorToJSONAPIErrors(ctx, msg)
 					// Preserve the ID of the original error as that's what gets logged, the client
@@ -78,6 +82,11 @@ func ErrorHandler(service *goa.Service, verbose bool) goa.Middleware {
 					}
 				}
 			}
+			if acceptsProblem(ctx) {
+				problem := ErrorToProblem(ctx, respErr)
+				problem.Status = status
+				return writeProblem(rw, problem)
+			}
 			return service.Send(ctx, status, respBody)
 		}
 	}
@@ -7,6 +7,7 @@ import (
 
 	"github.com/fabric8-services/fabric8-auth/app"
 	"github.com/fabric8-services/fabric8-auth/errors"
+	"github.com/fabric8-services/fabric8-auth/i18n"
 	"github.com/fabric8-services/fabric8-auth/log"
 
 	"github.com/goadesign/goa"
@@ -23,15 +24,17 @@ const (
 	ErrorCodeUnauthorizedError = "unauthorized_error"
 	ErrorCodeForbiddenError    = "forbidden_error"
 	ErrorCodeJWTSecurityError  = "jwt_security_error"
+	ErrorCodeTooManyRequests   = "too_many_requests"
 )
 
 // ErrorToJSONAPIError returns the JSONAPI representation
 // of an error and the HTTP status code that will be associated with it.
 // This function knows about the models package and the errors from there
 // as well as goa error classes.
+// The title and detail of the error are translated in the language of the request, if supported.
 func ErrorToJSONAPIError(ctx context.Context, err error) (app.JSONAPIError, int) {
 	cause := errs.Cause(err)
-	detail := cause.Error()
+	detail := i18n.ErrorMessage(ctx, cause)
 	var title, code string
 	var statusCode int
 	var id *string
@@ -39,35 +42,30 @@ func ErrorToJSONAPIError(ctx context.Context, err error) (app.JSONAPIError, int)
 	switch cause.(type) {
 	case errors.NotFoundError:
 		code = ErrorCodeNotFound
-		title = "Not found error"
 		statusCode = http.StatusNotFound
 	case errors.ConversionError:
 		code = ErrorCodeConversionError
-		title = "Conversion error"
 		statusCode = http.StatusBadRequest
 	case errors.BadParameterError:
 		code = ErrorCodeBadParameter
-		title = "Bad parameter error"
 		statusCode = http.StatusBadRequest
 	case errors.VersionConflictError:
 		code = ErrorCodeVersionConflict
-		title = "Version conflict error"
 		statusCode = http.StatusConflict
 	case errors.InternalError:
 		code = ErrorCodeInternalError
-		title = "Internal error"
 		statusCode = http.StatusInternalServerError
 	case errors.UnauthorizedError:
 		code = ErrorCodeUnauthorizedError
-		title = "Unauthorized error"
 		statusCode = http.StatusUnauthorized
 	case errors.ForbiddenError:
 		code = ErrorCodeForbiddenError
-		title = "Forbidden error"
 		statusCode = http.StatusForbidden
+	case errors.TooManyRequestsError:
+		code = ErrorCodeTooManyRequests
+		statusCode = http.StatusTooManyRequests
 	default:
 		code = ErrorCodeUnknownError
-		title = "Unknown error"
 		statusCode = http.StatusInternalServerError
 
 		cause := errs.Cause(err)
@@ -82,6 +80,9 @@ func ErrorToJSONAPIError(ctx context.Context, err error) (app.JSONAPIError, int)
 			detail = errResp.Detail
 		}
 	}
+	if title == "" {
+		title = i18n.Message(ctx, code, nil)
+	}
 	statusCodeStr := strconv.Itoa(statusCode)
 	jerr := app.JSONAPIError{
 		ID:     id,
@@ -133,12 +134,25 @@ type Conflict interface {
 	Conflict(*app.JSONAPIErrors) error
 }
 
+// PreconditionFailed represent a Context that can return a PreconditionFailed HTTP status
+type PreconditionFailed interface {
+	PreconditionFailed(*app.JSONAPIErrors) error
+}
+
 // JSONErrorResponse auto maps the provided error to the correct response type
 // If all else fails, InternalServerError is returned
+// The error is returned as RFC 7807 problem details instead if the client asked for them in the 'Accept' header.
 func JSONErrorResponse(obj interface{}, err error) error {
 	x := obj.(InternalServerError)
 	c := obj.(context.Context)
 
+	if acceptsProblem(c) {
+		problem := ErrorToProblem(c, err)
+		if _, ok := x.(PreconditionFailed); ok && problem.Status == http.StatusConflict {
+			problem.Status = http.StatusPreconditionFailed
+		}
+		return errs.WithStack(writeProblem(goa.ContextResponse(c), problem))
+	}
 	jsonErr, status := ErrorToJSONAPIErrors(c, err)
 	switch status {
 	case http.StatusBadRequest:
@@ -158,6 +172,14 @@ func JSONErrorResponse(obj interface{}, err error) error {
 			return errs.WithStack(ctx.Forbidden(jsonErr))
 		}
 	case http.StatusConflict:
+		// the conditional writes report the version conflicts with the 'If-Match' and 'If-Unmodified-Since' headers as failed preconditions
+		if ctx, ok := x.(PreconditionFailed); ok {
+			status := strconv.Itoa(http.StatusPreconditionFailed)
+			for _, e := range jsonErr.Errors {
+				e.Status = &status
+			}
+			return errs.WithStack(ctx.PreconditionFailed(jsonErr))
+		}
 		if ctx, ok := x.(Conflict); ok {
 			return errs.WithStack(ctx.Conflict(jsonErr))
 		}
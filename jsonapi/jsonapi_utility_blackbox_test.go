@@ -4,13 +4,17 @@ import (
 	"context"
 	"fmt"
 	"net/http"
+	"net/http/httptest"
 	"strconv"
 	"testing"
+	"time"
 
 	"github.com/fabric8-services/fabric8-auth/app"
 	"github.com/fabric8-services/fabric8-auth/errors"
 	"github.com/fabric8-services/fabric8-auth/jsonapi"
 	"github.com/fabric8-services/fabric8-auth/resource"
+
+	"github.com/goadesign/goa"
 	errs "github.com/pkg/errors"
 	"github.com/stretchr/testify/require"
 )
@@ -70,6 +74,14 @@ func TestErrorToJSONAPIError(t *testing.T) {
 	require.Equal(t, jsonapi.ErrorCodeForbiddenError, *jerr.Code)
 	require.Equal(t, strconv.Itoa(httpStatus), *jerr.Status)
 
+	// test too many requests error
+	jerr, httpStatus = jsonapi.ErrorToJSONAPIError(nil, errors.NewTooManyRequestsError("foo", time.Minute))
+	require.Equal(t, http.StatusTooManyRequests, httpStatus)
+	require.NotNil(t, jerr.Code)
+	require.NotNil(t, jerr.Status)
+	require.Equal(t, jsonapi.ErrorCodeTooManyRequests, *jerr.Code)
+	require.Equal(t, strconv.Itoa(httpStatus), *jerr.Status)
+
 	// test unspecified error
 	jerr, httpStatus = jsonapi.ErrorToJSONAPIError(nil, fmt.Errorf("foobar"))
 	require.Equal(t, http.StatusInternalServerError, httpStatus)
@@ -78,3 +90,23 @@ func TestErrorToJSONAPIError(t *testing.T) {
 	require.Equal(t, jsonapi.ErrorCodeUnknownError, *jerr.Code)
 	require.Equal(t, strconv.Itoa(httpStatus), *jerr.Status)
 }
+
+func TestErrorToJSONAPIErrorLocalized(t *testing.T) {
+	t.Parallel()
+	resource.Require(t, resource.UnitTest)
+	req := httptest.NewRequest("GET", "/api/foo", nil)
+	req.Header.Set("Accept-Language", "fr")
+	ctx := goa.NewContext(context.Background(), httptest.NewRecorder(), req, nil)
+
+	// test localized not found error
+	jerr, httpStatus := jsonapi.ErrorToJSONAPIError(ctx, errors.NewNotFoundError("identity", "42"))
+	require.Equal(t, http.StatusNotFound, httpStatus)
+	require.Equal(t, jsonapi.ErrorCodeNotFound, *jerr.Code)
+	require.Equal(t, "Introuvable", *jerr.Title)
+	require.Equal(t, "identity avec l'identifiant '42' introuvable", jerr.Detail)
+
+	// test default language
+	jerr, _ = jsonapi.ErrorToJSONAPIError(nil, errors.NewNotFoundError("identity", "42"))
+	require.Equal(t, "Not found error", *jerr.Title)
+	require.Equal(t, "identity with id '42' not found", jerr.Detail)
+}
@@ -0,0 +1,112 @@
+package jsonapi
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/fabric8-services/fabric8-auth/errors"
+
+	"github.com/goadesign/goa"
+	"github.com/goadesign/goa/middleware"
+	errs "github.com/pkg/errors"
+)
+
+const (
+	// ProblemMediaIdentifier is the media type of the RFC 7807 problem details, returned instead of the
+	// JSON-API errors when the client asks for it in the 'Accept' header of the request
+	ProblemMediaIdentifier = "application/problem+json"
+	// ProblemTypeBaseURI is the base of the URIs of the problem types, which are followed by the error code
+	ProblemTypeBaseURI = "https://fabric8-services.github.io/fabric8-auth/reference.html#problem-"
+)
+
+// Problem is the RFC 7807 representation of an error
+type Problem struct {
+	Type          string         `json:"type"`
+	Title         string         `json:"title"`
+	Status        int            `json:"status"`
+	Detail        string         `json:"detail,omitempty"`
+	Instance      string         `json:"instance,omitempty"`
+	Code          string         `json:"code"`
+	RequestID     string         `json:"request-id,omitempty"`
+	InvalidParams []InvalidParam `json:"invalid-params,omitempty"`
+}
+
+// InvalidParam is a parameter of the request which failed the validation
+type InvalidParam struct {
+	Name   string `json:"name"`
+	Reason string `json:"reason"`
+}
+
+// ErrorToProblem returns the RFC 7807 representation of an error. The type, title, status and detail
+// of the problem are the same as the ones of the JSON-API representation of the error.
+func ErrorToProblem(ctx context.Context, err error) Problem {
+	jerr, status := ErrorToJSONAPIError(ctx, err)
+	problem := Problem{
+		Type:   ProblemTypeBaseURI + *jerr.Code,
+		Title:  *jerr.Title,
+		Status: status,
+		Detail: jerr.Detail,
+		Code:   *jerr.Code,
+	}
+	if ctx != nil {
+		problem.RequestID = middleware.ContextRequestID(ctx)
+		if req := goa.ContextRequest(ctx); req != nil && req.Request != nil {
+			problem.Instance = req.URL.Path
+		}
+	}
+	switch cause := errs.Cause(err).(type) {
+	case errors.BadParameterError:
+		problem.InvalidParams = []InvalidParam{{Name: cause.Parameter(), Reason: problem.Detail}}
+	case *goa.ErrorResponse:
+		// the validation errors of goa hold the name of the invalid parameter or attribute in their metadata
+		for _, key := range []string{"param", "attribute"} {
+			if name, ok := cause.Meta[key].(string); ok {
+				problem.InvalidParams = []InvalidParam{{Name: name, Reason: cause.Detail}}
+				break
+			}
+		}
+	}
+	return problem
+}
+
+// acceptsProblem returns true if the 'Accept' header of the request prefers the RFC 7807 problem details
+// to the JSON-API errors, which remain the default
+func acceptsProblem(ctx context.Context) bool {
+	req := goa.ContextRequest(ctx)
+	if req == nil || req.Request == nil {
+		return false
+	}
+	var problemQuality, jsonAPIQuality float64
+	for _, accept := range strings.Split(req.Header.Get("Accept"), ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(accept))
+		if err != nil {
+			continue
+		}
+		quality := 1.0
+		if q, found := params["q"]; found {
+			quality, err = strconv.ParseFloat(q, 64)
+			if err != nil {
+				continue
+			}
+		}
+		switch mediaType {
+		case ProblemMediaIdentifier:
+			problemQuality = math.Max(problemQuality, quality)
+		case ErrorMediaIdentifier, "application/json":
+			jsonAPIQuality = math.Max(jsonAPIQuality, quality)
+		}
+	}
+	return problemQuality > 0 && problemQuality >= jsonAPIQuality
+}
+
+// writeProblem writes the problem in the response
+func writeProblem(rw http.ResponseWriter, problem Problem) error {
+	rw.Header().Set("Content-Type", ProblemMediaIdentifier)
+	rw.WriteHeader(problem.Status)
+	return json.NewEncoder(rw).Encode(problem)
+}
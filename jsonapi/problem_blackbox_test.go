@@ -0,0 +1,145 @@
+package jsonapi_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/fabric8-services/fabric8-auth/app"
+	"github.com/fabric8-services/fabric8-auth/errors"
+	"github.com/fabric8-services/fabric8-auth/jsonapi"
+	"github.com/fabric8-services/fabric8-auth/resource"
+
+	"github.com/goadesign/goa"
+	errs "github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestErrorToProblem(t *testing.T) {
+	t.Parallel()
+	resource.Require(t, resource.UnitTest)
+
+	t.Run("not found", func(t *testing.T) {
+		problem := jsonapi.ErrorToProblem(nil, errors.NewNotFoundError("foo", "bar"))
+		assert.Equal(t, jsonapi.ProblemTypeBaseURI+jsonapi.ErrorCodeNotFound, problem.Type)
+		assert.Equal(t, http.StatusNotFound, problem.Status)
+		assert.Equal(t, jsonapi.ErrorCodeNotFound, problem.Code)
+		assert.Equal(t, "foo with id 'bar' not found", problem.Detail)
+		assert.Empty(t, problem.InvalidParams)
+	})
+
+	t.Run("bad parameter", func(t *testing.T) {
+		problem := jsonapi.ErrorToProblem(nil, errs.Wrap(errors.NewBadParameterError("email", "foo").Expected("valid email"), "invalid user"))
+		assert.Equal(t, jsonapi.ProblemTypeBaseURI+jsonapi.ErrorCodeBadParameter, problem.Type)
+		assert.Equal(t, http.StatusBadRequest, problem.Status)
+		require.Len(t, problem.InvalidParams, 1)
+		assert.Equal(t, "email", problem.InvalidParams[0].Name)
+		assert.Equal(t, "Bad value for parameter 'email': 'foo' (expected: 'valid email')", problem.InvalidParams[0].Reason)
+	})
+
+	t.Run("version conflict", func(t *testing.T) {
+		problem := jsonapi.ErrorToProblem(nil, errors.NewVersionConflictError("foo"))
+		assert.Equal(t, http.StatusConflict, problem.Status)
+		assert.Equal(t, jsonapi.ErrorCodeVersionConflict, problem.Code)
+	})
+
+	t.Run("unauthorized", func(t *testing.T) {
+		problem := jsonapi.ErrorToProblem(nil, errors.NewUnauthorizedError("foo"))
+		assert.Equal(t, http.StatusUnauthorized, problem.Status)
+		assert.Equal(t, jsonapi.ErrorCodeUnauthorizedError, problem.Code)
+	})
+
+	t.Run("forbidden", func(t *testing.T) {
+		problem := jsonapi.ErrorToProblem(nil, errors.NewForbiddenError("foo"))
+		assert.Equal(t, http.StatusForbidden, problem.Status)
+		assert.Equal(t, jsonapi.ErrorCodeForbiddenError, problem.Code)
+	})
+
+	t.Run("internal", func(t *testing.T) {
+		problem := jsonapi.ErrorToProblem(nil, errors.NewInternalError(context.Background(), errs.New("foo")))
+		assert.Equal(t, http.StatusInternalServerError, problem.Status)
+		assert.Equal(t, jsonapi.ErrorCodeInternalError, problem.Code)
+	})
+}
+
+// errorContext a context which can only return an InternalServerError with JSON-API errors
+type errorContext struct {
+	context.Context
+	jsonAPIErrors *app.JSONAPIErrors
+}
+
+func (ctx *errorContext) InternalServerError(jerrors *app.JSONAPIErrors) error {
+	ctx.jsonAPIErrors = jerrors
+	return nil
+}
+
+func newErrorContext(accept string) (*errorContext, *httptest.ResponseRecorder) {
+	rw := httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "/api/foo?bar=baz", nil)
+	req.Header.Set("Accept", accept)
+	return &errorContext{Context: goa.NewContext(context.Background(), rw, req, nil)}, rw
+}
+
+func TestJSONErrorResponseWithProblem(t *testing.T) {
+	t.Parallel()
+	resource.Require(t, resource.UnitTest)
+
+	t.Run("problem accepted", func(t *testing.T) {
+		for _, accept := range []string{"application/problem+json", "application/vnd.api+json;q=0.5, application/problem+json"} {
+			// given
+			ctx, rw := newErrorContext(accept)
+			// when
+			err := jsonapi.JSONErrorResponse(ctx, errors.NewInternalError(ctx, errs.New("foo")))
+			// then
+			require.NoError(t, err)
+			assert.Nil(t, ctx.jsonAPIErrors)
+			assert.Equal(t, http.StatusInternalServerError, rw.Code)
+			assert.Equal(t, jsonapi.ProblemMediaIdentifier, rw.Header().Get("Content-Type"))
+			var problem jsonapi.Problem
+			require.NoError(t, json.Unmarshal(rw.Body.Bytes(), &problem))
+			assert.Equal(t, jsonapi.ErrorCodeInternalError, problem.Code)
+			assert.Equal(t, "/api/foo", problem.Instance)
+		}
+	})
+
+	t.Run("json-api accepted", func(t *testing.T) {
+		for _, accept := range []string{"", "*/*", "application/vnd.api+json", "application/problem+json;q=0.5, application/json"} {
+			// given
+			ctx, _ := newErrorContext(accept)
+			// when
+			err := jsonapi.JSONErrorResponse(ctx, errors.NewInternalError(ctx, errs.New("foo")))
+			// then
+			require.NoError(t, err)
+			require.NotNil(t, ctx.jsonAPIErrors)
+			assert.Equal(t, jsonapi.ErrorCodeInternalError, *ctx.jsonAPIErrors.Errors[0].Code)
+		}
+	})
+}
+
+func TestErrorHandlerWithProblem(t *testing.T) {
+	t.Parallel()
+	resource.Require(t, resource.UnitTest)
+	// given
+	service := goa.New("test")
+	handler := jsonapi.ErrorHandler(service, true)(func(ctx context.Context, rw http.ResponseWriter, req *http.Request) error {
+		return errors.NewBadParameterError("page[limit]", "foo")
+	})
+	rw := httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "/api/foo", nil)
+	req.Header.Set("Accept", "application/problem+json")
+	ctx := goa.NewContext(context.Background(), rw, req, nil)
+	// when
+	err := handler(ctx, rw, req)
+	// then
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusBadRequest, rw.Code)
+	assert.Equal(t, jsonapi.ProblemMediaIdentifier, rw.Header().Get("Content-Type"))
+	var problem jsonapi.Problem
+	require.NoError(t, json.Unmarshal(rw.Body.Bytes(), &problem))
+	assert.Equal(t, jsonapi.ProblemTypeBaseURI+jsonapi.ErrorCodeBadParameter, problem.Type)
+	require.Len(t, problem.InvalidParams, 1)
+	assert.Equal(t, "page[limit]", problem.InvalidParams[0].Name)
+}
@@ -2,7 +2,6 @@ package log
 
 import (
 	"context"
-	"encoding/json"
 	"errors"
 	"os"
 	"runtime"
@@ -53,6 +52,18 @@ func InitializeLogger(logJSON bool, lvl string) {
 	logger.Out = os.Stdout
 }
 
+// SetLogLevel changes the log level of the logger at runtime, e.g. when the configuration is reloaded.
+// The current log level is kept if the given one can't be parsed.
+func SetLogLevel(lvl string) {
+	logLevel, err := log.ParseLevel(lvl)
+	if err != nil {
+		log.Warnf("unable to parse log level configuration error: %q", err)
+		return
+	}
+	log.SetLevel(logLevel)
+	logger.Level = logLevel
+}
+
 // NewCustomizedLogger creates a custom logger specifying the desired log level
 // and the log format flag. Returns the logger object and the error.
 func NewCustomizedLogger(level string, logJSON bool) (*log.Logger, error) {
@@ -127,38 +138,22 @@ func Error(ctx context.Context, fields map[string]interface{}, format string, ar
 			if req := goa.ContextRequest(ctx); req != nil {
 				// Let's log some request details
 				if len(req.Header) > 0 {
-					headers := make(map[string]interface{}, len(req.Header))
-					for k, v := range req.Header {
-						// Hide sensitive information
-						if k == "Authorization" || k == "Cookie" {
-							headers[string(k)] = "*****"
-						} else {
-							headers[string(k)] = v
-						}
-					}
-					entry = entry.WithField("req_headers", headers)
+					// Hide sensitive information
+					entry = entry.WithField("req_headers", RedactHeaders(req.Header))
 				}
 				if len(req.Params) > 0 {
-					entry = entry.WithField("req_params", req.Params)
+					entry = entry.WithFields(Redact(map[string]interface{}{"req_params": req.Params}))
 				}
 				if req.ContentLength > 0 {
-					if mp, ok := req.Payload.(map[string]interface{}); ok && mp != nil {
-						entry = entry.WithField("req_payload", mp)
-					} else {
-						js, err := json.Marshal(req.Payload)
-						if err != nil {
-							js = []byte("<invalid JSON>")
-						}
-						entry = entry.WithField("req_payload", string(js))
-					}
+					entry = entry.WithField("req_payload", RedactPayload(req.Payload))
 				}
 			}
 		}
 
 		if len(args) > 0 {
-			entry.WithFields(fields).Errorf(format, args)
+			entry.WithFields(Redact(fields)).Errorf(format, args)
 		} else {
-			entry.WithFields(fields).Errorln(format)
+			entry.WithFields(Redact(fields)).Errorln(format)
 		}
 	}
 }
@@ -187,9 +182,9 @@ func Warn(ctx context.Context, fields map[string]interface{}, format string, arg
 		}
 
 		if len(args) > 0 {
-			entry.WithFields(fields).Warnf(format, args...)
+			entry.WithFields(Redact(fields)).Warnf(format, args...)
 		} else {
-			entry.WithFields(fields).Warnln(format)
+			entry.WithFields(Redact(fields)).Warnln(format)
 		}
 	}
 }
@@ -216,9 +211,9 @@ func Info(ctx context.Context, fields map[string]interface{}, format string, arg
 		}
 
 		if len(args) > 0 {
-			entry.WithFields(fields).Infof(format, args...)
+			entry.WithFields(Redact(fields)).Infof(format, args...)
 		} else {
-			entry.WithFields(fields).Infoln(format)
+			entry.WithFields(Redact(fields)).Infoln(format)
 		}
 	}
 }
@@ -241,9 +236,9 @@ func Panic(ctx context.Context, fields map[string]interface{}, format string, ar
 		}
 
 		if len(args) > 0 {
-			entry.WithFields(fields).Panicf(format, args)
+			entry.WithFields(Redact(fields)).Panicf(format, args)
 		} else {
-			entry.WithFields(fields).Panicln(format)
+			entry.WithFields(Redact(fields)).Panicln(format)
 		}
 	}
 }
@@ -270,9 +265,9 @@ func Debug(ctx context.Context, fields map[string]interface{}, format string, ar
 		}
 
 		if len(args) > 0 {
-			entry.WithFields(fields).Debugf(format, args...)
+			entry.WithFields(Redact(fields)).Debugf(format, args...)
 		} else {
-			entry.WithFields(fields).Debugln(format)
+			entry.WithFields(Redact(fields)).Debugln(format)
 		}
 	}
 }
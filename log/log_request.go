@@ -3,7 +3,6 @@ package log
 import (
 	"crypto/rand"
 	"encoding/base64"
-	"encoding/json"
 	"io"
 	"net"
 	"net/http"
@@ -53,11 +52,7 @@ func LogRequest(verbose bool) goa.Middleware {
 
 			if verbose {
 				if len(r.Header) > 0 {
-					properties := make(map[string]interface{}, len(r.Header))
-					for k, v := range r.Header {
-						properties[string(k)] = v
-					}
-					Info(ctx, properties, "request headers")
+					Info(ctx, RedactHeaders(r.Header), "request headers")
 				}
 				if len(r.Params) > 0 {
 					properties := make(map[string]interface{}, len(r.Params))
@@ -67,19 +62,12 @@ func LogRequest(verbose bool) goa.Middleware {
 					Info(ctx, properties, "request params")
 				}
 				if r.ContentLength > 0 {
-					if mp, ok := r.Payload.(map[string]interface{}); ok {
-						properties := make(map[string]interface{}, len(mp))
-						for k, v := range mp {
-							properties[string(k)] = v
-						}
-						Info(ctx, properties, "request payload")
+					// Not the most efficient but this is used for debugging
+					payload := RedactPayload(r.Payload)
+					if mp, ok := payload.(map[string]interface{}); ok {
+						Info(ctx, mp, "request payload")
 					} else {
-						// Not the most efficient but this is used for debugging
-						js, err := json.Marshal(r.Payload)
-						if err != nil {
-							js = []byte("<invalid JSON>")
-						}
-						Info(ctx, map[string]interface{}{"raw": string(js)}, "payload")
+						Info(ctx, map[string]interface{}{"raw": payload}, "payload")
 					}
 				}
 			}
@@ -0,0 +1,203 @@
+package log
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// RedactedValue replaces the sensitive values in the logs
+const RedactedValue = "*****"
+
+// DefaultSensitiveFields are the names of the log fields and request params/payload attributes
+// which are always redacted. A field is also redacted if its name ends with "_" and one of these names,
+// e.g. "client_secret" or "access_token".
+var DefaultSensitiveFields = []string{"password", "secret", "token", "authorization", "cookie"}
+
+// DefaultSensitiveHeaders are the names of the request headers which are always redacted
+var DefaultSensitiveHeaders = []string{"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie", "X-Vault-Token"}
+
+var (
+	// JWTs, whatever the field they are logged in
+	jwtPattern = regexp.MustCompile(`eyJ[A-Za-z0-9_-]*\.[A-Za-z0-9_-]+\.[A-Za-z0-9_-]*`)
+	// credentials of Authorization headers dumped in a message, e.g. "Bearer abc"
+	authorizationPattern = regexp.MustCompile(`(?i)\b(bearer|basic)\s+[^\s,"']+`)
+	// secrets sent as query or form parameters, e.g. "client_secret=abc&..."
+	secretParamPattern = regexp.MustCompile(`(?i)\b((?:access_token|refresh_token|id_token|client_secret|password|secret|token|code)=)[^&\s"']+`)
+)
+
+type redactor struct {
+	fields  map[string]bool
+	headers map[string]bool
+}
+
+var (
+	sensitiveData     = newRedactor(nil, nil)
+	sensitiveDataLock = &sync.RWMutex{}
+)
+
+func newRedactor(fields []string, headers []string) *redactor {
+	r := &redactor{
+		fields:  map[string]bool{},
+		headers: map[string]bool{},
+	}
+	for _, name := range append(DefaultSensitiveFields, fields...) {
+		r.fields[normalizeName(name)] = true
+	}
+	for _, name := range append(DefaultSensitiveHeaders, headers...) {
+		r.headers[http.CanonicalHeaderKey(name)] = true
+	}
+	return r
+}
+
+// ConfigureRedaction sets the names of the fields and headers which are redacted in addition to
+// DefaultSensitiveFields and DefaultSensitiveHeaders. Field names are case insensitive
+// and "-" matches "_".
+func ConfigureRedaction(fields []string, headers []string) {
+	r := newRedactor(fields, headers)
+	sensitiveDataLock.Lock()
+	defer sensitiveDataLock.Unlock()
+	sensitiveData = r
+}
+
+func currentRedactor() *redactor {
+	sensitiveDataLock.RLock()
+	defer sensitiveDataLock.RUnlock()
+	return sensitiveData
+}
+
+// Redact returns a copy of the given log fields where the values of the sensitive fields are replaced
+// by RedactedValue, and the JWTs, Authorization credentials and secret parameters found in the
+// other values are masked. Nested maps and slices are redacted too.
+func Redact(fields map[string]interface{}) map[string]interface{} {
+	if fields == nil {
+		return nil
+	}
+	return currentRedactor().redactFields(fields)
+}
+
+// RedactHeaders returns the given request headers as log fields, with the sensitive headers redacted
+func RedactHeaders(header http.Header) map[string]interface{} {
+	r := currentRedactor()
+	result := make(map[string]interface{}, len(header))
+	for k, v := range header {
+		if r.headers[http.CanonicalHeaderKey(k)] {
+			result[k] = RedactedValue
+		} else {
+			result[k] = r.redactValue(k, v)
+		}
+	}
+	return result
+}
+
+// RedactString masks the JWTs, Authorization credentials and secret parameters found in the given string
+func RedactString(s string) string {
+	s = jwtPattern.ReplaceAllString(s, RedactedValue)
+	s = authorizationPattern.ReplaceAllString(s, "$1 "+RedactedValue)
+	return secretParamPattern.ReplaceAllString(s, "${1}"+RedactedValue)
+}
+
+func (r *redactor) redactFields(fields map[string]interface{}) map[string]interface{} {
+	result := make(map[string]interface{}, len(fields))
+	for k, v := range fields {
+		result[k] = r.redactValue(k, v)
+	}
+	return result
+}
+
+func (r *redactor) redactValue(key string, value interface{}) interface{} {
+	if value == nil {
+		return nil
+	}
+	if r.isSensitiveField(key) {
+		return RedactedValue
+	}
+	switch v := value.(type) {
+	case string:
+		return RedactString(v)
+	case *string:
+		if v == nil {
+			return v
+		}
+		return RedactString(*v)
+	case []string:
+		result := make([]string, len(v))
+		for i, s := range v {
+			result[i] = RedactString(s)
+		}
+		return result
+	case []interface{}:
+		result := make([]interface{}, len(v))
+		for i, e := range v {
+			result[i] = r.redactValue("", e)
+		}
+		return result
+	case map[string]interface{}:
+		return r.redactFields(v)
+	case http.Header:
+		return RedactHeaders(v)
+	case url.Values:
+		return r.redactStringSlices(v)
+	case map[string][]string:
+		return r.redactStringSlices(v)
+	case *url.URL:
+		if v == nil {
+			return v
+		}
+		return RedactString(v.String())
+	case error:
+		msg := v.Error()
+		if redacted := RedactString(msg); redacted != msg {
+			return redacted
+		}
+		return v
+	default:
+		return v
+	}
+}
+
+func (r *redactor) redactStringSlices(values map[string][]string) map[string]interface{} {
+	result := make(map[string]interface{}, len(values))
+	for k, v := range values {
+		result[k] = r.redactValue(k, v)
+	}
+	return result
+}
+
+func (r *redactor) isSensitiveField(name string) bool {
+	if name == "" {
+		return false
+	}
+	n := normalizeName(name)
+	if r.fields[n] {
+		return true
+	}
+	if i := strings.LastIndex(n, "_"); i >= 0 {
+		return r.fields[n[i+1:]]
+	}
+	return false
+}
+
+func normalizeName(name string) string {
+	return strings.Replace(strings.ToLower(name), "-", "_", -1)
+}
+
+// RedactPayload returns the given request payload as log fields, with the sensitive attributes redacted.
+// Payloads which are not JSON objects are returned as a JSON string.
+func RedactPayload(payload interface{}) interface{} {
+	if mp, ok := payload.(map[string]interface{}); ok && mp != nil {
+		return Redact(mp)
+	}
+	js, err := json.Marshal(payload)
+	if err != nil {
+		return "<invalid JSON>"
+	}
+	var mp map[string]interface{}
+	if err := json.Unmarshal(js, &mp); err == nil && mp != nil {
+		return Redact(mp)
+	}
+	return RedactString(string(js))
+}
@@ -54,7 +54,7 @@ func (c *KeycloakIDPServiceClient) Create(ctx context.Context, keycloakLinkIDPRe
 	req.Header.Add("Authorization", "Bearer "+protectedAccessToken)
 	req.Header.Add("Content-Type", "application/json")
 
-	resp, err := c.client.Do(req)
+	resp, err := c.client.Do(req.WithContext(ctx))
 
 	if err != nil {
 		log.Error(ctx, map[string]interface{}{
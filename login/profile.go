@@ -112,7 +112,7 @@ func (userProfileClient *KeycloakUserProfileClient) Create(ctx context.Context,
 	req.Header.Add("Authorization", "Bearer "+protectedAccessToken)
 	req.Header.Add("Content-Type", "application/json")
 
-	resp, err := userProfileClient.client.Do(req)
+	resp, err := userProfileClient.client.Do(req.WithContext(ctx))
 
 	if err != nil {
 		log.Error(ctx, map[string]interface{}{
@@ -192,7 +192,7 @@ func (userProfileClient *KeycloakUserProfileClient) Update(ctx context.Context,
 	req.Header.Add("Authorization", "Bearer "+accessToken)
 	req.Header.Add("Content-Type", "application/json")
 
-	resp, err := userProfileClient.client.Do(req)
+	resp, err := userProfileClient.client.Do(req.WithContext(ctx))
 
 	if err != nil {
 		log.Error(ctx, map[string]interface{}{
@@ -245,7 +245,7 @@ func (userProfileClient *KeycloakUserProfileClient) Get(ctx context.Context, acc
 	req.Header.Add("Content-Type", "application/json")
 	req.Header.Add("Accept", "application/json, text/plain, */*")
 
-	resp, err := userProfileClient.client.Do(req)
+	resp, err := userProfileClient.client.Do(req.WithContext(ctx))
 
 	if err != nil {
 		log.Error(ctx, map[string]interface{}{
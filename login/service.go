@@ -16,14 +16,20 @@ import (
 	"github.com/fabric8-services/fabric8-auth/account"
 	"github.com/fabric8-services/fabric8-auth/app"
 	"github.com/fabric8-services/fabric8-auth/application"
+	"github.com/fabric8-services/fabric8-auth/cluster"
 	autherrors "github.com/fabric8-services/fabric8-auth/errors"
+	"github.com/fabric8-services/fabric8-auth/i18n"
+	"github.com/fabric8-services/fabric8-auth/invitation"
 	"github.com/fabric8-services/fabric8-auth/jsonapi"
 	"github.com/fabric8-services/fabric8-auth/log"
 	"github.com/fabric8-services/fabric8-auth/login/tokencontext"
+	"github.com/fabric8-services/fabric8-auth/metric"
+	"github.com/fabric8-services/fabric8-auth/outbox"
 	"github.com/fabric8-services/fabric8-auth/rest"
 	"github.com/fabric8-services/fabric8-auth/token"
+	"github.com/fabric8-services/fabric8-auth/token/link"
 	"github.com/fabric8-services/fabric8-auth/token/oauth"
-	"github.com/fabric8-services/fabric8-auth/wit"
+	"github.com/fabric8-services/fabric8-auth/webhook"
 
 	"github.com/dgrijalva/jwt-go"
 	"github.com/goadesign/goa"
@@ -39,32 +45,35 @@ type LoginServiceConfiguration interface {
 	GetNotApprovedRedirect() string
 	GetWITURL(*goa.RequestData) (string, error)
 	GetOpenShiftClientApiUrl() string
+	GetClusterAutoLinkClients() []string
+	cluster.Configuration
 }
 
 // NewKeycloakOAuthProvider creates a new login.Service capable of using keycloak for authorization
 func NewKeycloakOAuthProvider(identities account.IdentityRepository, users account.UserRepository, tokenManager token.Manager, db application.DB) *KeycloakOAuthProvider {
 	return &KeycloakOAuthProvider{
-		Identities:       identities,
-		Users:            users,
-		TokenManager:     tokenManager,
-		db:               db,
-		remoteWITService: &wit.RemoteWITServiceCaller{},
+		Identities:   identities,
+		Users:        users,
+		TokenManager: tokenManager,
+		db:           db,
 	}
 }
 
 // KeycloakOAuthProvider represents a keycloak IDP
 type KeycloakOAuthProvider struct {
-	Identities       account.IdentityRepository
-	Users            account.UserRepository
-	TokenManager     token.Manager
-	db               application.DB
-	remoteWITService wit.RemoteWITService
+	Identities   account.IdentityRepository
+	Users        account.UserRepository
+	TokenManager token.Manager
+	// ClusterLinkService links the OpenShift account of the cluster the user is provisioned to at login.
+	// The account is not linked at login if nil.
+	ClusterLinkService link.LinkOAuthService
+	db                 application.DB
 }
 
 // KeycloakOAuthService represents keycloak OAuth service interface
 type KeycloakOAuthService interface {
 	Perform(ctx *app.LoginLoginContext, config oauth.OauthConfig, serviceConfig LoginServiceConfiguration) error
-	CreateOrUpdateIdentity(ctx context.Context, accessToken string, configuration LoginServiceConfiguration) (*account.Identity, bool, error)
+	CreateOrUpdateIdentity(ctx context.Context, req *goa.RequestData, accessToken string, configuration LoginServiceConfiguration) (*account.Identity, bool, error)
 	Link(ctx *app.LinkLinkContext, brokerEndpoint string, clientID string, validRedirectURL string) error
 	LinkSession(ctx *app.SessionLinkContext, brokerEndpoint string, clientID string, validRedirectURL string) error
 	LinkCallback(ctx *app.CallbackLinkContext, brokerEndpoint string, clientID string) error
@@ -84,6 +93,8 @@ const (
 	apiClientParam       = "api_client"
 	apiTokenParam        = "api_token"
 	tokenJSONParam       = "token_json"
+	// browserClient is the client of the login requests without api_client param
+	browserClient = "browser"
 )
 
 // Perform performs authentication
@@ -99,11 +110,6 @@ func (keycloak *KeycloakOAuthProvider) Perform(ctx *app.LoginLoginContext, confi
 	}
 	validRedirectURL := serviceConfig.GetValidRedirectURLs()
 
-	witURL, err := serviceConfig.GetWITURL(ctx.RequestData)
-	if err != nil {
-		return jsonapi.JSONErrorResponse(ctx, autherrors.NewInternalError(ctx, err))
-	}
-
 	state := ctx.Params.Get("state")
 	code := ctx.Params.Get("code")
 
@@ -126,6 +132,7 @@ func (keycloak *KeycloakOAuthProvider) Perform(ctx *app.LoginLoginContext, confi
 				"state": state,
 				"err":   err,
 			}, "unknown state")
+			metric.RecordLoginFailure(metric.LoginReasonUnknownState)
 			jerrors, _ := jsonapi.ErrorToJSONAPIErrors(ctx, goa.ErrUnauthorized("unknown state. "+err.Error()))
 			return ctx.Unauthorized(jerrors)
 		}
@@ -142,7 +149,8 @@ func (keycloak *KeycloakOAuthProvider) Perform(ctx *app.LoginLoginContext, confi
 				"code": code,
 				"err":  err,
 			}, "keycloak exchange operation failed")
-			return redirectWithError(ctx, knownReferrer, err.Error())
+			metric.RecordLoginFailure(metric.LoginReasonCodeExchangeFailed)
+			return redirectWithError(ctx, knownReferrer, "login.code_exchange_failed", err)
 		}
 
 		log.Debug(ctx, map[string]interface{}{
@@ -159,18 +167,20 @@ func (keycloak *KeycloakOAuthProvider) Perform(ctx *app.LoginLoginContext, confi
 				"known_referrer": knownReferrer,
 				"err":            err,
 			}, "failed to parse referrer")
-			return redirectWithError(ctx, knownReferrer, err.Error())
+			metric.RecordLoginFailure(metric.LoginReasonInvalidReferrer)
+			return redirectWithError(ctx, knownReferrer, "login.invalid_referrer", err)
 		}
 
 		apiClient := referrerURL.Query().Get(apiClientParam)
 
-		identity, newUser, err := keycloak.CreateOrUpdateIdentity(ctx, keycloakToken.AccessToken, serviceConfig)
+		identity, _, err := keycloak.CreateOrUpdateIdentity(ctx, ctx.RequestData, keycloakToken.AccessToken, serviceConfig)
 		if err != nil {
 			log.Error(ctx, map[string]interface{}{
 				"err": err,
 			}, "failed to create a user and keycloak identity ")
 			switch err.(type) {
 			case autherrors.UnauthorizedError:
+				metric.RecordLoginFailure(metric.LoginReasonUserNotApproved)
 				if apiClient != "" {
 					// Return the api token
 					err = encodeToken(ctx, referrerURL, keycloakToken, apiClient)
@@ -196,6 +206,8 @@ func (keycloak *KeycloakOAuthProvider) Perform(ctx *app.LoginLoginContext, confi
 					ctx.ResponseData.Header().Set("Location", userNotApprovedRedirectURL)
 					return ctx.TemporaryRedirect()
 				}
+			default:
+				metric.RecordLoginFailure(metric.LoginReasonIdentityUpdateError)
 			}
 			return jsonapi.JSONErrorResponse(ctx, err)
 		}
@@ -207,38 +219,15 @@ func (keycloak *KeycloakOAuthProvider) Perform(ctx *app.LoginLoginContext, confi
 			"user_name":      identity.Username,
 		}, "local user created/updated")
 
-		// new user for WIT
-		if newUser {
-			err = keycloak.remoteWITService.CreateWITUser(ctx, ctx.RequestData, identity, witURL, identity.ID.String())
-			if err != nil {
-				log.Error(ctx, map[string]interface{}{
-					"err":         err,
-					"identity_id": identity.ID,
-					"username":    identity.Username,
-					"wit_url":     witURL,
-				}, "unable to create user in WIT ")
-				// let's carry on instead of erroring out
-			}
-		} else {
-			err = keycloak.updateWITUser(ctx, ctx.RequestData, identity, witURL, identity.ID.String())
-			if err != nil {
-				log.Error(ctx, map[string]interface{}{
-					"identity_id": identity.ID,
-					"username":    identity.Username,
-					"err":         err,
-					"wit_url":     witURL,
-				}, "unable to update user in WIT ")
-				// let's carry on instead of erroring out
-			}
-		}
-
 		err = encodeToken(ctx, referrerURL, keycloakToken, apiClient)
 		if err != nil {
 			log.Error(ctx, map[string]interface{}{
 				"err": err,
 			}, "failed to encode token")
-			return redirectWithError(ctx, knownReferrer, err.Error())
+			metric.RecordLoginFailure(metric.LoginReasonTokenEncodingError)
+			return redirectWithError(ctx, knownReferrer, "login.token_encoding_failed", err)
 		}
+		metric.RecordLogin()
 		log.Debug(ctx, map[string]interface{}{
 			"code":           code,
 			"state":          state,
@@ -247,6 +236,29 @@ func (keycloak *KeycloakOAuthProvider) Perform(ctx *app.LoginLoginContext, confi
 		}, "token encoded")
 
 		if s, err := strconv.ParseBool(referrerURL.Query().Get(initiateLinkingParam)); err != nil || !s {
+			if keycloak.autoLinkCluster(apiClient, serviceConfig, identity.User) {
+				// The tokens encoded in the referrer are not passed on, they would be stored along with the state of the linking
+				linkLocation, err := keycloak.clusterLinkLocation(ctx, ctx.RequestData, *identity, loginAgainURL(ctx.RequestData, knownReferrer, apiClient))
+				if err != nil {
+					// The login doesn't fail if the account can't be linked, the user can still link it later
+					log.Error(ctx, map[string]interface{}{
+						"err":         err,
+						"identity_id": identity.ID,
+						"cluster_url": identity.User.Cluster,
+						"user_name":   identity.Username,
+						"api_client":  ctx.APIClient,
+					}, "unable to initiate the linking of the OpenShift account")
+				} else if linkLocation != "" {
+					ctx.ResponseData.Header().Set("Location", linkLocation)
+					log.Info(ctx, map[string]interface{}{
+						"known_referrer": knownReferrer,
+						"user_name":      identity.Username,
+						"cluster_url":    identity.User.Cluster,
+						"api_client":     ctx.APIClient,
+					}, "OpenShift account not linked yet; redirecting to the cluster")
+					return ctx.TemporaryRedirect()
+				}
+			}
 			ctx.ResponseData.Header().Set("Location", referrerURL.String())
 			log.Info(ctx, map[string]interface{}{
 				"known_referrer": knownReferrer,
@@ -352,6 +364,80 @@ func encodeToken(ctx context.Context, referrer *url.URL, outhToken *oauth2.Token
 	return nil
 }
 
+// autoLinkCluster returns true if the OpenShift account of the given user is linked at login for the given API client.
+// The account of the users who were reassigned to another cluster is linked again whatever the client.
+func (keycloak *KeycloakOAuthProvider) autoLinkCluster(apiClient string, config LoginServiceConfiguration, user account.User) bool {
+	if keycloak.ClusterLinkService == nil {
+		return false
+	}
+	if user.ClusterLinkRequired {
+		return true
+	}
+	if apiClient == "" {
+		apiClient = browserClient
+	}
+	for _, client := range config.GetClusterAutoLinkClients() {
+		if client == "*" || client == apiClient {
+			return true
+		}
+	}
+	return false
+}
+
+// clusterLinkLocation returns the location which links the OpenShift account of the cluster the user of the given identity
+// is provisioned to and redirects back to the given referrer, or an empty string if the account is already linked
+// or the cluster of the user is not registered
+func (keycloak *KeycloakOAuthProvider) clusterLinkLocation(ctx context.Context, req *goa.RequestData, identity account.Identity, referrer string) (string, error) {
+	if identity.User.Cluster == "" {
+		return "", nil
+	}
+	c, err := keycloak.db.Clusters().LoadByURL(ctx, identity.User.Cluster)
+	if err != nil {
+		if ok, _ := autherrors.IsNotFoundError(err); ok {
+			log.Warn(ctx, map[string]interface{}{
+				"identity_id": identity.ID,
+				"cluster_url": identity.User.Cluster,
+			}, "the cluster of the user is not registered; the OpenShift account is not linked")
+			return "", nil
+		}
+		return "", err
+	}
+	tokens, err := keycloak.db.ExternalTokens().LoadByProviderIDAndIdentityID(ctx, c.TokenProviderID, identity.ID)
+	if err != nil {
+		return "", err
+	}
+	if len(tokens) > 0 {
+		if !identity.User.ClusterLinkRequired {
+			return "", nil
+		}
+		// the account was linked again since the user was reassigned to the cluster
+		identity.User.ClusterLinkRequired = false
+		return "", application.Transactional(keycloak.db, func(appl application.Application) error {
+			return appl.Users().Save(ctx, &identity.User)
+		})
+	}
+	return keycloak.ClusterLinkService.ProviderLocation(ctx, req, identity.ID.String(), c.URL, referrer)
+}
+
+// loginAgainURL returns the URL of a new login which redirects to the given referrer once the OpenShift account is linked.
+// The referrer is the one saved before the tokens were encoded, so the new login issues the tokens again.
+func loginAgainURL(req *goa.RequestData, referrer string, apiClient string) string {
+	referrerURL, err := url.Parse(referrer)
+	if err == nil {
+		// the api_client param is passed to the new login, which saves it again in the referrer
+		parameters := referrerURL.Query()
+		parameters.Del(apiClientParam)
+		referrerURL.RawQuery = parameters.Encode()
+		referrer = referrerURL.String()
+	}
+	parameters := url.Values{}
+	parameters.Set("redirect", referrer)
+	if apiClient != "" {
+		parameters.Set(apiClientParam, apiClient)
+	}
+	return rest.AbsoluteURL(req, "/api/login") + "?" + parameters.Encode()
+}
+
 func (keycloak *KeycloakOAuthProvider) saveParams(ctx *app.LoginLoginContext, redirect string) (*string, error) {
 	if ctx.APIClient != nil || (ctx.Link != nil && *ctx.Link) {
 		// We need to save the "link" and "api_client" params so we don't lose them when redirect to sso for auth and back to auth.
@@ -422,7 +508,7 @@ func (keycloak *KeycloakOAuthProvider) checkFederatedIdentity(ctx context.Contex
 		return false, autherrors.NewInternalError(ctx, errs.Wrap(err, "unable to create http request"))
 	}
 	req.Header.Add("Authorization", "Bearer "+token)
-	res, err := http.DefaultClient.Do(req)
+	res, err := http.DefaultClient.Do(req.WithContext(ctx))
 	if err != nil {
 		log.Error(ctx, map[string]interface{}{
 			"provider": provider,
@@ -584,8 +670,9 @@ func getProviderURL(req *goa.RequestData, state string, sessionState string, pro
 }
 
 // CreateOrUpdateIdentity creates a user and a keycloak identity. If the user and identity already exist then update them.
+// The events which create or update the user in WIT and set up the tenant of a new user are written in the same transaction.
 // Returns the user, identity and true if a new user and identity have been created
-func (keycloak *KeycloakOAuthProvider) CreateOrUpdateIdentity(ctx context.Context, accessToken string, configuration LoginServiceConfiguration) (*account.Identity, bool, error) {
+func (keycloak *KeycloakOAuthProvider) CreateOrUpdateIdentity(ctx context.Context, req *goa.RequestData, accessToken string, configuration LoginServiceConfiguration) (*account.Identity, bool, error) {
 
 	newIdentityCreated := false
 	claims, err := keycloak.TokenManager.ParseToken(ctx, accessToken)
@@ -639,9 +726,6 @@ func (keycloak *KeycloakOAuthProvider) CreateOrUpdateIdentity(ctx context.Contex
 		// from the token claims info.
 
 		_, err = fillUser(claims, identity)
-		if identity.User.Cluster == "" {
-			identity.User.Cluster = configuration.GetOpenShiftClientApiUrl()
-		}
 		if err != nil {
 			log.Error(ctx, map[string]interface{}{
 				"keycloak_identity_id": keycloakIdentityID,
@@ -652,6 +736,9 @@ func (keycloak *KeycloakOAuthProvider) CreateOrUpdateIdentity(ctx context.Contex
 
 		err = application.Transactional(keycloak.db, func(appl application.Application) error {
 			user := &identity.User
+			if user.Cluster == "" {
+				user.Cluster = placeUser(ctx, appl, configuration, claims.Region)
+			}
 			err := appl.Users().Create(ctx, user)
 			if err != nil {
 				return err
@@ -663,7 +750,18 @@ func (keycloak *KeycloakOAuthProvider) CreateOrUpdateIdentity(ctx context.Contex
 			identity.User = *user
 
 			err = appl.Identities().Create(ctx, identity)
-			return err
+			if err != nil {
+				return err
+			}
+			err = enqueueNewUserEvents(ctx, req, appl, identity.ID, configuration)
+			if err != nil {
+				return err
+			}
+			err = webhook.Emit(ctx, appl, webhook.EventTypeUserCreated, webhook.Data{IdentityID: identity.ID, Username: identity.Username})
+			if err != nil {
+				return err
+			}
+			return acceptPendingInvitations(ctx, req, appl, *identity)
 		})
 		if err != nil {
 			log.Error(ctx, map[string]interface{}{
@@ -715,7 +813,11 @@ func (keycloak *KeycloakOAuthProvider) CreateOrUpdateIdentity(ctx context.Contex
 					}, "unable to update identity")
 					return errors.New("failed to update identity " + err.Error())
 				}
-				return err
+				err = enqueueUpdatedUserEvents(ctx, req, appl, identity.ID, configuration)
+				if err != nil {
+					return err
+				}
+				return webhook.Emit(ctx, appl, webhook.EventTypeUserUpdated, webhook.Data{IdentityID: identity.ID, Username: identity.Username})
 			})
 			if err != nil {
 				log.Error(ctx, map[string]interface{}{
@@ -730,25 +832,92 @@ func (keycloak *KeycloakOAuthProvider) CreateOrUpdateIdentity(ctx context.Contex
 	return identity, newIdentityCreated, err
 }
 
-func (keycloak *KeycloakOAuthProvider) updateWITUser(ctx context.Context, request *goa.RequestData, identity *account.Identity, witURL string, identityID string) error {
-	updateUserPayload := &app.UpdateUsersPayload{
-		Data: &app.UpdateUserData{
-			Attributes: &app.UpdateIdentityDataAttributes{
-				Bio:      &identity.User.Bio,
-				Company:  &identity.User.Company,
-				Email:    &identity.User.Email,
-				FullName: &identity.User.FullName,
-				ImageURL: &identity.User.ImageURL,
-				URL:      &identity.User.URL,
-				Username: &identity.Username,
-			},
-		},
+// placeUser returns the registered cluster a new user is provisioned to, in the given region if any, i.e. the region
+// claim of the Keycloak token. The default cluster is used if the placement fails, so the users can still log in
+// when no cluster is available.
+func placeUser(ctx context.Context, appl application.Application, configuration LoginServiceConfiguration, region string) string {
+	apiURL, err := cluster.NewPlacement(configuration).Place(ctx, appl, region)
+	if err != nil {
+		log.Error(ctx, map[string]interface{}{
+			"err":     err,
+			"region":  region,
+			"cluster": configuration.GetOpenShiftClientApiUrl(),
+		}, "unable to choose the cluster of the new user, using the default cluster")
+		return configuration.GetOpenShiftClientApiUrl()
+	}
+	return apiURL
+}
+
+// enqueueNewUserEvents writes the events which create the user of the given identity in WIT and set up its tenant,
+// and marks the provisioning of the tenant as pending
+func enqueueNewUserEvents(ctx context.Context, req *goa.RequestData, appl application.Application, identityID uuid.UUID, configuration LoginServiceConfiguration) error {
+	witURL, err := configuration.GetWITURL(req)
+	if err != nil {
+		return err
+	}
+	witEvent, err := outbox.NewWITUserCreatedEvent(identityID, witURL, rest.AbsoluteURL(req, ""))
+	if err != nil {
+		return err
+	}
+	err = appl.OutboxEvents().Create(ctx, witEvent)
+	if err != nil {
+		return err
+	}
+	tenantEvent, err := outbox.NewTenantUserCreatedEvent(identityID, rest.AbsoluteURL(req, ""))
+	if err != nil {
+		return err
+	}
+	err = appl.OutboxEvents().Create(ctx, tenantEvent)
+	if err != nil {
+		return err
+	}
+	return appl.TenantProvisionings().Request(ctx, identityID)
+}
+
+// enqueueUpdatedUserEvents writes the event which updates the user of the given identity in WIT
+func enqueueUpdatedUserEvents(ctx context.Context, req *goa.RequestData, appl application.Application, identityID uuid.UUID, configuration LoginServiceConfiguration) error {
+	witURL, err := configuration.GetWITURL(req)
+	if err != nil {
+		return err
+	}
+	event, err := outbox.NewWITUserUpdatedEvent(identityID, witURL, rest.AbsoluteURL(req, ""))
+	if err != nil {
+		return err
+	}
+	return appl.OutboxEvents().Create(ctx, event)
+}
+
+// acceptPendingInvitations accepts the pending invitations sent to the verified email or username of the new identity
+func acceptPendingInvitations(ctx context.Context, req *goa.RequestData, appl application.Application, identity account.Identity) error {
+	invitations, err := appl.Invitations().Query(ctx, invitation.InvitationFilterPending(), invitation.InvitationFilterByInvitee(identity.User.VerifiedEmail(), identity.Username))
+	if err != nil {
+		return err
 	}
-	return keycloak.remoteWITService.UpdateWITUser(ctx, request, updateUserPayload, witURL, identityID)
+	for i := range invitations {
+		inv, err := appl.Invitations().Lock(ctx, invitations[i].ID)
+		if err != nil {
+			return err
+		}
+		// the invitation was cancelled or accepted in the meantime
+		if inv.State != invitation.StatePending {
+			continue
+		}
+		err = invitation.Accept(ctx, appl.Invitations(), appl.OutboxEvents(), appl.AuditEvents(), inv, identity.ID, rest.AbsoluteURL(req, ""))
+		if err != nil {
+			return err
+		}
+		err = webhook.Emit(ctx, appl, webhook.EventTypeCollaboratorAdded, webhook.Data{IdentityID: identity.ID, Username: identity.Username, SpaceID: &inv.ResourceID})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
-func redirectWithError(ctx *app.LoginLoginContext, knownReferrer string, errorString string) error {
-	ctx.ResponseData.Header().Set("Location", knownReferrer+"?error="+errorString)
+// redirectWithError redirects to the referrer with the message of the given key, translated in the language of the user, in the 'error' parameter
+func redirectWithError(ctx *app.LoginLoginContext, knownReferrer string, messageKey string, err error) error {
+	errorString := i18n.Message(ctx, messageKey, map[string]interface{}{"error": err.Error()})
+	ctx.ResponseData.Header().Set("Location", knownReferrer+"?error="+url.QueryEscape(errorString))
 	return ctx.TemporaryRedirect()
 }
 
@@ -776,13 +945,14 @@ func generateGravatarURL(email string) (string, error) {
 
 func fillUser(claims *token.TokenClaims, identity *account.Identity) (bool, error) {
 	isChanged := false
-	if identity.User.FullName != claims.Name || identity.User.Email != claims.Email || identity.User.Company != claims.Company || identity.Username != claims.Username || identity.User.ImageURL == "" {
+	if identity.User.FullName != claims.Name || identity.User.Email != claims.Email || identity.User.EmailVerified != claims.EmailVerified || identity.User.Company != claims.Company || identity.Username != claims.Username || identity.User.ImageURL == "" {
 		isChanged = true
 	} else {
 		return isChanged, nil
 	}
 	identity.User.FullName = claims.Name
 	identity.User.Email = claims.Email
+	identity.User.EmailVerified = claims.EmailVerified
 	identity.User.Company = claims.Company
 	identity.Username = claims.Username
 	if identity.User.ImageURL == "" {
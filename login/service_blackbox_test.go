@@ -7,6 +7,7 @@ import (
 	"net/http/httptest"
 	"net/url"
 	"os"
+	"strings"
 	"testing"
 	"time"
 
@@ -15,16 +16,21 @@ import (
 	config "github.com/fabric8-services/fabric8-auth/configuration"
 	"github.com/fabric8-services/fabric8-auth/errors"
 	"github.com/fabric8-services/fabric8-auth/gormtestsupport"
+	"github.com/fabric8-services/fabric8-auth/invitation"
 	. "github.com/fabric8-services/fabric8-auth/login"
+	"github.com/fabric8-services/fabric8-auth/outbox"
 	"github.com/fabric8-services/fabric8-auth/resource"
+	"github.com/fabric8-services/fabric8-auth/test"
 	testtoken "github.com/fabric8-services/fabric8-auth/test/token"
 	"github.com/fabric8-services/fabric8-auth/token"
+	"github.com/fabric8-services/fabric8-auth/token/provider"
 
 	"github.com/dgrijalva/jwt-go"
 	"github.com/goadesign/goa"
 	goajwt "github.com/goadesign/goa/middleware/security/jwt"
 	"github.com/goadesign/goa/uuid"
 	_ "github.com/lib/pq"
+	satoriuuid "github.com/satori/go.uuid"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"github.com/stretchr/testify/suite"
@@ -126,16 +132,33 @@ func (s *serviceBlackBoxTest) TestKeycloakAuthorizationRedirect() {
 }
 
 func (s *serviceBlackBoxTest) TestApprovedUserCreatedAndUpdated() {
+	s.SeedClusters()
 	claims := make(map[string]interface{})
 	token, err := testtoken.GenerateTokenWithClaims(claims)
 	require.Nil(s.T(), err)
 
-	identity, ok, err := s.loginService.CreateOrUpdateIdentity(context.Background(), token, s.Configuration)
+	identity, ok, err := s.loginService.CreateOrUpdateIdentity(context.Background(), s.requestData(), token, s.Configuration)
 	require.Nil(s.T(), err)
 	require.NotNil(s.T(), identity)
 	assert.True(s.T(), ok)
 	s.checkIfTokenMatchesIdentity(token, *identity)
-	assert.Equal(s.T(), s.Configuration.GetOpenShiftClientApiUrl(), identity.User.Cluster)
+	// the user is placed on one of the configured clusters
+	assert.Contains(s.T(), s.Configuration.GetOSOClusters(), identity.User.Cluster)
+
+	// the user is created in WIT and its tenant is set up by the outbox dispatcher
+	events, err := s.Application.OutboxEvents().Query(s.Ctx, outbox.EventFilterByIdentityID(identity.ID))
+	require.Nil(s.T(), err)
+	require.Len(s.T(), events, 2)
+	assert.Equal(s.T(), outbox.EventTypeUserCreated, events[0].EventType)
+	assert.Equal(s.T(), outbox.TargetWIT, events[0].Target)
+	assert.Equal(s.T(), outbox.EventTypeUserCreated, events[1].EventType)
+	assert.Equal(s.T(), outbox.TargetTenant, events[1].Target)
+	// the token of the user is not stored, the tenant is set up with a token issued by the auth service
+	require.NotNil(s.T(), events[1].Payload)
+	assert.NotContains(s.T(), *events[1].Payload, token)
+	provisioning, err := s.Application.TenantProvisionings().Load(context.Background(), identity.ID)
+	require.Nil(s.T(), err)
+	assert.Equal(s.T(), account.TenantProvisioningStatePending, provisioning.State)
 
 	updatedClaims := make(map[string]interface{})
 	updatedClaims["company"] = "Updated company"
@@ -147,11 +170,89 @@ func (s *serviceBlackBoxTest) TestApprovedUserCreatedAndUpdated() {
 	token, err = testtoken.UpdateToken(token, updatedClaims)
 	require.Nil(s.T(), err)
 
-	identity, ok, err = s.loginService.CreateOrUpdateIdentity(context.Background(), token, s.Configuration)
+	identity, ok, err = s.loginService.CreateOrUpdateIdentity(context.Background(), s.requestData(), token, s.Configuration)
 	require.Nil(s.T(), err)
 	require.NotNil(s.T(), identity)
 	assert.False(s.T(), ok)
 	s.checkIfTokenMatchesIdentity(token, *identity)
+
+	// the user is updated in WIT by the outbox dispatcher
+	events, err = s.Application.OutboxEvents().Query(s.Ctx, outbox.EventFilterByIdentityID(identity.ID), outbox.EventFilterByTarget(outbox.TargetWIT))
+	require.Nil(s.T(), err)
+	require.Len(s.T(), events, 2)
+	assert.Equal(s.T(), outbox.EventTypeUserUpdated, events[1].EventType)
+}
+
+func (s *serviceBlackBoxTest) TestPendingInvitationsAcceptedAtFirstLogin() {
+	// given invitations sent to the verified email of the user before the user signed up
+	inviter, err := test.CreateTestIdentity(s.DB, "invitation-inviter-"+uuid.NewV4().String(), account.KeycloakIDP)
+	require.Nil(s.T(), err)
+	email := fmt.Sprintf("testuser+%s@email.com", uuid.NewV4().String())
+	upperCaseEmail := strings.ToUpper(email)
+	spaceID := satoriuuid.NewV4()
+	pending := s.createInvitation(inviter.ID, spaceID, &upperCaseEmail, time.Now().Add(time.Hour))
+	expired := s.createInvitation(inviter.ID, satoriuuid.NewV4(), &email, time.Now().Add(-time.Hour))
+	token, err := testtoken.GenerateTokenWithClaims(map[string]interface{}{"email": email, "email_verified": true})
+	require.Nil(s.T(), err)
+
+	// when
+	identity, ok, err := s.loginService.CreateOrUpdateIdentity(context.Background(), s.requestData(), token, s.Configuration)
+
+	// then
+	require.Nil(s.T(), err)
+	assert.True(s.T(), ok)
+	assert.True(s.T(), identity.User.EmailVerified)
+	accepted, err := s.Application.Invitations().Load(context.Background(), pending.ID)
+	require.Nil(s.T(), err)
+	assert.Equal(s.T(), invitation.StateAccepted, accepted.State)
+	require.NotNil(s.T(), accepted.AcceptedBy)
+	assert.Equal(s.T(), identity.ID, *accepted.AcceptedBy)
+	notAccepted, err := s.Application.Invitations().Load(context.Background(), expired.ID)
+	require.Nil(s.T(), err)
+	assert.Equal(s.T(), invitation.StatePending, notAccepted.State)
+	// the user is added to the space policy by the outbox dispatcher
+	events, err := s.Application.OutboxEvents().Query(s.Ctx, outbox.EventFilterByIdentityID(identity.ID), outbox.EventFilterByTarget(outbox.TargetKeycloak))
+	require.Nil(s.T(), err)
+	require.Len(s.T(), events, 1)
+	assert.Equal(s.T(), outbox.EventTypeCollaboratorAdded, events[0].EventType)
+	var payload outbox.CollaboratorPayload
+	require.Nil(s.T(), events[0].DecodePayload(&payload))
+	assert.Equal(s.T(), spaceID, payload.SpaceID)
+}
+
+func (s *serviceBlackBoxTest) TestPendingInvitationsNotAcceptedForUnverifiedEmail() {
+	// given an invitation sent to an email which the user did not verify in Keycloak
+	inviter, err := test.CreateTestIdentity(s.DB, "invitation-inviter-"+uuid.NewV4().String(), account.KeycloakIDP)
+	require.Nil(s.T(), err)
+	email := fmt.Sprintf("testuser+%s@email.com", uuid.NewV4().String())
+	pending := s.createInvitation(inviter.ID, satoriuuid.NewV4(), &email, time.Now().Add(time.Hour))
+	token, err := testtoken.GenerateTokenWithClaims(map[string]interface{}{"email": email, "email_verified": false})
+	require.Nil(s.T(), err)
+
+	// when
+	identity, ok, err := s.loginService.CreateOrUpdateIdentity(context.Background(), s.requestData(), token, s.Configuration)
+
+	// then
+	require.Nil(s.T(), err)
+	assert.True(s.T(), ok)
+	assert.False(s.T(), identity.User.EmailVerified)
+	notAccepted, err := s.Application.Invitations().Load(context.Background(), pending.ID)
+	require.Nil(s.T(), err)
+	assert.Equal(s.T(), invitation.StatePending, notAccepted.State)
+	assert.Nil(s.T(), notAccepted.AcceptedBy)
+}
+
+func (s *serviceBlackBoxTest) createInvitation(inviterID satoriuuid.UUID, spaceID satoriuuid.UUID, email *string, expiresAt time.Time) *invitation.Invitation {
+	inv := &invitation.Invitation{
+		ResourceType: invitation.ResourceTypeSpace,
+		ResourceID:   spaceID,
+		Role:         invitation.RoleCollaborator,
+		InviterID:    inviterID,
+		Email:        email,
+		ExpiresAt:    expiresAt,
+	}
+	require.Nil(s.T(), s.Application.Invitations().Create(context.Background(), inv))
+	return inv
 }
 
 func (s *serviceBlackBoxTest) TestUnapprovedUserUnauthorized() {
@@ -160,11 +261,17 @@ func (s *serviceBlackBoxTest) TestUnapprovedUserUnauthorized() {
 	token, err := testtoken.GenerateTokenWithClaims(claims)
 	require.Nil(s.T(), err)
 
-	_, _, err = s.loginService.CreateOrUpdateIdentity(context.Background(), token, s.Configuration)
+	_, _, err = s.loginService.CreateOrUpdateIdentity(context.Background(), s.requestData(), token, s.Configuration)
 	require.NotNil(s.T(), err)
 	require.IsType(s.T(), errors.NewUnauthorizedError(""), err)
 }
 
+func (s *serviceBlackBoxTest) requestData() *goa.RequestData {
+	return &goa.RequestData{
+		Request: &http.Request{Host: "auth.service.domain.org"},
+	}
+}
+
 func (s *serviceBlackBoxTest) checkIfTokenMatchesIdentity(tokenString string, identity account.Identity) {
 	claims, err := testtoken.TokenManager.ParseToken(context.Background(), tokenString)
 	require.Nil(s.T(), err)
@@ -584,6 +691,137 @@ func (s *serviceBlackBoxTest) TestAPIClientForUnapprovedUsersReturnOK() {
 	s.checkLoginCallback(dummyOauth, rw, authorizeCtx, "api_token")
 }
 
+func (s *serviceBlackBoxTest) TestClusterAccountLinkedAtLogin() {
+	// given
+	s.SeedClusters()
+	linkService := &dummyLinkService{location: "https://cluster-url.example.org/oauth/authorize"}
+	loginService := NewKeycloakOAuthProvider(s.Application.Identities(), s.Application.Users(), testtoken.TokenManager, s.Application)
+	loginService.ClusterLinkService = linkService
+	rw, authorizeCtx := s.loginCallback(make(map[string]string))
+
+	// when
+	err := loginService.Perform(authorizeCtx, s.dummyOauth, s.Configuration)
+
+	// then the user is redirected to the cluster to link the account, and then logged in again
+	// since the tokens are not saved with the state of the linking
+	require.Nil(s.T(), err)
+	assert.Equal(s.T(), 307, rw.Code)
+	assert.Equal(s.T(), linkService.location, rw.Header().Get("Location"))
+	assert.NotContains(s.T(), linkService.redirectURL, "token_json")
+	assert.NotContains(s.T(), linkService.redirectURL, "api_token")
+	redirectURL, err := url.Parse(linkService.redirectURL)
+	require.Nil(s.T(), err)
+	assert.Equal(s.T(), "/api/login", redirectURL.Path)
+	assert.Equal(s.T(), "https://openshift.io/somepath", redirectURL.Query().Get("redirect"))
+	identityID, err := satoriuuid.FromString(linkService.identityID)
+	require.Nil(s.T(), err)
+	identities, err := s.Application.Identities().Query(account.IdentityFilterByID(identityID), account.IdentityWithUser())
+	require.Nil(s.T(), err)
+	require.Len(s.T(), identities, 1)
+	assert.Equal(s.T(), identities[0].User.Cluster, linkService.forResource)
+
+	// given the account is linked
+	cluster, err := s.Application.Clusters().LoadByURL(context.Background(), linkService.forResource)
+	require.Nil(s.T(), err)
+	err = s.Application.ExternalTokens().Create(context.Background(), &provider.ExternalToken{
+		ProviderID: cluster.TokenProviderID,
+		IdentityID: identityID,
+		Token:      "cluster-token",
+		Scope:      "user:full",
+	})
+	require.Nil(s.T(), err)
+	rw, authorizeCtx = s.loginCallback(make(map[string]string))
+
+	// when
+	err = loginService.Perform(authorizeCtx, s.dummyOauth, s.Configuration)
+
+	// then the user is redirected back to the referrer
+	require.Nil(s.T(), err)
+	assert.Equal(s.T(), 307, rw.Code)
+	assert.Contains(s.T(), rw.Header().Get("Location"), "https://openshift.io/somepath")
+	assert.Contains(s.T(), rw.Header().Get("Location"), "token_json")
+	assert.Equal(s.T(), 1, linkService.calls)
+}
+
+func (s *serviceBlackBoxTest) TestClusterAccountNotLinkedForAPIClient() {
+	// given an API client which is not configured to link the account at login
+	s.SeedClusters()
+	linkService := &dummyLinkService{location: "https://cluster-url.example.org/oauth/authorize"}
+	loginService := NewKeycloakOAuthProvider(s.Application.Identities(), s.Application.Users(), testtoken.TokenManager, s.Application)
+	loginService.ClusterLinkService = linkService
+	rw, authorizeCtx := s.loginCallback(map[string]string{"api_client": "vscode"})
+
+	// when
+	err := loginService.Perform(authorizeCtx, s.dummyOauth, s.Configuration)
+
+	// then
+	require.Nil(s.T(), err)
+	assert.Equal(s.T(), 307, rw.Code)
+	assert.Contains(s.T(), rw.Header().Get("Location"), "https://openshift.io/somepath")
+	assert.Contains(s.T(), rw.Header().Get("Location"), "api_token")
+	assert.Equal(s.T(), 0, linkService.calls)
+}
+
+func (s *serviceBlackBoxTest) TestClusterAccountLinkedAgainAfterReassignment() {
+	// given a user of an API client which is not configured to link the account at login
+	s.SeedClusters()
+	linkService := &dummyLinkService{location: "https://cluster-url.example.org/oauth/authorize"}
+	loginService := NewKeycloakOAuthProvider(s.Application.Identities(), s.Application.Users(), testtoken.TokenManager, s.Application)
+	loginService.ClusterLinkService = linkService
+	rw, authorizeCtx := s.loginCallback(map[string]string{"api_client": "vscode"})
+	require.Nil(s.T(), loginService.Perform(authorizeCtx, s.dummyOauth, s.Configuration))
+	require.Equal(s.T(), 0, linkService.calls)
+	claims, err := testtoken.TokenManager.ParseToken(context.Background(), s.dummyOauth.accessToken)
+	require.Nil(s.T(), err)
+	identityID, err := satoriuuid.FromString(claims.Subject)
+	require.Nil(s.T(), err)
+	identities, err := s.Application.Identities().Query(account.IdentityFilterByID(identityID), account.IdentityWithUser())
+	require.Nil(s.T(), err)
+	require.Len(s.T(), identities, 1)
+	// who was reassigned to another cluster
+	user := identities[0].User
+	user.ClusterLinkRequired = true
+	require.Nil(s.T(), s.Application.Users().Save(context.Background(), &user))
+	rw, authorizeCtx = s.loginCallback(map[string]string{"api_client": "vscode"})
+
+	// when
+	err = loginService.Perform(authorizeCtx, s.dummyOauth, s.Configuration)
+
+	// then the user is redirected to the cluster to link the account
+	require.Nil(s.T(), err)
+	assert.Equal(s.T(), 307, rw.Code)
+	assert.Equal(s.T(), linkService.location, rw.Header().Get("Location"))
+	assert.Equal(s.T(), user.Cluster, linkService.forResource)
+	// and then logged in again with the same API client
+	redirectURL, err := url.Parse(linkService.redirectURL)
+	require.Nil(s.T(), err)
+	assert.Equal(s.T(), "vscode", redirectURL.Query().Get("api_client"))
+	assert.Equal(s.T(), "https://openshift.io/somepath", redirectURL.Query().Get("redirect"))
+
+	// given the account is linked
+	cluster, err := s.Application.Clusters().LoadByURL(context.Background(), user.Cluster)
+	require.Nil(s.T(), err)
+	err = s.Application.ExternalTokens().Create(context.Background(), &provider.ExternalToken{
+		ProviderID: cluster.TokenProviderID,
+		IdentityID: identityID,
+		Token:      "cluster-token",
+		Scope:      "user:full",
+	})
+	require.Nil(s.T(), err)
+	rw, authorizeCtx = s.loginCallback(map[string]string{"api_client": "vscode"})
+
+	// when
+	err = loginService.Perform(authorizeCtx, s.dummyOauth, s.Configuration)
+
+	// then the user is redirected back to the referrer and won't be asked to link the account again
+	require.Nil(s.T(), err)
+	assert.Equal(s.T(), 307, rw.Code)
+	assert.Contains(s.T(), rw.Header().Get("Location"), "https://openshift.io/somepath")
+	updated, err := s.Application.Users().Load(context.Background(), user.ID)
+	require.Nil(s.T(), err)
+	assert.False(s.T(), updated.ClusterLinkRequired)
+}
+
 func (s *serviceBlackBoxTest) loginCallback(extraParams map[string]string) (*httptest.ResponseRecorder, *app.LoginLoginContext) {
 	// Setup request context
 	rw := httptest.NewRecorder()
@@ -690,3 +928,23 @@ func (c *dummyOauth2Config) Exchange(ctx netcontext.Context, code string) (*oaut
 	token = token.WithExtra(extra)
 	return token, nil
 }
+
+type dummyLinkService struct {
+	location    string
+	calls       int
+	identityID  string
+	forResource string
+	redirectURL string
+}
+
+func (l *dummyLinkService) ProviderLocation(ctx context.Context, req *goa.RequestData, identityID string, forResource string, redirectURL string) (string, error) {
+	l.calls++
+	l.identityID = identityID
+	l.forResource = forResource
+	l.redirectURL = redirectURL
+	return l.location, nil
+}
+
+func (l *dummyLinkService) Callback(ctx context.Context, req *goa.RequestData, state string, code string) (string, error) {
+	return "", nil
+}
@@ -1,30 +1,44 @@
 package main
 
 import (
+	"context"
 	"flag"
+	"fmt"
 	"net/http"
 	"os"
+	"os/signal"
 	"os/user"
 	"runtime"
+	"syscall"
 	"time"
 
 	"github.com/fabric8-services/fabric8-auth/account"
 	"github.com/fabric8-services/fabric8-auth/app"
 	"github.com/fabric8-services/fabric8-auth/application"
 	"github.com/fabric8-services/fabric8-auth/auth"
+	"github.com/fabric8-services/fabric8-auth/authorization/expiry"
+	"github.com/fabric8-services/fabric8-auth/cluster/registry"
 	"github.com/fabric8-services/fabric8-auth/configuration"
 	"github.com/fabric8-services/fabric8-auth/controller"
 	"github.com/fabric8-services/fabric8-auth/goamiddleware"
 	"github.com/fabric8-services/fabric8-auth/gormapplication"
+	"github.com/fabric8-services/fabric8-auth/health"
 	"github.com/fabric8-services/fabric8-auth/jsonapi"
 	"github.com/fabric8-services/fabric8-auth/log"
 	"github.com/fabric8-services/fabric8-auth/login"
 	keycloakLinkAPI "github.com/fabric8-services/fabric8-auth/login/link"
+	"github.com/fabric8-services/fabric8-auth/login/tokencontext"
+	"github.com/fabric8-services/fabric8-auth/metric"
 	"github.com/fabric8-services/fabric8-auth/migration"
+	"github.com/fabric8-services/fabric8-auth/outbox"
+	"github.com/fabric8-services/fabric8-auth/outbox/dispatcher"
+	"github.com/fabric8-services/fabric8-auth/ratelimit"
 	"github.com/fabric8-services/fabric8-auth/space/authz"
 	"github.com/fabric8-services/fabric8-auth/token"
 	"github.com/fabric8-services/fabric8-auth/token/keycloak"
 	"github.com/fabric8-services/fabric8-auth/token/link"
+	"github.com/fabric8-services/fabric8-auth/tracing"
+	"github.com/fabric8-services/fabric8-auth/wit"
 
 	"github.com/goadesign/goa"
 	"github.com/goadesign/goa/logging/logrus"
@@ -44,11 +58,17 @@ func main() {
 	var osoClusterConfigFile string
 	var printConfig bool
 	var migrateDB bool
+	var dryRun bool
+	var migrationStatus bool
+	var migrateToVersion int64
 	flag.StringVar(&configFile, "config", "", "Path to the config file to read")
 	flag.StringVar(&serviceAccountConfigFile, "serviceAccountConfig", "", "Path to the service account configuration file")
 	flag.StringVar(&osoClusterConfigFile, "osoClusterConfigFile", "", "Path to the OSO cluster configuration file")
 	flag.BoolVar(&printConfig, "printConfig", false, "Prints the config (including merged environment variables) and exits")
 	flag.BoolVar(&migrateDB, "migrateDatabase", false, "Migrates the database to the newest version and exits.")
+	flag.BoolVar(&dryRun, "dryRun", false, "Used with -migrateDatabase. Prints the pending versions and their SQL without applying them and exits.")
+	flag.BoolVar(&migrationStatus, "migrationStatus", false, "Prints the current and the pending versions of the database and exits.")
+	flag.Int64Var(&migrateToVersion, "migrateToVersion", -1, "Migrates the database forward or backward to the given version and exits.")
 	flag.Parse()
 
 	// Override default -config switch with environment variable only if -config switch was
@@ -73,6 +93,7 @@ func main() {
 
 	// Initialized developer mode flag and log level for the logger
 	log.InitializeLogger(config.IsLogJSON(), config.GetLogLevel())
+	log.ConfigureRedaction(config.GetLogRedactFields(), config.GetLogRedactHeaders())
 
 	printUserInfo()
 
@@ -106,6 +127,38 @@ func main() {
 	// Set the database transaction timeout
 	application.SetDatabaseTransactionTimeout(config.GetPostgresTransactionTimeout())
 
+	if migrationStatus {
+		status, err := migration.GetStatus(db.DB(), config.GetPostgresDatabase(), config)
+		if err != nil {
+			log.Panic(nil, map[string]interface{}{
+				"err": err,
+			}, "failed to get the migration status")
+		}
+		fmt.Printf("Current version: %d\nLatest version: %d\nPending versions: %v\n", status.CurrentVersion, status.LatestVersion, status.Pending)
+		os.Exit(0)
+	}
+
+	if migrateToVersion >= 0 {
+		err = migration.MigrateTo(db.DB(), config.GetPostgresDatabase(), config, migrateToVersion)
+		if err != nil {
+			log.Panic(nil, map[string]interface{}{
+				"target_version": migrateToVersion,
+				"err":            err,
+			}, "failed migration")
+		}
+		os.Exit(0)
+	}
+
+	if migrateDB && dryRun {
+		err = migration.DryRun(db.DB(), config.GetPostgresDatabase(), config, os.Stdout)
+		if err != nil {
+			log.Panic(nil, map[string]interface{}{
+				"err": err,
+			}, "failed migration dry run")
+		}
+		os.Exit(0)
+	}
+
 	// Migrate the schema
 	err = migration.Migrate(db.DB(), config.GetPostgresDatabase(), config)
 	if err != nil {
@@ -122,11 +175,52 @@ func main() {
 	// Load service accounts
 	//	application.s
 
+	// Set up tracing
+	shutdownTracing, err := tracing.Init(config)
+	if err != nil {
+		log.Panic(nil, map[string]interface{}{
+			"err": err,
+		}, "failed to set up tracing")
+	}
+	// Close the trace file last, once the server and the background jobs are stopped
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := shutdownTracing(ctx); err != nil {
+			log.Error(nil, map[string]interface{}{
+				"err": err,
+			}, "failed to shut tracing down")
+		}
+	}()
+
+	// Record the duration of the requests sent to Keycloak, WIT and the tenant service
+	outboundDependencies := map[string]string{
+		"keycloak": config.GetKeycloakURL(),
+		"tenant":   config.GetTenantServiceURL(),
+	}
+	if witURL, err := config.GetWITURL(nil); err == nil {
+		outboundDependencies["wit"] = witURL
+	}
+	for name, dependencyURL := range outboundDependencies {
+		if dependencyURL == "" {
+			continue
+		}
+		if err := metric.RegisterDependency(name, dependencyURL); err != nil {
+			log.Panic(nil, map[string]interface{}{
+				"dependency": name,
+				"url":        dependencyURL,
+				"err":        err,
+			}, "invalid dependency URL")
+		}
+	}
+	http.DefaultTransport = metric.Transport(http.DefaultTransport)
+
 	// Create service
 	service := goa.New("auth")
 
 	// Mount middleware
 	service.Use(middleware.RequestID())
+	service.Use(tracing.Middleware())
 	// Use our own log request to inject identity id and modify other properties
 	service.Use(log.LogRequest(config.IsPostgresDeveloperModeEnabled()))
 	service.Use(gzip.Middleware(9))
@@ -141,6 +235,53 @@ func main() {
 
 	appDB := gormapplication.NewGormDB(db)
 
+	// Register the clusters of the OSO cluster configuration file which are not in the cluster registry yet
+	err = registry.Seed(context.Background(), appDB.Clusters(), config.GetOSOClusterSeeds())
+	if err != nil {
+		log.Panic(nil, map[string]interface{}{
+			"err": err,
+		}, "failed to seed the cluster registry")
+	}
+
+	// The dependencies reported by the status endpoints
+	dependencies := health.NewRegistry(config.GetHealthCheckTimeout(), config.GetHealthCheckCacheTTL())
+	// Keycloak is required to serve most of the requests
+	dependencies.Register(health.NewHTTPChecker("keycloak", fmt.Sprintf("%s/auth/realms/%s", config.GetKeycloakURL(), config.GetKeycloakRealm())), true)
+	if witURL, err := config.GetWITURL(nil); err == nil {
+		dependencies.Register(health.NewHTTPChecker("wit", witURL+"/api/status"), false)
+	}
+	if config.GetTenantServiceURL() != "" {
+		dependencies.Register(health.NewHTTPChecker("tenant", config.GetTenantServiceURL()+"/api/status"), false)
+	}
+	if err := registerClusterCheckers(dependencies, appDB); err != nil {
+		log.Panic(nil, map[string]interface{}{
+			"err": err,
+		}, "failed to list the registered clusters")
+	}
+
+	// Reload the configuration when the configuration files change or on SIGHUP.
+	// The clusters added to the OSO cluster configuration file are registered on reload too.
+	stopWatchingConfig, err := config.Watch(func() {
+		log.SetLogLevel(config.GetLogLevel())
+		if err := registry.Seed(context.Background(), appDB.Clusters(), config.GetOSOClusterSeeds()); err != nil {
+			log.Error(nil, map[string]interface{}{
+				"err": err,
+			}, "failed to seed the cluster registry with the reloaded configuration")
+		}
+		if err := registerClusterCheckers(dependencies, appDB); err != nil {
+			log.Error(nil, map[string]interface{}{
+				"err": err,
+			}, "failed to refresh the health checks of the clusters")
+		}
+	})
+	if err != nil {
+		log.Error(nil, map[string]interface{}{
+			"err": err,
+		}, "failed to watch the configuration files. The configuration won't be reloaded without a restart")
+	} else {
+		defer stopWatchingConfig()
+	}
+
 	tokenManager, err := token.NewManager(config)
 	if err != nil {
 		log.Panic(nil, map[string]interface{}{
@@ -152,10 +293,20 @@ func main() {
 	service.Use(jwtMiddlewareTokenContext)
 
 	service.Use(login.InjectTokenManager(tokenManager))
+
+	// Throttle the requests to the actions exposed to brute force attacks
+	limiter, err := ratelimit.NewLimiter(config.GetRateLimitStore(), db)
+	if err != nil {
+		log.Panic(nil, map[string]interface{}{
+			"err": err,
+		}, "failed to set up rate limiting")
+	}
+	service.Use(ratelimit.Middleware(limiter, config))
+
 	service.Use(log.LogRequest(config.IsPostgresDeveloperModeEnabled()))
 	app.UseJWTMiddleware(service, jwt.New(tokenManager.PublicKeys(), nil, app.NewJWTSecurity()))
 
-	spaceAuthzService := authz.NewAuthzService(config)
+	spaceAuthzService := authz.NewAuthzService(config, appDB)
 	service.Use(authz.InjectAuthzService(spaceAuthzService))
 
 	// Mount "login" controller
@@ -167,9 +318,10 @@ func main() {
 	logoutCtrl := controller.NewLogoutController(service, &login.KeycloakLogoutService{}, config)
 	app.MountLogoutController(service, logoutCtrl)
 
-	providerFactory := link.NewOauthProviderFactory(config)
+	providerFactory := link.NewOauthProviderFactory(config, appDB)
 	linkService := link.NewLinkServiceWithFactory(config, appDB, providerFactory)
-	//providerFactory := link.NewOauthProviderFactory(configuration, appDB)
+	// The OpenShift account of the cluster of the user is linked at login
+	loginService.ClusterLinkService = linkService
 	keycloakExternalTokenService := keycloak.NewKeycloakTokenServiceClient(config)
 	// Mount "token" controller
 	tokenCtrl := controller.NewTokenController(service, appDB, loginService, linkService, providerFactory, tokenManager, &keycloakExternalTokenService, config)
@@ -180,7 +332,7 @@ func main() {
 	app.MountLinkController(service, linkCtrl)
 
 	// Mount "status" controller
-	statusCtrl := controller.NewStatusController(service, controller.NewGormDBChecker(db), config)
+	statusCtrl := controller.NewStatusController(service, controller.NewGormDBChecker(db), dependencies, config)
 	app.MountStatusController(service, statusCtrl)
 
 	// Mount "space" controller
@@ -189,10 +341,6 @@ func main() {
 
 	// Mount "user" controller
 	userCtrl := controller.NewUserController(service, appDB, tokenManager, config)
-	if config.GetTenantServiceURL() != "" {
-		log.Logger().Infof("Enabling Init Tenant service %v", config.GetTenantServiceURL())
-		userCtrl.InitTenant = account.NewInitTenant(config)
-	}
 	app.MountUserController(service, userCtrl)
 
 	// Mount "search" controller
@@ -210,6 +358,44 @@ func main() {
 	collaboratorsCtrl := controller.NewCollaboratorsController(service, appDB, config, auth.NewKeycloakPolicyManager(config))
 	app.MountCollaboratorsController(service, collaboratorsCtrl)
 
+	// Mount "access_requests" controller
+	accessRequestsCtrl := controller.NewAccessRequestsController(service, appDB, collaboratorsCtrl)
+	app.MountAccessRequestsController(service, accessRequestsCtrl)
+
+	// Mount "organizations" controller
+	organizationsCtrl := controller.NewOrganizationsController(service, appDB)
+	app.MountOrganizationsController(service, organizationsCtrl)
+
+	// Mount "teams" controller
+	teamsCtrl := controller.NewTeamsController(service, appDB)
+	app.MountTeamsController(service, teamsCtrl)
+
+	// Mount "invitations" controller
+	invitationsCtrl := controller.NewInvitationsController(service, appDB, config)
+	app.MountInvitationsController(service, invitationsCtrl)
+
+	// Mount "clusters" controller
+	clustersCtrl := controller.NewClustersController(service, appDB)
+	app.MountClustersController(service, clustersCtrl)
+
+	// Mount "webhooks" controller
+	webhooksCtrl := controller.NewWebhooksController(service, appDB, config)
+	app.MountWebhooksController(service, webhooksCtrl)
+
+	// Deliver the user events written in the outbox to WIT, tenant, Keycloak and the webhook subscriptions
+	outboxDispatcher := dispatcher.New(appDB, config, map[string]dispatcher.Deliverer{
+		outbox.TargetWIT:      dispatcher.NewWITDeliverer(&wit.RemoteWITServiceCaller{}),
+		outbox.TargetTenant:   dispatcher.NewTenantDeliverer(config),
+		outbox.TargetKeycloak: dispatcher.NewCollaboratorDeliverer(auth.NewKeycloakPolicyManager(config)),
+		outbox.TargetWebhook:  dispatcher.NewWebhookDeliverer(config),
+	})
+	stopOutboxDispatcher := outboxDispatcher.Start(tokencontext.ContextWithTokenManager(context.Background(), tokenManager))
+	defer stopOutboxDispatcher()
+
+	// Remove the expired role assignments and space collaborators
+	stopExpirySweeper := expiry.New(appDB, config).Start(context.Background())
+	defer stopExpirySweeper()
+
 	log.Logger().Infoln("Git Commit SHA: ", controller.Commit)
 	log.Logger().Infoln("UTC Build Time: ", controller.BuildTime)
 	log.Logger().Infoln("UTC Start Time: ", controller.StartTime)
@@ -238,8 +424,26 @@ func main() {
 		}(config.GetMetricsHTTPAddress())
 	}
 
+	server := &http.Server{Addr: config.GetHTTPAddress()}
+	if config.IsTracingEnabled() {
+		// The deferred calls are only run if the server is stopped: stop it gracefully on SIGINT and SIGTERM
+		// so the trace file is closed after the last spans are written
+		stop := make(chan os.Signal, 1)
+		signal.Notify(stop, syscall.SIGINT, syscall.SIGTERM)
+		go func() {
+			<-stop
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+			if err := server.Shutdown(ctx); err != nil {
+				log.Error(nil, map[string]interface{}{
+					"err": err,
+				}, "failed to stop the server gracefully")
+			}
+		}()
+	}
+
 	// Start http
-	if err := http.ListenAndServe(config.GetHTTPAddress(), nil); err != nil {
+	if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
 		log.Error(nil, map[string]interface{}{
 			"addr": config.GetHTTPAddress(),
 			"err":  err,
@@ -263,6 +467,20 @@ func configFileFromFlags(flagName string, envVarName string) string {
 	return ""
 }
 
+// registerClusterCheckers replaces the health checks of the clusters with the checks of the clusters of the cluster registry
+func registerClusterCheckers(dependencies *health.Registry, appDB application.DB) error {
+	clusters, err := appDB.Clusters().List(context.Background())
+	if err != nil {
+		return err
+	}
+	checkers := make([]health.DependencyChecker, 0, len(clusters))
+	for _, cluster := range clusters {
+		checkers = append(checkers, health.NewHTTPChecker("oso-cluster-"+cluster.Name, cluster.URL+"/healthz"))
+	}
+	dependencies.Replace("oso-cluster-", checkers, false)
+	return nil
+}
+
 func printUserInfo() {
 	u, err := user.Current()
 	if err != nil {
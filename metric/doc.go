@@ -0,0 +1,5 @@
+// Package metric provides the Prometheus metrics of the business events of the service
+// (logins, account linking, external token retrievals, service account token exchanges)
+// and of the requests sent to the dependencies of the service.
+// The metrics are registered in the default Prometheus registry which is served on /metrics.
+package metric
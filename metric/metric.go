@@ -0,0 +1,106 @@
+package metric
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	namespace = "auth"
+
+	// ResultSuccess is the value of the "result" label of successful events
+	ResultSuccess = "success"
+	// ResultFailure is the value of the "result" label of failed events
+	ResultFailure = "failure"
+)
+
+// The reasons of the login failures
+const (
+	LoginReasonUnknownState        = "unknown_state"
+	LoginReasonCodeExchangeFailed  = "code_exchange_failed"
+	LoginReasonInvalidReferrer     = "invalid_referrer"
+	LoginReasonUserNotApproved     = "user_not_approved"
+	LoginReasonIdentityUpdateError = "identity_update_failed"
+	LoginReasonTokenEncodingError  = "token_encoding_failed"
+)
+
+var (
+	logins = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "logins_total",
+		Help:      "Number of user logins by result and failure reason.",
+	}, []string{"result", "reason"})
+
+	accountLinks = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "account_links_total",
+		Help:      "Number of accounts linked to an external provider by provider and result.",
+	}, []string{"provider", "result"})
+
+	externalTokenRetrievals = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "external_token_retrievals_total",
+		Help:      "Number of external provider token retrievals by provider and result.",
+	}, []string{"provider", "result"})
+
+	externalTokenCacheHits = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "external_token_cache_hits_total",
+		Help:      "Number of external provider tokens retrieved from the database without calling Keycloak, by provider.",
+	}, []string{"provider"})
+
+	serviceAccountTokenExchanges = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "service_account_token_exchanges_total",
+		Help:      "Number of service account token exchanges by service account and result.",
+	}, []string{"service_account", "result"})
+
+	outboundRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "outbound_request_duration_seconds",
+		Help:      "Duration of the requests sent to the dependencies of the service by dependency, method and response status code.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"dependency", "method", "code"})
+)
+
+func init() {
+	prometheus.MustRegister(logins, accountLinks, externalTokenRetrievals, externalTokenCacheHits, serviceAccountTokenExchanges, outboundRequestDuration)
+}
+
+// RecordLogin counts a successful login
+func RecordLogin() {
+	logins.WithLabelValues(ResultSuccess, "").Inc()
+}
+
+// RecordLoginFailure counts a failed login. The reason is one of the LoginReason* constants.
+func RecordLoginFailure(reason string) {
+	logins.WithLabelValues(ResultFailure, reason).Inc()
+}
+
+// RecordAccountLink counts an attempt to link an account to the given provider, e.g. "github".
+// The attempt failed if err is not nil.
+func RecordAccountLink(provider string, err error) {
+	accountLinks.WithLabelValues(provider, result(err)).Inc()
+}
+
+// RecordExternalTokenRetrieval counts a retrieval of an external token of the given provider.
+// The token was found in the database if cacheHit is true.
+// The retrieval failed if err is not nil.
+func RecordExternalTokenRetrieval(provider string, cacheHit bool, err error) {
+	externalTokenRetrievals.WithLabelValues(provider, result(err)).Inc()
+	if cacheHit && err == nil {
+		externalTokenCacheHits.WithLabelValues(provider).Inc()
+	}
+}
+
+// RecordServiceAccountTokenExchange counts an exchange of service account credentials for a token.
+// Use "unknown" as the service account name if the client ID doesn't match any service account.
+func RecordServiceAccountTokenExchange(serviceAccount string, err error) {
+	serviceAccountTokenExchanges.WithLabelValues(serviceAccount, result(err)).Inc()
+}
+
+func result(err error) string {
+	if err != nil {
+		return ResultFailure
+	}
+	return ResultSuccess
+}
@@ -0,0 +1,100 @@
+package metric_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/fabric8-services/fabric8-auth/metric"
+	"github.com/fabric8-services/fabric8-auth/resource"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// find returns the sample of the metric with the given name and labels or nil if not found
+func find(t *testing.T, name string, labels map[string]string) *dto.Metric {
+	families, err := prometheus.DefaultGatherer.Gather()
+	require.NoError(t, err)
+	for _, family := range families {
+		if family.GetName() != name {
+			continue
+		}
+		for _, m := range family.GetMetric() {
+			matching := 0
+			for _, label := range m.GetLabel() {
+				if value, found := labels[label.GetName()]; found && value == label.GetValue() {
+					matching++
+				}
+			}
+			if matching == len(labels) {
+				return m
+			}
+		}
+	}
+	return nil
+}
+
+func counterValue(t *testing.T, name string, labels map[string]string) float64 {
+	m := find(t, name, labels)
+	if m == nil {
+		return 0
+	}
+	return m.GetCounter().GetValue()
+}
+
+func TestRecordLogin(t *testing.T) {
+	resource.Require(t, resource.UnitTest)
+
+	successes := counterValue(t, "auth_logins_total", map[string]string{"result": "success", "reason": ""})
+	notApproved := counterValue(t, "auth_logins_total", map[string]string{"result": "failure", "reason": metric.LoginReasonUserNotApproved})
+
+	metric.RecordLogin()
+	metric.RecordLoginFailure(metric.LoginReasonUserNotApproved)
+	metric.RecordLoginFailure(metric.LoginReasonUserNotApproved)
+
+	assert.Equal(t, successes+1, counterValue(t, "auth_logins_total", map[string]string{"result": "success", "reason": ""}))
+	assert.Equal(t, notApproved+2, counterValue(t, "auth_logins_total", map[string]string{"result": "failure", "reason": metric.LoginReasonUserNotApproved}))
+}
+
+func TestRecordExternalTokenRetrieval(t *testing.T) {
+	resource.Require(t, resource.UnitTest)
+
+	labels := map[string]string{"provider": "github", "result": "success"}
+	retrievals := counterValue(t, "auth_external_token_retrievals_total", labels)
+	failures := counterValue(t, "auth_external_token_retrievals_total", map[string]string{"provider": "github", "result": "failure"})
+	hits := counterValue(t, "auth_external_token_cache_hits_total", map[string]string{"provider": "github"})
+
+	metric.RecordExternalTokenRetrieval("github", true, nil)
+	metric.RecordExternalTokenRetrieval("github", false, nil)
+	metric.RecordExternalTokenRetrieval("github", false, errors.New("token is missing"))
+
+	assert.Equal(t, retrievals+2, counterValue(t, "auth_external_token_retrievals_total", labels))
+	assert.Equal(t, failures+1, counterValue(t, "auth_external_token_retrievals_total", map[string]string{"provider": "github", "result": "failure"}))
+	assert.Equal(t, hits+1, counterValue(t, "auth_external_token_cache_hits_total", map[string]string{"provider": "github"}))
+}
+
+func TestTransportRecordsDuration(t *testing.T) {
+	resource.Require(t, resource.UnitTest)
+
+	// given
+	ts := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.WriteHeader(http.StatusNotFound)
+	}))
+	defer ts.Close()
+	require.NoError(t, metric.RegisterDependency("keycloak", ts.URL+"/auth"))
+	client := &http.Client{Transport: metric.Transport(http.DefaultTransport)}
+
+	// when
+	res, err := client.Get(ts.URL + "/auth/realms/fabric8")
+
+	// then
+	require.NoError(t, err)
+	res.Body.Close()
+	m := find(t, "auth_outbound_request_duration_seconds", map[string]string{"dependency": "keycloak", "method": "GET", "code": "404"})
+	require.NotNil(t, m)
+	assert.True(t, m.GetHistogram().GetSampleCount() > 0)
+}
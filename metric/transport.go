@@ -0,0 +1,61 @@
+package metric
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// DependencyOther is the value of the "dependency" label of the requests sent to unregistered hosts
+const DependencyOther = "other"
+
+var (
+	dependencies     = map[string]string{}
+	dependenciesLock = &sync.RWMutex{}
+)
+
+// RegisterDependency names the dependency reachable at the given base URL, e.g. "keycloak".
+// The requests sent to the host of the URL are labeled with that name.
+func RegisterDependency(name string, baseURL string) error {
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		return err
+	}
+	dependenciesLock.Lock()
+	defer dependenciesLock.Unlock()
+	dependencies[u.Host] = name
+	return nil
+}
+
+func dependency(req *http.Request) string {
+	dependenciesLock.RLock()
+	defer dependenciesLock.RUnlock()
+	if name, found := dependencies[req.URL.Host]; found {
+		return name
+	}
+	return DependencyOther
+}
+
+// Transport wraps the given transport so the duration and the response status code
+// of the outbound requests are recorded. The status code is "error" if no response was received.
+func Transport(base http.RoundTripper) http.RoundTripper {
+	return &transport{base: base}
+}
+
+type transport struct {
+	base http.RoundTripper
+}
+
+// RoundTrip sends the request with the wrapped transport and records the duration of the request
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	start := time.Now()
+	res, err := t.base.RoundTrip(req)
+	code := "error"
+	if err == nil {
+		code = strconv.Itoa(res.StatusCode)
+	}
+	outboundRequestDuration.WithLabelValues(dependency(req), req.Method, code).Observe(time.Since(start).Seconds())
+	return res, err
+}
@@ -5,10 +5,15 @@ import (
 	"bytes"
 	"context"
 	"database/sql"
+	"fmt"
+	"io"
 	"net/http"
 	"net/url"
+	"sort"
+	"strings"
 	"sync"
 	"text/template"
+	"time"
 
 	"github.com/fabric8-services/fabric8-auth/log"
 
@@ -21,6 +26,18 @@ import (
 // by anybody who wants to modify the "version" table.
 const AdvisoryLockID = 42
 
+// MigrationLockID is the key of the cluster-wide advisory lock held for the whole
+// duration of a migration run, so that only one auth instance at a time migrates the database.
+// It must be different from AdvisoryLockID which is obtained for every single version
+// while the migration lock is held.
+const MigrationLockID = 4242
+
+// downSQLFileSuffix is the suffix of the SQL files which revert a version
+const downSQLFileSuffix = ".down.sql"
+
+// migrationLockRetryInterval is the interval between two attempts to obtain the migration lock
+const migrationLockRetryInterval = time.Second
+
 // fn defines the type of function that can be part of a migration steps
 type fn func(tx *sql.Tx) error
 
@@ -33,13 +50,20 @@ type Migrations []steps
 // mutex variable to lock/unlock the population of common types
 var populateLocker = &sync.Mutex{}
 
+// sqlEcho is the writer the executed SQL scripts are printed to during a dry run.
+// It's only set while populateLocker is held.
+var sqlEcho io.Writer
+
 type MigrationConfiguration interface {
 	GetOpenShiftClientApiUrl() string
+	GetPostgresMigrationLockTimeout() time.Duration
 }
 
 // Migrate executes the required migration of the database on startup.
 // For each successful migration, an entry will be written into the "version"
 // table, that states when a certain version was reached.
+// The whole migration is guarded by a cluster-wide advisory lock, so several
+// instances can be started concurrently against the same database.
 func Migrate(db *sql.DB, catalog string, configuration MigrationConfiguration) error {
 
 	var err error
@@ -47,8 +71,22 @@ func Migrate(db *sql.DB, catalog string, configuration MigrationConfiguration) e
 		return errs.Errorf("Database handle is nil\n")
 	}
 
-	m := GetMigrations(configuration)
+	populateLocker.Lock()
+	defer populateLocker.Unlock()
+
+	lockTx, err := acquireMigrationLock(db, configuration.GetPostgresMigrationLockTimeout())
+	if err != nil {
+		return err
+	}
+	defer releaseMigrationLock(lockTx)
+
+	return migrateUp(db, GetMigrations(configuration), catalog)
+}
 
+// migrateUp applies all the given migrations which have not been applied yet,
+// each version within its own transaction.
+func migrateUp(db *sql.DB, m Migrations, catalog string) error {
+	var err error
 	var tx *sql.Tx
 	for nextVersion := int64(0); nextVersion < int64(len(m)) && err == nil; nextVersion++ {
 
@@ -99,6 +137,251 @@ func Migrate(db *sql.DB, catalog string, configuration MigrationConfiguration) e
 	return nil
 }
 
+// Status holds the version the database is at and the versions which are not applied yet
+type Status struct {
+	CurrentVersion int64
+	LatestVersion  int64
+	Pending        []int64
+}
+
+// GetStatus returns the current and the pending versions of the database
+func GetStatus(db *sql.DB, catalog string, configuration MigrationConfiguration) (*Status, error) {
+	if db == nil {
+		return nil, errs.Errorf("Database handle is nil\n")
+	}
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, errs.Errorf("Failed to start transaction: %s\n", err)
+	}
+	defer tx.Rollback()
+
+	currentVersion, err := getCurrentVersion(tx, catalog)
+	if err != nil {
+		return nil, errs.WithStack(err)
+	}
+	m := GetMigrations(configuration)
+	status := &Status{
+		CurrentVersion: currentVersion,
+		LatestVersion:  int64(len(m)) - 1,
+		Pending:        []int64{},
+	}
+	for version := currentVersion + 1; version < int64(len(m)); version++ {
+		status.Pending = append(status.Pending, version)
+	}
+	return status, nil
+}
+
+// MigrateTo migrates the database to the given target version. If the target version is
+// higher than the current one the pending versions up to the target are applied, otherwise
+// the down migrations of all the versions above the target are executed, from the highest
+// to the lowest one. Each version is migrated within its own transaction.
+// No down migration is executed if any of the versions to roll back has none.
+func MigrateTo(db *sql.DB, catalog string, configuration MigrationConfiguration, targetVersion int64) error {
+	if db == nil {
+		return errs.Errorf("Database handle is nil\n")
+	}
+	m := GetMigrations(configuration)
+	if targetVersion < 0 || targetVersion >= int64(len(m)) {
+		return errs.Errorf("Unknown target version %d. Versions range from 0 to %d\n", targetVersion, len(m)-1)
+	}
+
+	populateLocker.Lock()
+	defer populateLocker.Unlock()
+
+	lockTx, err := acquireMigrationLock(db, configuration.GetPostgresMigrationLockTimeout())
+	if err != nil {
+		return err
+	}
+	defer releaseMigrationLock(lockTx)
+
+	currentVersion, err := getCurrentVersion(lockTx, catalog)
+	if err != nil {
+		return errs.WithStack(err)
+	}
+	if targetVersion >= currentVersion {
+		return migrateUp(db, m[:targetVersion+1], catalog)
+	}
+
+	down := GetDownMigrations(configuration)
+	for version := currentVersion; version > targetVersion; version-- {
+		if len(down[version]) == 0 {
+			return errs.Errorf("Version %d has no down migration. Can't migrate from version %d to version %d\n", version, currentVersion, targetVersion)
+		}
+	}
+	for version := currentVersion; version > targetVersion; version-- {
+		tx, err := db.Begin()
+		if err != nil {
+			return errs.Errorf("Failed to start transaction: %s\n", err)
+		}
+		if err := MigrateToPreviousVersion(tx, version, down, catalog); err != nil {
+			log.Info(nil, map[string]interface{}{
+				"version": version,
+				"err":     err,
+			}, "Rolling back transaction due to: %v", err)
+			if rbErr := tx.Rollback(); rbErr != nil {
+				return errs.Errorf("Error while rolling back transaction: %s\n", rbErr)
+			}
+			return err
+		}
+		if err := tx.Commit(); err != nil {
+			log.Error(nil, map[string]interface{}{
+				"version": version,
+				"err":     err,
+			}, "error during transaction commit: %v", err)
+			return errs.Errorf("Error during transaction commit: %s\n", err)
+		}
+	}
+	return nil
+}
+
+// MigrateToPreviousVersion reverts the given version by executing its down migration steps
+// and removing it from the "version" table. The given version must be the current version of the database.
+func MigrateToPreviousVersion(tx *sql.Tx, version int64, down Migrations, catalog string) error {
+	if _, err := tx.Exec("SELECT pg_advisory_xact_lock($1)", AdvisoryLockID); err != nil {
+		return errs.Errorf("Failed to acquire lock: %s\n", err)
+	}
+
+	currentVersion, err := getCurrentVersion(tx, catalog)
+	if err != nil {
+		return errs.WithStack(err)
+	}
+	if currentVersion != version {
+		return errs.Errorf("Can't revert version %d. The current version is %d\n", version, currentVersion)
+	}
+	if version >= int64(len(down)) || len(down[version]) == 0 {
+		return errs.Errorf("Version %d has no down migration\n", version)
+	}
+
+	log.Info(nil, map[string]interface{}{
+		"current_version": currentVersion,
+	}, "Attempt to revert DB version %v", version)
+
+	for j := range down[version] {
+		if err := down[version][j](tx); err != nil {
+			return errs.Errorf("Failed to execute down migration of step %d of version %d: %s\n", j, version, err)
+		}
+	}
+
+	if _, err := tx.Exec("DELETE FROM version WHERE version = $1", version); err != nil {
+		return errs.Errorf("Failed to revert DB version %d: %s\n", version, err)
+	}
+
+	log.Info(nil, map[string]interface{}{
+		"current_version": version - 1,
+	}, "Successfully reverted DB version %v", version)
+
+	return nil
+}
+
+// DryRun prints the pending versions and the SQL they would execute to the given writer
+// without applying them. All the pending versions are executed within a single transaction
+// which is always rolled back, so the SQL is checked against the actual database schema.
+func DryRun(db *sql.DB, catalog string, configuration MigrationConfiguration, w io.Writer) error {
+	if db == nil {
+		return errs.Errorf("Database handle is nil\n")
+	}
+
+	populateLocker.Lock()
+	defer populateLocker.Unlock()
+
+	lockTx, err := acquireMigrationLock(db, configuration.GetPostgresMigrationLockTimeout())
+	if err != nil {
+		return err
+	}
+	defer releaseMigrationLock(lockTx)
+
+	m := GetMigrations(configuration)
+
+	tx, err := db.Begin()
+	if err != nil {
+		return errs.Errorf("Failed to start transaction: %s\n", err)
+	}
+	// Nothing executed during the dry run must be committed
+	defer tx.Rollback()
+
+	currentVersion, err := getCurrentVersion(tx, catalog)
+	if err != nil {
+		return errs.WithStack(err)
+	}
+	if currentVersion+1 >= int64(len(m)) {
+		fmt.Fprintf(w, "-- Current version %d. Nothing to update.\n", currentVersion)
+		return nil
+	}
+	fmt.Fprintf(w, "-- Current version %d. Pending versions: %d to %d\n", currentVersion, currentVersion+1, len(m)-1)
+
+	sqlEcho = w
+	defer func() {
+		sqlEcho = nil
+	}()
+	for version := currentVersion + 1; version < int64(len(m)); version++ {
+		fmt.Fprintf(w, "\n-- Version %d\n", version)
+		for j := range m[version] {
+			fmt.Fprintf(w, "-- Step %d\n", j)
+			if err := m[version][j](tx); err != nil {
+				return errs.Errorf("Failed to execute migration of step %d of version %d: %s\n", j, version, err)
+			}
+		}
+	}
+	return nil
+}
+
+// acquireMigrationLock obtains the cluster-wide migration lock, waiting up to the given timeout
+// if another instance holds it. The lock is a transaction level advisory lock owned by the returned
+// transaction. It's released by releaseMigrationLock or as soon as the connection is lost, e.g. when
+// the pod holding it gets killed.
+func acquireMigrationLock(db *sql.DB, timeout time.Duration) (*sql.Tx, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, errs.Errorf("Failed to start transaction: %s\n", err)
+	}
+	start := time.Now()
+	waiting := false
+	for {
+		var locked bool
+		if err := tx.QueryRow("SELECT pg_try_advisory_xact_lock($1)", MigrationLockID).Scan(&locked); err != nil {
+			tx.Rollback()
+			return nil, errs.Errorf("Failed to acquire migration lock: %s\n", err)
+		}
+		if locked {
+			log.Info(nil, map[string]interface{}{
+				"lock_id": MigrationLockID,
+				"waited":  time.Since(start).String(),
+			}, "Acquired the database migration lock")
+			return tx, nil
+		}
+		if time.Since(start) >= timeout {
+			tx.Rollback()
+			log.Error(nil, map[string]interface{}{
+				"lock_id": MigrationLockID,
+				"timeout": timeout.String(),
+			}, "timed out waiting for the database migration lock")
+			return nil, errs.Errorf("Timed out after %v waiting for the database migration lock\n", timeout)
+		}
+		if !waiting {
+			log.Info(nil, map[string]interface{}{
+				"lock_id": MigrationLockID,
+				"timeout": timeout.String(),
+			}, "The database is being migrated by another instance. Waiting up to %v for the migration lock...", timeout)
+			waiting = true
+		}
+		time.Sleep(migrationLockRetryInterval)
+	}
+}
+
+// releaseMigrationLock releases the migration lock obtained by acquireMigrationLock
+func releaseMigrationLock(lockTx *sql.Tx) {
+	if err := lockTx.Rollback(); err != nil {
+		log.Error(nil, map[string]interface{}{
+			"lock_id": MigrationLockID,
+			"err":     err,
+		}, "failed to release the database migration lock")
+		return
+	}
+	log.Info(nil, map[string]interface{}{
+		"lock_id": MigrationLockID,
+	}, "Released the database migration lock")
+}
+
 // GetMigrations returns the migrations all the migrations we have.
 // Add your own migration to the end of this function.
 // IMPORTANT: ALWAYS APPEND AT THE END AND DON'T CHANGE THE ORDER OF MIGRATIONS!
@@ -143,6 +426,39 @@ func GetMigrations(configuration MigrationConfiguration) Migrations {
 	// version 11
 	m = append(m, steps{ExecuteSQLFile("011-add-username-to-external-token.sql")})
 
+	// version 12
+	m = append(m, steps{ExecuteSQLFile("012-rate-limit-buckets.sql")})
+
+	// version 13
+	m = append(m, steps{ExecuteSQLFile("013-outbox-events.sql")})
+
+	// version 14
+	m = append(m, steps{ExecuteSQLFile("014-organizations-teams.sql")})
+
+	// version 15
+	m = append(m, steps{ExecuteSQLFile("015-invitations.sql")})
+
+	// version 16
+	m = append(m, steps{ExecuteSQLFile("016-space-access-requests.sql")})
+
+	// version 17
+	m = append(m, steps{ExecuteSQLFile("017-role-expiry.sql")})
+
+	// version 18
+	m = append(m, steps{ExecuteSQLFile("018-clusters.sql")})
+
+	// version 19
+	m = append(m, steps{ExecuteSQLFile("019-tenant-provisionings.sql")})
+
+	// version 20
+	m = append(m, steps{ExecuteSQLFile("020-webhooks.sql")})
+
+	// version 21
+	m = append(m, steps{ExecuteSQLFile("021-users-email-verified.sql")})
+
+	// version 22
+	m = append(m, steps{ExecuteSQLFile("022-users-cluster-link-required.sql")})
+
 	// Version N
 	//
 	// In order to add an upgrade, simply append an array of MigrationFunc to the
@@ -170,6 +486,31 @@ func GetMigrations(configuration MigrationConfiguration) Migrations {
 	return m
 }
 
+// GetDownMigrations returns the down migrations of all the versions. The down migrations of a version
+// are the steps which revert it. They are optional: for versions without any down migration
+// the corresponding entry is empty.
+// Down migrations are packaged SQL files named after the version they revert: NNN-*.down.sql
+func GetDownMigrations(configuration MigrationConfiguration) Migrations {
+	m := GetMigrations(configuration)
+	down := make(Migrations, len(m))
+	names := AssetNames()
+	sort.Strings(names)
+	for _, name := range names {
+		if !strings.HasSuffix(name, downSQLFileSuffix) {
+			continue
+		}
+		var version int64
+		if _, err := fmt.Sscanf(name, "%03d-", &version); err != nil || version < 0 || version >= int64(len(m)) {
+			log.Warn(nil, map[string]interface{}{
+				"filename": name,
+			}, "ignoring down migration file which doesn't match any version")
+			continue
+		}
+		down[version] = append(down[version], ExecuteSQLFile(name))
+	}
+	return down
+}
+
 // ExecuteSQLFile loads the given filename from the packaged SQL files and
 // executes it on the given database. Golang text/template module is used
 // to handle all the optional arguments passed to the sql files
@@ -180,6 +521,7 @@ func ExecuteSQLFile(filename string, args ...string) fn {
 			return errs.Wrapf(err, "failed to find filename: %s", filename)
 		}
 
+		script := string(data)
 		if len(args) > 0 {
 			tmpl, err := template.New("sql").Parse(string(data))
 			if err != nil {
@@ -194,21 +536,17 @@ func ExecuteSQLFile(filename string, args ...string) fn {
 			}
 			// We need to flush the content of the writer
 			writer.Flush()
+			script = sqlScript.String()
+		}
 
-			_, err = db.Exec(sqlScript.String())
-			if err != nil {
-				log.Error(context.Background(), map[string]interface{}{
-					"err": err,
-				}, "failed to execute this query: \n\n%s\n\n", sqlScript.String())
-			}
-
-		} else {
-			_, err = db.Exec(string(data))
-			if err != nil {
-				log.Error(context.Background(), map[string]interface{}{
-					"err": err,
-				}, "failed to execute this query: \n\n%s\n\n", string(data))
-			}
+		if sqlEcho != nil {
+			fmt.Fprintf(sqlEcho, "-- %s\n%s\n", filename, script)
+		}
+		_, err = db.Exec(script)
+		if err != nil {
+			log.Error(context.Background(), map[string]interface{}{
+				"err": err,
+			}, "failed to execute this query: \n\n%s\n\n", script)
 		}
 
 		return errs.WithStack(err)
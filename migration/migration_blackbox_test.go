@@ -103,11 +103,70 @@ func TestMigrations(t *testing.T) {
 	t.Run("TestMigration09", testMigration09)
 	t.Run("TestMigration10", testMigration10)
 	t.Run("TestMigration11", testMigration11)
+	t.Run("TestMigration12", testMigration12)
+	t.Run("TestMigration13", testMigration13)
+	t.Run("TestMigration14", testMigration14)
+	t.Run("TestMigration15", testMigration15)
+	t.Run("TestMigration16", testMigration16)
+	t.Run("TestMigration17", testMigration17)
+	t.Run("TestMigration18", testMigration18)
+	t.Run("TestMigration19", testMigration19)
+	t.Run("TestMigration20", testMigration20)
+	t.Run("TestMigration21", testMigration21)
+	t.Run("TestMigration22", testMigration22)
 
 	// Perform the migration
 	if err := migration.Migrate(sqlDB, databaseName, conf); err != nil {
 		t.Fatalf("Failed to execute the migration: %s\n", err)
 	}
+
+	t.Run("TestMigrateDown", testMigrateDown)
+}
+
+func testMigrateDown(t *testing.T) {
+	latest := int64(len(migrations) - 1)
+	status, err := migration.GetStatus(sqlDB, databaseName, conf)
+	require.Nil(t, err)
+	assert.Equal(t, latest, status.CurrentVersion)
+	assert.Empty(t, status.Pending)
+
+	// Revert versions 20, 19, 18, 17, 16, 15, 14, 13, 12, 11 and 10
+	require.Nil(t, migration.MigrateTo(sqlDB, databaseName, conf, 9))
+	assert.False(t, dialect.HasTable("webhook_deliveries"))
+	assert.False(t, dialect.HasTable("webhook_subscriptions"))
+	assert.False(t, dialect.HasTable("tenant_provisionings"))
+	assert.False(t, dialect.HasTable("clusters"))
+	assert.False(t, dialect.HasTable("audit_events"))
+	assert.False(t, dialect.HasTable("space_collaborator_expiries"))
+	assert.False(t, dialect.HasTable("space_access_requests"))
+	assert.False(t, dialect.HasTable("invitations"))
+	assert.False(t, dialect.HasTable("space_teams"))
+	assert.False(t, dialect.HasTable("space_team_grants"))
+	assert.False(t, dialect.HasTable("outbox_events"))
+	assert.False(t, dialect.HasTable("rate_limit_buckets"))
+	assert.False(t, dialect.HasColumn("external_tokens", "username"))
+	assert.False(t, dialect.HasColumn("users", "cluster"))
+	status, err = migration.GetStatus(sqlDB, databaseName, conf)
+	require.Nil(t, err)
+	assert.Equal(t, int64(9), status.CurrentVersion)
+	assert.Equal(t, []int64{10, 11}, status.Pending[:2])
+
+	// Version 9 has no down migration
+	assert.NotNil(t, migration.MigrateTo(sqlDB, databaseName, conf, 8))
+	assert.True(t, dialect.HasTable("external_tokens"))
+
+	// Migrate forward again
+	require.Nil(t, migration.MigrateTo(sqlDB, databaseName, conf, latest))
+	assert.True(t, dialect.HasColumn("external_tokens", "username"))
+	assert.True(t, dialect.HasColumn("users", "cluster"))
+	assert.True(t, dialect.HasTable("rate_limit_buckets"))
+	assert.True(t, dialect.HasTable("outbox_events"))
+	assert.True(t, dialect.HasTable("space_teams"))
+	assert.True(t, dialect.HasTable("space_team_grants"))
+	assert.True(t, dialect.HasTable("space_access_requests"))
+	assert.True(t, dialect.HasTable("invitations"))
+	assert.True(t, dialect.HasTable("audit_events"))
+	assert.True(t, dialect.HasColumn("identity_role", "expires_at"))
 }
 
 func testMigration01(t *testing.T) {
@@ -176,6 +235,88 @@ func testMigration11(t *testing.T) {
 	assert.True(t, dialect.HasColumn("external_tokens", "username"))
 }
 
+func testMigration12(t *testing.T) {
+	migrateToVersion(sqlDB, migrations[:(13)], (13))
+
+	assert.True(t, dialect.HasTable("rate_limit_buckets"))
+}
+
+func testMigration13(t *testing.T) {
+	migrateToVersion(sqlDB, migrations[:(14)], (14))
+
+	assert.True(t, dialect.HasTable("outbox_events"))
+	assert.True(t, dialect.HasIndex("outbox_events", "idx_outbox_events_due"))
+}
+
+func testMigration14(t *testing.T) {
+	migrateToVersion(sqlDB, migrations[:(15)], (15))
+
+	assert.True(t, dialect.HasTable("space_teams"))
+	assert.True(t, dialect.HasColumn("space_teams", "auth_url"))
+	assert.True(t, dialect.HasTable("space_team_grants"))
+	assert.True(t, dialect.HasIndex("identity_role", "idx_identity_role_unique"))
+	var count int
+	err := sqlDB.QueryRow("SELECT count(*) FROM role r JOIN resource_type rt ON r.resource_type_id = rt.resource_type_id WHERE rt.name IN ('organization', 'team')").Scan(&count)
+	require.Nil(t, err)
+	assert.Equal(t, 4, count)
+}
+
+func testMigration15(t *testing.T) {
+	migrateToVersion(sqlDB, migrations[:(16)], (16))
+
+	assert.True(t, dialect.HasTable("invitations"))
+	assert.True(t, dialect.HasIndex("invitations", "idx_invitations_email"))
+}
+
+func testMigration16(t *testing.T) {
+	migrateToVersion(sqlDB, migrations[:(17)], (17))
+
+	assert.True(t, dialect.HasTable("space_access_requests"))
+	assert.True(t, dialect.HasIndex("space_access_requests", "idx_space_access_requests_pending"))
+}
+
+func testMigration17(t *testing.T) {
+	migrateToVersion(sqlDB, migrations[:(18)], (18))
+
+	assert.True(t, dialect.HasColumn("identity_role", "expires_at"))
+	assert.True(t, dialect.HasTable("space_collaborator_expiries"))
+	assert.True(t, dialect.HasTable("audit_events"))
+}
+
+func testMigration18(t *testing.T) {
+	migrateToVersion(sqlDB, migrations[:(19)], (19))
+
+	assert.True(t, dialect.HasTable("clusters"))
+	assert.True(t, dialect.HasIndex("clusters", "idx_clusters_url"))
+}
+
+func testMigration19(t *testing.T) {
+	migrateToVersion(sqlDB, migrations[:(20)], (20))
+
+	assert.True(t, dialect.HasTable("tenant_provisionings"))
+	assert.True(t, dialect.HasIndex("tenant_provisionings", "idx_tenant_provisionings_state"))
+}
+
+func testMigration20(t *testing.T) {
+	migrateToVersion(sqlDB, migrations[:(21)], (21))
+
+	assert.True(t, dialect.HasTable("webhook_subscriptions"))
+	assert.True(t, dialect.HasTable("webhook_deliveries"))
+	assert.True(t, dialect.HasIndex("webhook_deliveries", "idx_webhook_deliveries_subscription_id"))
+}
+
+func testMigration21(t *testing.T) {
+	migrateToVersion(sqlDB, migrations[:(22)], (22))
+
+	assert.True(t, dialect.HasColumn("users", "email_verified"))
+}
+
+func testMigration22(t *testing.T) {
+	migrateToVersion(sqlDB, migrations[:(23)], (23))
+
+	assert.True(t, dialect.HasColumn("users", "cluster_link_required"))
+}
+
 // runSQLscript loads the given filename from the packaged SQL test files and
 // executes it on the given database. Golang text/template module is used
 // to handle all the optional arguments passed to the sql test files
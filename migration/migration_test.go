@@ -1,16 +1,19 @@
 package migration
 
 import (
+	"bytes"
 	"database/sql"
 	"fmt"
 	"sync"
 	"testing"
+	"time"
 
 	config "github.com/fabric8-services/fabric8-auth/configuration"
 	"github.com/fabric8-services/fabric8-auth/resource"
 
 	_ "github.com/lib/pq"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestConcurrentMigrations(t *testing.T) {
@@ -39,3 +42,47 @@ func TestConcurrentMigrations(t *testing.T) {
 	}
 	wg.Wait()
 }
+
+func TestMigrationLockTimeout(t *testing.T) {
+	resource.Require(t, resource.Database)
+
+	configuration, err := config.GetConfigurationData()
+	require.Nil(t, err)
+	db, err := sql.Open("postgres", configuration.GetPostgresConfigString())
+	require.Nil(t, err)
+	defer db.Close()
+
+	// Hold the migration lock as if another instance was migrating the database
+	lockTx, err := acquireMigrationLock(db, time.Second)
+	require.Nil(t, err)
+
+	_, err = acquireMigrationLock(db, 2*time.Second)
+	require.NotNil(t, err)
+	assert.Contains(t, err.Error(), "waiting for the database migration lock")
+
+	// Once released the lock can be obtained again
+	releaseMigrationLock(lockTx)
+	lockTx, err = acquireMigrationLock(db, time.Second)
+	require.Nil(t, err)
+	releaseMigrationLock(lockTx)
+}
+
+func TestDryRun(t *testing.T) {
+	resource.Require(t, resource.Database)
+
+	configuration, err := config.GetConfigurationData()
+	require.Nil(t, err)
+	db, err := sql.Open("postgres", configuration.GetPostgresConfigString())
+	require.Nil(t, err)
+	defer db.Close()
+
+	err = Migrate(db, configuration.GetPostgresDatabase(), configuration)
+	require.Nil(t, err)
+
+	// The database is up to date so there is nothing to print but the current version
+	var out bytes.Buffer
+	err = DryRun(db, configuration.GetPostgresDatabase(), configuration, &out)
+	require.Nil(t, err)
+	assert.Contains(t, out.String(), "Nothing to update")
+	assert.Nil(t, sqlEcho)
+}
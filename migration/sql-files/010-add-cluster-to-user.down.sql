@@ -0,0 +1,2 @@
+-- Revert 010-add-cluster-to-user.sql
+ALTER TABLE users DROP COLUMN cluster;
@@ -0,0 +1,2 @@
+-- Revert 011-add-username-to-external-token.sql
+ALTER TABLE external_tokens DROP COLUMN username;
@@ -0,0 +1,2 @@
+-- Revert 012-rate-limit-buckets.sql
+DROP TABLE rate_limit_buckets;
@@ -0,0 +1,2 @@
+-- Revert 013-outbox-events.sql
+DROP TABLE outbox_events;
@@ -0,0 +1,20 @@
+-- Events written in the same transaction as the users and identities they are about,
+-- and delivered to the other services (WIT, tenant) by the outbox dispatcher
+CREATE TABLE outbox_events (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP WITH TIME ZONE,
+    updated_at TIMESTAMP WITH TIME ZONE,
+    deleted_at TIMESTAMP WITH TIME ZONE,
+    event_type TEXT NOT NULL,
+    target TEXT NOT NULL,
+    identity_id UUID NOT NULL,
+    payload JSONB,
+    state TEXT NOT NULL DEFAULT 'pending',
+    attempts INTEGER NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    last_error TEXT,
+    delivered_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX idx_outbox_events_due ON outbox_events (next_attempt_at) WHERE state = 'pending' AND deleted_at IS NULL;
+CREATE INDEX idx_outbox_events_identity_id ON outbox_events (identity_id);
@@ -0,0 +1,15 @@
+-- Revert 014-organizations-teams.sql
+-- The well-known resource types and roles are kept since resources may still refer to them
+DROP TABLE space_team_grants;
+DROP TABLE space_teams;
+DROP INDEX idx_resource_parent_resource_id;
+DROP INDEX idx_identity_role_resource_id;
+DROP INDEX idx_identity_role_unique;
+
+ALTER TABLE identity_role DROP CONSTRAINT identity_role_pkey;
+ALTER TABLE identity_role ALTER COLUMN identity_role_id DROP DEFAULT;
+CREATE SEQUENCE identity_role_identity_role_id_seq;
+ALTER TABLE identity_role ALTER COLUMN identity_role_id TYPE integer USING nextval('identity_role_identity_role_id_seq');
+ALTER TABLE identity_role ALTER COLUMN identity_role_id SET DEFAULT nextval('identity_role_identity_role_id_seq');
+ALTER SEQUENCE identity_role_identity_role_id_seq OWNED BY identity_role.identity_role_id;
+ALTER TABLE identity_role ADD PRIMARY KEY (identity_role_id);
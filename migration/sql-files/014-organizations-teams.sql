@@ -0,0 +1,44 @@
+-- identity_role rows are identified by UUIDs, as all the other authorization records
+ALTER TABLE identity_role DROP CONSTRAINT identity_role_pkey;
+ALTER TABLE identity_role ALTER COLUMN identity_role_id DROP DEFAULT;
+ALTER TABLE identity_role ALTER COLUMN identity_role_id TYPE uuid USING uuid_generate_v4();
+ALTER TABLE identity_role ALTER COLUMN identity_role_id SET DEFAULT uuid_generate_v4();
+ALTER TABLE identity_role ADD PRIMARY KEY (identity_role_id);
+DROP SEQUENCE IF EXISTS identity_role_identity_role_id_seq;
+
+CREATE UNIQUE INDEX idx_identity_role_unique ON identity_role (identity_id, resource_id, role_id) WHERE deleted_at IS NULL;
+CREATE INDEX idx_identity_role_resource_id ON identity_role (resource_id);
+CREATE INDEX idx_resource_parent_resource_id ON resource (parent_resource_id);
+
+-- well-known resource types of the organizations and teams, and their roles
+INSERT INTO resource_type (name, description, created_at, updated_at)
+    SELECT t.name, t.description, now(), now()
+    FROM (VALUES ('organization', 'An organization groups identities and teams'),
+                 ('team', 'A team groups identities of an organization')) AS t(name, description)
+    WHERE NOT EXISTS (SELECT 1 FROM resource_type WHERE resource_type.name = t.name AND deleted_at IS NULL);
+
+INSERT INTO role (resource_type_id, name, created_at, updated_at)
+    SELECT rt.resource_type_id, r.name, now(), now()
+    FROM resource_type rt, (VALUES ('admin'), ('member')) AS r(name)
+    WHERE rt.name IN ('organization', 'team')
+    AND rt.deleted_at IS NULL
+    AND NOT EXISTS (SELECT 1 FROM role WHERE role.resource_type_id = rt.resource_type_id AND role.name = r.name AND role.deleted_at IS NULL);
+
+-- teams added as space collaborators, whose members are added to the Keycloak policy of the space
+CREATE TABLE space_teams (
+    space_id uuid NOT NULL,
+    team_id uuid NOT NULL references resource(resource_id),
+    auth_url text NOT NULL,
+    created_at timestamp with time zone,
+    PRIMARY KEY (space_id, team_id)
+);
+
+CREATE INDEX idx_space_teams_team_id ON space_teams (team_id);
+
+-- space collaborators listed in the Keycloak policy of the space as members of the teams collaborating on the space
+CREATE TABLE space_team_grants (
+    space_id uuid NOT NULL,
+    identity_id uuid NOT NULL references identities(id) ON DELETE CASCADE,
+    created_at timestamp with time zone,
+    PRIMARY KEY (space_id, identity_id)
+);
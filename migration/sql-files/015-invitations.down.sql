@@ -0,0 +1,2 @@
+-- Revert 015-invitations.sql
+DROP TABLE invitations;
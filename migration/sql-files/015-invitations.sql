@@ -0,0 +1,22 @@
+-- invitations to collaborate on a resource, sent to an email or a username
+CREATE TABLE invitations (
+    id uuid primary key DEFAULT uuid_generate_v4(),
+    created_at timestamp with time zone,
+    updated_at timestamp with time zone,
+    deleted_at timestamp with time zone,
+    resource_type text NOT NULL,
+    resource_id uuid NOT NULL,
+    role text NOT NULL,
+    inviter_id uuid NOT NULL references identities(id),
+    email text NULL,
+    username text NULL,
+    expires_at timestamp with time zone NOT NULL,
+    state text NOT NULL DEFAULT 'pending',
+    accepted_by uuid NULL references identities(id),
+    accepted_at timestamp with time zone NULL,
+    CHECK (email IS NOT NULL OR username IS NOT NULL)
+);
+
+CREATE INDEX idx_invitations_resource ON invitations (resource_type, resource_id) WHERE state = 'pending';
+CREATE INDEX idx_invitations_email ON invitations (lower(email)) WHERE state = 'pending';
+CREATE INDEX idx_invitations_username ON invitations (username) WHERE state = 'pending';
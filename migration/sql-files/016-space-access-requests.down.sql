@@ -0,0 +1,2 @@
+-- Revert 016-space-access-requests.sql
+DROP TABLE space_access_requests;
@@ -0,0 +1,17 @@
+-- requests of users to join the collaborators of a space
+CREATE TABLE space_access_requests (
+    id uuid primary key DEFAULT uuid_generate_v4(),
+    created_at timestamp with time zone,
+    updated_at timestamp with time zone,
+    deleted_at timestamp with time zone,
+    space_id uuid NOT NULL,
+    requester_id uuid NOT NULL references identities(id),
+    message text,
+    state text NOT NULL DEFAULT 'pending',
+    decided_by uuid NULL references identities(id),
+    decided_at timestamp with time zone NULL
+);
+
+-- a user can't have more than one pending request per space
+CREATE UNIQUE INDEX idx_space_access_requests_pending ON space_access_requests (space_id, requester_id) WHERE state = 'pending' AND deleted_at IS NULL;
+CREATE INDEX idx_space_access_requests_space_id ON space_access_requests (space_id);
@@ -0,0 +1,5 @@
+-- Revert 017-role-expiry.sql
+DROP TABLE audit_events;
+DROP TABLE space_collaborator_expiries;
+DROP INDEX idx_identity_role_expires_at;
+ALTER TABLE identity_role DROP COLUMN expires_at;
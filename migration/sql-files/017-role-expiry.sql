@@ -0,0 +1,29 @@
+-- optional expiry of the role assignments
+ALTER TABLE identity_role ADD COLUMN expires_at timestamp with time zone NULL;
+CREATE INDEX idx_identity_role_expires_at ON identity_role (expires_at) WHERE expires_at IS NOT NULL AND deleted_at IS NULL;
+
+-- expiry of the time-bound space collaborators, who are listed in the Keycloak policy of the space
+CREATE TABLE space_collaborator_expiries (
+    space_id uuid NOT NULL,
+    identity_id uuid NOT NULL references identities(id) ON DELETE CASCADE,
+    expires_at timestamp with time zone NOT NULL,
+    auth_url text NOT NULL,
+    created_at timestamp with time zone,
+    updated_at timestamp with time zone,
+    PRIMARY KEY (space_id, identity_id)
+);
+CREATE INDEX idx_space_collaborator_expiries_expires_at ON space_collaborator_expiries (expires_at);
+
+-- audit trail of the changes of the access rights
+CREATE TABLE audit_events (
+    id uuid primary key DEFAULT uuid_generate_v4(),
+    created_at timestamp with time zone NOT NULL DEFAULT now(),
+    event_type text NOT NULL,
+    actor_id uuid NULL,
+    target_id uuid NULL,
+    resource_type text NOT NULL,
+    resource_id text NOT NULL,
+    request_id text,
+    details jsonb
+);
+CREATE INDEX idx_audit_events_resource ON audit_events (resource_type, resource_id, created_at);
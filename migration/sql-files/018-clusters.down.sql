@@ -0,0 +1,2 @@
+-- Revert 018-clusters.sql
+DROP TABLE clusters;
@@ -0,0 +1,19 @@
+-- registry of the OpenShift clusters the users are provisioned to
+-- service_account_token and auth_client_secret hold references to the secrets (like vault://path#key), not the secrets themselves
+CREATE TABLE clusters (
+    id uuid primary key DEFAULT uuid_generate_v4(),
+    created_at timestamp with time zone,
+    updated_at timestamp with time zone,
+    deleted_at timestamp with time zone,
+    name text NOT NULL,
+    url text NOT NULL,
+    console_url text,
+    service_account_token text,
+    token_provider_id uuid NOT NULL,
+    auth_client_id text NOT NULL,
+    auth_client_secret text NOT NULL,
+    auth_client_default_scope text NOT NULL,
+    capacity integer NOT NULL DEFAULT 0,
+    region text
+);
+CREATE UNIQUE INDEX idx_clusters_url ON clusters (url) WHERE deleted_at IS NULL;
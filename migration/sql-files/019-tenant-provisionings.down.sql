@@ -0,0 +1,2 @@
+-- Revert 019-tenant-provisionings.sql
+DROP TABLE tenant_provisionings;
@@ -0,0 +1,10 @@
+-- state of the provisioning of the tenant of each user, by identity
+CREATE TABLE tenant_provisionings (
+    identity_id uuid primary key REFERENCES identities (id) ON DELETE CASCADE,
+    created_at timestamp with time zone,
+    updated_at timestamp with time zone,
+    state text NOT NULL,
+    attempts integer NOT NULL DEFAULT 0,
+    error text
+);
+CREATE INDEX idx_tenant_provisionings_state ON tenant_provisionings (state);
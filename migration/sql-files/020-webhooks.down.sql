@@ -0,0 +1,3 @@
+-- Revert 020-webhooks.sql
+DROP TABLE webhook_deliveries;
+DROP TABLE webhook_subscriptions;
@@ -0,0 +1,26 @@
+-- URLs registered by the service accounts to be notified of the events of the given types
+CREATE TABLE webhook_subscriptions (
+    id uuid primary key DEFAULT uuid_generate_v4(),
+    created_at timestamp with time zone,
+    updated_at timestamp with time zone,
+    deleted_at timestamp with time zone,
+    service_account text NOT NULL,
+    url text NOT NULL,
+    event_types jsonb NOT NULL,
+    secret text NOT NULL
+);
+CREATE INDEX idx_webhook_subscriptions_service_account ON webhook_subscriptions (service_account) WHERE deleted_at IS NULL;
+
+-- log of the attempts to deliver the events to the subscriptions
+CREATE TABLE webhook_deliveries (
+    id uuid primary key DEFAULT uuid_generate_v4(),
+    created_at timestamp with time zone,
+    subscription_id uuid NOT NULL REFERENCES webhook_subscriptions (id) ON DELETE CASCADE,
+    outbox_event_id uuid NOT NULL,
+    event_type text NOT NULL,
+    attempt integer NOT NULL,
+    status_code integer NOT NULL DEFAULT 0,
+    error text,
+    duration_ms integer NOT NULL DEFAULT 0
+);
+CREATE INDEX idx_webhook_deliveries_subscription_id ON webhook_deliveries (subscription_id, created_at);
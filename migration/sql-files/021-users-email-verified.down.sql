@@ -0,0 +1,2 @@
+-- Revert 021-users-email-verified.sql
+ALTER TABLE users DROP COLUMN email_verified;
@@ -0,0 +1,3 @@
+-- the invitations are only accepted for the emails which were verified in Keycloak.
+-- The existing users are considered unverified until their next login.
+ALTER TABLE users ADD COLUMN email_verified boolean NOT NULL DEFAULT false;
@@ -0,0 +1,2 @@
+-- Revert 022-users-cluster-link-required.sql
+ALTER TABLE users DROP COLUMN cluster_link_required;
@@ -0,0 +1,2 @@
+-- the users reassigned to another cluster must link the OpenShift account of the new cluster at their next login
+ALTER TABLE users ADD COLUMN cluster_link_required boolean NOT NULL DEFAULT false;
@@ -0,0 +1,127 @@
+package dispatcher
+
+import (
+	"context"
+
+	"github.com/fabric8-services/fabric8-auth/application"
+	"github.com/fabric8-services/fabric8-auth/auth"
+	"github.com/fabric8-services/fabric8-auth/log"
+	"github.com/fabric8-services/fabric8-auth/outbox"
+
+	errs "github.com/pkg/errors"
+	uuid "github.com/satori/go.uuid"
+)
+
+// CollaboratorDeliverer adds the users to and removes the users from the Keycloak policies of the spaces they collaborate on
+type CollaboratorDeliverer struct {
+	policyManager auth.AuthzPolicyManager
+}
+
+// NewCollaboratorDeliverer creates a deliverer which updates the space policies with the given policy manager
+func NewCollaboratorDeliverer(policyManager auth.AuthzPolicyManager) *CollaboratorDeliverer {
+	return &CollaboratorDeliverer{policyManager: policyManager}
+}
+
+// Deliver adds the user of the event to or removes it from the collaborators of the space. The policy is
+// not updated if it already lists the collaborators as expected, so the retries are harmless.
+// The members of the teams collaborating on the space are added as long as they are members when the event is
+// delivered, and their team grant is recorded. They are removed only if they have a team grant and they are not
+// members anymore, so the direct collaborators stay in the policy. Conversely, a direct collaborator removed from
+// the space stays in the policy with a team grant if it's a member of the teams of the space, and the team grant
+// of a member added as direct collaborator is removed.
+// The space owner is never removed. The space resource is locked until the policy is updated, like by the
+// collaborators resource, so the concurrent updates of the collaborators of the same space are not lost.
+func (d *CollaboratorDeliverer) Deliver(ctx context.Context, db application.DB, event outbox.Event) error {
+	var update func(policy *auth.KeycloakPolicy, identityID string) bool
+	switch event.EventType {
+	case outbox.EventTypeCollaboratorAdded, outbox.EventTypeTeamMemberAdded:
+		update = d.policyManager.AddUserToPolicy
+	case outbox.EventTypeCollaboratorRemoved, outbox.EventTypeTeamMemberRemoved:
+		update = d.policyManager.RemoveUserFromPolicy
+	default:
+		return errs.Errorf("unsupported event type '%s'", event.EventType)
+	}
+	var payload outbox.CollaboratorPayload
+	if err := event.DecodePayload(&payload); err != nil {
+		return err
+	}
+	req, err := authRequestData(payload.AuthURL)
+	if err != nil {
+		return err
+	}
+	// the space resource is saved once the policy is updated, in the same transaction
+	return application.Transactional(db, func(appl application.Application) error {
+		resource, err := appl.SpaceResources().LockBySpace(ctx, payload.SpaceID)
+		if err != nil {
+			return err
+		}
+		apply, err := applyTeamGrant(ctx, appl, event, payload.SpaceID)
+		if err != nil || !apply {
+			return err
+		}
+		removal := event.EventType == outbox.EventTypeCollaboratorRemoved || event.EventType == outbox.EventTypeTeamMemberRemoved
+		if removal && uuid.Equal(resource.OwnerID, event.IdentityID) {
+			log.Warn(ctx, map[string]interface{}{
+				"space_id":    payload.SpaceID,
+				"identity_id": event.IdentityID,
+			}, "the space owner can't be removed from the collaborators")
+			return nil
+		}
+		policy, pat, err := d.policyManager.GetPolicy(ctx, req, resource.PolicyID)
+		if err != nil {
+			return err
+		}
+		if !update(policy, event.IdentityID.String()) {
+			log.Debug(ctx, map[string]interface{}{
+				"space_id":    payload.SpaceID,
+				"identity_id": event.IdentityID,
+				"event_type":  event.EventType,
+			}, "the space policy is already up to date")
+			return nil
+		}
+		// only the members who were not direct collaborators already are granted the space by their teams
+		if event.EventType == outbox.EventTypeTeamMemberAdded {
+			if err := appl.SpaceTeams().AddGrant(ctx, payload.SpaceID, event.IdentityID); err != nil {
+				return err
+			}
+		}
+		if err := d.policyManager.UpdatePolicy(ctx, req, *policy, *pat); err != nil {
+			return err
+		}
+		// the resource is updated to trigger the RPT token refreshing when the users try to access the space
+		_, err = appl.SpaceResources().Save(ctx, resource)
+		return err
+	})
+}
+
+// applyTeamGrant updates the team grant of the user of the event according to its membership in the teams
+// collaborating on the space. Returns false if the policy of the space must be left unchanged.
+func applyTeamGrant(ctx context.Context, appl application.Application, event outbox.Event, spaceID uuid.UUID) (bool, error) {
+	if event.EventType == outbox.EventTypeCollaboratorAdded {
+		_, err := appl.SpaceTeams().RemoveGrant(ctx, spaceID, event.IdentityID)
+		return err == nil, err
+	}
+	member, err := appl.SpaceTeams().HasMember(ctx, spaceID, event.IdentityID)
+	if err != nil {
+		return false, err
+	}
+	switch event.EventType {
+	case outbox.EventTypeTeamMemberAdded:
+		return member, nil
+	case outbox.EventTypeTeamMemberRemoved:
+		if member {
+			return false, nil
+		}
+		// the direct collaborators have no team grant
+		return appl.SpaceTeams().RemoveGrant(ctx, spaceID, event.IdentityID)
+	}
+	// a direct collaborator who is a member of the teams of the space keeps the access granted by the teams
+	if member {
+		log.Info(ctx, map[string]interface{}{
+			"space_id":    spaceID,
+			"identity_id": event.IdentityID,
+		}, "the removed collaborator is kept as member of the teams collaborating on the space")
+		return false, appl.SpaceTeams().AddGrant(ctx, spaceID, event.IdentityID)
+	}
+	return true, nil
+}
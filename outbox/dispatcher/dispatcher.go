@@ -0,0 +1,217 @@
+// Package dispatcher delivers the outbox events to the WIT, tenant and Keycloak services and to the webhook subscriptions. The failed deliveries
+// are retried with an exponential backoff and the events are dead-lettered after the max number of attempts.
+package dispatcher
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/fabric8-services/fabric8-auth/application"
+	"github.com/fabric8-services/fabric8-auth/log"
+	"github.com/fabric8-services/fabric8-auth/outbox"
+
+	errs "github.com/pkg/errors"
+)
+
+// Configuration represents the outbox dispatcher configuration
+type Configuration interface {
+	GetOutboxDispatchInterval() time.Duration
+	GetOutboxDispatchBatchSize() int
+	GetOutboxMaxAttempts() int
+	GetOutboxBackoffMin() time.Duration
+	GetOutboxBackoffMax() time.Duration
+}
+
+// claimDuration is the time the events are claimed for by a dispatcher. An event which is neither delivered nor
+// rescheduled by then, e.g. because the replica delivering it was stopped, is delivered again.
+const claimDuration = 5 * time.Minute
+
+// Deliverer delivers the events of a target service. The deliveries are not run in a transaction so the
+// calls to the target services never hold a database connection or a lock. The deliverers which need
+// consistent reads and writes run their own short transactions with the given DB.
+type Deliverer interface {
+	Deliver(ctx context.Context, db application.DB, event outbox.Event) error
+}
+
+// FailureRecorder is implemented by the deliverers which track the failed deliveries of their events.
+// The given application is bound to the transaction which saves the outcome of the delivery.
+type FailureRecorder interface {
+	// RecordFailure records that the event could not be delivered. The event is dead-lettered
+	// and won't be retried if dead is true.
+	RecordFailure(ctx context.Context, appl application.Application, event outbox.Event, err error, dead bool) error
+}
+
+// Dispatcher delivers the due outbox events with the deliverer of their target
+type Dispatcher struct {
+	db         application.DB
+	config     Configuration
+	deliverers map[string]Deliverer
+	now        func() time.Time
+}
+
+// New creates a dispatcher which delivers the events with the given deliverers, by target
+func New(db application.DB, config Configuration, deliverers map[string]Deliverer) *Dispatcher {
+	return &Dispatcher{
+		db:         db,
+		config:     config,
+		deliverers: deliverers,
+		now:        time.Now,
+	}
+}
+
+// Start dispatches the due events in the background at the configured interval until the returned
+// function is called. The given context must hold the token manager used to sign the service
+// account tokens sent to the other services.
+func (d *Dispatcher) Start(ctx context.Context) func() {
+	ticker := time.NewTicker(d.config.GetOutboxDispatchInterval())
+	done := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case <-ticker.C:
+				if _, err := d.Dispatch(ctx); err != nil {
+					log.Error(ctx, map[string]interface{}{
+						"err": err,
+					}, "failed to dispatch the outbox events")
+				}
+			case <-done:
+				ticker.Stop()
+				return
+			}
+		}
+	}()
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			close(done)
+		})
+	}
+}
+
+// Dispatch delivers a batch of due events and returns the number of events delivered.
+// The events are claimed in a first transaction so the replicas of the service never deliver the same
+// event concurrently, then delivered outside of any transaction, and the outcome of each delivery
+// is saved in its own transaction.
+func (d *Dispatcher) Dispatch(ctx context.Context) (int, error) {
+	events, err := d.claim(ctx)
+	if err != nil {
+		return 0, err
+	}
+	delivered := 0
+	for i := range events {
+		event := &events[i]
+		deliveryErr := d.deliver(ctx, *event)
+		err := application.Transactional(d.db, func(appl application.Application) error {
+			if deliveryErr != nil {
+				d.reschedule(ctx, event, deliveryErr)
+				if err := d.recordFailure(ctx, appl, *event, deliveryErr); err != nil {
+					return err
+				}
+			} else {
+				d.markDelivered(ctx, event)
+			}
+			return appl.OutboxEvents().Save(ctx, event)
+		})
+		if err != nil {
+			// the event is delivered again once its claim expires
+			return delivered, err
+		}
+		if deliveryErr == nil {
+			delivered++
+		}
+	}
+	return delivered, nil
+}
+
+// claim locks a batch of due events and postpones their next attempt by the claim duration,
+// so they are not due anymore for the other dispatchers once the transaction is committed
+func (d *Dispatcher) claim(ctx context.Context) ([]outbox.Event, error) {
+	var events []outbox.Event
+	err := application.Transactional(d.db, func(appl application.Application) error {
+		var err error
+		events, err = appl.OutboxEvents().LockDue(ctx, d.config.GetOutboxDispatchBatchSize())
+		if err != nil {
+			return err
+		}
+		claimedUntil := d.now().Add(claimDuration)
+		for i := range events {
+			events[i].NextAttemptAt = claimedUntil
+			if err := appl.OutboxEvents().Save(ctx, &events[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return events, err
+}
+
+func (d *Dispatcher) deliver(ctx context.Context, event outbox.Event) error {
+	deliverer, found := d.deliverers[event.Target]
+	if !found {
+		return errs.Errorf("no deliverer for the target '%s'", event.Target)
+	}
+	return deliverer.Deliver(ctx, d.db, event)
+}
+
+// recordFailure notifies the deliverer of the event of the failed delivery, if the deliverer tracks the failures
+func (d *Dispatcher) recordFailure(ctx context.Context, appl application.Application, event outbox.Event, err error) error {
+	recorder, ok := d.deliverers[event.Target].(FailureRecorder)
+	if !ok {
+		return nil
+	}
+	return recorder.RecordFailure(ctx, appl, event, err, event.State == outbox.StateDead)
+}
+
+// markDelivered marks the event as delivered. The payload is removed as it may contain credentials.
+func (d *Dispatcher) markDelivered(ctx context.Context, event *outbox.Event) {
+	now := d.now()
+	event.State = outbox.StateDelivered
+	event.Attempts++
+	event.DeliveredAt = &now
+	event.LastError = ""
+	event.Payload = nil
+	log.Info(ctx, map[string]interface{}{
+		"outbox_event_id": event.ID,
+		"event_type":      event.EventType,
+		"target":          event.Target,
+		"identity_id":     event.IdentityID,
+		"attempts":        event.Attempts,
+	}, "outbox event delivered")
+}
+
+// reschedule schedules the next attempt to deliver the event or dead-letters it
+// if it reached the max number of attempts
+func (d *Dispatcher) reschedule(ctx context.Context, event *outbox.Event, err error) {
+	event.Attempts++
+	event.LastError = err.Error()
+	fields := map[string]interface{}{
+		"outbox_event_id": event.ID,
+		"event_type":      event.EventType,
+		"target":          event.Target,
+		"identity_id":     event.IdentityID,
+		"attempts":        event.Attempts,
+		"err":             err,
+	}
+	if event.Attempts >= d.config.GetOutboxMaxAttempts() {
+		event.State = outbox.StateDead
+		log.Error(ctx, fields, "failed to deliver the outbox event, giving up")
+		return
+	}
+	event.NextAttemptAt = d.now().Add(d.backoff(event.Attempts))
+	fields["next_attempt_at"] = event.NextAttemptAt
+	log.Warn(ctx, fields, "failed to deliver the outbox event, will retry")
+}
+
+// backoff returns the delay before the next attempt after the given number of failed attempts
+func (d *Dispatcher) backoff(attempts int) time.Duration {
+	delay := d.config.GetOutboxBackoffMin()
+	max := d.config.GetOutboxBackoffMax()
+	for i := 1; i < attempts && delay < max; i++ {
+		delay *= 2
+	}
+	if delay > max {
+		return max
+	}
+	return delay
+}
@@ -0,0 +1,600 @@
+package dispatcher_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/fabric8-services/fabric8-auth/account"
+	"github.com/fabric8-services/fabric8-auth/application"
+	"github.com/fabric8-services/fabric8-auth/auth"
+	"github.com/fabric8-services/fabric8-auth/authorization/organization"
+	"github.com/fabric8-services/fabric8-auth/gormtestsupport"
+	"github.com/fabric8-services/fabric8-auth/login/tokencontext"
+	"github.com/fabric8-services/fabric8-auth/outbox"
+	"github.com/fabric8-services/fabric8-auth/outbox/dispatcher"
+	"github.com/fabric8-services/fabric8-auth/resource"
+	"github.com/fabric8-services/fabric8-auth/space"
+	testsupport "github.com/fabric8-services/fabric8-auth/test"
+	testtoken "github.com/fabric8-services/fabric8-auth/test/token"
+	"github.com/fabric8-services/fabric8-auth/webhook"
+
+	"github.com/goadesign/goa"
+	"github.com/satori/go.uuid"
+	"github.com/stretchr/testify/suite"
+)
+
+type dispatcherBlackBoxTest struct {
+	gormtestsupport.DBTestSuite
+	deliverer  *fakeDeliverer
+	dispatcher *dispatcher.Dispatcher
+}
+
+func TestRunDispatcherBlackBoxTest(t *testing.T) {
+	resource.Require(t, resource.Database)
+	suite.Run(t, &dispatcherBlackBoxTest{DBTestSuite: gormtestsupport.NewDBTestSuite()})
+}
+
+func (s *dispatcherBlackBoxTest) SetupTest() {
+	s.DBTestSuite.SetupTest()
+	s.deliverer = &fakeDeliverer{failures: map[uuid.UUID]error{}}
+	s.dispatcher = dispatcher.New(s.Application, dispatcherConfig{}, map[string]dispatcher.Deliverer{
+		outbox.TargetWIT: s.deliverer,
+	})
+}
+
+func (s *dispatcherBlackBoxTest) createEvent(target string) *outbox.Event {
+	event, err := outbox.NewEvent(outbox.EventTypeUserCreated, target, uuid.NewV4(), outbox.WITPayload{})
+	s.Require().NoError(err)
+	s.Require().NoError(s.Application.OutboxEvents().Create(s.Ctx, event))
+	return event
+}
+
+func (s *dispatcherBlackBoxTest) TestDispatchDelivered() {
+	// given
+	event := s.createEvent(outbox.TargetWIT)
+
+	// when
+	_, err := s.dispatcher.Dispatch(s.Ctx)
+
+	// then
+	s.Require().NoError(err)
+	s.True(s.deliverer.delivered[event.ID])
+	loaded, err := s.Application.OutboxEvents().Load(s.Ctx, event.ID)
+	s.Require().NoError(err)
+	s.Equal(outbox.StateDelivered, loaded.State)
+	s.Equal(1, loaded.Attempts)
+	s.NotNil(loaded.DeliveredAt)
+	s.Nil(loaded.Payload)
+}
+
+func (s *dispatcherBlackBoxTest) TestDispatchClaimsEventsBeforeDelivery() {
+	// given
+	event := s.createEvent(outbox.TargetWIT)
+	dispatchedAgain := 0
+	s.deliverer.onDeliver = func() {
+		// the event is claimed and not locked while it's delivered, so a concurrent dispatch skips it without waiting
+		loaded, err := s.Application.OutboxEvents().Load(s.Ctx, event.ID)
+		s.Require().NoError(err)
+		s.Equal(outbox.StatePending, loaded.State)
+		s.True(loaded.NextAttemptAt.After(time.Now()))
+		dispatchedAgain, err = s.dispatcher.Dispatch(s.Ctx)
+		s.Require().NoError(err)
+	}
+
+	// when
+	delivered, err := s.dispatcher.Dispatch(s.Ctx)
+
+	// then
+	s.Require().NoError(err)
+	s.Equal(1, delivered)
+	s.Equal(0, dispatchedAgain)
+	loaded, err := s.Application.OutboxEvents().Load(s.Ctx, event.ID)
+	s.Require().NoError(err)
+	s.Equal(outbox.StateDelivered, loaded.State)
+	s.Equal(1, loaded.Attempts)
+}
+
+func (s *dispatcherBlackBoxTest) TestDispatchFailureRetriedWithBackoff() {
+	// given
+	event := s.createEvent(outbox.TargetWIT)
+	s.deliverer.failures[event.IdentityID] = errors.New("WIT is unavailable")
+
+	// when
+	_, err := s.dispatcher.Dispatch(s.Ctx)
+
+	// then
+	s.Require().NoError(err)
+	loaded, err := s.Application.OutboxEvents().Load(s.Ctx, event.ID)
+	s.Require().NoError(err)
+	s.Equal(outbox.StatePending, loaded.State)
+	s.Equal(1, loaded.Attempts)
+	s.Equal("WIT is unavailable", loaded.LastError)
+	s.True(loaded.NextAttemptAt.After(time.Now().Add(50 * time.Second)))
+	s.NotNil(loaded.Payload)
+
+	// and the event is not delivered again before its next attempt
+	s.deliverer.failures = map[uuid.UUID]error{}
+	_, err = s.dispatcher.Dispatch(s.Ctx)
+	s.Require().NoError(err)
+	s.False(s.deliverer.delivered[event.ID])
+}
+
+func (s *dispatcherBlackBoxTest) TestDispatchDeadLettered() {
+	// given an event which failed all its attempts but one
+	event := s.createEvent(outbox.TargetWIT)
+	event.Attempts = dispatcherConfig{}.GetOutboxMaxAttempts() - 1
+	s.Require().NoError(s.Application.OutboxEvents().Save(s.Ctx, event))
+	s.deliverer.failures[event.IdentityID] = errors.New("WIT is unavailable")
+
+	// when
+	_, err := s.dispatcher.Dispatch(s.Ctx)
+
+	// then
+	s.Require().NoError(err)
+	loaded, err := s.Application.OutboxEvents().Load(s.Ctx, event.ID)
+	s.Require().NoError(err)
+	s.Equal(outbox.StateDead, loaded.State)
+	s.Equal(dispatcherConfig{}.GetOutboxMaxAttempts(), loaded.Attempts)
+	s.Equal("WIT is unavailable", loaded.LastError)
+}
+
+func (s *dispatcherBlackBoxTest) TestDispatchUnknownTarget() {
+	// given
+	event := s.createEvent("unknown")
+
+	// when
+	_, err := s.dispatcher.Dispatch(s.Ctx)
+
+	// then
+	s.Require().NoError(err)
+	loaded, err := s.Application.OutboxEvents().Load(s.Ctx, event.ID)
+	s.Require().NoError(err)
+	s.Equal(outbox.StatePending, loaded.State)
+	s.Contains(loaded.LastError, "no deliverer")
+}
+
+func (s *dispatcherBlackBoxTest) TestDispatchTenantProvisioningTracked() {
+	// given a tenant service which fails to set up the tenants
+	status := http.StatusInternalServerError
+	var method, path, authorization string
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		method, path, authorization = req.Method, req.URL.Path, req.Header.Get("Authorization")
+		rw.WriteHeader(status)
+	}))
+	defer server.Close()
+	d := dispatcher.New(s.Application, dispatcherConfig{}, map[string]dispatcher.Deliverer{
+		outbox.TargetTenant: dispatcher.NewTenantDeliverer(tenantConfig{url: server.URL}),
+	})
+	identity, err := testsupport.CreateTestIdentity(s.DB, "tenant-"+uuid.NewV4().String(), "kc")
+	s.Require().NoError(err)
+	event, err := outbox.NewTenantUserCreatedEvent(identity.ID, "https://auth.openshift.io")
+	s.Require().NoError(err)
+	s.Require().NoError(s.Application.OutboxEvents().Create(s.Ctx, event))
+	s.Require().NoError(s.Application.TenantProvisionings().Request(s.Ctx, identity.ID))
+	ctx := tokencontext.ContextWithTokenManager(s.Ctx, testtoken.TokenManager)
+
+	// when
+	_, err = d.Dispatch(ctx)
+
+	// then the setup is retried with the service account token of the auth service
+	s.Require().NoError(err)
+	provisioning, err := s.Application.TenantProvisionings().Load(s.Ctx, identity.ID)
+	s.Require().NoError(err)
+	s.Equal(account.TenantProvisioningStateInProgress, provisioning.State)
+	s.Equal(1, provisioning.Attempts)
+	s.Contains(provisioning.Error, "500")
+	s.Equal(http.MethodPost, method)
+	s.Equal("/api/tenants/"+identity.ID.String(), path)
+	s.NotEmpty(authorization)
+	loaded, err := s.Application.OutboxEvents().Load(s.Ctx, event.ID)
+	s.Require().NoError(err)
+	s.NotContains(*loaded.Payload, "token")
+
+	// when the last attempt fails
+	event.Attempts = dispatcherConfig{}.GetOutboxMaxAttempts() - 1
+	event.NextAttemptAt = time.Now()
+	event.State = outbox.StatePending
+	s.Require().NoError(s.Application.OutboxEvents().Save(s.Ctx, event))
+	_, err = d.Dispatch(ctx)
+
+	// then the provisioning failed
+	s.Require().NoError(err)
+	provisioning, err = s.Application.TenantProvisionings().Load(s.Ctx, identity.ID)
+	s.Require().NoError(err)
+	s.Equal(account.TenantProvisioningStateFailed, provisioning.State)
+	s.Equal(2, provisioning.Attempts)
+
+	// when the tenant is set up again
+	status = http.StatusOK
+	event, err = outbox.NewTenantUserCreatedEvent(identity.ID, "https://auth.openshift.io")
+	s.Require().NoError(err)
+	s.Require().NoError(s.Application.OutboxEvents().Create(s.Ctx, event))
+	s.Require().NoError(s.Application.TenantProvisionings().Request(s.Ctx, identity.ID))
+	_, err = d.Dispatch(ctx)
+
+	// then the tenant is ready
+	s.Require().NoError(err)
+	provisioning, err = s.Application.TenantProvisionings().Load(s.Ctx, identity.ID)
+	s.Require().NoError(err)
+	s.Equal(account.TenantProvisioningStateReady, provisioning.State)
+	s.Equal(0, provisioning.Attempts)
+	s.Empty(provisioning.Error)
+}
+
+func (s *dispatcherBlackBoxTest) TestDispatchWebhookSigned() {
+	// given a subscription whose URL fails to respond the first time
+	status := http.StatusServiceUnavailable
+	var received []byte
+	var headers http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		received, _ = ioutil.ReadAll(req.Body)
+		headers = req.Header
+		rw.WriteHeader(status)
+		rw.Write([]byte("internal details of the subscriber"))
+	}))
+	defer server.Close()
+	// the test server listens on the loopback address
+	d := dispatcher.New(s.Application, dispatcherConfig{}, map[string]dispatcher.Deliverer{
+		outbox.TargetWebhook: dispatcher.NewWebhookDeliverer(webhookConfig{allowPrivate: true}),
+	})
+	subscription := webhook.Subscription{
+		ServiceAccount: "test-service",
+		URL:            server.URL,
+		EventTypes:     webhook.EventTypes{webhook.EventTypeUserCreated},
+		Secret:         "secret",
+	}
+	s.Require().NoError(s.Application.WebhookSubscriptions().Create(s.Ctx, &subscription))
+	identityID := uuid.NewV4()
+	s.Require().NoError(webhook.Emit(s.Ctx, s.Application, webhook.EventTypeUserCreated, webhook.Data{IdentityID: identityID, Username: "jdoe"}))
+	events, err := s.Application.OutboxEvents().Query(s.Ctx, outbox.EventFilterByIdentityID(identityID), outbox.EventFilterByTarget(outbox.TargetWebhook))
+	s.Require().NoError(err)
+	s.Require().Len(events, 1)
+	event := events[0]
+
+	// when
+	_, err = d.Dispatch(s.Ctx)
+
+	// then the delivery is retried
+	s.Require().NoError(err)
+	loaded, err := s.Application.OutboxEvents().Load(s.Ctx, event.ID)
+	s.Require().NoError(err)
+	s.Equal(outbox.StatePending, loaded.State)
+	s.Contains(loaded.LastError, "503")
+
+	// when the URL responds
+	status = http.StatusNoContent
+	loaded.NextAttemptAt = time.Now()
+	s.Require().NoError(s.Application.OutboxEvents().Save(s.Ctx, loaded))
+	_, err = d.Dispatch(s.Ctx)
+
+	// then the event is delivered with the signature of its body
+	s.Require().NoError(err)
+	loaded, err = s.Application.OutboxEvents().Load(s.Ctx, event.ID)
+	s.Require().NoError(err)
+	s.Equal(outbox.StateDelivered, loaded.State)
+	s.Equal(webhook.Sign("secret", received), headers.Get(webhook.SignatureHeader))
+	s.Equal(webhook.EventTypeUserCreated, headers.Get(webhook.EventTypeHeader))
+	s.Equal(event.ID.String(), headers.Get(webhook.DeliveryHeader))
+	var body webhook.Body
+	s.Require().NoError(json.Unmarshal(received, &body))
+	s.Equal(webhook.EventTypeUserCreated, body.Type)
+	s.Equal(identityID, body.Data.IdentityID)
+	s.Equal("jdoe", body.Data.Username)
+	// and both attempts are logged, newest first
+	deliveries, err := s.Application.WebhookDeliveries().List(s.Ctx, subscription.ID, 10)
+	s.Require().NoError(err)
+	s.Require().Len(deliveries, 2)
+	s.Equal(2, deliveries[0].Attempt)
+	s.Equal(http.StatusNoContent, deliveries[0].StatusCode)
+	s.Empty(deliveries[0].Error)
+	s.Equal(1, deliveries[1].Attempt)
+	s.Equal(http.StatusServiceUnavailable, deliveries[1].StatusCode)
+	s.Contains(deliveries[1].Error, "503")
+	// but the body of the response is not recorded
+	s.NotContains(deliveries[1].Error, "internal details")
+}
+
+func (s *dispatcherBlackBoxTest) TestDispatchWebhookToPrivateAddressRefused() {
+	// given a subscription whose URL is a loopback address
+	called := false
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		called = true
+		rw.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+	d := dispatcher.New(s.Application, dispatcherConfig{}, map[string]dispatcher.Deliverer{
+		outbox.TargetWebhook: dispatcher.NewWebhookDeliverer(webhookConfig{}),
+	})
+	subscription := webhook.Subscription{
+		ServiceAccount: "test-service",
+		URL:            server.URL,
+		EventTypes:     webhook.EventTypes{webhook.EventTypeUserCreated},
+		Secret:         "secret",
+	}
+	s.Require().NoError(s.Application.WebhookSubscriptions().Create(s.Ctx, &subscription))
+	identityID := uuid.NewV4()
+	s.Require().NoError(webhook.Emit(s.Ctx, s.Application, webhook.EventTypeUserCreated, webhook.Data{IdentityID: identityID, Username: "jdoe"}))
+
+	// when
+	_, err := d.Dispatch(s.Ctx)
+
+	// then the event is not posted
+	s.Require().NoError(err)
+	s.False(called)
+	deliveries, err := s.Application.WebhookDeliveries().List(s.Ctx, subscription.ID, 10)
+	s.Require().NoError(err)
+	s.Require().Len(deliveries, 1)
+	s.Equal(0, deliveries[0].StatusCode)
+	s.Contains(deliveries[0].Error, "non public address")
+}
+
+func (s *dispatcherBlackBoxTest) TestDispatchWebhookOfDeletedSubscriptionDiscarded() {
+	// given
+	d := dispatcher.New(s.Application, dispatcherConfig{}, map[string]dispatcher.Deliverer{
+		outbox.TargetWebhook: dispatcher.NewWebhookDeliverer(webhookConfig{}),
+	})
+	subscription := webhook.Subscription{
+		ServiceAccount: "test-service",
+		URL:            "http://localhost:1/hook",
+		EventTypes:     webhook.EventTypes{webhook.EventTypeSpaceDeleted},
+		Secret:         "secret",
+	}
+	s.Require().NoError(s.Application.WebhookSubscriptions().Create(s.Ctx, &subscription))
+	identityID := uuid.NewV4()
+	spaceID := uuid.NewV4()
+	s.Require().NoError(webhook.Emit(s.Ctx, s.Application, webhook.EventTypeSpaceDeleted, webhook.Data{IdentityID: identityID, SpaceID: &spaceID}))
+	s.Require().NoError(s.Application.WebhookSubscriptions().Delete(s.Ctx, subscription.ID))
+
+	// when
+	_, err := d.Dispatch(s.Ctx)
+
+	// then
+	s.Require().NoError(err)
+	events, err := s.Application.OutboxEvents().Query(s.Ctx, outbox.EventFilterByIdentityID(identityID), outbox.EventFilterByTarget(outbox.TargetWebhook))
+	s.Require().NoError(err)
+	s.Require().Len(events, 1)
+	s.Equal(outbox.StateDelivered, events[0].State)
+	deliveries, err := s.Application.WebhookDeliveries().List(s.Ctx, subscription.ID, 10)
+	s.Require().NoError(err)
+	s.Empty(deliveries)
+}
+
+// fakeDeliverer records the delivered events and fails the delivery of the events of the given identities
+func (s *dispatcherBlackBoxTest) TestDeliverConcurrentCollaboratorChanges() {
+	// given a space whose policy lists its owner and a collaborator
+	owner := uuid.NewV4()
+	removed := uuid.NewV4()
+	added := uuid.NewV4()
+	resource, err := s.Application.SpaceResources().Create(s.Ctx, &space.Resource{
+		ResourceID: uuid.NewV4().String(),
+		PolicyID:   uuid.NewV4().String(),
+		SpaceID:    uuid.NewV4(),
+		OwnerID:    owner,
+	})
+	s.Require().NoError(err)
+	policyManager := &fakePolicyManager{delay: 100 * time.Millisecond}
+	policyManager.policy.AddUserToPolicy(owner.String())
+	policyManager.policy.AddUserToPolicy(removed.String())
+	deliverer := dispatcher.NewCollaboratorDeliverer(policyManager)
+	removal, err := outbox.NewCollaboratorRemovedEvent(removed, resource.SpaceID, "https://auth.openshift.io")
+	s.Require().NoError(err)
+	addition, err := outbox.NewCollaboratorAddedEvent(added, resource.SpaceID, "https://auth.openshift.io")
+	s.Require().NoError(err)
+
+	// when the collaborators are removed and added at the same time
+	var wg sync.WaitGroup
+	errs := make([]error, 2)
+	for i, event := range []*outbox.Event{removal, addition} {
+		wg.Add(1)
+		go func(i int, event outbox.Event) {
+			defer wg.Done()
+			errs[i] = deliverer.Deliver(s.Ctx, s.Application, event)
+		}(i, *event)
+	}
+	wg.Wait()
+
+	// then none of the changes is lost
+	s.Require().NoError(errs[0])
+	s.Require().NoError(errs[1])
+	s.Contains(policyManager.policy.Config.UserIDs, owner.String())
+	s.Contains(policyManager.policy.Config.UserIDs, added.String())
+	s.NotContains(policyManager.policy.Config.UserIDs, removed.String())
+}
+
+func (s *dispatcherBlackBoxTest) TestDeliverTeamMemberChanges() {
+	// given a space whose policy lists its owner and a direct collaborator, who are both members of a team of the space
+	owner, err := testsupport.CreateTestIdentity(s.DB, "dispatcher-owner-"+uuid.NewV4().String(), account.KeycloakIDP)
+	s.Require().NoError(err)
+	direct, err := testsupport.CreateTestIdentity(s.DB, "dispatcher-direct-"+uuid.NewV4().String(), account.KeycloakIDP)
+	s.Require().NoError(err)
+	member, err := testsupport.CreateTestIdentity(s.DB, "dispatcher-member-"+uuid.NewV4().String(), account.KeycloakIDP)
+	s.Require().NoError(err)
+	organizations := organization.NewService(s.Application)
+	org, err := organizations.Create(s.Ctx, organization.ResourceTypeOrganization, owner.ID, "Acme "+uuid.NewV4().String(), "", nil)
+	s.Require().NoError(err)
+	team, err := organizations.Create(s.Ctx, organization.ResourceTypeTeam, owner.ID, "Developers", "", &org.ResourceID)
+	s.Require().NoError(err)
+	for _, identityID := range []uuid.UUID{direct.ID, member.ID} {
+		_, err = organizations.AddMember(s.Ctx, organization.ResourceTypeTeam, owner.ID, team.ResourceID, identityID, organization.RoleMember, nil)
+		s.Require().NoError(err)
+	}
+	resource, err := s.Application.SpaceResources().Create(s.Ctx, &space.Resource{
+		ResourceID: uuid.NewV4().String(),
+		PolicyID:   uuid.NewV4().String(),
+		SpaceID:    uuid.NewV4(),
+		OwnerID:    owner.ID,
+	})
+	s.Require().NoError(err)
+	s.Require().NoError(s.Application.SpaceTeams().Add(s.Ctx, resource.SpaceID, team.ResourceID, "https://auth.openshift.io"))
+	policyManager := &fakePolicyManager{}
+	policyManager.policy.AddUserToPolicy(owner.ID.String())
+	policyManager.policy.AddUserToPolicy(direct.ID.String())
+	deliverer := dispatcher.NewCollaboratorDeliverer(policyManager)
+	deliver := func(newEvent func(uuid.UUID, uuid.UUID, string) (*outbox.Event, error), identityIDs ...uuid.UUID) {
+		for _, identityID := range identityIDs {
+			event, err := newEvent(identityID, resource.SpaceID, "https://auth.openshift.io")
+			s.Require().NoError(err)
+			s.Require().NoError(deliverer.Deliver(s.Ctx, s.Application, *event))
+		}
+	}
+
+	// when the members of the team are added to the policy
+	deliver(outbox.NewTeamMemberAddedEvent, owner.ID, direct.ID, member.ID)
+
+	// then
+	s.Contains(policyManager.policy.Config.UserIDs, owner.ID.String())
+	s.Contains(policyManager.policy.Config.UserIDs, direct.ID.String())
+	s.Contains(policyManager.policy.Config.UserIDs, member.ID.String())
+
+	// when they leave the team
+	for _, identityID := range []uuid.UUID{direct.ID, member.ID} {
+		_, err = organizations.RemoveMember(s.Ctx, organization.ResourceTypeTeam, owner.ID, team.ResourceID, identityID)
+		s.Require().NoError(err)
+	}
+	deliver(outbox.NewTeamMemberRemovedEvent, direct.ID, member.ID)
+
+	// then only the member who was granted the space by the team is removed
+	s.Contains(policyManager.policy.Config.UserIDs, owner.ID.String())
+	s.Contains(policyManager.policy.Config.UserIDs, direct.ID.String())
+	s.NotContains(policyManager.policy.Config.UserIDs, member.ID.String())
+}
+
+func (s *dispatcherBlackBoxTest) TestDeliverCollaboratorRemovalKeepsTeamMember() {
+	// given a space whose policy lists a direct collaborator who is a member of a team of the space
+	member, err := testsupport.CreateTestIdentity(s.DB, "dispatcher-member-"+uuid.NewV4().String(), account.KeycloakIDP)
+	s.Require().NoError(err)
+	organizations := organization.NewService(s.Application)
+	org, err := organizations.Create(s.Ctx, organization.ResourceTypeOrganization, member.ID, "Acme "+uuid.NewV4().String(), "", nil)
+	s.Require().NoError(err)
+	team, err := organizations.Create(s.Ctx, organization.ResourceTypeTeam, member.ID, "Developers", "", &org.ResourceID)
+	s.Require().NoError(err)
+	owner := uuid.NewV4()
+	resource, err := s.Application.SpaceResources().Create(s.Ctx, &space.Resource{
+		ResourceID: uuid.NewV4().String(),
+		PolicyID:   uuid.NewV4().String(),
+		SpaceID:    uuid.NewV4(),
+		OwnerID:    owner,
+	})
+	s.Require().NoError(err)
+	s.Require().NoError(s.Application.SpaceTeams().Add(s.Ctx, resource.SpaceID, team.ResourceID, "https://auth.openshift.io"))
+	policyManager := &fakePolicyManager{}
+	policyManager.policy.AddUserToPolicy(owner.String())
+	policyManager.policy.AddUserToPolicy(member.ID.String())
+	deliverer := dispatcher.NewCollaboratorDeliverer(policyManager)
+	removal, err := outbox.NewCollaboratorRemovedEvent(member.ID, resource.SpaceID, "https://auth.openshift.io")
+	s.Require().NoError(err)
+
+	// when the direct collaborator is removed
+	err = deliverer.Deliver(s.Ctx, s.Application, *removal)
+
+	// then it's kept as member of the team
+	s.Require().NoError(err)
+	s.Contains(policyManager.policy.Config.UserIDs, member.ID.String())
+	granted, err := s.Application.SpaceTeams().RemoveGrant(s.Ctx, resource.SpaceID, member.ID)
+	s.Require().NoError(err)
+	s.True(granted)
+}
+
+type fakeDeliverer struct {
+	failures  map[uuid.UUID]error
+	delivered map[uuid.UUID]bool
+	onDeliver func()
+}
+
+func (d *fakeDeliverer) Deliver(ctx context.Context, db application.DB, event outbox.Event) error {
+	if d.onDeliver != nil {
+		onDeliver := d.onDeliver
+		d.onDeliver = nil
+		onDeliver()
+	}
+	if err, found := d.failures[event.IdentityID]; found {
+		return err
+	}
+	if d.delivered == nil {
+		d.delivered = map[uuid.UUID]bool{}
+	}
+	d.delivered[event.ID] = true
+	return nil
+}
+
+// fakePolicyManager keeps the policy of a single space, like Keycloak it replaces the whole policy on update.
+// The policy is returned after the given delay, so the concurrent updates read the same policy unless they
+// are serialized by the caller.
+type fakePolicyManager struct {
+	lock   sync.Mutex
+	policy auth.KeycloakPolicy
+	delay  time.Duration
+}
+
+func (m *fakePolicyManager) GetPolicy(ctx context.Context, request *goa.RequestData, policyID string) (*auth.KeycloakPolicy, *string, error) {
+	m.lock.Lock()
+	policy := m.policy
+	m.lock.Unlock()
+	time.Sleep(m.delay)
+	pat := ""
+	return &policy, &pat, nil
+}
+
+func (m *fakePolicyManager) UpdatePolicy(ctx context.Context, request *goa.RequestData, policy auth.KeycloakPolicy, pat string) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.policy = policy
+	return nil
+}
+
+func (m *fakePolicyManager) AddUserToPolicy(p *auth.KeycloakPolicy, userID string) bool {
+	return p.AddUserToPolicy(userID)
+}
+
+func (m *fakePolicyManager) RemoveUserFromPolicy(p *auth.KeycloakPolicy, userID string) bool {
+	return p.RemoveUserFromPolicy(userID)
+}
+
+type dispatcherConfig struct{}
+
+func (c dispatcherConfig) GetOutboxDispatchInterval() time.Duration {
+	return time.Second
+}
+
+func (c dispatcherConfig) GetOutboxDispatchBatchSize() int {
+	return 100
+}
+
+func (c dispatcherConfig) GetOutboxMaxAttempts() int {
+	return 3
+}
+
+func (c dispatcherConfig) GetOutboxBackoffMin() time.Duration {
+	return time.Minute
+}
+
+func (c dispatcherConfig) GetOutboxBackoffMax() time.Duration {
+	return time.Hour
+}
+
+type tenantConfig struct {
+	url string
+}
+
+func (c tenantConfig) GetTenantServiceURL() string {
+	return c.url
+}
+
+type webhookConfig struct {
+	allowPrivate bool
+}
+
+func (c webhookConfig) GetWebhookDeliveryTimeout() time.Duration {
+	return 5 * time.Second
+}
+
+func (c webhookConfig) IsWebhookPrivateTargetAllowed() bool {
+	return c.allowPrivate
+}
@@ -0,0 +1,33 @@
+package dispatcher
+
+import (
+	"testing"
+	"time"
+
+	"github.com/fabric8-services/fabric8-auth/resource"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type backoffConfig struct {
+	Configuration
+}
+
+func (c backoffConfig) GetOutboxBackoffMin() time.Duration {
+	return 10 * time.Second
+}
+
+func (c backoffConfig) GetOutboxBackoffMax() time.Duration {
+	return time.Minute
+}
+
+func TestBackoff(t *testing.T) {
+	resource.Require(t, resource.UnitTest)
+	d := New(nil, backoffConfig{}, nil)
+
+	assert.Equal(t, 10*time.Second, d.backoff(1))
+	assert.Equal(t, 20*time.Second, d.backoff(2))
+	assert.Equal(t, 40*time.Second, d.backoff(3))
+	assert.Equal(t, time.Minute, d.backoff(4))
+	assert.Equal(t, time.Minute, d.backoff(100))
+}
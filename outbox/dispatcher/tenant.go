@@ -0,0 +1,104 @@
+package dispatcher
+
+import (
+	"context"
+
+	"github.com/fabric8-services/fabric8-auth/account"
+	"github.com/fabric8-services/fabric8-auth/application"
+	"github.com/fabric8-services/fabric8-auth/log"
+	"github.com/fabric8-services/fabric8-auth/outbox"
+	"github.com/fabric8-services/fabric8-auth/token"
+
+	errs "github.com/pkg/errors"
+)
+
+// TenantConfiguration represents the configuration of the tenant deliverer
+type TenantConfiguration interface {
+	GetTenantServiceURL() string
+}
+
+// TenantDeliverer sets up the tenants of the new users
+type TenantDeliverer struct {
+	config TenantConfiguration
+}
+
+// NewTenantDeliverer creates a deliverer which calls the tenant service
+func NewTenantDeliverer(config TenantConfiguration) *TenantDeliverer {
+	return &TenantDeliverer{config: config}
+}
+
+// Deliver sets up the tenant of the user of the event or moves the tenant of the user to the cluster the user
+// was reassigned to, on behalf of the auth service. The event is discarded if the tenant service is not configured.
+func (d *TenantDeliverer) Deliver(ctx context.Context, db application.DB, event outbox.Event) error {
+	switch event.EventType {
+	case outbox.EventTypeUserCreated, outbox.EventTypeUserClusterChanged, outbox.EventTypeUserTenantRequested:
+	default:
+		return errs.Errorf("unsupported event type '%s'", event.EventType)
+	}
+	if d.config.GetTenantServiceURL() == "" {
+		log.Debug(ctx, map[string]interface{}{
+			"outbox_event_id": event.ID,
+			"identity_id":     event.IdentityID,
+		}, "tenant service not configured, skipping the tenant update")
+		return nil
+	}
+	if event.EventType == outbox.EventTypeUserClusterChanged {
+		return d.updateCluster(ctx, event)
+	}
+	if err := d.setup(ctx, event); err != nil {
+		return err
+	}
+	return db.TenantProvisionings().RecordAttempt(ctx, event.IdentityID, account.TenantProvisioningStateReady, nil)
+}
+
+// RecordFailure records the failed attempt to set up the tenant of the user of the event. The provisioning
+// is failed once the event is dead-lettered.
+func (d *TenantDeliverer) RecordFailure(ctx context.Context, appl application.Application, event outbox.Event, err error, dead bool) error {
+	if event.EventType != outbox.EventTypeUserCreated && event.EventType != outbox.EventTypeUserTenantRequested {
+		return nil
+	}
+	state := account.TenantProvisioningStateInProgress
+	if dead {
+		state = account.TenantProvisioningStateFailed
+	}
+	return appl.TenantProvisionings().RecordAttempt(ctx, event.IdentityID, state, err)
+}
+
+// setup sets up the tenant of the user of the event on behalf of the auth service
+func (d *TenantDeliverer) setup(ctx context.Context, event outbox.Event) error {
+	var payload outbox.TenantPayload
+	if err := event.DecodePayload(&payload); err != nil {
+		return err
+	}
+	serviceAccountToken, err := authServiceAccountToken(ctx, payload.AuthURL)
+	if err != nil {
+		return err
+	}
+	return account.SetupTenantOnBehalf(ctx, d.config, serviceAccountToken, event.IdentityID.String())
+}
+
+// updateCluster moves the tenant of the user of the event to the cluster the user was reassigned to
+func (d *TenantDeliverer) updateCluster(ctx context.Context, event outbox.Event) error {
+	var payload outbox.TenantClusterPayload
+	if err := event.DecodePayload(&payload); err != nil {
+		return err
+	}
+	serviceAccountToken, err := authServiceAccountToken(ctx, payload.AuthURL)
+	if err != nil {
+		return err
+	}
+	return account.UpdateTenantCluster(ctx, d.config, serviceAccountToken, event.IdentityID.String())
+}
+
+// authServiceAccountToken returns the token of the service account of the auth service with the given URL
+func authServiceAccountToken(ctx context.Context, authURL string) (string, error) {
+	req, err := authRequestData(authURL)
+	if err != nil {
+		return "", err
+	}
+	manager, err := token.ReadManagerFromContext(ctx)
+	if err != nil {
+		return "", err
+	}
+	return (*manager).AuthServiceAccountToken(req)
+}
@@ -0,0 +1,120 @@
+package dispatcher
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"time"
+	"unicode/utf8"
+
+	"github.com/fabric8-services/fabric8-auth/application"
+	"github.com/fabric8-services/fabric8-auth/errors"
+	"github.com/fabric8-services/fabric8-auth/log"
+	"github.com/fabric8-services/fabric8-auth/outbox"
+	"github.com/fabric8-services/fabric8-auth/webhook"
+
+	errs "github.com/pkg/errors"
+)
+
+const (
+	// maxResponseBodyLogged is the max number of bytes of the body of a failed response which are logged
+	maxResponseBodyLogged = 512
+	// maxDeliveryErrorLength is the max length of the error recorded in the delivery log of a subscription
+	maxDeliveryErrorLength = 256
+)
+
+// WebhookConfiguration represents the configuration of the webhook deliverer
+type WebhookConfiguration interface {
+	GetWebhookDeliveryTimeout() time.Duration
+	IsWebhookPrivateTargetAllowed() bool
+}
+
+// WebhookDeliverer posts the events to the URLs of the webhook subscriptions
+type WebhookDeliverer struct {
+	client *http.Client
+}
+
+// NewWebhookDeliverer creates a deliverer which posts the events with the configured timeout.
+// The events are not posted to the loopback, private or link-local addresses, unless allowed by the configuration.
+func NewWebhookDeliverer(config WebhookConfiguration) *WebhookDeliverer {
+	return &WebhookDeliverer{client: webhook.NewHTTPClient(config.GetWebhookDeliveryTimeout(), config.IsWebhookPrivateTargetAllowed())}
+}
+
+// Deliver posts the signed body of the event to the URL of its subscription and records the attempt
+// in the delivery log of the subscription. The event is discarded if the subscription was deleted.
+func (d *WebhookDeliverer) Deliver(ctx context.Context, db application.DB, event outbox.Event) error {
+	var payload outbox.WebhookPayload
+	if err := event.DecodePayload(&payload); err != nil {
+		return err
+	}
+	subscription, err := db.WebhookSubscriptions().Load(ctx, payload.SubscriptionID)
+	if notFound, _ := errors.IsNotFoundError(err); notFound {
+		log.Info(ctx, map[string]interface{}{
+			"outbox_event_id": event.ID,
+			"subscription_id": payload.SubscriptionID,
+		}, "webhook subscription deleted, discarding the event")
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	delivery := webhook.Delivery{
+		SubscriptionID: subscription.ID,
+		OutboxEventID:  event.ID,
+		EventType:      event.EventType,
+		Attempt:        event.Attempts + 1,
+	}
+	start := time.Now()
+	deliveryErr := d.post(ctx, *subscription, event, payload.Body, &delivery)
+	delivery.DurationMillis = int64(time.Since(start) / time.Millisecond)
+	if deliveryErr != nil {
+		delivery.Error = truncate(deliveryErr.Error(), maxDeliveryErrorLength)
+	}
+	if err := db.WebhookDeliveries().Create(ctx, &delivery); err != nil {
+		return err
+	}
+	return deliveryErr
+}
+
+// post posts the body to the URL of the subscription and sets the status of the response on the given delivery.
+// Any status but 2xx is a failure. The body of the response is never returned, since the error is shown
+// to the service account in the delivery log; the beginning of the body of a failed response is logged instead.
+func (d *WebhookDeliverer) post(ctx context.Context, subscription webhook.Subscription, event outbox.Event, body []byte, delivery *webhook.Delivery) error {
+	req, err := http.NewRequest("POST", subscription.URL, bytes.NewReader(body))
+	if err != nil {
+		return errs.Wrapf(err, "invalid webhook URL '%s'", subscription.URL)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(webhook.SignatureHeader, webhook.Sign(subscription.Secret, body))
+	req.Header.Set(webhook.EventTypeHeader, event.EventType)
+	req.Header.Set(webhook.DeliveryHeader, event.ID.String())
+	res, err := d.client.Do(req.WithContext(ctx))
+	if err != nil {
+		return errs.Wrapf(err, "unable to post the %s event to %s", event.EventType, subscription.URL)
+	}
+	defer res.Body.Close()
+	delivery.StatusCode = res.StatusCode
+	if !delivery.Succeeded() {
+		body, _ := ioutil.ReadAll(io.LimitReader(res.Body, maxResponseBodyLogged))
+		log.Warn(ctx, map[string]interface{}{
+			"subscription_id": subscription.ID,
+			"status":          res.StatusCode,
+			"body":            string(body),
+		}, "the webhook URL responded with an error")
+		return errs.Errorf("the webhook URL responded with the status %d", res.StatusCode)
+	}
+	return nil
+}
+
+// truncate returns at most the first max bytes of the given message, without splitting a character
+func truncate(message string, max int) string {
+	if len(message) <= max {
+		return message
+	}
+	for max > 0 && !utf8.RuneStart(message[max]) {
+		max--
+	}
+	return message[:max]
+}
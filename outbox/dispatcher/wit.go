@@ -0,0 +1,92 @@
+package dispatcher
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+
+	"github.com/fabric8-services/fabric8-auth/account"
+	"github.com/fabric8-services/fabric8-auth/app"
+	"github.com/fabric8-services/fabric8-auth/application"
+	"github.com/fabric8-services/fabric8-auth/outbox"
+	"github.com/fabric8-services/fabric8-auth/wit"
+
+	"github.com/goadesign/goa"
+	errs "github.com/pkg/errors"
+)
+
+// WITDeliverer creates and updates the users in WIT. The user is sent as currently stored
+// in the database, whatever the changes which emitted the event.
+type WITDeliverer struct {
+	service wit.RemoteWITService
+}
+
+// NewWITDeliverer creates a deliverer which calls WIT with the given service
+func NewWITDeliverer(service wit.RemoteWITService) *WITDeliverer {
+	return &WITDeliverer{service: service}
+}
+
+// Deliver creates or updates the user of the event in WIT
+func (d *WITDeliverer) Deliver(ctx context.Context, db application.DB, event outbox.Event) error {
+	var payload outbox.WITPayload
+	if err := event.DecodePayload(&payload); err != nil {
+		return err
+	}
+	identities, err := db.Identities().Query(account.IdentityFilterByID(event.IdentityID), account.IdentityWithUser())
+	if err != nil {
+		return err
+	}
+	if len(identities) == 0 {
+		return errs.Errorf("identity %s not found", event.IdentityID)
+	}
+	identity := identities[0]
+	req, err := authRequestData(payload.AuthURL)
+	if err != nil {
+		return err
+	}
+	switch event.EventType {
+	case outbox.EventTypeUserCreated:
+		return d.service.CreateWITUser(ctx, req, &identity, payload.WITURL, identity.ID.String())
+	case outbox.EventTypeUserUpdated:
+		return d.service.UpdateWITUser(ctx, req, updateUserPayload(identity), payload.WITURL, identity.ID.String())
+	}
+	return errs.Errorf("unsupported event type '%s'", event.EventType)
+}
+
+// authRequestData returns request data for the given auth URL, which is the issuer
+// of the service account token sent to WIT and the base of the Keycloak endpoints
+func authRequestData(authURL string) (*goa.RequestData, error) {
+	u, err := url.Parse(authURL)
+	if err != nil {
+		return nil, errs.Wrapf(err, "invalid auth URL '%s'", authURL)
+	}
+	return &goa.RequestData{
+		Request: &http.Request{
+			URL:    u,
+			Host:   u.Host,
+			Header: http.Header{},
+		},
+	}, nil
+}
+
+func updateUserPayload(identity account.Identity) *app.UpdateUsersPayload {
+	attributes := &app.UpdateIdentityDataAttributes{
+		Bio:                &identity.User.Bio,
+		Company:            &identity.User.Company,
+		ContextInformation: identity.User.ContextInformation,
+		Email:              &identity.User.Email,
+		FullName:           &identity.User.FullName,
+		ImageURL:           &identity.User.ImageURL,
+		URL:                &identity.User.URL,
+		Username:           &identity.Username,
+	}
+	if identity.RegistrationCompleted {
+		attributes.RegistrationCompleted = &identity.RegistrationCompleted
+	}
+	return &app.UpdateUsersPayload{
+		Data: &app.UpdateUserData{
+			Attributes: attributes,
+			Type:       "identities",
+		},
+	}
+}
@@ -0,0 +1,6 @@
+// Package outbox stores the events about users which must be delivered to the other services
+// of the platform (WIT, tenant, Keycloak). The events are written in the same database transaction
+// as the users and identities they are about, so the auth database and the other services cannot
+// diverge when a service is unavailable. The events are delivered by the dispatcher in
+// outbox/dispatcher, at least once.
+package outbox
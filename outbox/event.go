@@ -0,0 +1,235 @@
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/fabric8-services/fabric8-auth/errors"
+	"github.com/fabric8-services/fabric8-auth/gormsupport"
+	"github.com/fabric8-services/fabric8-auth/log"
+	"github.com/fabric8-services/fabric8-auth/tracing"
+
+	"github.com/jinzhu/gorm"
+	errs "github.com/pkg/errors"
+	uuid "github.com/satori/go.uuid"
+)
+
+const (
+	// EventTypeUserCreated is the type of the events emitted when a user is created
+	EventTypeUserCreated = "user.created"
+	// EventTypeUserUpdated is the type of the events emitted when a user is updated
+	EventTypeUserUpdated = "user.updated"
+	// EventTypeCollaboratorAdded is the type of the events emitted when a user becomes a space collaborator
+	EventTypeCollaboratorAdded = "collaborator.added"
+	// EventTypeCollaboratorRemoved is the type of the events emitted when a user is not a space collaborator anymore
+	EventTypeCollaboratorRemoved = "collaborator.removed"
+	// EventTypeTeamMemberAdded is the type of the events emitted when a user becomes a member of a team collaborating on a space
+	EventTypeTeamMemberAdded = "collaborator.team_member_added"
+	// EventTypeTeamMemberRemoved is the type of the events emitted when a user is not a member of a team collaborating on a space anymore
+	EventTypeTeamMemberRemoved = "collaborator.team_member_removed"
+	// EventTypeUserClusterChanged is the type of the events emitted when a user is reassigned to another cluster
+	EventTypeUserClusterChanged = "user.cluster_changed"
+	// EventTypeUserTenantRequested is the type of the events emitted when the tenant of a user is set up again
+	EventTypeUserTenantRequested = "user.tenant_requested"
+
+	// TargetWIT is the target of the events delivered to the WIT service
+	TargetWIT = "wit"
+	// TargetTenant is the target of the events delivered to the tenant service
+	TargetTenant = "tenant"
+	// TargetKeycloak is the target of the events applied to the Keycloak authorization policies
+	TargetKeycloak = "keycloak"
+	// TargetWebhook is the target of the events delivered to the URLs of the webhook subscriptions
+	TargetWebhook = "webhook"
+
+	// StatePending is the state of the events which are not delivered yet
+	StatePending = "pending"
+	// StateDelivered is the state of the events which have been delivered
+	StateDelivered = "delivered"
+	// StateDead is the state of the events which could not be delivered after the max number of attempts
+	StateDead = "dead"
+)
+
+// Event describes a single event about a user to deliver to another service
+type Event struct {
+	gormsupport.Lifecycle
+	ID            uuid.UUID `sql:"type:uuid" gorm:"primary_key"` // This is the ID PK field
+	EventType     string
+	Target        string
+	IdentityID    uuid.UUID `sql:"type:uuid"`
+	Payload       *string   `sql:"type:jsonb"`
+	State         string
+	Attempts      int
+	NextAttemptAt time.Time
+	LastError     string
+	DeliveredAt   *time.Time
+}
+
+// TableName overrides the table name settings in Gorm to force a specific table name
+// in the database.
+func (m Event) TableName() string {
+	return "outbox_events"
+}
+
+// NewEvent returns a new pending event with the given payload encoded as JSON
+func NewEvent(eventType string, target string, identityID uuid.UUID, payload interface{}) (*Event, error) {
+	js, err := json.Marshal(payload)
+	if err != nil {
+		return nil, errs.Wrapf(err, "unable to encode the payload of the %s event", eventType)
+	}
+	p := string(js)
+	return &Event{
+		EventType:  eventType,
+		Target:     target,
+		IdentityID: identityID,
+		Payload:    &p,
+		State:      StatePending,
+	}, nil
+}
+
+// DecodePayload decodes the JSON payload of the event into the given value
+func (m Event) DecodePayload(v interface{}) error {
+	if m.Payload == nil {
+		return errs.Errorf("the %s event %s has no payload", m.EventType, m.ID)
+	}
+	return errs.WithStack(json.Unmarshal([]byte(*m.Payload), v))
+}
+
+// GormEventRepository is the implementation of the storage interface for Event.
+type GormEventRepository struct {
+	db *gorm.DB
+}
+
+// NewEventRepository creates a new storage type.
+func NewEventRepository(db *gorm.DB) *GormEventRepository {
+	return &GormEventRepository{db: db}
+}
+
+// EventRepository represents the storage interface.
+type EventRepository interface {
+	Load(ctx context.Context, id uuid.UUID) (*Event, error)
+	Create(ctx context.Context, event *Event) error
+	Save(ctx context.Context, event *Event) error
+	LockDue(ctx context.Context, limit int) ([]Event, error)
+	Query(ctx context.Context, funcs ...func(*gorm.DB) *gorm.DB) ([]Event, error)
+}
+
+// TableName overrides the table name settings in Gorm to force a specific table name
+// in the database.
+func (m *GormEventRepository) TableName() string {
+	return "outbox_events"
+}
+
+// CRUD Functions
+
+// Load returns a single Event as a Database Model
+func (m *GormEventRepository) Load(ctx context.Context, id uuid.UUID) (*Event, error) {
+	defer tracing.MeasureSince(ctx, []string{"goa", "db", "OutboxEvent", "load"}, time.Now())
+
+	var native Event
+	err := m.db.Table(m.TableName()).Where("id = ?", id).Find(&native).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, errors.NewNotFoundError("outbox_event", id.String())
+	}
+
+	return &native, errs.WithStack(err)
+}
+
+// Create creates a new record. The event is due immediately unless its next attempt is already set.
+func (m *GormEventRepository) Create(ctx context.Context, model *Event) error {
+	defer tracing.MeasureSince(ctx, []string{"goa", "db", "OutboxEvent", "create"}, time.Now())
+	if model.ID == uuid.Nil {
+		model.ID = uuid.NewV4()
+	}
+	if model.State == "" {
+		model.State = StatePending
+	}
+	if model.NextAttemptAt.IsZero() {
+		model.NextAttemptAt = gorm.NowFunc()
+	}
+	err := m.db.Create(model).Error
+	if err != nil {
+		log.Error(ctx, map[string]interface{}{
+			"outbox_event_id": model.ID,
+			"event_type":      model.EventType,
+			"target":          model.Target,
+			"err":             err,
+		}, "unable to create the outbox event")
+		return errs.WithStack(err)
+	}
+	log.Debug(ctx, map[string]interface{}{
+		"outbox_event_id": model.ID,
+		"event_type":      model.EventType,
+		"target":          model.Target,
+		"identity_id":     model.IdentityID,
+	}, "outbox event created!")
+	return nil
+}
+
+// Save modifies a single record. All the fields are saved, including the cleared ones.
+func (m *GormEventRepository) Save(ctx context.Context, model *Event) error {
+	defer tracing.MeasureSince(ctx, []string{"goa", "db", "OutboxEvent", "save"}, time.Now())
+
+	db := m.db.Save(model)
+	if db.Error != nil {
+		log.Error(ctx, map[string]interface{}{
+			"outbox_event_id": model.ID,
+			"err":             db.Error,
+		}, "unable to update the outbox event")
+		return errs.WithStack(db.Error)
+	}
+	log.Debug(ctx, map[string]interface{}{
+		"outbox_event_id": model.ID,
+		"state":           model.State,
+	}, "outbox event saved!")
+	return nil
+}
+
+// LockDue returns the pending events whose next attempt is due, oldest first, and locks them
+// until the end of the transaction. The events locked by a concurrent transaction, e.g. by
+// another replica of the service, are skipped.
+func (m *GormEventRepository) LockDue(ctx context.Context, limit int) ([]Event, error) {
+	defer tracing.MeasureSince(ctx, []string{"goa", "db", "OutboxEvent", "lockDue"}, time.Now())
+	var events []Event
+	err := m.db.Set("gorm:query_option", "FOR UPDATE SKIP LOCKED").
+		Where("state = ? AND next_attempt_at <= ?", StatePending, gorm.NowFunc()).
+		Order("next_attempt_at, created_at").
+		Limit(limit).
+		Find(&events).Error
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return nil, errs.WithStack(err)
+	}
+	return events, nil
+}
+
+// Query expose an open ended Query model
+func (m *GormEventRepository) Query(ctx context.Context, funcs ...func(*gorm.DB) *gorm.DB) ([]Event, error) {
+	defer tracing.MeasureSince(ctx, []string{"goa", "db", "OutboxEvent", "query"}, time.Now())
+	var events []Event
+	err := m.db.Scopes(funcs...).Table(m.TableName()).Order("created_at").Find(&events).Error
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return nil, errs.WithStack(err)
+	}
+	return events, nil
+}
+
+// EventFilterByIdentityID is a gorm filter by 'identity_id'
+func EventFilterByIdentityID(identityID uuid.UUID) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where("identity_id = ?", identityID)
+	}
+}
+
+// EventFilterByState is a gorm filter by 'state'
+func EventFilterByState(state string) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where("state = ?", state)
+	}
+}
+
+// EventFilterByTarget is a gorm filter by 'target'
+func EventFilterByTarget(target string) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where("target = ?", target)
+	}
+}
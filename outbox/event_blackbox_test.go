@@ -0,0 +1,113 @@
+package outbox_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/fabric8-services/fabric8-auth/gormtestsupport"
+	"github.com/fabric8-services/fabric8-auth/outbox"
+	"github.com/fabric8-services/fabric8-auth/resource"
+
+	"github.com/satori/go.uuid"
+	"github.com/stretchr/testify/suite"
+)
+
+type eventBlackBoxTest struct {
+	gormtestsupport.DBTestSuite
+	repo outbox.EventRepository
+}
+
+func TestRunEventBlackBoxTest(t *testing.T) {
+	resource.Require(t, resource.Database)
+	suite.Run(t, &eventBlackBoxTest{DBTestSuite: gormtestsupport.NewDBTestSuite()})
+}
+
+func (s *eventBlackBoxTest) SetupTest() {
+	s.DBTestSuite.SetupTest()
+	s.repo = outbox.NewEventRepository(s.DB)
+}
+
+func (s *eventBlackBoxTest) TestCreateAndLoad() {
+	// given
+	event, err := outbox.NewWITUserCreatedEvent(uuid.NewV4(), "https://api.openshift.io", "https://auth.openshift.io")
+	s.Require().NoError(err)
+
+	// when
+	err = s.repo.Create(s.Ctx, event)
+
+	// then
+	s.Require().NoError(err)
+	loaded, err := s.repo.Load(s.Ctx, event.ID)
+	s.Require().NoError(err)
+	s.Equal(outbox.EventTypeUserCreated, loaded.EventType)
+	s.Equal(outbox.TargetWIT, loaded.Target)
+	s.Equal(event.IdentityID, loaded.IdentityID)
+	s.Equal(outbox.StatePending, loaded.State)
+	s.False(loaded.NextAttemptAt.IsZero())
+	var payload outbox.WITPayload
+	s.Require().NoError(loaded.DecodePayload(&payload))
+	s.Equal("https://api.openshift.io", payload.WITURL)
+	s.Equal("https://auth.openshift.io", payload.AuthURL)
+}
+
+func (s *eventBlackBoxTest) TestSaveClearsPayload() {
+	// given
+	event, err := outbox.NewTenantUserCreatedEvent(uuid.NewV4(), "https://auth.openshift.io")
+	s.Require().NoError(err)
+	s.Require().NoError(s.repo.Create(s.Ctx, event))
+
+	// when
+	now := time.Now()
+	event.State = outbox.StateDelivered
+	event.DeliveredAt = &now
+	event.Payload = nil
+	err = s.repo.Save(s.Ctx, event)
+
+	// then
+	s.Require().NoError(err)
+	loaded, err := s.repo.Load(s.Ctx, event.ID)
+	s.Require().NoError(err)
+	s.Equal(outbox.StateDelivered, loaded.State)
+	s.Nil(loaded.Payload)
+	s.NotNil(loaded.DeliveredAt)
+}
+
+func (s *eventBlackBoxTest) TestLockDue() {
+	// given
+	identityID := uuid.NewV4()
+	due, err := outbox.NewWITUserCreatedEvent(identityID, "https://api.openshift.io", "https://auth.openshift.io")
+	s.Require().NoError(err)
+	s.Require().NoError(s.repo.Create(s.Ctx, due))
+	later, err := outbox.NewWITUserUpdatedEvent(identityID, "https://api.openshift.io", "https://auth.openshift.io")
+	s.Require().NoError(err)
+	later.NextAttemptAt = time.Now().Add(time.Hour)
+	s.Require().NoError(s.repo.Create(s.Ctx, later))
+
+	// when the due events are locked by a first transaction
+	tx1, err := s.Application.BeginTransaction()
+	s.Require().NoError(err)
+	defer tx1.Rollback()
+	locked1, err := tx1.OutboxEvents().LockDue(s.Ctx, 100)
+	s.Require().NoError(err)
+	// and by a concurrent transaction
+	tx2, err := s.Application.BeginTransaction()
+	s.Require().NoError(err)
+	defer tx2.Rollback()
+	locked2, err := tx2.OutboxEvents().LockDue(s.Ctx, 100)
+	s.Require().NoError(err)
+
+	// then only the first transaction gets the due event
+	s.True(containsEvent(locked1, due.ID))
+	s.False(containsEvent(locked1, later.ID))
+	s.False(containsEvent(locked2, due.ID))
+	s.False(containsEvent(locked2, later.ID))
+}
+
+func containsEvent(events []outbox.Event, id uuid.UUID) bool {
+	for _, e := range events {
+		if e.ID == id {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,102 @@
+package outbox
+
+import (
+	"encoding/json"
+
+	uuid "github.com/satori/go.uuid"
+)
+
+// WITPayload is the payload of the events delivered to WIT. The user is sent as stored in
+// the database when the event is delivered, so the retries never overwrite a newer update.
+type WITPayload struct {
+	// WITURL is the URL of the WIT service the user is created or updated in
+	WITURL string `json:"wit_url"`
+	// AuthURL is the URL of the auth service which issues the service account token sent to WIT
+	AuthURL string `json:"auth_url"`
+}
+
+// TenantPayload is the payload of the events which set up the tenant of a user. The tenant is set up with the
+// service account token of the auth service, so the tokens of the users are never stored in the events.
+type TenantPayload struct {
+	// AuthURL is the URL of the auth service which issues the service account token sent to the tenant service
+	AuthURL string `json:"auth_url"`
+}
+
+// TenantClusterPayload is the payload of the events which notify the tenant service, with the service account
+// token of the auth service, that a user was reassigned to another cluster
+type TenantClusterPayload struct {
+	// PreviousCluster is the API URL of the cluster the user was assigned to
+	PreviousCluster string `json:"previous_cluster"`
+	// Cluster is the API URL of the cluster the user is reassigned to
+	Cluster string `json:"cluster"`
+	// AuthURL is the URL of the auth service which issues the service account token sent to the tenant service
+	AuthURL string `json:"auth_url"`
+}
+
+// CollaboratorPayload is the payload of the events which add a user to or remove a user from the collaborators of a space
+type CollaboratorPayload struct {
+	// SpaceID is the ID of the space the user collaborates on
+	SpaceID uuid.UUID `json:"space_id"`
+	// AuthURL is the URL of the auth service the Keycloak endpoints are derived from
+	AuthURL string `json:"auth_url"`
+}
+
+// WebhookPayload is the payload of the events delivered to a webhook subscription
+type WebhookPayload struct {
+	// SubscriptionID is the ID of the subscription the event is delivered to
+	SubscriptionID uuid.UUID `json:"subscription_id"`
+	// Body is the JSON document posted to the URL of the subscription
+	Body json.RawMessage `json:"body"`
+}
+
+// NewWITUserCreatedEvent returns the event which creates the user of the given identity in WIT
+func NewWITUserCreatedEvent(identityID uuid.UUID, witURL string, authURL string) (*Event, error) {
+	return NewEvent(EventTypeUserCreated, TargetWIT, identityID, WITPayload{WITURL: witURL, AuthURL: authURL})
+}
+
+// NewWITUserUpdatedEvent returns the event which updates the user of the given identity in WIT
+func NewWITUserUpdatedEvent(identityID uuid.UUID, witURL string, authURL string) (*Event, error) {
+	return NewEvent(EventTypeUserUpdated, TargetWIT, identityID, WITPayload{WITURL: witURL, AuthURL: authURL})
+}
+
+// NewTenantUserCreatedEvent returns the event which sets up the tenant of the new user of the given identity
+func NewTenantUserCreatedEvent(identityID uuid.UUID, authURL string) (*Event, error) {
+	return NewEvent(EventTypeUserCreated, TargetTenant, identityID, TenantPayload{AuthURL: authURL})
+}
+
+// NewTenantClusterChangedEvent returns the event which moves the tenant of the user of the given identity to another cluster
+func NewTenantClusterChangedEvent(identityID uuid.UUID, previousCluster string, cluster string, authURL string) (*Event, error) {
+	return NewEvent(EventTypeUserClusterChanged, TargetTenant, identityID, TenantClusterPayload{PreviousCluster: previousCluster, Cluster: cluster, AuthURL: authURL})
+}
+
+// NewTenantRequestedEvent returns the event which sets up the tenant of the user of the given identity again
+func NewTenantRequestedEvent(identityID uuid.UUID, authURL string) (*Event, error) {
+	return NewEvent(EventTypeUserTenantRequested, TargetTenant, identityID, TenantPayload{AuthURL: authURL})
+}
+
+// NewCollaboratorAddedEvent returns the event which adds the user of the given identity to the collaborators of the given space
+func NewCollaboratorAddedEvent(identityID uuid.UUID, spaceID uuid.UUID, authURL string) (*Event, error) {
+	return NewEvent(EventTypeCollaboratorAdded, TargetKeycloak, identityID, CollaboratorPayload{SpaceID: spaceID, AuthURL: authURL})
+}
+
+// NewCollaboratorRemovedEvent returns the event which removes the user of the given identity from the collaborators of the given space
+func NewCollaboratorRemovedEvent(identityID uuid.UUID, spaceID uuid.UUID, authURL string) (*Event, error) {
+	return NewEvent(EventTypeCollaboratorRemoved, TargetKeycloak, identityID, CollaboratorPayload{SpaceID: spaceID, AuthURL: authURL})
+}
+
+// NewTeamMemberAddedEvent returns the event which adds the user of the given identity to the collaborators of the given
+// space as member of the teams collaborating on the space
+func NewTeamMemberAddedEvent(identityID uuid.UUID, spaceID uuid.UUID, authURL string) (*Event, error) {
+	return NewEvent(EventTypeTeamMemberAdded, TargetKeycloak, identityID, CollaboratorPayload{SpaceID: spaceID, AuthURL: authURL})
+}
+
+// NewTeamMemberRemovedEvent returns the event which removes the user of the given identity from the collaborators of the
+// given space if it was added as member of the teams collaborating on the space and it's not a member of them anymore
+func NewTeamMemberRemovedEvent(identityID uuid.UUID, spaceID uuid.UUID, authURL string) (*Event, error) {
+	return NewEvent(EventTypeTeamMemberRemoved, TargetKeycloak, identityID, CollaboratorPayload{SpaceID: spaceID, AuthURL: authURL})
+}
+
+// NewWebhookEvent returns the event which posts the given body to the URL of the given webhook subscription
+func NewWebhookEvent(eventType string, identityID uuid.UUID, subscriptionID uuid.UUID, body []byte) (*Event, error) {
+	return NewEvent(eventType, TargetWebhook, identityID, WebhookPayload{SubscriptionID: subscriptionID, Body: body})
+}
@@ -0,0 +1,5 @@
+// Package ratelimit throttles the requests to the actions which are exposed to brute force attacks,
+// like the service account token exchange or the login. The requests are limited with token buckets
+// per client IP, per service account ID and per identity. The buckets are stored in memory or,
+// to enforce the limits across all the replicas of the service, in Postgres.
+package ratelimit
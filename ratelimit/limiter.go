@@ -0,0 +1,69 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"time"
+
+	"github.com/fabric8-services/fabric8-auth/configuration"
+
+	"github.com/jinzhu/gorm"
+	errs "github.com/pkg/errors"
+)
+
+// Limiter takes a token from the bucket of the given key
+type Limiter interface {
+	// Allow returns true if the bucket of the key has a token left for the request.
+	// Otherwise it returns false and the time to wait before the next token is available.
+	Allow(ctx context.Context, key string, limit configuration.RateLimit) (bool, time.Duration, error)
+}
+
+// bucket is a token bucket which is refilled continuously
+type bucket struct {
+	tokens  float64
+	updated time.Time
+}
+
+// newBucket returns a full bucket
+func newBucket(limit configuration.RateLimit, now time.Time) *bucket {
+	return &bucket{tokens: float64(limit.Burst), updated: now}
+}
+
+// take refills the bucket for the time elapsed since the last request then takes a token if there is one left.
+// If there is no token left then it returns false and the time to wait before the next token is available.
+func (b *bucket) take(limit configuration.RateLimit, now time.Time) (bool, time.Duration) {
+	ratePerSecond := limit.PerMinute / 60
+	if elapsed := now.Sub(b.updated).Seconds(); elapsed > 0 {
+		b.tokens = math.Min(float64(limit.Burst), b.tokens+elapsed*ratePerSecond)
+	}
+	b.updated = now
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0
+	}
+	wait := (1 - b.tokens) / ratePerSecond
+	return false, time.Duration(wait * float64(time.Second))
+}
+
+// full returns true if the bucket would be full at the given time, i.e. it can be forgotten
+func (b *bucket) full(limit configuration.RateLimit, now time.Time) bool {
+	return b.tokens+now.Sub(b.updated).Seconds()*limit.PerMinute/60 >= float64(limit.Burst)
+}
+
+// The stores of the rate limit buckets
+const (
+	StoreMemory   = "memory"
+	StorePostgres = "postgres"
+)
+
+// NewLimiter creates the limiter which stores the buckets in the given store
+func NewLimiter(store string, db *gorm.DB) (Limiter, error) {
+	switch store {
+	case StoreMemory:
+		return NewMemoryLimiter(), nil
+	case StorePostgres:
+		return NewPostgresLimiter(db), nil
+	default:
+		return nil, errs.Errorf("unknown rate limit store '%s'. Supported stores: %s, %s", store, StoreMemory, StorePostgres)
+	}
+}
@@ -0,0 +1,60 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/fabric8-services/fabric8-auth/configuration"
+)
+
+// sweepInterval is the interval between two removals of the unused buckets
+const sweepInterval = time.Minute
+
+// MemoryLimiter stores the buckets in memory. The limits are enforced per replica of the service.
+type MemoryLimiter struct {
+	mux       sync.Mutex
+	buckets   map[string]*bucket
+	limits    map[string]configuration.RateLimit
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+// NewMemoryLimiter creates a limiter which stores the buckets in memory
+func NewMemoryLimiter() *MemoryLimiter {
+	return &MemoryLimiter{
+		buckets: map[string]*bucket{},
+		limits:  map[string]configuration.RateLimit{},
+		now:     time.Now,
+	}
+}
+
+// Allow takes a token from the bucket of the given key
+func (l *MemoryLimiter) Allow(ctx context.Context, key string, limit configuration.RateLimit) (bool, time.Duration, error) {
+	l.mux.Lock()
+	defer l.mux.Unlock()
+	now := l.now()
+	l.sweep(now)
+	b, found := l.buckets[key]
+	if !found {
+		b = newBucket(limit, now)
+		l.buckets[key] = b
+		l.limits[key] = limit
+	}
+	allowed, retryAfter := b.take(limit, now)
+	return allowed, retryAfter, nil
+}
+
+// sweep removes the buckets which are full again so the memory doesn't grow with every client seen
+func (l *MemoryLimiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < sweepInterval {
+		return
+	}
+	l.lastSweep = now
+	for key, b := range l.buckets {
+		if b.full(l.limits[key], now) {
+			delete(l.buckets, key)
+			delete(l.limits, key)
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/fabric8-services/fabric8-auth/configuration"
+	"github.com/fabric8-services/fabric8-auth/resource"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryLimiter(t *testing.T) {
+	resource.Require(t, resource.UnitTest)
+
+	now := time.Now()
+	limiter := NewMemoryLimiter()
+	limiter.now = func() time.Time { return now }
+	limit := configuration.RateLimit{PerMinute: 6, Burst: 2}
+
+	t.Run("burst", func(t *testing.T) {
+		for i := 0; i < 2; i++ {
+			allowed, _, err := limiter.Allow(context.Background(), "foo", limit)
+			require.NoError(t, err)
+			assert.True(t, allowed)
+		}
+		allowed, retryAfter, err := limiter.Allow(context.Background(), "foo", limit)
+		require.NoError(t, err)
+		assert.False(t, allowed)
+		// one token every 10 seconds
+		assert.Equal(t, 10*time.Second, retryAfter)
+	})
+
+	t.Run("other key", func(t *testing.T) {
+		allowed, _, err := limiter.Allow(context.Background(), "bar", limit)
+		require.NoError(t, err)
+		assert.True(t, allowed)
+	})
+
+	t.Run("refill", func(t *testing.T) {
+		now = now.Add(5 * time.Second)
+		allowed, retryAfter, err := limiter.Allow(context.Background(), "foo", limit)
+		require.NoError(t, err)
+		assert.False(t, allowed)
+		assert.Equal(t, 5*time.Second, retryAfter)
+
+		now = now.Add(5 * time.Second)
+		allowed, _, err = limiter.Allow(context.Background(), "foo", limit)
+		require.NoError(t, err)
+		assert.True(t, allowed)
+	})
+
+	t.Run("sweep", func(t *testing.T) {
+		now = now.Add(sweepInterval)
+		_, _, err := limiter.Allow(context.Background(), "baz", limit)
+		require.NoError(t, err)
+		// the buckets of foo and bar are full again
+		assert.Len(t, limiter.buckets, 1)
+	})
+}
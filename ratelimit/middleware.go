@@ -0,0 +1,162 @@
+package ratelimit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fabric8-services/fabric8-auth/configuration"
+	"github.com/fabric8-services/fabric8-auth/errors"
+	"github.com/fabric8-services/fabric8-auth/log"
+
+	jwt "github.com/dgrijalva/jwt-go"
+	"github.com/goadesign/goa"
+	goajwt "github.com/goadesign/goa/middleware/security/jwt"
+)
+
+// Configuration represents the rate limiting configuration
+type Configuration interface {
+	IsRateLimitEnabled() bool
+	GetRateLimits(action string) (configuration.ActionRateLimits, bool)
+	GetRateLimitTrustedProxies() int
+}
+
+// Middleware rejects the requests which exceed the rate limits of their action with a 429 Too Many Requests
+// error and a Retry-After header. It must be mounted after the jsonapi.ErrorHandler and the middleware
+// which extracts the token from the request so the limits per identity can be enforced.
+// The requests are accepted if the limiter fails.
+func Middleware(limiter Limiter, config Configuration) goa.Middleware {
+	return func(h goa.Handler) goa.Handler {
+		return func(ctx context.Context, rw http.ResponseWriter, req *http.Request) error {
+			if !config.IsRateLimitEnabled() {
+				return h(ctx, rw, req)
+			}
+			action := fmt.Sprintf("%s.%s", goa.ContextController(ctx), goa.ContextAction(ctx))
+			limits, found := config.GetRateLimits(action)
+			if !found {
+				return h(ctx, rw, req)
+			}
+			ip := clientIP(req, config.GetRateLimitTrustedProxies())
+			for _, b := range buckets(ctx, req, ip, strings.ToLower(action), limits) {
+				allowed, retryAfter, err := limiter.Allow(ctx, b.key, b.limit)
+				if err != nil {
+					log.Error(ctx, map[string]interface{}{
+						"err":    err,
+						"action": action,
+					}, "failed to check the rate limit. The request is accepted")
+					continue
+				}
+				if !allowed {
+					seconds := int(math.Ceil(retryAfter.Seconds()))
+					if seconds < 1 {
+						seconds = 1
+					}
+					log.Warn(ctx, map[string]interface{}{
+						"action":      action,
+						"bucket":      b.key,
+						"retry_after": seconds,
+					}, "rate limit exceeded")
+					rw.Header().Set("Retry-After", strconv.Itoa(seconds))
+					return errors.NewTooManyRequestsError(fmt.Sprintf("rate limit exceeded, retry in %d seconds", seconds), time.Duration(seconds)*time.Second)
+				}
+			}
+			return h(ctx, rw, req)
+		}
+	}
+}
+
+type limitedBucket struct {
+	key   string
+	limit configuration.RateLimit
+}
+
+// buckets returns the buckets the request takes a token from
+func buckets(ctx context.Context, req *http.Request, ip string, action string, limits configuration.ActionRateLimits) []limitedBucket {
+	var result []limitedBucket
+	if limits.IP != nil {
+		result = append(result, limitedBucket{fmt.Sprintf("%s:ip:%s", action, ip), *limits.IP})
+	}
+	if limits.ServiceAccount != nil {
+		if id := serviceAccountID(ctx, req); id != "" {
+			result = append(result, limitedBucket{fmt.Sprintf("%s:sa:%s", action, id), *limits.ServiceAccount})
+		}
+	}
+	if limits.Identity != nil {
+		if id := identityID(ctx); id != "" {
+			result = append(result, limitedBucket{fmt.Sprintf("%s:identity:%s", action, id), *limits.Identity})
+		}
+	}
+	return result
+}
+
+// clientIP returns the IP of the client. Each of the given number of trusted proxies in front of the service
+// appends the address of its own client to the X-Forwarded-For header, so the client IP is the right-most
+// address which was not added by them. The addresses on its left can be set by the client and are ignored.
+func clientIP(req *http.Request, trustedProxies int) string {
+	remoteIP, _, err := net.SplitHostPort(req.RemoteAddr)
+	if err != nil {
+		remoteIP = req.RemoteAddr
+	}
+	if trustedProxies <= 0 {
+		return remoteIP
+	}
+	var hops []string
+	for _, header := range req.Header["X-Forwarded-For"] {
+		for _, hop := range strings.Split(header, ",") {
+			if hop = strings.TrimSpace(hop); hop != "" {
+				hops = append(hops, hop)
+			}
+		}
+	}
+	// the remote address is the last trusted proxy
+	hops = append(hops, remoteIP)
+	if trustedProxies >= len(hops) {
+		// the request didn't go through all the trusted proxies
+		return hops[0]
+	}
+	return hops[len(hops)-1-trustedProxies]
+}
+
+// serviceAccountID returns the client_id param of the request, from the query or from the payload
+// which was decoded by goa before the middleware chain is called
+func serviceAccountID(ctx context.Context, req *http.Request) string {
+	if id := req.URL.Query().Get("client_id"); id != "" {
+		return id
+	}
+	r := goa.ContextRequest(ctx)
+	if r == nil || r.Payload == nil {
+		return ""
+	}
+	// the payload types of the actions keep the names of the attributes of the design in their JSON tags
+	data, err := json.Marshal(r.Payload)
+	if err != nil {
+		return ""
+	}
+	var payload struct {
+		ClientID string `json:"client_id"`
+	}
+	if err := json.Unmarshal(data, &payload); err != nil {
+		return ""
+	}
+	return payload.ClientID
+}
+
+// identityID returns the subject of the token of the request
+func identityID(ctx context.Context) string {
+	token := goajwt.ContextJWT(ctx)
+	if token == nil {
+		return ""
+	}
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return ""
+	}
+	sub, _ := claims["sub"].(string)
+	return sub
+}
@@ -0,0 +1,129 @@
+package ratelimit_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/fabric8-services/fabric8-auth/app"
+	"github.com/fabric8-services/fabric8-auth/configuration"
+	"github.com/fabric8-services/fabric8-auth/jsonapi"
+	"github.com/fabric8-services/fabric8-auth/ratelimit"
+	"github.com/fabric8-services/fabric8-auth/resource"
+
+	"github.com/goadesign/goa"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type rateLimitConfig struct {
+	limits         map[string]configuration.ActionRateLimits
+	trustedProxies int
+}
+
+func (c rateLimitConfig) IsRateLimitEnabled() bool {
+	return true
+}
+
+func (c rateLimitConfig) GetRateLimits(action string) (configuration.ActionRateLimits, bool) {
+	limits, found := c.limits[strings.ToLower(action)]
+	return limits, found
+}
+
+func (c rateLimitConfig) GetRateLimitTrustedProxies() int {
+	return c.trustedProxies
+}
+
+// tokenController only implements the exchange action, which checks the payload it receives
+type tokenController struct {
+	app.TokenController
+	t *testing.T
+}
+
+func (c *tokenController) Exchange(ctx *app.ExchangeTokenContext) error {
+	// the payload decoded before the middleware chain must be the one received by the action
+	require.NotNil(c.t, ctx.Payload.ClientSecret)
+	assert.Equal(c.t, "secret", *ctx.Payload.ClientSecret)
+	return ctx.OK(&app.OauthToken{})
+}
+
+func newExchangeService(t *testing.T, limits configuration.ActionRateLimits, trustedProxies int) *goa.Service {
+	service := goa.New("test")
+	service.Use(jsonapi.ErrorHandler(service, true))
+	service.Use(ratelimit.Middleware(ratelimit.NewMemoryLimiter(), rateLimitConfig{
+		limits:         map[string]configuration.ActionRateLimits{"token.exchange": limits},
+		trustedProxies: trustedProxies,
+	}))
+	app.MountTokenController(service, &tokenController{t: t})
+	return service
+}
+
+func exchange(service *goa.Service, ip string, clientID string, forwardedFor ...string) *httptest.ResponseRecorder {
+	form := url.Values{"grant_type": {"client_credentials"}, "client_id": {clientID}, "client_secret": {"secret"}}
+	req := httptest.NewRequest("POST", "/api/token", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	for _, f := range forwardedFor {
+		req.Header.Add("X-Forwarded-For", f)
+	}
+	req.RemoteAddr = ip + ":12345"
+	rw := httptest.NewRecorder()
+	service.Mux.ServeHTTP(rw, req)
+	return rw
+}
+
+func TestMiddlewareLimitsPerIP(t *testing.T) {
+	resource.Require(t, resource.UnitTest)
+	service := newExchangeService(t, configuration.ActionRateLimits{
+		IP: &configuration.RateLimit{PerMinute: 1, Burst: 2},
+	}, 0)
+
+	assert.Equal(t, http.StatusOK, exchange(service, "10.0.0.1", "sa1").Code)
+	assert.Equal(t, http.StatusOK, exchange(service, "10.0.0.1", "sa2").Code)
+	rw := exchange(service, "10.0.0.1", "sa3")
+	assert.Equal(t, http.StatusTooManyRequests, rw.Code)
+	assert.Equal(t, "60", rw.Header().Get("Retry-After"))
+	// other clients are not affected
+	assert.Equal(t, http.StatusOK, exchange(service, "10.0.0.2", "sa1").Code)
+}
+
+func TestMiddlewareLimitsPerServiceAccount(t *testing.T) {
+	resource.Require(t, resource.UnitTest)
+	service := newExchangeService(t, configuration.ActionRateLimits{
+		ServiceAccount: &configuration.RateLimit{PerMinute: 1, Burst: 1},
+	}, 0)
+
+	assert.Equal(t, http.StatusOK, exchange(service, "10.0.0.1", "sa1").Code)
+	// brute force from another IP
+	rw := exchange(service, "10.0.0.2", "sa1")
+	assert.Equal(t, http.StatusTooManyRequests, rw.Code)
+	assert.NotEmpty(t, rw.Header().Get("Retry-After"))
+	assert.Equal(t, http.StatusOK, exchange(service, "10.0.0.2", "sa2").Code)
+}
+
+func TestMiddlewareIgnoresForwardedForWithoutTrustedProxy(t *testing.T) {
+	resource.Require(t, resource.UnitTest)
+	service := newExchangeService(t, configuration.ActionRateLimits{
+		IP: &configuration.RateLimit{PerMinute: 1, Burst: 1},
+	}, 0)
+
+	assert.Equal(t, http.StatusOK, exchange(service, "10.0.0.1", "sa1", "192.168.0.1").Code)
+	// a spoofed header doesn't give a new bucket
+	assert.Equal(t, http.StatusTooManyRequests, exchange(service, "10.0.0.1", "sa1", "192.168.0.2").Code)
+}
+
+func TestMiddlewareUsesRightMostUntrustedForwardedFor(t *testing.T) {
+	resource.Require(t, resource.UnitTest)
+	// the requests go through a router at 10.0.0.1 which appends the address of its client
+	service := newExchangeService(t, configuration.ActionRateLimits{
+		IP: &configuration.RateLimit{PerMinute: 1, Burst: 1},
+	}, 1)
+
+	assert.Equal(t, http.StatusOK, exchange(service, "10.0.0.1", "sa1", "192.168.0.1").Code)
+	// the client prepends spoofed addresses to the header, the one added by the router is used
+	assert.Equal(t, http.StatusTooManyRequests, exchange(service, "10.0.0.1", "sa1", "1.2.3.4, 192.168.0.1").Code)
+	assert.Equal(t, http.StatusTooManyRequests, exchange(service, "10.0.0.1", "sa1", "5.6.7.8", "192.168.0.1").Code)
+	// other clients behind the router are not affected
+	assert.Equal(t, http.StatusOK, exchange(service, "10.0.0.1", "sa1", "192.168.0.2").Code)
+}
@@ -0,0 +1,89 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/fabric8-services/fabric8-auth/configuration"
+	"github.com/fabric8-services/fabric8-auth/log"
+	"github.com/fabric8-services/fabric8-auth/tracing"
+
+	"github.com/jinzhu/gorm"
+	errs "github.com/pkg/errors"
+)
+
+// bucketTTL is the time after which an unused bucket is removed from the database.
+// A bucket is full again long before for any sensible limit.
+const bucketTTL = time.Hour
+
+// PostgresLimiter stores the buckets in the rate_limit_buckets table so the limits
+// are shared by all the replicas of the service. The bucket of a key is locked while a token is taken.
+type PostgresLimiter struct {
+	db        *gorm.DB
+	mux       sync.Mutex
+	lastSweep time.Time
+}
+
+// NewPostgresLimiter creates a limiter which stores the buckets in the given database
+func NewPostgresLimiter(db *gorm.DB) *PostgresLimiter {
+	return &PostgresLimiter{db: db}
+}
+
+// Allow takes a token from the bucket of the given key
+func (l *PostgresLimiter) Allow(ctx context.Context, key string, limit configuration.RateLimit) (allowed bool, retryAfter time.Duration, err error) {
+	defer tracing.MeasureSince(ctx, []string{"goa", "db", "ratelimit", "allow"}, time.Now())
+	l.sweep(ctx)
+
+	tx := l.db.Begin()
+	if tx.Error != nil {
+		return false, 0, errs.WithStack(tx.Error)
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	// The database clock is used so all the replicas agree on the time
+	err = tx.Exec("INSERT INTO rate_limit_buckets (key, tokens, updated_at) VALUES (?, ?, now()) ON CONFLICT (key) DO NOTHING", key, limit.Burst).Error
+	if err != nil {
+		return false, 0, errs.Wrapf(err, "failed to create the rate limit bucket %s", key)
+	}
+	var row struct {
+		Tokens    float64
+		UpdatedAt time.Time
+		Now       time.Time
+	}
+	err = tx.Raw("SELECT tokens, updated_at, now() AS now FROM rate_limit_buckets WHERE key = ? FOR UPDATE", key).Scan(&row).Error
+	if err != nil {
+		return false, 0, errs.Wrapf(err, "failed to load the rate limit bucket %s", key)
+	}
+	b := &bucket{tokens: row.Tokens, updated: row.UpdatedAt}
+	allowed, retryAfter = b.take(limit, row.Now)
+	err = tx.Exec("UPDATE rate_limit_buckets SET tokens = ?, updated_at = ? WHERE key = ?", b.tokens, b.updated, key).Error
+	if err != nil {
+		return false, 0, errs.Wrapf(err, "failed to update the rate limit bucket %s", key)
+	}
+	if err = tx.Commit().Error; err != nil {
+		return false, 0, errs.WithStack(err)
+	}
+	return allowed, retryAfter, nil
+}
+
+// sweep removes the unused buckets from the database
+func (l *PostgresLimiter) sweep(ctx context.Context) {
+	l.mux.Lock()
+	if time.Since(l.lastSweep) < sweepInterval {
+		l.mux.Unlock()
+		return
+	}
+	l.lastSweep = time.Now()
+	l.mux.Unlock()
+	err := l.db.Exec("DELETE FROM rate_limit_buckets WHERE updated_at < now() - ? * interval '1 second'", bucketTTL.Seconds()).Error
+	if err != nil {
+		log.Error(ctx, map[string]interface{}{
+			"err": err,
+		}, "failed to remove the unused rate limit buckets")
+	}
+}
@@ -0,0 +1,44 @@
+package ratelimit_test
+
+import (
+	"testing"
+
+	"github.com/fabric8-services/fabric8-auth/configuration"
+	"github.com/fabric8-services/fabric8-auth/gormtestsupport"
+	"github.com/fabric8-services/fabric8-auth/ratelimit"
+	"github.com/fabric8-services/fabric8-auth/resource"
+
+	"github.com/satori/go.uuid"
+	"github.com/stretchr/testify/suite"
+)
+
+type postgresLimiterBlackBoxTest struct {
+	gormtestsupport.DBTestSuite
+}
+
+func TestRunPostgresLimiterBlackBoxTest(t *testing.T) {
+	resource.Require(t, resource.Database)
+	suite.Run(t, &postgresLimiterBlackBoxTest{DBTestSuite: gormtestsupport.NewDBTestSuite()})
+}
+
+func (s *postgresLimiterBlackBoxTest) TestAllow() {
+	// given two replicas sharing the same database
+	replica1 := ratelimit.NewPostgresLimiter(s.DB)
+	replica2 := ratelimit.NewPostgresLimiter(s.DB)
+	limit := configuration.RateLimit{PerMinute: 1, Burst: 2}
+	key := "token.exchange:sa:" + uuid.NewV4().String()
+
+	// when
+	allowed1, _, err := replica1.Allow(s.Ctx, key, limit)
+	s.Require().NoError(err)
+	allowed2, _, err := replica2.Allow(s.Ctx, key, limit)
+	s.Require().NoError(err)
+	allowed3, retryAfter, err := replica1.Allow(s.Ctx, key, limit)
+	s.Require().NoError(err)
+
+	// then
+	s.True(allowed1)
+	s.True(allowed2)
+	s.False(allowed3)
+	s.True(retryAfter > 0)
+}
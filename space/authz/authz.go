@@ -5,6 +5,7 @@ import (
 	"context"
 	"net/http"
 
+	"github.com/fabric8-services/fabric8-auth/application"
 	"github.com/fabric8-services/fabric8-auth/auth"
 	"github.com/fabric8-services/fabric8-auth/errors"
 	"github.com/fabric8-services/fabric8-auth/log"
@@ -14,6 +15,7 @@ import (
 	"github.com/dgrijalva/jwt-go"
 	"github.com/goadesign/goa"
 	goajwt "github.com/goadesign/goa/middleware/security/jwt"
+	uuid "github.com/satori/go.uuid"
 )
 
 // AuthzService represents a space authorization service
@@ -52,11 +54,13 @@ func (m *KeycloakAuthzServiceManager) EntitlementEndpoint() string {
 // KeycloakAuthzService implements AuthzService interface
 type KeycloakAuthzService struct {
 	config AuthzConfiguration
+	db     application.DB
 }
 
-// NewAuthzService constructs a new KeycloakAuthzService
-func NewAuthzService(config AuthzConfiguration) *KeycloakAuthzService {
-	return &KeycloakAuthzService{config: config}
+// NewAuthzService constructs a new KeycloakAuthzService. The members of the teams collaborating
+// on the spaces are resolved from the given DB, if any.
+func NewAuthzService(config AuthzConfiguration, db application.DB) *KeycloakAuthzService {
+	return &KeycloakAuthzService{config: config, db: db}
 }
 
 // Configuration returns authz service configuration
@@ -64,14 +68,54 @@ func (s *KeycloakAuthzService) Configuration() AuthzConfiguration {
 	return s.config
 }
 
-// Authorize returns true and the corresponding Requesting Party Token if the current user is among the space collaborators
+// Authorize returns true and the corresponding Requesting Party Token if the current user is among the space collaborators.
+// The time-bound collaborators whose access expired are ignored until they are removed from the space policy.
+// The members of the teams collaborating on the space are authorized from the DB too, since they are added to the
+// Keycloak policy of the space asynchronously by the outbox dispatcher.
 func (s *KeycloakAuthzService) Authorize(ctx context.Context, entitlementEndpoint string, spaceID string) (bool, error) {
 	jwttoken := goajwt.ContextJWT(ctx)
 	if jwttoken == nil {
 		return false, errors.NewUnauthorizedError("missing token")
 	}
 
-	return s.checkEntitlementForSpace(ctx, *jwttoken, entitlementEndpoint, spaceID)
+	ok, err := s.checkEntitlementForSpace(ctx, *jwttoken, entitlementEndpoint, spaceID)
+	if err != nil || s.db == nil {
+		return ok, err
+	}
+	spaceUUID, identityID, valid := spaceAndIdentity(*jwttoken, spaceID)
+	if !valid {
+		return ok, nil
+	}
+	if ok {
+		expired, err := s.db.SpaceCollaboratorExpiries().IsExpired(ctx, spaceUUID, identityID)
+		if err != nil || !expired {
+			return err == nil, err
+		}
+		log.Info(ctx, map[string]interface{}{
+			"space_id":    spaceUUID,
+			"identity_id": identityID,
+		}, "the access of the space collaborator expired")
+	}
+	// the members of the teams collaborating on the space are collaborators too
+	return s.db.SpaceTeams().HasMember(ctx, spaceUUID, identityID)
+}
+
+// spaceAndIdentity returns the ID of the space and the identity ID of the user of the token, if both are valid
+func spaceAndIdentity(token jwt.Token, spaceID string) (uuid.UUID, uuid.UUID, bool) {
+	spaceUUID, err := uuid.FromString(spaceID)
+	if err != nil {
+		return uuid.Nil, uuid.Nil, false
+	}
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return uuid.Nil, uuid.Nil, false
+	}
+	sub, _ := claims["sub"].(string)
+	identityID, err := uuid.FromString(sub)
+	if err != nil {
+		return uuid.Nil, uuid.Nil, false
+	}
+	return spaceUUID, identityID, true
 }
 
 func (s *KeycloakAuthzService) checkEntitlementForSpace(ctx context.Context, token jwt.Token, entitlementEndpoint string, spaceID string) (bool, error) {
@@ -44,7 +44,7 @@ func (s *TestAuthzSuite) SetupSuite() {
 	if s.Config.IsKeycloakTestsDisabled() {
 		s.T().Skip("Skipping Keycloak tests")
 	}
-	s.authzService = authz.NewAuthzService(s.Config)
+	s.authzService = authz.NewAuthzService(s.Config, nil)
 	s.entitlementEndpoint, err = s.Config.GetKeycloakEndpointEntitlement(nil)
 	if err != nil {
 		panic(fmt.Errorf("failed to get endpoint from configuration: %s", err.Error()))
@@ -96,7 +96,7 @@ func (s *TestAuthzSuite) checkPermissions(token string, spaceID string) bool {
 	tk := jwt.New(jwt.SigningMethodRS256)
 	tk.Raw = token
 	ctx := goajwt.WithJWT(context.Background(), tk)
-	authzService := authz.NewAuthzService(s.Config)
+	authzService := authz.NewAuthzService(s.Config, nil)
 	ok, err := authzService.Authorize(ctx, s.entitlementEndpoint, spaceID)
 	require.Nil(s.T(), err)
 	return ok
@@ -0,0 +1,149 @@
+package space
+
+import (
+	"context"
+	"time"
+
+	"github.com/fabric8-services/fabric8-auth/errors"
+	"github.com/fabric8-services/fabric8-auth/gormsupport"
+	"github.com/fabric8-services/fabric8-auth/log"
+	"github.com/fabric8-services/fabric8-auth/tracing"
+
+	"github.com/jinzhu/gorm"
+	uuid "github.com/satori/go.uuid"
+)
+
+const (
+	accessRequestTableName = "space_access_requests"
+
+	// AccessRequestStatePending is the state of the access requests waiting for a decision
+	AccessRequestStatePending = "pending"
+	// AccessRequestStateApproved is the state of the access requests of the users added to the space collaborators
+	AccessRequestStateApproved = "approved"
+	// AccessRequestStateDenied is the state of the access requests which have been denied
+	AccessRequestStateDenied = "denied"
+)
+
+// AccessRequest represents the request of a user to join the collaborators of a space
+type AccessRequest struct {
+	gormsupport.Lifecycle
+	ID          uuid.UUID `sql:"type:uuid default uuid_generate_v4()" gorm:"primary_key"`
+	SpaceID     uuid.UUID `sql:"type:uuid"`
+	RequesterID uuid.UUID `sql:"type:uuid"`
+	Message     string
+	State       string
+	DecidedBy   *uuid.UUID `sql:"type:uuid"`
+	DecidedAt   *time.Time
+}
+
+// TableName implements gorm.tabler
+func (r AccessRequest) TableName() string {
+	return accessRequestTableName
+}
+
+// AccessRequestRepository encapsulate storage & retrieval of the requests to access the spaces
+type AccessRequestRepository interface {
+	Create(ctx context.Context, request *AccessRequest) error
+	Save(ctx context.Context, request *AccessRequest) error
+	Load(ctx context.Context, ID uuid.UUID) (*AccessRequest, error)
+	Lock(ctx context.Context, ID uuid.UUID) (*AccessRequest, error)
+	List(ctx context.Context, spaceID uuid.UUID, state string) ([]AccessRequest, error)
+}
+
+// NewAccessRequestRepository creates a new space access request repo
+func NewAccessRequestRepository(db *gorm.DB) *GormAccessRequestRepository {
+	return &GormAccessRequestRepository{db}
+}
+
+// GormAccessRequestRepository implements AccessRequestRepository using gorm
+type GormAccessRequestRepository struct {
+	db *gorm.DB
+}
+
+// Create creates a pending access request
+// returns BadParameterError if the requester already has a pending request for the space, or InternalError
+func (r *GormAccessRequestRepository) Create(ctx context.Context, request *AccessRequest) error {
+	defer tracing.MeasureSince(ctx, []string{"goa", "db", "spaceaccessrequest", "create"}, time.Now())
+	if request.ID == uuid.Nil {
+		request.ID = uuid.NewV4()
+	}
+	request.State = AccessRequestStatePending
+	err := r.db.Create(request).Error
+	if gormsupport.IsUniqueViolation(err, "idx_space_access_requests_pending") {
+		return errors.NewBadParameterError("space", request.SpaceID.String()).Expected("a space without pending access request of the user")
+	}
+	if err != nil {
+		log.Error(ctx, map[string]interface{}{
+			"space_id":     request.SpaceID,
+			"requester_id": request.RequesterID,
+			"err":          err,
+		}, "unable to create the space access request")
+		return errors.NewInternalError(ctx, err)
+	}
+	log.Info(ctx, map[string]interface{}{
+		"space_id":          request.SpaceID,
+		"requester_id":      request.RequesterID,
+		"access_request_id": request.ID,
+	}, "Space access request created")
+	return nil
+}
+
+// Save updates the given access request
+// returns InternalError
+func (r *GormAccessRequestRepository) Save(ctx context.Context, request *AccessRequest) error {
+	defer tracing.MeasureSince(ctx, []string{"goa", "db", "spaceaccessrequest", "save"}, time.Now())
+	if err := r.db.Save(request).Error; err != nil {
+		log.Error(ctx, map[string]interface{}{
+			"access_request_id": request.ID,
+			"err":               err,
+		}, "unable to update the space access request")
+		return errors.NewInternalError(ctx, err)
+	}
+	return nil
+}
+
+// Load returns the access request for the given id
+// returns NotFoundError or InternalError
+func (r *GormAccessRequestRepository) Load(ctx context.Context, ID uuid.UUID) (*AccessRequest, error) {
+	defer tracing.MeasureSince(ctx, []string{"goa", "db", "spaceaccessrequest", "load"}, time.Now())
+	request := AccessRequest{}
+	tx := r.db.Where("id = ?", ID).First(&request)
+	if tx.RecordNotFound() {
+		return nil, errors.NewNotFoundError("space access request", ID.String())
+	}
+	if tx.Error != nil {
+		return nil, errors.NewInternalError(ctx, tx.Error)
+	}
+	return &request, nil
+}
+
+// Lock returns the access request for the given id and locks it until the end of the current transaction,
+// so a request is only decided once.
+// returns NotFoundError or InternalError
+func (r *GormAccessRequestRepository) Lock(ctx context.Context, ID uuid.UUID) (*AccessRequest, error) {
+	defer tracing.MeasureSince(ctx, []string{"goa", "db", "spaceaccessrequest", "lock"}, time.Now())
+	request := AccessRequest{}
+	tx := r.db.Set("gorm:query_option", "FOR UPDATE").Where("id = ?", ID).First(&request)
+	if tx.RecordNotFound() {
+		return nil, errors.NewNotFoundError("space access request", ID.String())
+	}
+	if tx.Error != nil {
+		return nil, errors.NewInternalError(ctx, tx.Error)
+	}
+	return &request, nil
+}
+
+// List returns the requests to access the given space in the given state, or in any state if the state is empty
+func (r *GormAccessRequestRepository) List(ctx context.Context, spaceID uuid.UUID, state string) ([]AccessRequest, error) {
+	defer tracing.MeasureSince(ctx, []string{"goa", "db", "spaceaccessrequest", "list"}, time.Now())
+	db := r.db.Where("space_id = ?", spaceID)
+	if state != "" {
+		db = db.Where("state = ?", state)
+	}
+	var requests []AccessRequest
+	err := db.Order("created_at").Find(&requests).Error
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return nil, errors.NewInternalError(ctx, err)
+	}
+	return requests, nil
+}
@@ -0,0 +1,100 @@
+package space_test
+
+import (
+	"testing"
+
+	"github.com/fabric8-services/fabric8-auth/account"
+	"github.com/fabric8-services/fabric8-auth/errors"
+	"github.com/fabric8-services/fabric8-auth/gormtestsupport"
+	"github.com/fabric8-services/fabric8-auth/resource"
+	"github.com/fabric8-services/fabric8-auth/space"
+	testsupport "github.com/fabric8-services/fabric8-auth/test"
+
+	errs "github.com/pkg/errors"
+	uuid "github.com/satori/go.uuid"
+	"github.com/stretchr/testify/suite"
+)
+
+func TestRunAccessRequestRepoBBTest(t *testing.T) {
+	resource.Require(t, resource.Database)
+	suite.Run(t, &accessRequestRepoBBTest{DBTestSuite: gormtestsupport.NewDBTestSuite()})
+}
+
+type accessRequestRepoBBTest struct {
+	gormtestsupport.DBTestSuite
+	repo      space.AccessRequestRepository
+	requester account.Identity
+}
+
+func (test *accessRequestRepoBBTest) SetupTest() {
+	test.DBTestSuite.SetupTest()
+	test.repo = space.NewAccessRequestRepository(test.DB)
+	var err error
+	test.requester, err = testsupport.CreateTestIdentity(test.DB, "space-access-request-test-"+uuid.NewV4().String(), account.KeycloakIDP)
+	test.Require().NoError(err)
+}
+
+func (test *accessRequestRepoBBTest) TestCreateLoadList() {
+	// given
+	spaceID := uuid.NewV4()
+	request := space.AccessRequest{SpaceID: spaceID, RequesterID: test.requester.ID, Message: "let me in"}
+
+	// when
+	err := test.repo.Create(test.Ctx, &request)
+
+	// then
+	test.Require().NoError(err)
+	loaded, err := test.repo.Load(test.Ctx, request.ID)
+	test.Require().NoError(err)
+	test.Equal(space.AccessRequestStatePending, loaded.State)
+	test.Equal("let me in", loaded.Message)
+	requests, err := test.repo.List(test.Ctx, spaceID, space.AccessRequestStatePending)
+	test.Require().NoError(err)
+	test.Require().Len(requests, 1)
+	requests, err = test.repo.List(test.Ctx, spaceID, space.AccessRequestStateApproved)
+	test.Require().NoError(err)
+	test.Empty(requests)
+	_, err = test.repo.Load(test.Ctx, uuid.NewV4())
+	test.IsType(errors.NotFoundError{}, errs.Cause(err))
+}
+
+func (test *accessRequestRepoBBTest) TestCreateSinglePendingRequest() {
+	// given
+	spaceID := uuid.NewV4()
+	first := space.AccessRequest{SpaceID: spaceID, RequesterID: test.requester.ID}
+	test.Require().NoError(test.repo.Create(test.Ctx, &first))
+
+	// when
+	err := test.repo.Create(test.Ctx, &space.AccessRequest{SpaceID: spaceID, RequesterID: test.requester.ID})
+
+	// then
+	test.IsType(errors.BadParameterError{}, errs.Cause(err))
+
+	// when the first request is denied
+	first.State = space.AccessRequestStateDenied
+	test.Require().NoError(test.repo.Save(test.Ctx, &first))
+
+	// then the user can ask again
+	test.Require().NoError(test.repo.Create(test.Ctx, &space.AccessRequest{SpaceID: spaceID, RequesterID: test.requester.ID}))
+	requests, err := test.repo.List(test.Ctx, spaceID, "")
+	test.Require().NoError(err)
+	test.Len(requests, 2)
+}
+
+func (test *accessRequestRepoBBTest) TestLock() {
+	// given
+	request := space.AccessRequest{SpaceID: uuid.NewV4(), RequesterID: test.requester.ID}
+	test.Require().NoError(test.repo.Create(test.Ctx, &request))
+
+	// when
+	tx := test.DB.Begin()
+	defer tx.Rollback()
+	locked, err := space.NewAccessRequestRepository(tx).Lock(test.Ctx, request.ID)
+
+	// then
+	test.Require().NoError(err)
+	test.Equal(request.ID, locked.ID)
+	test.Equal(space.AccessRequestStatePending, locked.State)
+	_, err = space.NewAccessRequestRepository(tx).Lock(test.Ctx, uuid.NewV4())
+	test.IsType(errors.NotFoundError{}, errs.Cause(err))
+}
@@ -0,0 +1,125 @@
+package space
+
+import (
+	"context"
+	"time"
+
+	"github.com/fabric8-services/fabric8-auth/errors"
+	"github.com/fabric8-services/fabric8-auth/log"
+	"github.com/fabric8-services/fabric8-auth/tracing"
+
+	"github.com/jinzhu/gorm"
+	uuid "github.com/satori/go.uuid"
+)
+
+const (
+	collaboratorExpiryTableName = "space_collaborator_expiries"
+)
+
+// CollaboratorExpiry represents the expiry of a time-bound space collaborator. The collaborators
+// are listed in the Keycloak policy of the space, which has no expiry, so the expired collaborators
+// are ignored when the space access is authorized until they are removed from the policy.
+type CollaboratorExpiry struct {
+	SpaceID    uuid.UUID `sql:"type:uuid" gorm:"primary_key"`
+	IdentityID uuid.UUID `sql:"type:uuid" gorm:"primary_key"`
+	ExpiresAt  time.Time
+	// AuthURL is the URL of the auth service the Keycloak endpoints are derived from when the collaborator is removed
+	AuthURL   string
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// TableName implements gorm.tabler
+func (e CollaboratorExpiry) TableName() string {
+	return collaboratorExpiryTableName
+}
+
+// Expired returns true if the collaborator has no access to the space anymore
+func (e CollaboratorExpiry) Expired() bool {
+	return !e.ExpiresAt.After(time.Now())
+}
+
+// CollaboratorExpiryRepository encapsulate storage & retrieval of the expiry of the time-bound space collaborators
+type CollaboratorExpiryRepository interface {
+	Set(ctx context.Context, expiry *CollaboratorExpiry) error
+	Load(ctx context.Context, spaceID uuid.UUID, identityID uuid.UUID) (*CollaboratorExpiry, error)
+	Delete(ctx context.Context, spaceID uuid.UUID, identityID uuid.UUID) error
+	IsExpired(ctx context.Context, spaceID uuid.UUID, identityID uuid.UUID) (bool, error)
+	LockExpired(ctx context.Context, limit int) ([]CollaboratorExpiry, error)
+}
+
+// NewCollaboratorExpiryRepository creates a new space collaborator expiry repo
+func NewCollaboratorExpiryRepository(db *gorm.DB) *GormCollaboratorExpiryRepository {
+	return &GormCollaboratorExpiryRepository{db}
+}
+
+// GormCollaboratorExpiryRepository implements CollaboratorExpiryRepository using gorm
+type GormCollaboratorExpiryRepository struct {
+	db *gorm.DB
+}
+
+// Set creates or replaces the expiry of the given space collaborator
+func (r *GormCollaboratorExpiryRepository) Set(ctx context.Context, expiry *CollaboratorExpiry) error {
+	defer tracing.MeasureSince(ctx, []string{"goa", "db", "spacecollaboratorexpiry", "set"}, time.Now())
+	if err := r.db.Save(expiry).Error; err != nil {
+		log.Error(ctx, map[string]interface{}{
+			"space_id":    expiry.SpaceID,
+			"identity_id": expiry.IdentityID,
+			"err":         err,
+		}, "unable to set the expiry of the space collaborator")
+		return errors.NewInternalError(ctx, err)
+	}
+	return nil
+}
+
+// Load returns the expiry of the given space collaborator
+// returns NotFoundError if the collaborator is not time-bound, or InternalError
+func (r *GormCollaboratorExpiryRepository) Load(ctx context.Context, spaceID uuid.UUID, identityID uuid.UUID) (*CollaboratorExpiry, error) {
+	defer tracing.MeasureSince(ctx, []string{"goa", "db", "spacecollaboratorexpiry", "load"}, time.Now())
+	expiry := CollaboratorExpiry{}
+	tx := r.db.Where("space_id = ? AND identity_id = ?", spaceID, identityID).First(&expiry)
+	if tx.RecordNotFound() {
+		return nil, errors.NewNotFoundError("space collaborator expiry", identityID.String())
+	}
+	if tx.Error != nil {
+		return nil, errors.NewInternalError(ctx, tx.Error)
+	}
+	return &expiry, nil
+}
+
+// Delete removes the expiry of the given space collaborator, if any
+func (r *GormCollaboratorExpiryRepository) Delete(ctx context.Context, spaceID uuid.UUID, identityID uuid.UUID) error {
+	defer tracing.MeasureSince(ctx, []string{"goa", "db", "spacecollaboratorexpiry", "delete"}, time.Now())
+	if err := r.db.Where("space_id = ? AND identity_id = ?", spaceID, identityID).Delete(CollaboratorExpiry{}).Error; err != nil {
+		return errors.NewInternalError(ctx, err)
+	}
+	return nil
+}
+
+// IsExpired returns true if the given identity is a time-bound collaborator of the space whose access expired
+func (r *GormCollaboratorExpiryRepository) IsExpired(ctx context.Context, spaceID uuid.UUID, identityID uuid.UUID) (bool, error) {
+	defer tracing.MeasureSince(ctx, []string{"goa", "db", "spacecollaboratorexpiry", "isExpired"}, time.Now())
+	var count int
+	err := r.db.Model(CollaboratorExpiry{}).Where("space_id = ? AND identity_id = ? AND expires_at <= ?", spaceID, identityID, gorm.NowFunc()).Count(&count).Error
+	if err != nil {
+		return false, errors.NewInternalError(ctx, err)
+	}
+	return count > 0, nil
+}
+
+// LockExpired returns the given max number of expired space collaborators, the oldest first, and locks
+// them until the end of the transaction. The collaborators locked by a concurrent transaction, e.g. by
+// another replica of the service, are skipped.
+func (r *GormCollaboratorExpiryRepository) LockExpired(ctx context.Context, limit int) ([]CollaboratorExpiry, error) {
+	defer tracing.MeasureSince(ctx, []string{"goa", "db", "spacecollaboratorexpiry", "lockExpired"}, time.Now())
+	var expiries []CollaboratorExpiry
+	err := r.db.Set("gorm:query_option", "FOR UPDATE SKIP LOCKED").
+		Where("expires_at <= ?", gorm.NowFunc()).
+		Order("expires_at").
+		Limit(limit).
+		Find(&expiries).Error
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return nil, errors.NewInternalError(ctx, err)
+	}
+	return expiries, nil
+}
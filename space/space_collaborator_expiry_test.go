@@ -0,0 +1,94 @@
+package space_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/fabric8-services/fabric8-auth/account"
+	"github.com/fabric8-services/fabric8-auth/errors"
+	"github.com/fabric8-services/fabric8-auth/gormtestsupport"
+	"github.com/fabric8-services/fabric8-auth/resource"
+	"github.com/fabric8-services/fabric8-auth/space"
+	testsupport "github.com/fabric8-services/fabric8-auth/test"
+
+	errs "github.com/pkg/errors"
+	uuid "github.com/satori/go.uuid"
+	"github.com/stretchr/testify/suite"
+)
+
+func TestRunCollaboratorExpiryRepoBBTest(t *testing.T) {
+	resource.Require(t, resource.Database)
+	suite.Run(t, &collaboratorExpiryRepoBBTest{DBTestSuite: gormtestsupport.NewDBTestSuite()})
+}
+
+type collaboratorExpiryRepoBBTest struct {
+	gormtestsupport.DBTestSuite
+	repo         space.CollaboratorExpiryRepository
+	collaborator account.Identity
+}
+
+func (test *collaboratorExpiryRepoBBTest) SetupTest() {
+	test.DBTestSuite.SetupTest()
+	test.repo = space.NewCollaboratorExpiryRepository(test.DB)
+	var err error
+	test.collaborator, err = testsupport.CreateTestIdentity(test.DB, "space-collaborator-expiry-test-"+uuid.NewV4().String(), account.KeycloakIDP)
+	test.Require().NoError(err)
+}
+
+func (test *collaboratorExpiryRepoBBTest) TestSetLoadDelete() {
+	// given
+	spaceID := uuid.NewV4()
+	expiry := space.CollaboratorExpiry{SpaceID: spaceID, IdentityID: test.collaborator.ID, ExpiresAt: time.Now().Add(time.Hour), AuthURL: "https://auth.openshift.io"}
+
+	// when
+	err := test.repo.Set(test.Ctx, &expiry)
+
+	// then
+	test.Require().NoError(err)
+	loaded, err := test.repo.Load(test.Ctx, spaceID, test.collaborator.ID)
+	test.Require().NoError(err)
+	test.False(loaded.Expired())
+	test.Equal("https://auth.openshift.io", loaded.AuthURL)
+	expired, err := test.repo.IsExpired(test.Ctx, spaceID, test.collaborator.ID)
+	test.Require().NoError(err)
+	test.False(expired)
+
+	// when the expiry is replaced
+	expiry.ExpiresAt = time.Now().Add(-time.Minute)
+	test.Require().NoError(test.repo.Set(test.Ctx, &expiry))
+
+	// then
+	expired, err = test.repo.IsExpired(test.Ctx, spaceID, test.collaborator.ID)
+	test.Require().NoError(err)
+	test.True(expired)
+
+	// when the expiry is deleted
+	test.Require().NoError(test.repo.Delete(test.Ctx, spaceID, test.collaborator.ID))
+
+	// then
+	_, err = test.repo.Load(test.Ctx, spaceID, test.collaborator.ID)
+	test.IsType(errors.NotFoundError{}, errs.Cause(err))
+	expired, err = test.repo.IsExpired(test.Ctx, spaceID, test.collaborator.ID)
+	test.Require().NoError(err)
+	test.False(expired)
+}
+
+func (test *collaboratorExpiryRepoBBTest) TestLockExpired() {
+	// given
+	expiredSpaceID := uuid.NewV4()
+	activeSpaceID := uuid.NewV4()
+	test.Require().NoError(test.repo.Set(test.Ctx, &space.CollaboratorExpiry{SpaceID: expiredSpaceID, IdentityID: test.collaborator.ID, ExpiresAt: time.Now().Add(-time.Minute)}))
+	test.Require().NoError(test.repo.Set(test.Ctx, &space.CollaboratorExpiry{SpaceID: activeSpaceID, IdentityID: test.collaborator.ID, ExpiresAt: time.Now().Add(time.Hour)}))
+
+	// when
+	expiries, err := test.repo.LockExpired(test.Ctx, 100)
+
+	// then
+	test.Require().NoError(err)
+	var spaceIDs []uuid.UUID
+	for _, expiry := range expiries {
+		spaceIDs = append(spaceIDs, expiry.SpaceID)
+	}
+	test.Contains(spaceIDs, expiredSpaceID)
+	test.NotContains(spaceIDs, activeSpaceID)
+}
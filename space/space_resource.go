@@ -9,8 +9,8 @@ import (
 	"github.com/fabric8-services/fabric8-auth/errors"
 	"github.com/fabric8-services/fabric8-auth/gormsupport"
 	"github.com/fabric8-services/fabric8-auth/log"
+	"github.com/fabric8-services/fabric8-auth/tracing"
 
-	"github.com/goadesign/goa"
 	"github.com/jinzhu/gorm"
 	uuid "github.com/satori/go.uuid"
 )
@@ -61,6 +61,7 @@ type ResourceRepository interface {
 	Load(ctx context.Context, ID uuid.UUID) (*Resource, error)
 	Delete(ctx context.Context, ID uuid.UUID) error
 	LoadBySpace(ctx context.Context, spaceID *uuid.UUID) (*Resource, error)
+	LockBySpace(ctx context.Context, spaceID uuid.UUID) (*Resource, error)
 }
 
 // NewResourceRepository creates a new space resource repo
@@ -76,7 +77,7 @@ type GormResourceRepository struct {
 // Load returns the space resource for the given id
 // returns NotFoundError or InternalError
 func (r *GormResourceRepository) Load(ctx context.Context, ID uuid.UUID) (*Resource, error) {
-	defer goa.MeasureSince([]string{"goa", "db", "spaceresource", "load"}, time.Now())
+	defer tracing.MeasureSince(ctx, []string{"goa", "db", "spaceresource", "load"}, time.Now())
 	res := Resource{}
 	tx := r.db.Where("id=?", ID).First(&res)
 	if tx.RecordNotFound() {
@@ -93,14 +94,14 @@ func (r *GormResourceRepository) Load(ctx context.Context, ID uuid.UUID) (*Resou
 
 // CheckExists returns nil if the given ID exists otherwise returns an error
 func (m *GormResourceRepository) CheckExists(ctx context.Context, id string) error {
-	defer goa.MeasureSince([]string{"goa", "db", "spaceresource", "exists"}, time.Now())
+	defer tracing.MeasureSince(ctx, []string{"goa", "db", "spaceresource", "exists"}, time.Now())
 	return repository.CheckExists(ctx, m.db, Resource{}.TableName(), id)
 }
 
 // Delete deletes the space resource with the given id
 // returns NotFoundError or InternalError
 func (r *GormResourceRepository) Delete(ctx context.Context, ID uuid.UUID) error {
-	defer goa.MeasureSince([]string{"goa", "db", "spaceresource", "delete"}, time.Now())
+	defer tracing.MeasureSince(ctx, []string{"goa", "db", "spaceresource", "delete"}, time.Now())
 	if ID == uuid.Nil {
 		log.Error(ctx, map[string]interface{}{
 			"space_resource_id": ID.String(),
@@ -129,7 +130,7 @@ func (r *GormResourceRepository) Delete(ctx context.Context, ID uuid.UUID) error
 // Save updates the given space resource in the DB
 // returns NotFoundError or InternalError
 func (r *GormResourceRepository) Save(ctx context.Context, p *Resource) (*Resource, error) {
-	defer goa.MeasureSince([]string{"goa", "db", "spaceresource", "save"}, time.Now())
+	defer tracing.MeasureSince(ctx, []string{"goa", "db", "spaceresource", "save"}, time.Now())
 	pr := Resource{}
 	tx := r.db.Where("id=?", p.ID).First(&pr)
 	if tx.RecordNotFound() {
@@ -163,7 +164,7 @@ func (r *GormResourceRepository) Save(ctx context.Context, p *Resource) (*Resour
 // Create creates a new Space Resource in the DB
 // returns InternalError
 func (r *GormResourceRepository) Create(ctx context.Context, resource *Resource) (*Resource, error) {
-	defer goa.MeasureSince([]string{"goa", "db", "spaceresource", "create"}, time.Now())
+	defer tracing.MeasureSince(ctx, []string{"goa", "db", "spaceresource", "create"}, time.Now())
 	if resource.ID == uuid.Nil {
 		resource.ID = uuid.NewV4()
 	}
@@ -181,7 +182,7 @@ func (r *GormResourceRepository) Create(ctx context.Context, resource *Resource)
 
 // LoadBySpace loads space resource by space ID
 func (r *GormResourceRepository) LoadBySpace(ctx context.Context, spaceID *uuid.UUID) (*Resource, error) {
-	defer goa.MeasureSince([]string{"goa", "db", "spaceresource", "loadBySpace"}, time.Now())
+	defer tracing.MeasureSince(ctx, []string{"goa", "db", "spaceresource", "loadBySpace"}, time.Now())
 	res := Resource{}
 	tx := r.db.Where("space_resources.space_id=?", *spaceID).First(&res)
 	if tx.RecordNotFound() {
@@ -195,3 +196,22 @@ func (r *GormResourceRepository) LoadBySpace(ctx context.Context, spaceID *uuid.
 	}
 	return &res, nil
 }
+
+// LockBySpace loads the space resource by space ID and locks it until the end of the current transaction,
+// so the concurrent changes of the space collaborators are applied one after the other.
+// returns NotFoundError or InternalError
+func (r *GormResourceRepository) LockBySpace(ctx context.Context, spaceID uuid.UUID) (*Resource, error) {
+	defer tracing.MeasureSince(ctx, []string{"goa", "db", "spaceresource", "lockBySpace"}, time.Now())
+	res := Resource{}
+	tx := r.db.Set("gorm:query_option", "FOR UPDATE").Where("space_resources.space_id=?", spaceID).First(&res)
+	if tx.RecordNotFound() {
+		log.Error(ctx, map[string]interface{}{
+			"space_id": spaceID.String(),
+		}, "Could not find space resource by space ID")
+		return nil, errors.NewNotFoundError("space resource", spaceID.String())
+	}
+	if tx.Error != nil {
+		return nil, errors.NewInternalError(ctx, tx.Error)
+	}
+	return &res, nil
+}
@@ -117,6 +117,14 @@ func (test *resourceRepoBBTest) TestLoadBySpace() {
 	assert.True(test.T(), (*res).Equal(*res2))
 }
 
+func (test *resourceRepoBBTest) TestLockBySpace() {
+	res, _ := expectResource(test.create(testResourceID, testPolicyID, testPermissionID), test.requireOk)
+
+	res2, _ := expectResource(test.lockBySpace(res.SpaceID), test.requireOk)
+	assert.True(test.T(), (*res).Equal(*res2))
+	expectResource(test.lockBySpace(uuid.NewV4()), test.assertNotFound())
+}
+
 func (test *resourceRepoBBTest) TestLoadByDifferentSpaceFails() {
 	test.create(testResourceID, testPolicyID, testPermissionID)
 
@@ -189,6 +197,13 @@ func (test *resourceRepoBBTest) loadBySpace(spaceID uuid.UUID) func() (*space.Re
 	}
 }
 
+func (test *resourceRepoBBTest) lockBySpace(spaceID uuid.UUID) func() (*space.Resource, error) {
+	return func() (*space.Resource, error) {
+		r, err := test.repo.LockBySpace(context.Background(), spaceID)
+		return r, err
+	}
+}
+
 func (test *resourceRepoBBTest) delete(id uuid.UUID) func() (*space.Resource, error) {
 	return func() (*space.Resource, error) {
 		err := test.repo.Delete(context.Background(), id)
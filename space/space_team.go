@@ -0,0 +1,189 @@
+package space
+
+import (
+	"context"
+	"time"
+
+	"github.com/fabric8-services/fabric8-auth/errors"
+	"github.com/fabric8-services/fabric8-auth/log"
+	"github.com/fabric8-services/fabric8-auth/tracing"
+
+	"github.com/jinzhu/gorm"
+	uuid "github.com/satori/go.uuid"
+)
+
+const (
+	teamTableName      = "space_teams"
+	teamGrantTableName = "space_team_grants"
+)
+
+// Team represents a team added to the collaborators of a space. The members of the team are added to the
+// Keycloak policy of the space by the outbox dispatcher, and are resolved from the DB when the space access
+// is authorized by the auth service (see space/authz) until then.
+type Team struct {
+	SpaceID uuid.UUID `sql:"type:uuid" gorm:"primary_key"`
+	TeamID  string    `sql:"type:uuid" gorm:"primary_key"`
+	// AuthURL is the URL of the auth service the Keycloak endpoints are derived from when the members of the team change
+	AuthURL   string
+	CreatedAt time.Time
+}
+
+// TableName implements gorm.tabler
+func (t Team) TableName() string {
+	return teamTableName
+}
+
+// TeamGrant represents a collaborator listed in the Keycloak policy of a space as member of the teams collaborating
+// on the space, rather than as direct collaborator. Only these collaborators are removed from the policy when they
+// are not members of the teams of the space anymore.
+type TeamGrant struct {
+	SpaceID    uuid.UUID `sql:"type:uuid" gorm:"primary_key"`
+	IdentityID uuid.UUID `sql:"type:uuid" gorm:"primary_key"`
+	CreatedAt  time.Time
+}
+
+// TableName implements gorm.tabler
+func (g TeamGrant) TableName() string {
+	return teamGrantTableName
+}
+
+// TeamRepository encapsulate storage & retrieval of the teams collaborating on spaces
+type TeamRepository interface {
+	Add(ctx context.Context, spaceID uuid.UUID, teamID string, authURL string) error
+	Remove(ctx context.Context, spaceID uuid.UUID, teamID string) error
+	List(ctx context.Context, spaceID uuid.UUID) ([]Team, error)
+	ListSpaces(ctx context.Context, teamID string) ([]Team, error)
+	RemoveTeam(ctx context.Context, teamID string) error
+	HasMember(ctx context.Context, spaceID uuid.UUID, identityID uuid.UUID) (bool, error)
+	AddGrant(ctx context.Context, spaceID uuid.UUID, identityID uuid.UUID) error
+	RemoveGrant(ctx context.Context, spaceID uuid.UUID, identityID uuid.UUID) (bool, error)
+}
+
+// NewTeamRepository creates a new space team repo
+func NewTeamRepository(db *gorm.DB) *GormTeamRepository {
+	return &GormTeamRepository{db}
+}
+
+// GormTeamRepository implements TeamRepository using gorm
+type GormTeamRepository struct {
+	db *gorm.DB
+}
+
+// Add adds the given team to the collaborators of the given space. The given auth URL is the one the Keycloak
+// endpoints are derived from when the members of the team change. Adding a team which already collaborates on
+// the space only replaces its auth URL.
+func (r *GormTeamRepository) Add(ctx context.Context, spaceID uuid.UUID, teamID string, authURL string) error {
+	defer tracing.MeasureSince(ctx, []string{"goa", "db", "spaceteam", "add"}, time.Now())
+	team := Team{SpaceID: spaceID, TeamID: teamID}
+	if err := r.db.Where(team).Assign(Team{AuthURL: authURL}).FirstOrCreate(&team).Error; err != nil {
+		log.Error(ctx, map[string]interface{}{
+			"space_id": spaceID,
+			"team_id":  teamID,
+			"err":      err,
+		}, "unable to add the team to the space collaborators")
+		return errors.NewInternalError(ctx, err)
+	}
+	log.Info(ctx, map[string]interface{}{
+		"space_id": spaceID,
+		"team_id":  teamID,
+	}, "Team added to the space collaborators")
+	return nil
+}
+
+// Remove removes the given team from the collaborators of the given space
+// returns NotFoundError or InternalError
+func (r *GormTeamRepository) Remove(ctx context.Context, spaceID uuid.UUID, teamID string) error {
+	defer tracing.MeasureSince(ctx, []string{"goa", "db", "spaceteam", "remove"}, time.Now())
+	tx := r.db.Where("space_id = ? AND team_id = ?", spaceID, teamID).Delete(Team{})
+	if err := tx.Error; err != nil {
+		log.Error(ctx, map[string]interface{}{
+			"space_id": spaceID,
+			"team_id":  teamID,
+			"err":      err,
+		}, "unable to remove the team from the space collaborators")
+		return errors.NewInternalError(ctx, err)
+	}
+	if tx.RowsAffected == 0 {
+		return errors.NewNotFoundError("space team", teamID)
+	}
+	return nil
+}
+
+// List returns the teams collaborating on the given space
+func (r *GormTeamRepository) List(ctx context.Context, spaceID uuid.UUID) ([]Team, error) {
+	defer tracing.MeasureSince(ctx, []string{"goa", "db", "spaceteam", "list"}, time.Now())
+	var teams []Team
+	err := r.db.Where("space_id = ?", spaceID).Order("created_at").Find(&teams).Error
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return nil, errors.NewInternalError(ctx, err)
+	}
+	return teams, nil
+}
+
+// ListSpaces returns the spaces the given team collaborates on
+func (r *GormTeamRepository) ListSpaces(ctx context.Context, teamID string) ([]Team, error) {
+	defer tracing.MeasureSince(ctx, []string{"goa", "db", "spaceteam", "listSpaces"}, time.Now())
+	var teams []Team
+	err := r.db.Where("team_id = ?", teamID).Order("created_at").Find(&teams).Error
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return nil, errors.NewInternalError(ctx, err)
+	}
+	return teams, nil
+}
+
+// RemoveTeam removes the given team from the collaborators of all the spaces
+func (r *GormTeamRepository) RemoveTeam(ctx context.Context, teamID string) error {
+	defer tracing.MeasureSince(ctx, []string{"goa", "db", "spaceteam", "removeTeam"}, time.Now())
+	if err := r.db.Where("team_id = ?", teamID).Delete(Team{}).Error; err != nil {
+		return errors.NewInternalError(ctx, err)
+	}
+	return nil
+}
+
+// HasMember returns true if the given identity has a role which is not expired in any of the teams collaborating on the given space
+func (r *GormTeamRepository) HasMember(ctx context.Context, spaceID uuid.UUID, identityID uuid.UUID) (bool, error) {
+	defer tracing.MeasureSince(ctx, []string{"goa", "db", "spaceteam", "hasMember"}, time.Now())
+	var exists bool
+	query := `
+		SELECT EXISTS (
+			SELECT 1 FROM space_teams st
+			JOIN resource r ON r.resource_id = st.team_id AND r.deleted_at IS NULL
+			JOIN identity_role ir ON ir.resource_id = st.team_id AND ir.deleted_at IS NULL
+			WHERE
+				st.space_id = $1
+				AND ir.identity_id = $2
+				AND (ir.expires_at IS NULL OR ir.expires_at > now())
+		)`
+	err := r.db.CommonDB().QueryRow(query, spaceID, identityID).Scan(&exists)
+	if err != nil {
+		return false, errors.NewInternalError(ctx, err)
+	}
+	return exists, nil
+}
+
+// AddGrant records that the given identity is listed in the Keycloak policy of the given space as member of
+// the teams collaborating on the space. Recording a grant twice is a no-op.
+func (r *GormTeamRepository) AddGrant(ctx context.Context, spaceID uuid.UUID, identityID uuid.UUID) error {
+	defer tracing.MeasureSince(ctx, []string{"goa", "db", "spaceteam", "addGrant"}, time.Now())
+	grant := TeamGrant{SpaceID: spaceID, IdentityID: identityID}
+	if err := r.db.Where(grant).FirstOrCreate(&grant).Error; err != nil {
+		log.Error(ctx, map[string]interface{}{
+			"space_id":    spaceID,
+			"identity_id": identityID,
+			"err":         err,
+		}, "unable to record the team grant of the space collaborator")
+		return errors.NewInternalError(ctx, err)
+	}
+	return nil
+}
+
+// RemoveGrant removes the team grant of the given identity in the given space, if any.
+// Returns true if the identity was listed in the Keycloak policy of the space as member of its teams.
+func (r *GormTeamRepository) RemoveGrant(ctx context.Context, spaceID uuid.UUID, identityID uuid.UUID) (bool, error) {
+	defer tracing.MeasureSince(ctx, []string{"goa", "db", "spaceteam", "removeGrant"}, time.Now())
+	tx := r.db.Where("space_id = ? AND identity_id = ?", spaceID, identityID).Delete(TeamGrant{})
+	if err := tx.Error; err != nil {
+		return false, errors.NewInternalError(ctx, err)
+	}
+	return tx.RowsAffected > 0, nil
+}
@@ -0,0 +1,113 @@
+package space_test
+
+import (
+	"testing"
+
+	"github.com/fabric8-services/fabric8-auth/account"
+	"github.com/fabric8-services/fabric8-auth/authorization/organization"
+	"github.com/fabric8-services/fabric8-auth/errors"
+	"github.com/fabric8-services/fabric8-auth/gormtestsupport"
+	"github.com/fabric8-services/fabric8-auth/resource"
+	"github.com/fabric8-services/fabric8-auth/space"
+	testsupport "github.com/fabric8-services/fabric8-auth/test"
+
+	errs "github.com/pkg/errors"
+	uuid "github.com/satori/go.uuid"
+	"github.com/stretchr/testify/suite"
+)
+
+func TestRunTeamRepoBBTest(t *testing.T) {
+	resource.Require(t, resource.Database)
+	suite.Run(t, &teamRepoBBTest{DBTestSuite: gormtestsupport.NewDBTestSuite()})
+}
+
+type teamRepoBBTest struct {
+	gormtestsupport.DBTestSuite
+	repo     space.TeamRepository
+	identity account.Identity
+	team     *organization.Group
+}
+
+func (test *teamRepoBBTest) SetupTest() {
+	test.DBTestSuite.SetupTest()
+	test.repo = space.NewTeamRepository(test.DB)
+	var err error
+	test.identity, err = test.createIdentity()
+	test.Require().NoError(err)
+	service := organization.NewService(test.Application)
+	org, err := service.Create(test.Ctx, organization.ResourceTypeOrganization, test.identity.ID, "space-team-test", "", nil)
+	test.Require().NoError(err)
+	test.team, err = service.Create(test.Ctx, organization.ResourceTypeTeam, test.identity.ID, "space-team-test", "", &org.ResourceID)
+	test.Require().NoError(err)
+}
+
+func (test *teamRepoBBTest) createIdentity() (account.Identity, error) {
+	return testsupport.CreateTestIdentity(test.DB, "space-team-test-"+uuid.NewV4().String(), account.KeycloakIDP)
+}
+
+func (test *teamRepoBBTest) TestAddListRemove() {
+	// given
+	spaceID := uuid.NewV4()
+
+	// when the team is added twice
+	test.Require().NoError(test.repo.Add(test.Ctx, spaceID, test.team.ResourceID, "http://localhost"))
+	test.Require().NoError(test.repo.Add(test.Ctx, spaceID, test.team.ResourceID, "https://auth.openshift.io"))
+
+	// then
+	teams, err := test.repo.List(test.Ctx, spaceID)
+	test.Require().NoError(err)
+	test.Require().Len(teams, 1)
+	test.Equal(test.team.ResourceID, teams[0].TeamID)
+	test.Equal("https://auth.openshift.io", teams[0].AuthURL)
+	spaces, err := test.repo.ListSpaces(test.Ctx, test.team.ResourceID)
+	test.Require().NoError(err)
+	test.Require().Len(spaces, 1)
+	test.Equal(spaceID, spaces[0].SpaceID)
+
+	// when
+	err = test.repo.Remove(test.Ctx, spaceID, test.team.ResourceID)
+
+	// then
+	test.Require().NoError(err)
+	teams, err = test.repo.List(test.Ctx, spaceID)
+	test.Require().NoError(err)
+	test.Empty(teams)
+	err = test.repo.Remove(test.Ctx, spaceID, test.team.ResourceID)
+	test.IsType(errors.NotFoundError{}, errs.Cause(err))
+}
+
+func (test *teamRepoBBTest) TestHasMember() {
+	// given
+	spaceID := uuid.NewV4()
+	other, err := test.createIdentity()
+	test.Require().NoError(err)
+	test.Require().NoError(test.repo.Add(test.Ctx, spaceID, test.team.ResourceID, "https://auth.openshift.io"))
+
+	// then
+	member, err := test.repo.HasMember(test.Ctx, spaceID, test.identity.ID)
+	test.Require().NoError(err)
+	test.True(member)
+	member, err = test.repo.HasMember(test.Ctx, spaceID, other.ID)
+	test.Require().NoError(err)
+	test.False(member)
+	member, err = test.repo.HasMember(test.Ctx, uuid.NewV4(), test.identity.ID)
+	test.Require().NoError(err)
+	test.False(member)
+}
+
+func (test *teamRepoBBTest) TestGrants() {
+	// given
+	spaceID := uuid.NewV4()
+
+	// when the grant is recorded twice
+	test.Require().NoError(test.repo.AddGrant(test.Ctx, spaceID, test.identity.ID))
+	test.Require().NoError(test.repo.AddGrant(test.Ctx, spaceID, test.identity.ID))
+
+	// then it's removed once
+	removed, err := test.repo.RemoveGrant(test.Ctx, spaceID, test.identity.ID)
+	test.Require().NoError(err)
+	test.True(removed)
+	removed, err = test.repo.RemoveGrant(test.Ctx, spaceID, test.identity.ID)
+	test.Require().NoError(err)
+	test.False(removed)
+}
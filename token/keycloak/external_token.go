@@ -58,7 +58,7 @@ func (c *KeycloakExternalTokenServiceClient) Get(ctx context.Context, accessToke
 	req.Header.Add("Authorization", "Bearer "+accessToken)
 	req.Header.Add("Accept", "application/json, text/plain, */*")
 
-	resp, err := c.client.Do(req)
+	resp, err := c.client.Do(req.WithContext(ctx))
 
 	if err != nil {
 		log.Error(ctx, map[string]interface{}{
@@ -115,7 +115,7 @@ func (c *KeycloakExternalTokenServiceClient) Delete(ctx context.Context, keycloa
 		return err
 	}
 	req.Header.Add("Authorization", "Bearer "+pat)
-	resp, err := c.client.Do(req)
+	resp, err := c.client.Do(req.WithContext(ctx))
 
 	if err != nil {
 		log.Error(ctx, map[string]interface{}{
@@ -9,12 +9,16 @@ import (
 	"strings"
 
 	"github.com/fabric8-services/fabric8-auth/application"
+	"github.com/fabric8-services/fabric8-auth/cluster/registry"
 	"github.com/fabric8-services/fabric8-auth/configuration"
 	errs "github.com/fabric8-services/fabric8-auth/errors"
 	"github.com/fabric8-services/fabric8-auth/log"
+	"github.com/fabric8-services/fabric8-auth/metric"
 	"github.com/fabric8-services/fabric8-auth/rest"
 	"github.com/fabric8-services/fabric8-auth/token/oauth"
 	"github.com/fabric8-services/fabric8-auth/token/provider"
+	"github.com/fabric8-services/fabric8-auth/tracing"
+	"github.com/fabric8-services/fabric8-auth/webhook"
 
 	"github.com/goadesign/goa"
 	"github.com/satori/go.uuid"
@@ -47,7 +51,7 @@ type LinkConfig interface {
 	GetGitHubClientDefaultScopes() string
 	GetGitHubClientSecret() string
 	IsTLSInsecureSkipVerify() bool
-	GetOSOClusters() map[string]configuration.OSOCluster
+	ResolveSecret(value string) (string, error)
 }
 
 // OauthProviderFactory represents oauth provider factory
@@ -56,15 +60,18 @@ type OauthProviderFactory interface {
 }
 
 // NewOauthProviderFactory returns the default Oauth provider factory.
-func NewOauthProviderFactory(config LinkConfig) *OauthProviderFactoryService {
+// The OpenShift clusters are resolved from the cluster registry of the given DB.
+func NewOauthProviderFactory(config LinkConfig, db application.DB) *OauthProviderFactoryService {
 	service := &OauthProviderFactoryService{
 		config: config,
+		db:     db,
 	}
 	return service
 }
 
 type OauthProviderFactoryService struct {
 	config LinkConfig
+	db     application.DB
 }
 
 // LinkService represents service for linking accounts
@@ -174,7 +181,7 @@ func (service *LinkService) Callback(ctx context.Context, req *goa.RequestData,
 		tr := &http.Transport{
 			TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
 		}
-		sslcli := &http.Client{Transport: tr}
+		sslcli := &http.Client{Transport: tracing.Transport(tr)}
 		ctx = context.WithValue(ctx, oauth2.HTTPClient, sslcli)
 	}
 
@@ -185,6 +192,7 @@ func (service *LinkService) Callback(ctx context.Context, req *goa.RequestData,
 			"code":  code,
 			"err":   err,
 		}, "exchange operation failed")
+		metric.RecordAccountLink(oauthProvider.TypeName(), err)
 		return "", err
 	}
 	if providerToken.AccessToken == "" {
@@ -193,11 +201,14 @@ func (service *LinkService) Callback(ctx context.Context, req *goa.RequestData,
 			"code":        code,
 			"provider_id": oauthProvider.ID(),
 		}, "access token return by provider is empty")
-		return "", errors.New("access token return by provider is empty")
+		err = errors.New("access token return by provider is empty")
+		metric.RecordAccountLink(oauthProvider.TypeName(), err)
+		return "", err
 	}
 
 	userProfile, err := oauthProvider.Profile(ctx, *providerToken)
 	if err != nil {
+		metric.RecordAccountLink(oauthProvider.TypeName(), err)
 		return "", err
 	}
 	err = application.Transactional(service.db, func(appl application.Application) error {
@@ -211,32 +222,35 @@ func (service *LinkService) Callback(ctx context.Context, req *goa.RequestData,
 			externalToken.Token = providerToken.AccessToken
 			externalToken.Username = userProfile.Username
 			err = appl.ExternalTokens().Save(ctx, &externalToken)
-			if err == nil {
-				log.Info(ctx, map[string]interface{}{
-					"provider_id":       oauthProvider.ID(),
-					"identity_id":       identityID,
-					"external_token_id": externalToken.ID,
-				}, "An existing token found. Account re-linked & new token saved.")
+			if err != nil {
+				return err
+			}
+			log.Info(ctx, map[string]interface{}{
+				"provider_id":       oauthProvider.ID(),
+				"identity_id":       identityID,
+				"external_token_id": externalToken.ID,
+			}, "An existing token found. Account re-linked & new token saved.")
+		} else {
+			externalToken := provider.ExternalToken{
+				Token:      providerToken.AccessToken,
+				IdentityID: identityUUID,
+				Scope:      oauthProvider.Scopes(),
+				ProviderID: oauthProvider.ID(),
+				Username:   userProfile.Username,
+			}
+			err = appl.ExternalTokens().Create(ctx, &externalToken)
+			if err != nil {
+				return err
 			}
-			return err
-		}
-		externalToken := provider.ExternalToken{
-			Token:      providerToken.AccessToken,
-			IdentityID: identityUUID,
-			Scope:      oauthProvider.Scopes(),
-			ProviderID: oauthProvider.ID(),
-			Username:   userProfile.Username,
-		}
-		err = appl.ExternalTokens().Create(ctx, &externalToken)
-		if err == nil {
 			log.Info(ctx, map[string]interface{}{
 				"provider_id":       oauthProvider.ID(),
 				"identity_id":       identityID,
 				"external_token_id": externalToken.ID,
 			}, "No old token found. Account linked & new token saved.")
 		}
-		return err
+		return webhook.Emit(ctx, appl, webhook.EventTypeIdentityLinked, webhook.Data{IdentityID: identityUUID, Provider: oauthProvider.TypeName(), Resource: forResource})
 	})
+	metric.RecordAccountLink(oauthProvider.TypeName(), err)
 	if err != nil {
 		log.Error(ctx, map[string]interface{}{
 			"state":       state,
@@ -255,6 +269,24 @@ func (service *LinkService) Callback(ctx context.Context, req *goa.RequestData,
 	return knownReferrer, nil
 }
 
+// resolveClusterSecrets returns the given cluster of the registry with the references to its secrets resolved
+func (service *OauthProviderFactoryService) resolveClusterSecrets(ctx context.Context, cluster registry.Cluster) (configuration.OSOCluster, error) {
+	osoCluster := cluster.OSOCluster()
+	var err error
+	osoCluster.ServiceAccountToken, err = service.config.ResolveSecret(cluster.ServiceAccountToken)
+	if err == nil {
+		osoCluster.AuthClientSecret, err = service.config.ResolveSecret(cluster.AuthClientSecret)
+	}
+	if err != nil {
+		log.Error(ctx, map[string]interface{}{
+			"cluster_url": cluster.URL,
+			"err":         err,
+		}, "unable to resolve the secrets of the cluster")
+		return osoCluster, errs.NewInternalError(ctx, err)
+	}
+	return osoCluster, nil
+}
+
 // NewOauthProvider creates a new oauth provider for the given resource URL
 func (service *OauthProviderFactoryService) NewOauthProvider(ctx context.Context, req *goa.RequestData, forResource string) (ProviderConfig, error) {
 	authURL := rest.AbsoluteURL(req, "")
@@ -265,11 +297,16 @@ func (service *OauthProviderFactoryService) NewOauthProvider(ctx context.Context
 	if resourceURL.Host == "github.com" {
 		return NewGitHubIdentityProvider(service.config.GetGitHubClientID(), service.config.GetGitHubClientSecret(), service.config.GetGitHubClientDefaultScopes(), authURL), nil
 	}
-	clusters := service.config.GetOSOClusters()
-	for apiURL, cluster := range clusters {
-		if strings.HasPrefix(forResource, apiURL) {
-			return NewOpenShiftIdentityProvider(cluster, authURL)
+	clusters, err := service.db.Clusters().List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if cluster, err := registry.Resolve(clusters, forResource); err == nil {
+		osoCluster, err := service.resolveClusterSecrets(ctx, *cluster)
+		if err != nil {
+			return nil, err
 		}
+		return NewOpenShiftIdentityProvider(osoCluster, authURL)
 	}
 	log.Error(ctx, map[string]interface{}{
 		"for": forResource,
@@ -39,7 +39,7 @@ func TestRunLinkTestSuite(t *testing.T) {
 
 func (s *LinkTestSuite) SetupSuite() {
 	s.DBTestSuite.SetupSuite()
-	providerFactory := NewOauthProviderFactory(s.Configuration)
+	providerFactory := NewOauthProviderFactory(s.Configuration, s.Application)
 	s.linkService = NewLinkServiceWithFactory(s.Configuration, s.Application, providerFactory)
 	s.requestData = &goa.RequestData{Request: &http.Request{
 		URL: &url.URL{Scheme: "https", Host: "auth.openshift.io"},
@@ -48,6 +48,7 @@ func (s *LinkTestSuite) SetupSuite() {
 
 func (s *LinkTestSuite) SetupTest() {
 	s.DBTestSuite.SetupTest()
+	s.SeedClusters()
 	var err error
 	s.testIdentity, err = test.CreateTestIdentity(s.DB, "TestLinkSuite user", "test provider")
 	require.Nil(s.T(), err)
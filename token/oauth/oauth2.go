@@ -52,7 +52,7 @@ func (provider *OauthIdentityProvider) UserProfilePayload(ctx context.Context, t
 		return nil, err
 	}
 	req.Header.Add("Authorization", "Bearer "+token.AccessToken)
-	res, err := http.DefaultClient.Do(req)
+	res, err := http.DefaultClient.Do(req.WithContext(ctx))
 	if err != nil {
 		log.Error(ctx, map[string]interface{}{
 			"err":         err.Error(),
@@ -10,6 +10,7 @@ import (
 	"github.com/fabric8-services/fabric8-auth/errors"
 	"github.com/fabric8-services/fabric8-auth/gormsupport"
 	"github.com/fabric8-services/fabric8-auth/log"
+	"github.com/fabric8-services/fabric8-auth/tracing"
 
 	"github.com/goadesign/goa"
 	"github.com/jinzhu/gorm"
@@ -79,7 +80,7 @@ func (m *GormExternalTokenRepository) TableName() string {
 // Load returns a single ExternalToken as a Database Model
 // This is more for use internally, and probably not what you want in  your controllers
 func (m *GormExternalTokenRepository) Load(ctx context.Context, id uuid.UUID) (*ExternalToken, error) {
-	defer goa.MeasureSince([]string{"goa", "db", "ExternalToken", "load"}, time.Now())
+	defer tracing.MeasureSince(ctx, []string{"goa", "db", "ExternalToken", "load"}, time.Now())
 
 	var native ExternalToken
 	err := m.db.Table(m.TableName()).Where("id = ?", id).Find(&native).Error
@@ -92,13 +93,13 @@ func (m *GormExternalTokenRepository) Load(ctx context.Context, id uuid.UUID) (*
 
 // CheckExists returns nil if the given ID exists otherwise returns an error
 func (m *GormExternalTokenRepository) CheckExists(ctx context.Context, id string) error {
-	defer goa.MeasureSince([]string{"goa", "db", "ExternalToken", "exists"}, time.Now())
+	defer tracing.MeasureSince(ctx, []string{"goa", "db", "ExternalToken", "exists"}, time.Now())
 	return repository.CheckHardDeletableExists(ctx, m.db, m.TableName(), id)
 }
 
 // Create creates a new record.
 func (m *GormExternalTokenRepository) Create(ctx context.Context, model *ExternalToken) error {
-	defer goa.MeasureSince([]string{"goa", "db", "ExternalToken", "create"}, time.Now())
+	defer tracing.MeasureSince(ctx, []string{"goa", "db", "ExternalToken", "create"}, time.Now())
 	if model.ID == uuid.Nil {
 		model.ID = uuid.NewV4()
 	}
@@ -118,7 +119,7 @@ func (m *GormExternalTokenRepository) Create(ctx context.Context, model *Externa
 
 // Save modifies a single record.
 func (m *GormExternalTokenRepository) Save(ctx context.Context, model *ExternalToken) error {
-	defer goa.MeasureSince([]string{"goa", "db", "ExternalToken", "save"}, time.Now())
+	defer tracing.MeasureSince(ctx, []string{"goa", "db", "ExternalToken", "save"}, time.Now())
 
 	obj, err := m.Load(ctx, model.ID)
 	if err != nil {
@@ -140,7 +141,7 @@ func (m *GormExternalTokenRepository) Save(ctx context.Context, model *ExternalT
 
 // Delete removes a single record. This is a hard delete!
 func (m *GormExternalTokenRepository) Delete(ctx context.Context, id uuid.UUID) error {
-	defer goa.MeasureSince([]string{"goa", "db", "ExternalToken", "delete"}, time.Now())
+	defer tracing.MeasureSince(ctx, []string{"goa", "db", "ExternalToken", "delete"}, time.Now())
 
 	obj := ExternalToken{ID: id}
 	db := m.db.Delete(obj)
@@ -180,7 +181,7 @@ func (m *GormExternalTokenRepository) Query(funcs ...func(*gorm.DB) *gorm.DB) ([
 
 // LoadByProviderIDAndIdentityID loads tokens by IdentityID and ProviderID
 func (m *GormExternalTokenRepository) LoadByProviderIDAndIdentityID(ctx context.Context, providerID uuid.UUID, identityID uuid.UUID) ([]ExternalToken, error) {
-	defer goa.MeasureSince([]string{"goa", "db", "ExternalToken", "LoadByProviderIDAndIdentityID"}, time.Now())
+	defer tracing.MeasureSince(ctx, []string{"goa", "db", "ExternalToken", "LoadByProviderIDAndIdentityID"}, time.Now())
 	var externalProviderTokens []ExternalToken
 	externalProviderTokens, err := m.Query(ExternalTokenFilterByIdentityID(identityID), ExternalTokenFilterByProviderID(providerID), ExternalTokenWithIdentity())
 	if err != nil && err != gorm.ErrRecordNotFound {
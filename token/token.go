@@ -53,9 +53,11 @@ type TokenClaims struct {
 	GivenName     string                `json:"given_name"`
 	FamilyName    string                `json:"family_name"`
 	Email         string                `json:"email"`
+	EmailVerified bool                  `json:"email_verified"`
 	Company       string                `json:"company"`
 	SessionState  string                `json:"session_state"`
 	Approved      bool                  `json:"approved"`
+	Region        string                `json:"region"`
 	Authorization *AuthorizationPayload `json:"authorization"`
 	jwt.StandardClaims
 }
@@ -461,6 +463,12 @@ func IsServiceAccount(ctx context.Context) bool {
 	return ok
 }
 
+// ServiceAccountName returns the name of the service account the request is done by,
+// or false if the request is not done by a service account
+func ServiceAccountName(ctx context.Context) (string, bool) {
+	return extractServiceAccountName(ctx)
+}
+
 func extractServiceAccountName(ctx context.Context) (string, bool) {
 	token := goajwt.ContextJWT(ctx)
 	if token == nil {
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/fabric8-services/fabric8-auth/client"
+)
+
+// apiClient performs the requests of the user-oriented commands, using the host, scheme, signer and dump
+// settings of the API client of the generated commands
+type apiClient struct {
+	*client.Client
+}
+
+// url returns the absolute URL of the given path of the service with the given query parameters
+func (c *apiClient) url(path string, query url.Values) *url.URL {
+	scheme := c.Scheme
+	if scheme == "" {
+		scheme = "http"
+	}
+	u := url.URL{Host: c.Host, Scheme: scheme, Path: path}
+	if query != nil {
+		u.RawQuery = query.Encode()
+	}
+	return &u
+}
+
+// do sends a request with the given JSON payload, if any, and decodes the JSON response in the given result, if any.
+// Unsuccessful responses are returned as errors holding the details of the JSON-API errors of the response.
+func (c *apiClient) do(ctx context.Context, method, path string, query url.Values, payload interface{}, result interface{}) error {
+	var body io.Reader
+	if payload != nil {
+		var b bytes.Buffer
+		if err := json.NewEncoder(&b).Encode(payload); err != nil {
+			return err
+		}
+		body = &b
+	}
+	req, err := http.NewRequest(method, c.url(path, query).String(), body)
+	if err != nil {
+		return err
+	}
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	return c.send(ctx, req, result)
+}
+
+// doForm sends a request with the given form payload and decodes the JSON response in the given result
+func (c *apiClient) doForm(ctx context.Context, method, path string, form url.Values, result interface{}) error {
+	req, err := http.NewRequest(method, c.url(path, nil).String(), strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return c.send(ctx, req, result)
+}
+
+func (c *apiClient) send(ctx context.Context, req *http.Request, result interface{}) error {
+	if c.JWTSigner != nil {
+		if err := c.JWTSigner.Sign(req); err != nil {
+			return err
+		}
+	}
+	resp, err := c.Client.Client.Do(ctx, req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return responseError(resp, data)
+	}
+	if result == nil || len(data) == 0 {
+		return nil
+	}
+	return json.Unmarshal(data, result)
+}
+
+// responseError returns the error of an unsuccessful response, made of the details of its JSON-API errors if any
+func responseError(resp *http.Response, data []byte) error {
+	var jerrors struct {
+		Errors []struct {
+			Code   string `json:"code"`
+			Detail string `json:"detail"`
+		} `json:"errors"`
+	}
+	if json.Unmarshal(data, &jerrors) == nil && len(jerrors.Errors) > 0 {
+		details := make([]string, 0, len(jerrors.Errors))
+		for _, e := range jerrors.Errors {
+			details = append(details, e.Detail)
+		}
+		return fmt.Errorf("%s: %s", resp.Status, strings.Join(details, "; "))
+	}
+	if message := strings.TrimSpace(string(data)); message != "" {
+		return fmt.Errorf("%s: %s", resp.Status, message)
+	}
+	return fmt.Errorf("%s", resp.Status)
+}
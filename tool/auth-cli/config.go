@@ -0,0 +1,89 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// cachedToken is a token of the user or of a service account cached after a login or an exchange
+type cachedToken struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+	TokenType    string `json:"token_type,omitempty"`
+}
+
+// tokenCache holds the cached tokens in the configuration directory of the user, by host of the service
+type tokenCache struct {
+	path string
+}
+
+// newTokenCache returns the cache of the tokens stored in $XDG_CONFIG_HOME/auth-cli, or in ~/.config/auth-cli
+// if XDG_CONFIG_HOME is not set
+func newTokenCache() *tokenCache {
+	dir := os.Getenv("XDG_CONFIG_HOME")
+	if dir == "" {
+		dir = filepath.Join(os.Getenv("HOME"), ".config")
+	}
+	return &tokenCache{path: filepath.Join(dir, "auth-cli", "tokens.json")}
+}
+
+func (c *tokenCache) load() (map[string]cachedToken, error) {
+	tokens := map[string]cachedToken{}
+	data, err := ioutil.ReadFile(c.path)
+	if os.IsNotExist(err) {
+		return tokens, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &tokens); err != nil {
+		return nil, err
+	}
+	return tokens, nil
+}
+
+func (c *tokenCache) store(tokens map[string]cachedToken) error {
+	if err := os.MkdirAll(filepath.Dir(c.path), 0700); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(tokens, "", "  ")
+	if err != nil {
+		return err
+	}
+	// the tokens grant access to the account of the user so only the user can read them
+	return ioutil.WriteFile(c.path, data, 0600)
+}
+
+// get returns the cached token of the given host, if any
+func (c *tokenCache) get(host string) (*cachedToken, error) {
+	tokens, err := c.load()
+	if err != nil {
+		return nil, err
+	}
+	if token, found := tokens[host]; found {
+		return &token, nil
+	}
+	return nil, nil
+}
+
+// put caches the token of the given host, replacing the previous one
+func (c *tokenCache) put(host string, token cachedToken) error {
+	tokens, err := c.load()
+	if err != nil {
+		return err
+	}
+	tokens[host] = token
+	return c.store(tokens)
+}
+
+// remove removes the cached token of the given host
+func (c *tokenCache) remove(host string) error {
+	tokens, err := c.load()
+	if err != nil {
+		return err
+	}
+	delete(tokens, host)
+	return c.store(tokens)
+}
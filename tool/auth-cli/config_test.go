@@ -0,0 +1,86 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/fabric8-services/fabric8-auth/resource"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTokenCache(t *testing.T) {
+	resource.Require(t, resource.UnitTest)
+	t.Parallel()
+
+	dir, err := ioutil.TempDir("", "auth-cli")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	cache := &tokenCache{path: filepath.Join(dir, "auth-cli", "tokens.json")}
+
+	t.Run("empty", func(t *testing.T) {
+		token, err := cache.get("openshift.io")
+		require.NoError(t, err)
+		assert.Nil(t, token)
+	})
+
+	t.Run("put", func(t *testing.T) {
+		require.NoError(t, cache.put("openshift.io", cachedToken{AccessToken: "first"}))
+		require.NoError(t, cache.put("localhost:8089", cachedToken{AccessToken: "local"}))
+		require.NoError(t, cache.put("openshift.io", cachedToken{AccessToken: "second", RefreshToken: "refresh"}))
+
+		token, err := cache.get("openshift.io")
+		require.NoError(t, err)
+		require.NotNil(t, token)
+		assert.Equal(t, cachedToken{AccessToken: "second", RefreshToken: "refresh"}, *token)
+		token, err = cache.get("localhost:8089")
+		require.NoError(t, err)
+		require.NotNil(t, token)
+		assert.Equal(t, "local", token.AccessToken)
+		// only the user can read the tokens
+		info, err := os.Stat(cache.path)
+		require.NoError(t, err)
+		assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+		info, err = os.Stat(filepath.Dir(cache.path))
+		require.NoError(t, err)
+		assert.Equal(t, os.FileMode(0700), info.Mode().Perm())
+	})
+
+	t.Run("remove", func(t *testing.T) {
+		require.NoError(t, cache.remove("openshift.io"))
+		require.NoError(t, cache.remove("unknown"))
+
+		token, err := cache.get("openshift.io")
+		require.NoError(t, err)
+		assert.Nil(t, token)
+		token, err = cache.get("localhost:8089")
+		require.NoError(t, err)
+		require.NotNil(t, token)
+		assert.Equal(t, "local", token.AccessToken)
+	})
+
+	t.Run("corrupted", func(t *testing.T) {
+		require.NoError(t, ioutil.WriteFile(cache.path, []byte("{"), 0600))
+
+		_, err := cache.get("openshift.io")
+
+		assert.Error(t, err)
+	})
+}
+
+func TestNewTokenCache(t *testing.T) {
+	resource.Require(t, resource.UnitTest)
+
+	home, xdg := os.Getenv("HOME"), os.Getenv("XDG_CONFIG_HOME")
+	defer os.Setenv("HOME", home)
+	defer os.Setenv("XDG_CONFIG_HOME", xdg)
+
+	os.Setenv("HOME", "/home/john")
+	os.Setenv("XDG_CONFIG_HOME", "")
+	assert.Equal(t, "/home/john/.config/auth-cli/tokens.json", newTokenCache().path)
+	os.Setenv("XDG_CONFIG_HOME", "/tmp/config")
+	assert.Equal(t, "/tmp/config/auth-cli/tokens.json", newTokenCache().path)
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+// clientSecretEnv is the environment variable of the secret of the service account, which keeps it out of the shell history
+const clientSecretEnv = "AUTH_CLIENT_SECRET"
+
+func newExchangeCommand(api *apiClient, cache *tokenCache, p *printer) *cobra.Command {
+	var clientID, clientSecret string
+	var save bool
+	command := &cobra.Command{
+		Use:   "exchange",
+		Short: "Obtain the token of a service account with its ID and secret",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if clientID == "" {
+				return fmt.Errorf("the ID of the service account must be set with --client-id")
+			}
+			if clientSecret == "" {
+				clientSecret = os.Getenv(clientSecretEnv)
+			}
+			if clientSecret == "" {
+				return fmt.Errorf("the secret of the service account must be set with --client-secret or %s", clientSecretEnv)
+			}
+			form := url.Values{
+				"grant_type":    {"client_credentials"},
+				"client_id":     {clientID},
+				"client_secret": {clientSecret},
+			}
+			var raw json.RawMessage
+			if err := api.doForm(context.Background(), "POST", "/api/token", form, &raw); err != nil {
+				return err
+			}
+			var token cachedToken
+			if err := json.Unmarshal(raw, &token); err != nil {
+				return err
+			}
+			if save {
+				if err := cache.put(api.Host, token); err != nil {
+					return err
+				}
+			}
+			return p.print(raw, []string{"TOKEN TYPE", "ACCESS TOKEN"}, [][]string{{token.TokenType, token.AccessToken}})
+		},
+	}
+	command.Flags().StringVar(&clientID, "client-id", "", "ID of the service account")
+	command.Flags().StringVar(&clientSecret, "client-secret", "", "Secret of the service account. Read from "+clientSecretEnv+" if not set")
+	command.Flags().BoolVar(&save, "save", false, "Cache the token to use it as the key of the next commands, in place of the token of the user")
+	return command
+}
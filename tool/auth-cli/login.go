@@ -0,0 +1,233 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+const (
+	// apiClientName is the name of the API client sent with the login requests
+	apiClientName = "auth-cli"
+	// loginTimeout is the time the browser flow waits for the user to log in
+	loginTimeout = 5 * time.Minute
+	// stateParam is the query parameter of the redirect URL holding the state of the login
+	stateParam = "state"
+)
+
+// errInvalidState is returned when the URL the login redirected to doesn't have the state of the login
+var errInvalidState = fmt.Errorf("the redirect URL doesn't have the state of the login")
+
+func newLoginCommand(api *apiClient, cache *tokenCache) *cobra.Command {
+	var noBrowser bool
+	command := &cobra.Command{
+		Use:   "login",
+		Short: "Log in and cache the token of the user",
+		Long: `Log in and cache the token of the user, which is then used by the other commands when no key is given.
+
+By default the login page is opened in the browser, which is redirected to the command once the user is logged in.
+With --no-browser the login URL is printed to be opened in a browser, possibly on another machine, and the URL
+the browser is eventually redirected to has to be copied from its address bar and pasted back in the command.
+This is not an OAuth device flow, which the service doesn't support: the page the browser is redirected to
+doesn't need to load. In both cases the redirect URL holds a random state which the command checks, so
+it only accepts the redirection of the login it started.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var token *cachedToken
+			var err error
+			if noBrowser {
+				token, err = pasteLogin(api, os.Stdin, os.Stderr)
+			} else {
+				token, err = browserLogin(api)
+			}
+			if err != nil {
+				return err
+			}
+			if err := cache.put(api.Host, *token); err != nil {
+				return err
+			}
+			fmt.Fprintf(os.Stderr, "Logged in to %s\n", api.Host)
+			return nil
+		},
+	}
+	command.Flags().BoolVar(&noBrowser, "no-browser", false, "Print the login URL instead of opening the browser, and read the URL the browser is redirected to")
+	return command
+}
+
+func newLogoutCommand(api *apiClient, cache *tokenCache) *cobra.Command {
+	return &cobra.Command{
+		Use:   "logout",
+		Short: "Remove the cached token of the user",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return cache.remove(api.Host)
+		},
+	}
+}
+
+func newWhoamiCommand(api *apiClient, p *printer) *cobra.Command {
+	return &cobra.Command{
+		Use:   "whoami",
+		Short: "Show the current user",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var raw json.RawMessage
+			if err := api.do(context.Background(), "GET", "/api/user", nil, nil, &raw); err != nil {
+				return err
+			}
+			var user struct {
+				Data userData `json:"data"`
+			}
+			if err := json.Unmarshal(raw, &user); err != nil {
+				return err
+			}
+			return p.print(raw, userHeader, [][]string{user.Data.row()})
+		},
+	}
+}
+
+// loginURL returns the URL of the login page which redirects to the given URL once the user is logged in
+func loginURL(api *apiClient, redirect string) string {
+	return api.url("/api/login", url.Values{
+		"redirect":   {redirect},
+		"api_client": {apiClientName},
+	}).String()
+}
+
+// newState returns the random state added to the redirect URL of a login
+func newState() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// redirectURL returns the given URL with the given state of the login
+func redirectURL(base string, state string) string {
+	return base + "?" + url.Values{stateParam: {state}}.Encode()
+}
+
+// browserLogin opens the login page in the browser and waits for the redirection to a local server
+func browserLogin(api *apiClient) (*cachedToken, error) {
+	state, err := newState()
+	if err != nil {
+		return nil, err
+	}
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+	defer listener.Close()
+
+	tokens := make(chan *cachedToken, 1)
+	errors := make(chan error, 1)
+	server := &http.Server{Handler: callbackHandler(state, tokens, errors)}
+	go server.Serve(listener)
+	defer server.Close()
+
+	// the redirect URL uses the 'localhost' host name, which is the one allowed by the default valid redirect URLs of the service
+	location := loginURL(api, redirectURL(fmt.Sprintf("http://localhost:%d/", listener.Addr().(*net.TCPAddr).Port), state))
+	if err := openBrowser(location); err != nil {
+		fmt.Fprintf(os.Stderr, "Unable to open the browser, open the following URL to log in:\n\n%s\n\n", location)
+	}
+	select {
+	case token := <-tokens:
+		return token, nil
+	case err := <-errors:
+		return nil, err
+	case <-time.After(loginTimeout):
+		return nil, fmt.Errorf("timed out waiting for the login")
+	}
+}
+
+// callbackHandler returns the handler of the local server the browser is redirected to once the user is logged in.
+// The token or the error of the login is sent to the given channels. The requests without the given state of the
+// login, e.g. sent by another page open in the browser, are rejected and the login keeps waiting.
+func callbackHandler(state string, tokens chan<- *cachedToken, errors chan<- error) http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		token, err := tokenFromRedirect(req.URL, state)
+		if err == errInvalidState {
+			http.Error(rw, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err != nil {
+			http.Error(rw, err.Error(), http.StatusUnauthorized)
+			select {
+			case errors <- err:
+			default:
+			}
+			return
+		}
+		fmt.Fprintln(rw, "You are logged in, you can close this window.")
+		select {
+		case tokens <- token:
+		default:
+		}
+	})
+}
+
+// pasteLogin prints the login URL to the given output and reads the URL the browser is redirected to once
+// the user is logged in, as pasted by the user in the given input
+func pasteLogin(api *apiClient, in io.Reader, out io.Writer) (*cachedToken, error) {
+	state, err := newState()
+	if err != nil {
+		return nil, err
+	}
+	fmt.Fprintf(out, "Open the following URL in a browser to log in:\n\n%s\n\n", loginURL(api, redirectURL("http://localhost/auth-cli", state)))
+	fmt.Fprint(out, "Then paste the URL of the page the browser is redirected to: ")
+	line, err := bufio.NewReader(in).ReadString('\n')
+	if err != nil && (err != io.EOF || line == "") {
+		return nil, err
+	}
+	redirect, err := url.Parse(strings.TrimSpace(line))
+	if err != nil {
+		return nil, err
+	}
+	return tokenFromRedirect(redirect, state)
+}
+
+// tokenFromRedirect returns the token the login page added to the URL it redirected to, which must have the given
+// state of the login
+func tokenFromRedirect(redirect *url.URL, state string) (*cachedToken, error) {
+	query := redirect.Query()
+	if subtle.ConstantTimeCompare([]byte(query.Get(stateParam)), []byte(state)) != 1 {
+		return nil, errInvalidState
+	}
+	if e := query.Get("error"); e != "" {
+		return nil, fmt.Errorf("login failed: %s", e)
+	}
+	tokenJSON := query.Get("api_token")
+	if tokenJSON == "" {
+		return nil, fmt.Errorf("no token found in '%s'", redirect)
+	}
+	var token cachedToken
+	if err := json.Unmarshal([]byte(tokenJSON), &token); err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+// openBrowser opens the given URL in the default browser of the user
+func openBrowser(location string) error {
+	switch runtime.GOOS {
+	case "darwin":
+		return exec.Command("open", location).Start()
+	case "windows":
+		return exec.Command("rundll32", "url.dll,FileProtocolHandler", location).Start()
+	default:
+		return exec.Command("xdg-open", location).Start()
+	}
+}
@@ -0,0 +1,198 @@
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/fabric8-services/fabric8-auth/client"
+	"github.com/fabric8-services/fabric8-auth/resource"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testTokenJSON = `{"access_token":"access","refresh_token":"refresh","token_type":"bearer"}`
+
+func redirectWith(t *testing.T, base string, query url.Values) *url.URL {
+	redirect, err := url.Parse(base + "?" + query.Encode())
+	require.NoError(t, err)
+	return redirect
+}
+
+func TestTokenFromRedirect(t *testing.T) {
+	resource.Require(t, resource.UnitTest)
+	t.Parallel()
+
+	t.Run("ok", func(t *testing.T) {
+		token, err := tokenFromRedirect(redirectWith(t, "http://localhost:8080/", url.Values{"state": {"s1"}, "api_token": {testTokenJSON}}), "s1")
+		require.NoError(t, err)
+		assert.Equal(t, cachedToken{AccessToken: "access", RefreshToken: "refresh", TokenType: "bearer"}, *token)
+	})
+
+	t.Run("login failed", func(t *testing.T) {
+		_, err := tokenFromRedirect(redirectWith(t, "http://localhost:8080/", url.Values{"state": {"s1"}, "error": {"access_denied"}}), "s1")
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "access_denied")
+	})
+
+	t.Run("no token", func(t *testing.T) {
+		_, err := tokenFromRedirect(redirectWith(t, "http://localhost:8080/", url.Values{"state": {"s1"}}), "s1")
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "no token found")
+	})
+
+	t.Run("invalid token", func(t *testing.T) {
+		_, err := tokenFromRedirect(redirectWith(t, "http://localhost:8080/", url.Values{"state": {"s1"}, "api_token": {"{"}}), "s1")
+		require.Error(t, err)
+	})
+
+	t.Run("other state", func(t *testing.T) {
+		_, err := tokenFromRedirect(redirectWith(t, "http://localhost:8080/", url.Values{"state": {"s2"}, "api_token": {testTokenJSON}}), "s1")
+		assert.Equal(t, errInvalidState, err)
+	})
+
+	t.Run("no state", func(t *testing.T) {
+		_, err := tokenFromRedirect(redirectWith(t, "http://localhost:8080/", url.Values{"api_token": {testTokenJSON}}), "s1")
+		assert.Equal(t, errInvalidState, err)
+	})
+}
+
+func TestNewState(t *testing.T) {
+	resource.Require(t, resource.UnitTest)
+	t.Parallel()
+
+	state, err := newState()
+	require.NoError(t, err)
+	other, err := newState()
+	require.NoError(t, err)
+	assert.Len(t, state, 32)
+	assert.NotEqual(t, state, other)
+	assert.Equal(t, "http://localhost:8080/?state="+state, redirectURL("http://localhost:8080/", state))
+}
+
+func TestCallbackHandler(t *testing.T) {
+	resource.Require(t, resource.UnitTest)
+	t.Parallel()
+
+	t.Run("ok", func(t *testing.T) {
+		tokens := make(chan *cachedToken, 1)
+		errors := make(chan error, 1)
+		rw := httptest.NewRecorder()
+		req := httptest.NewRequest("GET", redirectWith(t, "http://localhost:8080/", url.Values{"state": {"s1"}, "api_token": {testTokenJSON}}).String(), nil)
+
+		callbackHandler("s1", tokens, errors).ServeHTTP(rw, req)
+
+		assert.Equal(t, http.StatusOK, rw.Code)
+		require.Len(t, tokens, 1)
+		assert.Equal(t, "access", (<-tokens).AccessToken)
+		assert.Empty(t, errors)
+	})
+
+	t.Run("login failed", func(t *testing.T) {
+		tokens := make(chan *cachedToken, 1)
+		errors := make(chan error, 1)
+		rw := httptest.NewRecorder()
+		req := httptest.NewRequest("GET", redirectWith(t, "http://localhost:8080/", url.Values{"state": {"s1"}, "error": {"access_denied"}}).String(), nil)
+
+		callbackHandler("s1", tokens, errors).ServeHTTP(rw, req)
+
+		assert.Equal(t, http.StatusUnauthorized, rw.Code)
+		assert.Empty(t, tokens)
+		assert.Len(t, errors, 1)
+	})
+
+	t.Run("other state ignored", func(t *testing.T) {
+		tokens := make(chan *cachedToken, 1)
+		errors := make(chan error, 1)
+		rw := httptest.NewRecorder()
+		req := httptest.NewRequest("GET", redirectWith(t, "http://localhost:8080/", url.Values{"state": {"s2"}, "api_token": {testTokenJSON}}).String(), nil)
+
+		callbackHandler("s1", tokens, errors).ServeHTTP(rw, req)
+
+		// the login keeps waiting for the redirection with its state
+		assert.Equal(t, http.StatusBadRequest, rw.Code)
+		assert.Empty(t, tokens)
+		assert.Empty(t, errors)
+	})
+}
+
+func TestPasteLogin(t *testing.T) {
+	resource.Require(t, resource.UnitTest)
+	t.Parallel()
+
+	newAPI := func() *apiClient {
+		api := &apiClient{Client: client.New(nil)}
+		api.Scheme = "https"
+		api.Host = "auth.openshift.io"
+		return api
+	}
+	// pasteRedirect returns the input of the user pasting the redirect URL of the login printed in the given output
+	pasteRedirect := func(t *testing.T, out *bytes.Buffer, query url.Values) string {
+		lines := strings.Split(out.String(), "\n")
+		require.True(t, len(lines) > 2)
+		login, err := url.Parse(lines[2])
+		require.NoError(t, err)
+		assert.Equal(t, "auth.openshift.io", login.Host)
+		assert.Equal(t, "/api/login", login.Path)
+		assert.Equal(t, apiClientName, login.Query().Get("api_client"))
+		redirect, err := url.Parse(login.Query().Get("redirect"))
+		require.NoError(t, err)
+		assert.Equal(t, "localhost", redirect.Host)
+		state := redirect.Query().Get("state")
+		require.NotEmpty(t, state)
+		if query.Get("state") == "" {
+			query.Set("state", state)
+		}
+		return redirectWith(t, "http://localhost/auth-cli", query).String() + "\n"
+	}
+
+	t.Run("ok", func(t *testing.T) {
+		var out bytes.Buffer
+		in := &lazyReader{}
+		in.read = func() string {
+			return pasteRedirect(t, &out, url.Values{"api_token": {testTokenJSON}})
+		}
+
+		token, err := pasteLogin(newAPI(), in, &out)
+
+		require.NoError(t, err)
+		assert.Equal(t, "access", token.AccessToken)
+	})
+
+	t.Run("other state", func(t *testing.T) {
+		var out bytes.Buffer
+		in := &lazyReader{}
+		in.read = func() string {
+			return pasteRedirect(t, &out, url.Values{"state": {"other"}, "api_token": {testTokenJSON}})
+		}
+
+		_, err := pasteLogin(newAPI(), in, &out)
+
+		assert.Equal(t, errInvalidState, err)
+	})
+
+	t.Run("nothing pasted", func(t *testing.T) {
+		var out bytes.Buffer
+
+		_, err := pasteLogin(newAPI(), strings.NewReader(""), &out)
+
+		assert.Error(t, err)
+	})
+}
+
+// lazyReader returns the input computed when it's first read, once the login URL is printed
+type lazyReader struct {
+	read  func() string
+	input *strings.Reader
+}
+
+func (r *lazyReader) Read(p []byte) (int, error) {
+	if r.input == nil {
+		r.input = strings.NewReader(r.read())
+	}
+	return r.input.Read(p)
+}
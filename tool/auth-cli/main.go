@@ -33,8 +33,22 @@ func main() {
 	app.PersistentFlags().StringVar(&key, "key", "", "API key used for authentication")
 	app.PersistentFlags().StringVar(&format, "format", "Bearer %s", "Format used to create auth header or query from key")
 
+	// Register output flags
+	p := &printer{out: os.Stdout}
+	app.PersistentFlags().StringVarP(&p.format, "output", "o", outputTable, "Output format of the user commands: table or json")
+	app.PersistentPreRunE = func(cmd *cobra.Command, args []string) error {
+		return p.validate()
+	}
+
 	// Parse flags and setup signers
 	app.ParseFlags(os.Args)
+	cache := newTokenCache()
+	if key == "" {
+		// use the token cached by the login or exchange commands when no key is given
+		if token, err := cache.get(c.Host); err == nil && token != nil {
+			key = token.AccessToken
+		}
+	}
 	jwtSigner := newJWTSigner(key, format)
 
 	// Initialize API client
@@ -44,6 +58,18 @@ func main() {
 	// Register API commands
 	cli.RegisterCommands(app, c)
 
+	// Register user commands, which replace the API commands of the same name
+	api := &apiClient{Client: c}
+	registerUserCommands(app,
+		newLoginCommand(api, cache),
+		newLogoutCommand(api, cache),
+		newWhoamiCommand(api, p),
+		newTokenCommand(api, p),
+		newSpacesCommand(api, p),
+		newUsersCommand(api, p),
+		newExchangeCommand(api, cache, p),
+	)
+
 	// Execute!
 	if err := app.Execute(); err != nil {
 		fmt.Fprintf(os.Stderr, err.Error())
@@ -69,3 +95,23 @@ func newJWTSigner(key, format string) goaclient.Signer {
 	}
 
 }
+
+// registerUserCommands adds the given commands to the application, replacing the API commands of the same name
+func registerUserCommands(app *cobra.Command, commands ...*cobra.Command) {
+	for _, command := range commands {
+		for _, existing := range app.Commands() {
+			if existing.Name() == command.Name() {
+				app.RemoveCommand(existing)
+			}
+		}
+		app.AddCommand(command)
+	}
+}
+
+// checkArgs returns an error if the command is given less than the expected number of arguments
+func checkArgs(cmd *cobra.Command, args []string, expected int) error {
+	if len(args) < expected {
+		return fmt.Errorf("missing arguments, usage: %s", cmd.UseLine())
+	}
+	return nil
+}
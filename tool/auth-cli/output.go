@@ -0,0 +1,45 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+)
+
+const (
+	// outputTable prints the results as a table with a header
+	outputTable = "table"
+	// outputJSON prints the results as indented JSON
+	outputJSON = "json"
+)
+
+// printer prints the results of the user-oriented commands in the selected output format
+type printer struct {
+	out    io.Writer
+	format string
+}
+
+func (p *printer) validate() error {
+	switch p.format {
+	case outputTable, outputJSON:
+		return nil
+	}
+	return fmt.Errorf("invalid output format '%s' (expected '%s' or '%s')", p.format, outputTable, outputJSON)
+}
+
+// print prints the given value as JSON, or the given rows as a table with the given header
+func (p *printer) print(value interface{}, header []string, rows [][]string) error {
+	if p.format == outputJSON {
+		encoder := json.NewEncoder(p.out)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(value)
+	}
+	w := tabwriter.NewWriter(p.out, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, strings.Join(header, "\t"))
+	for _, row := range rows {
+		fmt.Fprintln(w, strings.Join(row, "\t"))
+	}
+	return w.Flush()
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/fabric8-services/fabric8-auth/resource"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPrinterValidate(t *testing.T) {
+	resource.Require(t, resource.UnitTest)
+	t.Parallel()
+
+	assert.NoError(t, (&printer{format: outputTable}).validate())
+	assert.NoError(t, (&printer{format: outputJSON}).validate())
+	assert.Error(t, (&printer{format: "yaml"}).validate())
+}
+
+func TestPrinterPrint(t *testing.T) {
+	resource.Require(t, resource.UnitTest)
+	t.Parallel()
+
+	value := map[string]string{"username": "john"}
+	header := []string{"USERNAME", "EMAIL"}
+	rows := [][]string{{"john", "john@example.com"}, {"jo", "jo@example.com"}}
+
+	t.Run("table", func(t *testing.T) {
+		var out bytes.Buffer
+		p := &printer{out: &out, format: outputTable}
+
+		require.NoError(t, p.print(value, header, rows))
+
+		assert.Equal(t, "USERNAME  EMAIL\njohn      john@example.com\njo        jo@example.com\n", out.String())
+	})
+
+	t.Run("table without rows", func(t *testing.T) {
+		var out bytes.Buffer
+		p := &printer{out: &out, format: outputTable}
+
+		require.NoError(t, p.print(value, header, nil))
+
+		assert.Equal(t, "USERNAME  EMAIL\n", out.String())
+	})
+
+	t.Run("json", func(t *testing.T) {
+		var out bytes.Buffer
+		p := &printer{out: &out, format: outputJSON}
+
+		require.NoError(t, p.print(value, header, rows))
+
+		assert.Equal(t, "{\n  \"username\": \"john\"\n}\n", out.String())
+	})
+}
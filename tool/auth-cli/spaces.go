@@ -0,0 +1,114 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+
+	"github.com/spf13/cobra"
+)
+
+// identityID is the JSON-API reference of a user identity in the updates of the space collaborators
+type identityID struct {
+	ID   string `json:"id"`
+	Type string `json:"type"`
+}
+
+// collaboratorsUpdate is the result of an update of the space collaborators
+type collaboratorsUpdate struct {
+	Updated   []identityID `json:"updated"`
+	Unchanged []identityID `json:"unchanged"`
+}
+
+func (u collaboratorsUpdate) rows() [][]string {
+	rows := make([][]string, 0, len(u.Updated)+len(u.Unchanged))
+	for _, id := range u.Updated {
+		rows = append(rows, []string{id.ID, "updated"})
+	}
+	for _, id := range u.Unchanged {
+		rows = append(rows, []string{id.ID, "unchanged"})
+	}
+	return rows
+}
+
+func collaboratorsPath(spaceID string) string {
+	return fmt.Sprintf("/api/spaces/%s/collaborators", url.PathEscape(spaceID))
+}
+
+func newSpacesCommand(api *apiClient, p *printer) *cobra.Command {
+	command := &cobra.Command{
+		Use:   "spaces",
+		Short: "Manage the spaces",
+	}
+	collaborators := &cobra.Command{
+		Use:   "collaborators",
+		Short: "Manage the collaborators of a space",
+	}
+	collaborators.AddCommand(
+		newListCollaboratorsCommand(api, p),
+		newUpdateCollaboratorsCommand(api, p, "add", "Add users to the collaborators of a space", "POST"),
+		newUpdateCollaboratorsCommand(api, p, "remove", "Remove users from the collaborators of a space", "DELETE"),
+	)
+	command.AddCommand(collaborators)
+	return command
+}
+
+func newListCollaboratorsCommand(api *apiClient, p *printer) *cobra.Command {
+	var offset, limit int
+	command := &cobra.Command{
+		Use:   "list SPACE_ID",
+		Short: "List the collaborators of a space",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := checkArgs(cmd, args, 1); err != nil {
+				return err
+			}
+			var raw json.RawMessage
+			if err := api.do(context.Background(), "GET", collaboratorsPath(args[0]), pageQuery(offset, limit), nil, &raw); err != nil {
+				return err
+			}
+			return printUserList(p, raw)
+		},
+	}
+	command.Flags().IntVar(&offset, "offset", 0, "Paging start position")
+	command.Flags().IntVar(&limit, "limit", 20, "Paging size")
+	return command
+}
+
+// newUpdateCollaboratorsCommand returns the command which adds or removes the collaborators of a space,
+// depending on the given HTTP method
+func newUpdateCollaboratorsCommand(api *apiClient, p *printer, use, short, method string) *cobra.Command {
+	var expiresAt string
+	command := &cobra.Command{
+		Use:   use + " SPACE_ID IDENTITY_ID...",
+		Short: short,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := checkArgs(cmd, args, 2); err != nil {
+				return err
+			}
+			var payload struct {
+				Data []identityID `json:"data"`
+			}
+			for _, id := range args[1:] {
+				payload.Data = append(payload.Data, identityID{ID: id, Type: "identities"})
+			}
+			var query url.Values
+			if expiresAt != "" {
+				query = url.Values{"expires_at": {expiresAt}}
+			}
+			var raw json.RawMessage
+			if err := api.do(context.Background(), method, collaboratorsPath(args[0]), query, payload, &raw); err != nil {
+				return err
+			}
+			var update collaboratorsUpdate
+			if err := json.Unmarshal(raw, &update); err != nil {
+				return err
+			}
+			return p.print(raw, []string{"IDENTITY ID", "RESULT"}, update.rows())
+		},
+	}
+	if method == "POST" {
+		command.Flags().StringVar(&expiresAt, "expires-at", "", "The date the access of the collaborators expires at, in RFC 3339 format. The access is given without time limit if not set")
+	}
+	return command
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"strconv"
+
+	"github.com/spf13/cobra"
+)
+
+// externalToken is the token of the user for an external provider such as GitHub or OpenShift
+type externalToken struct {
+	AccessToken string `json:"access_token"`
+	Scope       string `json:"scope"`
+	TokenType   string `json:"token_type"`
+	Username    string `json:"username"`
+}
+
+func newTokenCommand(api *apiClient, p *printer) *cobra.Command {
+	command := &cobra.Command{
+		Use:   "token",
+		Short: "Manage the tokens of the user for the external providers such as GitHub or OpenShift",
+	}
+	var resource string
+	command.PersistentFlags().StringVar(&resource, "for", "", "The resource of the external provider, e.g. https://github.com or https://api.starter-us-east-2.openshift.com")
+
+	var redirect string
+	link := &cobra.Command{
+		Use:   "link",
+		Short: "Link the account of the user to the external provider in the browser",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if resource == "" {
+				return fmt.Errorf("the resource of the external provider must be set with --for")
+			}
+			if redirect == "" {
+				redirect = api.url("/", nil).String()
+			}
+			var location struct {
+				RedirectLocation string `json:"redirect_location"`
+			}
+			query := url.Values{"for": {resource}, "redirect": {redirect}}
+			if err := api.do(context.Background(), "GET", "/api/token/link", query, nil, &location); err != nil {
+				return err
+			}
+			if err := openBrowser(location.RedirectLocation); err != nil {
+				fmt.Fprintf(os.Stderr, "Unable to open the browser, open the following URL to link the account:\n\n%s\n\n", location.RedirectLocation)
+			}
+			return nil
+		},
+	}
+	link.Flags().StringVar(&redirect, "redirect", "", "URL the browser is redirected to once the account is linked. The home page of the service if not set")
+
+	var forcePull bool
+	retrieve := &cobra.Command{
+		Use:   "retrieve",
+		Short: "Show the token of the user for the external provider",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if resource == "" {
+				return fmt.Errorf("the resource of the external provider must be set with --for")
+			}
+			query := url.Values{"for": {resource}}
+			if forcePull {
+				query.Set("force_pull", strconv.FormatBool(forcePull))
+			}
+			var raw json.RawMessage
+			if err := api.do(context.Background(), "GET", "/api/token", query, nil, &raw); err != nil {
+				return err
+			}
+			var token externalToken
+			if err := json.Unmarshal(raw, &token); err != nil {
+				return err
+			}
+			return p.print(raw, []string{"USERNAME", "TOKEN TYPE", "SCOPE", "ACCESS TOKEN"},
+				[][]string{{token.Username, token.TokenType, token.Scope, token.AccessToken}})
+		},
+	}
+	retrieve.Flags().BoolVar(&forcePull, "force-pull", false, "Pull the details of the user from the external provider")
+
+	unlink := &cobra.Command{
+		Use:   "unlink",
+		Short: "Unlink the account of the user from the external provider and delete its token",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if resource == "" {
+				return fmt.Errorf("the resource of the external provider must be set with --for")
+			}
+			return api.do(context.Background(), "DELETE", "/api/token", url.Values{"for": {resource}}, nil, nil)
+		},
+	}
+
+	command.AddCommand(link, retrieve, unlink)
+	return command
+}
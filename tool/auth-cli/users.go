@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/url"
+	"strconv"
+
+	"github.com/spf13/cobra"
+)
+
+// userData is the subset of the attributes of the users printed in the tables
+type userData struct {
+	ID         string `json:"id"`
+	Attributes struct {
+		IdentityID string `json:"identityID"`
+		Username   string `json:"username"`
+		FullName   string `json:"fullName"`
+		Email      string `json:"email"`
+		Cluster    string `json:"cluster"`
+	} `json:"attributes"`
+}
+
+var userHeader = []string{"ID", "IDENTITY ID", "USERNAME", "FULL NAME", "EMAIL", "CLUSTER"}
+
+func (u userData) row() []string {
+	return []string{u.ID, u.Attributes.IdentityID, u.Attributes.Username, u.Attributes.FullName, u.Attributes.Email, u.Attributes.Cluster}
+}
+
+// userList is a page of users
+type userList struct {
+	Data []userData `json:"data"`
+}
+
+func (l userList) rows() [][]string {
+	rows := make([][]string, 0, len(l.Data))
+	for _, u := range l.Data {
+		rows = append(rows, u.row())
+	}
+	return rows
+}
+
+// printUserList decodes the given page of users and prints it
+func printUserList(p *printer, raw json.RawMessage) error {
+	var users userList
+	if err := json.Unmarshal(raw, &users); err != nil {
+		return err
+	}
+	return p.print(raw, userHeader, users.rows())
+}
+
+// pageQuery returns the query of the requests of a page of the given size, starting at the given offset
+func pageQuery(offset, limit int) url.Values {
+	return url.Values{
+		"page[offset]": {strconv.Itoa(offset)},
+		"page[limit]":  {strconv.Itoa(limit)},
+	}
+}
+
+func newUsersCommand(api *apiClient, p *printer) *cobra.Command {
+	command := &cobra.Command{
+		Use:   "users",
+		Short: "Manage the users",
+	}
+	var offset, limit int
+	search := &cobra.Command{
+		Use:   "search QUERY",
+		Short: "Search the users matching the query",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := checkArgs(cmd, args, 1); err != nil {
+				return err
+			}
+			query := pageQuery(offset, limit)
+			query.Set("q", args[0])
+			var raw json.RawMessage
+			if err := api.do(context.Background(), "GET", "/api/search/users", query, nil, &raw); err != nil {
+				return err
+			}
+			return printUserList(p, raw)
+		},
+	}
+	search.Flags().IntVar(&offset, "offset", 0, "Paging start position")
+	search.Flags().IntVar(&limit, "limit", 20, "Paging size")
+	command.AddCommand(search)
+	return command
+}
@@ -0,0 +1,6 @@
+// Package tracing provides the distributed tracing of the incoming requests,
+// the repository calls and the outbound calls to the other services.
+// The W3C trace context is propagated and the traces are written to a file as OTLP/JSON. The OpenTelemetry SDK
+// is not used since it requires a more recent Go version than the one the service is built with, so the export
+// over the network is left to an OpenTelemetry collector reading the file.
+package tracing
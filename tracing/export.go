@@ -0,0 +1,250 @@
+package tracing
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"sync"
+
+	"github.com/fabric8-services/fabric8-auth/log"
+
+	"github.com/pkg/errors"
+)
+
+// Exporter exports the ended spans, e.g. to a file
+type Exporter interface {
+	ExportSpans(ctx context.Context, spans []*Span) error
+	Shutdown(ctx context.Context) error
+}
+
+// provider samples the new traces and exports the ended spans
+type provider struct {
+	exporter    Exporter
+	sampleBound uint64
+}
+
+var (
+	current    *provider
+	currentMux sync.RWMutex
+)
+
+func currentProvider() *provider {
+	currentMux.RLock()
+	defer currentMux.RUnlock()
+	return current
+}
+
+// Register records the spans with the given exporter and samples the given ratio of the new traces.
+// The spans are exported as soon as they end, so there is nothing to flush on shutdown.
+// The returned function stops recording the spans and shuts the exporter down.
+func Register(exporter Exporter, sampleRatio float64) func(context.Context) error {
+	p := &provider{
+		exporter:    exporter,
+		sampleBound: traceIDBound(sampleRatio),
+	}
+	currentMux.Lock()
+	current = p
+	currentMux.Unlock()
+	return func(ctx context.Context) error {
+		currentMux.Lock()
+		if current == p {
+			current = nil
+		}
+		currentMux.Unlock()
+		return p.exporter.Shutdown(ctx)
+	}
+}
+
+func (p *provider) sample(id TraceID) bool {
+	return sampledTraceID(id, p.sampleBound)
+}
+
+func (p *provider) export(span *Span) {
+	if err := p.exporter.ExportSpans(context.Background(), []*Span{span}); err != nil {
+		log.Error(nil, map[string]interface{}{
+			"err":  err,
+			"span": span.Name,
+		}, "failed to export the span")
+	}
+}
+
+// NewFileExporter returns an exporter which appends the spans to the given file, one OTLP/JSON request per line,
+// which is the format read by the "otlpjsonfile" receiver of the OpenTelemetry collector.
+// The file is closed when the exporter is shut down.
+func NewFileExporter(path string) (Exporter, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to open the trace file %s", path)
+	}
+	return &fileExporter{file: f, encoder: json.NewEncoder(f)}, nil
+}
+
+type fileExporter struct {
+	mux     sync.Mutex
+	file    *os.File
+	encoder *json.Encoder
+}
+
+func (e *fileExporter) ExportSpans(ctx context.Context, spans []*Span) error {
+	e.mux.Lock()
+	defer e.mux.Unlock()
+	return errors.Wrapf(e.encoder.Encode(toOTLP(spans)), "failed to write the spans to %s", e.file.Name())
+}
+
+func (e *fileExporter) Shutdown(ctx context.Context) error {
+	e.mux.Lock()
+	defer e.mux.Unlock()
+	return errors.Wrapf(e.file.Close(), "failed to close the trace file %s", e.file.Name())
+}
+
+// InMemoryExporter keeps the exported spans in memory. To be used in tests.
+type InMemoryExporter struct {
+	mux   sync.Mutex
+	spans []*Span
+}
+
+// ExportSpans keeps the given spans
+func (e *InMemoryExporter) ExportSpans(ctx context.Context, spans []*Span) error {
+	e.mux.Lock()
+	defer e.mux.Unlock()
+	e.spans = append(e.spans, spans...)
+	return nil
+}
+
+// Shutdown does nothing
+func (e *InMemoryExporter) Shutdown(ctx context.Context) error {
+	return nil
+}
+
+// Spans returns the exported spans, in the order they ended
+func (e *InMemoryExporter) Spans() []*Span {
+	e.mux.Lock()
+	defer e.mux.Unlock()
+	return append([]*Span{}, e.spans...)
+}
+
+// OTLP/JSON representation of the spans, see https://github.com/open-telemetry/opentelemetry-proto
+// The trace and span IDs are hex encoded and the 64-bit integers are encoded as strings.
+
+type otlpRequest struct {
+	ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+}
+
+type otlpResourceSpans struct {
+	Resource   otlpResource     `json:"resource"`
+	ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+}
+
+type otlpResource struct {
+	Attributes []otlpAttribute `json:"attributes"`
+}
+
+type otlpScopeSpans struct {
+	Scope otlpScope  `json:"scope"`
+	Spans []otlpSpan `json:"spans"`
+}
+
+type otlpScope struct {
+	Name string `json:"name"`
+}
+
+type otlpSpan struct {
+	TraceID           string          `json:"traceId"`
+	SpanID            string          `json:"spanId"`
+	ParentSpanID      string          `json:"parentSpanId,omitempty"`
+	Name              string          `json:"name"`
+	Kind              SpanKind        `json:"kind"`
+	StartTimeUnixNano string          `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string          `json:"endTimeUnixNano"`
+	Attributes        []otlpAttribute `json:"attributes,omitempty"`
+	Status            *otlpStatus     `json:"status,omitempty"`
+}
+
+type otlpStatus struct {
+	Code    int    `json:"code"`
+	Message string `json:"message,omitempty"`
+}
+
+// otlpStatusCodeError is the status code of the failed spans
+const otlpStatusCodeError = 2
+
+type otlpAttribute struct {
+	Key   string    `json:"key"`
+	Value otlpValue `json:"value"`
+}
+
+type otlpValue struct {
+	StringValue *string  `json:"stringValue,omitempty"`
+	IntValue    *string  `json:"intValue,omitempty"`
+	BoolValue   *bool    `json:"boolValue,omitempty"`
+	DoubleValue *float64 `json:"doubleValue,omitempty"`
+}
+
+func toOTLP(spans []*Span) otlpRequest {
+	result := make([]otlpSpan, 0, len(spans))
+	for _, span := range spans {
+		span.mux.Lock()
+		s := otlpSpan{
+			TraceID:           span.SpanContext.TraceID.String(),
+			SpanID:            span.SpanContext.SpanID.String(),
+			Name:              span.Name,
+			Kind:              span.Kind,
+			StartTimeUnixNano: strconv.FormatInt(span.StartTime.UnixNano(), 10),
+			EndTimeUnixNano:   strconv.FormatInt(span.EndTime.UnixNano(), 10),
+			Attributes:        toOTLPAttributes(span.Attributes),
+		}
+		if span.Parent.IsValid() {
+			s.ParentSpanID = span.Parent.SpanID.String()
+		}
+		if span.Error != "" {
+			s.Status = &otlpStatus{Code: otlpStatusCodeError, Message: span.Error}
+		}
+		span.mux.Unlock()
+		result = append(result, s)
+	}
+	return otlpRequest{
+		ResourceSpans: []otlpResourceSpans{{
+			Resource: otlpResource{
+				Attributes: toOTLPAttributes(map[string]interface{}{"service.name": ServiceName}),
+			},
+			ScopeSpans: []otlpScopeSpans{{
+				Scope: otlpScope{Name: instrumentationName},
+				Spans: result,
+			}},
+		}},
+	}
+}
+
+func toOTLPAttributes(attributes map[string]interface{}) []otlpAttribute {
+	keys := make([]string, 0, len(attributes))
+	for key := range attributes {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	result := make([]otlpAttribute, 0, len(keys))
+	for _, key := range keys {
+		var value otlpValue
+		switch v := attributes[key].(type) {
+		case string:
+			value.StringValue = &v
+		case bool:
+			value.BoolValue = &v
+		case int:
+			i := strconv.Itoa(v)
+			value.IntValue = &i
+		case int64:
+			i := strconv.FormatInt(v, 10)
+			value.IntValue = &i
+		case float64:
+			value.DoubleValue = &v
+		default:
+			s := fmt.Sprint(v)
+			value.StringValue = &s
+		}
+		result = append(result, otlpAttribute{Key: key, Value: value})
+	}
+	return result
+}
@@ -0,0 +1,236 @@
+package tracing
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SpanKind is the role of a span in a trace, with the values of the OTLP specification
+type SpanKind int
+
+const (
+	// SpanKindInternal is an operation within the service
+	SpanKindInternal SpanKind = 1
+	// SpanKindServer is the handling of an incoming request
+	SpanKindServer SpanKind = 2
+	// SpanKindClient is an outbound call, e.g. to the database or another service
+	SpanKindClient SpanKind = 3
+)
+
+// TraceID identifies a trace
+type TraceID [16]byte
+
+// String returns the hex representation of the trace ID
+func (id TraceID) String() string {
+	return hex.EncodeToString(id[:])
+}
+
+// SpanID identifies a span in a trace
+type SpanID [8]byte
+
+// String returns the hex representation of the span ID
+func (id SpanID) String() string {
+	return hex.EncodeToString(id[:])
+}
+
+// SpanContext is the part of a span which is propagated to the other services
+type SpanContext struct {
+	TraceID TraceID
+	SpanID  SpanID
+	Sampled bool
+}
+
+// IsValid returns true if both the trace ID and the span ID are set
+func (sc SpanContext) IsValid() bool {
+	return sc.TraceID != TraceID{} && sc.SpanID != SpanID{}
+}
+
+// Traceparent returns the value of the W3C traceparent header of the span context
+func (sc SpanContext) Traceparent() string {
+	flags := "00"
+	if sc.Sampled {
+		flags = "01"
+	}
+	return fmt.Sprintf("00-%s-%s-%s", sc.TraceID, sc.SpanID, flags)
+}
+
+// ParseTraceparent returns the span context of the given W3C traceparent header value
+func ParseTraceparent(value string) (SpanContext, bool) {
+	var sc SpanContext
+	parts := strings.Split(strings.TrimSpace(value), "-")
+	if len(parts) < 4 || len(parts[0]) != 2 || parts[0] == "ff" || (parts[0] == "00" && len(parts) != 4) {
+		return sc, false
+	}
+	if len(parts[1]) != 32 || len(parts[2]) != 16 || len(parts[3]) != 2 {
+		return sc, false
+	}
+	if _, err := hex.Decode(sc.TraceID[:], []byte(parts[1])); err != nil {
+		return sc, false
+	}
+	if _, err := hex.Decode(sc.SpanID[:], []byte(parts[2])); err != nil {
+		return sc, false
+	}
+	flags, err := hex.DecodeString(parts[3])
+	if err != nil {
+		return sc, false
+	}
+	sc.Sampled = flags[0]&0x01 == 0x01
+	return sc, sc.IsValid()
+}
+
+// Span is a timed operation of a trace
+type Span struct {
+	Name        string
+	Kind        SpanKind
+	SpanContext SpanContext
+	// Parent is the span context of the parent span, which is not valid for the root span of a trace
+	Parent     SpanContext
+	StartTime  time.Time
+	EndTime    time.Time
+	Attributes map[string]interface{}
+	// Error is the description of the error the operation failed with, if any
+	Error string
+
+	mux      sync.Mutex
+	ended    bool
+	provider *provider
+}
+
+// SetAttribute sets an attribute of the span
+func (s *Span) SetAttribute(key string, value interface{}) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	s.Attributes[key] = value
+}
+
+// SetError marks the span as failed with the given description
+func (s *Span) SetError(description string) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	s.Error = description
+}
+
+// End ends the span now. The span is exported if it's sampled.
+func (s *Span) End() {
+	s.EndAt(time.Now())
+}
+
+// EndAt ends the span at the given time. Calling it more than once has no effect.
+func (s *Span) EndAt(end time.Time) {
+	s.mux.Lock()
+	if s.ended {
+		s.mux.Unlock()
+		return
+	}
+	s.ended = true
+	s.EndTime = end
+	s.mux.Unlock()
+	if s.provider != nil && s.SpanContext.Sampled {
+		s.provider.export(s)
+	}
+}
+
+type spanKey struct{}
+
+type remoteSpanContextKey struct{}
+
+// ContextWithSpan returns a copy of the given context which holds the given span
+func ContextWithSpan(ctx context.Context, span *Span) context.Context {
+	return context.WithValue(ctx, spanKey{}, span)
+}
+
+// ContextWithRemoteSpanContext returns a copy of the given context which holds the span context of a caller,
+// to be used as the parent of the next span
+func ContextWithRemoteSpanContext(ctx context.Context, sc SpanContext) context.Context {
+	return context.WithValue(ctx, remoteSpanContextKey{}, sc)
+}
+
+// SpanFromContext returns the span held by the given context, or nil if there is none
+func SpanFromContext(ctx context.Context) *Span {
+	if ctx == nil {
+		return nil
+	}
+	span, _ := ctx.Value(spanKey{}).(*Span)
+	return span
+}
+
+// spanContextFromContext returns the span context of the span held by the given context,
+// or the span context of the caller if there is no span yet
+func spanContextFromContext(ctx context.Context) SpanContext {
+	if span := SpanFromContext(ctx); span != nil {
+		return span.SpanContext
+	}
+	if ctx == nil {
+		return SpanContext{}
+	}
+	sc, _ := ctx.Value(remoteSpanContextKey{}).(SpanContext)
+	return sc
+}
+
+// StartSpan starts a span at the given time, child of the span or of the caller span context held by the given context.
+// The returned context holds the new span.
+// A span which has no parent starts a new trace, which is sampled according to the configured ratio.
+// A span which has a parent is sampled if its parent is.
+func StartSpan(ctx context.Context, name string, kind SpanKind, start time.Time) (context.Context, *Span) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	p := currentProvider()
+	parent := spanContextFromContext(ctx)
+	span := &Span{
+		Name:       name,
+		Kind:       kind,
+		Parent:     parent,
+		StartTime:  start,
+		Attributes: map[string]interface{}{},
+		provider:   p,
+	}
+	if parent.IsValid() {
+		span.SpanContext.TraceID = parent.TraceID
+		span.SpanContext.Sampled = parent.Sampled
+	} else {
+		span.SpanContext.TraceID = newTraceID()
+		span.SpanContext.Sampled = p != nil && p.sample(span.SpanContext.TraceID)
+	}
+	span.SpanContext.SpanID = newSpanID()
+	return ContextWithSpan(ctx, span), span
+}
+
+func newTraceID() TraceID {
+	var id TraceID
+	for id == (TraceID{}) {
+		rand.Read(id[:])
+	}
+	return id
+}
+
+func newSpanID() SpanID {
+	var id SpanID
+	for id == (SpanID{}) {
+		rand.Read(id[:])
+	}
+	return id
+}
+
+// traceIDBound returns the value the lower half of a trace ID is compared to when sampling with the given ratio
+func traceIDBound(ratio float64) uint64 {
+	if ratio >= 1 {
+		return 1 << 63
+	}
+	if ratio <= 0 {
+		return 0
+	}
+	return uint64(ratio * (1 << 63))
+}
+
+// sampledTraceID returns true if the trace ID is below the given bound, which makes the sampling decision
+// the same for all the services using the same ratio
+func sampledTraceID(id TraceID, bound uint64) bool {
+	return binary.BigEndian.Uint64(id[8:16])>>1 < bound
+}
@@ -0,0 +1,141 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/fabric8-services/fabric8-auth/log"
+
+	"github.com/goadesign/goa"
+	"github.com/goadesign/goa/middleware"
+)
+
+const (
+	// ServiceName is the name of the service reported in the traces
+	ServiceName = "fabric8-auth"
+
+	instrumentationName = "github.com/fabric8-services/fabric8-auth"
+
+	// traceparentHeader is the W3C trace context header
+	traceparentHeader = "traceparent"
+)
+
+type tracingConfiguration interface {
+	IsTracingEnabled() bool
+	GetTracingFile() string
+	GetTracingSampleRatio() float64
+}
+
+// Init sets up the propagation of the trace context and, if tracing is enabled, the recording of the traces.
+// The outbound calls done with the default HTTP transport are traced and the trace context is propagated to them.
+// The traces are written to the tracing file as OTLP/JSON, to be shipped to a collector (e.g. Jaeger)
+// by the collector itself: the service doesn't export them over the network.
+// If tracing is not enabled then only the incoming trace context is propagated and no trace is recorded.
+// The returned function closes the tracing file. It must be called before the service exits.
+func Init(config tracingConfiguration) (func(context.Context) error, error) {
+	http.DefaultTransport = Transport(http.DefaultTransport)
+
+	if !config.IsTracingEnabled() {
+		log.Info(nil, nil, "Tracing not enabled. Traces are not recorded.")
+		return func(context.Context) error { return nil }, nil
+	}
+	exporter, err := NewFileExporter(config.GetTracingFile())
+	if err != nil {
+		return nil, err
+	}
+	shutdown := Register(exporter, config.GetTracingSampleRatio())
+	log.Info(nil, map[string]interface{}{
+		"file":         config.GetTracingFile(),
+		"sample_ratio": config.GetTracingSampleRatio(),
+	}, "Tracing enabled")
+	return shutdown, nil
+}
+
+// Transport wraps the given transport so the outbound requests are traced
+// and the trace context of the request context is propagated with the traceparent header
+func Transport(base http.RoundTripper) http.RoundTripper {
+	return &transport{base: base}
+}
+
+type transport struct {
+	base http.RoundTripper
+}
+
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if SpanFromContext(req.Context()) == nil {
+		// not part of a trace
+		return t.base.RoundTrip(req)
+	}
+	ctx, span := StartSpan(req.Context(), "HTTP "+req.Method, SpanKindClient, time.Now())
+	defer span.End()
+	span.SetAttribute("http.method", req.Method)
+	span.SetAttribute("http.url", req.URL.Scheme+"://"+req.URL.Host+req.URL.Path)
+
+	// the request must not be modified by a transport so the header is set on a copy
+	outbound := req.WithContext(ctx)
+	outbound.Header = make(http.Header, len(req.Header)+1)
+	for name, values := range req.Header {
+		outbound.Header[name] = values
+	}
+	outbound.Header.Set(traceparentHeader, span.SpanContext.Traceparent())
+
+	res, err := t.base.RoundTrip(outbound)
+	if err != nil {
+		span.SetError(err.Error())
+		return nil, err
+	}
+	span.SetAttribute("http.status_code", res.StatusCode)
+	if res.StatusCode >= http.StatusInternalServerError {
+		span.SetError(http.StatusText(res.StatusCode))
+	}
+	return res, nil
+}
+
+// Middleware starts a span for each goa action. The trace context of the incoming request
+// (the traceparent header) is used as the parent of the span.
+func Middleware() goa.Middleware {
+	return func(h goa.Handler) goa.Handler {
+		return func(ctx context.Context, rw http.ResponseWriter, req *http.Request) error {
+			if sc, ok := ParseTraceparent(req.Header.Get(traceparentHeader)); ok {
+				ctx = ContextWithRemoteSpanContext(ctx, sc)
+			}
+			name := fmt.Sprintf("%s.%s", goa.ContextController(ctx), goa.ContextAction(ctx))
+			ctx, span := StartSpan(ctx, name, SpanKindServer, time.Now())
+			defer span.End()
+			span.SetAttribute("http.method", req.Method)
+			span.SetAttribute("http.target", req.URL.Path)
+			span.SetAttribute("request_id", middleware.ContextRequestID(ctx))
+
+			err := h(ctx, rw, req)
+			if resp := goa.ContextResponse(ctx); resp != nil {
+				span.SetAttribute("http.status_code", resp.Status)
+				if resp.Status >= http.StatusInternalServerError {
+					span.SetError(http.StatusText(resp.Status))
+				}
+			}
+			if err != nil {
+				span.SetError(err.Error())
+			}
+			return err
+		}
+	}
+}
+
+// MeasureSince records the duration of a call as a goa metric (see goa.MeasureSince)
+// and as a span started at the given time, child of the span of the given context.
+// To be deferred at the beginning of repository calls:
+//
+//	defer tracing.MeasureSince(ctx, []string{"goa", "db", "user", "load"}, time.Now())
+func MeasureSince(ctx context.Context, key []string, start time.Time) {
+	goa.MeasureSince(key, start)
+	if SpanFromContext(ctx) == nil {
+		// no trace to attach the span to
+		return
+	}
+	_, span := StartSpan(ctx, strings.Join(key, "."), SpanKindClient, start)
+	span.SetAttribute("db.system", "postgresql")
+	span.End()
+}
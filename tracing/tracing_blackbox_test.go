@@ -0,0 +1,191 @@
+package tracing_test
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/fabric8-services/fabric8-auth/resource"
+	"github.com/fabric8-services/fabric8-auth/tracing"
+
+	"github.com/goadesign/goa"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseTraceparent(t *testing.T) {
+	resource.Require(t, resource.UnitTest)
+
+	sc, ok := tracing.ParseTraceparent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	require.True(t, ok)
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", sc.TraceID.String())
+	assert.Equal(t, "00f067aa0ba902b7", sc.SpanID.String())
+	assert.True(t, sc.Sampled)
+	assert.Equal(t, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", sc.Traceparent())
+
+	for _, invalid := range []string{
+		"",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7",
+		"00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01",
+		"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e473z-00f067aa0ba902b7-01",
+	} {
+		_, ok := tracing.ParseTraceparent(invalid)
+		assert.False(t, ok, "traceparent: '%s'", invalid)
+	}
+}
+
+func TestMiddlewareStartsSpanForAction(t *testing.T) {
+	resource.Require(t, resource.UnitTest)
+	exporter := &tracing.InMemoryExporter{}
+	shutdown := tracing.Register(exporter, 1)
+
+	// given an incoming request which belongs to a trace started by the caller
+	parent := "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	service := goa.New("test")
+	service.Use(tracing.Middleware())
+	var actionSpan tracing.SpanContext
+	handler := service.NewController("UsersController").MuxHandler("show", func(ctx context.Context, rw http.ResponseWriter, req *http.Request) error {
+		actionSpan = tracing.SpanFromContext(ctx).SpanContext
+		tracing.MeasureSince(ctx, []string{"goa", "db", "user", "load"}, time.Now())
+		goa.ContextResponse(ctx).WriteHeader(http.StatusOK)
+		return nil
+	}, nil)
+	req := httptest.NewRequest("GET", "/api/users/foo", nil)
+	req.Header.Set("traceparent", parent)
+
+	// when
+	handler(httptest.NewRecorder(), req, url.Values{})
+	require.NoError(t, shutdown(context.Background()))
+
+	// then the action span is a child of the caller span and the repository call a child of the action span
+	spans := exporter.Spans()
+	require.Len(t, spans, 2)
+	assert.Equal(t, "goa.db.user.load", spans[0].Name)
+	assert.Equal(t, actionSpan.SpanID, spans[0].Parent.SpanID)
+	assert.Equal(t, "UsersController.show", spans[1].Name)
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", spans[1].SpanContext.TraceID.String())
+	assert.Equal(t, "00f067aa0ba902b7", spans[1].Parent.SpanID.String())
+	assert.Equal(t, tracing.SpanKindServer, spans[1].Kind)
+	assert.Equal(t, http.StatusOK, spans[1].Attributes["http.status_code"])
+}
+
+func TestMiddlewareKeepsCallerSamplingDecision(t *testing.T) {
+	resource.Require(t, resource.UnitTest)
+	exporter := &tracing.InMemoryExporter{}
+	shutdown := tracing.Register(exporter, 1)
+
+	// given an incoming request which belongs to a trace the caller didn't sample
+	service := goa.New("test")
+	service.Use(tracing.Middleware())
+	handler := service.NewController("UsersController").MuxHandler("show", func(ctx context.Context, rw http.ResponseWriter, req *http.Request) error {
+		return nil
+	}, nil)
+	req := httptest.NewRequest("GET", "/api/users/foo", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00")
+
+	// when
+	handler(httptest.NewRecorder(), req, url.Values{})
+	require.NoError(t, shutdown(context.Background()))
+
+	// then
+	assert.Empty(t, exporter.Spans())
+}
+
+func TestMeasureSinceWithoutTrace(t *testing.T) {
+	resource.Require(t, resource.UnitTest)
+	exporter := &tracing.InMemoryExporter{}
+	shutdown := tracing.Register(exporter, 1)
+
+	tracing.MeasureSince(context.Background(), []string{"goa", "db", "user", "load"}, time.Now())
+	require.NoError(t, shutdown(context.Background()))
+
+	assert.Empty(t, exporter.Spans())
+}
+
+func TestTransportPropagatesTraceContext(t *testing.T) {
+	resource.Require(t, resource.UnitTest)
+	exporter := &tracing.InMemoryExporter{}
+	shutdown := tracing.Register(exporter, 1)
+
+	// given a downstream service which records the trace context it received
+	traceparents := make(chan string, 1)
+	ts := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		traceparents <- req.Header.Get("traceparent")
+	}))
+	defer ts.Close()
+	ctx, span := tracing.StartSpan(context.Background(), "test", tracing.SpanKindInternal, time.Now())
+	client := &http.Client{Transport: tracing.Transport(http.DefaultTransport)}
+	req, err := http.NewRequest("GET", ts.URL, nil)
+	require.NoError(t, err)
+
+	// when
+	res, err := client.Do(req.WithContext(ctx))
+
+	// then
+	require.NoError(t, err)
+	defer res.Body.Close()
+	span.End()
+	require.NoError(t, shutdown(context.Background()))
+	traceparent := <-traceparents
+	assert.Contains(t, traceparent, span.SpanContext.TraceID.String())
+	assert.Empty(t, req.Header.Get("traceparent"), "the original request must not be modified")
+	spans := exporter.Spans()
+	require.Len(t, spans, 2)
+	assert.Equal(t, "HTTP GET", spans[0].Name)
+	assert.Equal(t, spans[0].SpanContext.Traceparent(), traceparent)
+	assert.Equal(t, span.SpanContext.SpanID, spans[0].Parent.SpanID)
+}
+
+func TestFileExporter(t *testing.T) {
+	resource.Require(t, resource.UnitTest)
+	dir, err := ioutil.TempDir("", "traces")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "traces.json")
+	exporter, err := tracing.NewFileExporter(file)
+	require.NoError(t, err)
+	shutdown := tracing.Register(exporter, 1)
+
+	// when
+	_, span := tracing.StartSpan(context.Background(), "test", tracing.SpanKindInternal, time.Now())
+	span.SetAttribute("foo", "bar")
+	span.SetError("failure")
+	span.End()
+	require.NoError(t, shutdown(context.Background()))
+
+	// then the span is written as an OTLP/JSON request and the file is closed
+	content, err := ioutil.ReadFile(file)
+	require.NoError(t, err)
+	var request struct {
+		ResourceSpans []struct {
+			ScopeSpans []struct {
+				Spans []struct {
+					TraceID string `json:"traceId"`
+					Name    string `json:"name"`
+					Status  struct {
+						Code    int    `json:"code"`
+						Message string `json:"message"`
+					} `json:"status"`
+				} `json:"spans"`
+			} `json:"scopeSpans"`
+		} `json:"resourceSpans"`
+	}
+	require.NoError(t, json.Unmarshal(content, &request))
+	require.Len(t, request.ResourceSpans, 1)
+	require.Len(t, request.ResourceSpans[0].ScopeSpans[0].Spans, 1)
+	exported := request.ResourceSpans[0].ScopeSpans[0].Spans[0]
+	assert.Equal(t, "test", exported.Name)
+	assert.Equal(t, span.SpanContext.TraceID.String(), exported.TraceID)
+	assert.Equal(t, 2, exported.Status.Code)
+	assert.Equal(t, "failure", exported.Status.Message)
+	assert.Error(t, exporter.ExportSpans(context.Background(), []*tracing.Span{span}), "the file must be closed")
+}
@@ -0,0 +1,88 @@
+package webhook
+
+import (
+	"context"
+	"time"
+
+	"github.com/fabric8-services/fabric8-auth/errors"
+	"github.com/fabric8-services/fabric8-auth/log"
+	"github.com/fabric8-services/fabric8-auth/tracing"
+
+	"github.com/jinzhu/gorm"
+	uuid "github.com/satori/go.uuid"
+)
+
+// Delivery describes an attempt to deliver an event to a subscription
+type Delivery struct {
+	ID             uuid.UUID `sql:"type:uuid default uuid_generate_v4()" gorm:"primary_key"` // This is the ID PK field
+	CreatedAt      time.Time
+	SubscriptionID uuid.UUID `sql:"type:uuid"`
+	// OutboxEventID is the ID of the outbox event delivered to the subscription, which is the same for all the attempts
+	OutboxEventID uuid.UUID `sql:"type:uuid"`
+	EventType     string
+	// Attempt is the number of the attempt to deliver the event, starting at 1
+	Attempt int
+	// StatusCode is the HTTP status of the response of the subscription URL, zero if no response was received
+	StatusCode int
+	Error      string
+	// DurationMillis is the time to get the response of the subscription URL, in milliseconds
+	DurationMillis int64 `gorm:"column:duration_ms"`
+}
+
+// TableName overrides the table name settings in Gorm to force a specific table name
+// in the database.
+func (m Delivery) TableName() string {
+	return "webhook_deliveries"
+}
+
+// Succeeded returns true if the subscription URL acknowledged the event
+func (m Delivery) Succeeded() bool {
+	return m.StatusCode >= 200 && m.StatusCode < 300
+}
+
+// DeliveryRepository represents the storage interface.
+type DeliveryRepository interface {
+	Create(ctx context.Context, delivery *Delivery) error
+	List(ctx context.Context, subscriptionID uuid.UUID, limit int) ([]Delivery, error)
+}
+
+// NewDeliveryRepository creates a new storage type.
+func NewDeliveryRepository(db *gorm.DB) *GormDeliveryRepository {
+	return &GormDeliveryRepository{db: db}
+}
+
+// GormDeliveryRepository is the implementation of the storage interface for Delivery.
+type GormDeliveryRepository struct {
+	db *gorm.DB
+}
+
+// Create records a new delivery attempt
+// returns InternalError
+func (m *GormDeliveryRepository) Create(ctx context.Context, delivery *Delivery) error {
+	defer tracing.MeasureSince(ctx, []string{"goa", "db", "webhook_delivery", "create"}, time.Now())
+	if delivery.ID == uuid.Nil {
+		delivery.ID = uuid.NewV4()
+	}
+	err := m.db.Create(delivery).Error
+	if err != nil {
+		log.Error(ctx, map[string]interface{}{
+			"subscription_id": delivery.SubscriptionID,
+			"outbox_event_id": delivery.OutboxEventID,
+			"err":             err,
+		}, "unable to record the webhook delivery")
+		return errors.NewInternalError(ctx, err)
+	}
+	return nil
+}
+
+// List returns the most recent deliveries to the given subscription, newest first
+// returns InternalError
+func (m *GormDeliveryRepository) List(ctx context.Context, subscriptionID uuid.UUID, limit int) ([]Delivery, error) {
+	defer tracing.MeasureSince(ctx, []string{"goa", "db", "webhook_delivery", "list"}, time.Now())
+	var deliveries []Delivery
+	err := m.db.Where("subscription_id = ?", subscriptionID).Order("created_at desc").Limit(limit).Find(&deliveries).Error
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return nil, errors.NewInternalError(ctx, err)
+	}
+	return deliveries, nil
+}
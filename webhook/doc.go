@@ -0,0 +1,5 @@
+// Package webhook stores the URLs the service accounts subscribe to the identity and authorization events
+// with, and the log of the deliveries of the events to these URLs. The events are emitted in the same database
+// transaction as the changes they are about, as outbox events which are delivered by the outbox dispatcher,
+// with an HMAC signature of the body computed with the secret of the subscription.
+package webhook
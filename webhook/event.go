@@ -0,0 +1,123 @@
+package webhook
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"time"
+
+	"github.com/fabric8-services/fabric8-auth/log"
+	"github.com/fabric8-services/fabric8-auth/outbox"
+
+	errs "github.com/pkg/errors"
+	uuid "github.com/satori/go.uuid"
+)
+
+const (
+	// EventTypeUserCreated is the type of the events emitted when a user is created
+	EventTypeUserCreated = outbox.EventTypeUserCreated
+	// EventTypeUserUpdated is the type of the events emitted when a user is updated
+	EventTypeUserUpdated = outbox.EventTypeUserUpdated
+	// EventTypeIdentityLinked is the type of the events emitted when a user links an account of an external provider
+	EventTypeIdentityLinked = "identity.linked"
+	// EventTypeCollaboratorAdded is the type of the events emitted when a user becomes a space collaborator
+	EventTypeCollaboratorAdded = outbox.EventTypeCollaboratorAdded
+	// EventTypeCollaboratorRemoved is the type of the events emitted when a user is not a space collaborator anymore
+	EventTypeCollaboratorRemoved = outbox.EventTypeCollaboratorRemoved
+	// EventTypeSpaceDeleted is the type of the events emitted when a space is deleted
+	EventTypeSpaceDeleted = "space.deleted"
+
+	// SignatureHeader is the header of the HMAC-SHA256 signature of the body posted to a subscription
+	SignatureHeader = "X-Auth-Signature"
+	// EventTypeHeader is the header of the type of the event posted to a subscription
+	EventTypeHeader = "X-Auth-Event"
+	// DeliveryHeader is the header of the ID of the event posted to a subscription, which is the same for all
+	// the attempts to deliver the event, so the duplicates can be discarded
+	DeliveryHeader = "X-Auth-Delivery"
+)
+
+// SupportedEventTypes is the list of the types of the events the service accounts can subscribe to
+var SupportedEventTypes = []string{
+	EventTypeUserCreated,
+	EventTypeUserUpdated,
+	EventTypeIdentityLinked,
+	EventTypeCollaboratorAdded,
+	EventTypeCollaboratorRemoved,
+	EventTypeSpaceDeleted,
+}
+
+// IsSupported returns true if the service accounts can subscribe to the events of the given type
+func IsSupported(eventType string) bool {
+	return EventTypes(SupportedEventTypes).Contains(eventType)
+}
+
+// Data is the data of an event posted to a subscription. Only the fields relevant to the type of the event are set.
+type Data struct {
+	IdentityID uuid.UUID  `json:"identity_id"`
+	Username   string     `json:"username,omitempty"`
+	SpaceID    *uuid.UUID `json:"space_id,omitempty"`
+	// Provider is the type of the external provider of a linked account, e.g. "github" or "openshift-v3"
+	Provider string `json:"provider,omitempty"`
+	// Resource is the URL of the resource of a linked account
+	Resource string `json:"resource,omitempty"`
+}
+
+// Body is the JSON document posted to the URL of a subscription
+type Body struct {
+	ID        uuid.UUID `json:"id"`
+	Type      string    `json:"type"`
+	CreatedAt time.Time `json:"created_at"`
+	Data      Data      `json:"data"`
+}
+
+// Repositories is the subset of the application repositories used to emit the events
+type Repositories interface {
+	WebhookSubscriptions() SubscriptionRepository
+	OutboxEvents() outbox.EventRepository
+}
+
+// Emit notifies the subscriptions to the events of the given type of an event with the given data.
+// An outbox event is created for each subscription, so the event must be emitted in the transaction
+// of the changes it is about.
+func Emit(ctx context.Context, repos Repositories, eventType string, data Data) error {
+	subscriptions, err := repos.WebhookSubscriptions().ListByEventType(ctx, eventType)
+	if err != nil {
+		return err
+	}
+	if len(subscriptions) == 0 {
+		return nil
+	}
+	body, err := json.Marshal(Body{
+		ID:        uuid.NewV4(),
+		Type:      eventType,
+		CreatedAt: time.Now().UTC(),
+		Data:      data,
+	})
+	if err != nil {
+		return errs.Wrapf(err, "unable to encode the %s webhook event", eventType)
+	}
+	for _, subscription := range subscriptions {
+		event, err := outbox.NewWebhookEvent(eventType, data.IdentityID, subscription.ID, body)
+		if err != nil {
+			return err
+		}
+		if err := repos.OutboxEvents().Create(ctx, event); err != nil {
+			return err
+		}
+	}
+	log.Debug(ctx, map[string]interface{}{
+		"event_type":    eventType,
+		"identity_id":   data.IdentityID,
+		"subscriptions": len(subscriptions),
+	}, "webhook event emitted")
+	return nil
+}
+
+// Sign returns the value of the signature header of the given body, signed with the given secret
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,189 @@
+package webhook
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql/driver"
+	"encoding/hex"
+	"encoding/json"
+	"time"
+
+	"github.com/fabric8-services/fabric8-auth/errors"
+	"github.com/fabric8-services/fabric8-auth/gormsupport"
+	"github.com/fabric8-services/fabric8-auth/log"
+	"github.com/fabric8-services/fabric8-auth/tracing"
+
+	"github.com/jinzhu/gorm"
+	errs "github.com/pkg/errors"
+	uuid "github.com/satori/go.uuid"
+)
+
+// EventTypes is the list of the types of the events a subscription is notified of, stored as a JSON array
+type EventTypes []string
+
+// Value implements the driver.Valuer interface
+func (t EventTypes) Value() (driver.Value, error) {
+	if t == nil {
+		return []byte("[]"), nil
+	}
+	return json.Marshal(t)
+}
+
+// Scan implements the sql.Scanner interface
+func (t *EventTypes) Scan(src interface{}) error {
+	switch s := src.(type) {
+	case nil:
+		*t = nil
+		return nil
+	case []byte:
+		return json.Unmarshal(s, t)
+	case string:
+		return json.Unmarshal([]byte(s), t)
+	}
+	return errs.Errorf("unable to scan the event types from %T", src)
+}
+
+// Contains returns true if the given event type is in the list
+func (t EventTypes) Contains(eventType string) bool {
+	for _, e := range t {
+		if e == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// Subscription describes the URL a service account is notified of the events of the given types at
+type Subscription struct {
+	gormsupport.Lifecycle
+	ID uuid.UUID `sql:"type:uuid default uuid_generate_v4()" gorm:"primary_key"` // This is the ID PK field
+	// ServiceAccount is the name of the service account which owns the subscription
+	ServiceAccount string
+	URL            string
+	EventTypes     EventTypes `sql:"type:jsonb"`
+	// Secret is the key of the HMAC signature of the bodies sent to the URL. It is only returned
+	// to the service account when the subscription is created.
+	Secret string
+}
+
+// TableName overrides the table name settings in Gorm to force a specific table name
+// in the database.
+func (m Subscription) TableName() string {
+	return "webhook_subscriptions"
+}
+
+// NewSecret returns a new random secret to sign the bodies sent to a subscription with
+func NewSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", errs.Wrap(err, "unable to generate the webhook secret")
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// SubscriptionRepository represents the storage interface.
+type SubscriptionRepository interface {
+	Load(ctx context.Context, id uuid.UUID) (*Subscription, error)
+	Create(ctx context.Context, subscription *Subscription) error
+	Delete(ctx context.Context, id uuid.UUID) error
+	ListByServiceAccount(ctx context.Context, serviceAccount string) ([]Subscription, error)
+	ListByEventType(ctx context.Context, eventType string) ([]Subscription, error)
+}
+
+// NewSubscriptionRepository creates a new storage type.
+func NewSubscriptionRepository(db *gorm.DB) *GormSubscriptionRepository {
+	return &GormSubscriptionRepository{db: db}
+}
+
+// GormSubscriptionRepository is the implementation of the storage interface for Subscription.
+type GormSubscriptionRepository struct {
+	db *gorm.DB
+}
+
+// Load returns the subscription for the given id
+// returns NotFoundError or InternalError
+func (m *GormSubscriptionRepository) Load(ctx context.Context, id uuid.UUID) (*Subscription, error) {
+	defer tracing.MeasureSince(ctx, []string{"goa", "db", "webhook_subscription", "load"}, time.Now())
+	subscription := Subscription{}
+	tx := m.db.Where("id = ?", id).First(&subscription)
+	if tx.RecordNotFound() {
+		return nil, errors.NewNotFoundError("webhook_subscription", id.String())
+	}
+	if tx.Error != nil {
+		return nil, errors.NewInternalError(ctx, tx.Error)
+	}
+	return &subscription, nil
+}
+
+// Create creates a new subscription
+// returns InternalError
+func (m *GormSubscriptionRepository) Create(ctx context.Context, subscription *Subscription) error {
+	defer tracing.MeasureSince(ctx, []string{"goa", "db", "webhook_subscription", "create"}, time.Now())
+	if subscription.ID == uuid.Nil {
+		subscription.ID = uuid.NewV4()
+	}
+	err := m.db.Create(subscription).Error
+	if err != nil {
+		log.Error(ctx, map[string]interface{}{
+			"service_account": subscription.ServiceAccount,
+			"url":             subscription.URL,
+			"err":             err,
+		}, "unable to create the webhook subscription")
+		return errors.NewInternalError(ctx, err)
+	}
+	log.Info(ctx, map[string]interface{}{
+		"subscription_id": subscription.ID,
+		"service_account": subscription.ServiceAccount,
+		"event_types":     subscription.EventTypes,
+	}, "Webhook subscription created")
+	return nil
+}
+
+// Delete removes the subscription with the given id. The events which are not delivered yet are discarded.
+// returns NotFoundError or InternalError
+func (m *GormSubscriptionRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	defer tracing.MeasureSince(ctx, []string{"goa", "db", "webhook_subscription", "delete"}, time.Now())
+	tx := m.db.Delete(&Subscription{ID: id})
+	if tx.Error != nil {
+		log.Error(ctx, map[string]interface{}{
+			"subscription_id": id,
+			"err":             tx.Error,
+		}, "unable to delete the webhook subscription")
+		return errors.NewInternalError(ctx, tx.Error)
+	}
+	if tx.RowsAffected == 0 {
+		return errors.NewNotFoundError("webhook_subscription", id.String())
+	}
+	log.Info(ctx, map[string]interface{}{
+		"subscription_id": id,
+	}, "Webhook subscription deleted")
+	return nil
+}
+
+// ListByServiceAccount returns the subscriptions of the given service account, oldest first
+// returns InternalError
+func (m *GormSubscriptionRepository) ListByServiceAccount(ctx context.Context, serviceAccount string) ([]Subscription, error) {
+	defer tracing.MeasureSince(ctx, []string{"goa", "db", "webhook_subscription", "listByServiceAccount"}, time.Now())
+	var subscriptions []Subscription
+	err := m.db.Where("service_account = ?", serviceAccount).Order("created_at").Find(&subscriptions).Error
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return nil, errors.NewInternalError(ctx, err)
+	}
+	return subscriptions, nil
+}
+
+// ListByEventType returns the subscriptions which are notified of the events of the given type
+// returns InternalError
+func (m *GormSubscriptionRepository) ListByEventType(ctx context.Context, eventType string) ([]Subscription, error) {
+	defer tracing.MeasureSince(ctx, []string{"goa", "db", "webhook_subscription", "listByEventType"}, time.Now())
+	js, err := json.Marshal([]string{eventType})
+	if err != nil {
+		return nil, errors.NewInternalError(ctx, err)
+	}
+	var subscriptions []Subscription
+	err = m.db.Where("event_types @> ?::jsonb", string(js)).Order("created_at").Find(&subscriptions).Error
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return nil, errors.NewInternalError(ctx, err)
+	}
+	return subscriptions, nil
+}
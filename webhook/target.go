@@ -0,0 +1,120 @@
+package webhook
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+
+	errs "github.com/pkg/errors"
+)
+
+// privateNetworks are the networks of the addresses which are not routable on the internet
+var privateNetworks = mustParseCIDRs(
+	"0.0.0.0/8",
+	"10.0.0.0/8",
+	"100.64.0.0/10",
+	"172.16.0.0/12",
+	"192.168.0.0/16",
+	"198.18.0.0/15",
+	"fc00::/7",
+)
+
+func mustParseCIDRs(cidrs ...string) []*net.IPNet {
+	networks := make([]*net.IPNet, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			panic(err)
+		}
+		networks = append(networks, network)
+	}
+	return networks
+}
+
+// IsPublicIP returns true if the given address can be the target of a webhook, i.e. it is not a loopback,
+// private, link-local (like the cloud metadata endpoints), unspecified or multicast address
+func IsPublicIP(ip net.IP) bool {
+	if ip.IsLoopback() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() ||
+		ip.IsMulticast() || ip.IsUnspecified() {
+		return false
+	}
+	for _, network := range privateNetworks {
+		if network.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+// CheckURL returns an error unless the given URL is an absolute http or https URL whose host is a public address,
+// or a host name which only resolves to public addresses. A host name which can't be resolved is accepted,
+// its addresses are checked again each time an event is delivered anyway (see NewHTTPClient).
+func CheckURL(ctx context.Context, rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Hostname() == "" {
+		return errs.Errorf("'%s' is not an absolute http or https URL", rawURL)
+	}
+	if ip := net.ParseIP(u.Hostname()); ip != nil {
+		if !IsPublicIP(ip) {
+			return errs.Errorf("the host of the URL is the non public address %s", ip)
+		}
+		return nil
+	}
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, u.Hostname())
+	if err != nil {
+		return nil
+	}
+	for _, addr := range addrs {
+		if !IsPublicIP(addr.IP) {
+			return errs.Errorf("the host of the URL resolves to the non public address %s", addr.IP)
+		}
+	}
+	return nil
+}
+
+// NewHTTPClient returns a client which posts the events with the given timeout. Unless allowPrivate is true,
+// the client refuses to connect to the non public addresses, including when redirected. The addresses are checked
+// when connecting, so a host name which resolved to a public address when the subscription was created can't be
+// changed to resolve to an internal one later on.
+func NewHTTPClient(timeout time.Duration, allowPrivate bool) *http.Client {
+	if allowPrivate {
+		return &http.Client{Timeout: timeout}
+	}
+	dialer := &net.Dialer{Timeout: timeout}
+	return &http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			// no proxy, which would connect to the address on behalf of the client without any check
+			Proxy:               nil,
+			DialContext:         dialPublic(dialer),
+			TLSHandshakeTimeout: timeout,
+		},
+	}
+}
+
+// dialPublic returns a dial function which resolves the host and connects to the resolved address,
+// unless one of the addresses of the host is not public
+func dialPublic(dialer *net.Dialer) func(ctx context.Context, network, address string) (net.Conn, error) {
+	return func(ctx context.Context, network, address string) (net.Conn, error) {
+		host, port, err := net.SplitHostPort(address)
+		if err != nil {
+			return nil, err
+		}
+		addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+		if err != nil {
+			return nil, err
+		}
+		if len(addrs) == 0 {
+			return nil, errs.Errorf("no address found for %s", host)
+		}
+		for _, addr := range addrs {
+			if !IsPublicIP(addr.IP) {
+				return nil, errs.Errorf("refusing to connect to the non public address %s of %s", addr.IP, host)
+			}
+		}
+		// connect to the checked address rather than to the host, which could resolve to another address in between
+		return dialer.DialContext(ctx, network, net.JoinHostPort(addrs[0].IP.String(), port))
+	}
+}
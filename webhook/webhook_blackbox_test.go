@@ -0,0 +1,188 @@
+package webhook_test
+
+import (
+	"encoding/json"
+	"net"
+	"testing"
+
+	autherrors "github.com/fabric8-services/fabric8-auth/errors"
+	"github.com/fabric8-services/fabric8-auth/gormtestsupport"
+	"github.com/fabric8-services/fabric8-auth/outbox"
+	"github.com/fabric8-services/fabric8-auth/resource"
+	"github.com/fabric8-services/fabric8-auth/webhook"
+
+	errs "github.com/pkg/errors"
+	"github.com/satori/go.uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type webhookBlackBoxTest struct {
+	gormtestsupport.DBTestSuite
+}
+
+func TestRunWebhookBlackBoxTest(t *testing.T) {
+	resource.Require(t, resource.Database)
+	suite.Run(t, &webhookBlackBoxTest{DBTestSuite: gormtestsupport.NewDBTestSuite()})
+}
+
+func TestIsPublicIP(t *testing.T) {
+	resource.Require(t, resource.UnitTest)
+	for address, public := range map[string]bool{
+		"8.8.8.8":          true,
+		"2001:4860::8888":  true,
+		"127.0.0.1":        false,
+		"::1":              false,
+		"10.1.2.3":         false,
+		"172.16.0.1":       false,
+		"192.168.1.1":      false,
+		"169.254.169.254":  false,
+		"fe80::1":          false,
+		"fd00::1":          false,
+		"0.0.0.0":          false,
+		"::ffff:127.0.0.1": false,
+	} {
+		assert.Equal(t, public, webhook.IsPublicIP(net.ParseIP(address)), address)
+	}
+}
+
+func (s *webhookBlackBoxTest) createSubscription(serviceAccount string, eventTypes ...string) webhook.Subscription {
+	secret, err := webhook.NewSecret()
+	s.Require().NoError(err)
+	subscription := webhook.Subscription{
+		ServiceAccount: serviceAccount,
+		URL:            "https://example.com/hooks/" + uuid.NewV4().String(),
+		EventTypes:     eventTypes,
+		Secret:         secret,
+	}
+	s.Require().NoError(s.Application.WebhookSubscriptions().Create(s.Ctx, &subscription))
+	return subscription
+}
+
+func (s *webhookBlackBoxTest) TestCreateAndList() {
+	// given
+	serviceAccount := "service-" + uuid.NewV4().String()
+	users := s.createSubscription(serviceAccount, webhook.EventTypeUserCreated, webhook.EventTypeUserUpdated)
+	spaces := s.createSubscription(serviceAccount, webhook.EventTypeSpaceDeleted)
+	s.createSubscription("other-"+uuid.NewV4().String(), webhook.EventTypeUserCreated)
+
+	// when
+	loaded, err := s.Application.WebhookSubscriptions().Load(s.Ctx, users.ID)
+
+	// then
+	s.Require().NoError(err)
+	s.Equal(users.URL, loaded.URL)
+	s.Equal(webhook.EventTypes{webhook.EventTypeUserCreated, webhook.EventTypeUserUpdated}, loaded.EventTypes)
+	s.Equal(users.Secret, loaded.Secret)
+
+	// when
+	subscriptions, err := s.Application.WebhookSubscriptions().ListByServiceAccount(s.Ctx, serviceAccount)
+
+	// then
+	s.Require().NoError(err)
+	s.Require().Len(subscriptions, 2)
+	s.Equal(users.ID, subscriptions[0].ID)
+	s.Equal(spaces.ID, subscriptions[1].ID)
+
+	// when
+	subscriptions, err = s.Application.WebhookSubscriptions().ListByEventType(s.Ctx, webhook.EventTypeUserUpdated)
+
+	// then
+	s.Require().NoError(err)
+	s.Require().Len(subscriptions, 1)
+	s.Equal(users.ID, subscriptions[0].ID)
+}
+
+func (s *webhookBlackBoxTest) TestDelete() {
+	// given
+	subscription := s.createSubscription("service-"+uuid.NewV4().String(), webhook.EventTypeIdentityLinked)
+
+	// when
+	err := s.Application.WebhookSubscriptions().Delete(s.Ctx, subscription.ID)
+
+	// then
+	s.Require().NoError(err)
+	_, err = s.Application.WebhookSubscriptions().Load(s.Ctx, subscription.ID)
+	s.IsType(autherrors.NotFoundError{}, errs.Cause(err))
+	subscriptions, err := s.Application.WebhookSubscriptions().ListByEventType(s.Ctx, webhook.EventTypeIdentityLinked)
+	s.Require().NoError(err)
+	s.Empty(subscriptions)
+	// and the subscription can't be deleted twice
+	err = s.Application.WebhookSubscriptions().Delete(s.Ctx, subscription.ID)
+	s.IsType(autherrors.NotFoundError{}, errs.Cause(err))
+}
+
+func (s *webhookBlackBoxTest) TestEmit() {
+	// given
+	collaborators := s.createSubscription("service-"+uuid.NewV4().String(), webhook.EventTypeCollaboratorAdded)
+	other := s.createSubscription("service-"+uuid.NewV4().String(), webhook.EventTypeCollaboratorAdded, webhook.EventTypeCollaboratorRemoved)
+	s.createSubscription("service-"+uuid.NewV4().String(), webhook.EventTypeCollaboratorRemoved)
+	identityID := uuid.NewV4()
+	spaceID := uuid.NewV4()
+
+	// when
+	err := webhook.Emit(s.Ctx, s.Application, webhook.EventTypeCollaboratorAdded, webhook.Data{IdentityID: identityID, SpaceID: &spaceID})
+
+	// then an event is delivered to each subscription, with the same body
+	s.Require().NoError(err)
+	events, err := s.Application.OutboxEvents().Query(s.Ctx, outbox.EventFilterByIdentityID(identityID), outbox.EventFilterByTarget(outbox.TargetWebhook))
+	s.Require().NoError(err)
+	s.Require().Len(events, 2)
+	subscriptionIDs := map[uuid.UUID]bool{}
+	var bodies []webhook.Body
+	for _, event := range events {
+		s.Equal(webhook.EventTypeCollaboratorAdded, event.EventType)
+		var payload outbox.WebhookPayload
+		s.Require().NoError(event.DecodePayload(&payload))
+		subscriptionIDs[payload.SubscriptionID] = true
+		var body webhook.Body
+		s.Require().NoError(json.Unmarshal(payload.Body, &body))
+		bodies = append(bodies, body)
+	}
+	s.Equal(map[uuid.UUID]bool{collaborators.ID: true, other.ID: true}, subscriptionIDs)
+	s.Equal(bodies[0].ID, bodies[1].ID)
+	s.Equal(webhook.EventTypeCollaboratorAdded, bodies[0].Type)
+	s.Equal(identityID, bodies[0].Data.IdentityID)
+	s.Require().NotNil(bodies[0].Data.SpaceID)
+	s.Equal(spaceID, *bodies[0].Data.SpaceID)
+}
+
+func (s *webhookBlackBoxTest) TestRecordDeliveries() {
+	// given
+	subscription := s.createSubscription("service-"+uuid.NewV4().String(), webhook.EventTypeUserCreated)
+	eventID := uuid.NewV4()
+	for attempt := 1; attempt <= 3; attempt++ {
+		delivery := webhook.Delivery{
+			SubscriptionID: subscription.ID,
+			OutboxEventID:  eventID,
+			EventType:      webhook.EventTypeUserCreated,
+			Attempt:        attempt,
+			StatusCode:     500,
+			Error:          "internal error",
+			DurationMillis: 12,
+		}
+		s.Require().NoError(s.Application.WebhookDeliveries().Create(s.Ctx, &delivery))
+	}
+
+	// when
+	deliveries, err := s.Application.WebhookDeliveries().List(s.Ctx, subscription.ID, 2)
+
+	// then the most recent deliveries are returned first
+	s.Require().NoError(err)
+	s.Require().Len(deliveries, 2)
+	s.Equal(3, deliveries[0].Attempt)
+	s.Equal(2, deliveries[1].Attempt)
+	s.Equal(eventID, deliveries[0].OutboxEventID)
+	s.Equal(int64(12), deliveries[0].DurationMillis)
+	s.False(deliveries[0].Succeeded())
+}
+
+func (s *webhookBlackBoxTest) TestSign() {
+	// when
+	signature := webhook.Sign("secret", []byte(`{"id":"1"}`))
+
+	// then
+	s.Equal(signature, webhook.Sign("secret", []byte(`{"id":"1"}`)))
+	s.NotEqual(signature, webhook.Sign("other", []byte(`{"id":"1"}`)))
+	s.Regexp("^sha256=[0-9a-f]{64}$", signature)
+}